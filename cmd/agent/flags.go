@@ -70,6 +70,10 @@ var (
 
 	flagFlushevery           = fs.Int("flushevery", defaults.FlushEvery, "flush assembler every N packets")
 	flagDefragIPv4           = fs.Bool("ip4defrag", defaults.DefragIPv4, "Defragment IPv4 packets")
+	flagDefragIPv6           = fs.Bool("ip6defrag", defaults.DefragIPv6, "Defragment IPv6 packets")
+	flagIPFIX                = fs.String("ipfix", "", "export Connection audit records as IPFIX to the collector at the given UDP address")
+	flagIPFIXActiveTimeout   = fs.Duration("ipfix-active-timeout", defaults.IPFIXActiveTimeout, "maximum time a connection is buffered before it is sent to the IPFIX collector")
+	flagDecapsulateGTP       = fs.Bool("gtp-decap", defaults.DecapsulateGTP, "Decode packets tunneled in GTP-U separately")
 	flagChecksum             = fs.Bool("checksum", defaults.Checksum, "check TCP checksum")
 	flagNooptcheck           = fs.Bool("nooptcheck", defaults.NoOptCheck, "do not check TCP options (useful to ignore MSS on captures with TSO)")
 	flagIgnorefsmerr         = fs.Bool("ignorefsmerr", defaults.IgnoreFSMErr, "ignore TCP FSM errors")
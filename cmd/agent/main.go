@@ -115,6 +115,10 @@ func Run() {
 			MemBufferSize:        *flagMemBufferSize,
 			FlushEvery:           *flagFlushevery,
 			DefragIPv4:           *flagDefragIPv4,
+			DefragIPv6:           *flagDefragIPv6,
+			IPFIXExport:          *flagIPFIX,
+			IPFIXActiveTimeout:   *flagIPFIXActiveTimeout,
+			DecapsulateGTP:       *flagDecapsulateGTP,
 			Checksum:             *flagChecksum,
 			NoOptCheck:           *flagNooptcheck,
 			IgnoreFSMerr:         *flagIgnorefsmerr,
@@ -37,7 +37,7 @@ var (
 	flagGenerateConfig         = fs.Bool("gen-config", false, "generate config")
 	flagGenerateElasticIndices = fs.Bool("gen-elastic-indices", false, "generate elastic indices and mapping")
 	_                          = fs.String("config", "", "read configuration from file at path")
-	flagInput                  = fs.String("read", "", "read specified file, can either be a pcap or netcap audit record file")
+	flagInput                  = fs.String("read", "", "read specified pcap or pcapng file, optionally compressed with gzip, zstd, lz4 or xz, a directory or glob of capture files, or - for stdin")
 	flagMetricsAddr            = fs.String("metrics", "", "serve metrics at")
 	flagOutDir                 = fs.String("out", "", "specify output directory, will be created if it does not exist")
 	flagTimeout                = fs.Duration("timeout", 1*time.Second, "set the timeout for live capture, providing a value of zero will be substituted with pcap.BlockForever.")
@@ -47,6 +47,9 @@ var (
 	flagScatter         = fs.Bool("scatter", true, "generate a scatter plot for labeled audit records")
 	flagPPS             = fs.Bool("pps", false, "generate a line plot for throughput in packets per second")
 
+	flagReplay      = fs.Bool("replay", false, "replay the input file (-read) at the pace of the packet timestamps and process it like a live capture")
+	flagReplaySpeed = fs.Float64("replay-speed", 1, "speed multiplier for replay mode, e.g. 10 replays ten times faster than the original traffic")
+
 	flagBPF = fs.String("bpf", "", "supply a BPF filter to use prior to processing packets with netcap")
 
 	flagInclude = fs.String("include", "", "include specific decoders")
@@ -57,15 +60,24 @@ var (
 
 	flagInterface    = fs.String("iface", "", "attach to network interface and capture in live mode")
 	flagCompress     = fs.Bool("compress", true, "compress output with gzip")
+	flagIndex        = fs.Bool("index", false, "write protobuf audit records in indexed, gzip compressed blocks to allow reading time ranges, requires the gzip codec without dictionary")
 	flagBuffer       = fs.Bool("buf", true, "buffer data in memory before writing to disk")
 	flagWorkers      = fs.Int("workers", runtime.NumCPU()*2, "number of workers") // runtime.NumCPU()
 	flagPacketBuffer = fs.Int("pbuf", defaults.PacketBuffer, "set packet buffer size, for channels that feed data to workers")
 
+	flagAFPacket          = fs.Bool("afpacket", false, "capture live with AF_PACKET ring buffers and one socket per worker, the kernel distributes the packets by flow (linux only), each socket allocates afpacket-blocks * afpacket-block-size bytes (32 MB by default, for each of the -workers)")
+	flagAFPacketBlockSize = fs.Int("afpacket-block-size", defaults.AFPacketBlockSize, "size of the blocks in the AF_PACKET ring buffer of each worker in bytes, must be a multiple of the page size")
+	flagAFPacketBlocks    = fs.Int("afpacket-blocks", defaults.AFPacketNumBlocks, "number of blocks in the AF_PACKET ring buffer of each worker")
+	flagAFPacketFanoutID  = fs.Uint("afpacket-fanout-id", 0, "id of the AF_PACKET fanout group from 1 to 65535, must differ between processes capturing on the host, 0 derives it from the process id")
+
 	flagAnalyzer = fs.String("analyzer", "", "the analyzer to use")
 
 	flagCPUProfile    = fs.Bool("cpuprof", false, "create cpu profile")
 	flagMemProfile    = fs.Bool("memprof", false, "create memory profile")
 	flagIgnoreUnknown = fs.Bool("ignore-unknown", true, "disable writing unknown packets into a pcap file")
+	flagEvidence      = fs.Bool("evidence", false, "write the packets of flows with alerts, credentials, exploits or files into an annotated evidence.pcapng file")
+	flagPacketStore   = fs.Bool("packet-store", false, "write all packets into indexed pcap segments in the packets directory, to extract flows and audit records with net util -extract")
+	flagSegmentSize   = fs.Int64("packet-store-segment-size", defaults.PacketStoreSegmentSize, "size of the pcap segments in the packet store, in bytes")
 	flagPromiscMode   = fs.Bool("promisc", true, "toggle promiscuous mode for live capture")
 	flagSnapLen       = fs.Int("snaplen", defaults.SnapLen, "configure snaplen for live capture from interface")
 
@@ -87,6 +99,10 @@ var (
 	flagKibanaEndpoint   = fs.String("kibana", "", "kibana endpoint URL")
 	flagProto            = fs.Bool("proto", true, "output data as protobuf")
 	flagJSON             = fs.Bool("json", false, "output data as JSON")
+	flagEVE              = fs.Bool("eve", false, "output data as Suricata EVE JSON events for audit records with EVE equivalents")
+	flagParquet          = fs.Bool("parquet", false, "output data as Apache Parquet files, with a columnar schema derived from the audit record types")
+	flagSQLite           = fs.Bool("sqlite", false, "output data into a single SQLite database, with one table per audit record type")
+	flagZeek             = fs.Bool("zeek", false, "output data as Zeek logs for audit records with Zeek equivalents, in the Zeek JSON format when combined with -json")
 	flagContext          = fs.Bool("context", true, "add packet flow context to selected audit records")
 	flagHTTPShutdown     = fs.Bool("http-shutdown", false, "create local endpoint to trigger teardown via HTTP")
 
@@ -117,6 +133,10 @@ var (
 	// reassembly.
 	flagFlushevery           = fs.Int("flushevery", defaults.FlushEvery, "flush assembler every N packets")
 	flagDefragIPv4           = fs.Bool("ip4defrag", defaults.DefragIPv4, "Defragment IPv4 packets")
+	flagDefragIPv6           = fs.Bool("ip6defrag", defaults.DefragIPv6, "Defragment IPv6 packets")
+	flagIPFIX                = fs.String("ipfix", "", "export Connection audit records as IPFIX to the collector at the given UDP address")
+	flagIPFIXActiveTimeout   = fs.Duration("ipfix-active-timeout", defaults.IPFIXActiveTimeout, "maximum time a connection is buffered before it is sent to the IPFIX collector")
+	flagDecapsulateGTP       = fs.Bool("gtp-decap", defaults.DecapsulateGTP, "Decode packets tunneled in GTP-U separately")
 	flagChecksum             = fs.Bool("checksum", defaults.Checksum, "check TCP checksum")
 	flagNooptcheck           = fs.Bool("nooptcheck", defaults.NoOptCheck, "do not check TCP options (useful to ignore MSS on captures with TSO)")
 	flagIgnorefsmerr         = fs.Bool("ignorefsmerr", defaults.IgnoreFSMErr, "ignore TCP FSM errors")
@@ -153,5 +173,10 @@ var (
 	flagNumStreamWorkers    = fs.Int("stream-workers", 10000, "number of TCP / UDP stream workers")
 
 	flagCompressionBlockSize = fs.Int("compression-block-size", defaults.CompressionBlockSize, "block size used for parallel compression")
-	flagCompressionLevel     = fs.String("compression-level", compressionLevelToString(defaults.CompressionLevel), "level of compression")
+	flagCompressionLevel     = fs.String("compression-level", compressionLevelToString(defaults.CompressionLevel), "level of compression: max-speed, max-compression, none, default or a number from 1 to 9")
+	flagCompressionCodec     = fs.String("compression-codec", defaults.CompressionCodec, "codec for compressed output: gzip, zstd or lz4")
+	flagCompressionDict      = fs.String("compression-dict", "", "path to a dictionary for the zstd codec, trained with 'zstd --train'")
+
+	flagParquetCompression  = fs.String("parquet-compression", defaults.ParquetCompression, "codec for Parquet pages: none, snappy, gzip or zstd")
+	flagParquetRowGroupSize = fs.Int("parquet-row-group-size", defaults.ParquetRowGroupSize, "amount of uncompressed data per row group in Parquet files, in bytes")
 )
@@ -16,7 +16,9 @@ package capture
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
@@ -45,6 +47,7 @@ import (
 	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/io"
 	"github.com/dreadl0ck/netcap/metrics"
+	"github.com/dreadl0ck/netcap/parquet"
 	"github.com/dreadl0ck/netcap/reassembly"
 	"github.com/dreadl0ck/netcap/utils"
 )
@@ -171,6 +174,31 @@ func Run() {
 		os.Exit(1)
 	}
 
+	if !io.IsCodec(*flagCompressionCodec) {
+		log.Fatal("invalid compression codec: ", *flagCompressionCodec, ", expected one of ", io.Codecs)
+	}
+
+	if *flagAFPacketFanoutID > math.MaxUint16 {
+		log.Fatal("invalid AF_PACKET fanout group id: ", *flagAFPacketFanoutID, ", expected a value from 0 to 65535")
+	}
+
+	// indexed files consist of gzip members, which are decompressed separately for time range reads
+	if *flagIndex && (*flagCompressionCodec != io.CodecGzip || *flagCompressionDict != "") {
+		log.Fatal("-index writes gzip compressed blocks and cannot be combined with -compression-codec ", *flagCompressionCodec, " or -compression-dict")
+	}
+
+	if _, err := parquet.ParseCodec(*flagParquetCompression); err != nil {
+		log.Fatal(err)
+	}
+
+	var compressionDict []byte
+	if *flagCompressionDict != "" {
+		var errDict error
+		if compressionDict, errDict = ioutil.ReadFile(*flagCompressionDict); errDict != nil {
+			log.Fatal("failed to read compression dictionary: ", errDict)
+		}
+	}
+
 	var exportMetrics bool
 	if *flagMetricsAddr != "" {
 		metrics.ServeMetricsAt(*flagMetricsAddr, nil)
@@ -284,29 +312,38 @@ func Run() {
 
 	// init collector
 	c := collector.New(collector.Config{
-		Workers:               *flagWorkers,
-		PacketBufferSize:      *flagPacketBuffer,
-		WriteUnknownPackets:   !*flagIgnoreUnknown,
-		Promisc:               *flagPromiscMode,
-		SnapLen:               *flagSnapLen,
-		BaseLayer:             utils.GetBaseLayer(*flagBaseLayer),
-		DecodeOptions:         utils.GetDecodeOptions(*flagDecodeOptions),
-		DPI:                   *flagDPI,
-		ReassembleConnections: *flagReassembleConnections,
-		FreeOSMem:             *flagFreeOSMemory,
-		LogErrors:             *flagLogErrors,
-		NoPrompt:              *flagNoPrompt,
-		HTTPShutdownEndpoint:  *flagHTTPShutdown,
-		Timeout:               *flagTimeout,
-		Labels:                *flagLabels,
-		Scatter:               *flagScatter,
-		ScatterDuration:       *flagScatterDuration,
+		Workers:                *flagWorkers,
+		PacketBufferSize:       *flagPacketBuffer,
+		WriteUnknownPackets:    !*flagIgnoreUnknown,
+		WriteEvidencePcap:      *flagEvidence,
+		PacketStore:            *flagPacketStore,
+		PacketStoreSegmentSize: *flagSegmentSize,
+		AFPacket:               *flagAFPacket,
+		AFPacketBlockSize:      *flagAFPacketBlockSize,
+		AFPacketNumBlocks:      *flagAFPacketBlocks,
+		AFPacketFanoutID:       uint16(*flagAFPacketFanoutID),
+		ReplaySpeed:            *flagReplaySpeed,
+		Promisc:                *flagPromiscMode,
+		SnapLen:                *flagSnapLen,
+		BaseLayer:              utils.GetBaseLayer(*flagBaseLayer),
+		DecodeOptions:          utils.GetDecodeOptions(*flagDecodeOptions),
+		DPI:                    *flagDPI,
+		ReassembleConnections:  *flagReassembleConnections,
+		FreeOSMem:              *flagFreeOSMemory,
+		LogErrors:              *flagLogErrors,
+		NoPrompt:               *flagNoPrompt,
+		HTTPShutdownEndpoint:   *flagHTTPShutdown,
+		Timeout:                *flagTimeout,
+		Labels:                 *flagLabels,
+		Scatter:                *flagScatter,
+		ScatterDuration:        *flagScatterDuration,
 		DecoderConfig: &config.Config{
 			Quiet:         *flagQuiet,
 			PrintProgress: *flagPrintProgress,
 			Buffer:        *flagBuffer,
 			MemBufferSize: *flagMemBufferSize,
 			Compression:   *flagCompress,
+			Index:         *flagIndex,
 			CSV:           *flagCSV,
 			UnixSocket:    *flagUNIX,
 			Encode:        *flagEncode,
@@ -326,6 +363,10 @@ func Run() {
 			Out:                            *flagOutDir,
 			Proto:                          *flagProto,
 			JSON:                           *flagJSON,
+			Zeek:                           *flagZeek,
+			EVE:                            *flagEVE,
+			Parquet:                        *flagParquet,
+			SQLite:                         *flagSQLite,
 			Chan:                           false,
 			Source:                         source,
 			IncludePayloads:                *flagPayload,
@@ -333,6 +374,10 @@ func Run() {
 			AddContext:                     *flagContext,
 			FlushEvery:                     *flagFlushevery,
 			DefragIPv4:                     *flagDefragIPv4,
+			DefragIPv6:                     *flagDefragIPv6,
+			IPFIXExport:                    *flagIPFIX,
+			IPFIXActiveTimeout:             *flagIPFIXActiveTimeout,
+			DecapsulateGTP:                 *flagDecapsulateGTP,
 			Checksum:                       *flagChecksum,
 			NoOptCheck:                     *flagNooptcheck,
 			IgnoreFSMerr:                   *flagIgnorefsmerr,
@@ -367,6 +412,10 @@ func Run() {
 			RemoveClosedStreams:            *flagRemoveClosedStreams,
 			CompressionBlockSize:           *flagCompressionBlockSize,
 			CompressionLevel:               getCompressionLevel(*flagCompressionLevel),
+			CompressionCodec:               *flagCompressionCodec,
+			CompressionDict:                compressionDict,
+			ParquetCompression:             *flagParquetCompression,
+			ParquetRowGroupSize:            *flagParquetRowGroupSize,
 		},
 		ResolverConfig: resolvers.Config{
 			ReverseDNS:    *flagReverseDNS,
@@ -379,6 +428,24 @@ func Run() {
 	})
 	c.Bpf = *flagBPF
 	c.InputFile = *flagInput
+
+	if numEpochs > 1 && *flagInput == collector.Stdin {
+		log.Fatal("multiple epochs require an input file, stdin can only be read once")
+	}
+
+	if *flagReplay {
+		switch {
+		case live:
+			log.Fatal("replay mode requires an input file (-read), it can not be used with live capture")
+		case numEpochs > 1:
+			log.Fatal("replay mode can not be used with multiple epochs")
+		case *flagBPF != "":
+			log.Fatal("replay mode does not support BPF filters")
+		case *flagReplaySpeed <= 0:
+			log.Fatal("invalid replay speed: ", *flagReplaySpeed)
+		}
+	}
+
 	c.PrintTime = *flagTime
 	c.Epochs = numEpochs
 
@@ -400,6 +467,15 @@ func Run() {
 		return
 	}
 
+	// replay the input file at the pace of the capture
+	if *flagReplay {
+		if err = c.CollectReplay(*flagInput, context.Background()); err != nil {
+			log.Fatal("failed to replay packets: ", err)
+		}
+
+		return
+	}
+
 	// start timer
 	start := time.Now()
 
@@ -414,27 +490,12 @@ func Run() {
 	}
 
 	// if not, use native pcapgo version
-	isPcap, err := collector.IsPcap(*flagInput)
-	if err != nil {
-		// invalid path
-		fmt.Println("failed to open file:", err)
-		os.Exit(1)
-	}
-
-	if isPcap {
-		if err = c.CollectPcap(*flagInput); err != nil {
-			log.Fatal("failed to collect audit records from pcap file: ", err)
-		}
-	} else {
-		if err = c.CollectPcapNG(*flagInput); err != nil {
-			log.Fatal("failed to collect audit records from pcapng file: ", err)
-		}
+	if err = c.Collect(*flagInput); err != nil {
+		log.Fatal("failed to collect audit records: ", err)
 	}
 
 	if *flagTime {
-		// stat input file
-		stat, _ := os.Stat(*flagInput)
-		fmt.Println("size", humanize.Bytes(uint64(stat.Size())), "done in", time.Since(start))
+		fmt.Println("size", humanize.Bytes(uint64(c.InputSize())), "done in", time.Since(start))
 	}
 
 	// memory profiling
@@ -488,6 +549,10 @@ func makeWriterConfig(name string, typ types.Type, elasticAddrs []string) *io.Wr
 		CSV:        *flagCSV,
 		Proto:      *flagProto,
 		JSON:       *flagJSON,
+		Zeek:       *flagZeek,
+		EVE:        *flagEVE,
+		Parquet:    *flagParquet,
+		SQLite:     *flagSQLite,
 		Name:       name,
 		Type:       typ,
 		Null:       *flagNull,
@@ -501,6 +566,7 @@ func makeWriterConfig(name string, typ types.Type, elasticAddrs []string) *io.Wr
 		},
 		Buffer:        *flagBuffer,
 		Compress:      *flagCompress,
+		Index:         *flagIndex,
 		Out:           *flagOutDir,
 		Chan:          false,
 		ChanSize:      0,
@@ -15,6 +15,7 @@ package capture
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/klauspost/pgzip"
 
@@ -26,6 +27,8 @@ func printHeader() {
 	fmt.Println()
 	fmt.Println("capture tool usage examples:")
 	fmt.Println("	$ net capture -read dump.pcap")
+	fmt.Println("	$ net capture -read 'dumps/*.pcap.gz'")
+	fmt.Println("	$ tcpdump -i eth0 -w - | net capture -read -")
 	fmt.Println("	$ net capture -iface eth0")
 	fmt.Println()
 }
@@ -43,6 +46,10 @@ const (
 )
 
 func getCompressionLevel(in string) int {
+	if level, err := strconv.Atoi(in); err == nil && level >= pgzip.BestSpeed && level <= pgzip.BestCompression {
+		return level
+	}
+
 	switch in {
 	case pgzipMaxSpeed:
 		return pgzip.BestSpeed
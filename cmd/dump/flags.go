@@ -50,4 +50,8 @@ var (
 	flagJSON            = fs.Bool("json", false, "print as JSON")
 	flagMemBufferSize   = fs.Int("membuf-size", defaults.BufferSize, "set size for membuf")
 	flagForceColors     = fs.Bool("c", false, "force colors")
+	flagFrom            = fs.String("from", "", "only dump audit records at or after this time, in RFC3339 format")
+	flagTo              = fs.String("to", "", "only dump audit records before this time, in RFC3339 format")
+	flagCompressionDict = fs.String("compression-dict", "", "path to the dictionary used to compress the file with zstd")
+	flagSQL             = fs.String("sql", "", "run the SQL query against the netcap SQLite database from -read, results are printed as csv, or as table or JSON")
 )
@@ -53,6 +53,25 @@ func Run() {
 		os.Exit(1)
 	}
 
+	// run query against sqlite database and exit
+	if *flagSQL != "" || io.IsSQLite(*flagInput) {
+		if *flagSQL == "" {
+			log.Fatal("need a query for the SQLite database with the sql flag (-sql)")
+		}
+
+		err = io.QuerySQLite(os.Stdout, *flagInput, *flagSQL, io.DumpConfig{
+			Separator:    *flagSeparator,
+			TabSeparated: *flagTSV,
+			Table:        *flagTable,
+			JSON:         *flagJSON,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	if strings.HasSuffix(*flagInput, ".pcap") || strings.HasSuffix(*flagInput, ".pcapng") {
 		printHeader()
 		fmt.Println(ansi.Red + "> the dump tool is used to read netcap audit records" + ansi.Reset)
@@ -62,7 +81,7 @@ func Run() {
 
 	// read dumpfile header and exit
 	if *flagHeader { // open input file for reading
-		r, errOpen := io.Open(*flagInput, *flagMemBufferSize)
+		r, errOpen := io.OpenWithDictionary(*flagInput, *flagMemBufferSize, readDict(*flagCompressionDict))
 		if errOpen != nil {
 			panic(errOpen)
 		}
@@ -80,6 +99,7 @@ func Run() {
 			{"Version", h.Version},
 			{"Type", h.Type.String()},
 			{"ContainsPayloads", strconv.FormatBool(h.ContainsPayloads)},
+			{"Indexed", strconv.FormatBool(r.Indexed())},
 		})
 		os.Exit(0) // bye bye
 	}
@@ -90,7 +110,7 @@ func Run() {
 	types.FieldSeparator = *flagStructSeparator
 
 	// read ncap file and print to stdout
-	if filepath.Ext(*flagInput) == defaults.FileExtension || filepath.Ext(*flagInput) == ".gz" {
+	if filepath.Ext(*flagInput) == defaults.FileExtension || io.HasCompressionExtension(*flagInput) {
 		err = io.Dump(
 			os.Stdout,
 			io.DumpConfig{
@@ -105,6 +125,9 @@ func Run() {
 				JSON:         *flagJSON,
 				CSV:          *flagCSV,
 				ForceColors:  *flagForceColors,
+				From:         parseTime(*flagFrom),
+				To:           parseTime(*flagTo),
+				Dict:         readDict(*flagCompressionDict),
 			},
 		)
 		if err != nil {
@@ -2,6 +2,9 @@ package dump
 
 import (
 	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
 
 	"github.com/dreadl0ck/netcap/io"
 )
@@ -13,6 +16,9 @@ func printHeader() {
 	fmt.Println("	$ net dump -read TCP.ncap.gz")
 	fmt.Println("	$ net dump -fields -read TCP.ncap.gz")
 	fmt.Println("	$ net dump -read TCP.ncap.gz -select Timestamp,SrcPort,DstPort > tcp.csv")
+	fmt.Println("	$ net dump -read Connection.ncap.gz -from 2020-01-01T10:00:00Z -to 2020-01-01T10:05:00Z")
+	fmt.Println("	$ net dump -read TCP.ncap.zst")
+	fmt.Println("	$ net dump -read netcap.sqlite -sql 'SELECT SrcIP, COUNT(*) FROM TCP GROUP BY SrcIP' -table")
 	fmt.Println()
 }
 
@@ -21,3 +27,31 @@ func printUsage() {
 	printHeader()
 	fs.PrintDefaults()
 }
+
+// parseTime parses a time argument, an empty argument returns the zero time.
+func parseTime(arg string) time.Time {
+	if arg == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, arg)
+	if err != nil {
+		log.Fatal("invalid time ", arg, ": ", err)
+	}
+
+	return t
+}
+
+// readDict reads the compression dictionary at path, an empty path returns no dictionary.
+func readDict(path string) []byte {
+	if path == "" {
+		return nil
+	}
+
+	dict, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal("failed to read compression dictionary: ", err)
+	}
+
+	return dict
+}
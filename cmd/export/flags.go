@@ -39,7 +39,7 @@ var (
 	flagDumpJSON             = fs.Bool("dumpJson", false, "dump as JSON")
 	flagReplay               = fs.Bool("replay", false, "replay traffic (only works when exporting audit records directly!)")
 	flagDir                  = fs.String("dir", "", "path to directory with netcap audit records")
-	flagInput                = fs.String("read", "", "read specified file, can either be a pcap or netcap audit record file")
+	flagInput                = fs.String("read", "", "read specified pcap or pcapng file, optionally compressed with gzip, zstd, lz4 or xz, a directory or glob of capture files, or - for stdin")
 	flagInterface            = fs.String("iface", "", "attach to network interface and capture in live mode")
 	flagWorkers              = fs.Int("workers", runtime.NumCPU(), "number of workers")
 	flagPacketBuffer         = fs.Int("pbuf", defaults.PacketBuffer, "set packet buffer size, for channels that feed data to workers")
@@ -72,6 +72,10 @@ var (
 	flagDPI                  = fs.Bool("dpi", false, "use DPI for device profiling")
 	flagFlushevery           = fs.Int("flushevery", defaults.FlushEvery, "flush assembler every N packets")
 	flagDefragIPv4           = fs.Bool("ip4defrag", defaults.DefragIPv4, "Defragment IPv4 packets")
+	flagDefragIPv6           = fs.Bool("ip6defrag", defaults.DefragIPv6, "Defragment IPv6 packets")
+	flagIPFIX                = fs.String("ipfix", "", "export Connection audit records as IPFIX to the collector at the given UDP address")
+	flagIPFIXActiveTimeout   = fs.Duration("ipfix-active-timeout", defaults.IPFIXActiveTimeout, "maximum time a connection is buffered before it is sent to the IPFIX collector")
+	flagDecapsulateGTP       = fs.Bool("gtp-decap", defaults.DecapsulateGTP, "Decode packets tunneled in GTP-U separately")
 	flagChecksum             = fs.Bool("checksum", defaults.Checksum, "check TCP checksum")
 	flagNooptcheck           = fs.Bool("nooptcheck", defaults.NoOptCheck, "do not check TCP options (useful to ignore MSS on captures with TSO)")
 	flagIgnorefsmerr         = fs.Bool("ignorefsmerr", defaults.IgnoreFSMErr, "ignore TCP FSM errors")
@@ -75,7 +75,7 @@ func Run() {
 	}
 
 	switch {
-	case filepath.Ext(*flagInput) == defaults.FileExtension || filepath.Ext(*flagInput) == ".gz":
+	case filepath.Ext(*flagInput) == defaults.FileExtension || io.HasCompressionExtension(*flagInput):
 		metrics.ServeMetricsAt(*flagMetricsAddress, nil)
 		exportFile(*flagInput)
 	case *flagDir != "":
@@ -125,6 +125,10 @@ func Run() {
 				MemBufferSize:        *flagMemBufferSize,
 				FlushEvery:           *flagFlushevery,
 				DefragIPv4:           *flagDefragIPv4,
+				DefragIPv6:           *flagDefragIPv6,
+				IPFIXExport:          *flagIPFIX,
+				IPFIXActiveTimeout:   *flagIPFIXActiveTimeout,
+				DecapsulateGTP:       *flagDecapsulateGTP,
 				Checksum:             *flagChecksum,
 				NoOptCheck:           *flagNooptcheck,
 				IgnoreFSMerr:         *flagIgnorefsmerr,
@@ -196,21 +200,8 @@ func Run() {
 		}
 
 		// if not, use native pcapgo version
-		isPcap, errCheck := collector.IsPcap(*flagInput)
-		if errCheck != nil {
-			// invalid path
-			fmt.Println("failed to open file:", errCheck)
-			os.Exit(1)
-		}
-
-		if isPcap {
-			if err = c.CollectPcap(*flagInput); err != nil {
-				log.Fatal("failed to collect audit records from pcap file: ", err)
-			}
-		} else {
-			if err = c.CollectPcapNG(*flagInput); err != nil {
-				log.Fatal("failed to collect audit records from pcapng file: ", err)
-			}
+		if err = c.Collect(*flagInput); err != nil {
+			log.Fatal("failed to collect audit records: ", err)
 		}
 
 		// memory profiling
@@ -0,0 +1,234 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package flow
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/defaults"
+	netio "github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/netflow"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// maximum size of an export packet
+const maxPacketSize = 65535
+
+// Run parses the subcommand flags and handles the arguments.
+func Run() {
+	// parse commandline flags
+	fs.Usage = printUsage
+
+	err := fs.Parse(os.Args[2:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *flagGenerateConfig {
+		netio.GenerateConfig(fs, "flow")
+
+		return
+	}
+
+	netio.PrintBuildInfo()
+
+	if *flagOutDir != "" {
+		if err = os.MkdirAll(*flagOutDir, defaults.DirectoryPermission); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	c := newFlowCollector()
+
+	// run cleanup on signals
+	handleSignals(c)
+
+	log.Fatal(c.serve(strings.Split(*flagAddr, ",")))
+}
+
+// flowCollector converts the received flow records to audit records.
+type flowCollector struct {
+	sync.Mutex
+
+	decoder  *netflow.Decoder
+	profiles *netflow.Profiles
+
+	connWriter netio.AuditRecordWriter
+	numConns   int64
+	done       bool
+}
+
+func newFlowCollector() *flowCollector {
+	c := &flowCollector{
+		decoder:    netflow.NewDecoder(),
+		profiles:   netflow.NewProfiles(),
+		connWriter: netio.NewAuditRecordWriter(writerConfig("Connection", types.Type_NC_Connection)),
+	}
+
+	c.decoder.ScaleSampled = *flagScaleSampled
+
+	if err := c.connWriter.WriteHeader(types.Type_NC_Connection); err != nil {
+		log.Fatal("failed to write file header: ", err)
+	}
+
+	return c
+}
+
+func writerConfig(name string, t types.Type) *netio.WriterConfig {
+	return &netio.WriterConfig{
+		CSV:                  *flagCSV,
+		Proto:                *flagProto,
+		JSON:                 *flagJSON,
+		Name:                 name,
+		Type:                 t,
+		Buffer:               *flagBuffer,
+		Compress:             *flagCompress,
+		Out:                  *flagOutDir,
+		MemBufferSize:        *flagMemBufferSize,
+		Source:               "netflow " + *flagAddr,
+		Version:              netcap.Version,
+		StartTime:            time.Now(),
+		CompressionBlockSize: defaults.CompressionBlockSize,
+		CompressionLevel:     defaults.CompressionLevel,
+	}
+}
+
+// serve listens on all addresses and returns the first error encountered.
+func (c *flowCollector) serve(addrs []string) error {
+	errs := make(chan error, len(addrs))
+
+	for _, addr := range addrs {
+		pc, err := net.ListenPacket("udp", strings.TrimSpace(addr))
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("listening for flow exports on", pc.LocalAddr())
+
+		go func() {
+			errs <- c.listen(pc)
+		}()
+	}
+
+	err := <-errs
+
+	c.cleanup()
+
+	return err
+}
+
+func (c *flowCollector) listen(pc net.PacketConn) error {
+	defer func() {
+		if errClose := pc.Close(); errClose != nil {
+			fmt.Println("failed to close:", errClose)
+		}
+	}()
+
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		exporter := addr.String()
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			exporter = udpAddr.IP.String()
+		}
+
+		c.handlePacket(buf[:n], exporter)
+	}
+}
+
+// handlePacket decodes an export packet and writes the contained flows.
+func (c *flowCollector) handlePacket(data []byte, exporter string) {
+	flows, err := c.decoder.Decode(data, exporter)
+	if err != nil {
+		fmt.Println("failed to decode export packet from", exporter+":", err)
+	}
+
+	if *flagVerbose {
+		fmt.Printf("packet-received: bytes=%d from=%s flows=%d\n", len(data), exporter, len(flows))
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.done {
+		return
+	}
+
+	for _, fl := range flows {
+		if *flagProfiles {
+			c.profiles.Update(fl)
+		}
+
+		if err = c.connWriter.Write(fl.Connection()); err != nil {
+			fmt.Println("failed to write connection:", err)
+
+			continue
+		}
+
+		c.numConns++
+	}
+}
+
+// cleanup closes the connection writer and writes the IPProfiles.
+func (c *flowCollector) cleanup() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.done {
+		return
+	}
+	c.done = true
+
+	packets, flows, missing := c.decoder.Stats()
+	fmt.Println("received", packets, "export packets with", flows, "flows,", missing, "data sets without template")
+
+	name, size := c.connWriter.Close(c.numConns)
+	fmt.Println("wrote", c.numConns, "connections to", name, "("+fmt.Sprint(size), "bytes)")
+
+	if !*flagProfiles {
+		return
+	}
+
+	var (
+		profiles = c.profiles.Items()
+		w        = netio.NewAuditRecordWriter(writerConfig("IPProfile", types.Type_NC_IPProfile))
+	)
+
+	if err := w.WriteHeader(types.Type_NC_IPProfile); err != nil {
+		fmt.Println("failed to write file header:", err)
+
+		return
+	}
+
+	for _, p := range profiles {
+		if err := w.Write(p); err != nil {
+			fmt.Println("failed to write ip profile:", err)
+		}
+	}
+
+	name, size = w.Close(int64(len(profiles)))
+	fmt.Println("wrote", len(profiles), "ip profiles to", name, "("+fmt.Sprint(size), "bytes)")
+}
@@ -0,0 +1,56 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package flow
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dreadl0ck/netcap/io"
+)
+
+func printHeader() {
+	io.PrintLogo()
+	fmt.Println()
+	fmt.Println("flow tool usage examples:")
+	fmt.Println("	$ net flow")
+	fmt.Println("	$ net flow -addr 0.0.0.0:2055 -out flows")
+	fmt.Println("	$ net flow -addr :4739 -csv -scale=false")
+	fmt.Println()
+}
+
+// usage prints the use.
+func printUsage() {
+	printHeader()
+	fs.PrintDefaults()
+}
+
+func handleSignals(c *flowCollector) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	// start signal handler and cleanup routine
+	go func() {
+		sig := <-sigs
+
+		fmt.Println("\nreceived signal:", sig)
+
+		fmt.Println("exiting")
+
+		c.cleanup()
+		os.Exit(0)
+	}()
+}
@@ -0,0 +1,49 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package importer
+
+import (
+	"os"
+	"strings"
+
+	"github.com/namsral/flag"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/zeek"
+)
+
+// Flags returns all flags.
+func Flags() (flags []string) {
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f.Name)
+	})
+
+	return
+}
+
+var (
+	fs                 = flag.NewFlagSetWithEnvPrefix(os.Args[0], "NC", flag.ExitOnError)
+	flagGenerateConfig = fs.Bool("gen-config", false, "generate config")
+	_                  = fs.String("config", "", "read configuration from file at path")
+	flagInput          = fs.String("read", "", "comma separated list of Zeek log files or directories to import")
+	flagLogs           = fs.String("logs", strings.Join(zeek.Logs(), ","), "comma separated list of Zeek logs to import")
+	flagOutDir         = fs.String("out", "", "specify output directory, will be created if it does not exist")
+	flagCSV            = fs.Bool("csv", false, "output data as CSV")
+	flagProto          = fs.Bool("proto", true, "output data as protobuf")
+	flagJSON           = fs.Bool("json", false, "output data as JSON")
+	flagCompress       = fs.Bool("compress", true, "compress output with gzip")
+	flagBuffer         = fs.Bool("buf", true, "buffer data in memory before writing to disk")
+	flagMemBufferSize  = fs.Int("membuf-size", defaults.BufferSize, "set size for membuf")
+	flagQuiet          = fs.Bool("quiet", false, "don't print a line for each imported log file")
+)
@@ -0,0 +1,270 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package importer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/defaults"
+	netio "github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/zeek"
+)
+
+// Run parses the subcommand flags and handles the arguments.
+func Run() {
+	// parse commandline flags
+	fs.Usage = printUsage
+
+	err := fs.Parse(os.Args[2:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *flagGenerateConfig {
+		netio.GenerateConfig(fs, "import")
+
+		return
+	}
+
+	if *flagInput == "" {
+		log.Fatal("no input files provided, use the -read flag")
+	}
+
+	netio.PrintBuildInfo()
+
+	if *flagOutDir != "" {
+		if err = os.MkdirAll(*flagOutDir, defaults.DirectoryPermission); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	files, err := collectFiles(strings.Split(*flagInput, ","))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	im := &importer{
+		logs:    make(map[string]bool),
+		writers: make(map[types.Type]*recordWriter),
+	}
+
+	for _, l := range strings.Split(*flagLogs, ",") {
+		if _, ok := zeek.Type(l); !ok {
+			log.Fatal("unsupported log: ", l)
+		}
+
+		im.logs[l] = true
+	}
+
+	start := time.Now()
+
+	for _, f := range files {
+		if err = im.importFile(f); err != nil {
+			log.Fatal("failed to import ", f, ": ", err)
+		}
+	}
+
+	im.close()
+
+	fmt.Println("imported", len(files), "files in", time.Since(start))
+}
+
+// recordWriter writes the audit records of a single type.
+type recordWriter struct {
+	netio.AuditRecordWriter
+	numRecords int64
+}
+
+type importer struct {
+	logs    map[string]bool
+	writers map[types.Type]*recordWriter
+}
+
+// collectFiles returns the log files for the input paths, directories are searched recursively.
+func collectFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, p)
+
+			continue
+		}
+
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && strings.Contains(filepath.Base(path), ".log") {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// importFile converts the records of a log file, which can be gzip compressed.
+func (im *importer) importFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if errClose := f.Close(); errClose != nil {
+			fmt.Println("failed to close:", errClose)
+		}
+	}()
+
+	var in io.Reader = f
+
+	if strings.HasSuffix(name, ".gz") {
+		gr, errGzip := gzip.NewReader(f)
+		if errGzip != nil {
+			return errGzip
+		}
+
+		defer func() {
+			if errClose := gr.Close(); errClose != nil {
+				fmt.Println("failed to close gzip reader:", errClose)
+			}
+		}()
+
+		in = gr
+	}
+
+	var (
+		r          = zeek.NewReader(in)
+		numRecords int
+		skipped    = make(map[string]int)
+	)
+
+	for {
+		rec, errNext := r.Next()
+		if errNext == io.EOF {
+			break
+		}
+
+		if errNext != nil {
+			return errNext
+		}
+
+		// JSON logs do not contain the path unless it has been added to each record
+		path := r.Path()
+		if path == "" {
+			path = zeek.LogPath(name)
+		}
+
+		if !im.logs[path] {
+			skipped[path]++
+
+			continue
+		}
+
+		msgs, errConvert := zeek.Convert(path, rec)
+		if errConvert != nil {
+			return errConvert
+		}
+
+		w := im.writer(path)
+
+		for _, m := range msgs {
+			if err = w.Write(m); err != nil {
+				return err
+			}
+
+			w.numRecords++
+		}
+
+		numRecords++
+	}
+
+	if !*flagQuiet {
+		fmt.Println("imported", numRecords, "records from", name)
+
+		for path, n := range skipped {
+			fmt.Println("  skipped", n, "records of the", path, "log")
+		}
+	}
+
+	return nil
+}
+
+// writer returns the audit record writer for the type of the log, it is created on first use.
+func (im *importer) writer(path string) *recordWriter {
+	t, _ := zeek.Type(path)
+
+	if w, ok := im.writers[t]; ok {
+		return w
+	}
+
+	w := &recordWriter{
+		AuditRecordWriter: netio.NewAuditRecordWriter(&netio.WriterConfig{
+			CSV:                  *flagCSV,
+			Proto:                *flagProto,
+			JSON:                 *flagJSON,
+			Name:                 strings.TrimPrefix(t.String(), "NC_"),
+			Type:                 t,
+			Buffer:               *flagBuffer,
+			Compress:             *flagCompress,
+			Out:                  *flagOutDir,
+			MemBufferSize:        *flagMemBufferSize,
+			Source:               "zeek " + *flagInput,
+			Version:              netcap.Version,
+			StartTime:            time.Now(),
+			CompressionBlockSize: defaults.CompressionBlockSize,
+			CompressionLevel:     defaults.CompressionLevel,
+		}),
+	}
+
+	if err := w.WriteHeader(t); err != nil {
+		log.Fatal("failed to write file header: ", err)
+	}
+
+	im.writers[t] = w
+
+	return w
+}
+
+// close flushes and closes all writers.
+func (im *importer) close() {
+	for _, w := range im.writers {
+		name, size := w.Close(w.numRecords)
+		fmt.Println("wrote", w.numRecords, "audit records to", name, "("+fmt.Sprint(size), "bytes)")
+	}
+}
@@ -0,0 +1,36 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package importer
+
+import (
+	"fmt"
+
+	"github.com/dreadl0ck/netcap/io"
+)
+
+func printHeader() {
+	io.PrintLogo()
+	fmt.Println()
+	fmt.Println("import tool usage examples:")
+	fmt.Println("	$ net import -read conn.log")
+	fmt.Println("	$ net import -read /var/log/zeek -out imported")
+	fmt.Println("	$ net import -read logs/2020-01-01 -logs conn,dns,http -csv")
+	fmt.Println()
+}
+
+// usage prints the use.
+func printUsage() {
+	printHeader()
+	fs.PrintDefaults()
+}
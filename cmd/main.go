@@ -29,6 +29,8 @@ import (
 	"github.com/dreadl0ck/netcap/cmd/collect"
 	"github.com/dreadl0ck/netcap/cmd/dump"
 	"github.com/dreadl0ck/netcap/cmd/export"
+	"github.com/dreadl0ck/netcap/cmd/flow"
+	"github.com/dreadl0ck/netcap/cmd/importer"
 	"github.com/dreadl0ck/netcap/cmd/label"
 	"github.com/dreadl0ck/netcap/cmd/proxy"
 	"github.com/dreadl0ck/netcap/cmd/transform"
@@ -46,6 +48,8 @@ const (
 	cmdExport    = "export"
 	cmdDump      = "dump"
 	cmdCollect   = "collect"
+	cmdFlow      = "flow"
+	cmdImport    = "import"
 	cmdTransform = "transform"
 	cmdAgent     = "agent"
 	cmdVersion   = "version"
@@ -79,6 +83,8 @@ available subcommands:
   > export        exports audit records
   > dump          utility to read audit record files
   > collect       collector for audit records from agents
+  > flow          collector for NetFlow v5/v9 and IPFIX exports
+  > import        import Zeek logs as audit records
   > transform     maltego plugin
   > help          display this help
 
@@ -121,6 +127,10 @@ func main() {
 		dump.Run()
 	case cmdCollect:
 		collect.Run()
+	case cmdFlow:
+		flow.Run()
+	case cmdImport:
+		importer.Run()
 	case cmdTransform:
 		transform.Run()
 	case cmdAgent:
@@ -141,6 +151,8 @@ var completions = []string{
 	cmdExport,
 	cmdDump,
 	cmdCollect,
+	cmdFlow,
+	cmdImport,
 	cmdTransform,
 	cmdHelp,
 	cmdAgent,
@@ -182,6 +194,10 @@ func printCompletions(previous, current, full string) {
 		printFlags(dump.Flags())
 	case cmdCollect:
 		printFlags(collect.Flags())
+	case cmdFlow:
+		printFlags(flow.Flags())
+	case cmdImport:
+		printFlags(importer.Flags())
 	case cmdAgent:
 		printFlags(agent.Flags())
 	case cmdHelp:
@@ -241,6 +257,16 @@ func printCompletions(previous, current, full string) {
 		case cmdCollect:
 			handleConfigFlag()
 			printFlagsFiltered(collect.Flags())
+		case cmdFlow:
+			handleConfigFlag()
+			printFlagsFiltered(flow.Flags())
+		case cmdImport:
+			if previous == nameReadFlag {
+				printFileForExt(".log", extGzip)
+			}
+
+			handleConfigFlag()
+			printFlagsFiltered(importer.Flags())
 		case cmdAgent:
 			handleConfigFlag()
 			printFlagsFiltered(agent.Flags())
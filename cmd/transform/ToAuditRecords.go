@@ -55,6 +55,8 @@ var maltegoBaseConfig = collector.Config{
 		AddContext:                     true,
 		FlushEvery:                     100,
 		DefragIPv4:                     defaults.DefragIPv4,
+		DefragIPv6:                     defaults.DefragIPv6,
+		DecapsulateGTP:                 defaults.DecapsulateGTP,
 		Checksum:                       defaults.Checksum,
 		NoOptCheck:                     defaults.NoOptCheck,
 		IgnoreFSMerr:                   defaults.IgnoreFSMErr,
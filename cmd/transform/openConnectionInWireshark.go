@@ -24,6 +24,8 @@ import (
 
 	"github.com/dreadl0ck/maltego"
 	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/packetstore"
+	"github.com/dreadl0ck/netcap/utils"
 )
 
 var (
@@ -123,14 +125,7 @@ func openConnectionInWireshark() {
 	)
 
 	if !exists {
-		log.Println(tcpdump, args)
-
-		out, err := exec.Command(findExecutable(tcpdump, false), args...).CombinedOutput()
-		if err != nil {
-			maltego.Die(err.Error(), "open file failed:\n"+string(out))
-		}
-
-		log.Println(string(out))
+		extractConnection(in, lt, outFile, args)
 	}
 
 	log.Println(wireshark, outFile)
@@ -146,6 +141,44 @@ func openConnectionInWireshark() {
 	fmt.Println(trx.ReturnOutput())
 }
 
+// extractConnection writes the packets of the connection to outFile.
+// If the capture was processed with a packet store, the packets are read from its index,
+// otherwise the input pcap is filtered with tcpdump and the args.
+func extractConnection(in string, lt maltego.LocalTransform, outFile string, args []string) {
+	store, err := packetstore.Open(filepath.Join(in+".net", defaults.PacketStore))
+	if err != nil {
+		log.Println("packet store not available, using", tcpdump, err)
+		log.Println(tcpdump, args)
+
+		out, errExec := exec.Command(findExecutable(tcpdump, false), args...).CombinedOutput()
+		if errExec != nil {
+			maltego.Die(errExec.Error(), "open file failed:\n"+string(out))
+		}
+
+		log.Println(string(out))
+
+		return
+	}
+
+	f, err := os.Create(outFile)
+	if err != nil {
+		maltego.Die(err.Error(), "failed to create file")
+	}
+
+	flow := utils.CreateFlowIdent(lt.Values["srcip"], lt.Values["srcport"], lt.Values["dstip"], lt.Values["dstport"], "")
+
+	n, err := store.Extract(packetstore.Query{Flow: flow}, f)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		maltego.Die(err.Error(), "failed to extract packets from packet store")
+	}
+
+	log.Println("extracted", n, "packets of", flow, "from packet store")
+}
+
 // creates a bpf to filter for traffic of a single connection
 // defined by two hosts and two ports
 // eg: "(host 192.168.1.14 and port 56988) and (host 224.0.0.252 and port 5355)"
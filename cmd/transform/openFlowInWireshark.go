@@ -35,14 +35,7 @@ func openFlowInWireshark() {
 	)
 
 	if !exists {
-		log.Println(tcpdump, args)
-
-		out, err := exec.Command(findExecutable(tcpdump, false), args...).CombinedOutput()
-		if err != nil {
-			maltego.Die(err.Error(), "open file failed:\n"+string(out))
-		}
-
-		log.Println(string(out))
+		extractConnection(in, lt, outFile, args)
 	}
 
 	log.Println(wireshark, outFile)
@@ -80,7 +80,7 @@ func toConnectionsForService() {
 }
 
 func addConn(trx *maltego.Transform, conn *types.Connection, path string, min, max uint64, direction maltego.LinkDirection, service string) {
-	ent := addEntityWithPath(trx, "netcap.Connection", utils.CreateFlowIdent(conn.SrcIP, conn.SrcPort, conn.DstIP, conn.DstPort), path)
+	ent := addEntityWithPath(trx, "netcap.Connection", utils.CreateFlowIdent(conn.SrcIP, conn.SrcPort, conn.DstIP, conn.DstPort, conn.Encapsulation), path)
 
 	ent.SetLinkDirection(direction)
 	ent.SetLinkLabel(strconv.FormatInt(int64(conn.NumPackets), 10) + " pkts\n" + humanize.Bytes(uint64(conn.TotalSize)))
@@ -129,6 +129,7 @@ retry:
 				conn.SrcPort,
 				conn.DstIP,
 				conn.DstPort,
+				conn.Encapsulation,
 			),
 		)+".bin",
 	)
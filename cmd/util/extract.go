@@ -0,0 +1,145 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/packetstore"
+)
+
+// defaultExtractOut is the output path for extracted packets if no path is set with the out flag.
+const defaultExtractOut = "extracted.pcap"
+
+// extract writes the packets selected by the flags from the packet store into a pcap file.
+func extract() {
+	start := time.Now()
+
+	// a capture output directory contains the store in a subdirectory
+	dir := *flagExtract
+	if _, err := os.Stat(filepath.Join(dir, defaults.PacketStore)); err == nil {
+		dir = filepath.Join(dir, defaults.PacketStore)
+	}
+
+	store, err := packetstore.Open(dir)
+	if err != nil {
+		log.Fatal("failed to open packet store: ", err)
+	}
+
+	var q packetstore.Query
+
+	if *flagRecord > 0 {
+		if *flagInput == "" {
+			log.Fatal("need an audit record file with the read flag (-read) to extract the packets of a record")
+		}
+
+		q, err = packetstore.QueryForRecord(readRecord(*flagInput, *flagRecord))
+		if err != nil {
+			log.Fatal("failed to extract packets for record ", *flagRecord, ": ", err)
+		}
+	} else {
+		if *flagPort < 0 || *flagPort > 65535 {
+			log.Fatal("invalid port: ", *flagPort)
+		}
+
+		q = packetstore.Query{
+			Flow:        *flagFlow,
+			CommunityID: *flagCommunityID,
+			Host:        *flagHost,
+			Peer:        *flagPeer,
+			Port:        uint16(*flagPort),
+			From:        parseTime(*flagFrom),
+			To:          parseTime(*flagTo),
+		}
+	}
+
+	out := *flagOut
+	if out == "" {
+		out = defaultExtractOut
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriterSize(f, defaults.BufferSize)
+
+	numPackets, err := store.Extract(q, w)
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		log.Fatal("failed to extract packets: ", err)
+	}
+
+	fmt.Println("extracted", numPackets, "packets to", out, "("+fileSize(out)+") in", time.Since(start))
+}
+
+// readRecord returns the audit record with the number from the file, the first record has the number 1.
+func readRecord(path string, num int) proto.Message {
+	r, err := io.Open(path, *flagMemBufferSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer func() {
+		if errClose := r.Close(); errClose != nil {
+			log.Println("failed to close file: ", errClose)
+		}
+	}()
+
+	h, err := r.ReadHeader()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	record := io.InitRecord(h.Type)
+
+	for i := 0; i < num; i++ {
+		if err = r.Next(record); err != nil {
+			log.Fatal("failed to read record ", num, " from ", path, ": ", err)
+		}
+	}
+
+	return record
+}
+
+// parseTime parses the RFC3339 timestamp, an empty string yields the zero time.
+func parseTime(ts string) time.Time {
+	if ts == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		log.Fatal("invalid timestamp: ", err)
+	}
+
+	return t
+}
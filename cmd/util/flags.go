@@ -52,4 +52,20 @@ var (
 	flagForce           = fs.Bool("force", false, "disable prompts for user interaction")
 	flagVerbose         = fs.Bool("verbose", false, "enable verbose output")
 	flagDownloadGeolite = fs.Bool("download-geolite", false, "download geolite DB, requires API key in environment: "+env.GeoLiteAPIKey)
+	flagConvert         = fs.String("convert", "", "convert the file from -read to another compression codec: gzip, zstd, lz4 or none, or convert an audit record file to parquet")
+	flagOut             = fs.String("out", "", "output path for -convert, defaults to the input path with the extension of the codec, and for the pcap of -extract, defaults to extracted.pcap")
+	flagLevel           = fs.Int("compression-level", defaults.CompressionLevel, "compression level from 1 (fastest) to 9 (best) for -convert")
+	flagDict            = fs.String("compression-dict", "", "path to a dictionary used to compress the output of -convert with zstd")
+	flagInputDict       = fs.String("input-dict", "", "path to the dictionary the input of -convert was compressed with")
+	flagParquetCodec    = fs.String("parquet-compression", defaults.ParquetCompression, "codec for Parquet pages when converting to parquet: none, snappy, gzip or zstd")
+	flagRowGroupSize    = fs.Int("parquet-row-group-size", defaults.ParquetRowGroupSize, "amount of uncompressed data per row group when converting to parquet, in bytes")
+	flagExtract         = fs.String("extract", "", "extract packets from the packet store at path, or the packet store in the capture output directory at path, into a pcap file")
+	flagFlow            = fs.String("flow", "", "extract the packets of the flow with the identifier, in both directions: srcIP:srcPort->dstIP:dstPort")
+	flagCommunityID     = fs.String("community-id", "", "extract the packets of the flow with the community ID")
+	flagHost            = fs.String("host", "", "extract the packets from and to the host address")
+	flagPeer            = fs.String("peer", "", "extract the packets between -host and the peer address")
+	flagPort            = fs.Int("port", 0, "extract the packets from and to the port")
+	flagFrom            = fs.String("from", "", "extract the packets captured at or after the RFC3339 timestamp")
+	flagTo              = fs.String("to", "", "extract the packets captured before the RFC3339 timestamp")
+	flagRecord          = fs.Int("record", 0, "extract the packets of the audit record with the number from the file of -read, starting at 1")
 )
@@ -77,6 +77,18 @@ func Run() {
 		return
 	}
 
+	// util to convert files between compression codecs
+	if *flagConvert != "" {
+		convert()
+		return
+	}
+
+	// util to extract the packets of flows and audit records from a packet store
+	if *flagExtract != "" {
+		extract()
+		return
+	}
+
 	// util to check if fields count matches for all generated rows
 	if *flagCheckFields {
 		checkFields()
@@ -15,14 +15,21 @@ package util
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
 	"os/exec"
 	"reflect"
 	"strings"
 
+	"github.com/dustin/go-humanize"
+
 	"github.com/mgutz/ansi"
 
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/parquet"
 	"github.com/dreadl0ck/netcap/types"
 )
 
@@ -32,6 +39,11 @@ func printHeader() {
 	fmt.Println("util tool usage examples:")
 	fmt.Println("	$ net util -read TCP.ncap.gz -check")
 	fmt.Println("	$ net util -read TCP.ncap.gz -check -sep '/'")
+	fmt.Println("	$ net util -read TCP.ncap.gz -convert zstd")
+	fmt.Println("	$ net util -read TCP.ncap.zst -convert lz4 -compression-level 9 -out TCP.ncap.lz4")
+	fmt.Println("	$ net util -read TCP.ncap.gz -convert parquet -parquet-compression zstd")
+	fmt.Println("	$ net util -extract pcaps/traffic.net -flow 192.168.1.47:53032->165.227.109.154:80 -out flow.pcap")
+	fmt.Println("	$ net util -extract pcaps/traffic.net -read pcaps/traffic.net/Credentials.ncap.gz -record 3")
 	fmt.Println("	$ net util -ts2utc 1505839354.197231")
 	fmt.Println("	$ net util -download-geolite")
 	fmt.Println("	$ net util -update-dbs")
@@ -145,3 +157,103 @@ func checkFields() {
 		}
 	}
 }
+
+// convert writes the file from the read flag compressed with another codec.
+// The codec of the input is detected, so any audit record, CSV or JSON file can be converted.
+func convert() {
+	if *flagInput == "" {
+		log.Fatal("need a file to convert with the read flag (-read)")
+	}
+
+	if *flagConvert == formatParquet {
+		convertToParquet()
+
+		return
+	}
+
+	if *flagConvert != io.CodecNone && !io.IsCodec(*flagConvert) {
+		log.Fatal("invalid compression codec: ", *flagConvert, ", expected one of ", io.Codecs, " or none")
+	}
+
+	out := *flagOut
+	if out == "" {
+		// replace the compression extension of the input
+		out = *flagInput
+		for _, c := range io.Codecs {
+			out = strings.TrimSuffix(out, io.CompressionExtension(c))
+		}
+
+		out += io.CompressionExtension(*flagConvert)
+
+		if out == *flagInput {
+			log.Fatal("the file is already compressed with ", *flagConvert, ", set a different output path with -out")
+		}
+	}
+
+	err := io.Convert(*flagInput, out, io.ConvertConfig{
+		Codec:     *flagConvert,
+		Level:     *flagLevel,
+		Dict:      readFile(*flagDict),
+		InputDict: readFile(*flagInputDict),
+	})
+	if err != nil {
+		log.Fatal("failed to convert file: ", err)
+	}
+
+	fmt.Println("converted", *flagInput, "("+fileSize(*flagInput)+") to", out, "("+fileSize(out)+")")
+}
+
+// formatParquet is the value of the convert flag for converting audit records to Parquet.
+const formatParquet = "parquet"
+
+// convertToParquet writes the audit records from the file of the read flag to a Parquet file.
+func convertToParquet() {
+	codec, err := parquet.ParseCodec(*flagParquetCodec)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := *flagOut
+	if out == "" {
+		out = *flagInput
+		for _, c := range io.Codecs {
+			out = strings.TrimSuffix(out, io.CompressionExtension(c))
+		}
+
+		out = strings.TrimSuffix(out, defaults.FileExtension) + ".parquet"
+	}
+
+	numRecords, err := io.ConvertToParquet(*flagInput, out, parquet.Options{
+		Compression:  codec,
+		RowGroupSize: *flagRowGroupSize,
+		CreatedBy:    "netcap version " + netcap.Version,
+	}, readFile(*flagInputDict))
+	if err != nil {
+		log.Fatal("failed to convert file: ", err)
+	}
+
+	fmt.Println("converted", numRecords, "audit records from", *flagInput, "("+fileSize(*flagInput)+") to", out, "("+fileSize(out)+")")
+}
+
+// readFile returns the contents of the file at path, or nil for an empty path.
+func readFile(path string) []byte {
+	if path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return data
+}
+
+func fileSize(path string) string {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return "unknown size"
+	}
+
+	return humanize.Bytes(uint64(stat.Size()))
+}
@@ -0,0 +1,343 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/afpacket"
+	"github.com/dustin/go-humanize"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	"github.com/dreadl0ck/netcap/defaults"
+)
+
+// maxFanoutAttempts is the number of group ids tried when the fanout group id is derived from the process id.
+const maxFanoutAttempts = 16
+
+// afpacketPollTimeout is the time after which a read from an AF_PACKET socket returns without a packet,
+// to check whether the capture has been canceled.
+const afpacketPollTimeout = 100 * time.Millisecond
+
+// fanoutConfig configures the sockets of a fanout group.
+type fanoutConfig struct {
+	iface     string
+	sockets   int
+	blockSize int
+	numBlocks int
+	promisc   bool
+
+	// fanout group id, 0 derives the id from the process id
+	id uint16
+}
+
+// fanoutGroup is a group of AF_PACKET sockets with TPACKET_V3 ring buffers on an interface.
+// The kernel distributes the packets between the sockets by a symmetric hash over the flow,
+// so both directions of a flow are always read from the same socket.
+type fanoutGroup struct {
+	sockets []*afpacket.TPacket
+
+	// fanout group id
+	id uint16
+
+	// size of the ring buffers of all sockets in bytes
+	ringSize int
+
+	// socket that holds the promiscuous mode membership, -1 if promiscuous mode is not used
+	promiscFD int
+}
+
+// newFanoutGroup opens the sockets of a fanout group on the interface.
+func newFanoutGroup(conf fanoutConfig) (*fanoutGroup, error) {
+	if conf.sockets < 1 {
+		return nil, fmt.Errorf("invalid number of AF_PACKET sockets: %d", conf.sockets)
+	}
+
+	if conf.blockSize == 0 {
+		conf.blockSize = defaults.AFPacketBlockSize
+	}
+
+	if conf.numBlocks == 0 {
+		conf.numBlocks = defaults.AFPacketNumBlocks
+	}
+
+	g := &fanoutGroup{
+		ringSize:  conf.sockets * conf.blockSize * conf.numBlocks,
+		promiscFD: -1,
+	}
+
+	// the group id must be unique on the host, unrelated processes with the same id would share the packets.
+	// Without a configured id, the id is derived from the process id and the following ids are tried,
+	// if the first socket cannot join the group because it exists with different settings.
+	g.id = conf.id
+	if g.id == 0 {
+		g.id = uint16(os.Getpid())
+	}
+
+	for i := 0; i < conf.sockets; i++ {
+		s, err := afpacket.NewTPacket(
+			afpacket.OptInterface(conf.iface),
+			afpacket.TPacketVersion3,
+			afpacket.OptBlockSize(conf.blockSize),
+			afpacket.OptNumBlocks(conf.numBlocks),
+			afpacket.OptPollTimeout(afpacketPollTimeout),
+			// libpcap reinserts the VLAN tags stripped by the NIC as well
+			afpacket.OptAddVLANHeader(true),
+		)
+		if err != nil {
+			g.Close()
+
+			return nil, fmt.Errorf("failed to open AF_PACKET socket on %s: %w", conf.iface, err)
+		}
+
+		g.sockets = append(g.sockets, s)
+
+		if i == 0 && conf.id == 0 {
+			g.id, err = joinFreeFanoutGroup(s, g.id)
+		} else {
+			err = joinFanoutGroup(s, g.id)
+		}
+
+		if err != nil {
+			g.Close()
+
+			return nil, fmt.Errorf("failed to join AF_PACKET fanout group %d: %w", g.id, err)
+		}
+	}
+
+	if conf.promisc {
+		if err := g.setPromisc(conf.iface); err != nil {
+			g.Close()
+
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// joinFanoutGroup adds the socket to the fanout group with the id.
+func joinFanoutGroup(s *afpacket.TPacket, id uint16) error {
+	// the hash fanout mode is selected with the defrag flag, so fragments are delivered to the same socket
+	return s.SetFanout(afpacket.FanoutHashWithDefrag, id)
+}
+
+// joinFreeFanoutGroup adds the socket to the fanout group with the id, or the next ids,
+// as long as the kernel rejects the join because the group is used with different settings.
+// It returns the id of the joined group.
+func joinFreeFanoutGroup(s *afpacket.TPacket, id uint16) (uint16, error) {
+	for attempt := 1; ; attempt++ {
+		err := joinFanoutGroup(s, id)
+		if err == nil || attempt == maxFanoutAttempts || !(errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EEXIST)) {
+			return id, err
+		}
+
+		// 0 is reserved for deriving the id from the process id
+		if id++; id == 0 {
+			id++
+		}
+	}
+}
+
+// setPromisc enables promiscuous mode on the interface for the lifetime of the group.
+// The membership is held by a separate socket, the kernel drops it when the socket is closed.
+func (g *fanoutGroup) setPromisc(iface string) error {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open socket for promiscuous mode: %w", err)
+	}
+
+	err = unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &unix.PacketMreq{
+		Ifindex: int32(ifi.Index),
+		Type:    unix.PACKET_MR_PROMISC,
+	})
+	if err != nil {
+		_ = unix.Close(fd)
+
+		return fmt.Errorf("failed to enable promiscuous mode on %s: %w", iface, err)
+	}
+
+	g.promiscFD = fd
+
+	return nil
+}
+
+// setBPF sets the filter on all sockets of the group.
+func (g *fanoutGroup) setBPF(filter []bpf.RawInstruction) error {
+	for _, s := range g.sockets {
+		if err := s.SetBPF(filter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stats returns the number of packets received and dropped by the kernel for all sockets of the group.
+func (g *fanoutGroup) stats() (received, dropped int64) {
+	for _, s := range g.sockets {
+		_, v3, err := s.SocketStats()
+		if err != nil {
+			continue
+		}
+
+		// the kernel counts the dropped packets as received as well
+		received += int64(v3.Packets())
+		dropped += int64(v3.Drops())
+	}
+
+	return received, dropped
+}
+
+// Close closes all sockets of the group.
+func (g *fanoutGroup) Close() {
+	for _, s := range g.sockets {
+		s.Close()
+	}
+
+	if g.promiscFD != -1 {
+		_ = unix.Close(g.promiscFD)
+		g.promiscFD = -1
+	}
+}
+
+// collectAFPacket captures packets from the interface with one AF_PACKET socket per worker.
+// Each socket is read by its own goroutine, which passes the packets to the worker of the socket,
+// so that all packets of a flow are decoded by the same worker, in the order in which they were received.
+func (c *Collector) collectAFPacket(iface string, bpfFilter string, ctx context.Context) error {
+	g, err := newFanoutGroup(fanoutConfig{
+		iface:     iface,
+		sockets:   c.config.Workers,
+		blockSize: c.config.AFPacketBlockSize,
+		numBlocks: c.config.AFPacketNumBlocks,
+		promisc:   c.config.Promisc,
+		id:        c.config.AFPacketFanoutID,
+	})
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	// set BPF if requested
+	if bpfFilter != "" {
+		rb, errBPF := rawBPF(bpfFilter)
+		if errBPF != nil {
+			return errBPF
+		}
+
+		if err = g.setBPF(rb); err != nil {
+			return err
+		}
+	}
+
+	// initialize collector
+	if err = c.Init(); err != nil {
+		return err
+	}
+
+	c.printlnStdOut("capturing on", iface, "with", len(g.sockets), "AF_PACKET sockets in fanout group", g.id, "and",
+		humanize.Bytes(uint64(g.ringSize)), "of ring buffers")
+
+	c.mu.Lock()
+	c.isLive = true
+	c.captureStats = g.stats
+	c.mu.Unlock()
+
+	stopProgress := c.printProgressInterval()
+
+	var (
+		wg              sync.WaitGroup
+		errOnce         sync.Once
+		errRead         error
+		ctxRead, cancel = context.WithCancel(ctx)
+	)
+
+	for i, s := range g.sockets {
+		wg.Add(1)
+
+		go func(worker int, s *afpacket.TPacket) {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctxRead.Done():
+					return
+				default:
+				}
+
+				// the data is copied, since the packet is decoded after the next read
+				data, ci, errPacket := s.ReadPacketData()
+				if errPacket != nil {
+					if errors.Is(errPacket, afpacket.ErrTimeout) {
+						continue
+					}
+
+					errOnce.Do(func() {
+						errRead = fmt.Errorf("%s interface: %s bpf: %s: %w", errReadingPacketData, iface, bpfFilter, errPacket)
+						cancel()
+					})
+
+					return
+				}
+
+				// increment atomic packet counter
+				atomic.AddInt64(&c.current, 1)
+
+				// must be locked, otherwise a race occurs when sending a SIGINT
+				//  and triggering wg.Wait() in another goroutine...
+				c.statMutex.Lock()
+
+				// increment wait group for packet processing
+				c.wg.Add(1)
+
+				c.statMutex.Unlock()
+
+				c.handleRawPacketDataWorker(data, &ci, worker)
+			}
+		}(i, s)
+	}
+
+	<-ctxRead.Done()
+	wg.Wait()
+	cancel()
+
+	if errRead == nil {
+		fmt.Println("live capture canceled via context")
+	}
+
+	// Stop progress reporting
+	stopProgress <- struct{}{}
+
+	// run cleanup on channel exit
+	c.cleanup(false)
+
+	c.mu.Lock()
+	c.captureStats = nil
+	c.mu.Unlock()
+
+	return errRead
+}
@@ -0,0 +1,235 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/afpacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"golang.org/x/sys/unix"
+)
+
+// vethPair creates a pair of connected virtual ethernet interfaces in a new network namespace and returns their names.
+// The namespace is only entered by the thread of the calling goroutine, which stays locked to it.
+// The thread is terminated when the test goroutine exits, which removes the namespace together with the interfaces,
+// so the network configuration of the host is not modified.
+// The test is skipped if the namespace or the interfaces cannot be created, e.g. when not running as root.
+func vethPair(t *testing.T) (a, b string) {
+	t.Helper()
+
+	if os.Getuid() != 0 {
+		t.Skip("creating network namespaces requires root")
+	}
+
+	// never unlocked, to discard the thread after the test
+	runtime.LockOSThread()
+
+	if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+		t.Skip("failed to create network namespace: ", err)
+	}
+
+	a, b = "ncfanouta", "ncfanoutb"
+
+	// the command is started from the locked thread and inherits its namespace
+	if out, err := exec.Command("ip", "link", "add", a, "type", "veth", "peer", "name", b).CombinedOutput(); err != nil {
+		t.Skip("failed to create veth pair: ", err, string(out))
+	}
+
+	for _, iface := range []string{a, b} {
+		if out, err := exec.Command("ip", "link", "set", iface, "up").CombinedOutput(); err != nil {
+			t.Fatal("failed to bring up ", iface, ": ", err, string(out))
+		}
+	}
+
+	return a, b
+}
+
+func TestFanoutGroup(t *testing.T) {
+	a, b := vethPair(t)
+
+	g, err := newFanoutGroup(fanoutConfig{
+		iface:     b,
+		sockets:   4,
+		blockSize: 1 << 16,
+		numBlocks: 4,
+		promisc:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	w, err := afpacket.NewTPacket(afpacket.OptInterface(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	const (
+		numFlows   = 32
+		numPackets = 10
+	)
+
+	// send packets in both directions of each flow
+	for i := 0; i < numPackets; i++ {
+		for f := 0; f < numFlows; f++ {
+			var (
+				client = "10.99.0." + strconv.Itoa(f+1)
+				server = "10.99.1.1"
+				data   = tcpPacket(t, client, 40000+f, server, 80)
+			)
+
+			if i%2 == 1 {
+				data = tcpPacket(t, server, 80, client, 40000+f)
+			}
+
+			if err = w.WritePacketData(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var (
+		mu sync.Mutex
+		// flow key mapped to the sockets that received packets of the flow
+		flows = make(map[string]map[int]struct{})
+		total int
+		wg    sync.WaitGroup
+
+		deadline = time.Now().Add(5 * time.Second)
+		done     = make(chan struct{})
+		doneOnce sync.Once
+	)
+
+	for i, s := range g.sockets {
+		wg.Add(1)
+
+		go func(num int, s *afpacket.TPacket) {
+			defer wg.Done()
+
+			for time.Now().Before(deadline) {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				data, _, errRead := s.ReadPacketData()
+				if errRead != nil {
+					continue
+				}
+
+				// only count the test packets, the kernel sends IPv6 neighbor discovery when the interfaces come up
+				p := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+				if p.Layer(layers.LayerTypeTCP) == nil {
+					continue
+				}
+
+				key := packetFlowKeys(p)[1]
+
+				mu.Lock()
+
+				if flows[key] == nil {
+					flows[key] = make(map[int]struct{})
+				}
+
+				flows[key][num] = struct{}{}
+				total++
+
+				if total == numFlows*numPackets {
+					doneOnce.Do(func() { close(done) })
+				}
+
+				mu.Unlock()
+			}
+		}(i, s)
+	}
+
+	wg.Wait()
+
+	if total != numFlows*numPackets {
+		t.Fatal("expected", numFlows*numPackets, "packets, got", total)
+	}
+
+	if len(flows) != numFlows {
+		t.Fatal("expected", numFlows, "flows, got", len(flows))
+	}
+
+	var sockets = make(map[int]struct{})
+
+	for k, s := range flows {
+		if len(s) != 1 {
+			t.Fatal("packets of flow", k, "were delivered to", len(s), "sockets")
+		}
+
+		for num := range s {
+			sockets[num] = struct{}{}
+		}
+	}
+
+	if len(sockets) < 2 {
+		t.Fatal("expected the flows to be distributed over multiple sockets, got", len(sockets))
+	}
+
+	// the neighbor discovery packets are counted by the kernel as well
+	received, dropped := g.stats()
+	if received < numFlows*numPackets || dropped != 0 {
+		t.Fatal("unexpected socket statistics: received", received, "dropped", dropped)
+	}
+}
+
+func TestFanoutGroupID(t *testing.T) {
+	a, _ := vethPair(t)
+
+	// another process uses the group with different settings
+	other, err := afpacket.NewTPacket(afpacket.OptInterface(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	if err = other.SetFanout(afpacket.FanoutLoadBalance, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := fanoutConfig{iface: a, sockets: 2, blockSize: 1 << 16, numBlocks: 4, id: 100}
+
+	// a configured id is used as is
+	if _, err = newFanoutGroup(conf); err == nil {
+		t.Fatal("expected an error for a fanout group with different settings")
+	}
+
+	s, err := afpacket.NewTPacket(afpacket.OptInterface(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	id, err := joinFreeFanoutGroup(s, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 101 {
+		t.Fatal("expected the next fanout group id, got", id)
+	}
+}
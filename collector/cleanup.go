@@ -76,6 +76,15 @@ func (c *Collector) cleanup(force bool) {
 		//	c.log.Info(" timeout after ", zap.Duration("reassemblyTimeout", defaults.ReassemblyTimeout))
 	}
 
+	if c.ip6Defragger != nil {
+		reassembled, discarded, expired := c.ip6Defragger.Stats()
+		c.log.Info("IPv6 defragmentation",
+			zap.Int64("reassembled", reassembled),
+			zap.Int64("discarded", discarded),
+			zap.Int64("expired", expired),
+		)
+	}
+
 	if c.config.ReassembleConnections {
 		// teardown the TCP stream reassembly and print stats
 		tcp.CleanupReassembly(!force, c.assemblers)
@@ -87,6 +96,10 @@ func (c *Collector) cleanup(force bool) {
 func (c *Collector) teardown() {
 	c.log.Info("teardown")
 
+	if c.packetStore != nil {
+		c.closePacketStore()
+	}
+
 	// flush all gopacket decoders
 	for _, decoders := range c.goPacketDecoders {
 		for _, e := range decoders {
@@ -125,6 +138,15 @@ func (c *Collector) teardown() {
 		}
 	}
 
+	// the evidence audit records are complete after flushing the abstract decoders
+	if c.evidence != nil {
+		if err := c.writeEvidencePcap(); err != nil {
+			log.Println("failed to write evidence pcap", err)
+		}
+
+		c.evidence = nil
+	}
+
 	if alert.SocketConn != nil {
 		err := alert.SocketConn.Close()
 		if err != nil {
@@ -241,27 +263,12 @@ func (c *Collector) teardown() {
 		}
 
 		// if not, use native pcapgo version
-		isPcap, err := IsPcap(c.InputFile)
-		if err != nil {
-			// invalid path
-			fmt.Println("failed to open file:", err)
-			os.Exit(1)
-		}
-
-		if isPcap {
-			if err = c.CollectPcap(c.InputFile); err != nil {
-				log.Fatal("failed to collect audit records from pcap file: ", err)
-			}
-		} else {
-			if err = c.CollectPcapNG(c.InputFile); err != nil {
-				log.Fatal("failed to collect audit records from pcapng file: ", err)
-			}
+		if err = c.Collect(c.InputFile); err != nil {
+			log.Fatal("failed to collect audit records: ", err)
 		}
 
 		if c.PrintTime {
-			// stat input file
-			stat, _ := os.Stat(c.InputFile)
-			fmt.Println("size", humanize.Bytes(uint64(stat.Size())), "done in", time.Since(start), "total", time.Since(c.startFirst))
+			fmt.Println("size", humanize.Bytes(uint64(c.inputSize)), "done in", time.Since(start), "total", time.Since(c.startFirst))
 		}
 	}
 }
@@ -32,6 +32,7 @@ import (
 	"time"
 
 	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
 	"github.com/dustin/go-humanize"
 	"github.com/evilsocket/islazy/tui"
 	"github.com/mgutz/ansi"
@@ -46,7 +47,9 @@ import (
 	"github.com/dreadl0ck/netcap/defaults"
 	netio "github.com/dreadl0ck/netcap/io"
 	"github.com/dreadl0ck/netcap/label/manager"
+	"github.com/dreadl0ck/netcap/packetstore"
 	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/reassembly/ip6defrag"
 	"github.com/dreadl0ck/netcap/utils"
 )
 
@@ -112,6 +115,25 @@ type Collector struct {
 
 	// interval for tracking collector stats
 	statsInterval time.Duration
+
+	// shared by all workers, nil if IPv6 defragmentation is disabled
+	ip6Defragger *ip6defrag.Defragmenter
+
+	// flows of the evidence audit records and the input that is read again to write the evidence pcap,
+	// nil if writing the evidence pcap is disabled
+	evidence      *evidence
+	evidenceInput string
+
+	// link type of the capture
+	linkType layers.LinkType
+
+	// nil if the packet store is disabled
+	packetStore         *packetstore.Writer
+	numPacketsNotStored int64
+
+	// returns the number of packets received and dropped by the kernel,
+	// nil if the capture source provides no statistics
+	captureStats func() (received, dropped int64)
 }
 
 // New returns a new Collector instance.
@@ -120,7 +142,13 @@ func New(config Config) *Collector {
 		config.OutDirPermission = defaults.DirectoryPermission
 	}
 
+	var ip6Defragger *ip6defrag.Defragmenter
+	if config.DecoderConfig != nil && config.DecoderConfig.DefragIPv6 {
+		ip6Defragger = ip6defrag.NewDefragmenter()
+	}
+
 	return &Collector{
+		ip6Defragger:        ip6Defragger,
 		next:                1,
 		unknownProtosAtomic: decoderutils.NewAtomicCounterMap(),
 		allProtosAtomic:     decoderutils.NewAtomicCounterMap(),
@@ -131,6 +159,7 @@ func New(config Config) *Collector {
 		numEpochs:           1,
 		pps:                 map[time.Time]float64{},
 		statsInterval:       5 * time.Second,
+		linkType:            layers.LinkTypeEthernet,
 	}
 }
 
@@ -427,6 +456,11 @@ func (c *Collector) stats() {
 		}
 	}
 
+	if c.captureStats != nil {
+		received, dropped := c.captureStats()
+		res += "-> kernel received " + strconv.FormatInt(received, 10) + " packets and dropped " + strconv.FormatInt(dropped, 10) + "\n"
+	}
+
 	if _, err := fmt.Fprintln(target, res); err != nil {
 		fmt.Println("failed to print stats:", err)
 	}
@@ -527,9 +561,11 @@ func (c *Collector) printProgressInterval() chan struct{} {
 
 				// print to stderr
 				if !c.config.DecoderConfig.Quiet || c.config.DecoderConfig.PrintProgress { // print
+					drops := c.kernelDrops()
+
 					c.clearLine()
 					_, _ = fmt.Fprintf(os.Stderr,
-						c.progressString,
+						c.progressString+"%s",
 						utils.Progress(curr, num),
 						// decoder.Flows.Size(), // TODO: fetch this info from stats?
 						// decoder.Connections.Size(), // TODO: fetch this info from stats?
@@ -537,15 +573,17 @@ func (c *Collector) printProgressInterval() chan struct{} {
 						service.Store.Size(),
 						int(curr),
 						pps,
+						drops,
 					)
-					c.log.Sugar().Infof(c.progressString,
+					c.log.Sugar().Infof(c.progressString+"%s",
 						utils.Progress(curr, num),
 						// decoder.Flows.Size(), // TODO: fetch this info from stats?
 						// decoder.Connections.Size(), // TODO: fetch this info from stats?
 						packet.DeviceProfiles.Size(),
 						service.Store.Size(),
 						int(curr),
-						pps)
+						pps,
+						drops)
 				}
 			}
 		}
@@ -554,11 +592,32 @@ func (c *Collector) printProgressInterval() chan struct{} {
 	return stop
 }
 
+// kernelDrops returns the number of packets dropped by the kernel for the progress output,
+// or an empty string if the capture source provides no statistics.
+func (c *Collector) kernelDrops() string {
+	c.mu.Lock()
+	captureStats := c.captureStats
+	c.mu.Unlock()
+
+	if captureStats == nil {
+		return ""
+	}
+
+	received, dropped := captureStats()
+
+	return " kernel drops: " + strconv.FormatInt(dropped, 10) + " of " + strconv.FormatInt(received, 10)
+}
+
 // assemble the progress string once, to reduce recurring allocations.
 func (c *Collector) buildProgressString() {
 	c.progressString = "decoding packets... (%s) profiles: %d services: %d total packets: %d pkts/sec %d"
 }
 
+// InputSize returns the size of the input files in bytes, which is zero for stdin.
+func (c *Collector) InputSize() int64 {
+	return c.inputSize
+}
+
 // GetNumPackets returns the current number of processed packets.
 func (c *Collector) GetNumPackets() int64 {
 	return atomic.LoadInt64(&c.current)
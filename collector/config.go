@@ -91,6 +91,34 @@ type Config struct {
 	// Controls whether packets that had an unknown layer will get written into a separate file
 	WriteUnknownPackets bool
 
+	// Controls whether the packets of flows that produced alerts, credentials, exploits or files
+	// get written into an annotated pcapng file, which requires a second pass over the input file
+	WriteEvidencePcap bool
+
+	// Controls whether the raw packets get written into an indexed packet store in the output directory,
+	// which allows to extract the packets of flows and audit records later
+	PacketStore bool
+
+	// Size in bytes at which a new packet store segment is started
+	PacketStoreSegmentSize int64
+
+	// AFPacket enables live capture with AF_PACKET TPACKET_V3 ring buffers on linux.
+	// Each worker reads from its own socket, the kernel distributes the packets between the sockets by flow
+	AFPacket bool
+
+	// Size of the blocks in the ring buffer of each AF_PACKET socket, must be a multiple of the page size
+	AFPacketBlockSize int
+
+	// Number of blocks in the ring buffer of each AF_PACKET socket
+	AFPacketNumBlocks int
+
+	// Id of the AF_PACKET fanout group, must be unique for all processes capturing on the host.
+	// 0 derives the id from the process id and tries the following ids if the group exists with different settings
+	AFPacketFanoutID uint16
+
+	// Speed multiplier for CollectReplay, 0 and 1 replay the packets at their original pace
+	ReplaySpeed float64
+
 	// Resolver configuration
 	ResolverConfig resolvers.Config
 
@@ -0,0 +1,387 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dustin/go-humanize"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/decoder"
+	"github.com/dreadl0ck/netcap/defaults"
+	netio "github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/pcapng"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// evidencePcapName is the name of the evidence pcap in the output directory.
+const evidencePcapName = "evidence.pcapng"
+
+// evidenceTypes are the audit record types whose flows are written into the evidence pcap.
+var evidenceTypes = map[types.Type]struct{}{
+	types.Type_NC_Alert:       {},
+	types.Type_NC_Credentials: {},
+	types.Type_NC_Exploit:     {},
+	types.Type_NC_File:        {},
+}
+
+// evidence collects the flows of the audit records that are considered evidence.
+type evidence struct {
+	sync.Mutex
+
+	// flow keys mapped to the references of the audit records for the flow, e.g. "Credentials #3"
+	flows map[string][]string
+}
+
+func newEvidence() *evidence {
+	return &evidence{
+		flows: make(map[string][]string),
+	}
+}
+
+// references returns the audit record references for the flow keys.
+func (e *evidence) references(keys ...string) (refs []string) {
+	e.Lock()
+	defer e.Unlock()
+
+	for _, k := range keys {
+		refs = append(refs, e.flows[k]...)
+	}
+
+	return refs
+}
+
+// evidenceWriter wraps the writer of an abstract decoder and collects the flows of the written audit records.
+// The audit records are referenced by the decoder name and their position in the audit record file, starting at one.
+type evidenceWriter struct {
+	netio.AuditRecordWriter
+
+	name     string
+	evidence *evidence
+
+	// guarded by the evidence mutex
+	numRecords int
+}
+
+// Write writes the audit record and collects its flows.
+func (w *evidenceWriter) Write(msg proto.Message) error {
+	w.evidence.Lock()
+	defer w.evidence.Unlock()
+
+	// writes are serialized, so the number of records matches the position in the file
+	if err := w.AuditRecordWriter.Write(msg); err != nil {
+		return err
+	}
+
+	w.numRecords++
+	ref := w.name + " #" + strconv.Itoa(w.numRecords)
+
+	for _, k := range evidenceFlowKeys(msg) {
+		w.evidence.flows[k] = append(w.evidence.flows[k], ref)
+	}
+
+	return nil
+}
+
+// GetChan returns the channel of the wrapped writer, if it is a channel writer.
+func (w *evidenceWriter) GetChan() <-chan []byte {
+	if cw, ok := w.AuditRecordWriter.(netio.ChannelAuditRecordWriter); ok {
+		return cw.GetChan()
+	}
+
+	return nil
+}
+
+// collectEvidence wraps the writers of the abstract decoders for evidence audit records.
+func (c *Collector) collectEvidence() {
+	c.evidence = newEvidence()
+
+	for _, d := range c.abstractDecoders {
+		if _, ok := evidenceTypes[d.GetType()]; !ok {
+			continue
+		}
+
+		ad, ok := d.(*decoder.AbstractDecoder)
+		if !ok {
+			continue
+		}
+
+		ad.SetWriter(&evidenceWriter{
+			AuditRecordWriter: ad.Writer,
+			name:              ad.Name,
+			evidence:          c.evidence,
+		})
+	}
+}
+
+// evidenceFlowKeys returns the flow keys of an evidence audit record.
+func evidenceFlowKeys(msg proto.Message) (keys []string) {
+	add := func(k string) {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	switch r := msg.(type) {
+	case *types.Alert:
+		add(flowKey(r.SrcIP, r.SrcPort, r.DstIP, r.DstPort))
+	case *types.File:
+		add(flowKey(r.SrcIP, strconv.Itoa(int(r.SrcPort)), r.DstIP, strconv.Itoa(int(r.DstPort))))
+	case *types.Credentials:
+		add(flowIdentKey(r.Flow))
+	case *types.Exploit:
+		if r.Software != nil {
+			for _, f := range r.Software.Flows {
+				add(flowIdentKey(f))
+			}
+		}
+	}
+
+	return keys
+}
+
+// flowKey returns a key for the flow, which is identical for both directions.
+// Without ports, the key matches all traffic between the hosts.
+func flowKey(srcIP, srcPort, dstIP, dstPort string) string {
+	if srcIP == "" || dstIP == "" {
+		return ""
+	}
+
+	src, dst := srcIP, dstIP
+	if srcPort != "" && srcPort != "0" && dstPort != "" && dstPort != "0" {
+		src, dst = net.JoinHostPort(srcIP, srcPort), net.JoinHostPort(dstIP, dstPort)
+	}
+
+	if dst < src {
+		src, dst = dst, src
+	}
+
+	return src + "-" + dst
+}
+
+// flowIdentKey returns the flow key for a flow identifier, e.g: 192.168.1.47:53032->165.227.109.154:80.
+// The port is separated at the last colon, so IPv6 addresses are supported as well.
+func flowIdentKey(ident string) string {
+	// the encapsulation suffix is ignored, the inner flow is matched
+	if idx := strings.IndexByte(ident, '@'); idx != -1 {
+		ident = ident[:idx]
+	}
+
+	arr := strings.Split(ident, "->")
+	if len(arr) != 2 {
+		return ""
+	}
+
+	split := func(endpoint string) (host, port string) {
+		idx := strings.LastIndexByte(endpoint, ':')
+		if idx == -1 {
+			return "", ""
+		}
+
+		return endpoint[:idx], endpoint[idx+1:]
+	}
+
+	srcIP, srcPort := split(arr[0])
+	dstIP, dstPort := split(arr[1])
+
+	return flowKey(srcIP, srcPort, dstIP, dstPort)
+}
+
+// packetFlowKeys returns the keys of the flow and the hosts of a packet.
+// For tunneled traffic the innermost network and transport layers are used.
+func packetFlowKeys(p gopacket.Packet) []string {
+	var srcIP, dstIP, srcPort, dstPort string
+
+	for _, l := range p.Layers() {
+		switch layer := l.(type) {
+		case *layers.IPv4:
+			srcIP, dstIP = layer.SrcIP.String(), layer.DstIP.String()
+			srcPort, dstPort = "", ""
+		case *layers.IPv6:
+			srcIP, dstIP = layer.SrcIP.String(), layer.DstIP.String()
+			srcPort, dstPort = "", ""
+		case *layers.TCP:
+			srcPort, dstPort = strconv.Itoa(int(layer.SrcPort)), strconv.Itoa(int(layer.DstPort))
+		case *layers.UDP:
+			srcPort, dstPort = strconv.Itoa(int(layer.SrcPort)), strconv.Itoa(int(layer.DstPort))
+		}
+	}
+
+	if srcIP == "" {
+		return nil
+	}
+
+	keys := []string{flowKey(srcIP, "", dstIP, "")}
+	if srcPort != "" {
+		keys = append(keys, flowKey(srcIP, srcPort, dstIP, dstPort))
+	}
+
+	return keys
+}
+
+// writeEvidencePcap reads the input a second time and writes the packets of the flows
+// that produced evidence audit records into the evidence pcap in the output directory.
+// Each packet is annotated with packet comments that reference the audit records of its flow.
+func (c *Collector) writeEvidencePcap() error {
+	c.evidence.Lock()
+	numFlows := len(c.evidence.flows)
+	c.evidence.Unlock()
+
+	if numFlows == 0 {
+		c.log.Info("no evidence audit records, skipping evidence pcap")
+
+		return nil
+	}
+
+	if c.evidenceInput == "" || c.evidenceInput == Stdin {
+		c.printlnStdOut("evidence pcap requires a capture file as input, skipping", evidencePcapName)
+
+		return nil
+	}
+
+	paths, err := inputPaths(c.evidenceInput)
+	if err != nil {
+		return err
+	}
+
+	var src interface {
+		packetSource
+		io.Closer
+	}
+
+	if len(paths) > 1 {
+		src, err = newMergeInput(paths)
+	} else {
+		src, err = openInput(paths[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		errClose := src.Close()
+		if errClose != nil && !errors.Is(errClose, io.EOF) {
+			c.log.Error("failed to close input", zap.Error(errClose))
+		}
+	}()
+
+	path := filepath.Join(c.config.DecoderConfig.Out, evidencePcapName)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriterSize(f, defaults.BufferSize)
+
+	numPackets, err := c.filterEvidence(src, buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if stat, errStat := os.Stat(path); errStat == nil {
+		c.totalBytesWritten += stat.Size()
+		c.files[evidencePcapName] = humanize.Bytes(uint64(stat.Size()))
+	}
+
+	c.log.Info("wrote evidence pcap", zap.Int64("packets", numPackets), zap.Int("flows", numFlows))
+	c.printlnStdOut("wrote", numPackets, "packets of", numFlows, "evidence flows to", evidencePcapName)
+
+	return nil
+}
+
+// filterEvidence writes the packets with evidence references from src to w in the pcapng format.
+func (c *Collector) filterEvidence(src packetSource, w io.Writer) (numPackets int64, err error) {
+	pw, err := pcapng.NewWriter(w, pcapng.Section{
+		Application: "netcap " + netcap.Version,
+		Comment:     "packets of flows with alerts, credentials, exploits or extracted files",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// interfaces of the input mapped to the interfaces of the evidence pcap,
+	// the nil key is used for pcap inputs without interface descriptions
+	interfaces := make(map[*pcapng.Interface]*pcapng.Interface)
+
+	for {
+		data, ci, errRead := src.ReadPacketData()
+		if errRead != nil {
+			if errors.Is(errRead, io.EOF) || errors.Is(errRead, io.ErrUnexpectedEOF) {
+				return numPackets, nil
+			}
+
+			return numPackets, errors.Wrap(errRead, errReadingPacketData)
+		}
+
+		p := gopacket.NewPacket(data, c.baseLayer(&ci), gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+
+		refs := c.evidence.references(packetFlowKeys(p)...)
+		if len(refs) == 0 {
+			continue
+		}
+
+		var (
+			info  = &pcapng.PacketInfo{Comments: refs}
+			iface = pcapng.Interface{LinkType: src.LinkType()}
+			key   *pcapng.Interface
+		)
+
+		// the metadata of pcapng inputs is preserved
+		if in := pcapng.Info(&ci); in != nil {
+			key, iface = in.Interface, *in.Interface
+			info.Flags, info.DropCount = in.Flags, in.DropCount
+		}
+
+		out, ok := interfaces[key]
+		if !ok {
+			out, err = pw.AddInterface(iface)
+			if err != nil {
+				return numPackets, err
+			}
+
+			interfaces[key] = out
+		}
+
+		info.Interface = out
+
+		if err = pw.WritePacket(ci, data, info); err != nil {
+			return numPackets, err
+		}
+
+		numPackets++
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/pcapng"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// nopRecordWriter counts the written audit records.
+type nopRecordWriter struct {
+	numRecords int
+}
+
+func (w *nopRecordWriter) Write(proto.Message) error {
+	w.numRecords++
+
+	return nil
+}
+
+func (w *nopRecordWriter) WriteHeader(types.Type) error { return nil }
+
+func (w *nopRecordWriter) Close(int64) (string, int64) { return "", 0 }
+
+func TestFlowIdentKey(t *testing.T) {
+	key := flowIdentKey("192.168.1.47:53032->165.227.109.154:80")
+
+	if key == "" || key != flowIdentKey("165.227.109.154:80->192.168.1.47:53032") {
+		t.Fatal("expected the same key for both directions", key)
+	}
+
+	if key != flowKey("165.227.109.154", "80", "192.168.1.47", "53032") {
+		t.Fatal("unexpected key for flow", key)
+	}
+
+	if flowIdentKey("192.168.1.47:53032->165.227.109.154:80@vlan:1") != key {
+		t.Fatal("expected the encapsulation to be ignored")
+	}
+
+	if flowIdentKey("fe80::1:53032->fe80::2:80") != flowKey("fe80::2", "80", "fe80::1", "53032") {
+		t.Fatal("unexpected key for IPv6 flow", flowIdentKey("fe80::1:53032->fe80::2:80"))
+	}
+
+	if flowKey("10.0.0.1", "0", "10.0.0.2", "0") != flowKey("10.0.0.2", "", "10.0.0.1", "") {
+		t.Fatal("expected a host key without ports")
+	}
+
+	if flowIdentKey("invalid") != "" {
+		t.Fatal("expected no key for invalid flow identifier")
+	}
+}
+
+// tcpPacket returns an ethernet frame with a TCP segment between the endpoints.
+func tcpPacket(t *testing.T, srcIP string, srcPort int, dstIP string, dstPort int) []byte {
+	t.Helper()
+
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP(srcIP).To4(),
+		DstIP:    net.ParseIP(dstIP).To4(),
+	}
+
+	tcp := &layers.TCP{SrcPort: layers.TCPPort(srcPort), DstPort: layers.TCPPort(dstPort), ACK: true}
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		&layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+			DstMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 6},
+			EthernetType: layers.EthernetTypeIPv4,
+		},
+		ip,
+		tcp,
+		gopacket.Payload("data"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestFilterEvidence(t *testing.T) {
+	var (
+		c = &Collector{
+			config:   &Config{BaseLayer: layers.LayerTypeEthernet},
+			evidence: newEvidence(),
+		}
+		credentials = &evidenceWriter{AuditRecordWriter: &nopRecordWriter{}, name: "Credentials", evidence: c.evidence}
+		alerts      = &evidenceWriter{AuditRecordWriter: &nopRecordWriter{}, name: "Alert", evidence: c.evidence}
+	)
+
+	for _, r := range []struct {
+		w   *evidenceWriter
+		msg proto.Message
+	}{
+		{credentials, &types.Credentials{Flow: "10.0.0.1:1111->10.0.0.2:21"}},
+		{credentials, &types.Credentials{Flow: "10.0.0.2:21->10.0.0.1:1111"}},
+		{alerts, &types.Alert{SrcIP: "10.0.0.3", DstIP: "10.0.0.4"}},
+	} {
+		if err := r.w.Write(r.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := credentials.AuditRecordWriter.(*nopRecordWriter).numRecords; n != 2 {
+		t.Fatal("expected the records to be written to the wrapped writer, got", n)
+	}
+
+	var capture bytes.Buffer
+
+	w := pcapgo.NewWriter(&capture)
+	if err := w.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	}
+
+	packets := [][]byte{
+		tcpPacket(t, "10.0.0.1", 1111, "10.0.0.2", 21),
+		tcpPacket(t, "10.0.0.1", 2222, "10.0.0.2", 21),
+		tcpPacket(t, "10.0.0.2", 21, "10.0.0.1", 1111),
+		tcpPacket(t, "10.0.0.4", 443, "10.0.0.3", 3333),
+	}
+
+	for i, data := range packets {
+		err := w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     inputStart.Add(time.Duration(i) * time.Second),
+			CaptureLength: len(data),
+			Length:        len(data),
+		}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := pcapgo.NewReader(&capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+
+	numPackets, err := c.filterEvidence(src, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if numPackets != 3 {
+		t.Fatal("expected 3 evidence packets, got", numPackets)
+	}
+
+	r, err := pcapng.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []struct {
+		second   int
+		comments string
+	}{
+		{0, "Credentials #1; Credentials #2"},
+		{2, "Credentials #1; Credentials #2"},
+		{3, "Alert #1"},
+	} {
+		data, ci, errRead := r.ReadPacketData()
+		if errRead != nil {
+			t.Fatal(errRead)
+		}
+
+		info := pcapng.Info(&ci)
+		if !ci.Timestamp.Equal(inputStart.Add(time.Duration(expected.second)*time.Second)) || !bytes.Equal(data, packets[expected.second]) {
+			t.Fatal("unexpected packet", ci.Timestamp)
+		}
+
+		if comments := strings.Join(info.Comments, "; "); comments != expected.comments {
+			t.Fatal("unexpected comments", comments, "expected", expected.comments)
+		}
+
+		if info.Interface.LinkType != layers.LinkTypeEthernet {
+			t.Fatal("unexpected link type", info.Interface.LinkType)
+		}
+	}
+
+	if _, _, err = r.ReadPacketData(); !errors.Is(err, io.EOF) {
+		t.Fatal("expected EOF, got", err)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// message type for GTP-U packets that carry a tunneled user packet.
+const gtpMessageTypeGPDU = 255
+
+// decapsulateGTP returns the packet tunneled in the GTP-U layer of p along with the GTP-U layer.
+// nil is returned if p does not carry an IPv4 or IPv6 user packet.
+// The tunneled packet is decoded with the given options and inherits the capture info of p.
+func decapsulateGTP(p gopacket.Packet, opts gopacket.DecodeOptions) (gopacket.Packet, gopacket.Layer) {
+	gtp, ok := p.Layer(layers.LayerTypeGTPv1U).(*layers.GTPv1U)
+	if !ok || gtp.MessageType != gtpMessageTypeGPDU || p.NetworkLayer() == nil {
+		return nil, nil
+	}
+
+	payload := gtp.LayerPayload()
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	var first gopacket.LayerType
+
+	switch payload[0] >> 4 {
+	case 4:
+		first = layers.LayerTypeIPv4
+	case 6:
+		first = layers.LayerTypeIPv6
+	default:
+		return nil, nil
+	}
+
+	inner := gopacket.NewPacket(payload, first, opts)
+
+	md := inner.Metadata()
+	md.CaptureInfo = p.Metadata().CaptureInfo
+	md.CaptureInfo.CaptureLength = len(payload)
+	md.CaptureInfo.Length = len(payload)
+	md.Timestamp = md.CaptureInfo.Timestamp
+	md.CaptureLength = md.CaptureInfo.CaptureLength
+	md.Length = md.CaptureInfo.Length
+
+	return inner, gtp
+}
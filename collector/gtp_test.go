@@ -0,0 +1,129 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/packet"
+)
+
+var gtpOuterIPv4 = &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+
+// gtpPacket returns an ethernet packet that carries a DNS query from 10.45.0.1 in a GTP-U tunnel with the TEID 0x1234.
+func gtpPacket(t *testing.T) gopacket.Packet {
+	t.Helper()
+
+	var (
+		inner = &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 45, 0, 1}, DstIP: net.IP{8, 8, 8, 8}}
+		buf   = gopacket.NewSerializeBuffer()
+	)
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true},
+		&layers.Ethernet{SrcMAC: net.HardwareAddr{0, 0, 0x5e, 0, 0x53, 1}, DstMAC: net.HardwareAddr{0, 0, 0x5e, 0, 0x53, 2}, EthernetType: layers.EthernetTypeIPv4},
+		gtpOuterIPv4,
+		&layers.UDP{SrcPort: 2152, DstPort: 2152},
+		&layers.GTPv1U{Version: 1, ProtocolType: 1, MessageType: gtpMessageTypeGPDU, TEID: 0x1234},
+		inner,
+		&layers.UDP{SrcPort: 40000, DstPort: 53},
+		gopacket.Payload("query"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	p.Metadata().CaptureInfo.Timestamp = time.Unix(1, 0)
+
+	return p
+}
+
+func TestDecapsulateGTP(t *testing.T) {
+	p := gtpPacket(t)
+
+	decapsulated, gtpLayer := decapsulateGTP(p, gopacket.Default)
+	if decapsulated == nil {
+		t.Fatal("no inner packet", p.Layers())
+	}
+
+	if gtp, ok := gtpLayer.(*layers.GTPv1U); !ok || gtp.TEID != 0x1234 {
+		t.Fatal("unexpected GTP layer", gtpLayer)
+	}
+
+	if decapsulated.LinkLayer() != nil || decapsulated.NetworkLayer().NetworkFlow().Src().String() != "10.45.0.1" {
+		t.Fatal("unexpected inner packet", decapsulated.Layers())
+	}
+
+	if udp, ok := decapsulated.TransportLayer().(*layers.UDP); !ok || udp.DstPort != 53 {
+		t.Fatal("unexpected inner transport layer", decapsulated.TransportLayer())
+	}
+
+	if !decapsulated.Metadata().Timestamp.Equal(time.Unix(1, 0)) || decapsulated.Metadata().CaptureLength != len(decapsulated.Data()) {
+		t.Fatal("unexpected metadata", decapsulated.Metadata())
+	}
+
+	// echo requests carry no user packet
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true},
+		gtpOuterIPv4,
+		&layers.UDP{SrcPort: 2152, DstPort: 2152},
+		&layers.GTPv1U{Version: 1, ProtocolType: 1, MessageType: 1, SequenceNumberFlag: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decapsulated, _ = decapsulateGTP(gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default), gopacket.Default); decapsulated != nil {
+		t.Fatal("expected no inner packet for echo requests")
+	}
+}
+
+// countingDecoder counts the packets passed to it.
+type countingDecoder struct {
+	packet.DecoderAPI
+	packets []gopacket.Packet
+}
+
+func (d *countingDecoder) Decode(p gopacket.Packet) error {
+	d.packets = append(d.packets, p)
+
+	return nil
+}
+
+func (d *countingDecoder) GetName() string { return "Counting" }
+
+func TestDecodePacketGTP(t *testing.T) {
+	var (
+		dec = &countingDecoder{}
+		c   = New(Config{
+			DecoderConfig: &config.Config{DecapsulateGTP: true},
+		})
+		p = gtpPacket(t)
+	)
+
+	c.packetDecoders = []packet.DecoderAPI{dec}
+	c.decodePacket(p, nil, nil)
+
+	// the outer packet tracks the tunneled flow with its encapsulation,
+	// the decapsulated packet must not be passed to the custom decoders a second time.
+	if len(dec.packets) != 1 || dec.packets[0] != p {
+		t.Fatal("expected the custom decoder to be called once with the outer packet, got", len(dec.packets), "calls")
+	}
+}
@@ -133,6 +133,9 @@ func (c *Collector) Init() (err error) {
 		// wipe extracted files
 		_ = os.RemoveAll(filepath.Join(c.config.DecoderConfig.Out, defaults.FileStorage))
 
+		// wipe stored packets, they belong to the overwritten audit records
+		_ = os.RemoveAll(filepath.Join(c.config.DecoderConfig.Out, defaults.PacketStore))
+
 		// clear streams if present
 		if errStreams == nil || errConns == nil {
 			_ = os.RemoveAll(udpPath)
@@ -226,6 +229,16 @@ func (c *Collector) Init() (err error) {
 		zap.Int("abstractDecoders", len(c.abstractDecoders)),
 	)
 
+	if c.config.WriteEvidencePcap {
+		c.collectEvidence()
+	}
+
+	if c.config.PacketStore {
+		if err = c.openPacketStore(); err != nil {
+			return err
+		}
+	}
+
 	c.buildProgressString()
 	c.printlnStdOut("done in", time.Since(start))
 
@@ -0,0 +1,620 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
+
+	"github.com/dreadl0ck/netcap/decoder/packet"
+	"github.com/dreadl0ck/netcap/defaults"
+	netio "github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/pcapng"
+)
+
+// Stdin is the input path for reading a packet capture from stdin.
+const Stdin = "-"
+
+var (
+	magicXZ     = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicPcapNG = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+	// pcap magic numbers for microsecond and nanosecond timestamps, in both byte orders
+	magicsPcap = [][]byte{
+		{0xa1, 0xb2, 0xc3, 0xd4},
+		{0xd4, 0xc3, 0xb2, 0xa1},
+		{0xa1, 0xb2, 0x3c, 0x4d},
+		{0x4d, 0x3c, 0xb2, 0xa1},
+	}
+
+	// errNotPacketCapture is returned when the data does not start with a pcap or pcapng magic number.
+	errNotPacketCapture = errors.New("not a packet capture")
+)
+
+// packetSource is implemented by the pcap and pcapng readers.
+type packetSource interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	LinkType() layers.LinkType
+}
+
+// statisticsSource is implemented by inputs that can contain pcapng Interface Statistics Blocks.
+type statisticsSource interface {
+	setStatisticsHandler(h statisticsHandler)
+}
+
+// statisticsHandler is called for the Interface Statistics Blocks of pcapng inputs.
+type statisticsHandler = func(iface *pcapng.Interface, stats *pcapng.InterfaceStatistics)
+
+// inputFile is an opened packet capture, which can be compressed and read from stdin.
+type inputFile struct {
+	packetSource
+
+	path       string
+	size       int64
+	compressed bool
+
+	file         *os.File
+	decompressor io.Reader
+}
+
+// openInput opens the packet capture at path, or stdin for the path Stdin.
+// Compressed captures are decompressed, the compression and the capture format are detected from the magic bytes.
+func openInput(path string) (*inputFile, error) {
+	in := &inputFile{path: path}
+
+	if path == Stdin {
+		in.file = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		stat, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+
+			return nil, err
+		}
+
+		in.file = f
+		in.size = stat.Size()
+	}
+
+	err := in.open()
+	if err != nil {
+		_ = in.Close()
+
+		return nil, errors.Wrap(err, "failed to open "+path)
+	}
+
+	return in, nil
+}
+
+func (in *inputFile) open() error {
+	var (
+		br           = bufio.NewReaderSize(in.file, defaults.BufferSize)
+		magic, _     = br.Peek(len(magicXZ))
+		decompressor io.Reader
+		err          error
+	)
+
+	if bytes.HasPrefix(magic, magicXZ) {
+		decompressor, err = xz.NewReader(br)
+	} else {
+		decompressor, err = netio.NewDecompressor(br, nil)
+	}
+
+	// the decompressor is only set on success, the readers returned with an error cannot be closed
+	if err != nil {
+		return err
+	}
+
+	in.decompressor = decompressor
+
+	in.compressed = in.decompressor != io.Reader(br)
+
+	r := br
+	if in.compressed {
+		r = bufio.NewReaderSize(in.decompressor, defaults.BufferSize)
+	}
+
+	// errors of the decompressor surface when the first bytes are read
+	magic, err = r.Peek(len(magicPcapNG))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	switch {
+	case bytes.Equal(magic, magicPcapNG):
+		in.packetSource, err = pcapng.NewReader(r)
+	case isPcapMagic(magic):
+		in.packetSource, err = pcapgo.NewReader(r)
+	default:
+		err = errNotPacketCapture
+	}
+
+	return err
+}
+
+// isPcapMagic returns true if the data starts with a pcap magic number.
+func isPcapMagic(magic []byte) bool {
+	for _, m := range magicsPcap {
+		if bytes.Equal(magic, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (in *inputFile) setStatisticsHandler(h statisticsHandler) {
+	if r, ok := in.packetSource.(*pcapng.Reader); ok {
+		r.StatisticsHandler = h
+	}
+}
+
+// Close releases the decompressor and closes the file, stdin is left open.
+func (in *inputFile) Close() error {
+	errDecompressor := netio.CloseDecompressor(in.decompressor)
+
+	if in.file == os.Stdin {
+		return errDecompressor
+	}
+
+	if err := in.file.Close(); err != nil {
+		return err
+	}
+
+	return errDecompressor
+}
+
+// inputPaths returns the packet captures for the input path:
+// all files of a directory, the matches of a glob pattern, or the path itself.
+func inputPaths(path string) ([]string, error) {
+	if path == Stdin {
+		return []string{path}, nil
+	}
+
+	if strings.ContainsAny(path, "*?[") {
+		paths, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(paths) == 0 {
+			return nil, errors.New("no files match " + path)
+		}
+
+		return paths, nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stat.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	for _, f := range files {
+		if f.Mode().IsRegular() && !strings.HasPrefix(f.Name(), ".") {
+			paths = append(paths, filepath.Join(path, f.Name()))
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, errors.New("no files in directory " + path)
+	}
+
+	return paths, nil
+}
+
+// mergeInput is a capture that is merged from multiple files, for example rotated dumps.
+// The packets are returned ordered by their timestamps.
+// The files are opened in the order of their first packet, once the merged stream reaches it,
+// so that only overlapping files are open at the same time.
+type mergeInput struct {
+	linkType layers.LinkType
+
+	// files that have not been reached yet, ordered by their first packet
+	pending []mergeFile
+
+	// open files, ordered by their next packet
+	active mergeHeap
+
+	// size of all files in bytes
+	size int64
+
+	onStatistics statisticsHandler
+}
+
+// mergeFile is a capture file of a mergeInput, with its next packet.
+type mergeFile struct {
+	path  string
+	first time.Time
+
+	in   *inputFile
+	data []byte
+	ci   gopacket.CaptureInfo
+}
+
+// next reads the next packet of the file, and closes it at the end.
+func (f *mergeFile) next() (ok bool, err error) {
+	f.data, f.ci, err = f.in.ReadPacketData()
+	if err != nil {
+		errClose := f.in.Close()
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, errClose
+		}
+
+		return false, errors.Wrap(err, errReadingPacketData+" file: "+f.path)
+	}
+
+	return true, nil
+}
+
+type mergeHeap []*mergeFile
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return h[i].ci.Timestamp.Before(h[j].ci.Timestamp) }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) {
+	*h = append(*h, x.(*mergeFile))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	f := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return f
+}
+
+// newMergeInput reads the first packet of each file to determine the order of the files.
+// Files that are not packet captures are skipped, all captures must have the same link type.
+func newMergeInput(paths []string) (*mergeInput, error) {
+	m := &mergeInput{}
+
+	for _, p := range paths {
+		in, err := openInput(p)
+		if errors.Is(err, errNotPacketCapture) {
+			fmt.Println("skipping file that is not a packet capture:", p)
+
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		f := mergeFile{path: p, in: in}
+
+		ok, err := f.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			// no packets
+			continue
+		}
+
+		if len(m.pending) == 0 {
+			m.linkType = in.LinkType()
+		} else if in.LinkType() != m.linkType {
+			_ = in.Close()
+
+			return nil, fmt.Errorf("link type %s of %s does not match link type %s of %s", in.LinkType(), p, m.linkType, m.pending[0].path)
+		}
+
+		if err = in.Close(); err != nil {
+			return nil, err
+		}
+
+		m.pending = append(m.pending, mergeFile{path: p, first: f.ci.Timestamp})
+		m.size += in.size
+	}
+
+	if len(m.pending) == 0 {
+		return nil, errors.New("no packet captures with packets found")
+	}
+
+	sort.SliceStable(m.pending, func(i, j int) bool {
+		return m.pending[i].first.Before(m.pending[j].first)
+	})
+
+	return m, nil
+}
+
+// LinkType returns the link type of the merged captures.
+func (m *mergeInput) LinkType() layers.LinkType {
+	return m.linkType
+}
+
+// ReadPacketData returns the packet with the lowest timestamp of all files.
+func (m *mergeInput) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	// open the files that start before the next packet
+	for len(m.pending) > 0 && (len(m.active) == 0 || !m.active[0].ci.Timestamp.Before(m.pending[0].first)) {
+		f := m.pending[0]
+		m.pending = m.pending[1:]
+
+		in, err := openInput(f.path)
+		if err != nil {
+			return nil, gopacket.CaptureInfo{}, err
+		}
+
+		f.in = in
+		f.in.setStatisticsHandler(m.onStatistics)
+
+		ok, err := f.next()
+		if err != nil {
+			return nil, gopacket.CaptureInfo{}, err
+		}
+
+		if ok {
+			heap.Push(&m.active, &f)
+		}
+	}
+
+	if len(m.active) == 0 {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+
+	var (
+		f        = m.active[0]
+		data, ci = f.data, f.ci
+	)
+
+	ok, err := f.next()
+	if err != nil {
+		return nil, gopacket.CaptureInfo{}, err
+	}
+
+	if ok {
+		heap.Fix(&m.active, 0)
+	} else {
+		heap.Pop(&m.active)
+	}
+
+	return data, ci, nil
+}
+
+func (m *mergeInput) setStatisticsHandler(h statisticsHandler) {
+	m.onStatistics = h
+
+	for _, f := range m.active {
+		f.in.setStatisticsHandler(h)
+	}
+}
+
+// Close closes the open files.
+func (m *mergeInput) Close() error {
+	for _, f := range m.active {
+		if err := f.in.Close(); err != nil {
+			return err
+		}
+	}
+
+	m.active = nil
+	m.pending = nil
+
+	return nil
+}
+
+// Collect reads packets from the packet capture at path and decodes them.
+// The path can be a pcap or pcapng file, which may be compressed with gzip, zstd, lz4 or xz,
+// Stdin to read a capture from stdin, or a directory or glob pattern of captures,
+// which are processed as a single stream ordered by the packet timestamps.
+func (c *Collector) Collect(path string) error {
+	src, srcPath, err := c.openSource(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		errClose := src.Close()
+		if errClose != nil && !errors.Is(errClose, io.EOF) {
+			fmt.Println(errClose)
+		}
+	}()
+
+	return c.collectPackets(src, srcPath)
+}
+
+// inputSource is a packet source that has to be closed after reading.
+type inputSource interface {
+	packetSource
+	io.Closer
+}
+
+// openSource opens the packet capture at path for Collect and sets the input size.
+// The returned path identifies the capture for the second pass of the evidence pcap,
+// for a single file it is the path of the file.
+func (c *Collector) openSource(path string) (inputSource, string, error) {
+	paths, err := inputPaths(path)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to open input")
+	}
+
+	c.clearLine()
+
+	if len(paths) > 1 {
+		m, errMerge := newMergeInput(paths)
+		if errMerge != nil {
+			return nil, "", errMerge
+		}
+
+		c.inputSize = m.size
+		c.printlnStdOut("opening", len(m.pending), "files from", path+" | size:", humanize.Bytes(uint64(c.inputSize)))
+
+		return m, path, nil
+	}
+
+	in, err := openInput(paths[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	if path == Stdin {
+		c.printlnStdOut("reading from stdin")
+	} else {
+		c.printlnStdOut("opening", paths[0]+" | size:", humanize.Bytes(uint64(in.size)))
+	}
+
+	c.inputSize = in.size
+
+	// counting packets requires a second pass over the file,
+	// it is only done for uncompressed files to display the progress in percent.
+	if path != Stdin && !in.compressed {
+		start := time.Now()
+
+		c.printStdOut("counting packets...")
+
+		c.numPackets, err = countInputPackets(paths[0])
+		if err != nil {
+			_ = in.Close()
+
+			return nil, "", err
+		}
+
+		c.clearLine()
+		c.printlnStdOut("counting packets... done.", c.numPackets, "packets found in", time.Since(start))
+	}
+
+	return in, paths[0], nil
+}
+
+// countInputPackets returns the number of packets in a pcap or pcapng file.
+func countInputPackets(path string) (count int64, err error) {
+	in, err := openInput(path)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		errClose := in.Close()
+		if errClose != nil && !errors.Is(errClose, io.EOF) {
+			fmt.Println(errClose)
+		}
+	}()
+
+	for {
+		_, _, err = in.ReadPacketData()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return count, nil
+			}
+
+			return count, errors.Wrap(err, errReadingPacketData)
+		}
+
+		count++
+	}
+}
+
+// initSource initializes the collector for the link type of the source.
+func (c *Collector) initSource(src packetSource, path string) error {
+	c.handleLinkType(src.LinkType())
+	c.evidenceInput = path
+
+	// initialize collector
+	if err := c.Init(); err != nil {
+		return err
+	}
+
+	// interface statistics of pcapng captures are written as audit records
+	switch s := src.(type) {
+	case *pcapng.Reader:
+		s.StatisticsHandler = packet.WriteCaptureStatistics
+	case statisticsSource:
+		s.setStatisticsHandler(packet.WriteCaptureStatistics)
+	}
+
+	return nil
+}
+
+// collectPackets initializes the collector for the link type of the source and decodes all packets from it.
+func (c *Collector) collectPackets(src packetSource, path string) error {
+	if err := c.initSource(src, path); err != nil {
+		return err
+	}
+
+	var (
+		data         []byte
+		ci           gopacket.CaptureInfo
+		err          error
+		stopProgress = c.printProgressInterval()
+	)
+
+	for { // fetch the next packet data and packet header
+		data, ci, err = src.ReadPacketData()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+
+			stopProgress <- struct{}{}
+
+			return errors.Wrap(err, errReadingPacketData+" file: "+path)
+		}
+
+		// increment atomic packet counter
+		atomic.AddInt64(&c.current, 1)
+
+		// must be locked, otherwise a race occurs when sending a SIGINT
+		//  and triggering wg.Wait() in another goroutine...
+		c.statMutex.Lock()
+
+		// increment wait group for packet processing
+		c.wg.Add(1)
+
+		c.statMutex.Unlock()
+
+		c.handleRawPacketData(data, &ci)
+	}
+
+	// Stop progress reporting
+	stopProgress <- struct{}{}
+
+	// run cleanup on channel exit
+	c.cleanup(false)
+
+	return nil
+}
@@ -0,0 +1,278 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/dreadl0ck/netcap/pcapng"
+)
+
+var inputStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// writeInputPcap writes a capture with a packet for each second offset, compressed with the codec if it is not empty.
+func writeInputPcap(t *testing.T, path, codec string, ng bool, seconds ...int) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		w       io.Writer = f
+		closers []io.Closer
+	)
+
+	switch codec {
+	case "gzip":
+		gw := gzip.NewWriter(f)
+		w, closers = gw, append(closers, gw)
+	case "zstd":
+		zw, errZstd := zstd.NewWriter(f)
+		if errZstd != nil {
+			t.Fatal(errZstd)
+		}
+
+		w, closers = zw, append(closers, zw)
+	case "xz":
+		xw, errXZ := xz.NewWriter(f)
+		if errXZ != nil {
+			t.Fatal(errXZ)
+		}
+
+		w, closers = xw, append(closers, xw)
+	}
+
+	// deferred first, to run after flushing the pcapng writer
+	defer func() {
+		for _, c := range closers {
+			if errClose := c.Close(); errClose != nil {
+				t.Fatal(errClose)
+			}
+		}
+
+		if errClose := f.Close(); errClose != nil {
+			t.Fatal(errClose)
+		}
+	}()
+
+	var write func(ci gopacket.CaptureInfo, data []byte) error
+
+	if ng {
+		nw, errNg := pcapgo.NewNgWriter(w, layers.LinkTypeEthernet)
+		if errNg != nil {
+			t.Fatal(errNg)
+		}
+
+		write = nw.WritePacket
+
+		defer func() {
+			if errFlush := nw.Flush(); errFlush != nil {
+				t.Fatal(errFlush)
+			}
+		}()
+	} else {
+		pw := pcapgo.NewWriterNanos(w)
+		if err = pw.WriteFileHeader(1024, layers.LinkTypeEthernet); err != nil {
+			t.Fatal(err)
+		}
+
+		write = pw.WritePacket
+	}
+
+	for _, s := range seconds {
+		data := make([]byte, 60)
+		data[0] = byte(s)
+
+		err = write(gopacket.CaptureInfo{
+			Timestamp:     inputStart.Add(time.Duration(s) * time.Second),
+			CaptureLength: len(data),
+			Length:        len(data),
+		}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readSeconds returns the second offsets of all packets from the source.
+func readSeconds(t *testing.T, src packetSource) (seconds []int) {
+	t.Helper()
+
+	for {
+		_, ci, err := src.ReadPacketData()
+		if errors.Is(err, io.EOF) {
+			return seconds
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		seconds = append(seconds, int(ci.Timestamp.Sub(inputStart)/time.Second))
+	}
+}
+
+func TestOpenInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name  string
+		codec string
+		ng    bool
+	}{
+		{name: "plain.pcap"},
+		{name: "plain.pcapng", ng: true},
+		{name: "compressed.pcap.gz", codec: "gzip"},
+		{name: "compressed.pcapng.zst", codec: "zstd", ng: true},
+		{name: "compressed.pcapng.xz", codec: "xz", ng: true},
+	} {
+		path := filepath.Join(dir, tc.name)
+		writeInputPcap(t, path, tc.codec, tc.ng, 1, 2, 3)
+
+		in, errOpen := openInput(path)
+		if errOpen != nil {
+			t.Fatal(tc.name, errOpen)
+		}
+
+		if in.compressed != (tc.codec != "") {
+			t.Fatal(tc.name, "unexpected compression detection", in.compressed)
+		}
+
+		if in.LinkType() != layers.LinkTypeEthernet {
+			t.Fatal(tc.name, "unexpected link type", in.LinkType())
+		}
+
+		if _, ok := in.packetSource.(*pcapng.Reader); ok != tc.ng {
+			t.Fatal(tc.name, "unexpected reader", in.packetSource)
+		}
+
+		if seconds := readSeconds(t, in); len(seconds) != 3 || seconds[2] != 3 {
+			t.Fatal(tc.name, "unexpected packets", seconds)
+		}
+
+		if err = in.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// rotated files, with an overlap between the second and third file
+	writeInputPcap(t, filepath.Join(dir, "dump-3.pcap.gz"), "gzip", false, 7, 9, 10)
+	writeInputPcap(t, filepath.Join(dir, "dump-1.pcap"), "", false, 0, 1, 2)
+	writeInputPcap(t, filepath.Join(dir, "dump-2.pcapng"), "", true, 3, 5, 8)
+	writeInputPcap(t, filepath.Join(dir, "empty.pcap"), "", false)
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a capture"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := inputPaths(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) != 5 {
+		t.Fatal("expected 5 files, got", paths)
+	}
+
+	m, err := newMergeInput(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{0, 1, 2, 3, 5, 7, 8, 9, 10}
+	if seconds := readSeconds(t, m); !equalInts(seconds, expected) {
+		t.Fatal("unexpected packet order", seconds, "expected", expected)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the captures with packets are counted for the progress
+	var size int64
+
+	for _, name := range []string{"dump-1.pcap", "dump-2.pcapng", "dump-3.pcap.gz"} {
+		stat, errStat := os.Stat(filepath.Join(dir, name))
+		if errStat != nil {
+			t.Fatal(errStat)
+		}
+
+		size += stat.Size()
+	}
+
+	if m.size != size {
+		t.Fatal("expected a size of", size, "got", m.size)
+	}
+
+	paths, err = inputPaths(filepath.Join(dir, "dump-*.pcap*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) != 3 {
+		t.Fatal("expected 3 files, got", paths)
+	}
+
+	// a damaged capture is an error instead of being skipped
+	if err = ioutil.WriteFile(filepath.Join(dir, "dump-4.pcap.gz"), []byte{0x1f, 0x8b, 0x08, 0x00, 0x01}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if paths, err = inputPaths(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = newMergeInput(paths); err == nil || errors.Is(err, errNotPacketCapture) {
+		t.Fatal("expected an error for the damaged capture, got", err)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -31,6 +31,10 @@ import (
 // this is the darwin version that uses the pcap lib with c bindings to fetch packets
 // currently there is no other option to do that.
 func (c *Collector) CollectLive(iface, bpf string, ctx context.Context) error {
+	if c.config.AFPacket {
+		return errors.New("AF_PACKET capture is only supported on linux")
+	}
+
 	// open interface in live mode
 	// snaplen, promiscuous mode and the timeout value can be configured over the collector instance
 	handle, err := pcap.OpenLive(iface, int32(c.config.SnapLen), c.config.Promisc, c.config.Timeout)
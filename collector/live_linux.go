@@ -28,8 +28,12 @@ import (
 
 // CollectLive starts collection of data from the given interface.
 // optionally a BPF can be supplied.
-// this is the linux version that uses the pure go version from pcapgo to fetch packets live.
+// this is the linux version that uses the pure go version from pcapgo to fetch packets live,
+// or AF_PACKET ring buffers with one socket per worker if configured.
 func (c *Collector) CollectLive(i string, bpf string, ctx context.Context) error {
+	if c.config.AFPacket {
+		return c.collectAFPacket(i, bpf, ctx)
+	}
 
 	// use raw socket to fetch packet on linux live mode
 	handle, err := pcapgo.NewEthernetHandle(i)
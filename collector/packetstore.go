@@ -0,0 +1,87 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dustin/go-humanize"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/packetstore"
+	"github.com/dreadl0ck/netcap/pcapng"
+)
+
+// openPacketStore creates the packet store writer in the output directory.
+func (c *Collector) openPacketStore() error {
+	w, err := packetstore.NewWriter(packetstore.Config{
+		Dir:         filepath.Join(c.config.DecoderConfig.Out, defaults.PacketStore),
+		SegmentSize: c.config.PacketStoreSegmentSize,
+		LinkType:    c.linkType,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.packetStore = w
+
+	return nil
+}
+
+// storePacket writes the packet into the packet store.
+// Packets from pcapng interfaces with another link type than the capture are not stored,
+// because all segments of the store share the same link type.
+func (c *Collector) storePacket(data []byte, ci *gopacket.CaptureInfo) {
+	if info := pcapng.Info(ci); info != nil && info.Interface.LinkType != c.linkType {
+		atomic.AddInt64(&c.numPacketsNotStored, 1)
+
+		return
+	}
+
+	if err := c.packetStore.WritePacket(*ci, data); err != nil {
+		c.log.Error("failed to write packet into packet store", zap.Error(err))
+	}
+}
+
+// closePacketStore completes the last segment of the packet store and adds the store size to the output files.
+func (c *Collector) closePacketStore() {
+	if err := c.packetStore.Close(); err != nil {
+		c.log.Error("failed to close packet store", zap.Error(err))
+	}
+
+	c.packetStore = nil
+
+	if n := atomic.LoadInt64(&c.numPacketsNotStored); n > 0 {
+		c.printlnStdOut("packet store: skipped", n, "packets with a different link type than", c.linkType)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(c.config.DecoderConfig.Out, defaults.PacketStore))
+	if err != nil {
+		c.log.Error("failed to read packet store directory", zap.Error(err))
+
+		return
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.Size()
+	}
+
+	c.totalBytesWritten += size
+	c.files[defaults.PacketStore] = humanize.Bytes(uint64(size))
+}
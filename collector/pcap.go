@@ -18,7 +18,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"sync/atomic"
 	"time"
 
 	"github.com/dreadl0ck/gopacket"
@@ -26,6 +25,8 @@ import (
 	"github.com/dreadl0ck/gopacket/pcapgo"
 	"github.com/dustin/go-humanize"
 	"github.com/pkg/errors"
+
+	"github.com/dreadl0ck/netcap/decoder/packet"
 )
 
 const errReadingPacketData = "error reading packet data"
@@ -145,51 +146,7 @@ func (c *Collector) CollectPcap(path string) error {
 		}
 	}()
 
-	c.handleLinkType(r.LinkType())
-
-	// initialize collector
-	if err = c.Init(); err != nil {
-		return err
-	}
-
-	var (
-		data         []byte
-		ci           gopacket.CaptureInfo
-		stopProgress = c.printProgressInterval()
-	)
-
-	for { // fetch the next packet data and packet header
-		data, ci, err = r.ReadPacketData()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-
-			return errors.Wrap(err, errReadingPacketData+" file: "+path)
-		}
-
-		// increment atomic packet counter
-		atomic.AddInt64(&c.current, 1)
-
-		// must be locked, otherwise a race occurs when sending a SIGINT
-		//  and triggering wg.Wait() in another goroutine...
-		c.statMutex.Lock()
-
-		// increment wait group for packet processing
-		c.wg.Add(1)
-
-		c.statMutex.Unlock()
-
-		c.handleRawPacketData(data, &ci)
-	}
-
-	// Stop progress reporting
-	stopProgress <- struct{}{}
-
-	// run cleanup on channel exit
-	c.cleanup(false)
-
-	return nil
+	return c.collectPackets(r, path)
 }
 
 func (c *Collector) handleLinkType(lt layers.LinkType) {
@@ -198,28 +155,43 @@ func (c *Collector) handleLinkType(lt layers.LinkType) {
 	// TODO: why does this not work?
 	//c.config.BaseLayer = lt.LayerType()
 
+	baseLayer, ok := linkTypeLayer(lt)
+	if !ok {
+		log.Fatal("unhandled link type: ", lt)
+	}
+
+	c.config.BaseLayer = baseLayer
+	c.linkType = lt
+}
+
+// linkTypeLayer returns the layer type to start decoding packets of the link type with.
+func linkTypeLayer(lt layers.LinkType) (gopacket.LayerType, bool) {
 	switch lt {
 	case layers.LinkTypeEthernet:
-		c.config.BaseLayer = layers.LayerTypeEthernet
+		return layers.LayerTypeEthernet, true
 	case layers.LinkTypeRaw:
-		c.config.BaseLayer = layers.LayerTypeIPv4
+		return layers.LayerTypeIPv4, true
 	case layers.LinkTypeIPv4:
-		c.config.BaseLayer = layers.LayerTypeIPv4
+		return layers.LayerTypeIPv4, true
 	case layers.LinkTypeIPv6:
-		c.config.BaseLayer = layers.LayerTypeIPv6
+		return layers.LayerTypeIPv6, true
 	case layers.LinkTypeNull:
-		c.config.BaseLayer = layers.LayerTypeLoopback
+		return layers.LayerTypeLoopback, true
 	case layers.LinkTypeFDDI:
-		c.config.BaseLayer = layers.LayerTypeFDDI
+		return layers.LayerTypeFDDI, true
 	case layers.LinkTypeIEEE802_11:
-		c.config.BaseLayer = layers.LayerTypeDot11
+		return layers.LayerTypeDot11, true
 	case layers.LinkTypeIEEE80211Radio:
-		c.config.BaseLayer = layers.LayerTypeRadioTap
-	case layers.LinkTypePPP:
-		c.config.BaseLayer = layers.LayerTypePPP
+		return layers.LayerTypeRadioTap, true
+	case layers.LinkTypePPP, layers.LinkTypePPP_HDLC:
+		return layers.LayerTypePPP, true
+	case layers.LinkTypePPPEthernet:
+		return layers.LayerTypePPPoE, true
 	case layers.LinkTypeLinuxSLL:
-		c.config.BaseLayer = layers.LayerTypeLinuxSLL
+		return layers.LayerTypeLinuxSLL, true
+	case packet.LinkTypeLinuxSLL2:
+		return packet.LayerTypeLinuxSLL2, true
 	default:
-		log.Fatal("unhandled link type: ", lt)
+		return gopacket.LayerTypeZero, false
 	}
 }
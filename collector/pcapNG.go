@@ -17,17 +17,16 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"sync/atomic"
 	"time"
 
-	"github.com/dreadl0ck/gopacket"
-	"github.com/dreadl0ck/gopacket/pcapgo"
 	"github.com/dustin/go-humanize"
 	"github.com/pkg/errors"
+
+	"github.com/dreadl0ck/netcap/pcapng"
 )
 
-// openPcap opens pcap files.
-func openPcapNG(file string) (*pcapgo.NgReader, *os.File, error) {
+// openPcapNG opens pcapng files.
+func openPcapNG(file string) (*pcapng.Reader, *os.File, error) {
 	// get file handle
 	f, err := os.Open(file)
 	if err != nil {
@@ -35,8 +34,10 @@ func openPcapNG(file string) (*pcapgo.NgReader, *os.File, error) {
 	}
 
 	// try to create pcap reader
-	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	r, err := pcapng.NewReader(f)
 	if err != nil {
+		_ = f.Close()
+
 		return nil, nil, err
 	}
 
@@ -116,50 +117,5 @@ func (c *Collector) CollectPcapNG(path string) error {
 		}
 	}()
 
-	c.handleLinkType(r.LinkType())
-
-	// initialize collector
-	if err = c.Init(); err != nil {
-		return err
-	}
-
-	var (
-		data         []byte
-		ci           gopacket.CaptureInfo
-		stopProgress = c.printProgressInterval()
-	)
-
-	for {
-		// fetch the next packet data and packet header
-		data, ci, err = r.ReadPacketData()
-		if err != nil {
-			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-				break
-			}
-
-			return errors.Wrap(err, errReadingPacketData+" file: "+path)
-		}
-
-		// increment atomic packet counter
-		atomic.AddInt64(&c.current, 1)
-
-		// must be locked, otherwise a race occurs when sending a SIGINT
-		//  and triggering wg.Wait() in another goroutine...
-		c.statMutex.Lock()
-
-		// increment wait group for packet processing
-		c.wg.Add(1)
-
-		c.statMutex.Unlock()
-
-		c.handleRawPacketData(data, &ci)
-	}
-
-	// Stop progress reporting
-	stopProgress <- struct{}{}
-
-	// run cleanup on channel exit
-	c.cleanup(false)
-
-	return nil
+	return c.collectPackets(r, path)
 }
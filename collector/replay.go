@@ -0,0 +1,158 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// replayClock maps the timestamps of a capture to the wall clock time at which the packets are replayed.
+type replayClock struct {
+	speed float64
+
+	// timestamp of the first packet in the capture
+	first time.Time
+
+	// wall clock time at which the first packet was replayed
+	start time.Time
+}
+
+// due returns the wall clock time at which a packet with the timestamp is replayed.
+// Packets with a timestamp before the first packet are due immediately.
+func (r *replayClock) due(ts time.Time) time.Time {
+	offset := ts.Sub(r.first)
+	if offset < 0 {
+		offset = 0
+	}
+
+	return r.start.Add(time.Duration(float64(offset) / r.speed))
+}
+
+// sleepUntil blocks until t and reports whether t was reached before the context was canceled.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// CollectReplay reads packets from the packet capture at path like Collect,
+// but delivers them at the pace of their original timestamps, sped up by the configured replay speed.
+// The timestamp of each packet is set to the time at which it is delivered,
+// so that flow and connection timeouts and flushes behave as in a live capture of the replayed traffic.
+// The replay stops at the end of the capture or when the context is canceled.
+func (c *Collector) CollectReplay(path string, ctx context.Context) error {
+	speed := c.config.ReplaySpeed
+	if speed == 0 {
+		speed = 1
+	}
+
+	if speed < 0 {
+		return errors.New("invalid replay speed: " + strconv.FormatFloat(speed, 'g', -1, 64))
+	}
+
+	src, srcPath, err := c.openSource(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		errClose := src.Close()
+		if errClose != nil && !errors.Is(errClose, io.EOF) {
+			fmt.Println(errClose)
+		}
+	}()
+
+	if err = c.initSource(src, srcPath); err != nil {
+		return err
+	}
+
+	c.printlnStdOut("replaying", path, "at", strconv.FormatFloat(speed, 'g', -1, 64)+"x speed")
+
+	c.mu.Lock()
+	c.isLive = true
+	c.mu.Unlock()
+
+	stopProgress := c.printProgressInterval()
+
+	var clock *replayClock
+
+	for { // fetch the next packet data and packet header
+		data, ci, errRead := src.ReadPacketData()
+		if errRead != nil {
+			if errors.Is(errRead, io.EOF) || errors.Is(errRead, io.ErrUnexpectedEOF) {
+				break
+			}
+
+			stopProgress <- struct{}{}
+
+			return errors.Wrap(errRead, errReadingPacketData+" file: "+path)
+		}
+
+		if clock == nil {
+			clock = &replayClock{
+				speed: speed,
+				first: ci.Timestamp,
+				start: time.Now(),
+			}
+		}
+
+		ci.Timestamp = clock.due(ci.Timestamp)
+
+		if !sleepUntil(ctx, ci.Timestamp) {
+			fmt.Println("replay canceled via context")
+
+			break
+		}
+
+		// increment atomic packet counter
+		atomic.AddInt64(&c.current, 1)
+
+		// must be locked, otherwise a race occurs when sending a SIGINT
+		//  and triggering wg.Wait() in another goroutine...
+		c.statMutex.Lock()
+
+		// increment wait group for packet processing
+		c.wg.Add(1)
+
+		c.statMutex.Unlock()
+
+		c.handleRawPacketData(data, &ci)
+	}
+
+	// Stop progress reporting
+	stopProgress <- struct{}{}
+
+	// run cleanup on channel exit
+	c.cleanup(false)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReplayClock(t *testing.T) {
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		speed  float64
+		offset time.Duration
+		want   time.Duration
+	}{
+		{speed: 1, offset: 0, want: 0},
+		{speed: 1, offset: 10 * time.Second, want: 10 * time.Second},
+		{speed: 2, offset: 10 * time.Second, want: 5 * time.Second},
+		{speed: 0.5, offset: 10 * time.Second, want: 20 * time.Second},
+		{speed: 100, offset: time.Second, want: 10 * time.Millisecond},
+		// out of order packets before the first packet are due immediately
+		{speed: 1, offset: -time.Second, want: 0},
+	}
+
+	for _, test := range tests {
+		clock := &replayClock{
+			speed: test.speed,
+			first: inputStart,
+			start: start,
+		}
+
+		if got := clock.due(inputStart.Add(test.offset)); !got.Equal(start.Add(test.want)) {
+			t.Fatal("speed", test.speed, "offset", test.offset, ": expected", start.Add(test.want), "got", got)
+		}
+	}
+}
+
+func TestSleepUntil(t *testing.T) {
+	if !sleepUntil(context.Background(), time.Now().Add(-time.Second)) {
+		t.Fatal("expected a time in the past to be reached")
+	}
+
+	begin := time.Now()
+	if !sleepUntil(context.Background(), begin.Add(50*time.Millisecond)) {
+		t.Fatal("expected the time to be reached")
+	}
+
+	if d := time.Since(begin); d < 50*time.Millisecond {
+		t.Fatal("returned after", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if sleepUntil(ctx, time.Now().Add(time.Hour)) {
+		t.Fatal("expected the canceled context to interrupt the sleep")
+	}
+
+	if sleepUntil(ctx, time.Now().Add(-time.Second)) {
+		t.Fatal("expected no packet to be delivered after the context was canceled")
+	}
+}
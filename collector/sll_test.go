@@ -0,0 +1,133 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package collector
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+
+	"github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/packet"
+)
+
+// sllPayload is an IPv4 and UDP packet from 10.0.0.1:1234 to 10.0.0.2:53.
+const sllPayload = "450000200000000040110000" + "0a000001" + "0a000002" + "04d20035000c0000" + "74657374"
+
+// writeLinkTypePcap writes a pcap file with a single packet for the link type number.
+// The header is written manually, because pcapgo only accepts link types up to 255.
+func writeLinkTypePcap(t *testing.T, path string, linkType uint32, data []byte) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:6], 2)
+	binary.LittleEndian.PutUint16(header[6:8], 4)
+	binary.LittleEndian.PutUint32(header[16:20], 65535)
+	binary.LittleEndian.PutUint32(header[20:24], linkType)
+
+	if _, err = f.Write(header); err != nil {
+		t.Fatal(err)
+	}
+
+	err = pcapgo.NewWriter(f).WritePacket(gopacket.CaptureInfo{
+		Timestamp:     time.Unix(1, 0),
+		CaptureLength: len(data),
+		Length:        len(data),
+	}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSLLPcaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-sll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name      string
+		linkType  uint32
+		header    string
+		baseLayer gopacket.LayerType
+	}{
+		{
+			name:     "sll",
+			linkType: 113,
+			// outgoing, ARPHRD_ETHER, address length 6, address, protocol IPv4
+			header:    "0004" + "0001" + "0006" + "00005e0053010000" + "0800",
+			baseLayer: layers.LayerTypeLinuxSLL,
+		},
+		{
+			name:     "sll2",
+			linkType: 276,
+			// protocol IPv4, reserved, interface index 2, ARPHRD_ETHER, outgoing, address length 6, address
+			header:    "0800" + "0000" + "00000002" + "0001" + "04" + "06" + "00005e0053010000",
+			baseLayer: packet.LayerTypeLinuxSLL2,
+		},
+	} {
+		var (
+			path    = filepath.Join(dir, tc.name+".pcap")
+			data, _ = hex.DecodeString(tc.header + sllPayload)
+			c       = New(Config{DecoderConfig: &config.Config{Quiet: true}})
+		)
+
+		writeLinkTypePcap(t, path, tc.linkType, data)
+
+		in, errOpen := openInput(path)
+		if errOpen != nil {
+			t.Fatal(tc.name, errOpen)
+		}
+
+		c.handleLinkType(in.LinkType())
+
+		if c.config.BaseLayer != tc.baseLayer {
+			t.Fatal(tc.name, "unexpected base layer", c.config.BaseLayer)
+		}
+
+		raw, _, errRead := in.ReadPacketData()
+		if errRead != nil {
+			t.Fatal(tc.name, errRead)
+		}
+
+		_ = in.Close()
+
+		p := gopacket.NewPacket(raw, c.config.BaseLayer, gopacket.Default)
+
+		if p.LinkLayer() == nil || p.LinkLayer().LinkFlow().Src().String() != "00:00:5e:00:53:01" {
+			t.Fatal(tc.name, "unexpected link layer", p.Layers())
+		}
+
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || udp.DstPort != 53 || p.NetworkLayer().NetworkFlow().Dst().String() != "10.0.0.2" {
+			t.Fatal(tc.name, "payload was not decoded", p.Layers())
+		}
+	}
+}
@@ -25,15 +25,46 @@ import (
 	"github.com/dreadl0ck/gopacket/pcap"
 	"github.com/gogo/protobuf/proto"
 	"golang.org/x/net/bpf"
+
+	"github.com/dreadl0ck/netcap/pcapng"
 )
 
 func (c *Collector) handleRawPacketData(data []byte, ci *gopacket.CaptureInfo) {
+	// pass packet to a worker routine
+	c.handlePacket(c.newPacket(data, ci))
+}
+
+// handleRawPacketDataWorker passes the packet to the worker with the index,
+// for packet sources that deliver all packets of a flow to the same worker.
+func (c *Collector) handleRawPacketDataWorker(data []byte, ci *gopacket.CaptureInfo, worker int) {
+	c.workers[worker%c.numWorkers] <- c.newPacket(data, ci)
+}
+
+// newPacket stores the raw packet if the packet store is enabled and returns the packet for decoding.
+func (c *Collector) newPacket(data []byte, ci *gopacket.CaptureInfo) gopacket.Packet {
+	if c.packetStore != nil {
+		c.storePacket(data, ci)
+	}
+
 	// when not using lazy here, the packet will be decoded on the main thread!
-	p := gopacket.NewPacket(data, c.config.BaseLayer, c.config.DecodeOptions)
+	p := gopacket.NewPacket(data, c.baseLayer(ci), c.config.DecodeOptions)
 	p.Metadata().CaptureInfo = *ci
 
-	// pass packet to a worker routine
-	c.handlePacket(p)
+	return p
+}
+
+// baseLayer returns the decoder for the first layer of a packet.
+func (c *Collector) baseLayer(ci *gopacket.CaptureInfo) gopacket.Decoder {
+	// the interfaces of a pcapng file can have different link types
+	if info := pcapng.Info(ci); info != nil {
+		if l, ok := linkTypeLayer(info.Interface.LinkType); ok {
+			return l
+		}
+
+		return info.Interface.LinkType
+	}
+
+	return c.config.BaseLayer
 }
 
 // printProgressLive prints live statistics.
@@ -15,12 +15,16 @@ package collector
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"go.uber.org/zap"
 
 	"github.com/dreadl0ck/netcap/decoder/packet"
 	"github.com/dreadl0ck/netcap/decoder/stream/tcp"
+	"github.com/dreadl0ck/netcap/pcapng"
 	"github.com/dreadl0ck/netcap/reassembly"
 	"github.com/dreadl0ck/netcap/types"
 	"github.com/dreadl0ck/netcap/utils"
@@ -32,18 +36,6 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 	var (
 		in  = make(chan gopacket.Packet, c.config.PacketBufferSize)
 		pkt gopacket.Packet
-
-		errLayer gopacket.ErrorLayer
-		err      error
-
-		decoders  []*packet.GoPacketDecoder
-		dec       *packet.GoPacketDecoder
-		customDec packet.DecoderAPI
-		ok        bool
-
-		netLayer       gopacket.NetworkLayer
-		transportLayer gopacket.TransportLayer
-		layer          gopacket.Layer
 	)
 
 	// start worker
@@ -58,137 +50,225 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 			pkt.Metadata().Length = pkt.Metadata().CaptureInfo.Length
 			pkt.Metadata().CaptureLength = pkt.Metadata().CaptureInfo.CaptureLength
 
-			// pass packet to reassembly
-			if c.config.ReassembleConnections {
-				t := time.Now()
-				tcp.ReassemblePacket(pkt, assembler)
-				reassemblyTime.WithLabelValues().Set(float64(time.Since(t).Nanoseconds()))
+			// the fragment completing an IPv6 datagram is replaced by the reassembled packet
+			if c.ip6Defragger != nil {
+				if reassembled, err := c.ip6Defragger.DefragIPv6(pkt, c.config.DecodeOptions); err != nil {
+					c.log.Debug("failed to defragment IPv6 packet", zap.Error(err))
+				} else if reassembled != nil {
+					pkt = reassembled
+				}
 			}
 
-			// create context for packet
-			ctx := &types.PacketContext{}
+			c.decodePacket(pkt, assembler, nil)
 
-			if c.config.DecoderConfig.AddContext {
-				netLayer = pkt.NetworkLayer()
-				transportLayer = pkt.TransportLayer()
+			c.wg.Done()
+		}
+	}()
 
-				if netLayer != nil {
-					ctx.SrcIP = netLayer.NetworkFlow().Src().String()
-					ctx.DstIP = netLayer.NetworkFlow().Dst().String()
-				}
+	// return input channel
+	return in
+}
 
-				if transportLayer != nil {
-					ctx.SrcPort = utils.DecodePort(transportLayer.TransportFlow().Src().Raw())
-					ctx.DstPort = utils.DecodePort(transportLayer.TransportFlow().Dst().Raw())
-				}
-			}
+// decodePacket passes the packet to reassembly and invokes all decoders for it.
+// If the packet carries a tunneled packet that should be decapsulated,
+// the layers of the inner packet are decoded afterwards with the tunnel information added to their context.
+func (c *Collector) decodePacket(pkt gopacket.Packet, assembler *reassembly.Assembler, tunnel *types.PacketContext) {
+	var (
+		errLayer gopacket.ErrorLayer
+		err      error
 
-			// iterate over all layers
-			for _, layer = range pkt.Layers() {
+		decoders  []*packet.GoPacketDecoder
+		dec       *packet.GoPacketDecoder
+		customDec packet.DecoderAPI
+		ok        bool
 
-				// increment counter for layer type
-				c.allProtosAtomic.Inc(layer.LayerType().String())
+		netLayer       gopacket.NetworkLayer
+		transportLayer gopacket.TransportLayer
+		layer          gopacket.Layer
 
-				if c.config.DecoderConfig.ExportMetrics {
-					allProtosTotal.WithLabelValues(layer.LayerType().String()).Inc()
-				}
+		inner    gopacket.Packet
+		gtpLayer gopacket.Layer
+	)
 
-				// check if packet contains an unknown layer
-				switch layer.LayerType() {
-				case gopacket.LayerTypeZero: // not known to gopacket
-					// increase counter
-					c.unknownProtosAtomic.Inc(layer.LayerType().String())
+	// pass packet to reassembly
+	// packets decapsulated from a GTP-U tunnel have already been reassembled as part of the outer packet,
+	// which carries the tunnel in its flow key.
+	if c.config.ReassembleConnections && tunnel == nil {
+		t := time.Now()
+		tcp.ReassemblePacket(pkt, assembler)
+		reassemblyTime.WithLabelValues().Set(float64(time.Since(t).Nanoseconds()))
+	}
 
-					if c.config.DecoderConfig.ExportMetrics {
-						unknownProtosTotal.WithLabelValues(layer.LayerType().String()).Inc()
-					}
+	// create context for packet
+	ctx := &types.PacketContext{}
 
-					// write to unknown.pcap file
-					if err = c.writePacketToUnknownPcap(pkt); err != nil {
-						fmt.Println("failed to write packet to unknown.pcap file:", err)
-					}
+	if c.config.DecoderConfig.AddContext {
+		netLayer = pkt.NetworkLayer()
+		transportLayer = pkt.TransportLayer()
 
-					// call custom decoders
-					goto done
-				case gopacket.LayerTypeDecodeFailure:
-					// call custom decoders
-					goto done
-				}
+		if netLayer != nil {
+			ctx.SrcIP = netLayer.NetworkFlow().Src().String()
+			ctx.DstIP = netLayer.NetworkFlow().Dst().String()
+		}
 
-				// pick decoders from the decoderMap by looking up the layer type
-				if decoders, ok = c.goPacketDecoders[layer.LayerType()]; ok {
-					for _, dec = range decoders {
-						t := time.Now()
-						err = dec.Decode(ctx, pkt, layer)
-						gopacketDecoderTime.WithLabelValues(layer.LayerType().String()).Set(float64(time.Since(t).Nanoseconds()))
-						if err != nil {
-							if c.config.DecoderConfig.ExportMetrics {
-								decodingErrorsTotal.WithLabelValues(layer.LayerType().String(), err.Error()).Inc()
-							}
-
-							if err = c.logPacketError(pkt, "GoPacketDecoder Error: "+layer.LayerType().String()+": "+err.Error()); err != nil {
-								fmt.Println("failed to log packet error:", err)
-							}
-
-							goto done
-						}
-					}
-				} else { // no netcap decoder implemented
+		if transportLayer != nil {
+			ctx.SrcPort = utils.DecodePort(transportLayer.TransportFlow().Src().Raw())
+			ctx.DstPort = utils.DecodePort(transportLayer.TransportFlow().Dst().Raw())
+			ctx.Transport = transportLayer.LayerType().String()
+		}
+	}
 
-					// increment unknown layer type counter
-					c.unknownProtosAtomic.Inc(layer.LayerType().String())
-					if c.config.DecoderConfig.ExportMetrics {
-						unknownProtosTotal.WithLabelValues(layer.LayerType().String()).Inc()
-					}
+	if tunnel != nil {
+		ctx.TEID = tunnel.TEID
+		ctx.TunnelSrcIP = tunnel.TunnelSrcIP
+		ctx.TunnelDstIP = tunnel.TunnelDstIP
+		ctx.Interface = tunnel.Interface
+		ctx.InterfaceID = tunnel.InterfaceID
+		ctx.Direction = tunnel.Direction
+		ctx.Comment = tunnel.Comment
+		ctx.DropCount = tunnel.DropCount
+	} else if info := pcapng.Info(&pkt.Metadata().CaptureInfo); info != nil {
+		ctx.Interface = info.Interface.String()
+		ctx.InterfaceID = int32(info.Interface.ID)
+		ctx.Direction = info.Direction().String()
+		ctx.Comment = strings.Join(info.Comments, "; ")
+		ctx.DropCount = info.DropCount
+	}
 
-					// if its not a payload layer, write to unknown .pcap file
-					if layer.LayerType() != gopacket.LayerTypePayload {
-						if err = c.writePacketToUnknownPcap(pkt); err != nil {
-							fmt.Println("failed to write packet to unknown.pcap file:", err)
-						}
-					}
-				}
-			} // END goPacket.Layers()
+	if c.config.DecoderConfig.DecapsulateGTP {
+		inner, gtpLayer = decapsulateGTP(pkt, c.config.DecodeOptions)
+	}
+
+	// iterate over all layers
+	for _, layer = range pkt.Layers() {
+
+		// increment counter for layer type
+		c.allProtosAtomic.Inc(layer.LayerType().String())
+
+		if c.config.DecoderConfig.ExportMetrics {
+			allProtosTotal.WithLabelValues(layer.LayerType().String()).Inc()
+		}
+
+		// check if packet contains an unknown layer
+		switch layer.LayerType() {
+		case gopacket.LayerTypeZero: // not known to gopacket
+			// increase counter
+			c.unknownProtosAtomic.Inc(layer.LayerType().String())
+
+			if c.config.DecoderConfig.ExportMetrics {
+				unknownProtosTotal.WithLabelValues(layer.LayerType().String()).Inc()
+			}
+
+			// write to unknown.pcap file
+			if err = c.writePacketToUnknownPcap(pkt); err != nil {
+				fmt.Println("failed to write packet to unknown.pcap file:", err)
+			}
 
-		done:
 			// call custom decoders
-			for _, customDec = range c.packetDecoders {
+			goto done
+		case gopacket.LayerTypeDecodeFailure:
+			// call custom decoders
+			goto done
+		}
+
+		// pick decoders from the decoderMap by looking up the layer type
+		if decoders, ok = c.goPacketDecoders[layer.LayerType()]; ok {
+			for _, dec = range decoders {
 				t := time.Now()
-				err = customDec.Decode(pkt)
-				customDecoderTime.WithLabelValues(customDec.GetName()).Set(float64(time.Since(t).Nanoseconds()))
+				err = dec.Decode(ctx, pkt, layer)
+				gopacketDecoderTime.WithLabelValues(layer.LayerType().String()).Set(float64(time.Since(t).Nanoseconds()))
 				if err != nil {
 					if c.config.DecoderConfig.ExportMetrics {
-						decodingErrorsTotal.WithLabelValues(customDec.GetName(), err.Error()).Inc()
+						decodingErrorsTotal.WithLabelValues(layer.LayerType().String(), err.Error()).Inc()
 					}
-					if err = c.logPacketError(pkt, "PacketDecoder Error: "+customDec.GetName()+": "+err.Error()); err != nil {
+
+					if err = c.logPacketError(pkt, "GoPacketDecoder Error: "+layer.LayerType().String()+": "+err.Error()); err != nil {
 						fmt.Println("failed to log packet error:", err)
 					}
 
-					continue
+					goto done
 				}
 			}
+		} else { // no netcap decoder implemented
 
-			// Check for errors after decoding all layers
-			// if an error has occurred while decoding the packet
-			// it will be logged and written into the errors.pcap file
-			if errLayer = pkt.ErrorLayer(); errLayer != nil {
-				if err = c.logPacketError(pkt, errLayer.Error().Error()); err != nil {
-					fmt.Println("failed to log packet error:", err)
-				}
+			// increment unknown layer type counter
+			c.unknownProtosAtomic.Inc(layer.LayerType().String())
+			if c.config.DecoderConfig.ExportMetrics {
+				unknownProtosTotal.WithLabelValues(layer.LayerType().String()).Inc()
+			}
 
-				if c.config.DecoderConfig.ExportMetrics {
-					decodingErrorsTotal.WithLabelValues(errLayer.LayerType().String(), errLayer.Error().Error()).Inc()
+			// if its not a payload layer, write to unknown .pcap file
+			if layer.LayerType() != gopacket.LayerTypePayload {
+				if err = c.writePacketToUnknownPcap(pkt); err != nil {
+					fmt.Println("failed to write packet to unknown.pcap file:", err)
 				}
 			}
+		}
 
-			c.wg.Done()
+		// the layers of the tunneled packet are decoded with the inner packet
+		if layer == gtpLayer {
+			goto done
+		}
+	} // END goPacket.Layers()
+
+done:
+	// call custom decoders
+	// they are only called for the outer packet of a GTP-U tunnel,
+	// which already tracks the tunneled flow by its innermost layers and the tunnel encapsulation.
+	// calling them for the decapsulated packet as well would produce a second record for the same flow.
+	for _, customDec = range c.customDecoders(tunnel) {
+		t := time.Now()
+		err = customDec.Decode(pkt)
+		customDecoderTime.WithLabelValues(customDec.GetName()).Set(float64(time.Since(t).Nanoseconds()))
+		if err != nil {
+			if c.config.DecoderConfig.ExportMetrics {
+				decodingErrorsTotal.WithLabelValues(customDec.GetName(), err.Error()).Inc()
+			}
+			if err = c.logPacketError(pkt, "PacketDecoder Error: "+customDec.GetName()+": "+err.Error()); err != nil {
+				fmt.Println("failed to log packet error:", err)
+			}
 
 			continue
 		}
-	}()
+	}
 
-	// return input channel
-	return in
+	// Check for errors after decoding all layers
+	// if an error has occurred while decoding the packet
+	// it will be logged and written into the errors.pcap file
+	if errLayer = pkt.ErrorLayer(); errLayer != nil {
+		if err = c.logPacketError(pkt, errLayer.Error().Error()); err != nil {
+			fmt.Println("failed to log packet error:", err)
+		}
+
+		if c.config.DecoderConfig.ExportMetrics {
+			decodingErrorsTotal.WithLabelValues(errLayer.LayerType().String(), errLayer.Error().Error()).Inc()
+		}
+	}
+
+	if inner != nil {
+		gtp := gtpLayer.(*layers.GTPv1U)
+		src, dst := pkt.NetworkLayer().NetworkFlow().Endpoints()
+
+		c.decodePacket(inner, assembler, &types.PacketContext{
+			TEID:        gtp.TEID,
+			TunnelSrcIP: src.String(),
+			TunnelDstIP: dst.String(),
+			Interface:   ctx.Interface,
+			InterfaceID: ctx.InterfaceID,
+			Direction:   ctx.Direction,
+			Comment:     ctx.Comment,
+			DropCount:   ctx.DropCount,
+		})
+	}
+}
+
+// customDecoders returns the custom decoders to call for a packet with the given tunnel context.
+func (c *Collector) customDecoders(tunnel *types.PacketContext) []packet.DecoderAPI {
+	if tunnel != nil {
+		return nil
+	}
+
+	return c.packetDecoders
 }
 
 // spawn the configured number of workers.
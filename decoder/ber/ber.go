@@ -0,0 +1,303 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package ber implements a lenient parser for the ASN.1 Basic Encoding Rules,
+// as used by protocols such as Kerberos, LDAP and SNMP.
+// In contrast to encoding/asn1 it accepts the non-minimal length encodings
+// that are commonly emitted by real world implementations.
+package ber
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Tag classes.
+const (
+	ClassUniversal   = 0
+	ClassApplication = 1
+	ClassContext     = 2
+	ClassPrivate     = 3
+)
+
+// Universal tags.
+const (
+	TagBoolean     = 1
+	TagInteger     = 2
+	TagBitString   = 3
+	TagOctetString = 4
+	TagNull        = 5
+	TagOID         = 6
+	TagEnumerated  = 10
+	TagSequence    = 16
+	TagSet         = 17
+)
+
+var (
+	// ErrTruncated is returned if the data ends before the element is complete.
+	ErrTruncated = errors.New("ber: truncated element")
+
+	// ErrUnsupported is returned for indefinite lengths and oversized length fields.
+	ErrUnsupported = errors.New("ber: unsupported length encoding")
+
+	// ErrMaxDepth is returned if nested elements exceed MaxDepth.
+	ErrMaxDepth = errors.New("ber: maximum nesting depth exceeded")
+)
+
+// MaxDepth limits the nesting of constructed elements when parsing recursively.
+const MaxDepth = 64
+
+// Element is a single BER encoded type-length-value structure.
+type Element struct {
+	Class       int
+	Constructed bool
+	Tag         int
+
+	// Content holds the value bytes
+	Content []byte
+
+	// Size is the total number of bytes including the header
+	Size int
+}
+
+// Parse decodes the element at the beginning of data and returns the remaining bytes.
+func Parse(data []byte) (*Element, []byte, error) {
+	if len(data) < 2 {
+		return nil, data, ErrTruncated
+	}
+
+	var (
+		e   = &Element{}
+		pos = 1
+	)
+
+	e.Class = int(data[0] >> 6)
+	e.Constructed = data[0]&0x20 != 0
+	e.Tag = int(data[0] & 0x1f)
+
+	// high tag number form
+	if e.Tag == 0x1f {
+		e.Tag = 0
+
+		for {
+			if pos >= len(data) || pos > 5 {
+				return nil, data, ErrTruncated
+			}
+
+			b := data[pos]
+			pos++
+
+			e.Tag = e.Tag<<7 | int(b&0x7f)
+
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	if pos >= len(data) {
+		return nil, data, ErrTruncated
+	}
+
+	length := int(data[pos])
+	pos++
+
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return nil, data, ErrUnsupported
+		}
+
+		if pos+n > len(data) {
+			return nil, data, ErrTruncated
+		}
+
+		length = 0
+		for _, b := range data[pos : pos+n] {
+			length = length<<8 | int(b)
+		}
+
+		pos += n
+	}
+
+	if length < 0 || pos+length > len(data) {
+		return nil, data, ErrTruncated
+	}
+
+	e.Content = data[pos : pos+length]
+	e.Size = pos + length
+
+	return e, data[e.Size:], nil
+}
+
+// Children parses the content of a constructed element into its child elements.
+func (e *Element) Children() ([]*Element, error) {
+	var (
+		children []*Element
+		data     = e.Content
+	)
+
+	for len(data) > 0 {
+		c, rest, err := Parse(data)
+		if err != nil {
+			return children, err
+		}
+
+		children = append(children, c)
+		data = rest
+	}
+
+	return children, nil
+}
+
+// Is checks the class and tag of the element.
+func (e *Element) Is(class, tag int) bool {
+	return e != nil && e.Class == class && e.Tag == tag
+}
+
+// Int decodes the content as a two's complement integer.
+// Values exceeding 64 bits are truncated.
+func (e *Element) Int() int64 {
+	if e == nil || len(e.Content) == 0 {
+		return 0
+	}
+
+	var v int64
+
+	// sign extension
+	if e.Content[0]&0x80 != 0 {
+		v = -1
+	}
+
+	for _, b := range e.Content {
+		v = v<<8 | int64(b)
+	}
+
+	return v
+}
+
+// Uint decodes the content as an unsigned integer, as used for SNMP counters.
+// Values exceeding 64 bits are truncated.
+func (e *Element) Uint() uint64 {
+	if e == nil {
+		return 0
+	}
+
+	var v uint64
+	for _, b := range e.Content {
+		v = v<<8 | uint64(b)
+	}
+
+	return v
+}
+
+// OID decodes the content as object identifier in dotted notation.
+// An empty string is returned for malformed content.
+func (e *Element) OID() string {
+	if e == nil || len(e.Content) == 0 {
+		return ""
+	}
+
+	var (
+		b     strings.Builder
+		v     uint64
+		first = true
+	)
+
+	for i, c := range e.Content {
+		// reject subidentifiers that do not fit into 64 bits
+		if v > 1<<57 {
+			return ""
+		}
+
+		v = v<<7 | uint64(c&0x7f)
+
+		if c&0x80 != 0 {
+			// the last subidentifier is incomplete
+			if i == len(e.Content)-1 {
+				return ""
+			}
+
+			continue
+		}
+
+		if first {
+			// the first subidentifier encodes the first two arcs
+			arc := v / 40
+			if arc > 2 {
+				arc = 2
+			}
+
+			b.WriteString(strconv.FormatUint(arc, 10))
+			b.WriteByte('.')
+			b.WriteString(strconv.FormatUint(v-arc*40, 10))
+
+			first = false
+		} else {
+			b.WriteByte('.')
+			b.WriteString(strconv.FormatUint(v, 10))
+		}
+
+		v = 0
+	}
+
+	return b.String()
+}
+
+// Bool decodes the content as a boolean.
+func (e *Element) Bool() bool {
+	return e != nil && len(e.Content) > 0 && e.Content[0] != 0
+}
+
+// String returns the content as string.
+func (e *Element) String() string {
+	if e == nil {
+		return ""
+	}
+
+	return string(e.Content)
+}
+
+// Explicit returns the content of the first context specific child with the given tag.
+// For EXPLICIT tagging, this is the single element wrapped by the tag.
+func Explicit(children []*Element, tag int) *Element {
+	for _, c := range children {
+		if c.Is(ClassContext, tag) {
+			if !c.Constructed {
+				return c
+			}
+
+			inner, _, err := Parse(c.Content)
+			if err != nil {
+				return nil
+			}
+
+			return inner
+		}
+	}
+
+	return nil
+}
+
+// Context returns the first context specific child with the given tag,
+// as used with IMPLICIT tagging.
+func Context(children []*Element, tag int) *Element {
+	for _, c := range children {
+		if c.Is(ClassContext, tag) {
+			return c
+		}
+	}
+
+	return nil
+}
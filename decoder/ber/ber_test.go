@@ -0,0 +1,98 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ber
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	// SEQUENCE { INTEGER 5, [1] { OCTET STRING "abc" } } with a non-minimal long form length
+	data := []byte{0x30, 0x84, 0x00, 0x00, 0x00, 0x0a, 0x02, 0x01, 0x05, 0xa1, 0x05, 0x04, 0x03, 'a', 'b', 'c', 0xff}
+
+	e, rest, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !e.Is(ClassUniversal, TagSequence) || !e.Constructed {
+		t.Fatal("unexpected element", e.Class, e.Tag)
+	}
+
+	if len(rest) != 1 || e.Size != len(data)-1 {
+		t.Fatal("unexpected remainder", rest, e.Size)
+	}
+
+	children, err := e.Children()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(children) != 2 || children[0].Int() != 5 {
+		t.Fatal("unexpected children", children)
+	}
+
+	if s := Explicit(children, 1).String(); s != "abc" {
+		t.Fatal("unexpected explicit value", s)
+	}
+
+	if Explicit(children, 2) != nil {
+		t.Fatal("expected missing element to be nil")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, _, err := Parse([]byte{0x30, 0x05, 0x02, 0x01}); err != ErrTruncated {
+		t.Fatal("expected truncated error", err)
+	}
+
+	if _, _, err := Parse([]byte{0x30, 0x80, 0x00, 0x00}); err != ErrUnsupported {
+		t.Fatal("expected unsupported error for indefinite length", err)
+	}
+}
+
+func TestInt(t *testing.T) {
+	e := &Element{Content: []byte{0xff, 0x7f}}
+	if e.Int() != -129 {
+		t.Fatal("unexpected negative value", e.Int())
+	}
+
+	e = &Element{Content: []byte{0x00, 0x80}}
+	if e.Int() != 128 {
+		t.Fatal("unexpected positive value", e.Int())
+	}
+}
+
+func TestOID(t *testing.T) {
+	for _, c := range []struct {
+		in  []byte
+		out string
+	}{
+		// sysUpTime.0
+		{[]byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00}, "1.3.6.1.2.1.1.3.0"},
+		// multi byte subidentifier
+		{[]byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37}, "1.3.6.1.4.1.311"},
+		{[]byte{0x88, 0x37, 0x03}, "2.999.3"},
+		// truncated subidentifier
+		{[]byte{0x2b, 0x86}, ""},
+	} {
+		if oid := (&Element{Content: c.in}).OID(); oid != c.out {
+			t.Fatal("unexpected oid", oid, "expected", c.out)
+		}
+	}
+
+	if v := (&Element{Content: []byte{0x00, 0xff, 0xff, 0xff, 0xff}}).Uint(); v != 0xffffffff {
+		t.Fatal("unexpected unsigned value", v)
+	}
+}
@@ -42,6 +42,10 @@ var DefaultConfig = &Config{
 	AddContext:                 true,
 	FlushEvery:                 100,
 	DefragIPv4:                 false,
+	DefragIPv6:                 false,
+	IPFIXExport:                "",
+	IPFIXActiveTimeout:         defaults.IPFIXActiveTimeout,
+	DecapsulateGTP:             true,
 	Checksum:                   false,
 	NoOptCheck:                 false,
 	IgnoreFSMerr:               false,
@@ -70,6 +74,9 @@ var DefaultConfig = &Config{
 	RemoveClosedStreams:        false,
 	CompressionBlockSize:       defaults.CompressionBlockSize,
 	CompressionLevel:           defaults.CompressionLevel,
+	CompressionCodec:           defaults.CompressionCodec,
+	ParquetCompression:         defaults.ParquetCompression,
+	ParquetRowGroupSize:        defaults.ParquetRowGroupSize,
 	NumStreamWorkers:           runtime.NumCPU(),
 	StreamBufferSize:           100,
 }
@@ -187,6 +194,18 @@ type Config struct {
 	// Output JSON
 	JSON bool
 
+	// Output Zeek logs for audit records with Zeek equivalents
+	Zeek bool
+
+	// Output Suricata EVE JSON events for audit records with EVE equivalents
+	EVE bool
+
+	// Output Apache Parquet files
+	Parquet bool
+
+	// Output a single SQLite database with one table per audit record type
+	SQLite bool
+
 	// Discard all data and write nothing to disk
 	Null bool
 
@@ -226,6 +245,18 @@ type Config struct {
 	// Defragment IPv4 packets
 	DefragIPv4 bool
 
+	// Defragment IPv6 packets
+	DefragIPv6 bool
+
+	// Export Connection audit records as IPFIX to the collector at this address
+	IPFIXExport string
+
+	// Maximum time a connection is buffered before it is sent to the IPFIX collector
+	IPFIXActiveTimeout time.Duration
+
+	// Decode the packets tunneled in GTP-U separately
+	DecapsulateGTP bool
+
 	// ExportMetrics will export prometheus metrics
 	ExportMetrics bool
 
@@ -235,6 +266,9 @@ type Config struct {
 	// Compress data before writing it to disk with gzip
 	Compression bool
 
+	// Write protobuf audit records in indexed, compressed blocks to allow reading time ranges
+	Index bool
+
 	// IgnoreDecoderInitErrors allows to control whether to crash on Custom Decoder initialization errors (usually caused by missing database files)
 	// and enables users to use the decoders even if the files are not present, while just logging an error to stdout.
 	// If the init error does not allow the decoder to function at least partially,
@@ -265,4 +299,16 @@ type Config struct {
 
 	// CompressionLevel is the compression level to use by default
 	CompressionLevel int
+
+	// CompressionCodec is the codec used for compressed output: gzip, zstd or lz4
+	CompressionCodec string
+
+	// CompressionDict is an optional dictionary for the zstd codec
+	CompressionDict []byte
+
+	// ParquetCompression is the codec used for Parquet pages: none, snappy, gzip or zstd
+	ParquetCompression string
+
+	// ParquetRowGroupSize is the amount of uncompressed data per row group in Parquet files
+	ParquetRowGroupSize int
 }
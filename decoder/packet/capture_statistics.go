@@ -0,0 +1,69 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/pcapng"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// captureStatisticsDecoder does not decode packets, the collector passes
+// the Interface Statistics Blocks of pcapng input files to WriteCaptureStatistics.
+var captureStatisticsDecoder = newPacketDecoder(
+	types.Type_NC_CaptureStatistics,
+	"CaptureStatistics",
+	"Interface statistics recorded by the capture tool, read from pcapng Interface Statistics Blocks",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		return nil
+	},
+	nil,
+)
+
+// WriteCaptureStatistics writes an audit record for the statistics of a capture interface,
+// if the CaptureStatistics decoder is enabled.
+func WriteCaptureStatistics(iface *pcapng.Interface, stats *pcapng.InterfaceStatistics) {
+	if captureStatisticsDecoder.Writer == nil || !isPacketDecoderLoaded(captureStatisticsDecoder.GetName()) {
+		return
+	}
+
+	captureStatisticsDecoder.write(&types.CaptureStatistics{
+		Timestamp:      stats.Timestamp.UnixNano(),
+		Interface:      iface.String(),
+		InterfaceID:    int32(iface.ID),
+		LinkType:       iface.LinkType.String(),
+		StartTime:      unixNano(stats.StartTime),
+		EndTime:        unixNano(stats.EndTime),
+		Received:       stats.Received,
+		Dropped:        stats.Dropped,
+		FilterAccepted: stats.FilterAccepted,
+		OSDropped:      stats.OSDropped,
+		Delivered:      stats.Delivered,
+		Comment:        stats.Comment,
+	})
+}
+
+// unixNano returns the unix timestamp in nanoseconds, or zero for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
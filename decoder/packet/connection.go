@@ -25,22 +25,31 @@ import (
 	"time"
 
 	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/ja3"
 	"github.com/gogo/protobuf/proto"
 
+	"github.com/dreadl0ck/netcap/netflow"
 	"github.com/dreadl0ck/netcap/types"
 )
 
 // connectionID is a bidirectional connection
 // between two devices over the network
-// that includes the Link, Network and TransportLayer.
+// that includes the Link, Network and TransportLayer,
+// as well as the tags and tunnels the packets were carried in.
 type connectionID struct {
 	LinkFlowID      uint64
 	NetworkFlowID   uint64
 	TransportFlowID uint64
+	Encapsulation   string
 }
 
 func (c connectionID) String() string {
-	return strconv.FormatUint(c.LinkFlowID, 10) + strconv.FormatUint(c.NetworkFlowID, 10) + strconv.FormatUint(c.TransportFlowID, 10)
+	id := strconv.FormatUint(c.LinkFlowID, 10) + strconv.FormatUint(c.NetworkFlowID, 10) + strconv.FormatUint(c.TransportFlowID, 10)
+	if c.Encapsulation != "" {
+		return id + "@" + c.Encapsulation
+	}
+
+	return id
 }
 
 type connection struct {
@@ -48,6 +57,9 @@ type connection struct {
 	*types.Connection
 	clientIP string
 
+	// JA3 fingerprint of the client hello, only tracked for the IPFIX export
+	ja3 string
+
 	// to break the initialization loop when accessing the connectionDecoder variable within the connection processor
 	// we simply set a reference to it when passing connections to the workers.
 	decoder *Decoder
@@ -71,11 +83,36 @@ var conns = &atomicConnMap{
 	Items: make(map[string]*connection),
 }
 
+// ipfixExporter sends the connections to an IPFIX collector, if configured.
+var ipfixExporter *netflow.Exporter
+
+// number of packets per connection that are checked for a TLS client hello.
+const maxJA3Packets = 10
+
 var connectionDecoder = newPacketDecoder(
 	types.Type_NC_Connection,
 	"Connection",
 	"A connection represents bi-directional network communication between two hosts based on the combined link-, network- and transport layer identifiers",
-	nil,
+	func(decoder *Decoder) error {
+		if decoderconfig.Instance.IPFIXExport == "" {
+			return nil
+		}
+
+		var err error
+
+		ipfixExporter, err = netflow.NewExporter(decoderconfig.Instance.IPFIXExport)
+		if err != nil {
+			return err
+		}
+
+		if decoderconfig.Instance.IPFIXActiveTimeout > 0 {
+			ipfixExporter.ActiveTimeout = decoderconfig.Instance.IPFIXActiveTimeout
+		}
+
+		ipfixExporter.Start()
+
+		return nil
+	},
 	func(p gopacket.Packet) proto.Message {
 		return handlePacket(p)
 	},
@@ -93,24 +130,37 @@ var connectionDecoder = newPacketDecoder(
 		conns.Unlock()
 		cp.wg.Wait()
 
+		if ipfixExporter != nil {
+			if err := ipfixExporter.Close(); err != nil {
+				return err
+			}
+
+			if !decoderconfig.Instance.Quiet {
+				fmt.Println("\nexported", ipfixExporter.NumExported(), "connections via IPFIX to", decoderconfig.Instance.IPFIXExport)
+			}
+		}
+
 		return nil
 	},
 )
 
 func handlePacket(p gopacket.Packet) proto.Message {
+	// tunneled packets are tracked by their innermost network and transport layers
+	encapsulation, nl, tl := utils.DecodeEncapsulation(p)
+
 	// assemble connectionID
-	connID := connectionID{}
+	connID := connectionID{
+		Encapsulation: encapsulation.Key(),
+	}
 	ll := p.LinkLayer()
 	if ll != nil {
 		connID.LinkFlowID = ll.LinkFlow().FastHash()
 	}
 
-	nl := p.NetworkLayer()
 	if nl != nil {
 		connID.NetworkFlowID = nl.NetworkFlow().FastHash()
 	}
 
-	tl := p.TransportLayer()
 	if tl != nil {
 		connID.TransportFlowID = tl.TransportFlow().FastHash()
 	}
@@ -146,6 +196,8 @@ func handlePacket(p gopacket.Packet) proto.Message {
 				conn.SrcPort = tl.TransportFlow().Src().String()
 				conn.DstPort = tl.TransportFlow().Dst().String()
 			}
+
+			conn.Encapsulation = encapsulation.String()
 		}
 
 		// track amount of transferred bytes
@@ -161,7 +213,8 @@ func handlePacket(p gopacket.Packet) proto.Message {
 			}
 		}
 		conn.NumPackets++
-		trackTCPStats(conn.Connection, p)
+		trackTCPStats(conn.Connection, tl)
+		trackJA3(conn, p)
 		conn.TotalSize += int32(p.Metadata().Length)
 
 		// check if LAST timestamp was before the current packet
@@ -182,7 +235,8 @@ func handlePacket(p gopacket.Packet) proto.Message {
 		co.TimestampLast = p.Metadata().Timestamp.UnixNano()
 		co.TotalSize = int32(p.Metadata().Length)
 		co.NumPackets = 1
-		trackTCPStats(co, p)
+		co.Encapsulation = encapsulation.String()
+		trackTCPStats(co, tl)
 
 		if ll != nil {
 			co.LinkProto = ll.LayerType().String()
@@ -207,10 +261,13 @@ func handlePacket(p gopacket.Packet) proto.Message {
 		// track amount of transferred bytes
 		co.BytesClientToServer += int64(p.Metadata().Length)
 
-		conns.Items[connID.String()] = &connection{
+		conn := &connection{
 			Connection: co,
 			clientIP:   co.SrcIP,
 		}
+		trackJA3(conn, p)
+
+		conns.Items[connID.String()] = conn
 
 		// TODO: add dedicated stats structure for decoder pkg
 		// conns := atomic.AddInt64(&stream.stats.numConns, 1)
@@ -225,8 +282,8 @@ func handlePacket(p gopacket.Packet) proto.Message {
 	return nil
 }
 
-func trackTCPStats(co *types.Connection, p gopacket.Packet) {
-	if t, ok := p.TransportLayer().(*layers.TCP); ok {
+func trackTCPStats(co *types.Connection, tl gopacket.TransportLayer) {
+	if t, ok := tl.(*layers.TCP); ok {
 		if t.ACK {
 			co.NumACKFlags++
 		}
@@ -262,6 +319,17 @@ func trackTCPStats(co *types.Connection, p gopacket.Packet) {
 	}
 }
 
+// trackJA3 fingerprints the first TLS client hello of the connection for the IPFIX export.
+func trackJA3(conn *connection, p gopacket.Packet) {
+	if ipfixExporter == nil || conn.ja3 != "" || conn.NumPackets > maxJA3Packets || conn.TransportProto != layers.LayerTypeTCP.String() {
+		return
+	}
+
+	if p.ApplicationLayer() != nil {
+		conn.ja3 = ja3.DigestHexPacket(p)
+	}
+}
+
 func movingAverage(current int32, newValue int32, n int32) int32 {
 	return (current + (newValue - current)) / n
 }
@@ -367,6 +435,12 @@ func (cp *connectionProcessor) connectionWorker(wg *sync.WaitGroup) chan *connec
 
 			conn.decoder.writeConn(conn.Connection, conn.clientIP)
 
+			if ipfixExporter != nil {
+				if err := ipfixExporter.Export(conn.Connection, conn.ja3); err != nil {
+					fmt.Println("failed to export connection via IPFIX:", err)
+				}
+			}
+
 			cp.Lock()
 			cp.numDone++
 
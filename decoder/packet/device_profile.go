@@ -48,6 +48,21 @@ func (a *atomicDeviceProfileMap) Size() int {
 	return len(a.Items)
 }
 
+// deviceDiscovery holds the information a device announced about itself
+// via local service discovery protocols such as mDNS, LLMNR, NBNS or SSDP.
+type deviceDiscovery struct {
+	hostnames []string
+	models    []string
+	services  []string
+}
+
+// atomicDeviceDiscoveryMap contains all device discoveries and provides synchronized access.
+type atomicDeviceDiscoveryMap struct {
+	sync.Mutex
+	// SrcMAC to deviceDiscoveries
+	Items map[string]*deviceDiscovery
+}
+
 var (
 	// DeviceProfiles hold all connections.
 	DeviceProfiles = &atomicDeviceProfileMap{
@@ -55,6 +70,11 @@ var (
 	}
 	deviceProfiles int64
 
+	// DeviceDiscoveries hold names, models and services announced by devices.
+	DeviceDiscoveries = &atomicDeviceDiscoveryMap{
+		Items: make(map[string]*deviceDiscovery),
+	}
+
 	// flags for flushing intervals - no flushing for now.
 	// flagProfileFlushInterval = flag.Int("profile-flush-interval", 10000, "flush connections every X flows").
 
@@ -120,6 +140,50 @@ func newDeviceProfile(i *decoderutils.PacketInfo) *deviceProfile {
 	}
 }
 
+// addDeviceDiscovery records hostnames, models and services announced by the device with the given MAC address.
+// The information is attached to the device profile when the profiles are flushed.
+func addDeviceDiscovery(macAddr string, hostnames, models, services []string) {
+	if macAddr == "" || len(hostnames)+len(models)+len(services) == 0 {
+		return
+	}
+
+	DeviceDiscoveries.Lock()
+	defer DeviceDiscoveries.Unlock()
+
+	d, ok := DeviceDiscoveries.Items[macAddr]
+	if !ok {
+		d = new(deviceDiscovery)
+		DeviceDiscoveries.Items[macAddr] = d
+	}
+
+	d.hostnames = appendUnique(d.hostnames, hostnames...)
+	d.models = appendUnique(d.models, models...)
+	d.services = appendUnique(d.services, services...)
+}
+
+// applyDeviceDiscovery attaches the announced information for the device to its profile.
+func applyDeviceDiscovery(dp *types.DeviceProfile) {
+	DeviceDiscoveries.Lock()
+	defer DeviceDiscoveries.Unlock()
+
+	if d, ok := DeviceDiscoveries.Items[dp.MacAddr]; ok {
+		dp.Hostnames = appendUnique(dp.Hostnames, d.hostnames...)
+		dp.DeviceModels = appendUnique(dp.DeviceModels, d.models...)
+		dp.Services = appendUnique(dp.Services, d.services...)
+	}
+}
+
+// appendUnique appends all non empty values to list that are not yet contained in it.
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if v != "" && !containsString(list, v) {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
 func applyDeviceProfileUpdate(p *deviceProfile, i *decoderutils.PacketInfo) {
 	p.Lock()
 
@@ -188,6 +252,7 @@ var deviceProfileDecoder = newPacketDecoder(
 		// flush writer
 		for _, item := range DeviceProfiles.Items {
 			item.Lock()
+			applyDeviceDiscovery(item.DeviceProfile)
 			d.writeDeviceProfile(item.DeviceProfile)
 			item.Unlock()
 		}
@@ -178,6 +178,10 @@ func InitGoPacketDecoders(c *config.Config) (decoders map[gopacket.LayerType][]*
 				Label:      c.Label,
 				Proto:      c.Proto,
 				JSON:       c.JSON,
+				Zeek:       c.Zeek,
+				EVE:        c.EVE,
+				Parquet:    c.Parquet,
+				SQLite:     c.SQLite,
 				Chan:       c.Chan,
 				Null:       c.Null,
 				Elastic:    c.Elastic,
@@ -189,8 +193,10 @@ func InitGoPacketDecoders(c *config.Config) (decoders map[gopacket.LayerType][]*
 					BulkSize:       c.BulkSizeGoPacket,
 				},
 				Name:                 filename,
+				Type:                 dec.Type,
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
+				Index:                c.Index,
 				Out:                  c.Out,
 				MemBufferSize:        c.MemBufferSize,
 				Source:               c.Source,
@@ -199,6 +205,10 @@ func InitGoPacketDecoders(c *config.Config) (decoders map[gopacket.LayerType][]*
 				StartTime:            time.Now(),
 				CompressionBlockSize: c.CompressionBlockSize,
 				CompressionLevel:     c.CompressionLevel,
+				CompressionCodec:     c.CompressionCodec,
+				CompressionDict:      c.CompressionDict,
+				ParquetCompression:   c.ParquetCompression,
+				ParquetRowGroupSize:  c.ParquetRowGroupSize,
 			})
 
 			// write netcap header
@@ -0,0 +1,84 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseGTPv2CreateSession(t *testing.T) {
+	ies, _ := hex.DecodeString(
+		// IMSI 001010123456789
+		"01000800" + "000101214365" + "87f9" +
+			// MSISDN 491234567890
+			"4c000600" + "942143658709" +
+			// RAT type EUTRAN
+			"52000100" + "06" +
+			// serving network 001-01
+			"53000300" + "00f110" +
+			// F-TEID of the MME S11 interface
+			"57000900" + "8a" + "12345678" + "0a000001" +
+			// APN internet.com
+			"47000d00" + "08696e7465726e6574" + "03636f6d" +
+			// PAA IPv4
+			"4f000500" + "01" + "0a2d0001" +
+			// bearer context with EBI 5 and the S1-U SGW F-TEID
+			"5d001200" + "49000100" + "05" + "57000901" + "81" + "0000abcd" + "c0a80001",
+	)
+
+	data := []byte{0x48, 32, 0, 0, 0, 0, 0, 0, 0x00, 0x00, 0x01, 0x00}
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(ies)+8))
+	data = append(data, ies...)
+
+	g, err := parseGTPv2C(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.MessageType != "Create Session Request" || g.SequenceNumber != 1 || g.TEID != 0 {
+		t.Fatal("unexpected header", g)
+	}
+
+	if g.IMSI != "001010123456789" || g.MSISDN != "491234567890" || g.APN != "internet.com" {
+		t.Fatal("unexpected subscriber", g.IMSI, g.MSISDN, g.APN)
+	}
+
+	if g.RATType != "EUTRAN" || g.ServingNetwork != "001-01" || g.PDNAddress != "10.45.0.1" {
+		t.Fatal("unexpected session", g.RATType, g.ServingNetwork, g.PDNAddress)
+	}
+
+	if len(g.FTEIDs) != 2 || g.FTEIDs[0] != "S11 MME GTP-C:305419896@10.0.0.1" || g.FTEIDs[1] != "S1-U SGW GTP-U:43981@192.168.0.1" {
+		t.Fatal("unexpected F-TEIDs", g.FTEIDs)
+	}
+
+	if len(g.BearerIDs) != 1 || g.BearerIDs[0] != 5 {
+		t.Fatal("unexpected bearers", g.BearerIDs)
+	}
+
+	// GTPv1-C messages are not handled
+	if _, err = parseGTPv2C([]byte{0x32, 0x10, 0x00, 0x04, 0, 0, 0, 0, 0, 0, 0, 0}); err != errGTPv2Version {
+		t.Fatal("expected version error, got", err)
+	}
+}
+
+func TestInnerIPHeader(t *testing.T) {
+	data, _ := hex.DecodeString("4500001c00000000401100000a2d00010808080800350035000800000000")
+
+	src, dst, proto := innerIPHeader(data)
+	if src != "10.45.0.1" || dst != "8.8.8.8" || proto != 17 {
+		t.Fatal("unexpected inner header", src, dst, proto)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// GTPv1-U message types from 3GPP TS 29.281 section 6.1.
+var gtpv1uMessageTypes = map[uint8]string{
+	1:   "Echo Request",
+	2:   "Echo Response",
+	26:  "Error Indication",
+	31:  "Supported Extension Headers Notification",
+	253: "Tunnel Status",
+	254: "End Marker",
+	255: "G-PDU",
+}
+
+var gtpv1uDecoder = newGoPacketDecoder(
+	types.Type_NC_GTPv1U,
+	layers.LayerTypeGTPv1U,
+	"The GPRS Tunnelling Protocol user plane carries the traffic of mobile subscribers between the radio access network and the core network",
+	func(layer gopacket.Layer, timestamp int64) proto.Message {
+		if gtp, ok := layer.(*layers.GTPv1U); ok {
+			g := &types.GTPv1U{
+				Timestamp:      timestamp,
+				Version:        int32(gtp.Version),
+				ProtocolType:   int32(gtp.ProtocolType),
+				MessageType:    gtpv1uMessageType(gtp.MessageType),
+				MessageLength:  int32(gtp.MessageLength),
+				TEID:           gtp.TEID,
+				SequenceNumber: int32(gtp.SequenceNumber),
+				NPDU:           int32(gtp.NPDU),
+			}
+
+			for _, eh := range gtp.GTPExtensionHeaders {
+				g.ExtensionHeaders = append(g.ExtensionHeaders, int32(eh.Type))
+			}
+
+			g.InnerSrcIP, g.InnerDstIP, g.InnerProtocol = innerIPHeader(gtp.LayerPayload())
+
+			return g
+		}
+
+		return nil
+	},
+)
+
+func gtpv1uMessageType(t uint8) string {
+	if s, ok := gtpv1uMessageTypes[t]; ok {
+		return s
+	}
+
+	return "Unknown(" + strconv.Itoa(int(t)) + ")"
+}
+
+// innerIPHeader returns the addresses and the protocol from the header of a tunneled IPv4 or IPv6 packet.
+func innerIPHeader(data []byte) (src, dst string, protocol int32) {
+	const (
+		ipv4HeaderLength = 20
+		ipv6HeaderLength = 40
+	)
+
+	if len(data) == 0 {
+		return
+	}
+
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) >= ipv4HeaderLength {
+			return net.IP(data[12:16]).String(), net.IP(data[16:20]).String(), int32(data[9])
+		}
+	case 6:
+		if len(data) >= ipv6HeaderLength {
+			return net.IP(data[8:24]).String(), net.IP(data[24:40]).String(), int32(data[6])
+		}
+	}
+
+	return
+}
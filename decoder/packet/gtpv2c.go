@@ -0,0 +1,397 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	gtpcPort = 2123
+
+	gtpv2Version = 2
+
+	// header flags
+	gtpv2FlagTEID = 0x08
+
+	gtpv2HeaderLength     = 8
+	gtpv2HeaderLengthTEID = 12
+	gtpv2IEHeaderLength   = 4
+
+	// information element types from 3GPP TS 29.274 section 8.1.
+	gtpv2IEIMSI           = 1
+	gtpv2IECause          = 2
+	gtpv2IEAPN            = 71
+	gtpv2IEEBI            = 73
+	gtpv2IEMEI            = 75
+	gtpv2IEMSISDN         = 76
+	gtpv2IEPAA            = 79
+	gtpv2IERATType        = 82
+	gtpv2IEServingNetwork = 83
+	gtpv2IEFTEID          = 87
+	gtpv2IEBearerContext  = 93
+
+	// PDN types of the PDN address allocation
+	gtpv2PDNTypeIPv4   = 1
+	gtpv2PDNTypeIPv6   = 2
+	gtpv2PDNTypeIPv4v6 = 3
+)
+
+var (
+	errGTPv2Version = errors.New("not a GTPv2 message")
+	errGTPv2Short   = errors.New("GTPv2 message too short")
+
+	gtpv2MessageTypes = map[uint8]string{
+		1:   "Echo Request",
+		2:   "Echo Response",
+		3:   "Version Not Supported Indication",
+		32:  "Create Session Request",
+		33:  "Create Session Response",
+		34:  "Modify Bearer Request",
+		35:  "Modify Bearer Response",
+		36:  "Delete Session Request",
+		37:  "Delete Session Response",
+		64:  "Modify Bearer Command",
+		65:  "Modify Bearer Failure Indication",
+		66:  "Delete Bearer Command",
+		67:  "Delete Bearer Failure Indication",
+		68:  "Bearer Resource Command",
+		69:  "Bearer Resource Failure Indication",
+		95:  "Create Bearer Request",
+		96:  "Create Bearer Response",
+		97:  "Update Bearer Request",
+		98:  "Update Bearer Response",
+		99:  "Delete Bearer Request",
+		100: "Delete Bearer Response",
+		170: "Release Access Bearers Request",
+		171: "Release Access Bearers Response",
+		176: "Downlink Data Notification",
+		177: "Downlink Data Notification Acknowledge",
+	}
+
+	gtpv2Causes = map[uint8]string{
+		16:  "Request accepted",
+		17:  "Request accepted partially",
+		18:  "New PDN type due to network preference",
+		19:  "New PDN type due to single address bearer only",
+		64:  "Context Not Found",
+		65:  "Invalid Message Format",
+		66:  "Version not supported by next peer",
+		67:  "Invalid length",
+		68:  "Service not supported",
+		69:  "Mandatory IE incorrect",
+		70:  "Mandatory IE missing",
+		72:  "System failure",
+		73:  "No resources available",
+		78:  "Missing or unknown APN",
+		83:  "Preferred PDN type not supported",
+		84:  "All dynamic addresses are occupied",
+		92:  "User authentication failed",
+		93:  "APN access denied - no subscription",
+		94:  "Request rejected",
+		110: "Temporarily rejected due to handover/TAU/RAU procedure in progress",
+	}
+
+	gtpv2RATTypes = map[uint8]string{
+		1:  "UTRAN",
+		2:  "GERAN",
+		3:  "WLAN",
+		4:  "GAN",
+		5:  "HSPA Evolution",
+		6:  "EUTRAN",
+		7:  "Virtual",
+		8:  "EUTRAN-NB-IoT",
+		9:  "LTE-M",
+		10: "NR",
+	}
+
+	gtpv2InterfaceTypes = map[uint8]string{
+		0:  "S1-U eNodeB GTP-U",
+		1:  "S1-U SGW GTP-U",
+		2:  "S12 RNC GTP-U",
+		3:  "S12 SGW GTP-U",
+		4:  "S5/S8 SGW GTP-U",
+		5:  "S5/S8 PGW GTP-U",
+		6:  "S5/S8 SGW GTP-C",
+		7:  "S5/S8 PGW GTP-C",
+		10: "S11 MME GTP-C",
+		11: "S11/S4 SGW GTP-C",
+		26: "S11-U MME GTP-U",
+		27: "S11-U SGW GTP-U",
+	}
+)
+
+var gtpv2cDecoder = newPacketDecoder(
+	types.Type_NC_GTPv2C,
+	"GTPv2C",
+	"The GPRS Tunnelling Protocol control plane manages the sessions and bearers of mobile subscribers in the evolved packet core",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		if udp.SrcPort != gtpcPort && udp.DstPort != gtpcPort {
+			return nil
+		}
+
+		g, err := parseGTPv2C(udp.Payload)
+		if err != nil {
+			decoderLog.Debug("failed to parse GTPv2-C message", zap.Error(err))
+
+			return nil
+		}
+
+		g.Timestamp = p.Metadata().Timestamp.UnixNano()
+		g.SrcIP, g.DstIP = networkAddresses(p)
+		g.SrcPort = int32(udp.SrcPort)
+		g.DstPort = int32(udp.DstPort)
+
+		return g
+	},
+	nil,
+)
+
+// parseGTPv2C parses the header and information elements of a GTPv2-C message.
+// Piggybacked messages are ignored.
+func parseGTPv2C(data []byte) (*types.GTPv2C, error) {
+	if len(data) < gtpv2HeaderLength {
+		return nil, errGTPv2Short
+	}
+
+	if data[0]>>5 != gtpv2Version {
+		return nil, errGTPv2Version
+	}
+
+	var (
+		g      = &types.GTPv2C{MessageType: gtpv2MessageType(data[1])}
+		length = int(binary.BigEndian.Uint16(data[2:4])) + 4
+		offset = gtpv2HeaderLength
+	)
+
+	if data[0]&gtpv2FlagTEID != 0 {
+		if len(data) < gtpv2HeaderLengthTEID {
+			return nil, errGTPv2Short
+		}
+
+		g.TEID = binary.BigEndian.Uint32(data[4:8])
+		offset = gtpv2HeaderLengthTEID
+	}
+
+	g.SequenceNumber = uint32(data[offset-4])<<16 | uint32(data[offset-3])<<8 | uint32(data[offset-2])
+
+	if length > len(data) {
+		return nil, errGTPv2Short
+	}
+
+	parseGTPv2IEs(g, data[offset:length])
+
+	return g, nil
+}
+
+// parseGTPv2IEs adds the values of the information elements to the record.
+// Grouped bearer contexts are parsed recursively.
+func parseGTPv2IEs(g *types.GTPv2C, data []byte) {
+	for len(data) >= gtpv2IEHeaderLength {
+		var (
+			typ    = data[0]
+			length = int(binary.BigEndian.Uint16(data[1:3]))
+		)
+
+		if gtpv2IEHeaderLength+length > len(data) {
+			return
+		}
+
+		v := data[gtpv2IEHeaderLength : gtpv2IEHeaderLength+length]
+		data = data[gtpv2IEHeaderLength+length:]
+
+		if len(v) == 0 {
+			continue
+		}
+
+		switch typ {
+		case gtpv2IEIMSI:
+			g.IMSI = decodeTBCD(v)
+		case gtpv2IEMSISDN:
+			g.MSISDN = decodeTBCD(v)
+		case gtpv2IEMEI:
+			g.MEI = decodeTBCD(v)
+		case gtpv2IECause:
+			if g.Cause == "" {
+				g.Cause = gtpv2Cause(v[0])
+			}
+		case gtpv2IEAPN:
+			g.APN = decodeAPN(v)
+		case gtpv2IERATType:
+			g.RATType = gtpv2RATTypes[v[0]]
+			if g.RATType == "" {
+				g.RATType = strconv.Itoa(int(v[0]))
+			}
+		case gtpv2IEServingNetwork:
+			g.ServingNetwork = decodePLMN(v)
+		case gtpv2IEPAA:
+			g.PDNAddress = decodePAA(v)
+		case gtpv2IEFTEID:
+			if fteid := decodeFTEID(v); fteid != "" {
+				g.FTEIDs = append(g.FTEIDs, fteid)
+			}
+		case gtpv2IEEBI:
+			g.BearerIDs = append(g.BearerIDs, int32(v[0]&0x0f))
+		case gtpv2IEBearerContext:
+			parseGTPv2IEs(g, v)
+		}
+	}
+}
+
+func gtpv2MessageType(t uint8) string {
+	if s, ok := gtpv2MessageTypes[t]; ok {
+		return s
+	}
+
+	return "Unknown(" + strconv.Itoa(int(t)) + ")"
+}
+
+func gtpv2Cause(c uint8) string {
+	if s, ok := gtpv2Causes[c]; ok {
+		return s
+	}
+
+	return "Cause(" + strconv.Itoa(int(c)) + ")"
+}
+
+// decodeTBCD decodes telephony binary coded decimal digits, as used for IMSI, MSISDN and MEI.
+// The low nibble of each byte holds the first digit, a nibble of 0xf marks the end.
+func decodeTBCD(data []byte) string {
+	var b strings.Builder
+
+	for _, d := range data {
+		for _, n := range []byte{d & 0x0f, d >> 4} {
+			if n > 9 {
+				return b.String()
+			}
+
+			b.WriteByte('0' + n)
+		}
+	}
+
+	return b.String()
+}
+
+// decodeAPN decodes an access point name, which is encoded as a sequence of length prefixed labels.
+func decodeAPN(data []byte) string {
+	var labels []string
+
+	for len(data) > 0 {
+		l := int(data[0])
+		if l+1 > len(data) {
+			break
+		}
+
+		labels = append(labels, string(data[1:l+1]))
+		data = data[l+1:]
+	}
+
+	return strings.Join(labels, ".")
+}
+
+// decodePLMN decodes the mobile country and network code as MCC-MNC.
+func decodePLMN(data []byte) string {
+	if len(data) < 3 {
+		return ""
+	}
+
+	mcc := fmt.Sprintf("%d%d%d", data[0]&0x0f, data[0]>>4, data[1]&0x0f)
+	mnc := fmt.Sprintf("%d%d", data[2]&0x0f, data[2]>>4)
+
+	// the third MNC digit is set to 0xf for two digit network codes
+	if d := data[1] >> 4; d != 0x0f {
+		mnc += strconv.Itoa(int(d))
+	}
+
+	return mcc + "-" + mnc
+}
+
+// decodePAA returns the addresses of a PDN address allocation.
+func decodePAA(data []byte) string {
+	const (
+		ipv4Len = 4
+		// prefix length and address
+		ipv6Len = 17
+	)
+
+	switch v := data[1:]; data[0] & 0x07 {
+	case gtpv2PDNTypeIPv4:
+		if len(v) >= ipv4Len {
+			return net.IP(v[:ipv4Len]).String()
+		}
+	case gtpv2PDNTypeIPv6:
+		if len(v) >= ipv6Len {
+			return net.IP(v[1:ipv6Len]).String() + "/" + strconv.Itoa(int(v[0]))
+		}
+	case gtpv2PDNTypeIPv4v6:
+		if len(v) >= ipv6Len+ipv4Len {
+			return net.IP(v[1:ipv6Len]).String() + "/" + strconv.Itoa(int(v[0])) + " " + net.IP(v[ipv6Len:ipv6Len+ipv4Len]).String()
+		}
+	}
+
+	return ""
+}
+
+// decodeFTEID formats a fully qualified tunnel endpoint identifier as interface:TEID@address.
+func decodeFTEID(data []byte) string {
+	const (
+		flagV4 = 0x80
+		flagV6 = 0x40
+	)
+
+	if len(data) < 5 {
+		return ""
+	}
+
+	var (
+		flags = data[0]
+		teid  = binary.BigEndian.Uint32(data[1:5])
+		addrs []string
+		v     = data[5:]
+	)
+
+	if flags&flagV4 != 0 && len(v) >= net.IPv4len {
+		addrs = append(addrs, net.IP(v[:net.IPv4len]).String())
+		v = v[net.IPv4len:]
+	}
+
+	if flags&flagV6 != 0 && len(v) >= net.IPv6len {
+		addrs = append(addrs, net.IP(v[:net.IPv6len]).String())
+	}
+
+	iface, ok := gtpv2InterfaceTypes[flags&0x3f]
+	if !ok {
+		iface = strconv.Itoa(int(flags & 0x3f))
+	}
+
+	return iface + ":" + strconv.FormatUint(uint64(teid), 10) + "@" + strings.Join(addrs, ",")
+}
@@ -90,8 +90,14 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 		return nil
 	}
 
+	// identical addresses in different tenant networks are tracked separately
+	key := ipAddr
+	if i.Encapsulation != "" {
+		key += "@" + i.Encapsulation
+	}
+
 	ipProfiles.Lock()
-	if p, ok := ipProfiles.Items[ipAddr]; ok {
+	if p, ok := ipProfiles.Items[key]; ok {
 		ipProfiles.Unlock()
 
 		p.Lock()
@@ -103,7 +109,7 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 		p.Bytes += dataLen
 
 		// Transport Layer
-		if tl := i.Packet.TransportLayer(); tl != nil {
+		if tl := i.TransportLayer; tl != nil {
 			if source {
 				doSrcPortUpdate(p, utils.DecodePort(tl.TransportFlow().Src().Raw()), tl.LayerType().String(), dataLen)
 				doContactedPortUpdate(p, utils.DecodePort(tl.TransportFlow().Dst().Raw()), tl.LayerType().String(), dataLen)
@@ -211,11 +217,12 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 			DstPorts:       dstPorts,
 			ContactedPorts: contactedPorts,
 			SNIs:           sniMap,
+			Encapsulation:  i.Encapsulation,
 		},
 	}
 
 	ipProfiles.Lock()
-	ipProfiles.Items[ipAddr] = p
+	ipProfiles.Items[key] = p
 	ipProfiles.Unlock()
 
 	return p
@@ -308,7 +315,7 @@ func initPorts(i *decoderutils.PacketInfo, source bool) (
 	dstPorts,
 	contactedPorts []*types.Port,
 ) {
-	if tl := i.Packet.TransportLayer(); tl != nil {
+	if tl := i.TransportLayer; tl != nil {
 		// get packet size
 		dataLen := uint64(len(i.Packet.Data()))
 
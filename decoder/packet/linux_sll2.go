@@ -0,0 +1,121 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// linkTypeLinuxSLL2 is the link type number for Linux cooked captures v2, as created by tcpdump -i any since libpcap 1.10.
+const linkTypeLinuxSLL2 = 276
+
+// LinkTypeLinuxSLL2 is the link type reported by the gopacket pcap readers for SLL2 captures.
+// layers.LinkType is an uint8, so the link type number is truncated to 20.
+// Link type 20 is not assigned and has no decoder in gopacket, but the registration in layers.LinkTypeMetadata
+// is global: captures with the link type 20 are decoded as SLL2, and init panics if another package claimed it first.
+const LinkTypeLinuxSLL2 = layers.LinkType(linkTypeLinuxSLL2 & 0xff)
+
+const linuxSLL2HeaderSize = 20
+
+var errLinuxSLL2TooSmall = errors.New("Linux SLL2 packet too small")
+
+// LayerTypeLinuxSLL2 is the layer type for Linux cooked capture v2 headers.
+var LayerTypeLinuxSLL2 = gopacket.RegisterLayerType(2001, gopacket.LayerTypeMetadata{
+	Name:    "Linux SLL2",
+	Decoder: gopacket.DecodeFunc(decodeLinuxSLL2),
+})
+
+func init() {
+	// register the link type, so packet sources created for SLL2 handles can decode the packets
+	if name := layers.LinkTypeMetadata[LinkTypeLinuxSLL2].Name; name != "UnknownLinkType" {
+		panic("link type " + strconv.Itoa(int(LinkTypeLinuxSLL2)) + " for Linux SLL2 is already registered as " + name)
+	}
+
+	layers.LinkTypeMetadata[LinkTypeLinuxSLL2] = layers.EnumMetadata{
+		DecodeWith: gopacket.DecodeFunc(decodeLinuxSLL2),
+		Name:       "Linux SLL2",
+		LayerType:  LayerTypeLinuxSLL2,
+	}
+}
+
+// LinuxSLL2 is the header of a Linux cooked capture v2.
+// In contrast to the first version, it contains the interface index
+// and places the protocol type at the beginning of the header.
+// see https://www.tcpdump.org/linktypes/LINKTYPE_LINUX_SLL2.html
+type LinuxSLL2 struct {
+	layers.BaseLayer
+	ProtocolType   layers.EthernetType
+	InterfaceIndex uint32
+	ARPHRDType     uint16
+	PacketType     layers.LinuxSLLPacketType
+	AddrLen        uint8
+	Addr           net.HardwareAddr
+}
+
+// LayerType returns LayerTypeLinuxSLL2.
+func (sll *LinuxSLL2) LayerType() gopacket.LayerType { return LayerTypeLinuxSLL2 }
+
+// CanDecode returns LayerTypeLinuxSLL2.
+func (sll *LinuxSLL2) CanDecode() gopacket.LayerClass { return LayerTypeLinuxSLL2 }
+
+// LinkFlow returns a flow with the link layer address of the packet as source.
+func (sll *LinuxSLL2) LinkFlow() gopacket.Flow {
+	return gopacket.NewFlow(layers.EndpointMAC, sll.Addr, nil)
+}
+
+// NextLayerType returns the layer type of the payload.
+func (sll *LinuxSLL2) NextLayerType() gopacket.LayerType {
+	return sll.ProtocolType.LayerType()
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (sll *LinuxSLL2) DecodeFromBytes(data []byte, _ gopacket.DecodeFeedback) error {
+	if len(data) < linuxSLL2HeaderSize {
+		return errLinuxSLL2TooSmall
+	}
+
+	sll.ProtocolType = layers.EthernetType(binary.BigEndian.Uint16(data[0:2]))
+	sll.InterfaceIndex = binary.BigEndian.Uint32(data[4:8])
+	sll.ARPHRDType = binary.BigEndian.Uint16(data[8:10])
+	sll.PacketType = layers.LinuxSLLPacketType(data[10])
+	sll.AddrLen = data[11]
+
+	addrLen := int(sll.AddrLen)
+	if addrLen > 8 {
+		addrLen = 8
+	}
+
+	sll.Addr = net.HardwareAddr(data[12 : 12+addrLen])
+	sll.BaseLayer = layers.BaseLayer{Contents: data[:linuxSLL2HeaderSize], Payload: data[linuxSLL2HeaderSize:]}
+
+	return nil
+}
+
+func decodeLinuxSLL2(data []byte, p gopacket.PacketBuilder) error {
+	sll := &LinuxSLL2{}
+	if err := sll.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+
+	p.AddLayer(sll)
+	p.SetLinkLayer(sll)
+
+	return p.NextDecoder(sll.ProtocolType)
+}
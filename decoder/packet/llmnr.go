@@ -0,0 +1,71 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const llmnrPort = 5355
+
+var llmnrDecoder = newPacketDecoder(
+	types.Type_NC_LLMNR,
+	"LLMNR",
+	"Link-Local Multicast Name Resolution allows hosts to resolve names of neighbours on the same local link",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		if udp.SrcPort != llmnrPort && udp.DstPort != llmnrPort {
+			return nil
+		}
+
+		dns, ok := decodeDNSPayload(udp.Payload)
+		if !ok || len(dns.Questions) == 0 {
+			return nil
+		}
+
+		srcIP, dstIP := networkAddresses(p)
+		l := &types.LLMNR{
+			Timestamp: p.Metadata().Timestamp.UnixNano(),
+			SrcIP:     srcIP,
+			DstIP:     dstIP,
+			SrcMAC:    linkSrcAddress(p),
+			Response:  dns.QR,
+			Name:      string(dns.Questions[0].Name),
+			Type:      dns.Questions[0].Type.String(),
+		}
+
+		for _, a := range dns.Answers {
+			if (a.Type == layers.DNSTypeA || a.Type == layers.DNSTypeAAAA) && a.IP != nil {
+				l.Addresses = appendUnique(l.Addresses, a.IP.String())
+			}
+		}
+
+		// responses are sent by the owner of the name
+		if l.Response && len(l.Addresses) > 0 {
+			addDeviceDiscovery(l.SrcMAC, []string{l.Name}, nil, nil)
+		}
+
+		return l
+	},
+	nil,
+)
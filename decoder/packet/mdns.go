@@ -0,0 +1,166 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	mdnsPort = 5353
+
+	// service type enumeration name from RFC 6763 section 9.
+	dnssdServicesName = "_services._dns-sd._udp.local"
+)
+
+// TXT record keys that carry the device model, in order of preference.
+// model is used by apple devices in _device-info records, md by cast devices,
+// am by airplay receivers, and ty and usb_MDL by printers.
+var mdnsModelKeys = []string{"model", "md", "am", "ty", "usb_MDL"}
+
+var mdnsDecoder = newPacketDecoder(
+	types.Type_NC_MDNS,
+	"MDNS",
+	"Multicast DNS resolves host names and advertises services via DNS-SD in local networks without a name server",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		if udp.SrcPort != mdnsPort && udp.DstPort != mdnsPort {
+			return nil
+		}
+
+		dns, ok := decodeDNSPayload(udp.Payload)
+		if !ok {
+			return nil
+		}
+
+		srcIP, dstIP := networkAddresses(p)
+		m := &types.MDNS{
+			Timestamp: p.Metadata().Timestamp.UnixNano(),
+			SrcIP:     srcIP,
+			DstIP:     dstIP,
+			SrcMAC:    linkSrcAddress(p),
+			Response:  dns.QR,
+		}
+
+		for _, q := range dns.Questions {
+			m.Questions = appendUnique(m.Questions, string(q.Name))
+		}
+
+		var (
+			ownHostnames []string
+			ownServices  []string
+		)
+
+		// probes carry the proposed records in the authority section
+		for _, records := range [][]layers.DNSResourceRecord{dns.Answers, dns.Authorities, dns.Additionals} {
+			for i := range records {
+				rr := &records[i]
+				name := string(rr.Name)
+
+				switch rr.Type {
+				case layers.DNSTypeA, layers.DNSTypeAAAA:
+					if rr.IP == nil {
+						continue
+					}
+
+					m.Hostnames = appendUnique(m.Hostnames, name)
+					m.Addresses = appendUnique(m.Addresses, rr.IP.String())
+
+					if rr.IP.String() == srcIP {
+						ownHostnames = appendUnique(ownHostnames, name)
+					}
+				case layers.DNSTypePTR:
+					target := string(rr.PTR)
+
+					switch {
+					case strings.HasSuffix(name, ".in-addr.arpa") || strings.HasSuffix(name, ".ip6.arpa"):
+						m.Hostnames = appendUnique(m.Hostnames, target)
+					case name == dnssdServicesName:
+						m.ServiceTypes = appendUnique(m.ServiceTypes, target)
+					default:
+						m.ServiceTypes = appendUnique(m.ServiceTypes, name)
+						m.Services = appendUnique(m.Services, target)
+					}
+				case layers.DNSTypeSRV:
+					m.Services = appendUnique(m.Services, name)
+					m.Targets = appendUnique(m.Targets, string(rr.SRV.Name)+":"+strconv.Itoa(int(rr.SRV.Port)))
+					ownServices = appendUnique(ownServices, name)
+				case layers.DNSTypeTXT:
+					for _, txt := range rr.TXTs {
+						m.TXTs = appendUnique(m.TXTs, string(txt))
+					}
+
+					if m.Model == "" {
+						m.Model = mdnsModel(rr.TXTs)
+					}
+				}
+			}
+		}
+
+		if m.Response {
+			var models []string
+			if m.Model != "" {
+				models = []string{m.Model}
+			}
+
+			addDeviceDiscovery(m.SrcMAC, ownHostnames, models, ownServices)
+		}
+
+		return m
+	},
+	nil,
+)
+
+// decodeDNSPayload decodes a DNS message from an UDP payload.
+func decodeDNSPayload(data []byte) (*layers.DNS, bool) {
+	dns := new(layers.DNS)
+	if err := dns.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		decoderLog.Debug("failed to decode DNS message: " + err.Error())
+
+		return nil, false
+	}
+
+	return dns, true
+}
+
+// mdnsModel returns the device model from the key value pairs of a TXT record.
+func mdnsModel(txts [][]byte) string {
+	values := make(map[string]string, len(txts))
+
+	for _, txt := range txts {
+		if i := strings.IndexByte(string(txt), '='); i > 0 {
+			values[string(txt[:i])] = string(txt[i+1:])
+		}
+	}
+
+	for _, key := range mdnsModelKeys {
+		if v := values[key]; v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,229 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	nbnsPort = 137
+
+	// resource record types from RFC 1002 section 4.2.
+	nbnsTypeNB     layers.DNSType = 0x20
+	nbnsTypeNBSTAT layers.DNSType = 0x21
+
+	// length of a first level encoded NetBIOS name.
+	nbnsEncodedNameLength = 32
+
+	// flag for group names in the NB_FLAGS and NAME_FLAGS fields.
+	nbnsGroupFlag = 0x8000
+)
+
+var (
+	nbnsOperations = map[layers.DNSOpCode]string{
+		0:  "Query",
+		5:  "Registration",
+		6:  "Release",
+		7:  "WACK",
+		8:  "Refresh",
+		9:  "Refresh",
+		15: "Multi-Homed Registration",
+	}
+
+	// descriptions for the suffixes of unique names.
+	nbnsSuffixes = map[byte]string{
+		0x00: "Workstation",
+		0x01: "Messenger",
+		0x03: "Messenger",
+		0x06: "RAS Server",
+		0x1b: "Domain Master Browser",
+		0x1d: "Master Browser",
+		0x1f: "NetDDE",
+		0x20: "File Server",
+		0x21: "RAS Client",
+		0xbe: "Network Monitor Agent",
+		0xbf: "Network Monitor Application",
+	}
+
+	// descriptions for the suffixes of group names.
+	nbnsGroupSuffixes = map[byte]string{
+		0x00: "Domain Name",
+		0x01: "Master Browser",
+		0x1c: "Domain Controllers",
+		0x1e: "Browser Service Elections",
+	}
+)
+
+var nbnsDecoder = newPacketDecoder(
+	types.Type_NC_NBNS,
+	"NBNS",
+	"The NetBIOS name service registers and resolves NetBIOS names of hosts in local networks",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		if udp.SrcPort != nbnsPort && udp.DstPort != nbnsPort {
+			return nil
+		}
+
+		dns, ok := decodeDNSPayload(udp.Payload)
+		if !ok {
+			return nil
+		}
+
+		srcIP, dstIP := networkAddresses(p)
+		n := &types.NBNS{
+			Timestamp: p.Metadata().Timestamp.UnixNano(),
+			SrcIP:     srcIP,
+			DstIP:     dstIP,
+			SrcMAC:    linkSrcAddress(p),
+			Response:  dns.QR,
+			Operation: nbnsOperations[dns.OpCode],
+		}
+
+		if n.Operation == "" {
+			n.Operation = fmt.Sprint("Unknown(", dns.OpCode, ")")
+		}
+
+		var (
+			suffix byte
+			group  bool
+		)
+
+		switch {
+		case len(dns.Questions) > 0:
+			n.Name, suffix = decodeNetBIOSName(string(dns.Questions[0].Name))
+		case len(dns.Answers) > 0:
+			n.Name, suffix = decodeNetBIOSName(string(dns.Answers[0].Name))
+		}
+
+		var ownNames []string
+
+		for _, records := range [][]layers.DNSResourceRecord{dns.Answers, dns.Additionals} {
+			for i := range records {
+				rr := &records[i]
+
+				switch rr.Type {
+				case nbnsTypeNB:
+					// NB_FLAGS followed by the address for each entry
+					for data := rr.Data; len(data) >= 6; data = data[6:] {
+						group = group || (uint16(data[0])<<8|uint16(data[1]))&nbnsGroupFlag != 0
+						addr := net.IP(data[2:6]).String()
+						n.Addresses = appendUnique(n.Addresses, addr)
+
+						if addr == srcIP && !group {
+							ownNames = appendUnique(ownNames, n.Name)
+						}
+					}
+				case nbnsTypeNBSTAT:
+					names := parseNBSTAT(rr.Data)
+					n.Names = appendUnique(n.Names, names...)
+
+					// node status responses are sent by the node itself
+					for _, name := range names {
+						if strings.HasSuffix(name, "<00>") {
+							ownNames = appendUnique(ownNames, strings.TrimSuffix(name, "<00>"))
+
+							break
+						}
+					}
+				}
+			}
+		}
+
+		if n.Name != "" {
+			n.Suffix = nbnsSuffix(suffix, group)
+		}
+
+		addDeviceDiscovery(n.SrcMAC, ownNames, nil, nil)
+
+		return n
+	},
+	nil,
+)
+
+// decodeNetBIOSName decodes a first level encoded NetBIOS name (RFC 1001 section 14.1)
+// and returns the name without padding and the suffix byte.
+// The scope ID that may follow the encoded name is dropped.
+func decodeNetBIOSName(encoded string) (string, byte) {
+	if i := strings.IndexByte(encoded, '.'); i >= 0 {
+		encoded = encoded[:i]
+	}
+
+	if len(encoded) != nbnsEncodedNameLength {
+		return encoded, 0
+	}
+
+	name := make([]byte, nbnsEncodedNameLength/2)
+
+	for i := range name {
+		hi, lo := encoded[2*i]-'A', encoded[2*i+1]-'A'
+		if hi > 0x0f || lo > 0x0f {
+			return encoded, 0
+		}
+
+		name[i] = hi<<4 | lo
+	}
+
+	return strings.TrimRight(string(name[:15]), " \x00"), name[15]
+}
+
+// parseNBSTAT returns the names from the RDATA of a node status response as NAME<suffix>.
+func parseNBSTAT(data []byte) []string {
+	const nameEntryLength = 18
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	var (
+		num   = int(data[0])
+		names = make([]string, 0, num)
+	)
+
+	data = data[1:]
+
+	for i := 0; i < num && len(data) >= nameEntryLength; i++ {
+		names = append(names, fmt.Sprintf("%s<%02x>", strings.TrimRight(string(data[:15]), " \x00"), data[15]))
+		data = data[nameEntryLength:]
+	}
+
+	return names
+}
+
+// nbnsSuffix returns a description of the NetBIOS suffix.
+func nbnsSuffix(suffix byte, group bool) string {
+	suffixes := nbnsSuffixes
+	if group {
+		suffixes = nbnsGroupSuffixes
+	}
+
+	if s, ok := suffixes[suffix]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("<%02x>", suffix)
+}
@@ -198,6 +198,10 @@ func InitPacketDecoders(c *config.Config) (decoders []DecoderAPI, err error) {
 				Encode:     c.Encode,
 				Proto:      c.Proto,
 				JSON:       c.JSON,
+				Zeek:       c.Zeek,
+				EVE:        c.EVE,
+				Parquet:    c.Parquet,
+				SQLite:     c.SQLite,
 				Name:       dec.GetName(),
 				Type:       dec.GetType(),
 				Null:       c.Null,
@@ -211,6 +215,7 @@ func InitPacketDecoders(c *config.Config) (decoders []DecoderAPI, err error) {
 				},
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
+				Index:                c.Index,
 				Out:                  c.Out,
 				Chan:                 c.Chan,
 				ChanSize:             c.ChanSize,
@@ -221,6 +226,10 @@ func InitPacketDecoders(c *config.Config) (decoders []DecoderAPI, err error) {
 				StartTime:            time.Now(),
 				CompressionBlockSize: c.CompressionBlockSize,
 				CompressionLevel:     c.CompressionLevel,
+				CompressionCodec:     c.CompressionCodec,
+				CompressionDict:      c.CompressionDict,
+				ParquetCompression:   c.ParquetCompression,
+				ParquetRowGroupSize:  c.ParquetRowGroupSize,
 			})
 			dec.SetWriter(w)
 
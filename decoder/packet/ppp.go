@@ -0,0 +1,458 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// PPP control protocols.
+const (
+	pppTypeLCP    layers.PPPType = 0xc021
+	pppTypePAP    layers.PPPType = 0xc023
+	pppTypeCHAP   layers.PPPType = 0xc223
+	pppTypeEAP    layers.PPPType = 0xc227
+	pppTypeIPCP   layers.PPPType = 0x8021
+	pppTypeIPV6CP layers.PPPType = 0x8057
+	pppTypeCCP    layers.PPPType = 0x80fd
+)
+
+const (
+	// code, identifier and length
+	pppControlHeaderSize = 4
+
+	// LCP codes that are exchanged periodically and are not recorded
+	lcpEchoRequest    = 9
+	lcpEchoReply      = 10
+	lcpDiscardRequest = 11
+
+	papAuthenticateRequest = 1
+
+	chapChallenge = 1
+	chapResponse  = 2
+
+	// response value sizes of the CHAP algorithms
+	chapMD5ResponseSize     = 16
+	msCHAPv2ResponseSize    = 49
+	msCHAPv2ChallengeSize   = 16
+	msCHAPv2NTResponseStart = 24
+
+	hashcatModeCHAPMD5 = 4800
+	hashcatModeNetNTLM = 5500
+
+	servicePPP = "PPP"
+)
+
+var (
+	pppProtocols = map[layers.PPPType]string{
+		pppTypeLCP:    "LCP",
+		pppTypePAP:    "PAP",
+		pppTypeCHAP:   "CHAP",
+		pppTypeIPCP:   "IPCP",
+		pppTypeIPV6CP: "IPV6CP",
+		pppTypeCCP:    "CCP",
+	}
+
+	// codes shared by LCP and the network control protocols (RFC 1661).
+	pppControlCodes = []string{
+		"",
+		"Configure-Request",
+		"Configure-Ack",
+		"Configure-Nak",
+		"Configure-Reject",
+		"Terminate-Request",
+		"Terminate-Ack",
+		"Code-Reject",
+		"Protocol-Reject",
+		"Echo-Request",
+		"Echo-Reply",
+		"Discard-Request",
+		"Identification",
+		"Time-Remaining",
+		"Reset-Request",
+		"Reset-Ack",
+	}
+
+	papCodes  = []string{"", "Authenticate-Request", "Authenticate-Ack", "Authenticate-Nak"}
+	chapCodes = []string{"", "Challenge", "Response", "Success", "Failure"}
+
+	chapAlgorithms = map[byte]string{
+		0x05: "MD5",
+		0x80: "MS-CHAP",
+		0x81: "MS-CHAPv2",
+	}
+
+	// pending CHAP challenges, keyed by the session and identifier
+	chapChallenges   = make(map[string][]byte)
+	chapChallengesMu sync.Mutex
+)
+
+var pppDecoder = newPacketDecoder(
+	types.Type_NC_PPP,
+	"PPP",
+	"The Point-to-Point Protocol control packets negotiate the link, authentication and network configuration of a PPP session",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		ppp, ok := p.Layer(layers.LayerTypePPP).(*layers.PPP)
+		if !ok {
+			return nil
+		}
+
+		protocol, ok := pppProtocols[ppp.PPPType]
+		if !ok {
+			return nil
+		}
+
+		r := &types.PPP{
+			Timestamp: p.Metadata().Timestamp.UnixNano(),
+			Protocol:  protocol,
+		}
+
+		if pppoe, okPPPoE := p.Layer(layers.LayerTypePPPoE).(*layers.PPPoE); okPPPoE {
+			r.SessionID = int32(pppoe.SessionId)
+		}
+
+		// the PPP layer itself is the link layer on PPP links
+		if eth, okEth := p.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); okEth {
+			r.SrcMAC = eth.SrcMAC.String()
+			r.DstMAC = eth.DstMAC.String()
+		}
+
+		if !parsePPPControl(r, ppp.PPPType, ppp.Payload) {
+			return nil
+		}
+
+		return r
+	},
+	nil,
+)
+
+// parsePPPControl decodes a control protocol packet into the record.
+// It returns false for malformed packets and for keepalive messages.
+func parsePPPControl(r *types.PPP, typ layers.PPPType, data []byte) bool {
+	if len(data) < pppControlHeaderSize {
+		return false
+	}
+
+	var (
+		code   = data[0]
+		length = int(binary.BigEndian.Uint16(data[2:4]))
+	)
+
+	if length < pppControlHeaderSize || length > len(data) {
+		return false
+	}
+
+	r.Identifier = int32(data[1])
+	body := data[pppControlHeaderSize:length]
+
+	switch typ {
+	case pppTypePAP:
+		r.Code = codeName(papCodes, code)
+		parsePAP(r, code, body)
+	case pppTypeCHAP:
+		r.Code = codeName(chapCodes, code)
+		parseCHAP(r, code, body)
+	default:
+		if typ == pppTypeLCP && (code == lcpEchoRequest || code == lcpEchoReply || code == lcpDiscardRequest) {
+			return false
+		}
+
+		r.Code = codeName(pppControlCodes, code)
+
+		switch {
+		case code >= 1 && code <= 4:
+			parsePPPOptions(r, typ, body)
+		case code == 5 || code == 6:
+			r.Message = string(body)
+		}
+	}
+
+	return true
+}
+
+func codeName(names []string, code byte) string {
+	if int(code) < len(names) && names[code] != "" {
+		return names[code]
+	}
+
+	return "Code-" + strconv.Itoa(int(code))
+}
+
+// parsePPPOptions decodes the configuration options of LCP and the network control protocols.
+func parsePPPOptions(r *types.PPP, typ layers.PPPType, data []byte) {
+	for len(data) >= 2 {
+		var (
+			opt    = data[0]
+			length = int(data[1])
+		)
+
+		if length < 2 || length > len(data) {
+			return
+		}
+
+		var (
+			val  = data[2:length]
+			desc string
+		)
+
+		switch typ {
+		case pppTypeLCP:
+			desc = lcpOption(r, opt, val)
+		case pppTypeIPCP:
+			desc = ipcpOption(r, opt, val)
+		case pppTypeIPV6CP:
+			if opt == 1 {
+				r.InterfaceID = hex.EncodeToString(val)
+				desc = "Interface-Identifier=" + r.InterfaceID
+			}
+		}
+
+		if desc == "" {
+			desc = "Option-" + strconv.Itoa(int(opt))
+			if len(val) > 0 {
+				desc += "=" + hex.EncodeToString(val)
+			}
+		}
+
+		r.Options = append(r.Options, desc)
+		data = data[length:]
+	}
+}
+
+func lcpOption(r *types.PPP, opt byte, val []byte) string {
+	switch opt {
+	case 1:
+		if len(val) == 2 {
+			r.MRU = int32(binary.BigEndian.Uint16(val))
+
+			return "MRU=" + strconv.Itoa(int(r.MRU))
+		}
+	case 2:
+		return "ACCM=0x" + hex.EncodeToString(val)
+	case 3:
+		if len(val) >= 2 {
+			r.AuthProtocol = authProtocolName(layers.PPPType(binary.BigEndian.Uint16(val)), val[2:])
+
+			return "Auth-Protocol=" + r.AuthProtocol
+		}
+	case 5:
+		if len(val) == 4 {
+			r.MagicNumber = binary.BigEndian.Uint32(val)
+
+			return "Magic-Number=0x" + hex.EncodeToString(val)
+		}
+	case 7:
+		return "Protocol-Field-Compression"
+	case 8:
+		return "Address-and-Control-Field-Compression"
+	}
+
+	return ""
+}
+
+func authProtocolName(proto layers.PPPType, data []byte) string {
+	switch proto {
+	case pppTypePAP:
+		return "PAP"
+	case pppTypeEAP:
+		return "EAP"
+	case pppTypeCHAP:
+		if len(data) > 0 {
+			if alg, ok := chapAlgorithms[data[0]]; ok {
+				return "CHAP/" + alg
+			}
+		}
+
+		return "CHAP"
+	}
+
+	return "0x" + strconv.FormatUint(uint64(proto), 16)
+}
+
+func ipcpOption(r *types.PPP, opt byte, val []byte) string {
+	var (
+		name string
+		addr *string
+	)
+
+	switch opt {
+	case 3:
+		name, addr = "IP-Address", &r.IPAddress
+	case 129:
+		name, addr = "Primary-DNS", &r.PrimaryDNS
+	case 130:
+		name = "Primary-NBNS"
+	case 131:
+		name, addr = "Secondary-DNS", &r.SecondaryDNS
+	case 132:
+		name = "Secondary-NBNS"
+	default:
+		return ""
+	}
+
+	if len(val) != net.IPv4len {
+		return ""
+	}
+
+	ip := net.IP(val).String()
+	if addr != nil {
+		*addr = ip
+	}
+
+	return name + "=" + ip
+}
+
+// parsePAP extracts the cleartext credentials of a PAP Authenticate-Request,
+// or the message of the Authenticate-Ack and Authenticate-Nak.
+func parsePAP(r *types.PPP, code byte, data []byte) {
+	if code != papAuthenticateRequest {
+		if len(data) > 0 && int(data[0]) < len(data) {
+			r.Message = string(data[1 : 1+int(data[0])])
+		}
+
+		return
+	}
+
+	if len(data) < 1 || int(data[0])+1 >= len(data) {
+		return
+	}
+
+	var (
+		peerLen = int(data[0])
+		pwLen   = int(data[1+peerLen])
+	)
+
+	if 2+peerLen+pwLen > len(data) {
+		return
+	}
+
+	r.User = string(data[1 : 1+peerLen])
+	password := string(data[2+peerLen : 2+peerLen+pwLen])
+
+	writePPPCredentials(r, password, "PAP")
+}
+
+// parseCHAP tracks challenges and formats the responses as hashcat input.
+func parseCHAP(r *types.PPP, code byte, data []byte) {
+	if code != chapChallenge && code != chapResponse {
+		r.Message = string(data)
+
+		return
+	}
+
+	if len(data) < 1 || 1+int(data[0]) > len(data) {
+		return
+	}
+
+	var (
+		valueLen = int(data[0])
+		value    = data[1 : 1+valueLen]
+	)
+
+	r.User = string(data[1+valueLen:])
+
+	chapChallengesMu.Lock()
+	defer chapChallengesMu.Unlock()
+
+	if code == chapChallenge {
+		// the authenticator sends the challenge to the peer
+		chapChallenges[chapKey(r.SrcMAC, r.DstMAC, r.SessionID, r.Identifier)] = value
+
+		return
+	}
+
+	key := chapKey(r.DstMAC, r.SrcMAC, r.SessionID, r.Identifier)
+
+	challenge, ok := chapChallenges[key]
+	if !ok {
+		return
+	}
+
+	delete(chapChallenges, key)
+
+	if hash, notes := chapResponseHash(r.User, r.Identifier, challenge, value); hash != "" {
+		writePPPCredentials(r, hash, notes)
+	}
+}
+
+// chapResponseHash formats a CHAP-MD5 or MS-CHAPv2 response as hashcat input.
+func chapResponseHash(user string, id int32, challenge, response []byte) (hash, notes string) {
+	switch len(response) {
+	case chapMD5ResponseSize:
+		// response:challenge:identifier
+		hash = hex.EncodeToString(response) + ":" + hex.EncodeToString(challenge) + ":" + hex.EncodeToString([]byte{byte(id)})
+
+		return hash, "CHAP-MD5, hashcat mode " + strconv.Itoa(hashcatModeCHAPMD5)
+	case msCHAPv2ResponseSize:
+		if len(challenge) != msCHAPv2ChallengeSize {
+			return "", ""
+		}
+
+		var (
+			peerChallenge = response[:msCHAPv2ChallengeSize]
+			ntResponse    = response[msCHAPv2NTResponseStart : msCHAPv2NTResponseStart+24]
+			name          = user
+		)
+
+		// the challenge hash is calculated without the domain (RFC 2759)
+		if i := strings.LastIndex(name, "\\"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		h := sha1.New()
+		h.Write(peerChallenge)
+		h.Write(challenge)
+		h.Write([]byte(name))
+
+		// user::::ntresponse:challengehash
+		hash = user + "::::" + hex.EncodeToString(ntResponse) + ":" + hex.EncodeToString(h.Sum(nil)[:8])
+
+		return hash, "MS-CHAPv2, hashcat mode " + strconv.Itoa(hashcatModeNetNTLM)
+	}
+
+	return "", ""
+}
+
+func chapKey(authenticator, peer string, session, id int32) string {
+	return authenticator + "-" + peer + "-" + strconv.Itoa(int(session)) + "-" + strconv.Itoa(int(id))
+}
+
+func writePPPCredentials(r *types.PPP, password, notes string) {
+	if credentials.Decoder.Writer == nil {
+		return
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp: r.Timestamp,
+		Service:   servicePPP,
+		Flow:      r.SrcMAC + "->" + r.DstMAC,
+		User:      r.User,
+		Password:  password,
+		Notes:     notes,
+	})
+}
@@ -0,0 +1,144 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// ethernet header from 00:00:5e:00:53:01 to 00:00:5e:00:53:02.
+func pppoeFrame(ethType uint16, code byte, session uint16, payload []byte) []byte {
+	b := []byte{
+		0x00, 0x00, 0x5e, 0x00, 0x53, 0x02,
+		0x00, 0x00, 0x5e, 0x00, 0x53, 0x01,
+		byte(ethType >> 8), byte(ethType),
+		0x11, code, byte(session >> 8), byte(session), byte(len(payload) >> 8), byte(len(payload)),
+	}
+
+	return append(b, payload...)
+}
+
+func decodeTestPacket(data []byte, base gopacket.LayerType) gopacket.Packet {
+	p := gopacket.NewPacket(data, base, gopacket.Default)
+	p.Metadata().Timestamp = time.Now()
+
+	return p
+}
+
+func TestLinuxSLL2(t *testing.T) {
+	data, _ := hex.DecodeString(
+		// protocol IPv4, reserved, interface index 2, ARPHRD_ETHER, outgoing, address length 6, address
+		"0800" + "0000" + "00000002" + "0001" + "04" + "06" + "00005e0053010000" +
+			// IPv4 and UDP headers from 10.0.0.1:1234 to 10.0.0.2:53
+			"450000200000000040110000" + "0a000001" + "0a000002" +
+			"04d20035000c0000" + "74657374",
+	)
+
+	p := decodeTestPacket(data, LayerTypeLinuxSLL2)
+
+	sll, ok := p.Layer(LayerTypeLinuxSLL2).(*LinuxSLL2)
+	if !ok {
+		t.Fatal("no SLL2 layer", p.ErrorLayer())
+	}
+
+	if sll.InterfaceIndex != 2 || sll.PacketType != layers.LinuxSLLPacketTypeOutgoing || sll.Addr.String() != "00:00:5e:00:53:01" {
+		t.Fatal("unexpected header", sll.InterfaceIndex, sll.PacketType, sll.Addr)
+	}
+
+	udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok || udp.DstPort != 53 || p.NetworkLayer().NetworkFlow().Dst().String() != "10.0.0.2" {
+		t.Fatal("payload was not decoded", p.Layers())
+	}
+
+	if LinkTypeLinuxSLL2.LayerType() != LayerTypeLinuxSLL2 {
+		t.Fatal("link type not registered")
+	}
+}
+
+func TestPPPoEDiscovery(t *testing.T) {
+	tags, _ := hex.DecodeString("01010000" + "01020004" + "61633031" + "01030002" + "abcd" + "01040002" + "1234")
+	p := decodeTestPacket(pppoeFrame(0x8863, 0x07, 0, tags), layers.LayerTypeEthernet)
+
+	r, ok := pppoeDecoder.Handler(p).(*types.PPPoE)
+	if !ok {
+		t.Fatal("no PPPoE record")
+	}
+
+	if r.Code != "PADO" || r.ACName != "ac01" || r.HostUniq != "abcd" || r.ACCookie != "1234" || r.SrcMAC != "00:00:5e:00:53:01" {
+		t.Fatal("unexpected record", r)
+	}
+}
+
+func TestPPPControl(t *testing.T) {
+	// LCP Configure-Request: MRU 1492, CHAP with MS-CHAPv2, magic number
+	lcp, _ := hex.DecodeString("c021" + "01010013" + "010405d4" + "0305c22381" + "050612345678")
+	p := decodeTestPacket(pppoeFrame(0x8864, 0x00, 0x1a2b, lcp), layers.LayerTypeEthernet)
+
+	r, ok := pppDecoder.Handler(p).(*types.PPP)
+	if !ok {
+		t.Fatal("no PPP record")
+	}
+
+	if r.Protocol != "LCP" || r.Code != "Configure-Request" || r.SessionID != 0x1a2b || r.MRU != 1492 || r.AuthProtocol != "CHAP/MS-CHAPv2" || r.MagicNumber != 0x12345678 {
+		t.Fatal("unexpected LCP record", r)
+	}
+
+	// IPCP Configure-Ack with address and DNS server
+	ipcp, _ := hex.DecodeString("8021" + "02020010" + "0306c0a80102" + "81060a000001")
+	r, ok = pppDecoder.Handler(decodeTestPacket(pppoeFrame(0x8864, 0x00, 1, ipcp), layers.LayerTypeEthernet)).(*types.PPP)
+
+	if !ok || r.IPAddress != "192.168.1.2" || r.PrimaryDNS != "10.0.0.1" || len(r.Options) != 2 {
+		t.Fatal("unexpected IPCP record", r)
+	}
+
+	// LCP echo requests are not recorded
+	echo, _ := hex.DecodeString("c021" + "09010008" + "12345678")
+	if pppDecoder.Handler(decodeTestPacket(pppoeFrame(0x8864, 0x00, 1, echo), layers.LayerTypeEthernet)) != nil {
+		t.Fatal("expected no record for echo requests")
+	}
+
+	// PAP Authenticate-Request
+	pap, _ := hex.DecodeString("c023" + "01050010" + "05" + "616c696365" + "05" + "7365637265")
+	r, ok = pppDecoder.Handler(decodeTestPacket(pppoeFrame(0x8864, 0x00, 1, pap), layers.LayerTypeEthernet)).(*types.PPP)
+
+	if !ok || r.Protocol != "PAP" || r.Code != "Authenticate-Request" || r.User != "alice" {
+		t.Fatal("unexpected PAP record", r)
+	}
+}
+
+func TestCHAPResponseHash(t *testing.T) {
+	challenge, _ := hex.DecodeString("0102030405060708090a0b0c0d0e0f10")
+	response, _ := hex.DecodeString("afd09efdd6f8ca9f18ec77c5869788c3")
+
+	hash, notes := chapResponseHash("alice", 1, challenge, response)
+	if hash != "afd09efdd6f8ca9f18ec77c5869788c3:0102030405060708090a0b0c0d0e0f10:01" || notes != "CHAP-MD5, hashcat mode 4800" {
+		t.Fatal("unexpected CHAP-MD5 hash", hash, notes)
+	}
+
+	// test vectors from RFC 2759 section 9.2
+	challenge, _ = hex.DecodeString("5b5d7c7d7b3f2f3e3c2c602132262628")
+	response, _ = hex.DecodeString("21402324255e262a28295f2b3a337c7e" + "0000000000000000" + "82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df" + "00")
+
+	hash, _ = chapResponseHash("User", 1, challenge, response)
+	if hash != "User::::82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df:d02e4386bce91226" {
+		t.Fatal("unexpected MS-CHAPv2 hash", hash)
+	}
+}
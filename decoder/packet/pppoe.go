@@ -0,0 +1,115 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// PPPoE discovery tags from RFC 2516.
+const (
+	pppoeTagEndOfList        = 0x0000
+	pppoeTagServiceName      = 0x0101
+	pppoeTagACName           = 0x0102
+	pppoeTagHostUniq         = 0x0103
+	pppoeTagACCookie         = 0x0104
+	pppoeTagServiceNameError = 0x0201
+	pppoeTagACSystemError    = 0x0202
+	pppoeTagGenericError     = 0x0203
+)
+
+var pppoeCodes = map[layers.PPPoECode]string{
+	layers.PPPoECodePADI: "PADI",
+	layers.PPPoECodePADO: "PADO",
+	layers.PPPoECodePADR: "PADR",
+	layers.PPPoECodePADS: "PADS",
+	layers.PPPoECodePADT: "PADT",
+}
+
+var pppoeDecoder = newPacketDecoder(
+	types.Type_NC_PPPoE,
+	"PPPoE",
+	"The PPPoE discovery stage is used to find an access concentrator and to establish a PPP session over Ethernet",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		pppoe, ok := p.Layer(layers.LayerTypePPPoE).(*layers.PPPoE)
+		if !ok {
+			return nil
+		}
+
+		// session stage packets are handled by the PPP decoder
+		code, ok := pppoeCodes[pppoe.Code]
+		if !ok {
+			return nil
+		}
+
+		d := &types.PPPoE{
+			Timestamp: p.Metadata().Timestamp.UnixNano(),
+			Code:      code,
+			SessionID: int32(pppoe.SessionId),
+		}
+
+		if ll := p.LinkLayer(); ll != nil {
+			d.SrcMAC = ll.LinkFlow().Src().String()
+			d.DstMAC = ll.LinkFlow().Dst().String()
+		}
+
+		parsePPPoETags(d, pppoe.Payload)
+
+		return d
+	},
+	nil,
+)
+
+// parsePPPoETags decodes the tag list of a discovery packet.
+func parsePPPoETags(d *types.PPPoE, data []byte) {
+	for len(data) >= 4 {
+		var (
+			typ    = binary.BigEndian.Uint16(data[0:2])
+			length = int(binary.BigEndian.Uint16(data[2:4]))
+		)
+
+		if typ == pppoeTagEndOfList || 4+length > len(data) {
+			return
+		}
+
+		val := data[4 : 4+length]
+
+		switch typ {
+		case pppoeTagServiceName:
+			d.ServiceName = string(val)
+		case pppoeTagACName:
+			d.ACName = string(val)
+		case pppoeTagHostUniq:
+			d.HostUniq = hex.EncodeToString(val)
+		case pppoeTagACCookie:
+			d.ACCookie = hex.EncodeToString(val)
+		case pppoeTagServiceNameError:
+			d.Error = "Service-Name-Error: " + string(val)
+		case pppoeTagACSystemError:
+			d.Error = "AC-System-Error: " + string(val)
+		case pppoeTagGenericError:
+			d.Error = "Generic-Error: " + string(val)
+		}
+
+		data = data[4+length:]
+	}
+}
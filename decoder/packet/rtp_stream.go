@@ -0,0 +1,399 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	rtpVersion    = 2
+	rtpHeaderSize = 12
+
+	rtcpSenderReport   = 200
+	rtcpReceiverReport = 201
+	rtcpMaxPacketType  = 204
+
+	rtcpReportBlockSize = 24
+)
+
+// rtpStream tracks the sequence numbers and timing of a single RTP stream.
+type rtpStream struct {
+	*types.RTPStream
+
+	// key of the stream in the stream map
+	ident string
+
+	maxSeq uint16
+	// number of sequence number wrap arounds, shifted by 16 bits
+	cycles  int64
+	baseSeq int64
+
+	lastArrival   int64
+	lastTimestamp uint32
+
+	// interarrival jitter in timestamp units
+	jitter float64
+}
+
+// atomicRTPStreamMap contains all RTP streams and provides synchronized access.
+type atomicRTPStreamMap struct {
+	// capture time of the last check for timed out streams
+	// first field to guarantee 64 bit alignment for atomic access on 32 bit platforms
+	lastSweep int64
+
+	sync.Mutex
+	// flow and SSRC to stream
+	Items map[string]*rtpStream
+	// SSRC to streams, used to assign RTCP reports
+	BySSRC map[uint32][]*rtpStream
+}
+
+var (
+	rtpStreams = &atomicRTPStreamMap{
+		Items:  make(map[string]*rtpStream),
+		BySSRC: make(map[uint32][]*rtpStream),
+	}
+
+	// decoder instance used to write streams that ended before teardown, nil if the decoder is not enabled
+	rtpStreamWriter *Decoder
+)
+
+var rtpStreamDecoder = newPacketDecoder(
+	types.Type_NC_RTPStream,
+	"RTPStream",
+	"A RTPStream describes the quality of a RTP media stream negotiated via SIP, including packet loss, jitter and the codec",
+	func(d *Decoder) error {
+		rtpStreamWriter = d
+
+		return nil
+	},
+	func(p gopacket.Packet) proto.Message {
+		ts := p.Metadata().Timestamp.UnixNano()
+
+		for _, s := range expireRTPStreams(ts) {
+			rtpStreamWriter.writeRTPStream(s.RTPStream)
+		}
+
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		nl := p.NetworkLayer()
+		if nl == nil {
+			return nil
+		}
+
+		var (
+			srcIP = nl.NetworkFlow().Src().String()
+			dstIP = nl.NetworkFlow().Dst().String()
+		)
+
+		endpoint := lookupMediaEndpoint(dstIP, int(udp.DstPort))
+		if endpoint == nil {
+			// symmetric RTP, the packet was sent from an announced endpoint
+			endpoint = lookupMediaEndpoint(srcIP, int(udp.SrcPort))
+		}
+
+		if endpoint == nil {
+			return nil
+		}
+
+		atomic.StoreInt64(&endpoint.lastSeen, ts)
+
+		if isRTCP(udp.Payload) {
+			handleRTCP(udp.Payload)
+
+			return nil
+		}
+
+		if !endpoint.rtcp {
+			handleRTP(udp.Payload, endpoint.media, ts, srcIP, dstIP, int32(udp.SrcPort), int32(udp.DstPort))
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		rtpStreams.Lock()
+		for _, s := range rtpStreams.Items {
+			s.finish()
+			d.writeRTPStream(s.RTPStream)
+		}
+		rtpStreams.Unlock()
+
+		return nil
+	},
+)
+
+func lookupMediaEndpoint(ip string, port int) *mediaEndpoint {
+	sipMediaEndpoints.Lock()
+	defer sipMediaEndpoints.Unlock()
+
+	return sipMediaEndpoints.Items[net.JoinHostPort(ip, strconv.Itoa(port))]
+}
+
+// isRTCP distinguishes RTCP from RTP packets, which is needed when both are multiplexed on a single port (RFC 5761).
+func isRTCP(data []byte) bool {
+	return len(data) >= 8 && data[0]>>6 == rtpVersion && data[1] >= rtcpSenderReport && data[1] <= rtcpMaxPacketType
+}
+
+// handleRTP updates the statistics of the stream for a RTP packet.
+func handleRTP(data []byte, media *sdpMedia, ts int64, srcIP, dstIP string, srcPort, dstPort int32) {
+	if len(data) < rtpHeaderSize || data[0]>>6 != rtpVersion {
+		return
+	}
+
+	var (
+		pt        = int32(data[1] & 0x7f)
+		seq       = binary.BigEndian.Uint16(data[2:4])
+		timestamp = binary.BigEndian.Uint32(data[4:8])
+		ssrc      = binary.BigEndian.Uint32(data[8:12])
+		ident     = srcIP + ":" + strconv.Itoa(int(srcPort)) + "->" + dstIP + ":" + strconv.Itoa(int(dstPort)) + "/" + strconv.FormatUint(uint64(ssrc), 10)
+	)
+
+	rtpStreams.Lock()
+	defer rtpStreams.Unlock()
+
+	s, ok := rtpStreams.Items[ident]
+	if !ok {
+		codec := media.codec(pt)
+		s = &rtpStream{
+			RTPStream: &types.RTPStream{
+				TimestampFirst: ts,
+				SrcIP:          srcIP,
+				DstIP:          dstIP,
+				SrcPort:        srcPort,
+				DstPort:        dstPort,
+				SSRC:           ssrc,
+				PayloadType:    pt,
+				Codec:          codec.name,
+				ClockRate:      codec.clockRate,
+				CallID:         media.callID,
+			},
+			ident:         ident,
+			maxSeq:        seq,
+			baseSeq:       int64(seq),
+			lastArrival:   ts,
+			lastTimestamp: timestamp,
+		}
+
+		rtpStreams.Items[ident] = s
+		rtpStreams.BySSRC[ssrc] = append(rtpStreams.BySSRC[ssrc], s)
+	} else {
+		s.update(seq, timestamp, pt, ts)
+	}
+
+	s.TimestampLast = ts
+	s.NumPackets++
+	s.PayloadBytes += int64(len(data) - rtpHeaderSize)
+}
+
+// removeRTPStreams removes the streams selected by the function and returns them finished for writing.
+func removeRTPStreams(selected func(s *rtpStream) bool) (removed []*rtpStream) {
+	rtpStreams.Lock()
+	defer rtpStreams.Unlock()
+
+	for ident, s := range rtpStreams.Items {
+		if !selected(s) {
+			continue
+		}
+
+		delete(rtpStreams.Items, ident)
+
+		bySSRC := rtpStreams.BySSRC[s.SSRC]
+		for i, other := range bySSRC {
+			if other == s {
+				bySSRC = append(bySSRC[:i], bySSRC[i+1:]...)
+
+				break
+			}
+		}
+
+		if len(bySSRC) == 0 {
+			delete(rtpStreams.BySSRC, s.SSRC)
+		} else {
+			rtpStreams.BySSRC[s.SSRC] = bySSRC
+		}
+
+		s.finish()
+		removed = append(removed, s)
+	}
+
+	return removed
+}
+
+// expireRTPStreams removes the streams without packets for sipMediaTimeout and returns them,
+// it checks the streams at most once per sipSweepInterval of capture time.
+func expireRTPStreams(now int64) []*rtpStream {
+	last := atomic.LoadInt64(&rtpStreams.lastSweep)
+	if now-last < sipSweepInterval || !atomic.CompareAndSwapInt64(&rtpStreams.lastSweep, last, now) {
+		return nil
+	}
+
+	expired := removeRTPStreams(func(s *rtpStream) bool {
+		return now-s.TimestampLast >= sipMediaTimeout
+	})
+
+	if rtpStreamWriter == nil {
+		return nil
+	}
+
+	return expired
+}
+
+// update tracks the sequence number and interarrival jitter as described in RFC 3550 appendix A.
+func (s *rtpStream) update(seq uint16, timestamp uint32, pt int32, arrival int64) {
+	switch delta := seq - s.maxSeq; {
+	case delta == 0:
+		s.Duplicates++
+	case delta < 0x8000:
+		// in order, with a possible gap
+		if seq < s.maxSeq {
+			s.cycles += 1 << 16
+		}
+
+		if delta > 1 {
+			s.SequenceGaps++
+		}
+
+		s.maxSeq = seq
+	default:
+		s.OutOfOrder++
+	}
+
+	// packets with other payload types, e.g. telephone events or comfort noise, use different timing
+	if pt != s.PayloadType || s.ClockRate == 0 {
+		return
+	}
+
+	var (
+		// difference of the relative transit times in timestamp units
+		arrivalDelta   = float64(arrival-s.lastArrival) * float64(s.ClockRate) / 1e9
+		timestampDelta = float64(int32(timestamp - s.lastTimestamp))
+		d              = math.Abs(arrivalDelta - timestampDelta)
+	)
+
+	s.jitter += (d - s.jitter) / 16
+	s.lastArrival = arrival
+	s.lastTimestamp = timestamp
+
+	if j := s.jitterMillis(s.jitter); j > s.MaxJitter {
+		s.MaxJitter = j
+	}
+}
+
+func (s *rtpStream) jitterMillis(units float64) float64 {
+	if s.ClockRate == 0 {
+		return 0
+	}
+
+	return units / float64(s.ClockRate) * 1000
+}
+
+// finish calculates the loss statistics before the stream is written.
+func (s *rtpStream) finish() {
+	s.ExpectedPackets = s.cycles + int64(s.maxSeq) - s.baseSeq + 1
+	s.LostPackets = s.ExpectedPackets - (s.NumPackets - int64(s.Duplicates))
+
+	if s.LostPackets < 0 {
+		s.LostPackets = 0
+	}
+
+	if s.ExpectedPackets > 0 {
+		s.LossRate = float64(s.LostPackets) / float64(s.ExpectedPackets)
+	}
+
+	s.Jitter = s.jitterMillis(s.jitter)
+}
+
+// handleRTCP assigns sender and receiver reports from a compound RTCP packet to the RTP streams.
+func handleRTCP(data []byte) {
+	rtpStreams.Lock()
+	defer rtpStreams.Unlock()
+
+	for len(data) >= 8 && data[0]>>6 == rtpVersion {
+		var (
+			count  = int(data[0] & 0x1f)
+			typ    = data[1]
+			length = (int(binary.BigEndian.Uint16(data[2:4])) + 1) * 4
+			sender = binary.BigEndian.Uint32(data[4:8])
+			blocks int
+		)
+
+		if length > len(data) {
+			return
+		}
+
+		switch typ {
+		case rtcpSenderReport:
+			// header, sender SSRC and sender info
+			blocks = 28
+		case rtcpReceiverReport:
+			blocks = 8
+		}
+
+		for _, s := range rtpStreams.BySSRC[sender] {
+			s.RTCPPackets++
+		}
+
+		for i := 0; blocks != 0 && i < count; i++ {
+			off := blocks + i*rtcpReportBlockSize
+			if off+rtcpReportBlockSize > length {
+				break
+			}
+
+			var (
+				block  = data[off : off+rtcpReportBlockSize]
+				ssrc   = binary.BigEndian.Uint32(block[0:4])
+				lost   = int64(int32(binary.BigEndian.Uint32(block[4:8])<<8) >> 8)
+				jitter = binary.BigEndian.Uint32(block[12:16])
+			)
+
+			for _, s := range rtpStreams.BySSRC[ssrc] {
+				s.RTCPPackets++
+				s.ReportedLost = lost
+				s.ReportedJitter = s.jitterMillis(float64(jitter))
+			}
+		}
+
+		data = data[length:]
+	}
+}
+
+// writeRTPStream writes the stream.
+func (d *Decoder) writeRTPStream(s *types.RTPStream) {
+	if conf.ExportMetrics {
+		s.Inc()
+	}
+
+	atomic.AddInt64(&d.NumRecordsWritten, 1)
+
+	err := d.Writer.Write(s)
+	if err != nil {
+		log.Fatal("failed to write proto: ", err)
+	}
+}
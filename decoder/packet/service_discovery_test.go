@@ -0,0 +1,186 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	discoveryTestMAC = net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x53, 0x0a}
+	discoveryTestIP  = net.IP{192, 168, 1, 10}
+)
+
+// udpTestPacket serializes the payload into an ethernet frame sent from discoveryTestMAC and discoveryTestIP.
+func udpTestPacket(t *testing.T, dstPort layers.UDPPort, payload gopacket.SerializableLayer) gopacket.Packet {
+	eth := &layers.Ethernet{
+		SrcMAC:       discoveryTestMAC,
+		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0xfb},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      255,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    discoveryTestIP,
+		DstIP:    net.IP{224, 0, 0, 251},
+	}
+	udp := &layers.UDP{
+		SrcPort: dstPort,
+		DstPort: dstPort,
+	}
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, eth, ip, udp, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return decodeTestPacket(buf.Bytes(), layers.LayerTypeEthernet)
+}
+
+func TestMDNSAnnouncement(t *testing.T) {
+	const (
+		service  = "Living Room._googlecast._tcp.local"
+		hostname = "living-room.local"
+	)
+
+	p := udpTestPacket(t, mdnsPort, &layers.DNS{
+		QR: true,
+		AA: true,
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("_googlecast._tcp.local"), Type: layers.DNSTypePTR, Class: layers.DNSClassIN, PTR: []byte(service)},
+		},
+		Additionals: []layers.DNSResourceRecord{
+			{Name: []byte(service), Type: layers.DNSTypeSRV, Class: layers.DNSClassIN, SRV: layers.DNSSRV{Port: 8009, Name: []byte(hostname)}},
+			{Name: []byte(service), Type: layers.DNSTypeTXT, Class: layers.DNSClassIN, TXTs: [][]byte{[]byte("id=1234"), []byte("md=Chromecast")}},
+			{Name: []byte(hostname), Type: layers.DNSTypeA, Class: layers.DNSClassIN, IP: discoveryTestIP},
+		},
+	})
+
+	m, ok := mdnsDecoder.Handler(p).(*types.MDNS)
+	if !ok {
+		t.Fatal("no MDNS record")
+	}
+
+	if !m.Response || m.Model != "Chromecast" || m.SrcMAC != discoveryTestMAC.String() {
+		t.Fatal("unexpected record", m)
+	}
+
+	if len(m.ServiceTypes) != 1 || m.ServiceTypes[0] != "_googlecast._tcp.local" || len(m.Services) != 1 || m.Services[0] != service {
+		t.Fatal("unexpected services", m.ServiceTypes, m.Services)
+	}
+
+	if len(m.Targets) != 1 || m.Targets[0] != hostname+":8009" || len(m.Hostnames) != 1 || m.Addresses[0] != "192.168.1.10" {
+		t.Fatal("unexpected hosts", m.Targets, m.Hostnames, m.Addresses)
+	}
+
+	dp := &types.DeviceProfile{MacAddr: discoveryTestMAC.String()}
+	applyDeviceDiscovery(dp)
+
+	if !containsString(dp.Hostnames, hostname) || !containsString(dp.DeviceModels, "Chromecast") || !containsString(dp.Services, service) {
+		t.Fatal("discovery was not attached to the device profile", dp)
+	}
+}
+
+func TestLLMNRResponse(t *testing.T) {
+	p := udpTestPacket(t, llmnrPort, &layers.DNS{
+		QR:        true,
+		Questions: []layers.DNSQuestion{{Name: []byte("fileserver"), Type: layers.DNSTypeA, Class: layers.DNSClassIN}},
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("fileserver"), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 30, IP: discoveryTestIP},
+		},
+	})
+
+	l, ok := llmnrDecoder.Handler(p).(*types.LLMNR)
+	if !ok {
+		t.Fatal("no LLMNR record")
+	}
+
+	if !l.Response || l.Name != "fileserver" || l.Type != "A" || len(l.Addresses) != 1 || l.Addresses[0] != "192.168.1.10" {
+		t.Fatal("unexpected record", l)
+	}
+}
+
+func TestNBNSRegistration(t *testing.T) {
+	// DESKTOP-01<00> in first level encoding
+	const name = "EEEFFDELFEEPFACNDADB" + "CACACACACA" + "AA"
+
+	data := []byte{
+		0x12, 0x34, // transaction ID
+		0x29, 0x10, // registration request, recursion desired, broadcast
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+		0x20,
+	}
+	data = append(data, name...)
+	data = append(data,
+		0x00, 0x00, 0x20, 0x00, 0x01,
+		// additional record with a pointer to the question name
+		0xc0, 0x0c, 0x00, 0x20, 0x00, 0x01, 0x00, 0x04, 0x93, 0xe0, 0x00, 0x06, 0x00, 0x00,
+	)
+	data = append(data, discoveryTestIP...)
+
+	n, ok := nbnsDecoder.Handler(udpTestPacket(t, nbnsPort, gopacket.Payload(data))).(*types.NBNS)
+	if !ok {
+		t.Fatal("no NBNS record")
+	}
+
+	if n.Operation != "Registration" || n.Name != "DESKTOP-01" || n.Suffix != "Workstation" || len(n.Addresses) != 1 || n.Addresses[0] != "192.168.1.10" {
+		t.Fatal("unexpected record", n)
+	}
+}
+
+func TestParseNBSTAT(t *testing.T) {
+	data := []byte{2}
+	data = append(data, "DESKTOP-01     \x00\x04\x00"...)
+	data = append(data, "WORKGROUP      \x00\x84\x00"...)
+
+	names := parseNBSTAT(data)
+	if len(names) != 2 || names[0] != "DESKTOP-01<00>" || names[1] != "WORKGROUP<00>" {
+		t.Fatal("unexpected names", names)
+	}
+}
+
+func TestParseSSDP(t *testing.T) {
+	s := parseSSDP("NOTIFY * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"Location: http://192.168.1.10:49152/description.xml\r\n" +
+		"NT: urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+		"NTS: ssdp:alive\r\n" +
+		"SERVER: Linux/4.9 UPnP/1.0 Roku/9.4\r\n" +
+		"USN: uuid:2f402f80-da50-11e1-9b23-001788255acc::urn:schemas-upnp-org:device:MediaRenderer:1\r\n\r\n")
+
+	if s == nil {
+		t.Fatal("failed to parse SSDP message")
+	}
+
+	if s.Method != ssdpMethodNotify || s.NotificationType != "urn:schemas-upnp-org:device:MediaRenderer:1" || s.NotificationSubType != "ssdp:alive" ||
+		s.Location != "http://192.168.1.10:49152/description.xml" || s.Server != "Linux/4.9 UPnP/1.0 Roku/9.4" {
+		t.Fatal("unexpected record", s)
+	}
+
+	if parseSSDP("GET / HTTP/1.1\r\n\r\n") != nil {
+		t.Fatal("expected nil for non SSDP messages")
+	}
+}
@@ -0,0 +1,601 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	sipStateCompleted    = "Completed"
+	sipStateFailed       = "Failed"
+	sipStateCancelled    = "Cancelled"
+	sipStateUnterminated = "Unterminated"
+
+	sipRequestTerminated = 487
+
+	// sipMediaTimeout is the time without SIP messages or media packets after which a dialog or RTP stream
+	// is written and removed, it applies to calls whose BYE was not captured and to streams that stopped early.
+	sipMediaTimeout = int64(60 * time.Second)
+
+	// sipSweepInterval is the interval in capture time in which dialogs and streams are checked for the timeout.
+	sipSweepInterval = int64(10 * time.Second)
+)
+
+// rtpCodec is the encoding of a RTP payload type.
+type rtpCodec struct {
+	name      string
+	clockRate int32
+}
+
+func (c rtpCodec) String() string {
+	if c.clockRate == 0 {
+		return c.name
+	}
+
+	return c.name + "/" + strconv.Itoa(int(c.clockRate))
+}
+
+// static payload types from RFC 3551, used if the SDP has no rtpmap attribute.
+var rtpStaticPayloadTypes = map[int32]rtpCodec{
+	0:  {"PCMU", 8000},
+	3:  {"GSM", 8000},
+	4:  {"G723", 8000},
+	5:  {"DVI4", 8000},
+	6:  {"DVI4", 16000},
+	7:  {"LPC", 8000},
+	8:  {"PCMA", 8000},
+	9:  {"G722", 8000},
+	10: {"L16", 44100},
+	11: {"L16", 44100},
+	12: {"QCELP", 8000},
+	13: {"CN", 8000},
+	14: {"MPA", 90000},
+	15: {"G728", 8000},
+	16: {"DVI4", 11025},
+	17: {"DVI4", 22050},
+	18: {"G729", 8000},
+	25: {"CelB", 90000},
+	26: {"JPEG", 90000},
+	28: {"nv", 90000},
+	31: {"H261", 90000},
+	32: {"MPV", 90000},
+	33: {"MP2T", 90000},
+	34: {"H263", 90000},
+}
+
+// sdpMedia is a media description from a SDP offer or answer.
+type sdpMedia struct {
+	callID   string
+	kind     string
+	addr     string
+	port     int
+	rtcpPort int
+
+	// payload types in order of preference
+	payloadTypes []int32
+	codecs       map[int32]rtpCodec
+}
+
+// codec returns the encoding for the payload type.
+func (m *sdpMedia) codec(pt int32) rtpCodec {
+	if c, ok := m.codecs[pt]; ok {
+		return c
+	}
+
+	return rtpStaticPayloadTypes[pt]
+}
+
+// mediaEndpoint links the transport address of a RTP or RTCP flow to its media description.
+type mediaEndpoint struct {
+	// timestamp of the last packet sent to or from the endpoint, accessed atomically
+	// first field to guarantee 64 bit alignment for atomic access on 32 bit platforms
+	lastSeen int64
+
+	media *sdpMedia
+	rtcp  bool
+}
+
+// atomicMediaEndpointMap contains the RTP and RTCP endpoints announced via SDP and provides synchronized access.
+type atomicMediaEndpointMap struct {
+	sync.Mutex
+	// ip:port to media description
+	Items map[string]*mediaEndpoint
+}
+
+// sipDialog tracks the state of a SIP call.
+type sipDialog struct {
+	*types.SIPDialog
+	cancelled bool
+
+	// timestamp of the last SIP message of the dialog
+	lastSeen int64
+
+	// keys of the RTP and RTCP endpoints registered for the dialog
+	endpoints []string
+}
+
+// atomicSIPDialogMap contains all active SIP dialogs and provides synchronized access.
+type atomicSIPDialogMap struct {
+	// capture time of the last check for timed out dialogs
+	// first field to guarantee 64 bit alignment for atomic access on 32 bit platforms
+	lastSweep int64
+
+	sync.Mutex
+	// Call-ID to dialog
+	Items map[string]*sipDialog
+}
+
+var (
+	sipMediaEndpoints = &atomicMediaEndpointMap{
+		Items: make(map[string]*mediaEndpoint),
+	}
+
+	sipDialogs = &atomicSIPDialogMap{
+		Items: make(map[string]*sipDialog),
+	}
+
+	// decoder instance used to write timed out dialogs, nil if the decoder is not enabled
+	sipDialogWriter *Decoder
+)
+
+var sipDialogDecoder = newPacketDecoder(
+	types.Type_NC_SIPDialog,
+	"SIPDialog",
+	"A SIPDialog tracks a SIP call from the initial INVITE until it is terminated, including the negotiated codecs and the call duration",
+	func(d *Decoder) error {
+		sipDialogWriter = d
+
+		return nil
+	},
+	func(p gopacket.Packet) proto.Message {
+		for _, dialog := range expireSIPDialogs(p.Metadata().Timestamp.UnixNano()) {
+			sipDialogWriter.writeSIPDialog(dialog)
+		}
+
+		sip, ok := p.Layer(layers.LayerTypeSIP).(*layers.SIP)
+		if !ok {
+			return nil
+		}
+
+		var (
+			srcIP, dstIP     string
+			srcPort, dstPort int32
+		)
+
+		if nl := p.NetworkLayer(); nl != nil {
+			srcIP = nl.NetworkFlow().Src().String()
+			dstIP = nl.NetworkFlow().Dst().String()
+		}
+
+		switch t := p.TransportLayer().(type) {
+		case *layers.UDP:
+			srcPort, dstPort = int32(t.SrcPort), int32(t.DstPort)
+		case *layers.TCP:
+			srcPort, dstPort = int32(t.SrcPort), int32(t.DstPort)
+		}
+
+		if d := handleSIPMessage(sip, p.Metadata().Timestamp.UnixNano(), srcIP, dstIP, srcPort, dstPort); d != nil {
+			return d
+		}
+
+		return nil
+	},
+	func(d *Decoder) error {
+		// flush dialogs that have not been terminated
+		sipDialogs.Lock()
+		for _, dialog := range sipDialogs.Items {
+			dialog.State = sipStateUnterminated
+			d.writeSIPDialog(dialog.SIPDialog)
+		}
+		sipDialogs.Unlock()
+
+		return nil
+	},
+)
+
+// handleSIPMessage updates the dialog state for the message
+// and returns the dialog once the call has been terminated.
+func handleSIPMessage(sip *layers.SIP, ts int64, srcIP, dstIP string, srcPort, dstPort int32) *types.SIPDialog {
+	callID := sip.GetCallID()
+	if callID == "" {
+		return nil
+	}
+
+	sipDialogs.Lock()
+	defer sipDialogs.Unlock()
+
+	dialog, ok := sipDialogs.Items[callID]
+	if !ok {
+		// only INVITE requests start a new dialog
+		if sip.IsResponse || sip.Method != layers.SIPMethodInvite {
+			return nil
+		}
+
+		dialog = &sipDialog{
+			SIPDialog: &types.SIPDialog{
+				Timestamp:  ts,
+				CallID:     callID,
+				From:       sip.GetFrom(),
+				To:         sip.GetTo(),
+				UserAgent:  sip.GetUserAgent(),
+				CallerIP:   srcIP,
+				CalleeIP:   dstIP,
+				CallerPort: srcPort,
+				CalleePort: dstPort,
+			},
+		}
+		sipDialogs.Items[callID] = dialog
+	}
+
+	dialog.lastSeen = ts
+
+	if sip.IsResponse {
+		return handleSIPResponse(dialog, sip, ts)
+	}
+
+	switch sip.Method {
+	case layers.SIPMethodInvite:
+		// the initial INVITE or a re-INVITE carrying a new offer
+		dialog.addMedia(sip.Payload(), false)
+	case layers.SIPMethodAck:
+		// the answer is in the ACK if the INVITE had no offer
+		dialog.addMedia(sip.Payload(), true)
+	case layers.SIPMethodCancel:
+		// the dialog is completed with the 487 response to the INVITE
+		dialog.cancelled = true
+	case layers.SIPMethodBye:
+		dialog.EndTime = ts
+		if dialog.AnswerTime != 0 {
+			dialog.Duration = ts - dialog.AnswerTime
+		}
+
+		if srcIP == dialog.CallerIP && srcPort == dialog.CallerPort {
+			dialog.TerminatedBy = "caller"
+		} else {
+			dialog.TerminatedBy = "callee"
+		}
+
+		dialog.State = sipStateCompleted
+		endSIPDialog(dialog)
+
+		return dialog.SIPDialog
+	}
+
+	return nil
+}
+
+// handleSIPResponse processes responses to INVITE requests.
+func handleSIPResponse(dialog *sipDialog, sip *layers.SIP, ts int64) *types.SIPDialog {
+	if sip.Method != layers.SIPMethodInvite {
+		return nil
+	}
+
+	// provisional responses can carry an early answer, e.g. 183 Session Progress
+	dialog.addMedia(sip.Payload(), true)
+
+	if sip.ResponseCode < 200 || dialog.FinalStatus != 0 {
+		return nil
+	}
+
+	dialog.FinalStatus = int32(sip.ResponseCode)
+	dialog.FinalReason = sip.ResponseStatus
+	dialog.SetupTime = ts - dialog.Timestamp
+
+	if sip.ResponseCode < 300 {
+		dialog.AnswerTime = ts
+
+		return nil
+	}
+
+	// the call failed or was cancelled
+	dialog.EndTime = ts
+	dialog.State = sipStateFailed
+
+	if dialog.cancelled || sip.ResponseCode == sipRequestTerminated {
+		dialog.State = sipStateCancelled
+	}
+
+	endSIPDialog(dialog)
+
+	return dialog.SIPDialog
+}
+
+// addMedia registers the RTP and RTCP endpoints of a SDP body
+// and updates the codecs of the dialog. Codecs from an answer replace those from the offer.
+func (d *sipDialog) addMedia(body []byte, answer bool) {
+	media := parseSDP(body)
+	if len(media) == 0 {
+		return
+	}
+
+	replaceCodecs := answer || len(d.Codecs) == 0
+	if replaceCodecs {
+		d.Codecs = d.Codecs[:0]
+	}
+
+	for _, m := range media {
+		m.callID = d.CallID
+
+		rtp := net.JoinHostPort(m.addr, strconv.Itoa(m.port))
+		if !containsString(d.MediaEndpoints, rtp) {
+			d.MediaEndpoints = append(d.MediaEndpoints, rtp)
+		}
+
+		d.addEndpoint(rtp, &mediaEndpoint{media: m})
+		if m.rtcpPort != m.port {
+			d.addEndpoint(net.JoinHostPort(m.addr, strconv.Itoa(m.rtcpPort)), &mediaEndpoint{media: m, rtcp: true})
+		}
+
+		if !replaceCodecs {
+			continue
+		}
+
+		for _, pt := range m.payloadTypes {
+			if c := m.codec(pt); c.name != "" && !containsString(d.Codecs, c.String()) {
+				d.Codecs = append(d.Codecs, c.String())
+			}
+		}
+	}
+}
+
+// addEndpoint registers the media endpoint for the dialog.
+// A previous registration of the address by another dialog is replaced.
+func (d *sipDialog) addEndpoint(addr string, e *mediaEndpoint) {
+	e.lastSeen = d.lastSeen
+
+	sipMediaEndpoints.Lock()
+	sipMediaEndpoints.Items[addr] = e
+	sipMediaEndpoints.Unlock()
+
+	if !containsString(d.endpoints, addr) {
+		d.endpoints = append(d.endpoints, addr)
+	}
+}
+
+// endSIPDialog removes the dialog and its media endpoints and writes the RTP streams of the call.
+// The sipDialogs lock must be held by the caller.
+func endSIPDialog(d *sipDialog) {
+	delete(sipDialogs.Items, d.CallID)
+
+	sipMediaEndpoints.Lock()
+	for _, addr := range d.endpoints {
+		// the address may have been announced by a later call in the meantime
+		if e, ok := sipMediaEndpoints.Items[addr]; ok && e.media.callID == d.CallID {
+			delete(sipMediaEndpoints.Items, addr)
+		}
+	}
+	sipMediaEndpoints.Unlock()
+
+	for _, s := range removeRTPStreams(func(s *rtpStream) bool { return s.CallID == d.CallID }) {
+		if rtpStreamWriter != nil {
+			rtpStreamWriter.writeRTPStream(s.RTPStream)
+		}
+	}
+}
+
+// lastActivity returns the timestamp of the last SIP message or media packet of the dialog.
+// The sipDialogs lock must be held by the caller.
+func (d *sipDialog) lastActivity() int64 {
+	last := d.lastSeen
+
+	sipMediaEndpoints.Lock()
+	for _, addr := range d.endpoints {
+		if e, ok := sipMediaEndpoints.Items[addr]; ok && e.media.callID == d.CallID {
+			if ts := atomic.LoadInt64(&e.lastSeen); ts > last {
+				last = ts
+			}
+		}
+	}
+	sipMediaEndpoints.Unlock()
+
+	return last
+}
+
+// expireSIPDialogs ends the dialogs without SIP messages or media packets for sipMediaTimeout
+// and returns them, it checks the dialogs at most once per sipSweepInterval of capture time.
+func expireSIPDialogs(now int64) (expired []*types.SIPDialog) {
+	last := atomic.LoadInt64(&sipDialogs.lastSweep)
+	if now-last < sipSweepInterval || !atomic.CompareAndSwapInt64(&sipDialogs.lastSweep, last, now) {
+		return nil
+	}
+
+	sipDialogs.Lock()
+	defer sipDialogs.Unlock()
+
+	for _, d := range sipDialogs.Items {
+		active := d.lastActivity()
+		if now-active < sipMediaTimeout {
+			continue
+		}
+
+		d.State = sipStateUnterminated
+		d.EndTime = active
+
+		if d.AnswerTime != 0 {
+			d.Duration = active - d.AnswerTime
+		}
+
+		endSIPDialog(d)
+
+		if sipDialogWriter != nil {
+			expired = append(expired, d.SIPDialog)
+		}
+	}
+
+	return expired
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSDP extracts the media descriptions with a port from a session description (RFC 4566).
+// media with port zero has been rejected and is ignored.
+func parseSDP(body []byte) []*sdpMedia {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("v=")) {
+		return nil
+	}
+
+	var (
+		media       []*sdpMedia
+		current     *sdpMedia
+		sessionAddr string
+		scanner     = bufio.NewScanner(bytes.NewReader(body))
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+
+		val := line[2:]
+
+		switch line[0] {
+		case 'c':
+			// c=IN IP4 192.168.0.1
+			fields := strings.Fields(val)
+			if len(fields) < 3 {
+				continue
+			}
+
+			// strip the TTL and number of addresses from multicast addresses
+			addr := strings.Split(fields[2], "/")[0]
+			if current != nil {
+				current.addr = addr
+			} else {
+				sessionAddr = addr
+			}
+		case 'm':
+			// m=audio 49170 RTP/AVP 0 8 101
+			fields := strings.Fields(val)
+			if len(fields) < 3 {
+				current = nil
+
+				continue
+			}
+
+			port, err := strconv.Atoi(strings.Split(fields[1], "/")[0])
+			if err != nil || port == 0 || !strings.HasPrefix(fields[2], "RTP/") {
+				current = nil
+
+				continue
+			}
+
+			current = &sdpMedia{
+				kind:     fields[0],
+				addr:     sessionAddr,
+				port:     port,
+				rtcpPort: port + 1,
+				codecs:   make(map[int32]rtpCodec),
+			}
+
+			for _, f := range fields[3:] {
+				if pt, errPT := strconv.Atoi(f); errPT == nil {
+					current.payloadTypes = append(current.payloadTypes, int32(pt))
+				}
+			}
+
+			media = append(media, current)
+		case 'a':
+			if current == nil {
+				continue
+			}
+
+			switch {
+			case strings.HasPrefix(val, "rtpmap:"):
+				// a=rtpmap:101 telephone-event/8000
+				fields := strings.Fields(strings.TrimPrefix(val, "rtpmap:"))
+				if len(fields) < 2 {
+					continue
+				}
+
+				pt, err := strconv.Atoi(fields[0])
+				if err != nil {
+					continue
+				}
+
+				enc := strings.Split(fields[1], "/")
+				c := rtpCodec{name: enc[0]}
+
+				if len(enc) > 1 {
+					if rate, errRate := strconv.Atoi(enc[1]); errRate == nil {
+						c.clockRate = int32(rate)
+					}
+				}
+
+				current.codecs[int32(pt)] = c
+			case strings.HasPrefix(val, "rtcp:"):
+				// a=rtcp:53021 IN IP4 126.16.64.4
+				fields := strings.Fields(strings.TrimPrefix(val, "rtcp:"))
+				if len(fields) == 0 {
+					continue
+				}
+
+				if port, err := strconv.Atoi(fields[0]); err == nil {
+					current.rtcpPort = port
+				}
+			case val == "rtcp-mux":
+				current.rtcpPort = current.port
+			}
+		}
+	}
+
+	// drop media without a connection address
+	n := 0
+
+	for _, m := range media {
+		if m.addr != "" {
+			media[n] = m
+			n++
+		}
+	}
+
+	return media[:n]
+}
+
+// writeSIPDialog writes the dialog.
+func (d *Decoder) writeSIPDialog(dialog *types.SIPDialog) {
+	if conf.ExportMetrics {
+		dialog.Inc()
+	}
+
+	atomic.AddInt64(&d.NumRecordsWritten, 1)
+
+	err := d.Writer.Write(dialog)
+	if err != nil {
+		log.Fatal("failed to write proto: ", err)
+	}
+}
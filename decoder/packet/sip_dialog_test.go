@@ -0,0 +1,413 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const testSDPOffer = `v=0
+o=alice 2890844526 2890844526 IN IP4 10.0.0.1
+s=-
+c=IN IP4 10.0.0.1
+t=0 0
+m=audio 49170 RTP/AVP 0 8 101
+a=rtpmap:101 telephone-event/8000
+m=video 0 RTP/AVP 31
+`
+
+const testSDPAnswer = `v=0
+o=bob 2808844564 2808844564 IN IP4 10.0.0.2
+s=-
+t=0 0
+m=audio 3456 RTP/AVP 8
+c=IN IP4 10.0.0.2
+a=rtcp-mux
+`
+
+func sipMessage(t *testing.T, lines ...string) *layers.SIP {
+	data := strings.Join(lines, "\n")
+	data = strings.ReplaceAll(data, "\n", "\r\n")
+
+	sip := layers.NewSIP()
+	if err := sip.DecodeFromBytes([]byte(data), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+
+	return sip
+}
+
+func TestParseSDP(t *testing.T) {
+	media := parseSDP([]byte(testSDPOffer))
+	if len(media) != 1 {
+		t.Fatal("expected one active media description, got", len(media))
+	}
+
+	m := media[0]
+	if m.addr != "10.0.0.1" || m.port != 49170 || m.rtcpPort != 49171 || len(m.payloadTypes) != 3 {
+		t.Fatal("unexpected media description", m)
+	}
+
+	if c := m.codec(0); c.String() != "PCMU/8000" {
+		t.Fatal("unexpected static codec", c)
+	}
+
+	if c := m.codec(101); c.String() != "telephone-event/8000" {
+		t.Fatal("unexpected dynamic codec", c)
+	}
+
+	media = parseSDP([]byte(testSDPAnswer))
+	if len(media) != 1 || media[0].addr != "10.0.0.2" || media[0].rtcpPort != 3456 {
+		t.Fatal("unexpected answer", media)
+	}
+
+	if parseSDP([]byte("hello")) != nil {
+		t.Fatal("expected no media for non SDP body")
+	}
+}
+
+func TestSIPDialog(t *testing.T) {
+	var (
+		start  = time.Now()
+		callID = "a84b4c76e66710@pc33.example.com"
+		header = []string{
+			"Via: SIP/2.0/UDP pc33.example.com",
+			"From: Alice <sip:alice@example.com>;tag=1928301774",
+			"To: Bob <sip:bob@example.com>",
+			"Call-ID: " + callID,
+		}
+	)
+
+	invite := sipMessage(t, append(append([]string{"INVITE sip:bob@example.com SIP/2.0"}, header...),
+		"CSeq: 1 INVITE", "Content-Type: application/sdp", "", testSDPOffer)...)
+	if d := handleSIPMessage(invite, start.UnixNano(), "10.0.0.1", "10.0.0.2", 5060, 5060); d != nil {
+		t.Fatal("dialog must not be finished after INVITE")
+	}
+
+	ringing := sipMessage(t, append(append([]string{"SIP/2.0 180 Ringing"}, header...), "CSeq: 1 INVITE", "", "")...)
+	handleSIPMessage(ringing, start.Add(time.Second).UnixNano(), "10.0.0.2", "10.0.0.1", 5060, 5060)
+
+	ok := sipMessage(t, append(append([]string{"SIP/2.0 200 OK"}, header...),
+		"CSeq: 1 INVITE", "Content-Type: application/sdp", "", testSDPAnswer)...)
+	handleSIPMessage(ok, start.Add(2*time.Second).UnixNano(), "10.0.0.2", "10.0.0.1", 5060, 5060)
+
+	if lookupMediaEndpoint("10.0.0.2", 3456) == nil || lookupMediaEndpoint("10.0.0.1", 49171) == nil {
+		t.Fatal("expected media endpoints to be registered")
+	}
+
+	bye := sipMessage(t, append(append([]string{"BYE sip:alice@example.com SIP/2.0"}, header...), "CSeq: 1 BYE", "", "")...)
+
+	d := handleSIPMessage(bye, start.Add(62*time.Second).UnixNano(), "10.0.0.2", "10.0.0.1", 5060, 5060)
+	if d == nil {
+		t.Fatal("expected dialog after BYE")
+	}
+
+	if d.State != sipStateCompleted || d.FinalStatus != 200 || d.TerminatedBy != "callee" {
+		t.Fatal("unexpected dialog state", d.State, d.FinalStatus, d.TerminatedBy)
+	}
+
+	if d.Duration != int64(60*time.Second) || d.SetupTime != int64(2*time.Second) {
+		t.Fatal("unexpected timing", d.Duration, d.SetupTime)
+	}
+
+	if len(d.Codecs) != 1 || d.Codecs[0] != "PCMA/8000" {
+		t.Fatal("expected codec from the answer, got", d.Codecs)
+	}
+
+	if len(d.MediaEndpoints) != 2 {
+		t.Fatal("unexpected media endpoints", d.MediaEndpoints)
+	}
+
+	if _, exists := sipDialogs.Items[callID]; exists {
+		t.Fatal("terminated dialog must be removed")
+	}
+}
+
+func rtpPacket(seq uint16, timestamp uint32) []byte {
+	b := make([]byte, rtpHeaderSize+160)
+	b[0] = rtpVersion << 6
+	b[1] = 8
+	binary.BigEndian.PutUint16(b[2:], seq)
+	binary.BigEndian.PutUint32(b[4:], timestamp)
+	binary.BigEndian.PutUint32(b[8:], 0xcafe)
+
+	return b
+}
+
+func TestRTPStream(t *testing.T) {
+	var (
+		media = &sdpMedia{callID: "test", codecs: map[int32]rtpCodec{}}
+		start = time.Now().UnixNano()
+		ms20  = int64(20 * time.Millisecond)
+	)
+
+	// sequence numbers wrap around, 65534 is lost and 1 arrives late
+	for i, seq := range []uint16{65530, 65531, 65532, 65533, 65535, 0, 2, 1, 3, 3} {
+		handleRTP(rtpPacket(seq, uint32(i)*160), media, start+int64(i)*ms20, "10.0.0.1", "10.0.0.2", 4000, 3456)
+	}
+
+	s := rtpStreams.Items["10.0.0.1:4000->10.0.0.2:3456/51966"]
+	if s == nil {
+		t.Fatal("stream not found")
+	}
+
+	// receiver report with 2 packets lost and a jitter of 80 timestamp units
+	rr := make([]byte, 32)
+	rr[0] = rtpVersion<<6 | 1
+	rr[1] = rtcpReceiverReport
+	binary.BigEndian.PutUint16(rr[2:], 7)
+	binary.BigEndian.PutUint32(rr[4:], 0xbeef)
+	binary.BigEndian.PutUint32(rr[8:], 0xcafe)
+	binary.BigEndian.PutUint32(rr[12:], 2)
+	binary.BigEndian.PutUint32(rr[20:], 80)
+
+	if !isRTCP(rr) || isRTCP(rtpPacket(1, 1)) {
+		t.Fatal("failed to distinguish RTP and RTCP")
+	}
+
+	handleRTCP(rr)
+	s.finish()
+
+	if s.Codec != "PCMA" || s.ClockRate != 8000 || s.NumPackets != 10 {
+		t.Fatal("unexpected stream", s.Codec, s.ClockRate, s.NumPackets)
+	}
+
+	if s.ExpectedPackets != 10 || s.LostPackets != 1 || s.SequenceGaps != 2 || s.OutOfOrder != 1 || s.Duplicates != 1 {
+		t.Fatal("unexpected sequence statistics", s.ExpectedPackets, s.LostPackets, s.SequenceGaps, s.OutOfOrder, s.Duplicates)
+	}
+
+	// packets were sent with constant spacing
+	if s.Jitter > 0.001 {
+		t.Fatal("unexpected jitter", s.Jitter)
+	}
+
+	if s.RTCPPackets != 1 || s.ReportedLost != 2 || s.ReportedJitter != 10 {
+		t.Fatal("unexpected RTCP statistics", s.RTCPPackets, s.ReportedLost, s.ReportedJitter)
+	}
+}
+
+// recordWriter collects the written audit records.
+type recordWriter struct {
+	records []proto.Message
+}
+
+func (w *recordWriter) Write(msg proto.Message) error {
+	w.records = append(w.records, msg)
+
+	return nil
+}
+
+func (w *recordWriter) WriteHeader(types.Type) error { return nil }
+
+func (w *recordWriter) Close(int64) (string, int64) { return "", 0 }
+
+// startCall sends an INVITE and the answer for a call between 10.0.0.1 and 10.0.0.2 and returns the SIP header.
+func startCall(t *testing.T, callID string, start time.Time) []string {
+	t.Helper()
+
+	header := []string{
+		"Via: SIP/2.0/UDP pc33.example.com",
+		"From: Alice <sip:alice@example.com>;tag=1928301774",
+		"To: Bob <sip:bob@example.com>",
+		"Call-ID: " + callID,
+	}
+
+	invite := sipMessage(t, append(append([]string{"INVITE sip:bob@example.com SIP/2.0"}, header...),
+		"CSeq: 1 INVITE", "Content-Type: application/sdp", "", testSDPOffer)...)
+	handleSIPMessage(invite, start.UnixNano(), "10.0.0.1", "10.0.0.2", 5060, 5060)
+
+	ok := sipMessage(t, append(append([]string{"SIP/2.0 200 OK"}, header...),
+		"CSeq: 1 INVITE", "Content-Type: application/sdp", "", testSDPAnswer)...)
+	handleSIPMessage(ok, start.Add(time.Second).UnixNano(), "10.0.0.2", "10.0.0.1", 5060, 5060)
+
+	return header
+}
+
+// sendRTP passes a RTP packet to the answered media endpoint of the call, as the decoder handler does.
+func sendRTP(t *testing.T, seq uint16, ts time.Time) {
+	t.Helper()
+
+	e := lookupMediaEndpoint("10.0.0.2", 3456)
+	if e == nil {
+		t.Fatal("media endpoint not registered")
+	}
+
+	atomic.StoreInt64(&e.lastSeen, ts.UnixNano())
+	handleRTP(rtpPacket(seq, uint32(seq)*160), e.media, ts.UnixNano(), "10.0.0.1", "10.0.0.2", 5004, 3456)
+}
+
+// callStreams returns the number of tracked RTP streams of the call.
+func callStreams(callID string) (n int) {
+	rtpStreams.Lock()
+	defer rtpStreams.Unlock()
+
+	for _, s := range rtpStreams.Items {
+		if s.CallID == callID {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestSIPDialogEnd(t *testing.T) {
+	SetConfig(&config.Config{})
+
+	w := &recordWriter{}
+	rtpStreamWriter = &Decoder{Writer: w}
+
+	defer func() {
+		rtpStreamWriter = nil
+	}()
+
+	var (
+		start  = time.Now()
+		callID = "end@pc33.example.com"
+		header = startCall(t, callID, start)
+	)
+
+	for i := 0; i < 5; i++ {
+		sendRTP(t, uint16(i), start.Add(2*time.Second+time.Duration(i)*20*time.Millisecond))
+	}
+
+	bye := sipMessage(t, append(append([]string{"BYE sip:bob@example.com SIP/2.0"}, header...), "CSeq: 2 BYE", "", "")...)
+	if d := handleSIPMessage(bye, start.Add(10*time.Second).UnixNano(), "10.0.0.1", "10.0.0.2", 5060, 5060); d == nil {
+		t.Fatal("expected dialog after BYE")
+	}
+
+	if lookupMediaEndpoint("10.0.0.2", 3456) != nil || lookupMediaEndpoint("10.0.0.1", 49170) != nil {
+		t.Fatal("expected the media endpoints of the dialog to be removed")
+	}
+
+	if n := callStreams(callID); n != 0 {
+		t.Fatal("expected the streams of the dialog to be removed, got", n)
+	}
+
+	if len(w.records) != 1 {
+		t.Fatal("expected the stream of the dialog to be written, got", len(w.records), "records")
+	}
+
+	if s, ok := w.records[0].(*types.RTPStream); !ok || s.CallID != callID || s.NumPackets != 5 || s.ExpectedPackets != 5 {
+		t.Fatal("unexpected stream", w.records[0])
+	}
+}
+
+func TestSIPMediaTimeout(t *testing.T) {
+	SetConfig(&config.Config{})
+
+	var (
+		dialogs = &recordWriter{}
+		streams = &recordWriter{}
+	)
+
+	sipDialogWriter = &Decoder{Writer: dialogs}
+	rtpStreamWriter = &Decoder{Writer: streams}
+
+	defer func() {
+		sipDialogWriter = nil
+		rtpStreamWriter = nil
+	}()
+
+	var (
+		start  = time.Now()
+		callID = "timeout@pc33.example.com"
+		_      = startCall(t, callID, start)
+		active = start.Add(30 * time.Second)
+	)
+
+	atomic.StoreInt64(&sipDialogs.lastSweep, 0)
+	atomic.StoreInt64(&rtpStreams.lastSweep, 0)
+
+	sendRTP(t, 1, active)
+
+	// the media keeps the dialog alive after the last SIP message
+	if d := expireSIPDialogs(start.Add(80 * time.Second).UnixNano()); len(d) != 0 {
+		t.Fatal("dialog with recent media must not expire")
+	}
+
+	// streams left over by other tests expire as well
+	expireRTPStreams(start.Add(80 * time.Second).UnixNano())
+
+	if callStreams(callID) != 1 {
+		t.Fatal("stream with recent packets must not expire")
+	}
+
+	// checks within the sweep interval are skipped
+	if d := expireSIPDialogs(start.Add(85 * time.Second).UnixNano()); d != nil {
+		t.Fatal("expected no check within the sweep interval")
+	}
+
+	expired := expireSIPDialogs(start.Add(95 * time.Second).UnixNano())
+	if len(expired) != 1 || expired[0].CallID != callID {
+		t.Fatal("expected the idle dialog to expire, got", expired)
+	}
+
+	if d := expired[0]; d.State != sipStateUnterminated || d.EndTime != active.UnixNano() || d.Duration != int64(29*time.Second) {
+		t.Fatal("unexpected expired dialog", d.State, d.EndTime, d.Duration)
+	}
+
+	if _, ok := sipDialogs.Items[callID]; ok || lookupMediaEndpoint("10.0.0.2", 3456) != nil {
+		t.Fatal("expected the dialog and its endpoints to be removed")
+	}
+
+	// the streams of the dialog are written when it ends
+	if callStreams(callID) != 0 {
+		t.Fatal("expected the stream of the expired dialog to be removed")
+	}
+
+	var written int
+
+	for _, r := range streams.records {
+		if r.(*types.RTPStream).CallID == callID {
+			written++
+		}
+	}
+
+	if written != 1 {
+		t.Fatal("expected the stream of the expired dialog to be written once, got", written)
+	}
+
+	// streams that outlive their dialog expire on their own
+	orphan := &sdpMedia{callID: "orphan", codecs: map[int32]rtpCodec{}}
+	handleRTP(rtpPacket(1, 160), orphan, active.UnixNano(), "10.0.0.3", "10.0.0.4", 4000, 4002)
+
+	rtpStreams.Lock()
+	s := rtpStreams.Items["10.0.0.3:4000->10.0.0.4:4002/51966"]
+	rtpStreams.Unlock()
+
+	if s == nil {
+		t.Fatal("stream not found")
+	}
+
+	if expiredStreams := expireRTPStreams(active.Add(time.Minute).UnixNano()); len(expiredStreams) != 1 || expiredStreams[0] != s {
+		t.Fatal("expected the idle stream to expire, got", expiredStreams)
+	}
+
+	for _, other := range rtpStreams.BySSRC[s.SSRC] {
+		if other == s {
+			t.Fatal("expired stream must be removed from the SSRC index")
+		}
+	}
+}
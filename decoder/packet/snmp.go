@@ -0,0 +1,437 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"unicode"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/ber"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+const (
+	snmpPort     = 161
+	snmpTrapPort = 162
+
+	snmpVersion1  = 0
+	snmpVersion2c = 1
+	snmpVersion3  = 3
+
+	// user-based security model
+	snmpSecurityModelUSM = 3
+
+	// PDU types, encoded as context specific tags.
+	snmpGetRequest     = 0
+	snmpGetNextRequest = 1
+	snmpResponse       = 2
+	snmpSetRequest     = 3
+	snmpTrap           = 4
+	snmpGetBulkRequest = 5
+	snmpInformRequest  = 6
+	snmpV2Trap         = 7
+	snmpReport         = 8
+
+	// variables of SNMPv2 notifications
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSNMPTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+
+	serviceSNMP = "SNMP"
+)
+
+var (
+	errSNMPInvalidMessage = errors.New("invalid SNMP message")
+	errSNMPInvalidPDU     = errors.New("invalid SNMP PDU")
+
+	snmpVersions = map[int64]string{
+		snmpVersion1:  "v1",
+		snmpVersion2c: "v2c",
+		snmpVersion3:  "v3",
+	}
+
+	snmpPDUTypes = map[int]string{
+		snmpGetRequest:     "GetRequest",
+		snmpGetNextRequest: "GetNextRequest",
+		snmpResponse:       "Response",
+		snmpSetRequest:     "SetRequest",
+		snmpTrap:           "Trap",
+		snmpGetBulkRequest: "GetBulkRequest",
+		snmpInformRequest:  "InformRequest",
+		snmpV2Trap:         "SNMPv2-Trap",
+		snmpReport:         "Report",
+	}
+
+	// error-status values from RFC 3416.
+	snmpErrors = []string{
+		"noError",
+		"tooBig",
+		"noSuchName",
+		"badValue",
+		"readOnly",
+		"genErr",
+		"noAccess",
+		"wrongType",
+		"wrongLength",
+		"wrongEncoding",
+		"wrongValue",
+		"noCreation",
+		"inconsistentValue",
+		"resourceUnavailable",
+		"commitFailed",
+		"undoFailed",
+		"authorizationError",
+		"notWritable",
+		"inconsistentName",
+	}
+
+	// application specific types from the SNMPv2 SMI.
+	snmpApplicationTypes = map[int]string{
+		0: "IpAddress",
+		1: "Counter32",
+		2: "Gauge32",
+		3: "TimeTicks",
+		4: "Opaque",
+		6: "Counter64",
+		7: "Uinteger32",
+	}
+
+	// exceptions in responses, encoded as context specific tags.
+	snmpExceptions = map[int]string{
+		0: "noSuchObject",
+		1: "noSuchInstance",
+		2: "endOfMibView",
+	}
+)
+
+var snmpDecoder = newPacketDecoder(
+	types.Type_NC_SNMP,
+	"SNMP",
+	"The Simple Network Management Protocol is used to monitor and configure network devices",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		// tunneled packets are identified by their innermost network and transport layers, like connections
+		encapsulation, nl, tl := utils.DecodeEncapsulation(p)
+
+		udp, ok := tl.(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		if !isSNMPPort(udp.SrcPort) && !isSNMPPort(udp.DstPort) {
+			return nil
+		}
+
+		s, err := parseSNMP(udp.Payload)
+		if err != nil {
+			decoderLog.Debug("failed to parse SNMP message", zap.Error(err))
+
+			return nil
+		}
+
+		s.Timestamp = p.Metadata().Timestamp.UnixNano()
+		s.SrcPort = int32(udp.SrcPort)
+		s.DstPort = int32(udp.DstPort)
+
+		if nl != nil {
+			s.SrcIP = nl.NetworkFlow().Src().String()
+			s.DstIP = nl.NetworkFlow().Dst().String()
+		}
+
+		if s.Community != "" && credentials.Decoder.Writer != nil {
+			credentials.WriteCredentials(&types.Credentials{
+				Timestamp: s.Timestamp,
+				Service:   serviceSNMP,
+				Flow:      utils.CreateFlowIdent(s.SrcIP, strconv.Itoa(int(s.SrcPort)), s.DstIP, strconv.Itoa(int(s.DstPort)), encapsulation.String()),
+				Password:  s.Community,
+				Notes:     "SNMP" + s.Version + " community, " + s.PDUType,
+			})
+		}
+
+		return s
+	},
+	nil,
+)
+
+func isSNMPPort(port layers.UDPPort) bool {
+	return port == snmpPort || port == snmpTrapPort
+}
+
+// parseSNMP decodes a SNMP message:
+// v1 and v2c messages carry the community and the PDU, v3 messages a header, the security parameters and a scoped PDU.
+func parseSNMP(data []byte) (*types.SNMP, error) {
+	msg, _, err := ber.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !msg.Is(ber.ClassUniversal, ber.TagSequence) {
+		return nil, errSNMPInvalidMessage
+	}
+
+	fields, err := msg.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) < 3 || !fields[0].Is(ber.ClassUniversal, ber.TagInteger) {
+		return nil, errSNMPInvalidMessage
+	}
+
+	version, ok := snmpVersions[fields[0].Int()]
+	if !ok {
+		return nil, errSNMPInvalidMessage
+	}
+
+	s := &types.SNMP{
+		Version: version,
+	}
+
+	if fields[0].Int() == snmpVersion3 {
+		return s, parseSNMPv3(s, fields)
+	}
+
+	if !fields[1].Is(ber.ClassUniversal, ber.TagOctetString) {
+		return nil, errSNMPInvalidMessage
+	}
+
+	s.Community = fields[1].String()
+
+	return s, parseSNMPPDU(s, fields[2])
+}
+
+// parseSNMPv3 decodes the header, the user-based security model parameters and the scoped PDU, if it is not encrypted.
+func parseSNMPv3(s *types.SNMP, fields []*ber.Element) error {
+	if len(fields) < 4 {
+		return errSNMPInvalidMessage
+	}
+
+	header, err := fields[1].Children()
+	if err != nil {
+		return err
+	}
+
+	if len(header) < 4 {
+		return errSNMPInvalidMessage
+	}
+
+	s.MessageID = int32(header[0].Int())
+
+	var flags byte
+	if len(header[2].Content) > 0 {
+		flags = header[2].Content[0]
+	}
+
+	switch {
+	case flags&0x02 != 0:
+		s.SecurityLevel = "authPriv"
+	case flags&0x01 != 0:
+		s.SecurityLevel = "authNoPriv"
+	default:
+		s.SecurityLevel = "noAuthNoPriv"
+	}
+
+	if header[3].Int() == snmpSecurityModelUSM {
+		// the security parameters are encoded as BER inside of an octet string
+		if usm, _, errUSM := ber.Parse(fields[2].Content); errUSM == nil {
+			if params, errParams := usm.Children(); errParams == nil && len(params) >= 4 {
+				s.EngineID = hex.EncodeToString(params[0].Content)
+				s.EngineBoots = int32(params[1].Int())
+				s.EngineTime = int32(params[2].Int())
+				s.User = params[3].String()
+			}
+		}
+	}
+
+	// encrypted PDUs are transported as octet string
+	if fields[3].Is(ber.ClassUniversal, ber.TagOctetString) {
+		s.Encrypted = true
+
+		return nil
+	}
+
+	scoped, err := fields[3].Children()
+	if err != nil {
+		return err
+	}
+
+	if len(scoped) < 3 {
+		return errSNMPInvalidMessage
+	}
+
+	s.ContextEngineID = hex.EncodeToString(scoped[0].Content)
+	s.ContextName = scoped[1].String()
+
+	return parseSNMPPDU(s, scoped[2])
+}
+
+// parseSNMPPDU decodes the PDU fields and variable bindings.
+func parseSNMPPDU(s *types.SNMP, pdu *ber.Element) error {
+	name, ok := snmpPDUTypes[pdu.Tag]
+	if pdu.Class != ber.ClassContext || !pdu.Constructed || !ok {
+		return errSNMPInvalidPDU
+	}
+
+	s.PDUType = name
+
+	fields, err := pdu.Children()
+	if err != nil {
+		return err
+	}
+
+	// Trap-PDU ::= enterprise, agent-addr, generic-trap, specific-trap, time-stamp, variable-bindings
+	if pdu.Tag == snmpTrap {
+		if len(fields) < 6 {
+			return errSNMPInvalidPDU
+		}
+
+		s.Enterprise = fields[0].OID()
+		if len(fields[1].Content) == net.IPv4len {
+			s.AgentAddress = net.IP(fields[1].Content).String()
+		}
+
+		s.GenericTrap = int32(fields[2].Int())
+		s.SpecificTrap = int32(fields[3].Int())
+		s.Uptime = int64(fields[4].Uint())
+		s.VarBinds = parseSNMPVarBinds(fields[5])
+
+		return nil
+	}
+
+	// PDU ::= request-id, error-status, error-index, variable-bindings
+	if len(fields) < 4 {
+		return errSNMPInvalidPDU
+	}
+
+	s.RequestID = int32(fields[0].Int())
+	s.ErrorStatus = int32(fields[1].Int())
+	s.ErrorIndex = int32(fields[2].Int())
+	s.VarBinds = parseSNMPVarBinds(fields[3])
+
+	// GetBulkRequest uses these fields for non-repeaters and max-repetitions
+	if pdu.Tag != snmpGetBulkRequest && s.ErrorStatus != 0 {
+		s.Error = snmpErrorName(s.ErrorStatus)
+	}
+
+	// SNMPv2 notifications carry the uptime and trap identifier in the first two variables
+	if pdu.Tag == snmpV2Trap || pdu.Tag == snmpInformRequest {
+		for _, v := range s.VarBinds {
+			switch v.OID {
+			case oidSysUpTime:
+				up, _ := strconv.ParseInt(v.Value, 10, 64)
+				s.Uptime = up
+			case oidSNMPTrapOID:
+				s.TrapOID = v.Value
+			}
+		}
+	}
+
+	return nil
+}
+
+func snmpErrorName(status int32) string {
+	if status >= 0 && int(status) < len(snmpErrors) {
+		return snmpErrors[status]
+	}
+
+	return "unknown(" + strconv.Itoa(int(status)) + ")"
+}
+
+// parseSNMPVarBinds decodes the sequence of name and value pairs.
+// malformed bindings are skipped.
+func parseSNMPVarBinds(list *ber.Element) []*types.SNMPVarBind {
+	items, err := list.Children()
+	if err != nil && len(items) == 0 {
+		return nil
+	}
+
+	binds := make([]*types.SNMPVarBind, 0, len(items))
+
+	for _, item := range items {
+		pair, errPair := item.Children()
+		if errPair != nil || len(pair) != 2 || !pair[0].Is(ber.ClassUniversal, ber.TagOID) {
+			continue
+		}
+
+		typ, val := snmpValue(pair[1])
+		binds = append(binds, &types.SNMPVarBind{
+			OID:   pair[0].OID(),
+			Type:  typ,
+			Value: val,
+		})
+	}
+
+	return binds
+}
+
+// snmpValue returns the type name and a string representation for a variable value.
+func snmpValue(e *ber.Element) (typ, val string) {
+	switch e.Class {
+	case ber.ClassUniversal:
+		switch e.Tag {
+		case ber.TagInteger:
+			return "Integer", strconv.FormatInt(e.Int(), 10)
+		case ber.TagOctetString:
+			return "OctetString", snmpString(e.Content)
+		case ber.TagNull:
+			return "Null", ""
+		case ber.TagOID:
+			return "ObjectIdentifier", e.OID()
+		}
+	case ber.ClassApplication:
+		name, ok := snmpApplicationTypes[e.Tag]
+		if !ok {
+			break
+		}
+
+		switch e.Tag {
+		case 0:
+			if len(e.Content) == net.IPv4len {
+				return name, net.IP(e.Content).String()
+			}
+
+			return name, hex.EncodeToString(e.Content)
+		case 4:
+			return name, hex.EncodeToString(e.Content)
+		default:
+			return name, strconv.FormatUint(e.Uint(), 10)
+		}
+	case ber.ClassContext:
+		if name, ok := snmpExceptions[e.Tag]; ok {
+			return name, ""
+		}
+	}
+
+	return "Unknown", hex.EncodeToString(e.Content)
+}
+
+// snmpString returns printable octet strings as text, and binary values such as MAC addresses as hex.
+func snmpString(b []byte) string {
+	for _, r := range string(b) {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return hex.EncodeToString(b)
+		}
+	}
+
+	return string(b)
+}
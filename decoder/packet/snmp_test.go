@@ -0,0 +1,226 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// tlv encodes a BER element with a short form length.
+func tlv(tag byte, content ...[]byte) []byte {
+	var b []byte
+	for _, c := range content {
+		b = append(b, c...)
+	}
+
+	return append([]byte{tag, byte(len(b))}, b...)
+}
+
+func snmpInt(v byte) []byte {
+	return tlv(0x02, []byte{v})
+}
+
+var (
+	oidSysDescr     = tlv(0x06, []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00})
+	oidSysUpTimeBER = tlv(0x06, []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00})
+	oidTrapOIDBER   = tlv(0x06, []byte{0x2b, 0x06, 0x01, 0x06, 0x03, 0x01, 0x01, 0x04, 0x01, 0x00})
+	oidLinkDown     = tlv(0x06, []byte{0x2b, 0x06, 0x01, 0x06, 0x03, 0x01, 0x01, 0x05, 0x03})
+)
+
+func TestParseSNMPv2c(t *testing.T) {
+	data := tlv(0x30,
+		snmpInt(1),
+		tlv(0x04, []byte("public")),
+		tlv(0xa2, snmpInt(42), snmpInt(0), snmpInt(0),
+			tlv(0x30,
+				tlv(0x30, oidSysDescr, tlv(0x04, []byte("Linux router"))),
+				tlv(0x30, oidSysUpTimeBER, tlv(0x43, []byte{0x01, 0x00})),
+				tlv(0x30, oidSysDescr, tlv(0x04, []byte{0x00, 0x1b, 0x21})),
+			),
+		),
+	)
+
+	s, err := parseSNMP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != "v2c" || s.Community != "public" || s.PDUType != "Response" || s.RequestID != 42 {
+		t.Fatal("unexpected message", s)
+	}
+
+	if len(s.VarBinds) != 3 {
+		t.Fatal("expected 3 variable bindings, got", len(s.VarBinds))
+	}
+
+	if v := s.VarBinds[0]; v.OID != "1.3.6.1.2.1.1.1.0" || v.Type != "OctetString" || v.Value != "Linux router" {
+		t.Fatal("unexpected variable binding", v)
+	}
+
+	if v := s.VarBinds[1]; v.Type != "TimeTicks" || v.Value != "256" {
+		t.Fatal("unexpected variable binding", v)
+	}
+
+	if v := s.VarBinds[2]; v.Value != "001b21" {
+		t.Fatal("expected binary octet string as hex, got", v.Value)
+	}
+}
+
+func TestParseSNMPTraps(t *testing.T) {
+	// SNMPv1 linkDown trap from 10.0.0.1
+	data := tlv(0x30,
+		snmpInt(0),
+		tlv(0x04, []byte("private")),
+		tlv(0xa4,
+			tlv(0x06, []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x09}),
+			tlv(0x40, []byte{10, 0, 0, 1}),
+			snmpInt(2),
+			snmpInt(0),
+			tlv(0x43, []byte{0x30, 0x39}),
+			tlv(0x30),
+		),
+	)
+
+	s, err := parseSNMP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != "v1" || s.PDUType != "Trap" || s.Enterprise != "1.3.6.1.4.1.9" || s.AgentAddress != "10.0.0.1" {
+		t.Fatal("unexpected trap", s)
+	}
+
+	if s.GenericTrap != 2 || s.Uptime != 12345 {
+		t.Fatal("unexpected trap details", s.GenericTrap, s.Uptime)
+	}
+
+	// SNMPv2 notification with an error status
+	data = tlv(0x30,
+		snmpInt(1),
+		tlv(0x04, []byte("public")),
+		tlv(0xa7, snmpInt(7), snmpInt(5), snmpInt(0),
+			tlv(0x30,
+				tlv(0x30, oidSysUpTimeBER, tlv(0x43, []byte{0x30, 0x39})),
+				tlv(0x30, oidTrapOIDBER, oidLinkDown),
+			),
+		),
+	)
+
+	s, err = parseSNMP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.PDUType != "SNMPv2-Trap" || s.TrapOID != "1.3.6.1.6.3.1.1.5.3" || s.Uptime != 12345 || s.Error != "genErr" {
+		t.Fatal("unexpected notification", s)
+	}
+}
+
+func TestParseSNMPv3(t *testing.T) {
+	usm := tlv(0x30,
+		tlv(0x04, []byte{0x80, 0x00, 0x1f, 0x88, 0x04}),
+		snmpInt(3),
+		snmpInt(100),
+		tlv(0x04, []byte("admin")),
+		tlv(0x04, make([]byte, 12)),
+		tlv(0x04, make([]byte, 8)),
+	)
+
+	data := tlv(0x30,
+		snmpInt(3),
+		tlv(0x30, snmpInt(9), tlv(0x02, []byte{0x05, 0xdc}), tlv(0x04, []byte{0x07}), snmpInt(3)),
+		tlv(0x04, usm),
+		tlv(0x04, []byte{0xde, 0xad, 0xbe, 0xef}),
+	)
+
+	s, err := parseSNMP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Version != "v3" || s.MessageID != 9 || s.SecurityLevel != "authPriv" || !s.Encrypted {
+		t.Fatal("unexpected message", s)
+	}
+
+	if s.User != "admin" || s.EngineID != "80001f8804" || s.EngineBoots != 3 || s.EngineTime != 100 {
+		t.Fatal("unexpected security parameters", s)
+	}
+
+	// plaintext scoped PDU
+	data = tlv(0x30,
+		snmpInt(3),
+		tlv(0x30, snmpInt(10), tlv(0x02, []byte{0x05, 0xdc}), tlv(0x04, []byte{0x04}), snmpInt(3)),
+		tlv(0x04, usm),
+		tlv(0x30,
+			tlv(0x04, []byte{0x80, 0x00, 0x1f, 0x88, 0x04}),
+			tlv(0x04, []byte("ctx")),
+			tlv(0xa0, snmpInt(1), snmpInt(0), snmpInt(0), tlv(0x30, tlv(0x30, oidSysDescr, tlv(0x05)))),
+		),
+	)
+
+	s, err = parseSNMP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.SecurityLevel != "noAuthNoPriv" || s.ContextName != "ctx" || s.PDUType != "GetRequest" || len(s.VarBinds) != 1 {
+		t.Fatal("unexpected message", s)
+	}
+
+	if _, err = parseSNMP([]byte{0x30, 0x03, 0x02, 0x01, 0x02}); err == nil {
+		t.Fatal("expected error for unknown version")
+	}
+}
+
+func TestSNMPInVXLAN(t *testing.T) {
+	var (
+		mac = net.HardwareAddr{0, 0, 0x5e, 0, 0x53, 1}
+		buf = gopacket.NewSerializeBuffer()
+		msg = tlv(0x30, snmpInt(1), tlv(0x04, []byte("public")), tlv(0xa0, snmpInt(1), snmpInt(0), snmpInt(0), tlv(0x30)))
+	)
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true},
+		&layers.Ethernet{SrcMAC: mac, DstMAC: mac, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{192, 168, 1, 2}, DstIP: net.IP{192, 168, 1, 1}},
+		&layers.UDP{SrcPort: 50000, DstPort: 4789},
+		&layers.VXLAN{ValidIDFlag: true, VNI: 5000},
+		&layers.Ethernet{SrcMAC: mac, DstMAC: mac, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}},
+		&layers.UDP{SrcPort: 40000, DstPort: 161},
+		gopacket.Payload(msg),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	p.Metadata().Timestamp = time.Now()
+
+	// the message is read from the tunneled packet, which is identified by the inner addresses
+	s, ok := snmpDecoder.Handler(p).(*types.SNMP)
+	if !ok {
+		t.Fatal("no SNMP record")
+	}
+
+	if s.Community != "public" || s.SrcIP != "10.0.0.1" || s.DstIP != "10.0.0.2" || s.SrcPort != 40000 || s.DstPort != 161 {
+		t.Fatal("unexpected record", s)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	ssdpPort = 1900
+
+	ssdpMethodNotify   = "NOTIFY"
+	ssdpMethodSearch   = "M-SEARCH"
+	ssdpMethodResponse = "Response"
+
+	ssdpByeBye = "ssdp:byebye"
+)
+
+var ssdpDecoder = newPacketDecoder(
+	types.Type_NC_SSDP,
+	"SSDP",
+	"The Simple Service Discovery Protocol is used by UPnP devices to advertise and discover services",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) == 0 {
+			return nil
+		}
+
+		if udp.SrcPort != ssdpPort && udp.DstPort != ssdpPort {
+			return nil
+		}
+
+		s := parseSSDP(string(udp.Payload))
+		if s == nil {
+			return nil
+		}
+
+		s.Timestamp = p.Metadata().Timestamp.UnixNano()
+		s.SrcIP, s.DstIP = networkAddresses(p)
+		s.SrcPort = int32(udp.SrcPort)
+		s.DstPort = int32(udp.DstPort)
+		s.SrcMAC = linkSrcAddress(p)
+
+		// advertisements and search responses describe the sender
+		if s.Method != ssdpMethodSearch && s.NotificationSubType != ssdpByeBye {
+			var (
+				models   []string
+				services []string
+			)
+
+			if s.Server != "" {
+				models = []string{s.Server}
+			}
+
+			if strings.HasPrefix(s.NotificationType, "urn:") {
+				services = []string{s.NotificationType}
+			}
+
+			addDeviceDiscovery(s.SrcMAC, nil, models, services)
+		}
+
+		return s
+	},
+	nil,
+)
+
+// parseSSDP parses the start line and headers of a SSDP message.
+// nil is returned if the data is not a SSDP message.
+func parseSSDP(data string) *types.SSDP {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+
+	s := new(types.SSDP)
+
+	switch startLine := lines[0]; {
+	case strings.HasPrefix(startLine, ssdpMethodNotify+" "):
+		s.Method = ssdpMethodNotify
+	case strings.HasPrefix(startLine, ssdpMethodSearch+" "):
+		s.Method = ssdpMethodSearch
+	case strings.HasPrefix(startLine, "HTTP/"):
+		s.Method = ssdpMethodResponse
+	default:
+		return nil
+	}
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			continue
+		}
+
+		value := strings.TrimSpace(line[i+1:])
+
+		switch strings.ToUpper(strings.TrimSpace(line[:i])) {
+		case "NT", "ST":
+			s.NotificationType = value
+		case "NTS":
+			s.NotificationSubType = value
+		case "USN":
+			s.USN = value
+		case "LOCATION":
+			s.Location = value
+		case "SERVER":
+			s.Server = value
+		case "USER-AGENT":
+			s.UserAgent = value
+		}
+	}
+
+	return s
+}
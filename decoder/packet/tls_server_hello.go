@@ -22,6 +22,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 
 	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
 )
 
 var tlsServerHelloDecoder = newPacketDecoder(
@@ -49,14 +50,17 @@ var tlsServerHelloDecoder = newPacketDecoder(
 				dstMac = ll.LinkFlow().Dst().String()
 			}
 
-			if nl := p.NetworkLayer(); nl != nil {
-				srcIP = p.NetworkLayer().NetworkFlow().Src().String()
-				dstIP = p.NetworkLayer().NetworkFlow().Dst().String()
+			// tunneled packets are identified by their innermost network and transport layers, like connections
+			encapsulation, nl, tl := utils.DecodeEncapsulation(p)
+
+			if nl != nil {
+				srcIP = nl.NetworkFlow().Src().String()
+				dstIP = nl.NetworkFlow().Dst().String()
 			}
 
-			if tl := p.TransportLayer(); tl != nil {
-				srcPort = int(binary.BigEndian.Uint16(p.TransportLayer().TransportFlow().Src().Raw()))
-				dstPort = int(binary.BigEndian.Uint16(p.TransportLayer().TransportFlow().Dst().Raw()))
+			if tl != nil {
+				srcPort = int(binary.BigEndian.Uint16(tl.TransportFlow().Src().Raw()))
+				dstPort = int(binary.BigEndian.Uint16(tl.TransportFlow().Dst().Raw()))
 			}
 
 			return &types.TLSServerHello{
@@ -88,6 +92,7 @@ var tlsServerHelloDecoder = newPacketDecoder(
 				SrcPort:                      int32(srcPort),
 				DstPort:                      int32(dstPort),
 				Extensions:                   extensions,
+				Encapsulation:                encapsulation.String(),
 			}
 		}
 
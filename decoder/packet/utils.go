@@ -28,6 +28,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/dreadl0ck/gopacket"
+
 	"github.com/dreadl0ck/netcap/decoder/core"
 	"github.com/dreadl0ck/netcap/decoder/stream"
 	"github.com/dreadl0ck/netcap/defaults"
@@ -377,6 +379,24 @@ func pad(in interface{}, length int) string {
 	return fmt.Sprintf("%-"+strconv.Itoa(length)+"s", in)
 }
 
+// networkAddresses returns the source and destination address of the packets network layer.
+func networkAddresses(p gopacket.Packet) (src, dst string) {
+	if nl := p.NetworkLayer(); nl != nil {
+		return nl.NetworkFlow().Src().String(), nl.NetworkFlow().Dst().String()
+	}
+
+	return "", ""
+}
+
+// linkSrcAddress returns the source address of the packets link layer.
+func linkSrcAddress(p gopacket.Packet) string {
+	if ll := p.LinkLayer(); ll != nil {
+		return ll.LinkFlow().Src().String()
+	}
+
+	return ""
+}
+
 //func logReassemblyInfo(s string, a ...interface{}) {
 //	if conf.Debug {
 //		logger.ReassemblyLog.Printf("INFO: "+s, a...)
@@ -157,6 +157,10 @@ func InitAbstractDecoders(c *config.Config) (decoders []core.DecoderAPI, err err
 				Label:   c.Label,
 				Proto:   c.Proto,
 				JSON:    c.JSON,
+				Zeek:    c.Zeek,
+				EVE:     c.EVE,
+				Parquet: c.Parquet,
+				SQLite:  c.SQLite,
 				Name:    d.GetName(),
 				Type:    d.GetType(),
 				Null:    c.Null,
@@ -170,6 +174,7 @@ func InitAbstractDecoders(c *config.Config) (decoders []core.DecoderAPI, err err
 				},
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
+				Index:                c.Index,
 				Out:                  c.Out,
 				Chan:                 c.Chan,
 				ChanSize:             c.ChanSize,
@@ -180,6 +185,10 @@ func InitAbstractDecoders(c *config.Config) (decoders []core.DecoderAPI, err err
 				StartTime:            time.Now(),
 				CompressionBlockSize: c.CompressionBlockSize,
 				CompressionLevel:     c.CompressionLevel,
+				CompressionCodec:     c.CompressionCodec,
+				CompressionDict:      c.CompressionDict,
+				ParquetCompression:   c.ParquetCompression,
+				ParquetRowGroupSize:  c.ParquetRowGroupSize,
 			})
 			d.SetWriter(w)
 
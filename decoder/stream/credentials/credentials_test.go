@@ -1,10 +1,13 @@
 package credentials
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"log"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	"github.com/dreadl0ck/netcap/decoder/db"
 	"github.com/dreadl0ck/netcap/logger"
@@ -342,3 +345,127 @@ cmpzMyBlYzNhNTlmZWQzOTVhYmExZWM2MzY3YzRmNGI0MWFjMA==
 		t.Fatal("incorrect pass, got:", c.Password, "expected: rjs3 ec3a59fed395aba1ec6367c4f4b41ac0")
 	}
 }
+
+// ntlmChallengeMessage creates a minimal NTLMSSP CHALLENGE message.
+func ntlmChallengeMessage(challenge []byte) []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], ntlmChallenge)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmFlagUnicode)
+	copy(msg[24:], challenge)
+
+	return msg
+}
+
+// ntlmAuthenticateMessage creates a NTLMSSP AUTHENTICATE message with unicode strings.
+func ntlmAuthenticateMessage(lm, nt []byte, domain, user, workstation string) []byte {
+	msg := make([]byte, 64)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], ntlmAuthenticate)
+	binary.LittleEndian.PutUint32(msg[60:], ntlmFlagUnicode)
+
+	for i, field := range [][]byte{lm, nt, utf16LE(domain), utf16LE(user), utf16LE(workstation)} {
+		pos := 12 + i*8
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(len(msg)))
+		msg = append(msg, field...)
+	}
+
+	return msg
+}
+
+func utf16LE(s string) []byte {
+	var out []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(r), byte(r>>8))
+	}
+
+	return out
+}
+
+func TestNTLMHarvesterHTTP(t *testing.T) {
+	var (
+		challenge = []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+		ntProof   = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		blob      = []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xaa, 0xbb}
+		auth      = ntlmAuthenticateMessage(make([]byte, 24), append(ntProof, blob...), "CORP", "alice", "WS01")
+	)
+
+	data := "GET / HTTP/1.1\r\nHost: intranet\r\nAuthorization: NTLM TlRMTVNTUAABAAAAB4IIogAAAAAAAAAAAAAAAAAAAAAKAGFKAAAADw==\r\n\r\n" +
+		"HTTP/1.1 401 Unauthorized\r\nWWW-Authenticate: NTLM " + base64.StdEncoding.EncodeToString(ntlmChallengeMessage(challenge)) + "\r\n\r\n" +
+		"GET / HTTP/1.1\r\nHost: intranet\r\nAuthorization: NTLM " + base64.StdEncoding.EncodeToString(auth) + "\r\n\r\n" +
+		"HTTP/1.1 200 OK\r\n\r\n"
+
+	creds := ntlmHarvester([]byte(data), "test", time.Now())
+	if len(creds) != 1 {
+		t.Fatal("expected one credential, got:", len(creds))
+	}
+
+	c := creds[0]
+	if c.User != "CORP\\alice" {
+		t.Fatal("incorrect user, got:", c.User)
+	}
+
+	expected := "alice::CORP:1122334455667788:0102030405060708090a0b0c0d0e0f10:0101000000000000aabb"
+	if c.Password != expected {
+		t.Fatal("incorrect hash, got:", c.Password, "expected:", expected)
+	}
+
+	if c.Notes != "NetNTLMv2, hashcat mode 5600, workstation: WS01" {
+		t.Fatal("incorrect notes, got:", c.Notes)
+	}
+}
+
+func TestNTLMHarvesterRaw(t *testing.T) {
+	var (
+		challenge = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+		lm        = make([]byte, 24)
+		nt        = make([]byte, 24)
+	)
+
+	for i := range lm {
+		lm[i] = 0xaa
+		nt[i] = 0xbb
+	}
+
+	// binary framing around the messages, as found in SMB session setup requests and responses
+	data := append([]byte{0x00, 0x00, 0x01, 0x00, 0xfe, 'S', 'M', 'B'}, ntlmChallengeMessage(challenge)...)
+	data = append(data, 0x00, 0x00, 0x02, 0x00, 0xfe, 'S', 'M', 'B')
+	data = append(data, ntlmAuthenticateMessage(lm, nt, "", "bob", "")...)
+
+	creds := ntlmHarvester(data, "test", time.Now())
+	if len(creds) != 1 {
+		t.Fatal("expected one credential, got:", len(creds))
+	}
+
+	expected := "bob:::" + strings.Repeat("aa", 24) + ":" + strings.Repeat("bb", 24) + ":0102030405060708"
+	if creds[0].Password != expected {
+		t.Fatal("incorrect hash, got:", creds[0].Password, "expected:", expected)
+	}
+
+	if creds[0].User != "bob" || creds[0].Notes != "NetNTLMv1, hashcat mode 5500" {
+		t.Fatal("unexpected credentials:", creds[0])
+	}
+
+	// authentication without a challenge cannot be cracked
+	if creds = ntlmHarvester(ntlmAuthenticateMessage(lm, nt, "", "bob", ""), "test", time.Now()); len(creds) != 0 {
+		t.Fatal("expected no credentials, got:", len(creds))
+	}
+}
+
+func TestContainsNTLM(t *testing.T) {
+	for _, data := range []string{
+		"\x00\x00\x01\x00\xfeSMBNTLMSSP\x00\x02",
+		"Authorization: NTLM TlRMTVNTUAABAAAAB4IIogAAAAAAAAAAAAAAAAAAAAAKAGFKAAAADw==\r\n",
+		"WWW-Authenticate: Negotiate oYIBCTCCAQWgAwoBAaEMBgorBgEEAYI3AgIKooHv\r\n",
+	} {
+		if !ContainsNTLM([]byte(data)) {
+			t.Fatal("expected NTLM authentication in:", data)
+		}
+	}
+
+	if ContainsNTLM([]byte("GET / HTTP/1.1\r\nHost: intranet\r\nAuthorization: Basic dXNlcjpwYXNz\r\n\r\n")) {
+		t.Fatal("unexpected NTLM authentication")
+	}
+}
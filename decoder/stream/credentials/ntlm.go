@@ -0,0 +1,359 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	serviceNTLM = "NTLM"
+
+	ntlmNegotiate    = 1
+	ntlmChallenge    = 2
+	ntlmAuthenticate = 3
+
+	// NTLMSSP_NEGOTIATE_UNICODE, strings are encoded as UTF-16LE when set.
+	ntlmFlagUnicode = 0x00000001
+
+	// size of a NTLMv1 challenge response,
+	// NTLMv2 responses are longer since they carry the client blob.
+	ntlmV1ResponseSize = 24
+
+	// hashcat modes for NetNTLMv1 and NetNTLMv2.
+	hashcatModeNetNTLMv1 = 5500
+	hashcatModeNetNTLMv2 = 5600
+)
+
+var (
+	ntlmSignature = []byte("NTLMSSP\x00")
+
+	// NTLMSSP messages transported as base64, e.g. in HTTP NTLM authentication or SMTP / IMAP / POP3 AUTH NTLM.
+	// the base64 encoded signature always starts with TlRMTVNTUA.
+	reNTLMBase64 = regexp.MustCompile(`TlRMTVNTUA[A-Za-z0-9+/]*={0,2}`)
+
+	// SPNEGO tokens from HTTP Negotiate authentication, which can wrap NTLMSSP messages.
+	reNegotiateBase64 = regexp.MustCompile(`(?:Authorization|Authenticate): Negotiate ([A-Za-z0-9+/]+={0,2})`)
+
+	// ntlmMarkers are present in all data that can contain NTLMSSP messages.
+	ntlmMarkers = [][]byte{
+		[]byte("NTLMSSP"),
+		[]byte("TlRMTVNTUA"),
+		[]byte(": Negotiate "),
+	}
+)
+
+// ntlmMessage is a NTLMSSP message found in a network conversation.
+type ntlmMessage struct {
+	// position in the conversation, used to restore the message order.
+	offset int
+
+	typ   uint32
+	flags uint32
+
+	// CHALLENGE_MESSAGE
+	serverChallenge []byte
+
+	// AUTHENTICATE_MESSAGE
+	lmResponse  []byte
+	ntResponse  []byte
+	domain      string
+	user        string
+	workstation string
+}
+
+// HarvestNTLM searches the entire conversation for NTLMSSP messages and writes credentials
+// for each AUTHENTICATE message that can be paired with a previously seen CHALLENGE.
+// Raw NTLMSSP messages are found in binary protocols like SMB, LDAP, MSSQL or DCE/RPC,
+// base64 encoded messages in HTTP Authorization and WWW-Authenticate headers and the AUTH NTLM mechanism of mail protocols.
+func HarvestNTLM(data []byte, ident string, ts time.Time) {
+	// only use harvesters when credential audit record type is loaded
+	if !useHarvesters {
+		return
+	}
+
+	for _, c := range ntlmHarvester(data, ident, ts) {
+		WriteCredentials(c)
+	}
+}
+
+// ContainsNTLM checks for the raw or base64 encoded NTLMSSP signature, or a HTTP Negotiate header.
+// It is cheaper than HarvestNTLM and allows to skip data without NTLM authentication.
+func ContainsNTLM(data []byte) bool {
+	for _, m := range ntlmMarkers {
+		if bytes.Contains(data, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HarvestersEnabled returns whether the credential harvesters are active.
+// This allows callers to skip preparing data for the harvesters.
+func HarvestersEnabled() bool {
+	return useHarvesters
+}
+
+// ntlmHarvester returns credentials for all NTLM authentications in the data.
+func ntlmHarvester(data []byte, ident string, ts time.Time) []*types.Credentials {
+	var (
+		messages  = findNTLMMessages(data)
+		challenge []byte
+		creds     []*types.Credentials
+	)
+
+	for _, m := range messages {
+		switch m.typ {
+		case ntlmChallenge:
+			challenge = m.serverChallenge
+		case ntlmAuthenticate:
+			if challenge == nil {
+				credLog.Debug("NTLM authenticate message without challenge: " + ident)
+
+				continue
+			}
+
+			if c := ntlmCredentials(m, challenge, ident, ts); c != nil {
+				creds = append(creds, c)
+			}
+
+			// a challenge is only valid for a single authentication
+			challenge = nil
+		}
+	}
+
+	return creds
+}
+
+// ntlmCredentials formats the challenge response of an AUTHENTICATE message as hashcat input.
+// anonymous authentications are ignored.
+func ntlmCredentials(m *ntlmMessage, challenge []byte, ident string, ts time.Time) *types.Credentials {
+	if m.user == "" || len(m.ntResponse) == 0 {
+		return nil
+	}
+
+	var (
+		hash  string
+		notes string
+	)
+
+	if len(m.ntResponse) > ntlmV1ResponseSize {
+		// user::domain:challenge:NTProofStr:blob
+		hash = m.user + "::" + m.domain + ":" +
+			hex.EncodeToString(challenge) + ":" +
+			hex.EncodeToString(m.ntResponse[:16]) + ":" +
+			hex.EncodeToString(m.ntResponse[16:])
+		notes = "NetNTLMv2, hashcat mode " + strconv.Itoa(hashcatModeNetNTLMv2)
+	} else {
+		// user::domain:lm:nt:challenge
+		hash = m.user + "::" + m.domain + ":" +
+			hex.EncodeToString(m.lmResponse) + ":" +
+			hex.EncodeToString(m.ntResponse) + ":" +
+			hex.EncodeToString(challenge)
+		notes = "NetNTLMv1, hashcat mode " + strconv.Itoa(hashcatModeNetNTLMv1)
+	}
+
+	if m.workstation != "" {
+		notes += ", workstation: " + m.workstation
+	}
+
+	user := m.user
+	if m.domain != "" {
+		user = m.domain + "\\" + m.user
+	}
+
+	return &types.Credentials{
+		Timestamp: ts.UnixNano(),
+		Service:   serviceNTLM,
+		Flow:      ident,
+		User:      user,
+		Password:  hash,
+		Notes:     notes,
+	}
+}
+
+// findNTLMMessages collects raw and base64 encoded NTLMSSP messages from the data,
+// ordered by their position in the conversation.
+func findNTLMMessages(data []byte) []*ntlmMessage {
+	messages := scanNTLM(data, 0)
+
+	for _, loc := range reNTLMBase64.FindAllIndex(data, -1) {
+		if raw := decodeBase64(data[loc[0]:loc[1]]); raw != nil {
+			messages = append(messages, scanNTLM(raw, loc[0])...)
+		}
+	}
+
+	for _, loc := range reNegotiateBase64.FindAllSubmatchIndex(data, -1) {
+		// plain NTLMSSP tokens have already been handled above
+		if bytes.HasPrefix(data[loc[2]:loc[3]], []byte("TlRMTVNTUA")) {
+			continue
+		}
+
+		if raw := decodeBase64(data[loc[2]:loc[3]]); raw != nil {
+			messages = append(messages, scanNTLM(raw, loc[2])...)
+		}
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].offset < messages[j].offset
+	})
+
+	return messages
+}
+
+// scanNTLM parses all messages that start with the NTLMSSP signature.
+// offset is added to the message positions for ordering.
+func scanNTLM(data []byte, offset int) []*ntlmMessage {
+	var (
+		messages []*ntlmMessage
+		pos      int
+	)
+
+	for {
+		i := bytes.Index(data[pos:], ntlmSignature)
+		if i < 0 {
+			return messages
+		}
+
+		pos += i
+		if m := parseNTLMMessage(data[pos:]); m != nil {
+			m.offset = offset + pos
+			messages = append(messages, m)
+		}
+
+		pos += len(ntlmSignature)
+	}
+}
+
+// parseNTLMMessage parses a CHALLENGE or AUTHENTICATE message, data starts at the NTLMSSP signature.
+// since the message length is not known when scanning a stream, all fields are bounds checked against the remaining data.
+func parseNTLMMessage(data []byte) *ntlmMessage {
+	if len(data) < 12 || !bytes.HasPrefix(data, ntlmSignature) {
+		return nil
+	}
+
+	m := &ntlmMessage{
+		typ: binary.LittleEndian.Uint32(data[8:12]),
+	}
+
+	switch m.typ {
+	case ntlmNegotiate:
+		if len(data) >= 16 {
+			m.flags = binary.LittleEndian.Uint32(data[12:16])
+		}
+	case ntlmChallenge:
+		if len(data) < 32 {
+			return nil
+		}
+
+		m.flags = binary.LittleEndian.Uint32(data[20:24])
+		m.serverChallenge = data[24:32]
+	case ntlmAuthenticate:
+		if len(data) < 52 {
+			return nil
+		}
+
+		// the negotiate flags are missing in old implementations, assume unicode in that case
+		m.flags = ntlmFlagUnicode
+		if len(data) >= 64 {
+			m.flags = binary.LittleEndian.Uint32(data[60:64])
+		}
+
+		var ok bool
+		if m.lmResponse, ok = ntlmField(data, 12); !ok {
+			return nil
+		}
+
+		if m.ntResponse, ok = ntlmField(data, 20); !ok {
+			return nil
+		}
+
+		for _, f := range []struct {
+			offset int
+			val    *string
+		}{
+			{28, &m.domain},
+			{36, &m.user},
+			{44, &m.workstation},
+		} {
+			b, valid := ntlmField(data, f.offset)
+			if !valid {
+				return nil
+			}
+
+			*f.val = ntlmString(b, m.flags)
+		}
+	default:
+		return nil
+	}
+
+	return m
+}
+
+// ntlmField reads the payload referenced by the security buffer at the given position:
+// length (2 bytes), allocated space (2 bytes) and offset from the start of the message (4 bytes).
+func ntlmField(data []byte, pos int) ([]byte, bool) {
+	var (
+		length = int(binary.LittleEndian.Uint16(data[pos : pos+2]))
+		offset = int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+	)
+
+	if length == 0 {
+		return nil, true
+	}
+
+	if offset < 0 || offset+length > len(data) {
+		return nil, false
+	}
+
+	return data[offset : offset+length], true
+}
+
+// ntlmString decodes a string field according to the negotiated character set.
+func ntlmString(b []byte, flags uint32) string {
+	if flags&ntlmFlagUnicode == 0 {
+		return string(b)
+	}
+
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+
+	return string(utf16.Decode(u))
+}
+
+func decodeBase64(in []byte) []byte {
+	out, err := base64.StdEncoding.DecodeString(string(in))
+	if err != nil {
+		// try again without padding
+		out, err = base64.RawStdEncoding.DecodeString(string(bytes.TrimRight(in, "=")))
+		if err != nil {
+			return nil
+		}
+	}
+
+	return out
+}
@@ -59,6 +59,9 @@ type httpRequest struct {
 	timestamp int64
 	clientIP  string
 	serverIP  string
+
+	clientPort int32
+	serverPort int32
 }
 
 type httpResponse struct {
@@ -66,6 +69,9 @@ type httpResponse struct {
 	timestamp int64
 	clientIP  string
 	serverIP  string
+
+	clientPort int32
+	serverPort int32
 }
 
 type httpReader struct {
@@ -117,10 +123,12 @@ func (h *httpReader) Decode() {
 
 			atomic.AddInt64(&streamutils.Stats.NumRequests, 1)
 			setRequest(ht, &httpRequest{
-				request:   res.response.Request,
-				timestamp: res.timestamp,
-				clientIP:  res.clientIP,
-				serverIP:  res.serverIP,
+				request:    res.response.Request,
+				timestamp:  res.timestamp,
+				clientIP:   res.clientIP,
+				serverIP:   res.serverIP,
+				clientPort: res.clientPort,
+				serverPort: res.serverPort,
 			})
 		} else {
 			// response without matching request
@@ -308,10 +316,12 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 	streamutils.Stats.Unlock()
 
 	h.responses = append(h.responses, &httpResponse{
-		response:  res,
-		timestamp: h.conversation.FirstServerPacket.UnixNano(),
-		clientIP:  h.conversation.ClientIP,
-		serverIP:  h.conversation.ServerIP,
+		response:   res,
+		timestamp:  h.conversation.FirstServerPacket.UnixNano(),
+		clientIP:   h.conversation.ClientIP,
+		serverIP:   h.conversation.ServerIP,
+		clientPort: h.conversation.ClientPort,
+		serverPort: h.conversation.ServerPort,
 	})
 
 	// write responses to disk if configured
@@ -412,10 +422,12 @@ func (h *httpReader) readRequest(b *bufio.Reader) error {
 	t := h.conversation.FirstClientPacket.UnixNano()
 
 	request := &httpRequest{
-		request:   req,
-		timestamp: t,
-		clientIP:  h.conversation.ClientIP,
-		serverIP:  h.conversation.ServerIP,
+		request:    req,
+		timestamp:  t,
+		clientIP:   h.conversation.ClientIP,
+		serverIP:   h.conversation.ServerIP,
+		clientPort: h.conversation.ClientPort,
+		serverPort: h.conversation.ServerPort,
 	}
 
 	// parse form values
@@ -62,9 +62,11 @@ func setRequest(h *types.HTTP, req *httpRequest) {
 	h.Referer = removeCommas(req.request.Referer())
 	h.URL = removeCommas(req.request.URL.String())
 
-	// retrieve ip addresses set on the request while processing
+	// retrieve ip addresses and ports set on the request while processing
 	h.SrcIP = req.clientIP
 	h.DstIP = req.serverIP
+	h.SrcPort = req.clientPort
+	h.DstPort = req.serverPort
 
 	h.ReqCookies = readCookies(req.request.Cookies())
 	h.Parameters = readParameters(req.request.Form)
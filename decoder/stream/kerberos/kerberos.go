@@ -0,0 +1,52 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var kerberosLog = zap.NewNop()
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_Kerberos,
+	Name:        serviceKerberos,
+	Description: "Kerberos is a network authentication protocol that issues tickets to clients via a Key Distribution Center",
+	PostInit: func(d *decoder.StreamDecoder) error {
+		var err error
+		kerberosLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"kerberos",
+			decoderconfig.Instance.Debug,
+		)
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isKDCRequest(client)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return kerberosLog.Sync()
+	},
+	Factory: &kerberosReader{},
+	Typ:     core.All,
+}
+
+const serviceKerberos = "Kerberos"
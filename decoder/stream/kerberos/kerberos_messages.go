@@ -0,0 +1,348 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/dreadl0ck/netcap/decoder/ber"
+)
+
+/*
+ * Kerberos V5 messages
+ * https://tools.ietf.org/html/rfc4120#section-5
+ */
+
+// application tags of the message types.
+const (
+	msgASReq    = 10
+	msgASRep    = 11
+	msgTGSReq   = 12
+	msgTGSRep   = 13
+	msgAPReq    = 14
+	msgAPRep    = 15
+	msgKRBError = 30
+)
+
+var messageTypeNames = map[int]string{
+	msgASReq:    "AS-REQ",
+	msgASRep:    "AS-REP",
+	msgTGSReq:   "TGS-REQ",
+	msgTGSRep:   "TGS-REP",
+	msgAPReq:    "AP-REQ",
+	msgAPRep:    "AP-REP",
+	msgKRBError: "KRB-ERROR",
+}
+
+// encryption types.
+const (
+	etypeAES128 = 17
+	etypeAES256 = 18
+	etypeRC4    = 23
+)
+
+// pre authentication data types.
+const (
+	paTGSReq = 1
+)
+
+var errorNames = map[int32]string{
+	0:  "KDC_ERR_NONE",
+	1:  "KDC_ERR_NAME_EXP",
+	2:  "KDC_ERR_SERVICE_EXP",
+	3:  "KDC_ERR_BAD_PVNO",
+	6:  "KDC_ERR_C_PRINCIPAL_UNKNOWN",
+	7:  "KDC_ERR_S_PRINCIPAL_UNKNOWN",
+	8:  "KDC_ERR_PRINCIPAL_NOT_UNIQUE",
+	12: "KDC_ERR_POLICY",
+	13: "KDC_ERR_BADOPTION",
+	14: "KDC_ERR_ETYPE_NOSUPP",
+	16: "KDC_ERR_PADATA_TYPE_NOSUPP",
+	18: "KDC_ERR_CLIENT_REVOKED",
+	23: "KDC_ERR_KEY_EXPIRED",
+	24: "KDC_ERR_PREAUTH_FAILED",
+	25: "KDC_ERR_PREAUTH_REQUIRED",
+	31: "KRB_AP_ERR_BAD_INTEGRITY",
+	32: "KRB_AP_ERR_TKT_EXPIRED",
+	34: "KRB_AP_ERR_REPEAT",
+	37: "KRB_AP_ERR_SKEW",
+	41: "KRB_AP_ERR_MODIFIED",
+	52: "KRB_ERR_RESPONSE_TOO_BIG",
+	60: "KRB_ERR_GENERIC",
+	68: "KDC_ERR_WRONG_REALM",
+}
+
+// errorName returns the symbolic name of a Kerberos error code.
+func errorName(code int32) string {
+	if n, ok := errorNames[code]; ok {
+		return n
+	}
+
+	return "UNKNOWN"
+}
+
+var errInvalidMessage = errors.New("invalid kerberos message")
+
+// encryptedData is an EncryptedData structure.
+type encryptedData struct {
+	etype  int32
+	cipher []byte
+}
+
+// message contains the relevant fields of a Kerberos message.
+type message struct {
+	msgType      int
+	realm        string
+	clientName   string
+	serviceName  string
+	etypes       []int32
+	preAuthTypes []int32
+
+	errorCode int32
+	errorText string
+
+	// replies only
+	ticketRealm string
+	ticket      *encryptedData
+	encPart     *encryptedData
+
+	// offset of the message in the reassembled stream
+	offset int
+}
+
+// isKDCRequest checks if the data starts with an AS-REQ or TGS-REQ,
+// either directly as sent via UDP, or prefixed with the record mark used for TCP.
+func isKDCRequest(data []byte) bool {
+	if len(data) > 4 && data[0] == 0 {
+		data = data[4:]
+	}
+
+	// application class, constructed
+	return len(data) > 2 && (data[0] == 0x60|msgASReq || data[0] == 0x60|msgTGSReq)
+}
+
+// readMessages parses all messages from one direction of a conversation.
+// Messages sent via TCP are prefixed with a four byte length.
+func readMessages(data []byte) (messages []*message, err error) {
+	pos := 0
+
+	for pos < len(data) {
+		start := pos
+
+		// record mark for TCP: the high bit is reserved and the length is never large enough to use the first byte
+		if data[pos] == 0 {
+			if pos+4 > len(data) {
+				return messages, ber.ErrTruncated
+			}
+
+			length := int(binary.BigEndian.Uint32(data[pos:]))
+			pos += 4
+
+			if pos+length > len(data) {
+				return messages, ber.ErrTruncated
+			}
+		}
+
+		e, _, errParse := ber.Parse(data[pos:])
+		if errParse != nil {
+			return messages, errParse
+		}
+
+		pos += e.Size
+
+		m, errMsg := parseMessage(e)
+		if errMsg != nil {
+			return messages, errMsg
+		}
+
+		m.offset = start
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+// parseMessage decodes a Kerberos message from its outer application tagged element.
+func parseMessage(e *ber.Element) (*message, error) {
+	if e.Class != ber.ClassApplication || !e.Constructed {
+		return nil, errInvalidMessage
+	}
+
+	seq, _, err := ber.Parse(e.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := seq.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &message{msgType: e.Tag}
+
+	switch e.Tag {
+	case msgASReq, msgTGSReq:
+		parseKDCReq(fields, m)
+	case msgASRep, msgTGSRep:
+		parseKDCRep(fields, m)
+	case msgKRBError:
+		parseKRBError(fields, m)
+	default:
+		return nil, errInvalidMessage
+	}
+
+	return m, nil
+}
+
+func parseKDCReq(fields []*ber.Element, m *message) {
+	// padata [3] SEQUENCE OF PA-DATA
+	if padata := ber.Explicit(fields, 3); padata != nil {
+		entries, _ := padata.Children()
+		for _, pa := range entries {
+			paFields, _ := pa.Children()
+			typ := int32(ber.Explicit(paFields, 1).Int())
+			m.preAuthTypes = append(m.preAuthTypes, typ)
+
+			// the AP-REQ of a TGS-REQ carries the ticket granting ticket
+			if typ == paTGSReq {
+				if value := ber.Explicit(paFields, 2); value != nil {
+					if ap, _, errAP := ber.Parse(value.Content); errAP == nil && ap.Is(ber.ClassApplication, msgAPReq) {
+						m.ticketRealm = ticketRealm(ap)
+					}
+				}
+			}
+		}
+	}
+
+	// req-body [4] KDC-REQ-BODY
+	body := ber.Explicit(fields, 4)
+	if body == nil {
+		return
+	}
+
+	bodyFields, _ := body.Children()
+	m.clientName = principalName(ber.Explicit(bodyFields, 1))
+	m.realm = ber.Explicit(bodyFields, 2).String()
+	m.serviceName = principalName(ber.Explicit(bodyFields, 3))
+
+	if etypes := ber.Explicit(bodyFields, 8); etypes != nil {
+		list, _ := etypes.Children()
+		for _, et := range list {
+			m.etypes = append(m.etypes, int32(et.Int()))
+		}
+	}
+}
+
+func parseKDCRep(fields []*ber.Element, m *message) {
+	if padata := ber.Explicit(fields, 2); padata != nil {
+		entries, _ := padata.Children()
+		for _, pa := range entries {
+			paFields, _ := pa.Children()
+			m.preAuthTypes = append(m.preAuthTypes, int32(ber.Explicit(paFields, 1).Int()))
+		}
+	}
+
+	m.realm = ber.Explicit(fields, 3).String()
+	m.clientName = principalName(ber.Explicit(fields, 4))
+
+	// ticket [5] Ticket ::= [APPLICATION 1] SEQUENCE
+	if ticket := ber.Explicit(fields, 5); ticket != nil {
+		if seq, _, err := ber.Parse(ticket.Content); err == nil {
+			ticketFields, _ := seq.Children()
+			m.ticketRealm = ber.Explicit(ticketFields, 1).String()
+			m.serviceName = principalName(ber.Explicit(ticketFields, 2))
+			m.ticket = parseEncryptedData(ber.Explicit(ticketFields, 3))
+		}
+	}
+
+	m.encPart = parseEncryptedData(ber.Explicit(fields, 6))
+	if m.encPart != nil {
+		m.etypes = append(m.etypes, m.encPart.etype)
+	}
+}
+
+func parseKRBError(fields []*ber.Element, m *message) {
+	m.errorCode = int32(ber.Explicit(fields, 6).Int())
+	m.clientName = principalName(ber.Explicit(fields, 8))
+	m.realm = ber.Explicit(fields, 9).String()
+	m.serviceName = principalName(ber.Explicit(fields, 10))
+	m.errorText = strings.TrimRight(ber.Explicit(fields, 11).String(), "\x00")
+}
+
+// ticketRealm extracts the realm of the ticket contained in an AP-REQ.
+func ticketRealm(ap *ber.Element) string {
+	seq, _, err := ber.Parse(ap.Content)
+	if err != nil {
+		return ""
+	}
+
+	fields, _ := seq.Children()
+
+	ticket := ber.Explicit(fields, 3)
+	if ticket == nil {
+		return ""
+	}
+
+	ticketSeq, _, err := ber.Parse(ticket.Content)
+	if err != nil {
+		return ""
+	}
+
+	ticketFields, _ := ticketSeq.Children()
+
+	return ber.Explicit(ticketFields, 1).String()
+}
+
+// principalName joins the components of a PrincipalName.
+func principalName(e *ber.Element) string {
+	if e == nil {
+		return ""
+	}
+
+	fields, _ := e.Children()
+
+	names := ber.Explicit(fields, 1)
+	if names == nil {
+		return ""
+	}
+
+	components, _ := names.Children()
+	parts := make([]string, 0, len(components))
+
+	for _, c := range components {
+		parts = append(parts, c.String())
+	}
+
+	return strings.Join(parts, "/")
+}
+
+func parseEncryptedData(e *ber.Element) *encryptedData {
+	if e == nil {
+		return nil
+	}
+
+	fields, _ := e.Children()
+
+	d := &encryptedData{
+		etype: int32(ber.Explicit(fields, 0).Int()),
+	}
+
+	if cipher := ber.Explicit(fields, 2); cipher != nil {
+		d.cipher = cipher.Content
+	}
+
+	return d
+}
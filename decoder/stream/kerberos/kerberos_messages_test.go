@@ -0,0 +1,188 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// tlv encodes a BER element with the given identifier octet.
+func tlv(id byte, content ...[]byte) []byte {
+	c := bytes.Join(content, nil)
+
+	if len(c) < 0x80 {
+		return append([]byte{id, byte(len(c))}, c...)
+	}
+
+	return append([]byte{id, 0x82, byte(len(c) >> 8), byte(len(c))}, c...)
+}
+
+func explicit(tag int, content ...[]byte) []byte {
+	return tlv(0xa0|byte(tag), content...)
+}
+
+func integer(v byte) []byte {
+	return tlv(0x02, []byte{v})
+}
+
+func generalString(s string) []byte {
+	return tlv(0x1b, []byte(s))
+}
+
+func principal(names ...string) []byte {
+	var components [][]byte
+	for _, n := range names {
+		components = append(components, generalString(n))
+	}
+
+	return tlv(0x30, explicit(0, integer(1)), explicit(1, tlv(0x30, components...)))
+}
+
+func encrypted(etype byte, cipher []byte) []byte {
+	return tlv(0x30, explicit(0, integer(etype)), explicit(2, tlv(0x04, cipher)))
+}
+
+var cipher = bytes.Repeat([]byte{0xab}, 40)
+
+func kdcRep(msgType byte, sname []string, ticketEtype, encEtype byte) []byte {
+	return tlv(0x60|msgType, tlv(0x30,
+		explicit(0, integer(5)),
+		explicit(1, integer(msgType)),
+		explicit(3, generalString("CORP.LOCAL")),
+		explicit(4, principal("alice")),
+		explicit(5, tlv(0x61, tlv(0x30,
+			explicit(0, integer(5)),
+			explicit(1, generalString("CORP.LOCAL")),
+			explicit(2, principal(sname...)),
+			explicit(3, encrypted(ticketEtype, cipher)),
+		))),
+		explicit(6, encrypted(encEtype, cipher)),
+	))
+}
+
+func TestASReq(t *testing.T) {
+	req := tlv(0x60|msgASReq, tlv(0x30,
+		explicit(1, integer(5)),
+		explicit(2, integer(msgASReq)),
+		explicit(4, tlv(0x30,
+			explicit(0, tlv(0x03, []byte{0, 0x40, 0x81, 0, 0x10})),
+			explicit(1, principal("alice")),
+			explicit(2, generalString("CORP.LOCAL")),
+			explicit(3, principal("krbtgt", "CORP.LOCAL")),
+			explicit(8, tlv(0x30, integer(18), integer(17), integer(23))),
+		)),
+	))
+
+	// TCP record mark
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(len(req)))
+	data = append(data, req...)
+
+	if !isKDCRequest(data) || !isKDCRequest(req) {
+		t.Fatal("expected request to be detected")
+	}
+
+	messages, err := readMessages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatal("unexpected number of messages", len(messages))
+	}
+
+	m := messages[0]
+	if m.clientName != "alice" || m.realm != "CORP.LOCAL" || m.serviceName != "krbtgt/CORP.LOCAL" {
+		t.Fatal("unexpected names", m.clientName, m.realm, m.serviceName)
+	}
+
+	if len(m.etypes) != 3 || m.etypes[2] != etypeRC4 {
+		t.Fatal("unexpected encryption types", m.etypes)
+	}
+}
+
+func TestASREPRoasting(t *testing.T) {
+	messages, err := readMessages(kdcRep(msgASRep, []string{"krbtgt", "CORP.LOCAL"}, etypeAES256, etypeRC4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := messages[0]
+	if m.clientName != "alice" || m.ticket.etype != etypeAES256 || m.encPart.etype != etypeRC4 {
+		t.Fatal("unexpected reply", m.clientName, m.ticket, m.encPart)
+	}
+
+	hash, mode := roastingHash(m)
+	if mode != 18200 {
+		t.Fatal("unexpected mode", mode)
+	}
+
+	if !strings.HasPrefix(hash, "$krb5asrep$23$alice@CORP.LOCAL:"+strings.Repeat("ab", 16)+"$"+strings.Repeat("ab", 24)) {
+		t.Fatal("unexpected hash", hash)
+	}
+}
+
+func TestTGSREPRoasting(t *testing.T) {
+	messages, err := readMessages(kdcRep(msgTGSRep, []string{"MSSQLSvc", "db.corp.local"}, etypeRC4, etypeAES256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, mode := roastingHash(messages[0])
+	if mode != 13100 {
+		t.Fatal("unexpected mode", mode)
+	}
+
+	if !strings.HasPrefix(hash, "$krb5tgs$23$*alice$CORP.LOCAL$MSSQLSvc/db.corp.local*$") {
+		t.Fatal("unexpected hash", hash)
+	}
+
+	messages, err = readMessages(kdcRep(msgTGSRep, []string{"MSSQLSvc", "db.corp.local"}, etypeAES128, etypeAES256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, mode = roastingHash(messages[0])
+	if mode != 19600 || hash != "$krb5tgs$17$MSSQLSvc/db.corp.local$CORP.LOCAL$"+strings.Repeat("ab", 12)+"$"+strings.Repeat("ab", 28) {
+		t.Fatal("unexpected AES hash", mode, hash)
+	}
+
+	// tickets for krbtgt are not crackable
+	messages, _ = readMessages(kdcRep(msgTGSRep, []string{"krbtgt", "CORP.LOCAL"}, etypeRC4, etypeRC4))
+	if hash, _ = roastingHash(messages[0]); hash != "" {
+		t.Fatal("expected no hash for krbtgt ticket")
+	}
+}
+
+func TestKRBError(t *testing.T) {
+	data := tlv(0x60|msgKRBError, tlv(0x30,
+		explicit(0, integer(5)),
+		explicit(1, integer(msgKRBError)),
+		explicit(6, integer(25)),
+		explicit(9, generalString("CORP.LOCAL")),
+		explicit(10, principal("krbtgt", "CORP.LOCAL")),
+	))
+
+	messages, err := readMessages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if errorString(messages[0]) != "KDC_ERR_PREAUTH_REQUIRED" {
+		t.Fatal("unexpected error", messages[0].errorCode)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+type kerberosReader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new Kerberos reader.
+func (h *kerberosReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &kerberosReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream and writes an audit record for each Kerberos message.
+func (h *kerberosReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil || len(h.conversation.Data) == 0 {
+		return
+	}
+
+	// only TCP fragments carry an assembler context
+	transport := "TCP"
+	if h.conversation.Data[0].Context() == nil {
+		transport = "UDP"
+	}
+
+	client, server := streamutils.SplitConversation(h.conversation.Data)
+
+	for _, s := range []*streamutils.TimedStream{client, server} {
+		messages, err := readMessages(s.Data)
+		if err != nil {
+			kerberosLog.Debug("failed to parse message",
+				zap.String("ident", h.conversation.Ident),
+				zap.Error(err),
+			)
+		}
+
+		for _, m := range messages {
+			h.write(&types.Kerberos{
+				Timestamp:            s.TimeAt(m.offset).UnixNano(),
+				ClientIP:             h.conversation.ClientIP,
+				ServerIP:             h.conversation.ServerIP,
+				ClientPort:           h.conversation.ClientPort,
+				ServerPort:           h.conversation.ServerPort,
+				Transport:            transport,
+				MessageType:          messageTypeNames[m.msgType],
+				Realm:                m.realm,
+				ClientName:           m.clientName,
+				ServiceName:          m.serviceName,
+				EncryptionTypes:      m.etypes,
+				TicketEncryptionType: ticketEtype(m),
+				PreAuthTypes:         m.preAuthTypes,
+				ErrorCode:            m.errorCode,
+				Error:                errorString(m),
+				ErrorText:            m.errorText,
+				Flow:                 h.conversation.Ident,
+			})
+
+			if credentials.Decoder.Writer != nil {
+				h.writeRoastingHash(m, s.TimeAt(m.offset).UnixNano())
+			}
+		}
+	}
+}
+
+func ticketEtype(m *message) int32 {
+	if m.ticket == nil {
+		return 0
+	}
+
+	return m.ticket.etype
+}
+
+func errorString(m *message) string {
+	if m.msgType != msgKRBError {
+		return ""
+	}
+
+	return errorName(m.errorCode)
+}
+
+// writeRoastingHash emits crackable material from AS-REP and TGS-REP messages.
+func (h *kerberosReader) writeRoastingHash(m *message, ts int64) {
+	hash, mode := roastingHash(m)
+	if hash == "" {
+		return
+	}
+
+	notes := messageTypeNames[m.msgType] + " roasting, hashcat mode " + strconv.Itoa(mode)
+	if m.msgType == msgTGSRep && m.ticket.etype != etypeRC4 {
+		notes += ", the salt is derived from the service account name, which might differ from the SPN " + m.serviceName
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp: ts,
+		Service:   serviceKerberos,
+		Flow:      h.conversation.Ident,
+		User:      m.clientName + "@" + m.realm,
+		Password:  hash,
+		Notes:     notes,
+	})
+}
+
+// roastingHash formats the encrypted part of an AS-REP, or the service ticket of a TGS-REP,
+// as hashcat input and returns the corresponding hashcat mode.
+func roastingHash(m *message) (string, int) {
+	var (
+		data   *encryptedData
+		prefix string
+		user   = m.clientName
+		realm  = m.realm
+	)
+
+	switch m.msgType {
+	case msgASRep:
+		data = m.encPart
+		prefix = "$krb5asrep$"
+	case msgTGSRep:
+		// tickets for the ticket granting service are encrypted with the krbtgt key
+		if strings.HasPrefix(m.serviceName, "krbtgt") {
+			return "", 0
+		}
+
+		data = m.ticket
+		prefix = "$krb5tgs$"
+	default:
+		return "", 0
+	}
+
+	if data == nil || len(data.cipher) <= 16 {
+		return "", 0
+	}
+
+	c := data.cipher
+
+	switch data.etype {
+	case etypeRC4:
+		// RC4-HMAC: the checksum is prepended to the cipher text
+		if m.msgType == msgASRep {
+			return prefix + "23$" + user + "@" + realm + ":" + hex.EncodeToString(c[:16]) + "$" + hex.EncodeToString(c[16:]), 18200
+		}
+
+		return prefix + "23$*" + user + "$" + realm + "$" + m.serviceName + "*$" + hex.EncodeToString(c[:16]) + "$" + hex.EncodeToString(c[16:]), 13100
+	case etypeAES128, etypeAES256:
+		// AES: the truncated HMAC-SHA1-96 checksum is appended to the cipher text
+		var (
+			checksum = hex.EncodeToString(c[len(c)-12:])
+			edata    = hex.EncodeToString(c[:len(c)-12])
+			mode     int
+		)
+
+		if m.msgType == msgASRep {
+			mode = 32100
+		} else {
+			// the key of the service account is salted with its own name
+			user = m.serviceName
+			realm = m.ticketRealm
+			mode = 19600
+		}
+
+		if data.etype == etypeAES256 {
+			mode += 100
+		}
+
+		return prefix + strconv.Itoa(int(data.etype)) + "$" + user + "$" + realm + "$" + checksum + "$" + edata, mode
+	default:
+		return "", 0
+	}
+}
+
+func (h *kerberosReader) write(rec *types.Kerberos) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		rec.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(rec)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,419 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ldap
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/netcap/decoder/ber"
+)
+
+/*
+ * LDAP v3 messages
+ * https://tools.ietf.org/html/rfc4511
+ */
+
+// application tags of the protocol operations.
+const (
+	opBindRequest           = 0
+	opBindResponse          = 1
+	opUnbindRequest         = 2
+	opSearchRequest         = 3
+	opSearchResultEntry     = 4
+	opSearchResultDone      = 5
+	opModifyRequest         = 6
+	opModifyResponse        = 7
+	opAddRequest            = 8
+	opAddResponse           = 9
+	opDelRequest            = 10
+	opDelResponse           = 11
+	opModifyDNRequest       = 12
+	opModifyDNResponse      = 13
+	opCompareRequest        = 14
+	opCompareResponse       = 15
+	opAbandonRequest        = 16
+	opSearchResultReference = 19
+	opExtendedRequest       = 23
+	opExtendedResponse      = 24
+	opIntermediateResponse  = 25
+)
+
+var operationNames = map[int]string{
+	opBindRequest:     "Bind",
+	opUnbindRequest:   "Unbind",
+	opSearchRequest:   "Search",
+	opModifyRequest:   "Modify",
+	opAddRequest:      "Add",
+	opDelRequest:      "Delete",
+	opModifyDNRequest: "ModifyDN",
+	opCompareRequest:  "Compare",
+	opAbandonRequest:  "Abandon",
+	opExtendedRequest: "Extended",
+}
+
+// oidStartTLS is the name of the extended operation to upgrade the connection to TLS.
+const oidStartTLS = "1.3.6.1.4.1.1466.20037"
+
+var scopeNames = map[int64]string{
+	0: "baseObject",
+	1: "singleLevel",
+	2: "wholeSubtree",
+}
+
+var resultNames = map[int32]string{
+	0:  "success",
+	1:  "operationsError",
+	2:  "protocolError",
+	3:  "timeLimitExceeded",
+	4:  "sizeLimitExceeded",
+	5:  "compareFalse",
+	6:  "compareTrue",
+	7:  "authMethodNotSupported",
+	8:  "strongerAuthRequired",
+	10: "referral",
+	11: "adminLimitExceeded",
+	12: "unavailableCriticalExtension",
+	13: "confidentialityRequired",
+	14: "saslBindInProgress",
+	16: "noSuchAttribute",
+	17: "undefinedAttributeType",
+	18: "inappropriateMatching",
+	19: "constraintViolation",
+	20: "attributeOrValueExists",
+	21: "invalidAttributeSyntax",
+	32: "noSuchObject",
+	33: "aliasProblem",
+	34: "invalidDNSyntax",
+	36: "aliasDereferencingProblem",
+	48: "inappropriateAuthentication",
+	49: "invalidCredentials",
+	50: "insufficientAccessRights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+	54: "loopDetect",
+	64: "namingViolation",
+	65: "objectClassViolation",
+	66: "notAllowedOnNonLeaf",
+	67: "notAllowedOnRDN",
+	68: "entryAlreadyExists",
+	69: "objectClassModsProhibited",
+	71: "affectsMultipleDSAs",
+	80: "other",
+}
+
+// resultName returns the symbolic name of a result code.
+func resultName(code int32) string {
+	if n, ok := resultNames[code]; ok {
+		return n
+	}
+
+	return "unknown"
+}
+
+var errInvalidMessage = errors.New("invalid ldap message")
+
+// message is a single LDAPMessage.
+type message struct {
+	id int32
+	op *ber.Element
+
+	// offset of the message in the reassembled stream
+	offset int
+}
+
+// isLDAPMessage checks if the data starts with a sequence of an integer message id and an application tagged operation.
+func isLDAPMessage(data []byte) bool {
+	if len(data) < 2 || data[0] != 0x30 {
+		return false
+	}
+
+	m, err := parseMessage(data)
+
+	return err == nil && m.op != nil
+}
+
+func parseMessage(data []byte) (*message, error) {
+	e, _, err := ber.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.Is(ber.ClassUniversal, ber.TagSequence) {
+		return nil, errInvalidMessage
+	}
+
+	children, err := e.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(children) < 2 || !children[0].Is(ber.ClassUniversal, ber.TagInteger) || children[1].Class != ber.ClassApplication {
+		return nil, errInvalidMessage
+	}
+
+	return &message{
+		id: int32(children[0].Int()),
+		op: children[1],
+	}, nil
+}
+
+// readMessages parses all messages from one direction of a conversation.
+// Parsing stops at the first element that is not a valid message,
+// for example when a security layer has been negotiated during a SASL bind.
+func readMessages(data []byte) (messages []*message, err error) {
+	pos := 0
+
+	for pos < len(data) {
+		e, _, errParse := ber.Parse(data[pos:])
+		if errParse != nil {
+			return messages, errParse
+		}
+
+		m, errMsg := parseMessage(data[pos : pos+e.Size])
+		if errMsg != nil {
+			return messages, errMsg
+		}
+
+		m.offset = pos
+		messages = append(messages, m)
+		pos += e.Size
+	}
+
+	return messages, nil
+}
+
+// bindRequest contains the fields of a BindRequest.
+type bindRequest struct {
+	name        string
+	authMethod  string
+	password    string
+	mechanism   string
+	credentials []byte
+}
+
+func parseBindRequest(op *ber.Element) *bindRequest {
+	children, _ := op.Children()
+	if len(children) < 3 {
+		return nil
+	}
+
+	b := &bindRequest{
+		name: children[1].String(),
+	}
+
+	switch auth := children[2]; {
+	case auth.Is(ber.ClassContext, 0):
+		b.authMethod = "simple"
+		b.password = auth.String()
+	case auth.Is(ber.ClassContext, 3):
+		sasl, _ := auth.Children()
+		if len(sasl) > 0 {
+			b.mechanism = sasl[0].String()
+		}
+
+		if len(sasl) > 1 {
+			b.credentials = sasl[1].Content
+		}
+
+		b.authMethod = "SASL " + b.mechanism
+	}
+
+	return b
+}
+
+// searchRequest contains the fields of a SearchRequest.
+type searchRequest struct {
+	baseObject string
+	scope      string
+	filter     string
+	attributes []string
+}
+
+func parseSearchRequest(op *ber.Element) *searchRequest {
+	children, _ := op.Children()
+	if len(children) < 7 {
+		return nil
+	}
+
+	s := &searchRequest{
+		baseObject: children[0].String(),
+		scope:      scopeNames[children[1].Int()],
+		filter:     filterString(children[6], 0),
+	}
+
+	if len(children) > 7 {
+		attrs, _ := children[7].Children()
+		for _, a := range attrs {
+			s.attributes = append(s.attributes, a.String())
+		}
+	}
+
+	return s
+}
+
+// entryName returns the DN of the entry targeted by an operation,
+// or the name of an extended operation.
+func entryName(op *ber.Element) string {
+	switch op.Tag {
+	case opDelRequest:
+		// the DN is the primitive content of the operation
+		return op.String()
+	case opExtendedRequest:
+		children, _ := op.Children()
+
+		return ber.Context(children, 0).String()
+	default:
+		children, _ := op.Children()
+		if len(children) == 0 {
+			return ""
+		}
+
+		return children[0].String()
+	}
+}
+
+// ldapResult contains the fields of an LDAPResult.
+type ldapResult struct {
+	code              int32
+	matchedDN         string
+	diagnosticMessage string
+}
+
+func parseResult(op *ber.Element) *ldapResult {
+	children, _ := op.Children()
+	if len(children) < 3 {
+		return nil
+	}
+
+	return &ldapResult{
+		code:              int32(children[0].Int()),
+		matchedDN:         children[1].String(),
+		diagnosticMessage: children[2].String(),
+	}
+}
+
+// isResponse checks if the operation is the final response for a request.
+func isResponse(tag int) bool {
+	switch tag {
+	case opBindResponse, opSearchResultDone, opModifyResponse, opAddResponse,
+		opDelResponse, opModifyDNResponse, opCompareResponse, opExtendedResponse:
+		return true
+	default:
+		return false
+	}
+}
+
+// filterString converts a search filter into its string representation, as defined in RFC 4515.
+func filterString(f *ber.Element, depth int) string {
+	if f == nil || f.Class != ber.ClassContext {
+		return ""
+	}
+
+	if depth > ber.MaxDepth {
+		return "(...)"
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('(')
+
+	switch f.Tag {
+	case 0, 1, 2:
+		// and, or, not
+		b.WriteByte("&|!"[f.Tag])
+
+		children, _ := f.Children()
+		for _, c := range children {
+			b.WriteString(filterString(c, depth+1))
+		}
+	case 3, 5, 6, 8:
+		// equalityMatch, greaterOrEqual, lessOrEqual, approxMatch
+		children, _ := f.Children()
+		if len(children) == 2 {
+			b.WriteString(escape(children[0].Content))
+			b.WriteString([]string{3: "=", 5: ">=", 6: "<=", 8: "~="}[f.Tag])
+			b.WriteString(escape(children[1].Content))
+		}
+	case 4:
+		// substrings
+		children, _ := f.Children()
+		if len(children) == 2 {
+			b.WriteString(escape(children[0].Content))
+			b.WriteByte('=')
+
+			parts, _ := children[1].Children()
+			if len(parts) > 0 && parts[0].Tag != 0 {
+				b.WriteByte('*')
+			}
+
+			for i, p := range parts {
+				b.WriteString(escape(p.Content))
+
+				if i < len(parts)-1 || p.Tag != 2 {
+					b.WriteByte('*')
+				}
+			}
+		}
+	case 7:
+		// present
+		b.WriteString(escape(f.Content))
+		b.WriteString("=*")
+	case 9:
+		// extensibleMatch
+		children, _ := f.Children()
+		if t := ber.Context(children, 2); t != nil {
+			b.WriteString(escape(t.Content))
+		}
+
+		if dn := ber.Context(children, 4); dn.Bool() {
+			b.WriteString(":dn")
+		}
+
+		if rule := ber.Context(children, 1); rule != nil {
+			b.WriteByte(':')
+			b.WriteString(escape(rule.Content))
+		}
+
+		b.WriteString(":=")
+
+		if v := ber.Context(children, 3); v != nil {
+			b.WriteString(escape(v.Content))
+		}
+	default:
+		b.WriteString("unknown filter " + strconv.Itoa(f.Tag))
+	}
+
+	b.WriteByte(')')
+
+	return b.String()
+}
+
+// escape encodes special and non printable characters in filter values.
+func escape(v []byte) string {
+	var b strings.Builder
+
+	for _, c := range v {
+		switch {
+		case c == '*' || c == '(' || c == ')' || c == '\\' || c < 0x20 || c > 0x7e:
+			b.WriteString("\\" + strconv.FormatInt(int64(c)>>4, 16) + strconv.FormatInt(int64(c)&0xf, 16))
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
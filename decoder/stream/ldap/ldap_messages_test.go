@@ -0,0 +1,132 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ldap
+
+import (
+	"bytes"
+	"testing"
+)
+
+// tlv encodes a BER element with the given identifier octet.
+func tlv(id byte, content ...[]byte) []byte {
+	c := bytes.Join(content, nil)
+
+	return append([]byte{id, 0x84, 0, 0, byte(len(c) >> 8), byte(len(c))}, c...)
+}
+
+func octetString(s string) []byte {
+	return tlv(0x04, []byte(s))
+}
+
+func ldapMessage(id byte, op []byte) []byte {
+	return tlv(0x30, tlv(0x02, []byte{id}), op)
+}
+
+func TestBindRequest(t *testing.T) {
+	data := ldapMessage(1, tlv(0x60,
+		tlv(0x02, []byte{3}),
+		octetString("cn=admin,dc=corp,dc=local"),
+		tlv(0x80, []byte("secret")),
+	))
+
+	if !isLDAPMessage(data) {
+		t.Fatal("expected message to be detected")
+	}
+
+	messages, err := readMessages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := parseBindRequest(messages[0].op)
+	if b == nil {
+		t.Fatal("failed to parse bind request")
+	}
+
+	if b.name != "cn=admin,dc=corp,dc=local" || b.authMethod != "simple" || b.password != "secret" {
+		t.Fatal("unexpected bind request", b.name, b.authMethod, b.password)
+	}
+}
+
+func TestSearchRequest(t *testing.T) {
+	filter := tlv(0xa0,
+		tlv(0xa3, octetString("objectClass"), octetString("user")),
+		tlv(0xa1,
+			tlv(0xa4, octetString("cn"), tlv(0x30, tlv(0x80, []byte("adm")), tlv(0x82, []byte("in")))),
+			tlv(0x87, []byte("mail")),
+		),
+		tlv(0xa2, tlv(0xa5, octetString("badPwdCount"), octetString("3"))),
+	)
+
+	data := ldapMessage(2, tlv(0x63,
+		octetString("dc=corp,dc=local"),
+		tlv(0x0a, []byte{2}),
+		tlv(0x0a, []byte{0}),
+		tlv(0x02, []byte{0}),
+		tlv(0x02, []byte{0}),
+		tlv(0x01, []byte{0}),
+		filter,
+		tlv(0x30, octetString("sAMAccountName"), octetString("memberOf")),
+	))
+
+	messages, err := readMessages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := parseSearchRequest(messages[0].op)
+	if s == nil {
+		t.Fatal("failed to parse search request")
+	}
+
+	if s.baseObject != "dc=corp,dc=local" || s.scope != "wholeSubtree" {
+		t.Fatal("unexpected search request", s.baseObject, s.scope)
+	}
+
+	if s.filter != "(&(objectClass=user)(|(cn=adm*in)(mail=*))(!(badPwdCount>=3)))" {
+		t.Fatal("unexpected filter", s.filter)
+	}
+
+	if len(s.attributes) != 2 || s.attributes[1] != "memberOf" {
+		t.Fatal("unexpected attributes", s.attributes)
+	}
+}
+
+func TestResult(t *testing.T) {
+	data := ldapMessage(1, tlv(0x61,
+		tlv(0x0a, []byte{49}),
+		octetString(""),
+		octetString("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error"),
+	))
+
+	messages, err := readMessages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isResponse(messages[0].op.Tag) {
+		t.Fatal("expected bind response")
+	}
+
+	res := parseResult(messages[0].op)
+	if res == nil || resultName(res.code) != "invalidCredentials" {
+		t.Fatal("unexpected result", res)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if s := escape([]byte("a*(b)\\\x00")); s != "a\\2a\\28b\\29\\5c\\00" {
+		t.Fatal("unexpected escaped value", s)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ldap
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+type ldapReader struct {
+	conversation *core.ConversationInfo
+}
+
+// operation is a request along with the data that is not part of the audit record.
+type operation struct {
+	record   *types.LDAP
+	password string
+}
+
+// New will instantiate a new LDAP reader.
+func (h *ldapReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &ldapReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream and writes an audit record for each LDAP operation.
+func (h *ldapReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	client, server := streamutils.SplitConversation(h.conversation.Data)
+
+	requests, err := readMessages(client.Data)
+	if err != nil {
+		ldapLog.Debug("stopped parsing requests",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+	}
+
+	responses, err := readMessages(server.Data)
+	if err != nil {
+		ldapLog.Debug("stopped parsing responses",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+	}
+
+	var (
+		// operations waiting for a response, by message id
+		pending = make(map[int32][]*operation)
+		ordered []*operation
+	)
+
+	for _, m := range requests {
+		op := h.newOperation(m, client)
+		if op == nil {
+			continue
+		}
+
+		ordered = append(ordered, op)
+
+		if m.op.Tag != opUnbindRequest && m.op.Tag != opAbandonRequest {
+			pending[m.id] = append(pending[m.id], op)
+		}
+	}
+
+	for _, m := range responses {
+		queue := pending[m.id]
+		if len(queue) == 0 {
+			// response without request, e.g. an unsolicited notice of disconnection
+			continue
+		}
+
+		op := queue[0]
+
+		switch {
+		case m.op.Tag == opSearchResultEntry:
+			op.record.NumEntries++
+		case isResponse(m.op.Tag):
+			if res := parseResult(m.op); res != nil {
+				op.record.ResultCode = res.code
+				op.record.Result = resultName(res.code)
+				op.record.DiagnosticMessage = res.diagnosticMessage
+			}
+
+			op.record.Latency = server.TimeAt(m.offset).UnixNano() - op.record.Timestamp
+			pending[m.id] = queue[1:]
+		}
+	}
+
+	for _, op := range ordered {
+		if op.record.Operation == operationNames[opBindRequest] && op.password != "" && credentials.Decoder.Writer != nil {
+			credentials.WriteCredentials(&types.Credentials{
+				Timestamp: op.record.Timestamp,
+				Service:   serviceLDAP,
+				Flow:      h.conversation.Ident,
+				User:      op.record.DN,
+				Password:  op.password,
+				Notes:     "simple bind, result: " + op.record.Result,
+			})
+		}
+
+		h.write(op.record)
+	}
+}
+
+// newOperation creates the audit record for a request.
+func (h *ldapReader) newOperation(m *message, client *streamutils.TimedStream) *operation {
+	name, ok := operationNames[m.op.Tag]
+	if !ok {
+		return nil
+	}
+
+	op := &operation{
+		record: &types.LDAP{
+			Timestamp:  client.TimeAt(m.offset).UnixNano(),
+			ClientIP:   h.conversation.ClientIP,
+			ServerIP:   h.conversation.ServerIP,
+			ClientPort: h.conversation.ClientPort,
+			ServerPort: h.conversation.ServerPort,
+			MessageID:  m.id,
+			Operation:  name,
+			Flow:       h.conversation.Ident,
+		},
+	}
+
+	switch m.op.Tag {
+	case opBindRequest:
+		if b := parseBindRequest(m.op); b != nil {
+			op.record.DN = b.name
+			op.record.AuthMethod = b.authMethod
+			op.password = b.password
+		}
+	case opSearchRequest:
+		if s := parseSearchRequest(m.op); s != nil {
+			op.record.DN = s.baseObject
+			op.record.Scope = s.scope
+			op.record.Filter = s.filter
+			op.record.Attributes = s.attributes
+		}
+	case opUnbindRequest, opAbandonRequest:
+		// no arguments of interest
+	default:
+		op.record.DN = entryName(m.op)
+		if op.record.DN == oidStartTLS {
+			op.record.Operation = "StartTLS"
+		}
+	}
+
+	return op
+}
+
+func (h *ldapReader) write(rec *types.LDAP) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		rec.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(rec)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
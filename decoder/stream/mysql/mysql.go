@@ -0,0 +1,63 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package mysql
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var mysqlLog = zap.NewNop()
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_MySQL,
+	Name:        serviceMySQL,
+	Description: "The MySQL client server protocol is used to issue queries to MySQL and MariaDB database servers",
+	PostInit: func(d *decoder.StreamDecoder) error {
+		var err error
+		mysqlLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"mysql",
+			decoderconfig.Instance.Debug,
+		)
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isGreeting(server)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return mysqlLog.Sync()
+	},
+	Factory: &mysqlReader{},
+	Typ:     core.TCP,
+}
+
+const serviceMySQL = "MySQL"
+
+// isGreeting checks if the data starts with a protocol version 10 initial handshake packet.
+func isGreeting(data []byte) bool {
+	if len(data) < 5 {
+		return false
+	}
+
+	length := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+
+	return data[3] == 0 && data[4] == protocolVersion && length > 1 && length <= len(data)-4
+}
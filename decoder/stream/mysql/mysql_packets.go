@@ -0,0 +1,620 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+/*
+ * MySQL client server protocol
+ * https://dev.mysql.com/doc/internals/en/client-server-protocol.html
+ */
+
+const (
+	protocolVersion = 0x0a
+
+	// header bytes of generic response packets.
+	headerOK          = 0x00
+	headerLocalInfile = 0xfb
+	headerEOF         = 0xfe
+	headerERR         = 0xff
+
+	// size of a packet header: 3 bytes payload length and 1 byte sequence id.
+	packetHeaderSize = 4
+
+	// maximum payload size of a single packet, bigger payloads are split.
+	maxPayloadSize = 0xffffff
+
+	// EOF packets are shorter than 9 bytes, which distinguishes them from rows starting with a length encoded integer.
+	// The OK packets that replace them with CLIENT_DEPRECATE_EOF can be longer.
+	maxEOFSize = 9
+
+	// capability flags.
+	clientConnectWithDB              = 0x00000008
+	clientSSL                        = 0x00000800
+	clientSecureConnection           = 0x00008000
+	clientPluginAuth                 = 0x00080000
+	clientPluginAuthLenencClientData = 0x00200000
+	clientDeprecateEOF               = 0x01000000
+
+	// status flag indicating that another result set follows.
+	serverMoreResultsExists = 0x0008
+
+	// length of the scramble used by the mysql_native_password plugin.
+	scrambleLength = 20
+
+	nativePassword = "mysql_native_password"
+)
+
+// command bytes.
+const (
+	comSleep            = 0x00
+	comQuit             = 0x01
+	comInitDB           = 0x02
+	comQuery            = 0x03
+	comFieldList        = 0x04
+	comCreateDB         = 0x05
+	comDropDB           = 0x06
+	comRefresh          = 0x07
+	comShutdown         = 0x08
+	comStatistics       = 0x09
+	comProcessInfo      = 0x0a
+	comConnect          = 0x0b
+	comProcessKill      = 0x0c
+	comDebug            = 0x0d
+	comPing             = 0x0e
+	comChangeUser       = 0x11
+	comBinlogDump       = 0x12
+	comStmtPrepare      = 0x16
+	comStmtExecute      = 0x17
+	comStmtSendLongData = 0x18
+	comStmtClose        = 0x19
+	comStmtReset        = 0x1a
+	comSetOption        = 0x1b
+	comStmtFetch        = 0x1c
+	comResetConnection  = 0x1f
+)
+
+var commandNames = map[byte]string{
+	comSleep:            "COM_SLEEP",
+	comQuit:             "COM_QUIT",
+	comInitDB:           "COM_INIT_DB",
+	comQuery:            "COM_QUERY",
+	comFieldList:        "COM_FIELD_LIST",
+	comCreateDB:         "COM_CREATE_DB",
+	comDropDB:           "COM_DROP_DB",
+	comRefresh:          "COM_REFRESH",
+	comShutdown:         "COM_SHUTDOWN",
+	comStatistics:       "COM_STATISTICS",
+	comProcessInfo:      "COM_PROCESS_INFO",
+	comConnect:          "COM_CONNECT",
+	comProcessKill:      "COM_PROCESS_KILL",
+	comDebug:            "COM_DEBUG",
+	comPing:             "COM_PING",
+	comChangeUser:       "COM_CHANGE_USER",
+	comBinlogDump:       "COM_BINLOG_DUMP",
+	comStmtPrepare:      "COM_STMT_PREPARE",
+	comStmtExecute:      "COM_STMT_EXECUTE",
+	comStmtSendLongData: "COM_STMT_SEND_LONG_DATA",
+	comStmtClose:        "COM_STMT_CLOSE",
+	comStmtReset:        "COM_STMT_RESET",
+	comSetOption:        "COM_SET_OPTION",
+	comStmtFetch:        "COM_STMT_FETCH",
+	comResetConnection:  "COM_RESET_CONNECTION",
+}
+
+// commandName returns a human readable name for the command byte.
+func commandName(c byte) string {
+	if n, ok := commandNames[c]; ok {
+		return n
+	}
+
+	return "UNKNOWN"
+}
+
+// hasResponse indicates whether the server replies to the command.
+func hasResponse(c byte) bool {
+	switch c {
+	case comQuit, comStmtSendLongData, comStmtClose:
+		return false
+	default:
+		return true
+	}
+}
+
+var (
+	errShortPacket = errors.New("packet too short")
+	errNoGreeting  = errors.New("not a protocol version 10 handshake")
+)
+
+// packet is a single MySQL protocol packet.
+type packet struct {
+	seq     byte
+	payload []byte
+
+	// offset of the packet in the reassembled stream
+	offset int
+}
+
+// readPackets splits the stream into packets.
+// Incomplete packets at the end of the stream are discarded,
+// payloads that exceed the maximum packet size are joined.
+func readPackets(data []byte) (packets []*packet) {
+	var (
+		pos  int
+		last *packet
+	)
+
+	for pos+packetHeaderSize <= len(data) {
+		var (
+			length = int(data[pos]) | int(data[pos+1])<<8 | int(data[pos+2])<<16
+			seq    = data[pos+3]
+			start  = pos + packetHeaderSize
+		)
+
+		if start+length > len(data) {
+			break
+		}
+
+		payload := data[start : start+length]
+
+		if last != nil && len(last.payload) >= maxPayloadSize && len(last.payload)%maxPayloadSize == 0 {
+			// continuation of a large payload
+			last.payload = append(last.payload[:len(last.payload):len(last.payload)], payload...)
+		} else {
+			last = &packet{
+				seq:     seq,
+				payload: payload,
+				offset:  pos,
+			}
+			packets = append(packets, last)
+		}
+
+		pos = start + length
+	}
+
+	return packets
+}
+
+// greeting is the initial handshake packet sent by the server.
+type greeting struct {
+	serverVersion string
+	connectionID  uint32
+	capabilities  uint32
+	scramble      []byte
+	authPlugin    string
+}
+
+func parseGreeting(p []byte) (*greeting, error) {
+	if len(p) == 0 || p[0] != protocolVersion {
+		return nil, errNoGreeting
+	}
+
+	r := &reader{data: p, pos: 1}
+	g := &greeting{
+		serverVersion: r.nullTerminated(),
+	}
+
+	g.connectionID = r.uint32()
+	g.scramble = append(g.scramble, r.bytes(8)...)
+	r.skip(1) // filler
+	g.capabilities = uint32(r.uint16())
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// the remaining fields are optional
+	if r.remaining() == 0 {
+		return g, nil
+	}
+
+	r.skip(1) // character set
+	r.skip(2) // status flags
+	g.capabilities |= uint32(r.uint16()) << 16
+	scrambleLen := int(r.byte())
+	r.skip(10) // reserved
+
+	if g.capabilities&clientSecureConnection != 0 {
+		n := scrambleLen - 8
+		if n < 13 {
+			n = 13
+		}
+		// the second part of the scramble is terminated by a null byte
+		g.scramble = append(g.scramble, bytes.TrimRight(r.bytes(n), "\x00")...)
+	}
+
+	if g.capabilities&clientPluginAuth != 0 {
+		g.authPlugin = r.nullTerminated()
+	}
+
+	if r.err != nil && len(g.scramble) < scrambleLength {
+		return nil, r.err
+	}
+
+	return g, nil
+}
+
+// handshakeResponse is the HandshakeResponse41 sent by the client.
+type handshakeResponse struct {
+	capabilities uint32
+	user         string
+	authResponse []byte
+	database     string
+	authPlugin   string
+
+	// client requested to switch to TLS
+	ssl bool
+}
+
+func parseHandshakeResponse(p []byte) (*handshakeResponse, error) {
+	r := &reader{data: p}
+	h := &handshakeResponse{
+		capabilities: r.uint32(),
+	}
+
+	r.skip(4)  // max packet size
+	r.skip(1)  // character set
+	r.skip(23) // reserved
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// a SSLRequest packet is a truncated handshake response
+	if r.remaining() == 0 {
+		h.ssl = h.capabilities&clientSSL != 0
+
+		return h, nil
+	}
+
+	h.user = r.nullTerminated()
+
+	switch {
+	case h.capabilities&clientPluginAuthLenencClientData != 0:
+		h.authResponse = r.bytes(int(r.lenencInt()))
+	case h.capabilities&clientSecureConnection != 0:
+		h.authResponse = r.bytes(int(r.byte()))
+	default:
+		h.authResponse = []byte(r.nullTerminated())
+	}
+
+	if h.capabilities&clientConnectWithDB != 0 && r.remaining() > 0 {
+		h.database = r.nullTerminated()
+	}
+
+	if h.capabilities&clientPluginAuth != 0 && r.remaining() > 0 {
+		h.authPlugin = r.nullTerminated()
+	}
+
+	return h, r.err
+}
+
+// parseAuthSwitchRequest returns the plugin name and the new scramble.
+func parseAuthSwitchRequest(p []byte) (plugin string, scramble []byte) {
+	r := &reader{data: p, pos: 1}
+	plugin = r.nullTerminated()
+	scramble = bytes.TrimRight(r.rest(), "\x00")
+
+	return plugin, scramble
+}
+
+// parseChangeUser parses the COM_CHANGE_USER payload and returns the user and database.
+func parseChangeUser(p []byte) (user, database string) {
+	r := &reader{data: p, pos: 1}
+	user = r.nullTerminated()
+	r.skip(int(r.byte())) // auth response
+	database = r.nullTerminated()
+
+	return user, database
+}
+
+// response summarizes the server response to a command.
+type response struct {
+	status       string
+	errorCode    int32
+	errorMessage string
+	affectedRows int64
+	numRows      int64
+
+	// statement identifier returned for COM_STMT_PREPARE
+	statementID uint32
+}
+
+// isEOF checks if the payload is an EOF packet,
+// or an OK packet with the EOF header that replaces it when CLIENT_DEPRECATE_EOF was negotiated.
+// The OK packet carries session state information if it changed, so it is only distinguished
+// from a row starting with an 8 byte length encoded integer by being shorter than the maximum payload size.
+func isEOF(p []byte, deprecateEOF bool) bool {
+	if len(p) == 0 || p[0] != headerEOF {
+		return false
+	}
+
+	if deprecateEOF {
+		return len(p) < maxPayloadSize
+	}
+
+	return len(p) < maxEOFSize
+}
+
+// moreResults checks the status flags of an OK or EOF packet for another result set.
+func moreResults(p []byte, deprecateEOF bool) bool {
+	r := &reader{data: p, pos: 1}
+
+	if p[0] == headerEOF && !deprecateEOF {
+		r.skip(2) // warnings
+	} else {
+		r.lenencInt() // affected rows
+		r.lenencInt() // last insert id
+	}
+
+	return r.uint16()&serverMoreResultsExists != 0
+}
+
+// responseLength returns the number of packets at the beginning of packets
+// that belong to the server response to cmd, the response ends with its terminating OK, ERR or EOF packet.
+// If CLIENT_DEPRECATE_EOF was negotiated, the EOF packets after the column definitions are omitted.
+func responseLength(cmd byte, packets []*packet, deprecateEOF bool) int {
+	var i int
+
+	for i < len(packets) {
+		p := packets[i].payload
+		i++
+
+		switch {
+		case len(p) == 0, p[0] == headerERR:
+			return i
+		case p[0] == headerOK && cmd == comStmtPrepare:
+			// the OK is followed by the parameter and column definitions
+			r := &reader{data: p, pos: 5}
+			numColumns, numParams := int(r.uint16()), int(r.uint16())
+
+			for _, n := range []int{numParams, numColumns} {
+				if n == 0 {
+					continue
+				}
+
+				i += n
+				if !deprecateEOF {
+					i++
+				}
+			}
+
+			return min(i, len(packets))
+		case p[0] == headerOK, isEOF(p, deprecateEOF):
+			if !moreResults(p, deprecateEOF) {
+				return i
+			}
+		case p[0] == headerLocalInfile:
+			// the server replies with OK or ERR after the client sent the file
+			continue
+		case cmd == comStatistics:
+			// human readable string without header
+			return i
+		default:
+			// result set: column count, column definitions, EOF, rows, terminator
+			r := &reader{data: p}
+
+			i += int(r.lenencInt())
+			if !deprecateEOF {
+				i++
+			}
+
+			i = resultSetEnd(packets, i, deprecateEOF)
+			if i > len(packets) {
+				return len(packets)
+			}
+
+			if last := packets[i-1].payload; last[0] == headerERR || !moreResults(last, deprecateEOF) {
+				return i
+			}
+		}
+	}
+
+	return i
+}
+
+// resultSetEnd returns the index after the packet that terminates the rows starting at index i.
+func resultSetEnd(packets []*packet, i int, deprecateEOF bool) int {
+	for ; i < len(packets); i++ {
+		if p := packets[i].payload; isEOF(p, deprecateEOF) || len(p) > 0 && p[0] == headerERR {
+			return i + 1
+		}
+	}
+
+	return len(packets) + 1
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func parseErr(p []byte, res *response) {
+	r := &reader{data: p, pos: 1}
+	res.status = "ERR"
+	res.errorCode = int32(r.uint16())
+
+	// the SQL state is prefixed with a marker
+	if r.remaining() > 0 && r.data[r.pos] == '#' {
+		r.skip(6)
+	}
+
+	res.errorMessage = string(r.rest())
+}
+
+// parseResponse interprets the packets the server sent in reply to a command.
+// If CLIENT_DEPRECATE_EOF was negotiated, the EOF packets after the column definitions are omitted.
+func parseResponse(cmd byte, packets []*packet, deprecateEOF bool) *response {
+	res := new(response)
+
+	if len(packets) == 0 || len(packets[0].payload) == 0 {
+		return res
+	}
+
+	p := packets[0].payload
+
+	switch {
+	case p[0] == headerERR:
+		parseErr(p, res)
+	case p[0] == headerOK && cmd == comStmtPrepare:
+		r := &reader{data: p, pos: 1}
+		res.status = "OK"
+		res.statementID = r.uint32()
+	case p[0] == headerOK:
+		r := &reader{data: p, pos: 1}
+		res.status = "OK"
+		res.affectedRows = int64(r.lenencInt())
+	case p[0] == headerLocalInfile:
+		res.status = "LOCAL_INFILE"
+
+		// the final OK or ERR is sent after the client transmitted the file
+		if last := packets[len(packets)-1].payload; len(last) > 0 && last[0] == headerERR {
+			parseErr(last, res)
+		}
+	case isEOF(p, deprecateEOF):
+		res.status = "EOF"
+	default:
+		// result set: column count, column definitions, EOF, rows, terminator
+		r := &reader{data: p}
+		numColumns := int(r.lenencInt())
+		i := 1 + numColumns
+
+		res.status = "RESULTSET"
+
+		if !deprecateEOF {
+			i++
+		}
+
+		for ; i < len(packets); i++ {
+			pl := packets[i].payload
+			if isEOF(pl, deprecateEOF) {
+				break
+			}
+
+			if len(pl) > 0 && pl[0] == headerERR {
+				parseErr(pl, res)
+
+				break
+			}
+
+			res.numRows++
+		}
+	}
+
+	return res
+}
+
+// reader is a helper to consume the MySQL protocol data types.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) remaining() int {
+	if r.pos >= len(r.data) {
+		return 0
+	}
+
+	return len(r.data) - r.pos
+}
+
+func (r *reader) bytes(n int) []byte {
+	if n < 0 || r.remaining() < n {
+		r.err = errShortPacket
+		r.pos = len(r.data)
+
+		return nil
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *reader) rest() []byte {
+	return r.bytes(r.remaining())
+}
+
+func (r *reader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+
+	return 0
+}
+
+func (r *reader) nullTerminated() string {
+	if r.remaining() == 0 {
+		return ""
+	}
+
+	i := bytes.IndexByte(r.data[r.pos:], 0)
+	if i == -1 {
+		return string(r.rest())
+	}
+
+	s := string(r.data[r.pos : r.pos+i])
+	r.pos += i + 1
+
+	return s
+}
+
+// lenencInt reads a length encoded integer.
+func (r *reader) lenencInt() uint64 {
+	switch b := r.byte(); b {
+	case 0xfc:
+		return uint64(r.uint16())
+	case 0xfd:
+		if v := r.bytes(3); v != nil {
+			return uint64(v[0]) | uint64(v[1])<<8 | uint64(v[2])<<16
+		}
+	case 0xfe:
+		if v := r.bytes(8); v != nil {
+			return binary.LittleEndian.Uint64(v)
+		}
+	default:
+		return uint64(b)
+	}
+
+	return 0
+}
@@ -0,0 +1,255 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// encodePacket prepends the packet header to the payload.
+func encodePacket(seq byte, payload []byte) []byte {
+	return append([]byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}, payload...)
+}
+
+var (
+	scramble = []byte("abcdefgh" + "ijklmnopqrst")
+
+	// protocol 10 greeting from a MySQL 8 server using mysql_native_password
+	greetingPayload = bytes.Join([][]byte{
+		{protocolVersion},
+		[]byte("8.0.21\x00"),
+		{0x08, 0x00, 0x00, 0x00}, // connection id
+		scramble[:8],
+		{0x00},       // filler
+		{0xff, 0xff}, // capabilities lower
+		{0xff},       // charset
+		{0x02, 0x00}, // status
+		{0xff, 0xc7}, // capabilities upper
+		{21},         // length of auth plugin data
+		make([]byte, 10),
+		scramble[8:],
+		{0x00},
+		[]byte(nativePassword + "\x00"),
+	}, nil)
+
+	authResponse = []byte{
+		0x8d, 0x71, 0x7a, 0x6d, 0x2b, 0x3c, 0x91, 0x4b, 0x2f, 0x11,
+		0x9f, 0x8e, 0x22, 0x45, 0xbb, 0x01, 0x1c, 0x63, 0x0a, 0x7d,
+	}
+
+	handshakeResponsePayload = bytes.Join([][]byte{
+		{0x0d, 0xa2, 0x08, 0x00}, // capabilities: connect with db, secure connection, plugin auth
+		{0x00, 0x00, 0x00, 0x01}, // max packet size
+		{0x21},                   // charset
+		make([]byte, 23),
+		[]byte("root\x00"),
+		{byte(len(authResponse))},
+		authResponse,
+		[]byte("shop\x00"),
+		[]byte(nativePassword + "\x00"),
+	}, nil)
+)
+
+func TestParseGreeting(t *testing.T) {
+	if !isGreeting(encodePacket(0, greetingPayload)) {
+		t.Fatal("expected greeting to be detected")
+	}
+
+	g, err := parseGreeting(greetingPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.serverVersion != "8.0.21" {
+		t.Fatal("unexpected server version", g.serverVersion)
+	}
+
+	if !bytes.Equal(g.scramble, scramble) {
+		t.Fatal("unexpected scramble", string(g.scramble))
+	}
+
+	if g.authPlugin != nativePassword {
+		t.Fatal("unexpected auth plugin", g.authPlugin)
+	}
+}
+
+func TestParseHandshakeResponse(t *testing.T) {
+	h, err := parseHandshakeResponse(handshakeResponsePayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.user != "root" {
+		t.Fatal("unexpected user", h.user)
+	}
+
+	if h.database != "shop" {
+		t.Fatal("unexpected database", h.database)
+	}
+
+	if hex.EncodeToString(h.authResponse) != hex.EncodeToString(authResponse) {
+		t.Fatal("unexpected auth response", hex.EncodeToString(h.authResponse))
+	}
+
+	if h.authPlugin != nativePassword {
+		t.Fatal("unexpected auth plugin", h.authPlugin)
+	}
+
+	// SSLRequest
+	h, err = parseHandshakeResponse(handshakeResponsePayload[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.ssl {
+		t.Fatal("capabilities did not include CLIENT_SSL")
+	}
+}
+
+func TestParseResultSet(t *testing.T) {
+	data := bytes.Join([][]byte{
+		encodePacket(1, []byte{0x02}),                   // column count
+		encodePacket(2, []byte("\x03defid")),            // column definition
+		encodePacket(3, []byte("\x03defname")),          // column definition
+		encodePacket(4, []byte{0xfe, 0, 0, 0x02, 0}),    // EOF
+		encodePacket(5, []byte("\x011\x05alice")),       // row
+		encodePacket(6, []byte("\x012\x03bob")),         // row
+		encodePacket(7, []byte{0xfe, 0, 0, 0x02, 0}),    // EOF
+		encodePacket(1, []byte{0x00, 0x03, 0, 2, 0, 0}), // OK for the next command
+	}, nil)
+
+	packets := readPackets(data)
+	if len(packets) != 8 {
+		t.Fatal("unexpected number of packets", len(packets))
+	}
+
+	res := parseResponse(comQuery, packets[:7], false)
+	if res.status != "RESULTSET" {
+		t.Fatal("unexpected status", res.status)
+	}
+
+	if res.numRows != 2 {
+		t.Fatal("unexpected number of rows", res.numRows)
+	}
+
+	res = parseResponse(comQuery, packets[7:], false)
+	if res.status != "OK" || res.affectedRows != 3 {
+		t.Fatal("unexpected OK response", res.status, res.affectedRows)
+	}
+}
+
+func TestParseErr(t *testing.T) {
+	res := parseResponse(comQuery, []*packet{{
+		seq:     1,
+		payload: []byte("\xff\x7a\x04#42S02Table 'shop.users' doesn't exist"),
+	}}, false)
+
+	if res.status != "ERR" {
+		t.Fatal("unexpected status", res.status)
+	}
+
+	if res.errorCode != 1146 {
+		t.Fatal("unexpected error code", res.errorCode)
+	}
+
+	if res.errorMessage != "Table 'shop.users' doesn't exist" {
+		t.Fatal("unexpected error message", res.errorMessage)
+	}
+}
+
+func TestResponseLength(t *testing.T) {
+	// the sequence id of a result set with more than 255 rows wraps around
+	var rows [][]byte
+	for i := 0; i < 300; i++ {
+		rows = append(rows, encodePacket(byte(3+i), []byte("\x03row")))
+	}
+
+	data := bytes.Join([][]byte{
+		encodePacket(1, []byte{0x01}),        // column count
+		encodePacket(2, []byte("\x03defid")), // column definition
+		bytes.Join(rows, nil),
+		encodePacket(47, []byte{0xfe, 0, 0, 0x02, 0, 0, 0}), // OK with EOF header
+		encodePacket(1, []byte{0x00, 0x01, 0, 2, 0, 0}),     // OK for the next command
+	}, nil)
+
+	packets := readPackets(data)
+
+	if n := responseLength(comQuery, packets, true); n != 303 {
+		t.Fatal("unexpected response length", n)
+	}
+
+	if res := parseResponse(comQuery, packets[:303], true); res.status != "RESULTSET" || res.numRows != 300 {
+		t.Fatal("unexpected result set", res.status, res.numRows)
+	}
+
+	if n := responseLength(comQuery, packets[303:], true); n != 1 {
+		t.Fatal("unexpected response length", n)
+	}
+
+	// prepared statement with one parameter and two columns
+	data = bytes.Join([][]byte{
+		encodePacket(1, []byte{0x00, 1, 0, 0, 0, 2, 0, 1, 0, 0, 0, 0}),
+		encodePacket(2, []byte("\x03defparam")),
+		encodePacket(3, []byte{0xfe, 0, 0, 0x02, 0}),
+		encodePacket(4, []byte("\x03defid")),
+		encodePacket(5, []byte("\x03defname")),
+		encodePacket(6, []byte{0xfe, 0, 0, 0x02, 0}),
+		encodePacket(1, []byte{0x00, 0x00, 0, 2, 0, 0}),
+	}, nil)
+
+	if n := responseLength(comStmtPrepare, readPackets(data), false); n != 6 {
+		t.Fatal("unexpected prepare response length", n)
+	}
+
+	// two result sets returned by a stored procedure
+	data = bytes.Join([][]byte{
+		encodePacket(1, []byte{0x01}),
+		encodePacket(2, []byte("\x03defid")),
+		encodePacket(3, []byte{0xfe, 0, 0, 0x02, 0}),
+		encodePacket(4, []byte("\x011")),
+		encodePacket(5, []byte{0xfe, 0, 0, 0x0a, 0}), // more results exist
+		encodePacket(6, []byte{0x01}),
+		encodePacket(7, []byte("\x03defid")),
+		encodePacket(8, []byte{0xfe, 0, 0, 0x0a, 0}),
+		encodePacket(9, []byte{0xfe, 0, 0, 0x0a, 0}), // no rows, more results exist
+		encodePacket(10, []byte{0x00, 0x00, 0, 2, 0, 0}),
+	}, nil)
+
+	if n := responseLength(comQuery, readPackets(data), false); n != 10 {
+		t.Fatal("unexpected multi result set response length", n)
+	}
+
+	// with CLIENT_DEPRECATE_EOF, the result set ends with an OK packet that carries the changed session state
+	data = bytes.Join([][]byte{
+		encodePacket(1, []byte{0x01}),
+		encodePacket(2, []byte("\x03defid")),
+		encodePacket(3, []byte("\x011")),
+		encodePacket(4, []byte("\x012")),
+		// EOF header, affected rows, last insert id, status with SERVER_SESSION_STATE_CHANGED, warnings, info, session state
+		encodePacket(5, []byte("\xfe\x00\x00\x02\x40\x00\x00\x00\x0c\x00\x0a\x04shop\x01\x00\x02\x00\x01")),
+		encodePacket(1, []byte{0x00, 0x00, 0, 2, 0, 0}),
+	}, nil)
+
+	packets = readPackets(data)
+
+	if n := responseLength(comQuery, packets, true); n != 5 {
+		t.Fatal("unexpected response length with session state", n)
+	}
+
+	if res := parseResponse(comQuery, packets[:5], true); res.status != "RESULTSET" || res.numRows != 2 {
+		t.Fatal("unexpected result set with session state", res.status, res.numRows)
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package mysql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+type mysqlReader struct {
+	conversation *core.ConversationInfo
+
+	serverVersion string
+	user          string
+	database      string
+
+	// CLIENT_DEPRECATE_EOF was negotiated by client and server
+	deprecateEOF bool
+
+	// authentication state
+	authPlugin   string
+	scramble     []byte
+	authResponse []byte
+	authStatus   string
+
+	// prepared statements by their identifier
+	statements map[uint32]string
+}
+
+// New will instantiate a new MySQL reader.
+func (h *mysqlReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &mysqlReader{
+		conversation: conv,
+		statements:   make(map[uint32]string),
+	}
+}
+
+// Decode parses the stream according to the MySQL client server protocol.
+func (h *mysqlReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	var (
+		client, server = streamutils.SplitConversation(h.conversation.Data)
+		clientPackets  = readPackets(client.Data)
+		serverPackets  = readPackets(server.Data)
+	)
+
+	if len(serverPackets) == 0 || len(clientPackets) == 0 {
+		return
+	}
+
+	g, err := parseGreeting(serverPackets[0].payload)
+	if err != nil {
+		mysqlLog.Debug("failed to parse greeting",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+
+		return
+	}
+
+	h.serverVersion = g.serverVersion
+	h.authPlugin = g.authPlugin
+	h.scramble = g.scramble
+
+	hr, err := parseHandshakeResponse(clientPackets[0].payload)
+	if err != nil {
+		mysqlLog.Debug("failed to parse handshake response",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+
+		return
+	}
+
+	if hr.ssl {
+		mysqlLog.Debug("connection switched to TLS", zap.String("ident", h.conversation.Ident))
+
+		return
+	}
+
+	h.user = hr.user
+	h.database = hr.database
+	h.authResponse = hr.authResponse
+	h.deprecateEOF = g.capabilities&hr.capabilities&clientDeprecateEOF != 0
+
+	if hr.authPlugin != "" {
+		h.authPlugin = hr.authPlugin
+	}
+
+	clientPackets, serverPackets = h.processAuthentication(clientPackets[1:], serverPackets[1:])
+
+	if credentials.Decoder.Writer != nil {
+		h.writeCredentials()
+	}
+
+	h.processCommands(client, server, clientPackets, serverPackets)
+}
+
+// processAuthentication consumes the packets of the authentication phase
+// and returns the remaining packets of the command phase.
+func (h *mysqlReader) processAuthentication(clientPackets, serverPackets []*packet) ([]*packet, []*packet) {
+	var c, s int
+
+	for ; s < len(serverPackets); s++ {
+		p := serverPackets[s].payload
+		if len(p) == 0 {
+			continue
+		}
+
+		switch p[0] {
+		case headerOK:
+			h.authStatus = "OK"
+		case headerERR:
+			res := new(response)
+			parseErr(p, res)
+			h.authStatus = "ERR: " + res.errorMessage
+		case headerEOF:
+			// auth switch request, the client answers with the response for the requested plugin
+			h.authPlugin, h.scramble = parseAuthSwitchRequest(p)
+			if c < len(clientPackets) && clientPackets[c].seq != 0 {
+				h.authResponse = clientPackets[c].payload
+				c++
+			}
+
+			continue
+		default:
+			// additional authentication data exchanged by the plugin
+			for c < len(clientPackets) && clientPackets[c].seq != 0 && clientPackets[c].seq < serverPackets[s].seq {
+				c++
+			}
+
+			continue
+		}
+
+		break
+	}
+
+	// skip remaining packets from the client that belong to the authentication phase
+	for c < len(clientPackets) && clientPackets[c].seq != 0 {
+		c++
+	}
+
+	if s < len(serverPackets) {
+		s++
+	}
+
+	return clientPackets[c:], serverPackets[s:]
+}
+
+// writeCredentials emits the login of the session.
+// For the mysql_native_password plugin, the challenge and response are exported in the hashcat format (mode 11200).
+func (h *mysqlReader) writeCredentials() {
+	if h.user == "" {
+		return
+	}
+
+	var (
+		password string
+		notes    = "plugin: " + h.authPlugin
+	)
+
+	switch {
+	case len(h.authResponse) == 0:
+		notes += ", empty password"
+	case h.authPlugin == nativePassword && len(h.scramble) == scrambleLength && len(h.authResponse) == scrambleLength:
+		password = "$mysqlna$" + hex.EncodeToString(h.scramble) + "*" + hex.EncodeToString(h.authResponse)
+		notes += ", hashcat mode 11200"
+	}
+
+	if h.authStatus != "" {
+		notes += ", auth: " + h.authStatus
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp: h.conversation.FirstClientPacket.UnixNano(),
+		Service:   serviceMySQL,
+		Flow:      h.conversation.Ident,
+		User:      h.user,
+		Password:  password,
+		Notes:     notes,
+	})
+}
+
+// processCommands pairs the commands with the server responses and writes an audit record for each of them.
+func (h *mysqlReader) processCommands(client, server *streamutils.TimedStream, clientPackets, serverPackets []*packet) {
+	// only the first packet of a command carries sequence id 0
+	var commands []*packet
+
+	for _, p := range clientPackets {
+		if p.seq == 0 && len(p.payload) > 0 {
+			commands = append(commands, p)
+		}
+	}
+
+	for i, p := range commands {
+		var (
+			cmd = p.payload[0]
+			arg = p.payload[1:]
+			ts  = client.TimeAt(p.offset)
+			rec = &types.MySQL{
+				Timestamp:     ts.UnixNano(),
+				ClientIP:      h.conversation.ClientIP,
+				ServerIP:      h.conversation.ServerIP,
+				ClientPort:    h.conversation.ClientPort,
+				ServerPort:    h.conversation.ServerPort,
+				ServerVersion: h.serverVersion,
+				User:          h.user,
+				Database:      h.database,
+				Command:       commandName(cmd),
+				Flow:          h.conversation.Ident,
+			}
+		)
+
+		switch cmd {
+		case comQuery, comStmtPrepare, comInitDB, comCreateDB, comDropDB, comFieldList:
+			rec.Statement = string(arg)
+		case comStmtExecute, comStmtReset, comStmtFetch:
+			if len(arg) >= 4 {
+				rec.Statement = h.statements[binary.LittleEndian.Uint32(arg)]
+			}
+		case comChangeUser:
+			h.user, h.database = parseChangeUser(p.payload)
+			rec.User, rec.Database = h.user, h.database
+		}
+
+		var packets []*packet
+
+		// skip server packets sent before the command, which remain from an incomplete response
+		for len(serverPackets) > 0 && server.TimeAt(serverPackets[0].offset).Before(ts) {
+			serverPackets = serverPackets[1:]
+		}
+
+		if hasResponse(cmd) {
+			// the response ends with its terminating packet, or with the next command if it is incomplete
+			n := responseLength(cmd, serverPackets, h.deprecateEOF)
+
+			if i+1 < len(commands) {
+				next := client.TimeAt(commands[i+1].offset)
+
+				for j, sp := range serverPackets[:n] {
+					if server.TimeAt(sp.offset).After(next) {
+						n = j
+
+						break
+					}
+				}
+			}
+
+			packets, serverPackets = serverPackets[:n], serverPackets[n:]
+		}
+
+		if len(packets) > 0 {
+			res := parseResponse(cmd, packets, h.deprecateEOF)
+
+			rec.Status = res.status
+			rec.ErrorCode = res.errorCode
+			rec.Error = res.errorMessage
+			rec.AffectedRows = res.affectedRows
+			rec.NumRows = res.numRows
+			rec.Latency = server.TimeAt(packets[0].offset).Sub(ts).Nanoseconds()
+
+			if res.status == "OK" {
+				switch cmd {
+				case comInitDB:
+					h.database = rec.Statement
+				case comStmtPrepare:
+					h.statements[res.statementID] = rec.Statement
+				}
+			}
+		}
+
+		h.write(rec)
+	}
+}
+
+func (h *mysqlReader) write(rec *types.MySQL) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		rec.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(rec)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package postgres
+
+import (
+	"encoding/binary"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var postgresLog = zap.NewNop()
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_PostgreSQL,
+	Name:        servicePostgreSQL,
+	Description: "The PostgreSQL frontend backend protocol is used to issue queries to PostgreSQL database servers",
+	PostInit: func(d *decoder.StreamDecoder) error {
+		var err error
+		postgresLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"postgres",
+			decoderconfig.Instance.Debug,
+		)
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		return isStartup(client)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return postgresLog.Sync()
+	},
+	Factory: &postgresReader{},
+	Typ:     core.TCP,
+}
+
+const servicePostgreSQL = "PostgreSQL"
+
+// isStartup checks if the data starts with a startup, SSL or GSSAPI encryption request.
+func isStartup(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	if length < 8 || length > 10000 {
+		return false
+	}
+
+	switch binary.BigEndian.Uint32(data[4:]) {
+	case protocolVersion3, codeSSLRequest, codeGSSENCRequest:
+		return true
+	default:
+		return false
+	}
+}
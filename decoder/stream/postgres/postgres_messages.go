@@ -0,0 +1,280 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+/*
+ * PostgreSQL frontend backend protocol version 3
+ * https://www.postgresql.org/docs/current/protocol-message-formats.html
+ */
+
+const (
+	protocolVersion3  = 196608
+	codeCancelRequest = 80877102
+	codeSSLRequest    = 80877103
+	codeGSSENCRequest = 80877104
+
+	// authentication request codes.
+	authOK                = 0
+	authCleartextPassword = 3
+	authMD5Password       = 5
+	authSASL              = 10
+	authSASLContinue      = 11
+	authSASLFinal         = 12
+)
+
+// frontend message types.
+const (
+	msgBind         = 'B'
+	msgClose        = 'C'
+	msgDescribe     = 'D'
+	msgExecute      = 'E'
+	msgFunctionCall = 'F'
+	msgFlush        = 'H'
+	msgParse        = 'P'
+	msgPassword     = 'p'
+	msgQuery        = 'Q'
+	msgSync         = 'S'
+	msgTerminate    = 'X'
+)
+
+// backend message types.
+const (
+	msgAuthentication  = 'R'
+	msgCommandComplete = 'C'
+	msgDataRow         = 'D'
+	msgErrorResponse   = 'E'
+	msgEmptyQuery      = 'I'
+	msgParameterStatus = 'S'
+	msgReadyForQuery   = 'Z'
+)
+
+var frontendMessageNames = map[byte]string{
+	msgBind:         "Bind",
+	msgClose:        "Close",
+	msgDescribe:     "Describe",
+	msgExecute:      "Execute",
+	msgFunctionCall: "FunctionCall",
+	msgParse:        "Parse",
+	msgQuery:        "Query",
+}
+
+// message is a single typed protocol message.
+type message struct {
+	typ     byte
+	payload []byte
+
+	// offset of the message in the reassembled stream
+	offset int
+}
+
+// readMessages parses typed messages starting at pos, incomplete messages at the end are discarded.
+func readMessages(data []byte, pos int) (messages []*message) {
+	for pos+5 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos+1:]))
+		if length < 4 || pos+1+length > len(data) {
+			break
+		}
+
+		messages = append(messages, &message{
+			typ:     data[pos],
+			payload: data[pos+5 : pos+1+length],
+			offset:  pos,
+		})
+
+		pos += 1 + length
+	}
+
+	return messages
+}
+
+// readUntyped parses a message without a type byte, as used during connection startup.
+// It returns the request code, the payload and the position of the next message.
+func readUntyped(data []byte, pos int) (code uint32, payload []byte, next int, ok bool) {
+	if pos+8 > len(data) {
+		return 0, nil, pos, false
+	}
+
+	length := int(binary.BigEndian.Uint32(data[pos:]))
+	if length < 8 || pos+length > len(data) {
+		return 0, nil, pos, false
+	}
+
+	return binary.BigEndian.Uint32(data[pos+4:]), data[pos+8 : pos+length], pos + length, true
+}
+
+// parseStartupParameters parses the null terminated key value pairs of a startup message.
+func parseStartupParameters(p []byte) map[string]string {
+	var (
+		params = make(map[string]string)
+		fields = bytes.Split(p, []byte{0})
+	)
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		if len(fields[i]) == 0 {
+			break
+		}
+
+		params[string(fields[i])] = string(fields[i+1])
+	}
+
+	return params
+}
+
+// cstrings splits a payload into its null terminated strings.
+func cstrings(p []byte) []string {
+	var out []string
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, 0)
+		if i == -1 {
+			out = append(out, string(p))
+
+			break
+		}
+
+		out = append(out, string(p[:i]))
+		p = p[i+1:]
+	}
+
+	return out
+}
+
+// parseParse returns the statement name and query of a Parse message.
+func parseParse(p []byte) (name, query string) {
+	s := cstrings(p)
+	if len(s) < 2 {
+		return "", ""
+	}
+
+	return s[0], s[1]
+}
+
+// parseBind returns the portal and statement name of a Bind message.
+func parseBind(p []byte) (portal, statement string) {
+	s := cstrings(p)
+	if len(s) < 2 {
+		return "", ""
+	}
+
+	return s[0], s[1]
+}
+
+// errorResponse contains the relevant fields of an ErrorResponse message.
+type errorResponse struct {
+	severity string
+	code     string
+	message  string
+}
+
+func parseErrorResponse(p []byte) *errorResponse {
+	e := new(errorResponse)
+
+	for _, f := range cstrings(p) {
+		if len(f) < 2 {
+			continue
+		}
+
+		switch f[0] {
+		case 'S':
+			e.severity = f[1:]
+		case 'C':
+			e.code = f[1:]
+		case 'M':
+			e.message = f[1:]
+		}
+	}
+
+	return e
+}
+
+// rowsFromCommandTag extracts the number of affected rows from a command tag, e.g. "INSERT 0 5" or "SELECT 3".
+func rowsFromCommandTag(tag string) int64 {
+	fields := strings.Fields(tag)
+	if len(fields) < 2 {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// cycle summarizes the backend messages sent until the next ReadyForQuery.
+type cycle struct {
+	status     string
+	commandTag string
+	sqlState   string
+	error      string
+	numRows    int64
+
+	// offset of the first message of the cycle
+	offset int
+}
+
+// readCycles groups backend messages into query cycles terminated by ReadyForQuery.
+func readCycles(messages []*message) (cycles []*cycle) {
+	var (
+		current  *cycle
+		dataRows int64
+		tagRows  int64
+		tags     []string
+	)
+
+	for _, m := range messages {
+		if current == nil {
+			current = &cycle{
+				status: "OK",
+				offset: m.offset,
+			}
+		}
+
+		switch m.typ {
+		case msgDataRow:
+			dataRows++
+		case msgCommandComplete:
+			tag := strings.TrimRight(string(m.payload), "\x00")
+			tags = append(tags, tag)
+			tagRows += rowsFromCommandTag(tag)
+		case msgEmptyQuery:
+			current.status = "EMPTY"
+		case msgErrorResponse:
+			e := parseErrorResponse(m.payload)
+			current.status = "ERROR"
+			current.sqlState = e.code
+			current.error = e.message
+		case msgReadyForQuery:
+			current.commandTag = strings.Join(tags, "; ")
+			current.numRows = dataRows
+			if dataRows == 0 {
+				current.numRows = tagRows
+			}
+
+			cycles = append(cycles, current)
+			current = nil
+			dataRows, tagRows, tags = 0, 0, nil
+		}
+	}
+
+	return cycles
+}
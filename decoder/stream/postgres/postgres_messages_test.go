@@ -0,0 +1,168 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+)
+
+// encodeMessage creates a typed protocol message.
+func encodeMessage(typ byte, payload []byte) []byte {
+	b := make([]byte, 5, 5+len(payload))
+	b[0] = typ
+	binary.BigEndian.PutUint32(b[1:], uint32(4+len(payload)))
+
+	return append(b, payload...)
+}
+
+// encodeUntyped creates a message without type byte, as used for the startup.
+func encodeUntyped(code uint32, payload []byte) []byte {
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(8+len(payload)))
+	binary.BigEndian.PutUint32(b[4:], code)
+
+	return append(b, payload...)
+}
+
+func authRequest(code uint32, data []byte) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, code)
+
+	return encodeMessage(msgAuthentication, append(b, data...))
+}
+
+func TestStartup(t *testing.T) {
+	var (
+		client = bytes.Join([][]byte{
+			encodeUntyped(codeSSLRequest, nil),
+			encodeUntyped(protocolVersion3, []byte("user\x00alice\x00database\x00shop\x00application_name\x00psql\x00\x00")),
+		}, nil)
+		server = []byte("N")
+		h      = &postgresReader{conversation: &core.ConversationInfo{}, statements: make(map[string]string)}
+	)
+
+	if !isStartup(client) {
+		t.Fatal("expected startup to be detected")
+	}
+
+	clientPos, serverPos, ok := h.processStartup(client, server)
+	if !ok {
+		t.Fatal("failed to process startup")
+	}
+
+	if clientPos != len(client) || serverPos != 1 {
+		t.Fatal("unexpected positions", clientPos, serverPos)
+	}
+
+	if h.user != "alice" || h.database != "shop" || h.application != "psql" {
+		t.Fatal("unexpected startup parameters", h.user, h.database, h.application)
+	}
+
+	// SSL accepted
+	if _, _, ok = h.processStartup(client, []byte("S")); ok {
+		t.Fatal("expected encrypted connection to be skipped")
+	}
+}
+
+func TestMD5Authentication(t *testing.T) {
+	var (
+		client = encodeMessage(msgPassword, []byte("md5a3556571e93b0d20722ba62be61e8c2d\x00"))
+		server = bytes.Join([][]byte{
+			authRequest(authMD5Password, []byte{0x01, 0x02, 0x03, 0x04}),
+			authRequest(authOK, nil),
+			encodeMessage(msgParameterStatus, []byte("server_version\x0012.4\x00")),
+			encodeMessage(msgReadyForQuery, []byte("I")),
+			encodeMessage(msgCommandComplete, []byte("SELECT 1\x00")),
+		}, nil)
+		h = &postgresReader{conversation: &core.ConversationInfo{}, statements: make(map[string]string)}
+	)
+
+	clientMessages, serverMessages := h.processAuthentication(readMessages(client, 0), readMessages(server, 0))
+	if len(clientMessages) != 0 || len(serverMessages) != 1 {
+		t.Fatal("unexpected number of remaining messages", len(clientMessages), len(serverMessages))
+	}
+
+	if h.authMethod != "md5" || h.authStatus != "OK" {
+		t.Fatal("unexpected auth state", h.authMethod, h.authStatus)
+	}
+
+	if !bytes.Equal(h.salt, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Fatal("unexpected salt", h.salt)
+	}
+
+	if h.password != "md5a3556571e93b0d20722ba62be61e8c2d" {
+		t.Fatal("unexpected password", h.password)
+	}
+
+	if h.serverVersion != "12.4" {
+		t.Fatal("unexpected server version", h.serverVersion)
+	}
+}
+
+func TestRequestsAndCycles(t *testing.T) {
+	var (
+		client = bytes.Join([][]byte{
+			encodeMessage(msgQuery, []byte("SELECT * FROM users\x00")),
+			encodeMessage(msgParse, []byte("s1\x00INSERT INTO users VALUES ($1)\x00\x00\x00")),
+			encodeMessage(msgBind, []byte("\x00s1\x00\x00\x00")),
+			encodeMessage(msgExecute, []byte("\x00\x00\x00\x00\x00")),
+			encodeMessage(msgSync, nil),
+			encodeMessage(msgQuery, []byte("SELECT * FROM missing\x00")),
+		}, nil)
+		server = bytes.Join([][]byte{
+			encodeMessage('T', []byte{0, 0}),
+			encodeMessage(msgDataRow, []byte{0, 0}),
+			encodeMessage(msgDataRow, []byte{0, 0}),
+			encodeMessage(msgCommandComplete, []byte("SELECT 2\x00")),
+			encodeMessage(msgReadyForQuery, []byte("I")),
+			encodeMessage('1', nil),
+			encodeMessage('2', nil),
+			encodeMessage(msgCommandComplete, []byte("INSERT 0 1\x00")),
+			encodeMessage(msgReadyForQuery, []byte("I")),
+			encodeMessage(msgErrorResponse, []byte("SERROR\x00C42P01\x00Mrelation \"missing\" does not exist\x00\x00")),
+			encodeMessage(msgReadyForQuery, []byte("I")),
+		}, nil)
+		h = &postgresReader{conversation: &core.ConversationInfo{}, statements: make(map[string]string)}
+	)
+
+	requests := h.readRequests(readMessages(client, 0))
+	if len(requests) != 3 {
+		t.Fatal("unexpected number of requests", len(requests))
+	}
+
+	if requests[1].command != "Parse,Bind,Execute" || requests[1].statement != "INSERT INTO users VALUES ($1)" {
+		t.Fatal("unexpected extended query request", requests[1].command, requests[1].statement)
+	}
+
+	cycles := readCycles(readMessages(server, 0))
+	if len(cycles) != 3 {
+		t.Fatal("unexpected number of cycles", len(cycles))
+	}
+
+	if cycles[0].status != "OK" || cycles[0].numRows != 2 || cycles[0].commandTag != "SELECT 2" {
+		t.Fatal("unexpected first cycle", cycles[0])
+	}
+
+	if cycles[1].numRows != 1 {
+		t.Fatal("unexpected number of rows for insert", cycles[1].numRows)
+	}
+
+	if cycles[2].status != "ERROR" || cycles[2].sqlState != "42P01" || cycles[2].error != "relation \"missing\" does not exist" {
+		t.Fatal("unexpected error cycle", cycles[2])
+	}
+}
@@ -0,0 +1,347 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package postgres
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+type postgresReader struct {
+	conversation *core.ConversationInfo
+
+	serverVersion string
+	user          string
+	database      string
+	application   string
+
+	// authentication state
+	authMethod string
+	password   string
+	salt       []byte
+	authStatus string
+
+	// prepared statements by name
+	statements map[string]string
+}
+
+// request is a group of frontend messages that is answered with a single ReadyForQuery.
+type request struct {
+	command   string
+	statement string
+	offset    int
+}
+
+// New will instantiate a new PostgreSQL reader.
+func (h *postgresReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &postgresReader{
+		conversation: conv,
+		statements:   make(map[string]string),
+	}
+}
+
+// Decode parses the stream according to the PostgreSQL frontend backend protocol.
+func (h *postgresReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	client, server := streamutils.SplitConversation(h.conversation.Data)
+
+	clientPos, serverPos, ok := h.processStartup(client.Data, server.Data)
+	if !ok {
+		return
+	}
+
+	var (
+		clientMessages = readMessages(client.Data, clientPos)
+		serverMessages = readMessages(server.Data, serverPos)
+	)
+
+	clientMessages, serverMessages = h.processAuthentication(clientMessages, serverMessages)
+
+	if credentials.Decoder.Writer != nil {
+		h.writeCredentials()
+	}
+
+	var (
+		requests = h.readRequests(clientMessages)
+		cycles   = readCycles(serverMessages)
+	)
+
+	for i, req := range requests {
+		ts := client.TimeAt(req.offset)
+		rec := &types.PostgreSQL{
+			Timestamp:     ts.UnixNano(),
+			ClientIP:      h.conversation.ClientIP,
+			ServerIP:      h.conversation.ServerIP,
+			ClientPort:    h.conversation.ClientPort,
+			ServerPort:    h.conversation.ServerPort,
+			ServerVersion: h.serverVersion,
+			User:          h.user,
+			Database:      h.database,
+			Application:   h.application,
+			Command:       req.command,
+			Statement:     req.statement,
+			Flow:          h.conversation.Ident,
+		}
+
+		if i < len(cycles) {
+			c := cycles[i]
+			rec.Status = c.status
+			rec.CommandTag = c.commandTag
+			rec.SQLState = c.sqlState
+			rec.Error = c.error
+			rec.NumRows = c.numRows
+			rec.Latency = server.TimeAt(c.offset).Sub(ts).Nanoseconds()
+		}
+
+		h.write(rec)
+	}
+}
+
+// processStartup handles the untyped messages at the beginning of a connection
+// and returns the positions of the first typed messages in the client and server streams.
+// If the connection has been upgraded to an encrypted transport, ok will be false.
+func (h *postgresReader) processStartup(client, server []byte) (clientPos, serverPos int, ok bool) {
+	for {
+		code, payload, next, valid := readUntyped(client, clientPos)
+		if !valid {
+			return 0, 0, false
+		}
+
+		clientPos = next
+
+		switch code {
+		case codeSSLRequest, codeGSSENCRequest:
+			// the server answers with a single byte
+			if serverPos >= len(server) {
+				return 0, 0, false
+			}
+
+			answer := server[serverPos]
+			serverPos++
+
+			if answer == 'S' || answer == 'G' {
+				postgresLog.Debug("connection switched to an encrypted transport",
+					zap.String("ident", h.conversation.Ident),
+				)
+
+				return 0, 0, false
+			}
+		case protocolVersion3:
+			params := parseStartupParameters(payload)
+			h.user = params["user"]
+			h.database = params["database"]
+			h.application = params["application_name"]
+
+			// the database defaults to the user name
+			if h.database == "" {
+				h.database = h.user
+			}
+
+			return clientPos, serverPos, true
+		default:
+			// cancel requests and unsupported protocol versions
+			return 0, 0, false
+		}
+	}
+}
+
+// processAuthentication consumes the messages exchanged until the server is ready for the first query
+// and returns the remaining messages.
+func (h *postgresReader) processAuthentication(clientMessages, serverMessages []*message) ([]*message, []*message) {
+	var (
+		c, s      int
+		passwords []*message
+	)
+
+	// password, SASL initial and SASL response messages all use the same type
+	for c < len(clientMessages) && clientMessages[c].typ == msgPassword {
+		passwords = append(passwords, clientMessages[c])
+		c++
+	}
+
+	for ; s < len(serverMessages); s++ {
+		m := serverMessages[s]
+
+		switch m.typ {
+		case msgAuthentication:
+			if len(m.payload) < 4 {
+				continue
+			}
+
+			switch code := binary.BigEndian.Uint32(m.payload); code {
+			case authOK:
+				h.authStatus = "OK"
+			case authCleartextPassword:
+				h.authMethod = "cleartext"
+				if len(passwords) > 0 {
+					h.password = strings.TrimRight(string(passwords[0].payload), "\x00")
+					passwords = passwords[1:]
+				}
+			case authMD5Password:
+				h.authMethod = "md5"
+				h.salt = m.payload[4:]
+				if len(passwords) > 0 {
+					h.password = strings.TrimRight(string(passwords[0].payload), "\x00")
+					passwords = passwords[1:]
+				}
+			case authSASL:
+				h.authMethod = "SASL " + strings.Join(cstrings(m.payload[4:]), " ")
+				if len(passwords) > 0 {
+					passwords = passwords[1:]
+				}
+			case authSASLContinue:
+				if len(passwords) > 0 {
+					passwords = passwords[1:]
+				}
+			case authSASLFinal:
+			default:
+				h.authMethod = "code " + strconv.FormatUint(uint64(code), 10)
+			}
+		case msgParameterStatus:
+			if kv := cstrings(m.payload); len(kv) == 2 && kv[0] == "server_version" {
+				h.serverVersion = kv[1]
+			}
+		case msgErrorResponse:
+			h.authStatus = "ERROR: " + parseErrorResponse(m.payload).message
+		case msgReadyForQuery:
+			return clientMessages[c:], serverMessages[s+1:]
+		}
+	}
+
+	return clientMessages[c:], serverMessages[s:]
+}
+
+// writeCredentials emits the login of the session.
+// MD5 challenge responses are exported in the hashcat format (mode 11100).
+func (h *postgresReader) writeCredentials() {
+	if h.user == "" {
+		return
+	}
+
+	var (
+		password string
+		notes    []string
+	)
+
+	switch h.authMethod {
+	case "":
+		notes = append(notes, "no password required")
+	case "cleartext":
+		password = h.password
+		notes = append(notes, "cleartext password")
+	case "md5":
+		if strings.HasPrefix(h.password, "md5") && len(h.salt) == 4 {
+			password = "$postgres$" + h.user + "*" + hex.EncodeToString(h.salt) + "*" + strings.TrimPrefix(h.password, "md5")
+			notes = append(notes, "hashcat mode 11100")
+		}
+	default:
+		notes = append(notes, "auth method: "+h.authMethod)
+	}
+
+	if h.authStatus != "" {
+		notes = append(notes, "auth: "+h.authStatus)
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp: h.conversation.FirstClientPacket.UnixNano(),
+		Service:   servicePostgreSQL,
+		Flow:      h.conversation.Ident,
+		User:      h.user,
+		Password:  password,
+		Notes:     strings.Join(notes, ", "),
+	})
+}
+
+// readRequests groups the frontend messages into requests:
+// a simple query is answered directly, the messages of the extended query protocol are collected until a Sync.
+func (h *postgresReader) readRequests(messages []*message) (requests []*request) {
+	var (
+		current *request
+		names   []string
+	)
+
+	for _, m := range messages {
+		switch m.typ {
+		case msgQuery:
+			requests = append(requests, &request{
+				command:   frontendMessageNames[msgQuery],
+				statement: strings.TrimRight(string(m.payload), "\x00"),
+				offset:    m.offset,
+			})
+		case msgFunctionCall:
+			requests = append(requests, &request{
+				command: frontendMessageNames[msgFunctionCall],
+				offset:  m.offset,
+			})
+		case msgParse, msgBind, msgDescribe, msgExecute, msgClose:
+			if current == nil {
+				current = &request{offset: m.offset}
+			}
+
+			names = append(names, frontendMessageNames[m.typ])
+
+			switch m.typ {
+			case msgParse:
+				name, query := parseParse(m.payload)
+				h.statements[name] = query
+				current.statement = query
+			case msgBind:
+				if _, stmt := parseBind(m.payload); current.statement == "" {
+					current.statement = h.statements[stmt]
+				}
+			}
+		case msgSync:
+			if current == nil {
+				current = &request{offset: m.offset}
+			}
+
+			current.command = strings.Join(names, ",")
+			requests = append(requests, current)
+			current, names = nil, nil
+		}
+	}
+
+	return requests
+}
+
+func (h *postgresReader) write(rec *types.PostgreSQL) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		rec.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(rec)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
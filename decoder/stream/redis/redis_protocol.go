@@ -0,0 +1,239 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package redis
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+/*
+ * Redis serialization protocol (RESP2 and RESP3)
+ * https://redis.io/topics/protocol
+ */
+
+const (
+	typeSimpleString = '+'
+	typeError        = '-'
+	typeInteger      = ':'
+	typeBulkString   = '$'
+	typeArray        = '*'
+
+	// RESP3 types
+	typeNull           = '_'
+	typeDouble         = ','
+	typeBoolean        = '#'
+	typeBlobError      = '!'
+	typeVerbatimString = '='
+	typeBigNumber      = '('
+	typeMap            = '%'
+	typeSet            = '~'
+	typeAttribute      = '|'
+	typePush           = '>'
+
+	// maximum nesting level for aggregate types
+	maxDepth = 32
+
+	// arguments exceeding this size are truncated in the audit records
+	maxArgumentLength = 512
+)
+
+var (
+	errIncomplete = errors.New("incomplete value")
+	errInvalid    = errors.New("invalid value")
+	errTooDeep    = errors.New("maximum nesting level exceeded")
+
+	crlf = []byte("\r\n")
+)
+
+// value is a decoded RESP value.
+type value struct {
+	typ   byte
+	str   string
+	elems []*value
+	null  bool
+}
+
+// isError checks if the value is a simple or blob error.
+func (v *value) isError() bool {
+	return v.typ == typeError || v.typ == typeBlobError
+}
+
+// numElements returns the number of elements for aggregate types,
+// zero for null values and one for everything else.
+func (v *value) numElements() int64 {
+	switch {
+	case v.null:
+		return 0
+	case v.typ == typeMap:
+		return int64(len(v.elems) / 2)
+	case v.elems != nil:
+		return int64(len(v.elems))
+	default:
+		return 1
+	}
+}
+
+// isRESPCommand checks if the data starts with a command encoded as array of bulk strings.
+func isRESPCommand(data []byte) bool {
+	return len(data) > 4 && data[0] == typeArray && data[1] >= '0' && data[1] <= '9' && bytes.Contains(data, crlf)
+}
+
+// isRESPReply checks if the data starts with a RESP type marker.
+func isRESPReply(data []byte) bool {
+	if len(data) < 3 || !bytes.Contains(data, crlf) {
+		return false
+	}
+
+	switch data[0] {
+	case typeSimpleString, typeError, typeInteger, typeBulkString, typeArray,
+		typeNull, typeDouble, typeBoolean, typeBlobError, typeVerbatimString,
+		typeBigNumber, typeMap, typeSet, typeAttribute, typePush:
+		return true
+	default:
+		return false
+	}
+}
+
+// readLine returns the content until the next CRLF and the position after it.
+func readLine(data []byte, pos int) (string, int, error) {
+	i := bytes.Index(data[pos:], crlf)
+	if i == -1 {
+		return "", pos, errIncomplete
+	}
+
+	return string(data[pos : pos+i]), pos + i + 2, nil
+}
+
+// parseCommand parses a command sent by the client,
+// either as RESP array or as inline command separated by whitespace.
+func parseCommand(data []byte, pos int) (args []string, next int, err error) {
+	if pos >= len(data) {
+		return nil, pos, errIncomplete
+	}
+
+	if data[pos] != typeArray {
+		line, n, errLine := readLine(data, pos)
+		if errLine != nil {
+			return nil, pos, errLine
+		}
+
+		return splitInline(line), n, nil
+	}
+
+	v, next, err := parseValue(data, pos, 0)
+	if err != nil {
+		return nil, pos, err
+	}
+
+	for _, e := range v.elems {
+		args = append(args, e.str)
+	}
+
+	return args, next, nil
+}
+
+// splitInline splits an inline command into its arguments.
+func splitInline(line string) []string {
+	var args []string
+
+	for _, f := range bytes.Fields([]byte(line)) {
+		args = append(args, string(f))
+	}
+
+	return args
+}
+
+// parseValue decodes a single RESP value starting at pos.
+func parseValue(data []byte, pos, depth int) (*value, int, error) {
+	if depth > maxDepth {
+		return nil, pos, errTooDeep
+	}
+
+	if pos >= len(data) {
+		return nil, pos, errIncomplete
+	}
+
+	typ := data[pos]
+
+	line, next, err := readLine(data, pos+1)
+	if err != nil {
+		return nil, pos, err
+	}
+
+	v := &value{typ: typ}
+
+	switch typ {
+	case typeSimpleString, typeError, typeInteger, typeDouble, typeBoolean, typeBigNumber:
+		v.str = line
+	case typeNull:
+		v.null = true
+	case typeBulkString, typeBlobError, typeVerbatimString:
+		n, errConv := strconv.Atoi(line)
+		if errConv != nil {
+			return nil, pos, errInvalid
+		}
+
+		if n < 0 {
+			v.null = true
+
+			return v, next, nil
+		}
+
+		if next+n+2 > len(data) {
+			return nil, pos, errIncomplete
+		}
+
+		v.str = string(data[next : next+n])
+		next += n + 2
+	case typeArray, typeSet, typePush, typeMap, typeAttribute:
+		n, errConv := strconv.Atoi(line)
+		if errConv != nil {
+			return nil, pos, errInvalid
+		}
+
+		if n < 0 {
+			v.null = true
+
+			return v, next, nil
+		}
+
+		if typ == typeMap || typ == typeAttribute {
+			n *= 2
+		}
+
+		v.elems = make([]*value, 0, n)
+
+		for i := 0; i < n; i++ {
+			var e *value
+
+			e, next, err = parseValue(data, next, depth+1)
+			if err != nil {
+				return nil, pos, err
+			}
+
+			v.elems = append(v.elems, e)
+		}
+
+		// attributes carry auxiliary data and are followed by the actual reply
+		if typ == typeAttribute {
+			return parseValue(data, next, depth+1)
+		}
+	default:
+		return nil, pos, errInvalid
+	}
+
+	return v, next, nil
+}
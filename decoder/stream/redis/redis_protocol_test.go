@@ -0,0 +1,112 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package redis
+
+import (
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	data := []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\nPING\r\n")
+
+	if !isRESPCommand(data) {
+		t.Fatal("expected command to be detected")
+	}
+
+	args, next, err := parseCommand(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(args) != 3 || args[0] != "SET" || args[1] != "key" || args[2] != "value" {
+		t.Fatal("unexpected arguments", args)
+	}
+
+	// inline command
+	args, next, err = parseCommand(data, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(args) != 1 || args[0] != "PING" || next != len(data) {
+		t.Fatal("unexpected inline command", args, next)
+	}
+}
+
+func TestParseReplies(t *testing.T) {
+	data := []byte("+OK\r\n-WRONGPASS invalid username-password pair\r\n:42\r\n$-1\r\n*2\r\n$1\r\na\r\n*1\r\n:1\r\n%1\r\n+key\r\n#t\r\n$5\r\nhel")
+
+	if !isRESPReply(data) {
+		t.Fatal("expected reply to be detected")
+	}
+
+	var (
+		pos    int
+		values []*value
+	)
+
+	for {
+		v, next, err := parseValue(data, pos, 0)
+		if err != nil {
+			if err != errIncomplete {
+				t.Fatal(err)
+			}
+
+			break
+		}
+
+		values = append(values, v)
+		pos = next
+	}
+
+	if len(values) != 6 {
+		t.Fatal("unexpected number of values", len(values))
+	}
+
+	if values[0].str != "OK" || values[0].isError() {
+		t.Fatal("unexpected simple string", values[0])
+	}
+
+	if !values[1].isError() || values[1].str != "WRONGPASS invalid username-password pair" {
+		t.Fatal("unexpected error", values[1])
+	}
+
+	if values[2].str != "42" || values[2].numElements() != 1 {
+		t.Fatal("unexpected integer", values[2])
+	}
+
+	if !values[3].null || values[3].numElements() != 0 {
+		t.Fatal("expected null bulk string", values[3])
+	}
+
+	if values[4].numElements() != 2 || values[4].elems[1].elems[0].str != "1" {
+		t.Fatal("unexpected nested array", values[4])
+	}
+
+	if values[5].numElements() != 1 || values[5].elems[1].str != "t" {
+		t.Fatal("unexpected map", values[5])
+	}
+}
+
+func TestStatement(t *testing.T) {
+	long := make([]byte, maxArgumentLength+10)
+	for i := range long {
+		long[i] = 'x'
+	}
+
+	s := statement([]string{"SET", "key", string(long)})
+	if len(s) != len("SET key ")+maxArgumentLength+3 {
+		t.Fatal("expected argument to be truncated", len(s))
+	}
+}
@@ -0,0 +1,263 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+type redisReader struct {
+	conversation *core.ConversationInfo
+
+	user     string
+	database int32
+}
+
+// command is a request issued by the client.
+type command struct {
+	args   []string
+	offset int
+}
+
+// reply is a response sent by the server.
+type reply struct {
+	value  *value
+	offset int
+}
+
+// New will instantiate a new Redis reader.
+func (h *redisReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &redisReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the Redis serialization protocol.
+func (h *redisReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	var (
+		client, server = streamutils.SplitConversation(h.conversation.Data)
+		commands       = h.readCommands(client.Data)
+		replies        = h.readReplies(server.Data)
+	)
+
+	for i, c := range commands {
+		ts := client.TimeAt(c.offset)
+		rec := &types.Redis{
+			Timestamp:  ts.UnixNano(),
+			ClientIP:   h.conversation.ClientIP,
+			ServerIP:   h.conversation.ServerIP,
+			ClientPort: h.conversation.ClientPort,
+			ServerPort: h.conversation.ServerPort,
+			User:       h.user,
+			Database:   h.database,
+			Command:    strings.ToUpper(c.args[0]),
+			Statement:  statement(c.args),
+			Flow:       h.conversation.Ident,
+		}
+
+		var res *value
+		if i < len(replies) {
+			res = replies[i].value
+			rec.NumElements = res.numElements()
+			rec.Latency = server.TimeAt(replies[i].offset).Sub(ts).Nanoseconds()
+
+			if res.isError() {
+				rec.Status = "ERR"
+				rec.Error = res.str
+			} else {
+				rec.Status = "OK"
+			}
+		}
+
+		h.processCommand(rec.Command, c.args, res)
+		h.write(rec)
+
+		// replies after entering the subscriber or monitor mode are no longer paired with commands
+		switch rec.Command {
+		case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "MONITOR":
+			return
+		}
+	}
+}
+
+func (h *redisReader) readCommands(data []byte) (commands []*command) {
+	for pos := 0; pos < len(data); {
+		args, next, err := parseCommand(data, pos)
+		if err != nil {
+			if !errors.Is(err, errIncomplete) {
+				redisLog.Debug("failed to parse command",
+					zap.String("ident", h.conversation.Ident),
+					zap.Error(err),
+				)
+			}
+
+			break
+		}
+
+		// ignore empty lines
+		if len(args) > 0 {
+			commands = append(commands, &command{
+				args:   args,
+				offset: pos,
+			})
+		}
+
+		pos = next
+	}
+
+	return commands
+}
+
+func (h *redisReader) readReplies(data []byte) (replies []*reply) {
+	for pos := 0; pos < len(data); {
+		v, next, err := parseValue(data, pos, 0)
+		if err != nil {
+			if !errors.Is(err, errIncomplete) {
+				redisLog.Debug("failed to parse reply",
+					zap.String("ident", h.conversation.Ident),
+					zap.Error(err),
+				)
+			}
+
+			break
+		}
+
+		// out of band push messages are not replies to a command
+		if v.typ != typePush {
+			replies = append(replies, &reply{
+				value:  v,
+				offset: pos,
+			})
+		}
+
+		pos = next
+	}
+
+	return replies
+}
+
+// processCommand updates the connection state for commands that change the session.
+func (h *redisReader) processCommand(cmd string, args []string, res *value) {
+	success := res != nil && !res.isError()
+
+	switch cmd {
+	case "SELECT":
+		if success && len(args) == 2 {
+			if db, err := strconv.Atoi(args[1]); err == nil {
+				h.database = int32(db)
+			}
+		}
+	case "AUTH":
+		// AUTH password or AUTH username password
+		switch len(args) {
+		case 2:
+			h.writeCredentials("default", args[1], res)
+		case 3:
+			h.writeCredentials(args[1], args[2], res)
+		}
+	case "HELLO":
+		// HELLO protover AUTH username password
+		for i := 2; i+2 < len(args); i++ {
+			if strings.EqualFold(args[i], "AUTH") {
+				h.writeCredentials(args[i+1], args[i+2], res)
+
+				break
+			}
+		}
+	}
+}
+
+func (h *redisReader) writeCredentials(user, password string, res *value) {
+	if res != nil && !res.isError() {
+		h.user = user
+	}
+
+	if credentials.Decoder.Writer == nil {
+		return
+	}
+
+	var notes string
+
+	switch {
+	case res == nil:
+		notes = "auth: no reply"
+	case res.isError():
+		notes = "auth: " + res.str
+	default:
+		notes = "auth: OK"
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp: h.conversation.FirstClientPacket.UnixNano(),
+		Service:   serviceRedis,
+		Flow:      h.conversation.Ident,
+		User:      user,
+		Password:  password,
+		Notes:     notes,
+	})
+}
+
+// statement joins the command arguments and truncates large values.
+func statement(args []string) string {
+	var b strings.Builder
+
+	for i, a := range args {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		if len(a) > maxArgumentLength {
+			b.WriteString(a[:maxArgumentLength])
+			b.WriteString("...")
+
+			continue
+		}
+
+		b.WriteString(a)
+	}
+
+	return b.String()
+}
+
+func (h *redisReader) write(rec *types.Redis) {
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		rec.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(rec)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -9,7 +9,12 @@ import (
 	"time"
 
 	"github.com/dreadl0ck/netcap/decoder/stream/http"
+	"github.com/dreadl0ck/netcap/decoder/stream/kerberos"
+	"github.com/dreadl0ck/netcap/decoder/stream/ldap"
+	"github.com/dreadl0ck/netcap/decoder/stream/mysql"
 	"github.com/dreadl0ck/netcap/decoder/stream/pop3"
+	"github.com/dreadl0ck/netcap/decoder/stream/postgres"
+	"github.com/dreadl0ck/netcap/decoder/stream/redis"
 	"github.com/dreadl0ck/netcap/decoder/stream/smtp"
 	"github.com/dreadl0ck/netcap/decoder/stream/ssh"
 
@@ -33,10 +38,15 @@ var Debug bool
 // DefaultStreamDecoders contains stream decoders mapped to their protocols default port
 // int32 is used to avoid casting when looking up values
 var DefaultStreamDecoders = map[int32]core.StreamDecoderAPI{
-	80:  http.Decoder,
-	110: pop3.Decoder,
-	22:  ssh.Decoder,
-	25:  smtp.Decoder,
+	80:   http.Decoder,
+	110:  pop3.Decoder,
+	22:   ssh.Decoder,
+	25:   smtp.Decoder,
+	3306: mysql.Decoder,
+	5432: postgres.Decoder,
+	6379: redis.Decoder,
+	88:   kerberos.Decoder,
+	389:  ldap.Decoder,
 } // contains all available stream decoders
 
 // package level init.
@@ -151,6 +161,10 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 				Label:   c.Label,
 				Proto:   c.Proto,
 				JSON:    c.JSON,
+				Zeek:    c.Zeek,
+				EVE:     c.EVE,
+				Parquet: c.Parquet,
+				SQLite:  c.SQLite,
 				Name:    dec.GetName(),
 				Type:    dec.GetType(),
 				Null:    c.Null,
@@ -164,6 +178,7 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 				},
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
+				Index:                c.Index,
 				Out:                  c.Out,
 				Chan:                 c.Chan,
 				ChanSize:             c.ChanSize,
@@ -174,6 +189,10 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 				StartTime:            time.Now(),
 				CompressionBlockSize: c.CompressionBlockSize,
 				CompressionLevel:     c.CompressionLevel,
+				CompressionCodec:     c.CompressionCodec,
+				CompressionDict:      c.CompressionDict,
+				ParquetCompression:   c.ParquetCompression,
+				ParquetRowGroupSize:  c.ParquetRowGroupSize,
 			})
 			dec.SetWriter(w)
 
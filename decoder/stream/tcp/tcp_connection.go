@@ -425,15 +425,21 @@ var aMu sync.Mutex
 // ReassemblePacket takes care of submitting a TCP / UDP packet to the reassembly.
 func ReassemblePacket(packet gopacket.Packet, assembler *reassembly.Assembler) {
 
+	// tunneled packets are reassembled based on their innermost network and transport layers,
+	// the encapsulation is added to the flow key to keep overlapping tenant address spaces apart.
+	encapsulation, netLayer, transportLayer := utils.DecodeEncapsulation(packet)
+	if netLayer == nil || transportLayer == nil {
+		return
+	}
+
 	// TODO: make transport layer reassembler configurable
 	// prevent passing any non TCP packets in here
-	tcpLayer := packet.Layer(layers.LayerTypeTCP)
-	if tcpLayer == nil {
+	tcp, ok := transportLayer.(*layers.TCP)
+	if !ok {
 
 		// handle UDP stream reconstruction
-		udpLayer := packet.Layer(layers.LayerTypeUDP)
-		if udpLayer != nil {
-			udp.Streams.HandleUDP(packet, udpLayer)
+		if udpLayer, isUDP := transportLayer.(*layers.UDP); isUDP {
+			udp.Streams.HandleUDP(packet, udpLayer, netLayer, encapsulation)
 		}
 
 		return
@@ -480,10 +486,8 @@ func ReassemblePacket(packet gopacket.Packet, assembler *reassembly.Assembler) {
 		}
 	}
 
-	tcp := tcpLayer.(*layers.TCP)
-
 	if decoderconfig.Instance.Checksum {
-		err := tcp.SetNetworkLayerForChecksum(packet.NetworkLayer())
+		err := tcp.SetNetworkLayerForChecksum(netLayer)
 		if err != nil {
 			log.Fatalf("Failed to set network layer for checksum: %s\n", err)
 		}
@@ -496,8 +500,10 @@ func ReassemblePacket(packet gopacket.Packet, assembler *reassembly.Assembler) {
 	// for debugging:
 	// assembleWithContextTimeout(packet, assembler, tcp)
 	aMu.Lock()
-	assembler.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, &context{
-		CaptureInfo: packet.Metadata().CaptureInfo,
+	assembler.AssembleWithContext(netLayer.NetworkFlow(), tcp, &context{
+		CaptureInfo:      packet.Metadata().CaptureInfo,
+		Encapsulation:    encapsulation.String(),
+		EncapsulationKey: encapsulation.Key(),
 	})
 	aMu.Unlock()
 
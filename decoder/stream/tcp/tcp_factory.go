@@ -67,12 +67,17 @@ func (factory *connectionFactory) New(net, transport gopacket.Flow, ac reassembl
 		zap.String("transport", transport.String()),
 	)
 
+	var encapsulation string
+	if c, ok := ac.(*context); ok {
+		encapsulation = c.Encapsulation
+	}
+
 	// parent structure for tracking the bidirectional connection
 	str := &tcpConnection{
 		net:         net,
 		transport:   transport,
 		tcpstate:    reassembly.NewTCPSimpleFSM(factory.FSMOptions),
-		ident:       utils.CreateFlowIdentFromLayerFlows(net, transport),
+		ident:       utils.CreateFlowIdentFromLayerFlows(net, transport, encapsulation),
 		optchecker:  reassembly.NewTCPOptionCheck(),
 		firstPacket: ac.GetCaptureInfo().Timestamp,
 	}
@@ -114,9 +119,19 @@ func (factory *connectionFactory) waitGoRoutines() {
 // context is the assembler context.
 type context struct {
 	CaptureInfo gopacket.CaptureInfo
+
+	// Encapsulation of the packet as seen on the wire,
+	// EncapsulationKey is the direction independent variant used as part of the flow key.
+	Encapsulation    string
+	EncapsulationKey string
 }
 
 // GetCaptureInfo returns the gopacket.CaptureInfo from the context.
 func (c *context) GetCaptureInfo() gopacket.CaptureInfo {
 	return c.CaptureInfo
 }
+
+// GetEncapsulation returns the direction independent encapsulation key.
+func (c *context) GetEncapsulation() string {
+	return c.EncapsulationKey
+}
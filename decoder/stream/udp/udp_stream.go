@@ -41,17 +41,27 @@ type udpStream struct {
 	sync.Mutex
 	data    core.DataFragments
 	decoder core.StreamDecoderInterface
+
+	// encapsulation as seen on the first packet of the stream
+	encapsulation string
+}
+
+// udpStreamKey identifies a stream by the hash of its transport flow
+// and the encapsulation the packets were carried in.
+type udpStreamKey struct {
+	transport     uint64
+	encapsulation string
 }
 
 // udpStreamPool holds a pool of UDP streams.
 type udpStreamPool struct {
 	sync.Mutex
-	streams map[uint64]*udpStream
+	streams map[udpStreamKey]*udpStream
 }
 
 func newUDPStreamPool() *udpStreamPool {
 	return &udpStreamPool{
-		streams: make(map[uint64]*udpStream),
+		streams: make(map[udpStreamKey]*udpStream),
 	}
 }
 
@@ -63,29 +73,34 @@ func (u *udpStreamPool) size() int {
 }
 
 // HandleUDP takes an UDP packet and tracks the data seen for the conversation.
-func (u *udpStreamPool) HandleUDP(packet gopacket.Packet, udpLayer gopacket.Layer) {
+func (u *udpStreamPool) HandleUDP(packet gopacket.Packet, udpLayer gopacket.TransportLayer, netLayer gopacket.NetworkLayer, encapsulation *utils.Encapsulation) {
+	var (
+		k = udpStreamKey{
+			transport:     udpLayer.TransportFlow().FastHash(),
+			encapsulation: encapsulation.Key(),
+		}
+		data = &core.StreamData{
+			RawData:            udpLayer.LayerPayload(),
+			CaptureInformation: packet.Metadata().CaptureInfo,
+			Trans:              udpLayer.TransportFlow(),
+			Net:                netLayer.NetworkFlow(),
+		}
+	)
+
 	u.Lock()
-	if s, ok := u.streams[packet.TransportLayer().TransportFlow().FastHash()]; ok {
+	if s, ok := u.streams[k]; ok {
 		u.Unlock()
 
 		s.Lock()
-		s.data = append(s.data, &core.StreamData{
-			RawData:            udpLayer.LayerPayload(),
-			CaptureInformation: packet.Metadata().CaptureInfo,
-			Trans:              packet.TransportLayer().TransportFlow(),
-			Net:                packet.NetworkLayer().NetworkFlow(),
-		})
+		s.data = append(s.data, data)
 		s.Unlock()
 	} else {
 		// add new
-		str := new(udpStream)
-		str.data = append(str.data, &core.StreamData{
-			RawData:            udpLayer.LayerPayload(),
-			CaptureInformation: packet.Metadata().CaptureInfo,
-			Trans:              packet.TransportLayer().TransportFlow(),
-			Net:                packet.NetworkLayer().NetworkFlow(),
-		})
-		u.streams[packet.TransportLayer().TransportFlow().FastHash()] = str
+		str := &udpStream{
+			encapsulation: encapsulation.String(),
+		}
+		str.data = append(str.data, data)
+		u.streams[k] = str
 		u.Unlock()
 	}
 }
@@ -179,7 +194,7 @@ func (u *udpStream) decode() {
 
 	conv := &core.ConversationInfo{
 		Data:              u.data,
-		Ident:             utils.CreateFlowIdentFromLayerFlows(u.data[0].Network(), u.data[0].Transport()),
+		Ident:             utils.CreateFlowIdentFromLayerFlows(u.data[0].Network(), u.data[0].Transport(), u.encapsulation),
 		FirstClientPacket: u.data[0].CaptureInfo().Timestamp,
 		FirstServerPacket: serverFirstReply,
 		ClientIP:          u.data[0].Network().Src().String(),
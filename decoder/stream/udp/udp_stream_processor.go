@@ -153,7 +153,7 @@ func (usp *udpStreamProcessor) streamWorker(wg *sync.WaitGroup) chan *udpStream
 				clientTransport = s.data[0].Transport()
 				clientNetwork = s.data[0].Network()
 				firstPacket = s.data[0].CaptureInfo().Timestamp
-				ident = utils.CreateFlowIdentFromLayerFlows(clientNetwork, clientTransport)
+				ident = utils.CreateFlowIdentFromLayerFlows(clientNetwork, clientTransport, s.encapsulation)
 			} else {
 				// skip empty conns
 				continue
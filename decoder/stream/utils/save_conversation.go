@@ -38,6 +38,10 @@ const (
 	binaryFileExtension = ".bin"
 	protoTCP            = "TCP"
 	protoUDP            = "UDP"
+
+	// maximum amount of conversation data searched for NTLM authentication,
+	// starting at the first fragment that contains a NTLMSSP message.
+	maxNTLMDataSize = 1024 * 1024 // 1 MB
 )
 
 // SaveConversation will save TCP / UDP conversations to disk
@@ -53,6 +57,14 @@ func SaveConversation(proto string, conversation core.DataFragments, ident strin
 	banner := createBannerFromConversation(conversation)
 	credentials.RunHarvesters(banner, transport, ident, firstPacket)
 
+	// NTLM authentication usually happens after the banner size,
+	// e.g. after SMB protocol negotiation, so the conversation is searched from the first NTLMSSP message.
+	if credentials.HarvestersEnabled() {
+		if data := ntlmData(conversation); data != nil {
+			credentials.HarvestNTLM(data, ident, firstPacket)
+		}
+	}
+
 	if !decoderconfig.Instance.SaveConns {
 		return nil
 	}
@@ -196,6 +208,29 @@ retry:
 	return nil
 }
 
+// ntlmData returns the raw data of the conversation starting at the first fragment with a NTLMSSP message,
+// up to maxNTLMDataSize bytes. If no fragment contains a NTLMSSP message, nil is returned.
+func ntlmData(conversation core.DataFragments) []byte {
+	for i, d := range conversation {
+		if !credentials.ContainsNTLM(d.Raw()) {
+			continue
+		}
+
+		var data []byte
+		for _, d = range conversation[i:] {
+			if len(data) >= maxNTLMDataSize {
+				break
+			}
+
+			data = append(data, d.Raw()...)
+		}
+
+		return data
+	}
+
+	return nil
+}
+
 func createBannerFromConversation(conversation core.DataFragments) []byte {
 	var (
 		banner    = make([]byte, 0, decoderconfig.Instance.HarvesterBannerSize)
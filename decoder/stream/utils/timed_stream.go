@@ -0,0 +1,79 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+// TimedStream contains the payload of a single direction of a conversation,
+// along with the capture timestamps of the fragments it was assembled from.
+// It allows binary protocol decoders to parse the payload as a contiguous byte slice,
+// while still being able to retrieve the time at which a message has been seen on the wire.
+type TimedStream struct {
+	Data []byte
+
+	offsets []int
+	times   []time.Time
+}
+
+// TimeAt returns the capture timestamp of the fragment that contains the byte at the given offset.
+func (s *TimedStream) TimeAt(offset int) time.Time {
+	if len(s.offsets) == 0 {
+		return time.Time{}
+	}
+
+	// find the last fragment that starts before or at offset
+	i := sort.Search(len(s.offsets), func(i int) bool {
+		return s.offsets[i] > offset
+	})
+	if i > 0 {
+		i--
+	}
+
+	return s.times[i]
+}
+
+func (s *TimedStream) add(data []byte, ts time.Time) {
+	s.offsets = append(s.offsets, len(s.Data))
+	s.times = append(s.times, ts)
+	s.Data = append(s.Data, data...)
+}
+
+// SplitConversation separates the data fragments of a conversation by direction.
+func SplitConversation(data core.DataFragments) (client, server *TimedStream) {
+	client = new(TimedStream)
+	server = new(TimedStream)
+
+	for _, d := range data {
+		var ts time.Time
+		if d.Context() != nil {
+			ts = d.Context().GetCaptureInfo().Timestamp
+		} else {
+			ts = d.CaptureInfo().Timestamp
+		}
+
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			client.add(d.Raw(), ts)
+		} else {
+			server.add(d.Raw(), ts)
+		}
+	}
+
+	return client, server
+}
@@ -15,9 +15,12 @@ package utils
 
 import (
 	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/utils"
 )
 
 // PacketInfo contains packet meta information.
+// For tunneled packets the addresses and transport layer are taken from the innermost layers.
 type PacketInfo struct {
 	Packet    gopacket.Packet
 	Timestamp int64
@@ -25,6 +28,10 @@ type PacketInfo struct {
 	DstMAC    string
 	SrcIP     string
 	DstIP     string
+
+	// Encapsulation identifies the tenant address space the IPs belong to, empty if not tagged or tunneled.
+	Encapsulation  string
+	TransportLayer gopacket.TransportLayer
 }
 
 // NewPacketInfo returns a new packet summary
@@ -39,10 +46,14 @@ func NewPacketInfo(p gopacket.Packet) *PacketInfo {
 		i.DstMAC = ll.LinkFlow().Dst().String()
 	}
 
-	if nl := p.NetworkLayer(); nl != nil {
+	encapsulation, nl, tl := utils.DecodeEncapsulation(p)
+	if nl != nil {
 		i.SrcIP = nl.NetworkFlow().Src().String()
 		i.DstIP = nl.NetworkFlow().Dst().String()
 	}
 
+	i.Encapsulation = encapsulation.Tenant()
+	i.TransportLayer = tl
+
 	return i
 }
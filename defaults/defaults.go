@@ -50,6 +50,34 @@ const (
 	// CompressionLevel is the compression level to use by default.
 	CompressionLevel = flate.BestSpeed
 
+	// CompressionCodec is the codec used to compress audit records by default.
+	CompressionCodec = "gzip"
+
+	// IndexBlockSize is the amount of uncompressed data per block in indexed audit record files.
+	IndexBlockSize = 1024 * 1024 * 1 // 1 MB
+
+	// ParquetCompression is the codec used to compress Parquet pages by default.
+	ParquetCompression = "snappy"
+
+	// ParquetRowGroupSize is the amount of uncompressed data per row group in Parquet files.
+	ParquetRowGroupSize = 1024 * 1024 * 8 // 8 MB
+
+	// SQLiteBatchSize is the number of audit records inserted per transaction into SQLite databases.
+	SQLiteBatchSize = 1000
+
+	// IPFIXActiveTimeout is the maximum time a connection is buffered before it is sent to the IPFIX collector.
+	IPFIXActiveTimeout = 5 * time.Second
+
+	// PacketStoreSegmentSize is the size at which the packet store starts a new pcap segment.
+	PacketStoreSegmentSize = 1024 * 1024 * 512 // 512 MB
+
+	// AFPacketBlockSize is the size of the blocks in the AF_PACKET ring buffer of each worker.
+	AFPacketBlockSize = 1024 * 1024 * 1 // 1 MB
+
+	// AFPacketNumBlocks is the number of blocks in the AF_PACKET ring buffer of each worker.
+	// Each worker allocates AFPacketNumBlocks * AFPacketBlockSize bytes, 32 MB with the defaults.
+	AFPacketNumBlocks = 32
+
 	// TCP Stream Reassembly:
 	// default settings are meant to be forgiving in terms of TCP state machine correctness
 	// in order to capture as much information as possible.
@@ -73,6 +101,12 @@ const (
 	// DefragIPv4 controls defragmentation for IPv4.
 	DefragIPv4 = true
 
+	// DefragIPv6 controls defragmentation for IPv6.
+	DefragIPv6 = true
+
+	// DecapsulateGTP controls whether packets tunneled in GTP-U are decoded separately.
+	DecapsulateGTP = true
+
 	// NoOptCheck controls TCP option checking for the reassembly state machine.
 	NoOptCheck = true
 
@@ -85,6 +119,9 @@ const (
 	// FileStorage is the default location for storing extracted files.
 	FileStorage = "files"
 
+	// PacketStore is the default location for the packet store in the output directory.
+	PacketStore = "packets"
+
 	// DirectoryPermission for all created folders.
 	DirectoryPermission = 0o777
 
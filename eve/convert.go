@@ -0,0 +1,535 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package eve
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// converters for the audit record types with an EVE equivalent.
+var converters = map[types.Type]func(msg proto.Message) *Event{
+	types.Type_NC_Connection:     fromConnection,
+	types.Type_NC_DNS:            fromDNS,
+	types.Type_NC_HTTP:           fromHTTP,
+	types.Type_NC_TLSClientHello: fromTLSClientHello,
+	types.Type_NC_File:           fromFile,
+	types.Type_NC_SMTP:           fromSMTP,
+	types.Type_NC_SSH:            fromSSH,
+	types.Type_NC_Alert:          fromAlert,
+}
+
+// Supported returns true if audit records of the type can be converted to EVE events.
+func Supported(t types.Type) bool {
+	_, ok := converters[t]
+
+	return ok
+}
+
+// New converts an audit record to an EVE event.
+// It returns nil if the type is not supported or the audit record can not be represented as an event.
+func New(msg proto.Message) *Event {
+	r, ok := msg.(types.AuditRecord)
+	if !ok {
+		return nil
+	}
+
+	convert, ok := converters[r.NetcapType()]
+	if !ok {
+		return nil
+	}
+
+	return convert(msg)
+}
+
+// flowIDMask limits the flow ids to 51 bits, so they are exact in JavaScript based tools.
+const flowIDMask = 1<<51 - 1
+
+// Suricata names of the transport protocols.
+var protocols = map[string]uint8{
+	"TCP":       uint8(layers.IPProtocolTCP),
+	"UDP":       uint8(layers.IPProtocolUDP),
+	"ICMP":      uint8(layers.IPProtocolICMPv4),
+	"IPv6-ICMP": uint8(layers.IPProtocolICMPv6),
+	"SCTP":      uint8(layers.IPProtocolSCTP),
+}
+
+// protoName returns the Suricata name of a transport protocol, as named by netcap or Zeek.
+func protoName(transport string) string {
+	switch strings.ToUpper(transport) {
+	case "TCP":
+		return "TCP"
+	case "UDP":
+		return "UDP"
+	case "ICMPV4", "ICMP":
+		return "ICMP"
+	case "ICMPV6":
+		return "IPv6-ICMP"
+	case "SCTP":
+		return "SCTP"
+	}
+
+	return transport
+}
+
+// setFlow sets the endpoints of the event and the identifiers of the flow.
+// The flow id is the same for both directions, so events of a flow can be correlated.
+func (e *Event) setFlow(srcIP, srcPort, dstIP, dstPort, transport string) {
+	e.SrcIP = srcIP
+	e.DestIP = dstIP
+	e.SrcPort, _ = strconv.Atoi(srcPort)
+	e.DestPort, _ = strconv.Atoi(dstPort)
+	e.Proto = protoName(transport)
+
+	var (
+		src      = net.ParseIP(srcIP)
+		dst      = net.ParseIP(dstIP)
+		num, ok  = protocols[e.Proto]
+		hasPorts = e.SrcPort != 0 && e.DestPort != 0
+		usePorts = e.Proto == "TCP" || e.Proto == "UDP" || e.Proto == "SCTP"
+	)
+
+	// audit records written without the ports can not be matched with their flow
+	if src == nil || dst == nil || usePorts && !hasPorts {
+		return
+	}
+
+	a, b := srcIP+"/"+srcPort, dstIP+"/"+dstPort
+	if a > b {
+		a, b = b, a
+	}
+
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%s|%s|%s", a, b, e.Proto)
+	e.FlowID = binary.BigEndian.Uint64(h.Sum(nil)) & flowIDMask
+
+	// ICMP audit records carry no message type and code, which the community id requires
+	if ok && usePorts {
+		e.CommunityID = utils.CommunityID(0, src, dst, uint16(e.SrcPort), uint16(e.DestPort), num)
+	}
+}
+
+// appProto returns the Suricata name of an application protocol.
+func appProto(app string) string {
+	switch app {
+	case "", "Payload":
+		return ""
+	case "TLS":
+		return "tls"
+	}
+
+	return strings.ToLower(app)
+}
+
+func fromConnection(msg proto.Message) *Event {
+	c, ok := msg.(*types.Connection)
+	if !ok || c.SrcIP == "" {
+		// Suricata only tracks IP flows
+		return nil
+	}
+
+	e := &Event{
+		Timestamp: timestamp(c.TimestampLast),
+		EventType: TypeFlow,
+		AppProto:  appProto(c.ApplicationProto),
+		Flow: &Flow{
+			BytesToServer: c.BytesClientToServer,
+			BytesToClient: c.BytesServerToClient,
+			Start:         timestamp(c.TimestampFirst),
+			End:           timestamp(c.TimestampLast),
+			Age:           (c.TimestampLast - c.TimestampFirst) / 1e9,
+			State:         "established",
+		},
+	}
+
+	e.setFlow(c.SrcIP, c.SrcPort, c.DstIP, c.DstPort, c.TransportProto)
+
+	if e.Proto != "TCP" {
+		if c.BytesServerToClient == 0 {
+			e.Flow.State = "new"
+		}
+
+		return e
+	}
+
+	e.TCP = &TCP{
+		SYN: c.NumSYNFlags > 0,
+		FIN: c.NumFINFlags > 0,
+		RST: c.NumRSTFlags > 0,
+		PSH: c.NumPSHFlags > 0,
+		ACK: c.NumACKFlags > 0,
+		URG: c.NumURGFlags > 0,
+		ECN: c.NumECEFlags > 0,
+		CWR: c.NumCWRFlags > 0,
+	}
+
+	var flags uint8
+
+	for i, set := range []bool{e.TCP.FIN, e.TCP.SYN, e.TCP.RST, e.TCP.PSH, e.TCP.ACK, e.TCP.URG, e.TCP.ECN, e.TCP.CWR} {
+		if set {
+			flags |= 1 << uint(i)
+		}
+	}
+
+	e.TCP.TCPFlags = fmt.Sprintf("%02x", flags)
+
+	switch {
+	case e.TCP.FIN || e.TCP.RST:
+		e.Flow.State = "closed"
+	case !e.TCP.ACK:
+		e.Flow.State = "new"
+	}
+
+	return e
+}
+
+// names of the DNS response codes.
+var responseCodes = map[int32]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// rrType returns the name of a DNS resource record type.
+func rrType(t int32) string {
+	if name := layers.DNSType(t).String(); name != "Unknown" {
+		return name
+	}
+
+	return strconv.Itoa(int(t))
+}
+
+func fromDNS(msg proto.Message) *Event {
+	d, ok := msg.(*types.DNS)
+	if !ok {
+		return nil
+	}
+
+	e := &Event{
+		Timestamp: timestamp(d.Timestamp),
+		EventType: TypeDNS,
+		AppProto:  TypeDNS,
+		DNS: &DNS{
+			Type:   "query",
+			ID:     int(d.ID),
+			Opcode: int(d.OpCode),
+		},
+	}
+
+	// records written before the transport was tracked are almost always UDP
+	transport := d.Transport
+	if transport == "" {
+		transport = "UDP"
+	}
+
+	e.setFlow(d.SrcIP, strconv.Itoa(int(d.SrcPort)), d.DstIP, strconv.Itoa(int(d.DstPort)), transport)
+
+	if len(d.Questions) > 0 {
+		e.DNS.RRName = d.Questions[0].Name
+		e.DNS.RRType = rrType(d.Questions[0].Type)
+	}
+
+	if !d.QR {
+		e.DNS.RD = d.RD
+
+		return e
+	}
+
+	e.DNS.Version = 2
+	e.DNS.Type = "answer"
+	e.DNS.QR = true
+	e.DNS.AA = d.AA
+	e.DNS.TC = d.TC
+	e.DNS.RD = d.RD
+	e.DNS.RA = d.RA
+	e.DNS.Flags = fmt.Sprintf("%x", dnsFlags(d))
+
+	if name, ok := responseCodes[d.ResponseCode]; ok {
+		e.DNS.RCode = name
+	} else {
+		e.DNS.RCode = strconv.Itoa(int(d.ResponseCode))
+	}
+
+	for _, a := range d.Answers {
+		e.DNS.Answers = append(e.DNS.Answers, &DNSAnswer{
+			RRName: a.Name,
+			RRType: rrType(a.Type),
+			TTL:    a.TTL,
+			RData:  rdata(a),
+		})
+	}
+
+	return e
+}
+
+// dnsFlags returns the flags field of the DNS header.
+func dnsFlags(d *types.DNS) uint16 {
+	flags := uint16(d.OpCode&0xf)<<11 | uint16(d.Z&0x7)<<4 | uint16(d.ResponseCode&0xf)
+
+	for bit, set := range map[uint]bool{15: d.QR, 10: d.AA, 9: d.TC, 8: d.RD, 7: d.RA} {
+		if set {
+			flags |= 1 << bit
+		}
+	}
+
+	return flags
+}
+
+// rdata returns the data of a resource record in textual form.
+func rdata(rr *types.DNSResourceRecord) string {
+	switch {
+	case rr.IP != "":
+		return rr.IP
+	case len(rr.CNAME) > 0:
+		return string(rr.CNAME)
+	case len(rr.PTR) > 0:
+		return string(rr.PTR)
+	case len(rr.NS) > 0:
+		return string(rr.NS)
+	case rr.MX != nil:
+		return rr.MX.Name
+	case len(rr.TXTs) > 0:
+		txts := make([]string, len(rr.TXTs))
+		for i, t := range rr.TXTs {
+			txts[i] = string(t)
+		}
+
+		return strings.Join(txts, " ")
+	}
+
+	return ""
+}
+
+func fromHTTP(msg proto.Message) *Event {
+	h, ok := msg.(*types.HTTP)
+	if !ok {
+		return nil
+	}
+
+	e := &Event{
+		Timestamp: timestamp(h.Timestamp),
+		EventType: TypeHTTP,
+		AppProto:  TypeHTTP,
+		HTTP: &HTTP{
+			Hostname:    h.Host,
+			URL:         h.URL,
+			UserAgent:   h.UserAgent,
+			ContentType: h.ResContentType,
+			Referer:     h.Referer,
+			Method:      h.Method,
+			Protocol:    h.Proto,
+			Status:      int(h.StatusCode),
+			Length:      int(h.ResContentLength),
+		},
+	}
+
+	e.setFlow(h.SrcIP, strconv.Itoa(int(h.SrcPort)), h.DstIP, strconv.Itoa(int(h.DstPort)), "TCP")
+
+	return e
+}
+
+// TLS version names.
+var tlsVersions = map[int32]string{
+	0x0002: "SSLv2",
+	0x0300: "SSLv3",
+	0x0301: "TLS 1.0",
+	0x0302: "TLS 1.1",
+	0x0303: "TLS 1.2",
+	0x0304: "TLS 1.3",
+}
+
+func fromTLSClientHello(msg proto.Message) *Event {
+	h, ok := msg.(*types.TLSClientHello)
+	if !ok {
+		return nil
+	}
+
+	version := h.HandshakeVersion
+	if version == 0 {
+		version = h.Version
+	}
+
+	e := &Event{
+		Timestamp: timestamp(h.Timestamp),
+		EventType: TypeTLS,
+		AppProto:  TypeTLS,
+		TLS: &TLS{
+			SNI:     h.SNI,
+			Version: tlsVersions[version],
+		},
+	}
+
+	if e.TLS.Version == "" {
+		e.TLS.Version = "UNDETERMINED"
+	}
+
+	if h.Ja3 != "" {
+		e.TLS.JA3 = &Hash{Hash: h.Ja3}
+	}
+
+	e.setFlow(h.SrcIP, strconv.Itoa(int(h.SrcPort)), h.DstIP, strconv.Itoa(int(h.DstPort)), "TCP")
+
+	return e
+}
+
+func fromFile(msg proto.Message) *Event {
+	f, ok := msg.(*types.File)
+	if !ok {
+		return nil
+	}
+
+	e := &Event{
+		Timestamp: timestamp(f.Timestamp),
+		EventType: TypeFileInfo,
+		AppProto:  appProto(f.Source),
+		FileInfo: &FileInfo{
+			Filename: f.Name,
+			State:    "CLOSED",
+			MD5:      f.Hash,
+			Stored:   f.Location != "",
+			Size:     f.Length,
+			MimeType: f.ContentTypeDetected,
+		},
+	}
+
+	e.setFlow(f.SrcIP, strconv.Itoa(int(f.SrcPort)), f.DstIP, strconv.Itoa(int(f.DstPort)), "TCP")
+
+	return e
+}
+
+func fromSMTP(msg proto.Message) *Event {
+	s, ok := msg.(*types.SMTP)
+	if !ok {
+		return nil
+	}
+
+	e := &Event{
+		Timestamp: timestamp(s.Timestamp),
+		EventType: TypeSMTP,
+		AppProto:  TypeSMTP,
+		SMTP:      &SMTP{},
+	}
+
+	e.setFlow(s.SrcIP, strconv.Itoa(int(s.SrcPort)), s.DstIP, strconv.Itoa(int(s.DstPort)), "TCP")
+
+	return e
+}
+
+func fromSSH(msg proto.Message) *Event {
+	s, ok := msg.(*types.SSH)
+	if !ok {
+		return nil
+	}
+
+	// the endpoints always refer to the client as source
+	flow := s.Flow
+	if !s.IsClient {
+		flow = utils.ReverseFlowIdent(flow)
+	}
+
+	var (
+		srcIP, srcPort, dstIP, dstPort = utils.ParseFlowIdent(flow)
+		host                           = &SSHHost{}
+		e                              = &Event{
+			Timestamp: timestamp(s.Timestamp),
+			EventType: TypeSSH,
+			AppProto:  TypeSSH,
+			SSH:       &SSH{},
+		}
+	)
+
+	e.setFlow(srcIP, srcPort, dstIP, dstPort, "TCP")
+
+	// identification string: SSH-protoversion-softwareversion SP comments
+	if parts := strings.SplitN(s.Ident, "-", 3); len(parts) == 3 && parts[0] == "SSH" {
+		host.ProtoVersion = parts[1]
+		host.SoftwareVersion = strings.Fields(parts[2] + " ")[0]
+	}
+
+	if s.HASSH != "" {
+		host.HASSH = &Hash{Hash: s.HASSH, String: s.Algorithms}
+	}
+
+	if s.IsClient {
+		e.SSH.Client = host
+	} else {
+		e.SSH.Server = host
+	}
+
+	return e
+}
+
+// signatureIDBase is the start of the signature id range used for netcap alerts,
+// within the range reserved for local rules.
+const signatureIDBase = 1000000
+
+func fromAlert(msg proto.Message) *Event {
+	a, ok := msg.(*types.Alert)
+	if !ok {
+		return nil
+	}
+
+	// derive a stable signature id from the alert name
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(a.Name))
+
+	e := &Event{
+		Timestamp: timestamp(a.Timestamp),
+		EventType: TypeAlert,
+		Alert: &Alert{
+			Action:      "allowed",
+			GID:         1,
+			SignatureID: signatureIDBase + h.Sum32()%signatureIDBase,
+			Rev:         1,
+			Signature:   a.Name,
+			Category:    a.Description,
+			Severity:    3,
+			Metadata:    map[string][]string{},
+		},
+	}
+
+	e.setFlow(a.SrcIP, a.SrcPort, a.DstIP, a.DstPort, a.Protocol)
+
+	if a.MITRE != "" {
+		e.Alert.Metadata["mitre_technique_id"] = []string{a.MITRE}
+	}
+
+	if a.IPReputation != "" {
+		e.Alert.Metadata["ip_reputation"] = []string{a.IPReputation}
+	}
+
+	if a.Domain != "" {
+		e.Alert.Metadata["domain"] = []string{a.Domain}
+	}
+
+	return e
+}
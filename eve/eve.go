@@ -0,0 +1,189 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package eve implements the conversion of netcap audit records into events
+// of the Suricata EVE JSON format, so tools that ingest EVE can consume netcap output.
+package eve
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TimeFormat is the layout of the EVE timestamps.
+const TimeFormat = "2006-01-02T15:04:05.000000-0700"
+
+// Event types.
+const (
+	TypeFlow     = "flow"
+	TypeDNS      = "dns"
+	TypeHTTP     = "http"
+	TypeTLS      = "tls"
+	TypeFileInfo = "fileinfo"
+	TypeSMTP     = "smtp"
+	TypeSSH      = "ssh"
+	TypeAlert    = "alert"
+)
+
+// Event is a single EVE record, the protocol specific object matching the event type is set.
+type Event struct {
+	Timestamp   string `json:"timestamp"`
+	FlowID      uint64 `json:"flow_id,omitempty"`
+	EventType   string `json:"event_type"`
+	SrcIP       string `json:"src_ip,omitempty"`
+	SrcPort     int    `json:"src_port,omitempty"`
+	DestIP      string `json:"dest_ip,omitempty"`
+	DestPort    int    `json:"dest_port,omitempty"`
+	Proto       string `json:"proto,omitempty"`
+	CommunityID string `json:"community_id,omitempty"`
+	AppProto    string `json:"app_proto,omitempty"`
+
+	Flow     *Flow     `json:"flow,omitempty"`
+	TCP      *TCP      `json:"tcp,omitempty"`
+	DNS      *DNS      `json:"dns,omitempty"`
+	HTTP     *HTTP     `json:"http,omitempty"`
+	TLS      *TLS      `json:"tls,omitempty"`
+	FileInfo *FileInfo `json:"fileinfo,omitempty"`
+	SMTP     *SMTP     `json:"smtp,omitempty"`
+	SSH      *SSH      `json:"ssh,omitempty"`
+	Alert    *Alert    `json:"alert,omitempty"`
+}
+
+// Flow holds the counters of a flow event.
+// The packet counters are not split by direction in netcap and therefore omitted.
+type Flow struct {
+	BytesToServer int64  `json:"bytes_toserver"`
+	BytesToClient int64  `json:"bytes_toclient"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Age           int64  `json:"age"`
+	State         string `json:"state"`
+	Alerted       bool   `json:"alerted"`
+}
+
+// TCP holds the flags seen on a TCP flow.
+type TCP struct {
+	TCPFlags string `json:"tcp_flags"`
+	SYN      bool   `json:"syn,omitempty"`
+	FIN      bool   `json:"fin,omitempty"`
+	RST      bool   `json:"rst,omitempty"`
+	PSH      bool   `json:"psh,omitempty"`
+	ACK      bool   `json:"ack,omitempty"`
+	URG      bool   `json:"urg,omitempty"`
+	ECN      bool   `json:"ecn,omitempty"`
+	CWR      bool   `json:"cwr,omitempty"`
+}
+
+// DNS is a query or answer, in the version 2 format of the dns event.
+type DNS struct {
+	Version int          `json:"version,omitempty"`
+	Type    string       `json:"type"`
+	ID      int          `json:"id"`
+	Flags   string       `json:"flags,omitempty"`
+	QR      bool         `json:"qr,omitempty"`
+	AA      bool         `json:"aa,omitempty"`
+	TC      bool         `json:"tc,omitempty"`
+	RD      bool         `json:"rd,omitempty"`
+	RA      bool         `json:"ra,omitempty"`
+	Opcode  int          `json:"opcode"`
+	RRName  string       `json:"rrname,omitempty"`
+	RRType  string       `json:"rrtype,omitempty"`
+	TxID    int          `json:"tx_id"`
+	RCode   string       `json:"rcode,omitempty"`
+	Answers []*DNSAnswer `json:"answers,omitempty"`
+}
+
+// DNSAnswer is a resource record of a DNS answer.
+type DNSAnswer struct {
+	RRName string `json:"rrname"`
+	RRType string `json:"rrtype"`
+	TTL    uint32 `json:"ttl"`
+	RData  string `json:"rdata,omitempty"`
+}
+
+// HTTP holds the request and response of a http event.
+type HTTP struct {
+	Hostname    string `json:"hostname,omitempty"`
+	URL         string `json:"url,omitempty"`
+	UserAgent   string `json:"http_user_agent,omitempty"`
+	ContentType string `json:"http_content_type,omitempty"`
+	Referer     string `json:"http_refer,omitempty"`
+	Method      string `json:"http_method,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Length      int    `json:"length"`
+}
+
+// TLS holds the client hello information of a tls event.
+type TLS struct {
+	SNI     string `json:"sni,omitempty"`
+	Version string `json:"version,omitempty"`
+	JA3     *Hash  `json:"ja3,omitempty"`
+}
+
+// Hash is a fingerprint, with the fingerprinted string if known.
+type Hash struct {
+	Hash   string `json:"hash"`
+	String string `json:"string,omitempty"`
+}
+
+// FileInfo describes a file extracted from a flow.
+type FileInfo struct {
+	Filename string `json:"filename"`
+	Gaps     bool   `json:"gaps"`
+	State    string `json:"state"`
+	MD5      string `json:"md5,omitempty"`
+	Stored   bool   `json:"stored"`
+	Size     int64  `json:"size"`
+	TxID     int    `json:"tx_id"`
+	MimeType string `json:"mimetype,omitempty"`
+}
+
+// SMTP is the smtp event object.
+// It is empty because netcap does not keep the parameters of the SMTP commands.
+type SMTP struct{}
+
+// SSH holds the client or the server side of a ssh event, netcap writes one record per side.
+type SSH struct {
+	Client *SSHHost `json:"client,omitempty"`
+	Server *SSHHost `json:"server,omitempty"`
+}
+
+// SSHHost describes the software of one side of a SSH connection.
+type SSHHost struct {
+	ProtoVersion    string `json:"proto_version,omitempty"`
+	SoftwareVersion string `json:"software_version,omitempty"`
+	HASSH           *Hash  `json:"hassh,omitempty"`
+}
+
+// Alert holds the signature information of an alert event.
+type Alert struct {
+	Action      string              `json:"action"`
+	GID         int                 `json:"gid"`
+	SignatureID uint32              `json:"signature_id"`
+	Rev         int                 `json:"rev"`
+	Signature   string              `json:"signature"`
+	Category    string              `json:"category"`
+	Severity    int                 `json:"severity"`
+	Metadata    map[string][]string `json:"metadata,omitempty"`
+}
+
+// Marshal returns the event as a single JSON line, without the trailing newline.
+func (e *Event) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// timestamp formats a unix timestamp in nanoseconds in the EVE layout.
+func timestamp(ns int64) string {
+	return time.Unix(0, ns).UTC().Format(TimeFormat)
+}
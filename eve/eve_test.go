@@ -0,0 +1,167 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package eve
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestConnection(t *testing.T) {
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 123456000, time.UTC)
+	c := &types.Connection{
+		TimestampFirst:      ts.UnixNano(),
+		TimestampLast:       ts.Add(3 * time.Second).UnixNano(),
+		TransportProto:      "TCP",
+		ApplicationProto:    "TLS",
+		SrcIP:               "128.232.110.120",
+		SrcPort:             "34855",
+		DstIP:               "66.35.250.204",
+		DstPort:             "80",
+		BytesClientToServer: 100,
+		BytesServerToClient: 200,
+		NumSYNFlags:         2,
+		NumACKFlags:         5,
+		NumFINFlags:         2,
+	}
+
+	e := New(c)
+	if e == nil {
+		t.Fatal("expected a flow event")
+	}
+
+	if e.EventType != TypeFlow || e.AppProto != "tls" || e.Proto != "TCP" || e.DestPort != 80 {
+		t.Fatal("unexpected event", e)
+	}
+
+	if e.CommunityID != "1:LQU9qZlK+B5F3KDmev6m5PMibrg=" {
+		t.Fatal("unexpected community id", e.CommunityID)
+	}
+
+	if e.Flow.Start != "2020-01-01T00:00:00.123456+0000" || e.Flow.Age != 3 || e.Flow.State != "closed" {
+		t.Fatal("unexpected flow", e.Flow)
+	}
+
+	if e.TCP.TCPFlags != "13" || !e.TCP.SYN || e.TCP.RST {
+		t.Fatal("unexpected tcp flags", e.TCP)
+	}
+
+	// the flow id must not depend on the direction
+	reply := New(&types.Alert{SrcIP: c.DstIP, SrcPort: c.DstPort, DstIP: c.SrcIP, DstPort: c.SrcPort, Protocol: "tcp", Name: "test"})
+	if reply.FlowID != e.FlowID || reply.FlowID == 0 || reply.FlowID > flowIDMask {
+		t.Fatal("unexpected flow id", reply.FlowID, e.FlowID)
+	}
+
+	if New(&types.Connection{SrcMAC: "00:00:00:00:00:01", DstMAC: "00:00:00:00:00:02"}) != nil {
+		t.Fatal("expected no event for a non IP connection")
+	}
+}
+
+func TestDNS(t *testing.T) {
+	d := &types.DNS{
+		ID:        4711,
+		QR:        true,
+		RD:        true,
+		RA:        true,
+		SrcIP:     "8.8.8.8",
+		SrcPort:   53,
+		DstIP:     "192.168.1.10",
+		DstPort:   51234,
+		Questions: []*types.DNSQuestion{{Name: "example.com", Type: 1, Class: 1}},
+		Answers:   []*types.DNSResourceRecord{{Name: "example.com", Type: 1, TTL: 300, IP: "93.184.216.34"}},
+	}
+
+	data, err := New(d).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e struct {
+		EventType string `json:"event_type"`
+		DNS       struct {
+			Type    string
+			Flags   string
+			RRName  string
+			RRType  string
+			RCode   string
+			Answers []map[string]interface{}
+		}
+	}
+
+	if err = json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.EventType != TypeDNS || e.DNS.Type != "answer" || e.DNS.Flags != "8180" || e.DNS.RRType != "A" || e.DNS.RCode != "NOERROR" {
+		t.Fatal("unexpected event", string(data))
+	}
+
+	if len(e.DNS.Answers) != 1 || e.DNS.Answers[0]["rdata"] != "93.184.216.34" {
+		t.Fatal("unexpected answers", string(data))
+	}
+
+	if ev := New(d); ev.Proto != "UDP" || ev.CommunityID == "" {
+		t.Fatal("expected UDP for records without transport", ev.Proto)
+	}
+
+	d.Transport = "TCP"
+	if ev := New(d); ev.Proto != "TCP" {
+		t.Fatal("expected transport of the record", ev.Proto)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var (
+		c = New(&types.Connection{SrcIP: "192.168.1.10", SrcPort: "51234", DstIP: "93.184.216.34", DstPort: "80", TransportProto: "TCP"})
+		h = &types.HTTP{
+			Method:  "GET",
+			Host:    "example.com",
+			URL:     "/",
+			SrcIP:   "192.168.1.10",
+			SrcPort: 51234,
+			DstIP:   "93.184.216.34",
+			DstPort: 80,
+		}
+		e = New(h)
+	)
+
+	if e.FlowID == 0 || e.FlowID != c.FlowID || e.CommunityID != c.CommunityID || e.DestPort != 80 {
+		t.Fatal("expected the identifiers of the connection", e.FlowID, e.CommunityID)
+	}
+
+	// records written without the ports can not be matched with their flow
+	h.SrcPort, h.DstPort = 0, 0
+	if e = New(h); e.FlowID != 0 || e.CommunityID != "" || e.SrcIP != h.SrcIP {
+		t.Fatal("unexpected flow identifiers", e.FlowID, e.CommunityID)
+	}
+}
+
+func TestSSH(t *testing.T) {
+	e := New(&types.SSH{
+		Flow:  "10.0.0.2:22->10.0.0.1:50000",
+		Ident: "SSH-2.0-OpenSSH_8.2p1 Ubuntu-4ubuntu0.1",
+		HASSH: "b12d2871a1189eff20364cf5333619ee",
+	})
+
+	if e.SrcIP != "10.0.0.1" || e.DestPort != 22 || e.SSH.Client != nil {
+		t.Fatal("expected the client as source", e)
+	}
+
+	if s := e.SSH.Server; s.ProtoVersion != "2.0" || s.SoftwareVersion != "OpenSSH_8.2p1" || s.HASSH.Hash == "" {
+		t.Fatal("unexpected server", s)
+	}
+}
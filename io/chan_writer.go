@@ -16,13 +16,10 @@ package io
 import (
 	"bufio"
 	"go.uber.org/zap"
-	"log"
 	"os"
-	"runtime"
 	"sync"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/klauspost/pgzip"
 
 	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/delimited"
@@ -34,7 +31,7 @@ type chanWriter struct {
 	mu sync.Mutex
 
 	bWriter *bufio.Writer
-	gWriter *pgzip.Writer
+	zWriter compressor
 	dWriter *delimited.Writer
 	cWriter *chanProtoWriter
 
@@ -61,15 +58,9 @@ func newChanWriter(wc *WriterConfig) *chanWriter {
 	// buffer data?
 	if wc.Buffer {
 		if wc.Compress {
-			// experiment: pgzip -> file
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.file, wc.CompressionLevel)
-
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-			// experiment: buffer -> pgzip
-			w.bWriter = bufio.NewWriterSize(w.gWriter, wc.MemBufferSize)
+			w.zWriter = mustCompressor(w.file, wc)
+			// experiment: buffer -> compressor
+			w.bWriter = bufio.NewWriterSize(w.zWriter, wc.MemBufferSize)
 			// experiment: delimited -> buffer
 			w.dWriter = delimited.NewWriter(w.bWriter)
 		} else {
@@ -78,27 +69,14 @@ func newChanWriter(wc *WriterConfig) *chanWriter {
 		}
 	} else {
 		if wc.Compress {
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.file, wc.CompressionLevel)
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-			w.dWriter = delimited.NewWriter(w.gWriter)
+			w.zWriter = mustCompressor(w.file, wc)
+			w.dWriter = delimited.NewWriter(w.zWriter)
 		} else {
 			// write into channel writer without compression
 			w.dWriter = delimited.NewWriter(w.cWriter)
 		}
 	}
 
-	if w.gWriter != nil {
-		// To get any performance gains, you should at least be compressing more than 1 megabyte of data at the time.
-		// You should at least have a block size of 100k and at least a number of blocks that match the number of cores
-		// you would like to utilize, but about twice the number of blocks would be the best.
-		if err := w.gWriter.SetConcurrency(wc.CompressionBlockSize, runtime.GOMAXPROCS(0)*2); err != nil {
-			log.Fatal("failed to configure compression package: ", err)
-		}
-	}
-
 	return w
 }
 
@@ -139,7 +117,7 @@ func (w *chanWriter) Close(numRecords int64) (name string, size int64) {
 	}
 
 	if w.wc.Compress {
-		closeGzipWriters(w.gWriter)
+		closeCompressors(w.zWriter)
 	}
 
 	return closeFile(w.wc.Out, w.file, w.wc.Name, numRecords)
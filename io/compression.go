@@ -0,0 +1,312 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+
+	"github.com/dreadl0ck/netcap/defaults"
+)
+
+// Compression codecs for audit record files.
+const (
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+	CodecLZ4  = "lz4"
+
+	// CodecNone is used to convert files into the uncompressed format.
+	CodecNone = "none"
+)
+
+// Codecs lists the supported compression codecs.
+var Codecs = []string{CodecGzip, CodecZstd, CodecLZ4}
+
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicLZ4  = []byte{0x04, 0x22, 0x4d, 0x18}
+
+	lz4Levels = []lz4.CompressionLevel{
+		lz4.Fast, lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4,
+		lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+	}
+
+	errLZ4Dict = errors.New("dictionaries are only supported by the zstd codec")
+)
+
+// compressor is implemented by the compressing writers of all codecs.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// IsCodec returns true if the name is one of the supported compression codecs.
+func IsCodec(name string) bool {
+	for _, c := range Codecs {
+		if c == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CompressionExtension returns the file extension for the codec.
+func CompressionExtension(codec string) string {
+	switch codec {
+	case CodecZstd:
+		return ".zst"
+	case CodecLZ4:
+		return ".lz4"
+	case CodecNone:
+		return ""
+	default:
+		return ".gz"
+	}
+}
+
+// HasCompressionExtension returns true if the file name ends with the extension of a supported codec.
+func HasCompressionExtension(name string) bool {
+	for _, c := range Codecs {
+		if strings.HasSuffix(name, CompressionExtension(c)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectCodec returns the codec for the magic bytes at the start of a file,
+// or an empty string if the data is not compressed.
+func DetectCodec(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		return CodecGzip
+	case bytes.HasPrefix(magic, magicZstd):
+		return CodecZstd
+	case bytes.HasPrefix(magic, magicLZ4):
+		return CodecLZ4
+	default:
+		return ""
+	}
+}
+
+// zstdLevel maps the flate compression levels used in the configuration to the zstd encoder levels.
+func zstdLevel(level int) zstd.EncoderLevel {
+	switch {
+	case level < 0:
+		return zstd.SpeedDefault
+	case level <= 2:
+		return zstd.SpeedFastest
+	case level <= 5:
+		return zstd.SpeedDefault
+	case level <= 8:
+		return zstd.SpeedBetterCompression
+	default:
+		return zstd.SpeedBestCompression
+	}
+}
+
+// lz4Level maps the flate compression levels used in the configuration to the lz4 levels.
+func lz4Level(level int) lz4.CompressionLevel {
+	switch {
+	case level < 0:
+		return lz4.Fast
+	case level >= len(lz4Levels):
+		return lz4.Level9
+	default:
+		return lz4Levels[level]
+	}
+}
+
+// newCompressor returns a compressing writer for the codec of the writer config.
+func newCompressor(w io.Writer, wc *WriterConfig) (compressor, error) {
+	switch wc.CompressionCodec {
+	case CodecZstd:
+		opts := []zstd.EOption{zstd.WithEncoderLevel(zstdLevel(wc.CompressionLevel))}
+		if len(wc.CompressionDict) > 0 {
+			opts = append(opts, zstd.WithEncoderDict(wc.CompressionDict))
+		}
+
+		return zstd.NewWriter(w, opts...)
+	case CodecLZ4:
+		if len(wc.CompressionDict) > 0 {
+			return nil, errLZ4Dict
+		}
+
+		lw := lz4.NewWriter(w)
+		if err := lw.Apply(lz4.CompressionLevelOption(lz4Level(wc.CompressionLevel))); err != nil {
+			return nil, err
+		}
+
+		return lw, nil
+	case CodecGzip, "":
+		g, err := pgzip.NewWriterLevel(w, wc.CompressionLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		// To get any performance gains, you should at least be compressing more than 1 megabyte of data at the time.
+		// You should at least have a block size of 100k and at least a number of blocks that match the number of cores
+		// you would like to utilize, but about twice the number of blocks would be the best.
+		if err = g.SetConcurrency(wc.CompressionBlockSize, runtime.GOMAXPROCS(0)*2); err != nil {
+			return nil, err
+		}
+
+		return g, nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec: %q", wc.CompressionCodec)
+	}
+}
+
+// mustCompressor returns a compressing writer for the writer config and exits if it cannot be configured.
+func mustCompressor(w io.Writer, wc *WriterConfig) compressor {
+	c, err := newCompressor(w, wc)
+	if err != nil {
+		log.Fatal("failed to configure compression package: ", err)
+	}
+
+	return c
+}
+
+// NewDecompressor returns a reader for the decompressed data of r, the codec is detected from the magic bytes.
+// Uncompressed data is returned as is.
+// The dictionary is used for zstd compressed data.
+func NewDecompressor(r *bufio.Reader, dict []byte) (io.Reader, error) {
+	magic, _ := r.Peek(len(magicZstd))
+
+	switch DetectCodec(magic) {
+	case CodecGzip:
+		return gzip.NewReader(r)
+	case CodecZstd:
+		var opts []zstd.DOption
+		if len(dict) > 0 {
+			opts = append(opts, zstd.WithDecoderDicts(dict))
+		}
+
+		return zstd.NewReader(r, opts...)
+	case CodecLZ4:
+		return lz4.NewReader(r), nil
+	default:
+		return r, nil
+	}
+}
+
+// CloseDecompressor releases the resources of a reader returned by NewDecompressor.
+func CloseDecompressor(r io.Reader) error {
+	switch d := r.(type) {
+	case *gzip.Reader:
+		return d.Close()
+	case *zstd.Decoder:
+		d.Close()
+	}
+
+	return nil
+}
+
+// ConvertConfig configures the conversion of a file between compression codecs.
+type ConvertConfig struct {
+	// Codec is the target codec, CodecNone writes the uncompressed data
+	Codec string
+
+	// Level is the flate style compression level, which is mapped to the levels of the other codecs
+	Level int
+
+	// Dict is the dictionary used to compress the output
+	Dict []byte
+
+	// InputDict is the dictionary for reading the input, if it was compressed with one
+	InputDict []byte
+}
+
+// Convert decompresses the file at in and writes it to out, compressed with the configured codec.
+// The codec of the input is detected from the magic bytes.
+// Since the data is copied unchanged, this works for all file formats, including CSV and JSON.
+func Convert(in, out string, c ConvertConfig) error {
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := NewDecompressor(bufio.NewReaderSize(f, defaults.BufferSize), c.InputDict)
+	if err != nil {
+		return err
+	}
+	defer CloseDecompressor(r)
+
+	o, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	var (
+		bw = bufio.NewWriterSize(o, defaults.BufferSize)
+		w  io.Writer
+		cw compressor
+	)
+
+	if c.Codec == CodecNone {
+		w = bw
+	} else {
+		cw, err = newCompressor(bw, &WriterConfig{
+			CompressionCodec:     c.Codec,
+			CompressionLevel:     c.Level,
+			CompressionDict:      c.Dict,
+			CompressionBlockSize: defaults.CompressionBlockSize,
+		})
+		if err != nil {
+			o.Close()
+
+			return err
+		}
+
+		w = cw
+	}
+
+	if _, err = io.Copy(w, r); err != nil {
+		o.Close()
+
+		return err
+	}
+
+	if cw != nil {
+		if err = cw.Close(); err != nil {
+			o.Close()
+
+			return err
+		}
+	}
+
+	if err = bw.Flush(); err != nil {
+		o.Close()
+
+		return err
+	}
+
+	return o.Close()
+}
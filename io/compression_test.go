@@ -0,0 +1,184 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/defaults"
+)
+
+func compressedConfig(dir, codec string, buffer bool) *WriterConfig {
+	return &WriterConfig{
+		Proto:                true,
+		Compress:             true,
+		Buffer:               buffer,
+		Name:                 "TCP",
+		Out:                  dir,
+		Source:               "unit tests",
+		Version:              netcap.Version,
+		StartTime:            time.Now(),
+		CompressionLevel:     defaults.CompressionLevel,
+		CompressionBlockSize: defaults.CompressionBlockSize,
+		CompressionCodec:     codec,
+	}
+}
+
+// countRecords opens the audit record file and returns the number of records.
+func countRecords(t *testing.T, path string, dict []byte) int {
+	t.Helper()
+
+	r, err := OpenWithDictionary(path, defaults.BufferSize, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err = r.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	return len(readAll(t, r))
+}
+
+func TestCodecs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-codecs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const numRecords = 1000
+
+	for _, codec := range Codecs {
+		for _, buffer := range []bool{false, true} {
+			wc := compressedConfig(dir, codec, buffer)
+			writeTCP(t, newProtoWriter(wc), time.Now(), numRecords)
+
+			path := filepath.Join(dir, "TCP"+defaults.FileExtension+CompressionExtension(codec))
+
+			magic := make([]byte, 4)
+			if f, errOpen := os.Open(path); errOpen != nil {
+				t.Fatal(errOpen)
+			} else {
+				_, _ = f.Read(magic)
+				f.Close()
+			}
+
+			if DetectCodec(magic) != codec {
+				t.Fatal("expected", codec, "got", DetectCodec(magic))
+			}
+
+			if n := countRecords(t, path, nil); n != numRecords {
+				t.Fatal(codec, "expected", numRecords, "records, got", n)
+			}
+		}
+	}
+}
+
+func TestCodecDictionary(t *testing.T) {
+	wc := &WriterConfig{
+		CompressionCodec: CodecLZ4,
+		CompressionDict:  bytes.Repeat([]byte("netcap audit records"), 100),
+	}
+
+	if _, err := newCompressor(ioutil.Discard, wc); err != errLZ4Dict {
+		t.Fatal("expected an error for lz4 with a dictionary, got", err)
+	}
+}
+
+// TestCompressedCSV checks that buffered and compressed CSV files are complete.
+func TestCompressedCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-codecs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, codec := range Codecs {
+		wc := compressedConfig(dir, codec, true)
+		wc.Proto = false
+		wc.CSV = true
+
+		writeTCP(t, newCSVWriter(wc), time.Now(), 100)
+
+		f, errOpen := os.Open(filepath.Join(dir, "TCP.csv"+CompressionExtension(codec)))
+		if errOpen != nil {
+			t.Fatal(errOpen)
+		}
+
+		r, errDecompress := NewDecompressor(bufio.NewReader(f), nil)
+		if errDecompress != nil {
+			t.Fatal(errDecompress)
+		}
+
+		data, errRead := ioutil.ReadAll(r)
+		if errRead != nil {
+			t.Fatal(codec, errRead)
+		}
+
+		_ = CloseDecompressor(r)
+		f.Close()
+
+		// header and records
+		if n := bytes.Count(data, []byte("\n")); n < 101 {
+			t.Fatal(codec, "expected at least 101 lines, got", n)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-codecs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTCP(t, newProtoWriter(compressedConfig(dir, CodecGzip, true)), time.Now(), 100)
+
+	var (
+		in   = filepath.Join(dir, "TCP.ncap.gz")
+		dict = bytes.Repeat([]byte("netcap"), 10)
+	)
+
+	for _, codec := range []string{CodecZstd, CodecLZ4, CodecNone} {
+		out := filepath.Join(dir, "converted"+defaults.FileExtension+CompressionExtension(codec))
+
+		if err = Convert(in, out, ConvertConfig{Codec: codec, Level: 9}); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := countRecords(t, out, nil); n != 100 {
+			t.Fatal(codec, "expected 100 records, got", n)
+		}
+
+		in = out
+	}
+
+	out := filepath.Join(dir, "dict.ncap.lz4")
+	if err = Convert(in, out, ConvertConfig{Codec: CodecLZ4, Level: 1, Dict: dict}); err == nil {
+		t.Fatal("expected an error for lz4 with a dictionary")
+	}
+
+	if err = Convert(in, out, ConvertConfig{Codec: "brotli"}); err == nil {
+		t.Fatal("expected an error for an unknown codec")
+	}
+}
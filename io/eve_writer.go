@@ -0,0 +1,171 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/eve"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// eveFileName is the name of the EVE file inside the output directory, without extension.
+const eveFileName = "eve"
+
+// eveFile is the EVE file shared by the writers of all audit record types,
+// as consumers expect the events of all types in a single file.
+type eveFile struct {
+	mu         sync.Mutex
+	refs       int
+	numRecords int64
+
+	bWriter *bufio.Writer
+	zWriter compressor
+	out     io.Writer
+	file    *os.File
+}
+
+var (
+	eveFilesMu sync.Mutex
+
+	// open EVE files, by output directory
+	eveFiles = make(map[string]*eveFile)
+)
+
+// openEVEFile returns the EVE file for the output directory of the config, creating it on first use.
+func openEVEFile(wc *WriterConfig) *eveFile {
+	eveFilesMu.Lock()
+	defer eveFilesMu.Unlock()
+
+	if f, ok := eveFiles[wc.Out]; ok {
+		f.refs++
+
+		return f
+	}
+
+	f := &eveFile{refs: 1}
+
+	// create file
+	if wc.Compress {
+		f.file = createFile(filepath.Join(wc.Out, eveFileName), ".json"+CompressionExtension(wc.CompressionCodec))
+	} else {
+		f.file = createFile(filepath.Join(wc.Out, eveFileName), ".json")
+	}
+	ioLog.Info("create eve file", zap.String("base", filepath.Join(wc.Out, eveFileName)))
+
+	f.out = f.file
+
+	if wc.Buffer {
+		f.bWriter = bufio.NewWriterSize(f.file, wc.MemBufferSize)
+		f.out = f.bWriter
+	}
+
+	if wc.Compress {
+		f.zWriter = mustCompressor(f.out, wc)
+
+		f.out = f.zWriter
+	}
+
+	eveFiles[wc.Out] = f
+
+	return f
+}
+
+// eveWriter is a structure that supports writing audit records to disk as Suricata EVE JSON events.
+type eveWriter struct {
+	f  *eveFile
+	wc *WriterConfig
+}
+
+// newEVEWriter initializes and configures a new eveWriter instance.
+// Audit record types without an EVE equivalent are discarded.
+func newEVEWriter(wc *WriterConfig) AuditRecordWriter {
+	if !eve.Supported(wc.Type) {
+		ioLog.Info("no eve event type for type, discarding audit records", zap.String("type", wc.Type.String()))
+
+		return newNullWriter(wc)
+	}
+
+	if wc.MemBufferSize <= 0 {
+		wc.MemBufferSize = defaults.BufferSize
+	}
+
+	return &eveWriter{
+		f:  openEVEFile(wc),
+		wc: wc,
+	}
+}
+
+// Write writes an audit record as EVE event.
+func (w *eveWriter) Write(msg proto.Message) error {
+	e := eve.New(msg)
+	if e == nil {
+		// not representable as event, for example a connection without IP layer
+		return nil
+	}
+
+	data, err := e.Marshal()
+	if err != nil {
+		return err
+	}
+
+	w.f.mu.Lock()
+	defer w.f.mu.Unlock()
+
+	_, err = w.f.out.Write(append(data, '\n'))
+
+	return err
+}
+
+// WriteHeader does nothing, EVE files have no header.
+func (w *eveWriter) WriteHeader(t types.Type) error {
+	return nil
+}
+
+// Close releases the shared EVE file, which is flushed and closed by the last writer.
+// Only the last writer returns the name and size of the file.
+func (w *eveWriter) Close(numRecords int64) (name string, size int64) {
+	eveFilesMu.Lock()
+	defer eveFilesMu.Unlock()
+
+	w.f.mu.Lock()
+	defer w.f.mu.Unlock()
+
+	w.f.numRecords += numRecords
+	w.f.refs--
+
+	if w.f.refs > 0 {
+		return "", 0
+	}
+
+	delete(eveFiles, w.wc.Out)
+
+	if w.wc.Compress {
+		closeCompressors(w.f.zWriter)
+	}
+
+	if w.wc.Buffer {
+		flushWriters(w.f.bWriter)
+	}
+
+	return closeFile(w.wc.Out, w.f.file, eveFileName, w.f.numRecords)
+}
@@ -22,8 +22,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/klauspost/pgzip"
-
 	"github.com/dreadl0ck/netcap/defaults"
 )
 
@@ -44,7 +42,7 @@ func flushWriters(writers ...flushableWriter) {
 	}
 }
 
-func closeGzipWriters(writers ...*pgzip.Writer) {
+func closeCompressors(writers ...compressor) {
 	for _, w := range writers {
 		err := w.Flush()
 		if err != nil {
@@ -111,11 +109,20 @@ func removeAuditRecordFileIfEmpty(name string, numRecords int64) (size int64) {
 }
 
 func isCSV(name string) bool {
-	return strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz")
+	return hasExtension(name, ".csv")
 }
 
 func isJSON(name string) bool {
-	return strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")
+	return hasExtension(name, ".json")
+}
+
+// hasExtension returns true if the file name ends with ext, optionally followed by the extension of a compression codec.
+func hasExtension(name, ext string) bool {
+	for _, c := range Codecs {
+		name = strings.TrimSuffix(name, CompressionExtension(c))
+	}
+
+	return strings.HasSuffix(name, ext)
 }
 
 func removeEmptyNewlineDelimitedFile(name string) (size int64) {
@@ -0,0 +1,239 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+ * Indexed Audit Record Files
+ *
+ * An indexed file is a sequence of gzip members, and therefore a valid gzip stream
+ * that can be read sequentially like any other compressed audit record file:
+ *
+ *   header member | block member ... | index member ... | trailer member
+ *
+ * The header member holds the netcap file header, each block member a batch of length delimited records.
+ * The index and trailer members contain no data, their information is stored in the extra field
+ * of the gzip header, which sequential readers ignore.
+ * The trailer has a fixed size, so it can be read from the end of the file to locate the index.
+ */
+
+var (
+	// ErrNotIndexed is returned for operations that require an indexed audit record file.
+	ErrNotIndexed = errors.New("audit record file is not indexed")
+
+	// ErrInvalidIndex is returned when the index of an audit record file is corrupted.
+	ErrInvalidIndex = errors.New("invalid audit record file index")
+)
+
+const (
+	indexVersion = 1
+
+	// sizes of the encoded structures
+	blockEntrySize  = 28
+	trailerDataSize = 13
+
+	// gzip header with the extra field flag set, followed by the length of the extra field.
+	memberHeaderSize = 12
+
+	// identifier and length of an extra field subfield.
+	subfieldHeaderSize = 4
+
+	// maximum length of the extra field
+	maxExtraSize = 0xffff
+
+	// number of block entries per index member
+	entriesPerMember = (maxExtraSize - subfieldHeaderSize) / blockEntrySize
+)
+
+// gzip subfield identifiers for the index and trailer members.
+var (
+	subfieldIndex   = [2]byte{'N', 'I'}
+	subfieldTrailer = [2]byte{'N', 'T'}
+)
+
+// emptyMemberTail is a final empty stored deflate block, followed by the CRC32 and size of no data.
+var emptyMemberTail = []byte{0x01, 0x00, 0x00, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+
+// trailerSize is the size of the trailer member.
+const trailerSize = memberHeaderSize + subfieldHeaderSize + trailerDataSize + 13
+
+// block describes a gzip member with audit records.
+type block struct {
+	offset  int64
+	records uint32
+
+	// timestamps of the audit records in the block, in nanoseconds
+	minTime int64
+	maxTime int64
+}
+
+// overlaps returns true if the block can contain audit records in the time range, zero values are unbounded.
+func (b block) overlaps(from, to int64) bool {
+	return (from == 0 || b.maxTime >= from) && (to == 0 || b.minTime < to)
+}
+
+// emptyMember returns a gzip member without data, that carries the data in a subfield of the extra field.
+func emptyMember(id [2]byte, data []byte) []byte {
+	m := []byte{
+		0x1f, 0x8b, // magic
+		8,          // deflate
+		4,          // FEXTRA
+		0, 0, 0, 0, // modification time
+		0,   // extra flags
+		255, // unknown OS
+	}
+
+	m = appendUint16(m, uint16(subfieldHeaderSize+len(data)))
+	m = append(m, id[0], id[1])
+	m = appendUint16(m, uint16(len(data)))
+	m = append(m, data...)
+
+	return append(m, emptyMemberTail...)
+}
+
+// parseEmptyMember returns the subfield data of an empty member created by emptyMember.
+func parseEmptyMember(m []byte, id [2]byte) ([]byte, error) {
+	if len(m) < memberHeaderSize+subfieldHeaderSize || m[0] != 0x1f || m[1] != 0x8b || m[3] != 4 {
+		return nil, ErrInvalidIndex
+	}
+
+	var (
+		extra = m[memberHeaderSize:]
+		size  = int(binary.LittleEndian.Uint16(m[memberHeaderSize-2:]))
+		n     = int(binary.LittleEndian.Uint16(extra[2:]))
+	)
+
+	if extra[0] != id[0] || extra[1] != id[1] || size != subfieldHeaderSize+n || len(extra) < size {
+		return nil, ErrInvalidIndex
+	}
+
+	return extra[subfieldHeaderSize:size], nil
+}
+
+// encodeIndex returns the index and trailer members for the blocks, the index starts at the offset.
+func encodeIndex(blocks []block, offset int64) []byte {
+	var out []byte
+
+	for i := 0; i < len(blocks) || i == 0; i += entriesPerMember {
+		var data []byte
+
+		for j := i; j < len(blocks) && j < i+entriesPerMember; j++ {
+			b := blocks[j]
+			data = appendUint64(data, uint64(b.offset))
+			data = appendUint32(data, b.records)
+			data = appendUint64(data, uint64(b.minTime))
+			data = appendUint64(data, uint64(b.maxTime))
+		}
+
+		out = append(out, emptyMember(subfieldIndex, data)...)
+	}
+
+	trailer := []byte{indexVersion}
+	trailer = appendUint64(trailer, uint64(offset))
+	trailer = appendUint32(trailer, uint32(len(blocks)))
+
+	return append(out, emptyMember(subfieldTrailer, trailer)...)
+}
+
+// readIndex reads the block index of an audit record file.
+// It returns ErrNotIndexed for files without index.
+func readIndex(f *os.File) ([]block, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if stat.Size() < trailerSize {
+		return nil, ErrNotIndexed
+	}
+
+	buf := make([]byte, trailerSize)
+	if _, err = f.ReadAt(buf, stat.Size()-trailerSize); err != nil {
+		return nil, err
+	}
+
+	trailer, err := parseEmptyMember(buf, subfieldTrailer)
+	if err != nil || len(trailer) != trailerDataSize {
+		return nil, ErrNotIndexed
+	}
+
+	if trailer[0] != indexVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidIndex, trailer[0])
+	}
+
+	var (
+		offset    = int64(binary.LittleEndian.Uint64(trailer[1:]))
+		numBlocks = int(binary.LittleEndian.Uint32(trailer[9:]))
+	)
+
+	if offset < 0 || offset > stat.Size()-trailerSize || numBlocks > int(stat.Size()/blockEntrySize) {
+		return nil, ErrInvalidIndex
+	}
+
+	var (
+		blocks = make([]block, 0, numBlocks)
+		r      = io.NewSectionReader(f, offset, stat.Size()-trailerSize-offset)
+	)
+
+	for len(blocks) < numBlocks {
+		member := make([]byte, memberHeaderSize)
+		if _, err = io.ReadFull(r, member); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIndex, err)
+		}
+
+		member = append(member, make([]byte, int(binary.LittleEndian.Uint16(member[memberHeaderSize-2:]))+len(emptyMemberTail))...)
+		if _, err = io.ReadFull(r, member[memberHeaderSize:]); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIndex, err)
+		}
+
+		data, err := parseEmptyMember(member, subfieldIndex)
+		if err != nil || len(data) == 0 || len(data)%blockEntrySize != 0 {
+			return nil, ErrInvalidIndex
+		}
+
+		for ; len(data) > 0; data = data[blockEntrySize:] {
+			blocks = append(blocks, block{
+				offset:  int64(binary.LittleEndian.Uint64(data)),
+				records: binary.LittleEndian.Uint32(data[8:]),
+				minTime: int64(binary.LittleEndian.Uint64(data[12:])),
+				maxTime: int64(binary.LittleEndian.Uint64(data[20:])),
+			})
+		}
+	}
+
+	if len(blocks) != numBlocks {
+		return nil, ErrInvalidIndex
+	}
+
+	return blocks, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
@@ -0,0 +1,185 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/delimited"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// indexedWriter is a structure that supports writing protobuf audit records to disk
+// in independently compressed blocks, with an index for seeking by time.
+type indexedWriter struct {
+	mu sync.Mutex
+
+	// current block
+	buf     bytes.Buffer
+	dWriter *delimited.Writer
+	current block
+
+	// written blocks
+	blocks  []block
+	gWriter *gzip.Writer
+	bWriter *bufio.Writer
+	offset  int64
+
+	file *os.File
+	wc   *WriterConfig
+}
+
+// newIndexedWriter initializes and configures a new indexedWriter instance.
+func newIndexedWriter(wc *WriterConfig) *indexedWriter {
+	w := &indexedWriter{
+		wc: wc,
+	}
+
+	if wc.MemBufferSize <= 0 {
+		wc.MemBufferSize = defaults.BufferSize
+	}
+
+	if wc.IndexBlockSize <= 0 {
+		wc.IndexBlockSize = defaults.IndexBlockSize
+	}
+
+	// indexed files are always compressed
+	w.file = createFile(filepath.Join(wc.Out, wc.Name), defaults.FileExtensionCompressed)
+	ioLog.Info("create indexedWriter", zap.String("base", filepath.Join(wc.Out, wc.Name)), zap.String("type", wc.Type.String()))
+
+	w.bWriter = bufio.NewWriterSize(w.file, wc.MemBufferSize)
+	w.dWriter = delimited.NewWriter(&w.buf)
+
+	var err error
+	if w.gWriter, err = gzip.NewWriterLevel(w.bWriter, wc.CompressionLevel); err != nil {
+		panic(err)
+	}
+
+	return w
+}
+
+// Write writes a protobuf message into the current block, which is compressed once it reached the block size.
+func (w *indexedWriter) Write(msg proto.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.dWriter.PutProto(msg); err != nil {
+		return err
+	}
+
+	if r, ok := msg.(types.AuditRecord); ok {
+		t := r.Time()
+
+		if w.current.records == 0 || t < w.current.minTime {
+			w.current.minTime = t
+		}
+
+		if w.current.records == 0 || t > w.current.maxTime {
+			w.current.maxTime = t
+		}
+	}
+
+	w.current.records++
+
+	if w.buf.Len() >= w.wc.IndexBlockSize {
+		return w.flushBlock()
+	}
+
+	return nil
+}
+
+// WriteHeader writes the netcap file header into its own gzip member.
+func (w *indexedWriter) WriteHeader(t types.Type) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.dWriter.PutProto(NewHeader(t, w.wc.Source, w.wc.Version, w.wc.IncludesPayloads, w.wc.StartTime)); err != nil {
+		return err
+	}
+
+	return w.compress()
+}
+
+// flushBlock compresses the current block and adds it to the index.
+func (w *indexedWriter) flushBlock() error {
+	if w.current.records == 0 {
+		return nil
+	}
+
+	w.current.offset = w.offset
+	w.blocks = append(w.blocks, w.current)
+	w.current = block{}
+
+	return w.compress()
+}
+
+// compress writes the buffered data as a gzip member.
+func (w *indexedWriter) compress() error {
+	c := &countingWriter{w: w.bWriter}
+	w.gWriter.Reset(c)
+
+	if _, err := w.gWriter.Write(w.buf.Bytes()); err != nil {
+		return err
+	}
+
+	if err := w.gWriter.Close(); err != nil {
+		return err
+	}
+
+	w.offset += c.n
+	w.buf.Reset()
+
+	return nil
+}
+
+// Close compresses the last block, writes the index and closes the associated file handles.
+func (w *indexedWriter) Close(numRecords int64) (name string, size int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.flushBlock(); err != nil {
+		ioLog.Error("failed to write block", zap.Error(err), zap.String("name", w.wc.Name))
+	}
+
+	if _, err := w.bWriter.Write(encodeIndex(w.blocks, w.offset)); err != nil {
+		ioLog.Error("failed to write index", zap.Error(err), zap.String("name", w.wc.Name))
+	}
+
+	flushWriters(w.bWriter)
+
+	return closeFile(w.wc.Out, w.file, w.wc.Name, numRecords)
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
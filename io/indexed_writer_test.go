@@ -0,0 +1,188 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// readAll returns the timestamps of the remaining audit records.
+func readAll(t *testing.T, r *Reader) (timestamps []int64) {
+	t.Helper()
+
+	tcp := new(types.TCP)
+
+	for {
+		err := r.Next(tcp)
+		if errors.Is(err, io.EOF) {
+			return timestamps
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		timestamps = append(timestamps, tcp.Timestamp)
+	}
+}
+
+// writeTCP writes TCP audit records with one second between the timestamps.
+func writeTCP(t *testing.T, w AuditRecordWriter, start time.Time, numRecords int) {
+	t.Helper()
+
+	if err := w.WriteHeader(types.Type_NC_TCP); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < numRecords; i++ {
+		if err := w.Write(&types.TCP{Timestamp: start.Add(time.Duration(i) * time.Second).UnixNano(), SrcPort: int32(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, size := w.Close(int64(numRecords)); size == 0 {
+		t.Fatal("no bytes written")
+	}
+}
+
+func TestIndexedWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := newIndexedWriter(&WriterConfig{
+		Proto:            true,
+		Index:            true,
+		IndexBlockSize:   512,
+		Name:             "TCP",
+		Out:              dir,
+		Source:           "unit tests",
+		Version:          netcap.Version,
+		StartTime:        time.Now(),
+		CompressionLevel: defaults.CompressionLevel,
+	})
+
+	const numRecords = 1000
+
+	start := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	writeTCP(t, w, start, numRecords)
+
+	if len(w.blocks) < 10 {
+		t.Fatal("expected multiple blocks, got", len(w.blocks))
+	}
+
+	r, err := Open(filepath.Join(dir, "TCP"+defaults.FileExtensionCompressed), defaults.BufferSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if !r.Indexed() {
+		t.Fatal("expected an indexed file")
+	}
+
+	if h, errHeader := r.ReadHeader(); errHeader != nil || h.Type != types.Type_NC_TCP {
+		t.Fatal("invalid header", h, errHeader)
+	}
+
+	// sequential reads ignore the index
+	if n := len(readAll(t, r)); n != numRecords {
+		t.Fatal("expected", numRecords, "records, got", n)
+	}
+
+	// 10:05 - 10:10
+	if err = r.SetTimeRange(start.Add(5*time.Minute), start.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamps := readAll(t, r)
+	if len(timestamps) != 300 || timestamps[0] != start.Add(5*time.Minute).UnixNano() {
+		t.Fatal("unexpected records in range", len(timestamps))
+	}
+
+	if err = r.SetTimeRange(time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = r.SeekTime(start.Add(990 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamps = readAll(t, r)
+	if len(timestamps) < 10 || len(timestamps) > 100 || timestamps[len(timestamps)-1] != start.Add((numRecords-1)*time.Second).UnixNano() {
+		t.Fatal("unexpected records after seeking", len(timestamps))
+	}
+
+	if err = r.SeekTime(start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(readAll(t, r)); n != 0 {
+		t.Fatal("expected no records after the end, got", n)
+	}
+}
+
+func TestTimeRangeNotIndexed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	writeTCP(t, newProtoWriter(&WriterConfig{
+		Proto:                true,
+		Name:                 "TCP",
+		Compress:             true,
+		Out:                  dir,
+		Source:               "unit tests",
+		Version:              netcap.Version,
+		StartTime:            time.Now(),
+		CompressionBlockSize: defaults.CompressionBlockSize,
+		CompressionLevel:     defaults.CompressionLevel,
+	}), start, 100)
+
+	r, err := Open(filepath.Join(dir, "TCP"+defaults.FileExtensionCompressed), defaults.BufferSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err = r.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Indexed() || !errors.Is(r.SeekTime(start), ErrNotIndexed) {
+		t.Fatal("expected a file without index")
+	}
+
+	if err = r.SetTimeRange(start.Add(10*time.Second), start.Add(20*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamps := readAll(t, r)
+	if len(timestamps) != 10 || timestamps[0] != start.Add(10*time.Second).UnixNano() {
+		t.Fatal("unexpected records in range", len(timestamps))
+	}
+}
@@ -19,15 +19,12 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sync"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/gogo/protobuf/proto"
-	"github.com/klauspost/pgzip"
 
 	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/delimited"
@@ -38,7 +35,7 @@ import (
 type jsonWriter struct {
 	mu      sync.Mutex
 	bWriter *bufio.Writer
-	gWriter *pgzip.Writer
+	zWriter compressor
 	dWriter *delimited.Writer
 	jWriter *jsonProtoWriter
 
@@ -57,7 +54,7 @@ func newJSONWriter(wc *WriterConfig) *jsonWriter {
 
 	// create file
 	if wc.Compress {
-		w.file = createFile(filepath.Join(wc.Out, w.wc.Name), ".json.gz")
+		w.file = createFile(filepath.Join(wc.Out, w.wc.Name), ".json"+CompressionExtension(wc.CompressionCodec))
 	} else {
 		w.file = createFile(filepath.Join(wc.Out, w.wc.Name), ".json")
 	}
@@ -67,39 +64,20 @@ func newJSONWriter(wc *WriterConfig) *jsonWriter {
 		w.bWriter = bufio.NewWriterSize(w.file, wc.MemBufferSize)
 
 		if wc.Compress {
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.bWriter, wc.CompressionLevel)
-
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-
-			w.jWriter = newJSONProtoWriter(w.gWriter)
+			w.zWriter = mustCompressor(w.bWriter, wc)
+			w.jWriter = newJSONProtoWriter(w.zWriter)
 		} else {
 			w.jWriter = newJSONProtoWriter(w.bWriter)
 		}
 	} else {
 		if wc.Compress {
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.file, wc.CompressionLevel)
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-			w.jWriter = newJSONProtoWriter(w.gWriter)
+			w.zWriter = mustCompressor(w.file, wc)
+			w.jWriter = newJSONProtoWriter(w.zWriter)
 		} else {
 			w.jWriter = newJSONProtoWriter(w.file)
 		}
 	}
 
-	if w.gWriter != nil {
-		// To get any performance gains, you should at least be compressing more than 1 megabyte of data at the time.
-		// You should at least have a block size of 100k and at least a number of blocks that match the number of cores
-		// you would like to utilize, but about twice the number of blocks would be the best.
-		if err := w.gWriter.SetConcurrency(wc.CompressionBlockSize, runtime.GOMAXPROCS(0)*2); err != nil {
-			log.Fatal("failed to configure compression package: ", err)
-		}
-	}
-
 	return w
 }
 
@@ -128,12 +106,13 @@ func (w *jsonWriter) Close(numRecords int64) (name string, size int64) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.wc.Buffer {
-		flushWriters(w.bWriter)
+	// the compressor writes into the buffer, close it first
+	if w.wc.Compress {
+		closeCompressors(w.zWriter)
 	}
 
-	if w.wc.Compress {
-		closeGzipWriters(w.gWriter)
+	if w.wc.Buffer {
+		flushWriters(w.bWriter)
 	}
 
 	return closeFile(w.wc.Out, w.file, w.wc.Name, numRecords)
@@ -171,6 +171,40 @@ func InitRecord(typ types.Type) (record proto.Message) {
 		record = new(types.Mail)
 	case types.Type_NC_Alert:
 		record = new(types.Alert)
+	case types.Type_NC_MySQL:
+		record = new(types.MySQL)
+	case types.Type_NC_PostgreSQL:
+		record = new(types.PostgreSQL)
+	case types.Type_NC_Redis:
+		record = new(types.Redis)
+	case types.Type_NC_Kerberos:
+		record = new(types.Kerberos)
+	case types.Type_NC_LDAP:
+		record = new(types.LDAP)
+	case types.Type_NC_SNMP:
+		record = new(types.SNMP)
+	case types.Type_NC_SIPDialog:
+		record = new(types.SIPDialog)
+	case types.Type_NC_RTPStream:
+		record = new(types.RTPStream)
+	case types.Type_NC_PPPoE:
+		record = new(types.PPPoE)
+	case types.Type_NC_PPP:
+		record = new(types.PPP)
+	case types.Type_NC_MDNS:
+		record = new(types.MDNS)
+	case types.Type_NC_LLMNR:
+		record = new(types.LLMNR)
+	case types.Type_NC_NBNS:
+		record = new(types.NBNS)
+	case types.Type_NC_SSDP:
+		record = new(types.SSDP)
+	case types.Type_NC_GTPv1U:
+		record = new(types.GTPv1U)
+	case types.Type_NC_GTPv2C:
+		record = new(types.GTPv2C)
+	case types.Type_NC_CaptureStatistics:
+		record = new(types.CaptureStatistics)
 	default:
 		panic("InitRecord: unknown type: " + typ.String())
 	}
@@ -0,0 +1,176 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/parquet"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// parquetWriter is a structure that supports writing audit records to disk as Apache Parquet files.
+type parquetWriter struct {
+	sync.Mutex
+
+	bWriter *bufio.Writer
+	pWriter *parquet.Writer
+
+	file *os.File
+	wc   *WriterConfig
+}
+
+// newParquetWriter initializes and configures a new parquetWriter instance.
+func newParquetWriter(wc *WriterConfig) *parquetWriter {
+	w := &parquetWriter{}
+	w.wc = wc
+
+	if wc.MemBufferSize <= 0 {
+		wc.MemBufferSize = defaults.BufferSize
+	}
+
+	codec, err := parquet.ParseCodec(wc.ParquetCompression)
+	if err != nil {
+		panic(err)
+	}
+
+	// create file
+	w.file = createFile(filepath.Join(wc.Out, w.wc.Name), ".parquet")
+	ioLog.Info("create parquetWriter", zap.String("base", filepath.Join(wc.Out, wc.Name)), zap.String("type", wc.Type.String()))
+
+	// the row groups are written at once, a buffer is always used
+	w.bWriter = bufio.NewWriterSize(w.file, wc.MemBufferSize)
+
+	w.pWriter, err = parquet.NewWriter(w.bWriter, reflect.TypeOf(InitRecord(wc.Type)), parquet.Options{
+		Compression:  codec,
+		RowGroupSize: wc.ParquetRowGroupSize,
+		CreatedBy:    "netcap version " + wc.Version,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return w
+}
+
+// Write writes an audit record as row.
+func (w *parquetWriter) Write(msg proto.Message) error {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.pWriter.Write(msg)
+}
+
+// WriteHeader stores the netcap header in the file metadata,
+// which is written to the file footer on close.
+func (w *parquetWriter) WriteHeader(t types.Type) error {
+	w.Lock()
+	defer w.Unlock()
+
+	setParquetMetadata(w.pWriter, NewHeader(t, w.wc.Source, w.wc.Version, w.wc.IncludesPayloads, w.wc.StartTime))
+
+	return nil
+}
+
+// Close writes the remaining rows and the file footer, then flushes and closes the associated file handles.
+func (w *parquetWriter) Close(numRecords int64) (name string, size int64) {
+	w.Lock()
+	defer w.Unlock()
+
+	if err := w.pWriter.Close(); err != nil {
+		ioLog.Error("failed to close parquet writer", zap.String("type", w.wc.Type.String()), zap.Error(err))
+	}
+
+	flushWriters(w.bWriter)
+
+	return closeFile(w.wc.Out, w.file, w.wc.Name, numRecords)
+}
+
+// setParquetMetadata adds the fields of the netcap header to the file metadata.
+func setParquetMetadata(w *parquet.Writer, h *types.Header) {
+	w.SetMetadata("netcap.type", h.Type.String())
+	w.SetMetadata("netcap.created", time.Unix(0, h.Created).UTC().Format(time.RFC3339Nano))
+	w.SetMetadata("netcap.source", h.InputSource)
+	w.SetMetadata("netcap.version", h.Version)
+	w.SetMetadata("netcap.containsPayloads", strconv.FormatBool(h.ContainsPayloads))
+}
+
+// ConvertToParquet reads the audit records from the netcap file at in and writes them to a Parquet file at out.
+// The dictionary is needed for input files compressed with a dictionary, the number of records written is returned.
+func ConvertToParquet(in, out string, opts parquet.Options, dict []byte) (numRecords int64, err error) {
+	r, err := OpenWithDictionary(in, defaults.BufferSize, dict)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	header, err := r.ReadHeader()
+	if err != nil {
+		return 0, err
+	}
+
+	record := InitRecord(header.Type)
+
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if errClose := f.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	bw := bufio.NewWriterSize(f, defaults.BufferSize)
+
+	pw, err := parquet.NewWriter(bw, reflect.TypeOf(record), opts)
+	if err != nil {
+		return 0, err
+	}
+
+	setParquetMetadata(pw, header)
+
+	for {
+		err = r.Next(record)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			return pw.NumRows(), err
+		}
+
+		if err = pw.Write(record); err != nil {
+			return pw.NumRows(), err
+		}
+	}
+
+	if err = pw.Close(); err != nil {
+		return pw.NumRows(), err
+	}
+
+	return pw.NumRows(), bw.Flush()
+}
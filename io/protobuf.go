@@ -16,14 +16,11 @@ package io
 import (
 	"bufio"
 	"go.uber.org/zap"
-	"log"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sync"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/klauspost/pgzip"
 
 	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/delimited"
@@ -35,7 +32,7 @@ type protoWriter struct {
 	mu sync.Mutex
 
 	bWriter *bufio.Writer
-	gWriter *pgzip.Writer
+	zWriter compressor
 	dWriter *delimited.Writer
 	pWriter *delimitedProtoWriter
 
@@ -53,7 +50,7 @@ func newProtoWriter(wc *WriterConfig) *protoWriter {
 	}
 
 	if wc.Compress {
-		w.file = createFile(filepath.Join(wc.Out, wc.Name), defaults.FileExtensionCompressed)
+		w.file = createFile(filepath.Join(wc.Out, wc.Name), defaults.FileExtension+CompressionExtension(wc.CompressionCodec))
 	} else {
 		w.file = createFile(filepath.Join(wc.Out, wc.Name), defaults.FileExtension)
 	}
@@ -62,15 +59,9 @@ func newProtoWriter(wc *WriterConfig) *protoWriter {
 	// buffer data?
 	if wc.Buffer {
 		if wc.Compress {
-			// experiment: pgzip -> file
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.file, wc.CompressionLevel)
-
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-			// experiment: buffer -> pgzip
-			w.bWriter = bufio.NewWriterSize(w.gWriter, wc.MemBufferSize)
+			w.zWriter = mustCompressor(w.file, wc)
+			// experiment: buffer -> compressor
+			w.bWriter = bufio.NewWriterSize(w.zWriter, wc.MemBufferSize)
 			// experiment: delimited -> buffer
 			w.dWriter = delimited.NewWriter(w.bWriter)
 		} else {
@@ -79,12 +70,8 @@ func newProtoWriter(wc *WriterConfig) *protoWriter {
 		}
 	} else {
 		if w.wc.Compress {
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.file, wc.CompressionLevel)
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-			w.dWriter = delimited.NewWriter(w.gWriter)
+			w.zWriter = mustCompressor(w.file, wc)
+			w.dWriter = delimited.NewWriter(w.zWriter)
 		} else {
 			w.dWriter = delimited.NewWriter(w.file)
 		}
@@ -92,15 +79,6 @@ func newProtoWriter(wc *WriterConfig) *protoWriter {
 
 	w.pWriter = newDelimitedProtoWriter(w.dWriter)
 
-	if w.gWriter != nil {
-		// To get any performance gains, you should at least be compressing more than 1 megabyte of data at the time.
-		// You should at least have a block size of 100k and at least a number of blocks that match the number of cores
-		// you would like to utilize, but about twice the number of blocks would be the best.
-		if err := w.gWriter.SetConcurrency(wc.CompressionBlockSize, runtime.GOMAXPROCS(0)*2); err != nil {
-			log.Fatal("failed to configure compression package: ", err)
-		}
-	}
-
 	return w
 }
 
@@ -127,7 +105,7 @@ func (w *protoWriter) Close(numRecords int64) (name string, size int64) {
 	}
 
 	if w.wc.Compress {
-		closeGzipWriters(w.gWriter)
+		closeCompressors(w.zWriter)
 	}
 
 	return closeFile(w.wc.Out, w.file, w.wc.Name, numRecords)
@@ -16,8 +16,9 @@ package io
 import (
 	"bufio"
 	"compress/gzip"
+	"io"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/gogo/protobuf/proto"
@@ -32,11 +33,27 @@ type Reader struct {
 	file    *os.File
 	bReader *bufio.Reader
 	gReader *gzip.Reader
+	cReader io.Reader // decompressing reader for all codecs
 	dReader *delimited.Reader
+
+	// block index, nil for files that are not indexed
+	blocks []block
+
+	// time range in nanoseconds, zero values are unbounded
+	from, to int64
+
+	// blocks in the time range that remain to be read
+	pending []block
 }
 
 // Open a netcap audit record file for reading.
+// The compression codec is detected from the magic bytes at the start of the file.
 func Open(file string, memBufSize int) (*Reader, error) {
+	return OpenWithDictionary(file, memBufSize, nil)
+}
+
+// OpenWithDictionary opens a netcap audit record file that was compressed with a zstd or lz4 dictionary.
+func OpenWithDictionary(file string, memBufSize int, dict []byte) (*Reader, error) {
 	r := &Reader{}
 
 	h, err := os.Open(file)
@@ -51,30 +68,34 @@ func Open(file string, memBufSize int) (*Reader, error) {
 	r.file = h
 	r.bReader = bufio.NewReaderSize(h, memBufSize)
 
-	if filepath.Ext(file) == ".gz" {
-		r.gReader, err = gzip.NewReader(r.bReader)
-		if err != nil {
+	r.cReader, err = NewDecompressor(r.bReader, dict)
+	if err != nil {
+		return nil, err
+	}
+
+	// only gzip compressed files can carry a block index
+	if g, ok := r.cReader.(*gzip.Reader); ok {
+		r.gReader = g
+
+		r.blocks, err = readIndex(h)
+		if err != nil && !errors.Is(err, ErrNotIndexed) {
 			return nil, err
 		}
-
-		r.dReader = delimited.NewReader(r.gReader)
-	} else {
-		r.dReader = delimited.NewReader(r.bReader)
 	}
 
+	r.dReader = delimited.NewReader(r.cReader)
+
 	return r, nil
 }
 
 // Close the file.
 func (r *Reader) Close() error {
-	if r.gReader != nil {
-		err := r.gReader.Close()
-		if err != nil {
-			return err
-		}
+	err := CloseDecompressor(r.cReader)
+	if err != nil {
+		return err
 	}
 
-	err := r.file.Sync()
+	err = r.file.Sync()
 	if err != nil {
 		return err
 	}
@@ -83,8 +104,136 @@ func (r *Reader) Close() error {
 }
 
 // Next Message.
+// If a time range is set, audit records outside of the range are skipped.
 func (r *Reader) Next(msg proto.Message) error {
-	return r.dReader.NextProto(msg)
+	for {
+		err := r.dReader.NextProto(msg)
+		if err == io.EOF && len(r.pending) > 0 {
+			if err = r.readBlock(r.pending[0], false); err != nil {
+				return err
+			}
+
+			r.pending = r.pending[1:]
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if r.inRange(msg) {
+			return nil
+		}
+	}
+}
+
+// Indexed returns true if the file contains a block index, which allows seeking by time.
+func (r *Reader) Indexed() bool {
+	return r.blocks != nil
+}
+
+// SeekTime positions an indexed reader at the first block that contains audit records at or after the time.
+// Since audit records are not necessarily written in chronological order,
+// records before the time can still follow. Use SetTimeRange to filter them.
+// The file header must have been read before.
+func (r *Reader) SeekTime(t time.Time) error {
+	if !r.Indexed() {
+		return ErrNotIndexed
+	}
+
+	r.pending = nil
+
+	for _, b := range r.blocks {
+		if b.overlaps(t.UnixNano(), 0) {
+			return r.readBlock(b, true)
+		}
+	}
+
+	// no records at or after the time, position at the end of the data
+	return r.readBlock(block{offset: r.end()}, true)
+}
+
+// SetTimeRange restricts Next to audit records with a timestamp in the range [from, to).
+// A zero time leaves the range unbounded on that side.
+// For indexed files, only the blocks that overlap the range are decompressed,
+// other files are read sequentially and filtered.
+// The file header must have been read before.
+func (r *Reader) SetTimeRange(from, to time.Time) error {
+	r.from, r.to = 0, 0
+
+	if !from.IsZero() {
+		r.from = from.UnixNano()
+	}
+
+	if !to.IsZero() {
+		r.to = to.UnixNano()
+	}
+
+	if !r.Indexed() {
+		return nil
+	}
+
+	r.pending = nil
+
+	for _, b := range r.blocks {
+		if b.overlaps(r.from, r.to) {
+			r.pending = append(r.pending, b)
+		}
+	}
+
+	// position at the end of the data, Next continues with the pending blocks
+	return r.readBlock(block{offset: r.end()}, false)
+}
+
+// end returns the offset after the last block of an indexed file.
+func (r *Reader) end() int64 {
+	if stat, err := r.file.Stat(); err == nil {
+		return stat.Size()
+	}
+
+	return 0
+}
+
+// readBlock continues reading at the start of the block,
+// if multistream is false reading stops at the end of the block.
+func (r *Reader) readBlock(b block, multistream bool) error {
+	if _, err := r.file.Seek(b.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r.bReader.Reset(r.file)
+
+	if b.offset >= r.end() {
+		r.dReader = delimited.NewReader(r.bReader)
+
+		return nil
+	}
+
+	if err := r.gReader.Reset(r.bReader); err != nil {
+		return err
+	}
+
+	r.gReader.Multistream(multistream)
+	r.dReader = delimited.NewReader(r.gReader)
+
+	return nil
+}
+
+// inRange returns true if the audit record is within the time range of the reader.
+func (r *Reader) inRange(msg proto.Message) bool {
+	if r.from == 0 && r.to == 0 {
+		return true
+	}
+
+	a, ok := msg.(types.AuditRecord)
+	if !ok {
+		return true
+	}
+
+	t := a.Time()
+
+	return (r.from == 0 || t >= r.from) && (r.to == 0 || t < r.to)
 }
 
 // ReadHeader reads the file header.
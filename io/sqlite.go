@@ -0,0 +1,137 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// QuerySQLite runs the query against the netcap SQLite database at path and writes the resulting rows to w.
+// Rows are written as JSON objects, as table or as CSV with header line, according to the dump configuration.
+func QuerySQLite(w io.Writer, path, query string, c DumpConfig) error {
+	// the driver would create an empty database for a missing file
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to run query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	if c.Separator == "\\t" || c.TabSeparated {
+		c.Separator = "\t"
+	}
+
+	if c.Separator == "" {
+		c.Separator = ","
+	}
+
+	var (
+		values = make([]interface{}, len(columns))
+		ptrs   = make([]interface{}, len(columns))
+		table  [][]string
+	)
+
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	if !c.JSON && !c.Table {
+		if _, err = fmt.Fprintln(w, strings.Join(columns, c.Separator)); err != nil {
+			return err
+		}
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		switch {
+		case c.JSON:
+			obj := make(map[string]interface{}, len(columns))
+
+			for i, col := range columns {
+				if b, ok := values[i].([]byte); ok {
+					obj[col] = hex.EncodeToString(b)
+				} else {
+					obj[col] = values[i]
+				}
+			}
+
+			data, errMarshal := json.Marshal(obj)
+			if errMarshal != nil {
+				return errMarshal
+			}
+
+			if _, err = fmt.Fprintln(w, string(data)); err != nil {
+				return err
+			}
+		case c.Table:
+			table = append(table, sqliteRow(values))
+		default:
+			if _, err = fmt.Fprintln(w, strings.Join(sqliteRow(values), c.Separator)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if c.Table {
+		tui.Table(w, columns, table)
+	}
+
+	return nil
+}
+
+// sqliteRow formats the values of a result row, blobs are hex encoded.
+func sqliteRow(values []interface{}) []string {
+	row := make([]string, len(values))
+
+	for i, v := range values {
+		switch val := v.(type) {
+		case nil:
+		case []byte:
+			row[i] = hex.EncodeToString(val)
+		default:
+			row[i] = fmt.Sprint(val)
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,408 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	_ "github.com/mattn/go-sqlite3" // register the sqlite3 driver
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// sqliteFileName is the name of the database inside the output directory, without extension.
+	sqliteFileName = "netcap"
+
+	// sqliteExtension is the file extension of netcap SQLite databases.
+	sqliteExtension = ".sqlite"
+
+	// sqliteHeaderTable holds the netcap header of each audit record table.
+	sqliteHeaderTable = "NetcapHeader"
+)
+
+// sqliteIndexedFields are the fields that get an index in every table that contains them,
+// nested fields are matched by the name of the field in the nested message.
+var sqliteIndexedFields = map[string]bool{
+	"Timestamp":      true,
+	"TimestampFirst": true,
+	"SrcIP":          true,
+	"DstIP":          true,
+	"UID":            true,
+	"Ident":          true,
+	"Flow":           true,
+}
+
+// IsSQLite returns true if the file name has the extension of a netcap SQLite database.
+func IsSQLite(name string) bool {
+	return filepath.Ext(name) == sqliteExtension
+}
+
+// sqliteDB is the database shared by the writers of all audit record types,
+// each type is stored in its own table.
+type sqliteDB struct {
+	mu         sync.Mutex
+	refs       int
+	numRecords int64
+
+	db   *sql.DB
+	path string
+}
+
+var (
+	sqliteDBsMu sync.Mutex
+
+	// open databases, by output directory
+	sqliteDBs = make(map[string]*sqliteDB)
+)
+
+// openSQLiteDB returns the database for the output directory of the config, creating it on first use.
+func openSQLiteDB(wc *WriterConfig) *sqliteDB {
+	sqliteDBsMu.Lock()
+	defer sqliteDBsMu.Unlock()
+
+	if d, ok := sqliteDBs[wc.Out]; ok {
+		d.refs++
+
+		return d
+	}
+
+	d := &sqliteDB{
+		refs: 1,
+		path: filepath.Join(wc.Out, sqliteFileName+sqliteExtension),
+	}
+
+	// start with an empty database, like the other writers truncate their files
+	if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+
+	ioLog.Info("create sqlite database", zap.String("path", d.path))
+
+	// the journal is kept in memory, the database is written once by a single process
+	db, err := sql.Open("sqlite3", "file:"+d.path+"?_journal_mode=MEMORY&_synchronous=OFF")
+	if err != nil {
+		panic(err)
+	}
+
+	// sqlite allows a single writer, serialize access from all audit record writers
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE " + quoteIdent(sqliteHeaderTable) + " (Type TEXT PRIMARY KEY, Created INTEGER, Source TEXT, Version TEXT, ContainsPayloads INTEGER)")
+	if err != nil {
+		panic(err)
+	}
+
+	d.db = db
+	sqliteDBs[wc.Out] = d
+
+	return d
+}
+
+// sqliteColumn is a column of an audit record table.
+type sqliteColumn struct {
+	name    string
+	sqlType string
+	index   []int // index sequence of the struct field, for nested messages
+	json    bool  // repeated fields and maps are stored as JSON
+	indexed bool
+}
+
+// sqliteColumns derives the columns for a protobuf message type from the field properties.
+// Nested messages are flattened into columns with the name of the parent field as prefix.
+func sqliteColumns(t reflect.Type, prefix string, index []int) (columns []sqliteColumn) {
+	props := proto.GetProperties(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("protobuf") == "" {
+			// XXX_ fields
+			continue
+		}
+
+		var (
+			p    = props.Prop[i]
+			name = prefix + p.OrigName
+			idx  = append(index[:len(index):len(index)], i)
+		)
+
+		switch {
+		case f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct:
+			columns = append(columns, sqliteColumns(f.Type.Elem(), name+"_", idx)...)
+
+			continue
+		case f.Type.Kind() == reflect.Map || (p.Repeated && f.Type.Kind() == reflect.Slice):
+			columns = append(columns, sqliteColumn{name: name, sqlType: "TEXT", index: idx, json: true})
+
+			continue
+		}
+
+		var sqlType string
+
+		switch f.Type.Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			sqlType = "INTEGER"
+		case reflect.Float32, reflect.Float64:
+			sqlType = "REAL"
+		case reflect.Slice:
+			sqlType = "BLOB"
+		default:
+			sqlType = "TEXT"
+		}
+
+		columns = append(columns, sqliteColumn{
+			name:    name,
+			sqlType: sqlType,
+			index:   idx,
+			indexed: sqliteIndexedFields[p.OrigName],
+		})
+	}
+
+	return columns
+}
+
+// value returns the value of the column for the audit record v, nil for fields of absent nested messages.
+func (c *sqliteColumn) value(v reflect.Value) (interface{}, error) {
+	for _, i := range c.index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, nil
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	if c.json {
+		if v.Len() == 0 {
+			return nil, nil
+		}
+
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		return string(data), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// sqlite integers are signed 64 bit values, the driver rejects uint64 values with the high bit set
+		return int64(v.Uint()), nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// quoteIdent quotes an SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqliteWriter is a structure that supports writing audit records into a table of an SQLite database.
+type sqliteWriter struct {
+	sync.Mutex
+
+	d       *sqliteDB
+	table   string
+	columns []sqliteColumn
+	insert  string
+	rows    [][]interface{}
+
+	wc *WriterConfig
+}
+
+// newSQLiteWriter initializes and configures a new sqliteWriter instance,
+// and creates the table for the audit record type with its indexes.
+func newSQLiteWriter(wc *WriterConfig) *sqliteWriter {
+	w := &sqliteWriter{
+		d:       openSQLiteDB(wc),
+		table:   wc.Name,
+		columns: sqliteColumns(reflect.TypeOf(InitRecord(wc.Type)).Elem(), "", nil),
+		wc:      wc,
+	}
+
+	ioLog.Info("create sqliteWriter", zap.String("table", w.table), zap.String("type", wc.Type.String()))
+
+	var (
+		defs         = make([]string, len(w.columns))
+		names        = make([]string, len(w.columns))
+		placeholders = make([]string, len(w.columns))
+		stmts        []string
+	)
+
+	for i, c := range w.columns {
+		defs[i] = quoteIdent(c.name) + " " + c.sqlType
+		names[i] = quoteIdent(c.name)
+		placeholders[i] = "?"
+
+		if c.indexed {
+			stmts = append(stmts, "CREATE INDEX "+quoteIdent(w.table+"_"+c.name)+" ON "+quoteIdent(w.table)+" ("+quoteIdent(c.name)+")")
+		}
+	}
+
+	stmts = append([]string{"CREATE TABLE " + quoteIdent(w.table) + " (" + strings.Join(defs, ", ") + ")"}, stmts...)
+
+	for _, s := range stmts {
+		if _, err := w.d.db.Exec(s); err != nil {
+			panic(fmt.Errorf("failed to create table %s: %w", w.table, err))
+		}
+	}
+
+	w.insert = "INSERT INTO " + quoteIdent(w.table) + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+
+	return w
+}
+
+// Write adds an audit record as row, rows are inserted in batches.
+func (w *sqliteWriter) Write(msg proto.Message) error {
+	v := reflect.ValueOf(msg)
+	row := make([]interface{}, len(w.columns))
+
+	for i := range w.columns {
+		val, err := w.columns[i].value(v)
+		if err != nil {
+			return err
+		}
+
+		row[i] = val
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	w.rows = append(w.rows, row)
+
+	if len(w.rows) >= defaults.SQLiteBatchSize {
+		return w.flush()
+	}
+
+	return nil
+}
+
+// flush inserts the buffered rows in a single transaction.
+// If the transaction fails, the batch is dropped, so a bad row does not stop all following records of the table.
+func (w *sqliteWriter) flush() (err error) {
+	if len(w.rows) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			ioLog.Error("failed to insert SQLite batch, dropping it",
+				zap.String("table", w.table),
+				zap.Int("rows", len(w.rows)),
+				zap.Error(err),
+			)
+		}
+
+		w.rows = w.rows[:0]
+	}()
+
+	tx, err := w.d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(w.insert)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	for _, row := range w.rows {
+		if _, err = stmt.Exec(row...); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	if err = stmt.Close(); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WriteHeader stores the netcap header in the header table.
+func (w *sqliteWriter) WriteHeader(t types.Type) error {
+	h := NewHeader(t, w.wc.Source, w.wc.Version, w.wc.IncludesPayloads, w.wc.StartTime)
+
+	_, err := w.d.db.Exec(
+		"INSERT OR REPLACE INTO "+quoteIdent(sqliteHeaderTable)+" VALUES (?, ?, ?, ?, ?)",
+		w.table, h.Created, h.InputSource, h.Version, h.ContainsPayloads,
+	)
+
+	return err
+}
+
+// Close inserts the remaining rows and releases the shared database, which is closed by the last writer.
+// Tables without audit records are dropped, only the last writer returns the name and size of the database.
+func (w *sqliteWriter) Close(numRecords int64) (name string, size int64) {
+	w.Lock()
+	_ = w.flush() // errors are logged by flush
+	w.Unlock()
+
+	if numRecords == 0 {
+		_, err := w.d.db.Exec("DROP TABLE " + quoteIdent(w.table))
+		if err == nil {
+			_, err = w.d.db.Exec("DELETE FROM "+quoteIdent(sqliteHeaderTable)+" WHERE Type = ?", w.table)
+		}
+
+		if err != nil {
+			ioLog.Error("failed to remove empty table", zap.String("table", w.table), zap.Error(err))
+		}
+	}
+
+	sqliteDBsMu.Lock()
+	defer sqliteDBsMu.Unlock()
+
+	w.d.mu.Lock()
+	defer w.d.mu.Unlock()
+
+	w.d.numRecords += numRecords
+	w.d.refs--
+
+	if w.d.refs > 0 {
+		return "", 0
+	}
+
+	delete(sqliteDBs, w.wc.Out)
+
+	if err := w.d.db.Close(); err != nil {
+		ioLog.Error("failed to close sqlite database", zap.String("path", w.d.path), zap.Error(err))
+	}
+
+	return filepath.Base(w.d.path), removeAuditRecordFileIfEmpty(w.d.path, w.d.numRecords)
+}
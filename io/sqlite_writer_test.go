@@ -0,0 +1,224 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestSQLiteColumns(t *testing.T) {
+	columns := sqliteColumns(reflect.TypeOf(types.HTTP{}), "", nil)
+
+	byName := make(map[string]sqliteColumn)
+	for _, c := range columns {
+		byName[c.name] = c
+	}
+
+	for name, want := range map[string]string{
+		"Timestamp":     "INTEGER",
+		"URL":           "TEXT",
+		"ReqCookies":    "TEXT",
+		"RequestHeader": "TEXT",
+		"RequestBody":   "BLOB",
+	} {
+		if c, ok := byName[name]; !ok || c.sqlType != want {
+			t.Fatal("unexpected column", name, c.sqlType, "expected", want)
+		}
+	}
+
+	if !byName["ReqCookies"].json || !byName["RequestHeader"].json {
+		t.Fatal("expected repeated fields and maps as json")
+	}
+
+	if !byName["Timestamp"].indexed || !byName["SrcIP"].indexed || byName["URL"].indexed {
+		t.Fatal("unexpected indexes")
+	}
+
+	// nested messages are flattened, fields of absent messages are null
+	byName = make(map[string]sqliteColumn)
+	for _, c := range sqliteColumns(reflect.TypeOf(types.Dot11{}), "", nil) {
+		byName[c.name] = c
+	}
+
+	tid, ok := byName["QOS_TID"]
+	if !ok {
+		t.Fatal("missing column for nested field")
+	}
+
+	if v, errValue := tid.value(reflect.ValueOf(&types.Dot11{})); errValue != nil || v != nil {
+		t.Fatal("expected null for absent message, got", v, errValue)
+	}
+
+	if v, errValue := tid.value(reflect.ValueOf(&types.Dot11{QOS: &types.Dot11QOS{TID: 5}})); errValue != nil || v != int32(5) {
+		t.Fatal("unexpected value", v, errValue)
+	}
+
+	columns = sqliteColumns(reflect.TypeOf(types.TCP{}), "", nil)
+	for _, c := range columns {
+		if strings.HasPrefix(c.name, "XXX_") {
+			t.Fatal("unexpected column", c.name)
+		}
+	}
+}
+
+func TestSQLiteWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newConfig := func(name string, typ types.Type) *WriterConfig {
+		return &WriterConfig{
+			SQLite:    true,
+			Name:      name,
+			Type:      typ,
+			Out:       dir,
+			Source:    "unit tests",
+			Version:   netcap.Version,
+			StartTime: time.Now(),
+		}
+	}
+
+	var (
+		tcp  = newSQLiteWriter(newConfig("TCP", types.Type_NC_TCP))
+		http = newSQLiteWriter(newConfig("HTTP", types.Type_NC_HTTP))
+		udp  = newSQLiteWriter(newConfig("UDP", types.Type_NC_UDP))
+	)
+
+	for _, w := range []*sqliteWriter{tcp, http, udp} {
+		if err = w.WriteHeader(w.wc.Type); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, r := range tcps {
+		if err = tcp.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = http.Write(&types.HTTP{
+		Timestamp:     1,
+		Method:        "GET",
+		ReqCookies:    []*types.HTTPCookie{{Name: "session", Value: "secret"}},
+		RequestHeader: map[string]string{"Accept": "*/*"},
+		RequestBody:   []byte{0xde, 0xad},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the last writer returns the database
+	if name, _ := tcp.Close(int64(len(tcps))); name != "" {
+		t.Fatal("expected no name from the first writer, got", name)
+	}
+
+	if name, _ := udp.Close(0); name != "" {
+		t.Fatal("expected no name from the second writer, got", name)
+	}
+
+	name, size := http.Close(1)
+	if name != sqliteFileName+sqliteExtension || size == 0 {
+		t.Fatal("unexpected database", name, size)
+	}
+
+	path := filepath.Join(dir, name)
+
+	var buf bytes.Buffer
+
+	err = QuerySQLite(&buf, path, "SELECT SrcIP, COUNT(*) FROM TCP GROUP BY SrcIP ORDER BY SrcIP", DumpConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "SrcIP,COUNT(*)\n172.217.6.163,1\n192.168.1.14,2\n"; buf.String() != expected {
+		t.Fatal("unexpected result", buf.String(), "expected", expected)
+	}
+
+	buf.Reset()
+
+	err = QuerySQLite(&buf, path, "SELECT Method, ReqCookies, RequestHeader, RequestBody FROM HTTP", DumpConfig{JSON: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"Method":"GET","ReqCookies":"[{\"Name\":\"session\",\"Value\":\"secret\"}]","RequestBody":"dead","RequestHeader":"{\"Accept\":\"*/*\"}"}` + "\n"; buf.String() != expected {
+		t.Fatal("unexpected result", buf.String(), "expected", expected)
+	}
+
+	buf.Reset()
+
+	// empty tables are dropped
+	err = QuerySQLite(&buf, path, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name", DumpConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "name\nHTTP\nNetcapHeader\nTCP\n"; buf.String() != expected {
+		t.Fatal("unexpected tables", buf.String(), "expected", expected)
+	}
+}
+
+func TestSQLiteFailedBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := newSQLiteWriter(&WriterConfig{
+		SQLite:    true,
+		Name:      "TCP",
+		Type:      types.Type_NC_TCP,
+		Out:       dir,
+		Source:    "unit tests",
+		Version:   netcap.Version,
+		StartTime: time.Now(),
+	})
+
+	if err = w.Write(tcps[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the batch cannot be inserted without the table
+	if _, err = w.d.db.Exec("DROP TABLE " + quoteIdent(w.table)); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Lock()
+	err = w.flush()
+	w.Unlock()
+
+	if err == nil {
+		t.Fatal("expected an error for the missing table")
+	}
+
+	// the failed batch is dropped instead of being retried with every following write
+	if len(w.rows) != 0 {
+		t.Fatal("expected the failed batch to be dropped, got", len(w.rows), "rows")
+	}
+
+	w.Close(0)
+}
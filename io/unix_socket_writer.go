@@ -16,13 +16,10 @@ package io
 import (
 	"bufio"
 	"go.uber.org/zap"
-	"log"
 	"net"
 	"path/filepath"
-	"runtime"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/klauspost/pgzip"
 
 	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/types"
@@ -31,7 +28,7 @@ import (
 // unixSocketWriter is a structure that supports writing CSV audit records to disk.
 type unixSocketWriter struct {
 	bWriter          *bufio.Writer
-	gWriter          *pgzip.Writer
+	zWriter          compressor
 	unixSocketWriter *csvProtoWriter
 
 	conn *net.UnixConn
@@ -56,39 +53,20 @@ func newUnixSocketWriter(wc *WriterConfig) *unixSocketWriter {
 		w.bWriter = bufio.NewWriterSize(w.conn, wc.MemBufferSize)
 
 		if wc.Compress {
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.bWriter, wc.CompressionLevel)
-
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-
-			w.unixSocketWriter = newCSVProtoWriter(w.gWriter, wc.Encode, wc.Label)
+			w.zWriter = mustCompressor(w.bWriter, wc)
+			w.unixSocketWriter = newCSVProtoWriter(w.zWriter, wc.Encode, wc.Label)
 		} else {
 			w.unixSocketWriter = newCSVProtoWriter(w.bWriter, wc.Encode, wc.Label)
 		}
 	} else {
 		if wc.Compress {
-			var errGzipWriter error
-			w.gWriter, errGzipWriter = pgzip.NewWriterLevel(w.conn, wc.CompressionLevel)
-			if errGzipWriter != nil {
-				panic(errGzipWriter)
-			}
-			w.unixSocketWriter = newCSVProtoWriter(w.gWriter, wc.Encode, wc.Label)
+			w.zWriter = mustCompressor(w.conn, wc)
+			w.unixSocketWriter = newCSVProtoWriter(w.zWriter, wc.Encode, wc.Label)
 		} else {
 			w.unixSocketWriter = newCSVProtoWriter(w.conn, wc.Encode, wc.Label)
 		}
 	}
 
-	if w.gWriter != nil {
-		// To get any performance gains, you should at least be compressing more than 1 megabyte of data at the time.
-		// You should at least have a block size of 100k and at least a number of blocks that match the number of cores
-		// you would like to utilize, but about twice the number of blocks would be the best.
-		if err := w.gWriter.SetConcurrency(wc.CompressionBlockSize, runtime.GOMAXPROCS(0)*2); err != nil {
-			log.Fatal("failed to configure compression package: ", err)
-		}
-	}
-
 	return w
 }
 
@@ -111,12 +89,13 @@ func (w *unixSocketWriter) WriteHeader(t types.Type) error {
 // Close flushes and closes the writer and the associated file handles.
 func (w *unixSocketWriter) Close(numRecords int64) (name string, size int64) {
 
-	if w.wc.Buffer {
-		flushWriters(w.bWriter)
+	// the compressor writes into the buffer, close it first
+	if w.wc.Compress {
+		closeCompressors(w.zWriter)
 	}
 
-	if w.wc.Compress {
-		closeGzipWriters(w.gWriter)
+	if w.wc.Buffer {
+		flushWriters(w.bWriter)
 	}
 
 	err := w.conn.Close()
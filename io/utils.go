@@ -95,6 +95,9 @@ type DumpConfig struct {
 	Path          string
 	Separator     string
 	Selection     string
+	From          time.Time
+	To            time.Time
+	Dict          []byte
 	MemBufferSize int
 	JSON          bool
 	Table         bool
@@ -112,7 +115,7 @@ func Dump(w *os.File, c DumpConfig) error {
 	var (
 		isTTY  = terminal.IsTerminal(int(w.Fd())) || c.ForceColors
 		count  = 0
-		r, err = Open(c.Path, c.MemBufferSize)
+		r, err = OpenWithDictionary(c.Path, c.MemBufferSize, c.Dict)
 	)
 
 	if err != nil {
@@ -136,6 +139,12 @@ func Dump(w *os.File, c DumpConfig) error {
 		return errFileHeader
 	}
 
+	if !c.From.IsZero() || !c.To.IsZero() {
+		if err = r.SetTimeRange(c.From, c.To); err != nil {
+			return fmt.Errorf("failed to set time range: %w", err)
+		}
+	}
+
 	var (
 		record = InitRecord(header.Type)
 		// rows for table print
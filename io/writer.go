@@ -39,6 +39,14 @@ func NewAuditRecordWriter(wc *WriterConfig) AuditRecordWriter {
 	switch {
 	case wc.UnixSocket:
 		return newUnixSocketWriter(wc)
+	case wc.Zeek:
+		return newZeekWriter(wc)
+	case wc.EVE:
+		return newEVEWriter(wc)
+	case wc.Parquet:
+		return newParquetWriter(wc)
+	case wc.SQLite:
+		return newSQLiteWriter(wc)
 	case wc.CSV:
 		return newCSVWriter(wc)
 	case wc.Chan:
@@ -51,6 +59,8 @@ func NewAuditRecordWriter(wc *WriterConfig) AuditRecordWriter {
 		return newElasticWriter(wc)
 
 	// proto is the default, so this option should be checked last to allow overwriting it
+	case wc.Proto && wc.Index:
+		return newIndexedWriter(wc)
 	case wc.Proto:
 		return newProtoWriter(wc)
 	default:
@@ -32,6 +32,18 @@ type WriterConfig struct {
 	// JSON writer
 	JSON bool
 
+	// Zeek log writer, writes the Zeek JSON format when combined with JSON
+	Zeek bool
+
+	// Suricata EVE JSON writer
+	EVE bool
+
+	// Apache Parquet writer
+	Parquet bool
+
+	// SQLite writer
+	SQLite bool
+
 	// Channel writer
 	Chan bool
 
@@ -68,6 +80,25 @@ type WriterConfig struct {
 	CompressionBlockSize int
 	CompressionLevel     int
 
+	// CompressionCodec is the codec for compressed output: gzip, zstd or lz4, defaults to gzip
+	CompressionCodec string
+
+	// CompressionDict is a dictionary trained with 'zstd --train' for the zstd codec
+	CompressionDict []byte
+
+	// Index writes protobuf audit records in compressed blocks with an index for time range reads,
+	// the blocks are always compressed with gzip, CompressionCodec and CompressionDict are ignored
+	Index bool
+
+	// IndexBlockSize is the amount of uncompressed data per block in indexed files
+	IndexBlockSize int
+
+	// ParquetCompression is the codec for Parquet pages: none, snappy, gzip or zstd
+	ParquetCompression string
+
+	// ParquetRowGroupSize is the amount of uncompressed data per row group in Parquet files
+	ParquetRowGroupSize int
+
 	// Encode data on the fly
 	Encode bool
 
@@ -0,0 +1,141 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/zeek"
+)
+
+// zeekDir is the directory inside the output directory that holds the Zeek logs.
+const zeekDir = "zeek"
+
+// zeekWriter is a structure that supports writing audit records to disk as Zeek logs.
+type zeekWriter struct {
+	mu      sync.Mutex
+	bWriter *bufio.Writer
+	zWriter compressor
+	encoder *zeek.Encoder
+	log     *zeek.Log
+
+	file *os.File
+	wc   *WriterConfig
+}
+
+// newZeekWriter initializes and configures a new zeekWriter instance.
+// Audit record types without a Zeek equivalent are discarded.
+func newZeekWriter(wc *WriterConfig) AuditRecordWriter {
+	l, ok := zeek.LogFor(wc.Type)
+	if !ok {
+		ioLog.Info("no zeek log for type, discarding audit records", zap.String("type", wc.Type.String()))
+
+		return newNullWriter(wc)
+	}
+
+	w := &zeekWriter{
+		log: l,
+		wc:  wc,
+	}
+
+	if wc.MemBufferSize <= 0 {
+		wc.MemBufferSize = defaults.BufferSize
+	}
+
+	// create file, in a subdirectory to avoid collisions with the netcap logs
+	if err := os.MkdirAll(filepath.Join(wc.Out, zeekDir), defaults.DirectoryPermission); err != nil {
+		panic(err)
+	}
+
+	if wc.Compress {
+		w.file = createFile(filepath.Join(wc.Out, zeekDir, l.Path), ".log"+CompressionExtension(wc.CompressionCodec))
+	} else {
+		w.file = createFile(filepath.Join(wc.Out, zeekDir, l.Path), ".log")
+	}
+	ioLog.Info("create zeekWriter", zap.String("base", filepath.Join(wc.Out, zeekDir, l.Path)), zap.String("type", wc.Type.String()))
+
+	var out io.Writer = w.file
+
+	if wc.Buffer {
+		w.bWriter = bufio.NewWriterSize(w.file, wc.MemBufferSize)
+		out = w.bWriter
+	}
+
+	if wc.Compress {
+		w.zWriter = mustCompressor(out, wc)
+
+		out = w.zWriter
+	}
+
+	w.encoder = zeek.NewEncoder(out, l, wc.JSON)
+
+	return w
+}
+
+// Write writes an audit record as a log entry.
+func (w *zeekWriter) Write(msg proto.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	r := w.log.Record(msg)
+	if r == nil {
+		// not representable in the log, for example a connection without IP layer
+		return nil
+	}
+
+	return w.encoder.Encode(r)
+}
+
+// WriteHeader writes the header of the Zeek log.
+func (w *zeekWriter) WriteHeader(t types.Type) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	open := w.wc.StartTime
+	if open.IsZero() {
+		open = time.Now()
+	}
+
+	return w.encoder.WriteHeader(open)
+}
+
+// Close flushes and closes the writer and the associated file handles.
+func (w *zeekWriter) Close(numRecords int64) (name string, size int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.encoder.WriteFooter(time.Now()); err != nil {
+		ioLog.Error("failed to write zeek log footer", zap.Error(err))
+	}
+
+	if w.wc.Compress {
+		closeCompressors(w.zWriter)
+	}
+
+	if w.wc.Buffer {
+		flushWriters(w.bWriter)
+	}
+
+	return closeFile(filepath.Join(w.wc.Out, zeekDir), w.file, w.log.Path, numRecords)
+}
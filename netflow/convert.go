@@ -0,0 +1,224 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// TCP flag bits as reported in the tcpControlBits information element.
+const (
+	tcpFlagFIN = 1 << iota
+	tcpFlagSYN
+	tcpFlagRST
+	tcpFlagPSH
+	tcpFlagACK
+	tcpFlagURG
+	tcpFlagECE
+	tcpFlagCWR
+)
+
+// Ident returns the flow identifier in the format used by the other netcap audit records.
+func (fl *Flow) Ident() string {
+	return utils.CreateFlowIdent(
+		fl.SrcIP.String(),
+		strconv.Itoa(int(fl.SrcPort)),
+		fl.DstIP.String(),
+		strconv.Itoa(int(fl.DstPort)),
+		"",
+	)
+}
+
+// uid identifies the flow record of an exporter, since the same five tuple
+// is reported again for every active timeout of a long running flow.
+func (fl *Flow) uid() string {
+	sum := md5.Sum([]byte(fl.Exporter + "/" + strconv.Itoa(int(fl.ObservationDomain)) + "/" + fl.Ident() + "/" + strconv.FormatInt(fl.Start.UnixNano(), 10)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (fl *Flow) networkProto() string {
+	if fl.SrcIP.To4() != nil {
+		return layers.LayerTypeIPv4.String()
+	}
+
+	return layers.LayerTypeIPv6.String()
+}
+
+func (fl *Flow) transportProto() string {
+	return layers.IPProtocol(fl.Protocol).String()
+}
+
+// Connection converts the flow into a connection audit record.
+// Since exporters only report the union of all TCP flags seen for a flow,
+// the flag counters are set to one for each flag that was present.
+func (fl *Flow) Connection() *types.Connection {
+	c := &types.Connection{
+		UID:                 fl.uid(),
+		TimestampFirst:      fl.Start.UnixNano(),
+		TimestampLast:       fl.End.UnixNano(),
+		Duration:            fl.End.Sub(fl.Start).Nanoseconds(),
+		NetworkProto:        fl.networkProto(),
+		TransportProto:      fl.transportProto(),
+		SrcIP:               fl.SrcIP.String(),
+		DstIP:               fl.DstIP.String(),
+		SrcPort:             strconv.Itoa(int(fl.SrcPort)),
+		DstPort:             strconv.Itoa(int(fl.DstPort)),
+		TotalSize:           int32(fl.Bytes),
+		NumPackets:          int32(fl.Packets),
+		BytesClientToServer: int64(fl.Bytes),
+	}
+
+	if len(fl.SrcMAC) != 0 || len(fl.DstMAC) != 0 {
+		c.LinkProto = layers.LayerTypeEthernet.String()
+		c.SrcMAC = fl.SrcMAC.String()
+		c.DstMAC = fl.DstMAC.String()
+	}
+
+	if fl.Protocol == uint8(layers.IPProtocolTCP) {
+		for _, f := range []struct {
+			bit     uint8
+			counter *int32
+		}{
+			{tcpFlagFIN, &c.NumFINFlags},
+			{tcpFlagSYN, &c.NumSYNFlags},
+			{tcpFlagRST, &c.NumRSTFlags},
+			{tcpFlagPSH, &c.NumPSHFlags},
+			{tcpFlagACK, &c.NumACKFlags},
+			{tcpFlagURG, &c.NumURGFlags},
+			{tcpFlagECE, &c.NumECEFlags},
+			{tcpFlagCWR, &c.NumCWRFlags},
+		} {
+			if fl.TCPFlags&f.bit != 0 {
+				*f.counter = 1
+			}
+		}
+	}
+
+	return c
+}
+
+// Profiles aggregates flow records into IPProfiles.
+type Profiles struct {
+	sync.Mutex
+	items map[string]*types.IPProfile
+}
+
+// NewProfiles returns an empty profile store.
+func NewProfiles() *Profiles {
+	return &Profiles{
+		items: make(map[string]*types.IPProfile),
+	}
+}
+
+// Update adds the flow to the profiles of its source and destination address.
+func (p *Profiles) Update(fl *Flow) {
+	if fl.SrcIP == nil || fl.DstIP == nil {
+		return
+	}
+
+	var (
+		proto   = fl.transportProto()
+		srcPort = int32(fl.SrcPort)
+		dstPort = int32(fl.DstPort)
+	)
+
+	p.Lock()
+	defer p.Unlock()
+
+	src := p.get(fl.SrcIP.String(), fl)
+	src.SrcPorts = addPort(src.SrcPorts, srcPort, proto, fl)
+	src.ContactedPorts = addPort(src.ContactedPorts, dstPort, proto, fl)
+
+	dst := p.get(fl.DstIP.String(), fl)
+	dst.DstPorts = addPort(dst.DstPorts, dstPort, proto, fl)
+	dst.ContactedPorts = addPort(dst.ContactedPorts, srcPort, proto, fl)
+}
+
+// get returns the profile for the address and adds the counters and timestamps of the flow.
+func (p *Profiles) get(addr string, fl *Flow) *types.IPProfile {
+	var (
+		first = fl.Start.UnixNano()
+		last  = fl.End.UnixNano()
+	)
+
+	prof, ok := p.items[addr]
+	if !ok {
+		prof = &types.IPProfile{
+			Addr:           addr,
+			TimestampFirst: first,
+			TimestampLast:  last,
+		}
+		p.items[addr] = prof
+	}
+
+	prof.NumPackets += int64(fl.Packets)
+	prof.Bytes += fl.Bytes
+
+	if first < prof.TimestampFirst {
+		prof.TimestampFirst = first
+	}
+
+	if last > prof.TimestampLast {
+		prof.TimestampLast = last
+	}
+
+	return prof
+}
+
+// Items returns all profiles ordered by address.
+func (p *Profiles) Items() []*types.IPProfile {
+	p.Lock()
+	defer p.Unlock()
+
+	items := make([]*types.IPProfile, 0, len(p.items))
+	for _, prof := range p.items {
+		items = append(items, prof)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Addr < items[j].Addr
+	})
+
+	return items
+}
+
+func addPort(ports []*types.Port, num int32, proto string, fl *Flow) []*types.Port {
+	for _, port := range ports {
+		if port.PortNumber == num && port.Protocol == proto {
+			port.Stats.Packets += fl.Packets
+			port.Stats.Bytes += fl.Bytes
+
+			return ports
+		}
+	}
+
+	return append(ports, &types.Port{
+		PortNumber: num,
+		Protocol:   proto,
+		Stats: &types.PortStats{
+			Packets: fl.Packets,
+			Bytes:   fl.Bytes,
+		},
+	})
+}
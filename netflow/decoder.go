@@ -0,0 +1,391 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// Decoder decodes export packets and keeps track of the templates and sampling parameters announced by the exporters.
+// It is safe for concurrent use, but the packets of a single exporter must be passed in the order they were received.
+type Decoder struct {
+	sync.Mutex
+
+	// ScaleSampled multiplies the packet and byte counters of sampled flows with their sampling interval.
+	ScaleSampled bool
+
+	templates  map[templateKey]*template
+	sampling   map[domainKey]uint32
+	samplers   map[samplerKey]uint32
+	systemInit map[domainKey]time.Time
+
+	// statistics
+	numPackets         int64
+	numFlows           int64
+	numMissingTemplate int64
+}
+
+// NewDecoder returns a new Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		ScaleSampled: true,
+		templates:    make(map[templateKey]*template),
+		sampling:     make(map[domainKey]uint32),
+		samplers:     make(map[samplerKey]uint32),
+		systemInit:   make(map[domainKey]time.Time),
+	}
+}
+
+// Stats returns the number of decoded packets and flows,
+// and the number of data sets that were skipped because their template was not yet known.
+func (d *Decoder) Stats() (packets, flows, missingTemplates int64) {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.numPackets, d.numFlows, d.numMissingTemplate
+}
+
+// Decode parses an export packet received from the given exporter and returns the contained flow records.
+func (d *Decoder) Decode(data []byte, exporter string) ([]*Flow, error) {
+	if len(data) < 2 {
+		return nil, ErrShortPacket
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	var (
+		flows []*Flow
+		err   error
+	)
+
+	switch binary.BigEndian.Uint16(data) {
+	case VersionNetflow5:
+		flows, err = d.decodeV5(data, exporter)
+	case VersionNetflow9:
+		flows, err = d.decodeV9(data, exporter)
+	case VersionIPFIX:
+		flows, err = d.decodeIPFIX(data, exporter)
+	default:
+		return nil, ErrUnsupportedVersion
+	}
+
+	d.numPackets++
+	d.numFlows += int64(len(flows))
+
+	return flows, err
+}
+
+// exportContext holds the header information needed to resolve the records of a packet.
+type exportContext struct {
+	version    uint16
+	domain     domainKey
+	exportTime time.Time
+
+	// NetFlow v9 only
+	sysUptime    uint32
+	hasSysUptime bool
+}
+
+// decodeDataSet decodes the records of a data set according to the template with the given ID.
+func (d *Decoder) decodeDataSet(ctx *exportContext, id uint16, data []byte) ([]*Flow, error) {
+	t, ok := d.templates[templateKey{exporter: ctx.domain.exporter, domain: ctx.domain.domain, id: id}]
+	if !ok {
+		d.numMissingTemplate++
+
+		return nil, nil
+	}
+
+	// the minimum record length is used to detect the padding at the end of the set
+	var minLength int
+	for _, f := range t.fields {
+		if f.length == variableLength {
+			minLength++
+		} else {
+			minLength += int(f.length)
+		}
+	}
+
+	if minLength == 0 {
+		return nil, ErrInvalidSet
+	}
+
+	var flows []*Flow
+
+	for len(data) >= minLength {
+		var (
+			fl     = &Flow{}
+			values = make(map[uint16][]byte, len(t.fields))
+		)
+
+		n, err := readRecord(t, data, func(f field, value []byte) {
+			if t.options {
+				if f.enterprise == 0 {
+					values[f.id] = value
+				}
+
+				return
+			}
+
+			fl.set(f, value)
+		})
+		if err != nil {
+			return flows, err
+		}
+
+		data = data[n:]
+
+		if t.options {
+			d.applyOptions(ctx, values)
+
+			continue
+		}
+
+		d.resolve(ctx, fl)
+		flows = append(flows, fl)
+	}
+
+	return flows, nil
+}
+
+// readRecord calls fn for each field of the record at the start of data
+// and returns the number of bytes consumed.
+func readRecord(t *template, data []byte, fn func(f field, value []byte)) (int, error) {
+	var off int
+
+	for _, f := range t.fields {
+		l := int(f.length)
+
+		if f.length == variableLength {
+			if off >= len(data) {
+				return 0, ErrShortPacket
+			}
+
+			l = int(data[off])
+			off++
+
+			if l == 255 {
+				if off+2 > len(data) {
+					return 0, ErrShortPacket
+				}
+
+				l = int(binary.BigEndian.Uint16(data[off:]))
+				off += 2
+			}
+		}
+
+		if off+l > len(data) {
+			return 0, ErrShortPacket
+		}
+
+		fn(f, data[off:off+l])
+		off += l
+	}
+
+	return off, nil
+}
+
+// applyOptions stores the sampling parameters and system init time announced in an options record.
+func (d *Decoder) applyOptions(ctx *exportContext, values map[uint16][]byte) {
+	if v, ok := values[fieldSystemInitTimeMilliseconds]; ok {
+		d.systemInit[ctx.domain] = time.Unix(0, int64(uintValue(v))*int64(time.Millisecond))
+	}
+
+	var interval uint32
+
+	if v, ok := values[fieldSamplingInterval]; ok {
+		interval = uint32(uintValue(v))
+	} else if v, ok = values[fieldSamplerRandomInterval]; ok {
+		interval = uint32(uintValue(v))
+	} else if v, ok = values[fieldSamplingPacketInterval]; ok {
+		// n out of n+m packets are selected (RFC 5477)
+		n := uintValue(v)
+		if space, exists := values[fieldSamplingPacketSpace]; exists && n != 0 {
+			interval = uint32((n + uintValue(space)) / n)
+		}
+	}
+
+	if interval == 0 {
+		return
+	}
+
+	for _, id := range []uint16{fieldSamplerID, fieldSelectorID} {
+		if v, ok := values[id]; ok {
+			d.samplers[samplerKey{domainKey: ctx.domain, id: uintValue(v)}] = interval
+
+			return
+		}
+	}
+
+	d.sampling[ctx.domain] = interval
+}
+
+// resolve computes the absolute timestamps of the flow and applies the sampling interval.
+func (d *Decoder) resolve(ctx *exportContext, fl *Flow) {
+	fl.Exporter = ctx.domain.exporter
+	fl.ObservationDomain = ctx.domain.domain
+	fl.Version = ctx.version
+
+	if fl.Start.IsZero() && fl.hasUptime {
+		var boot time.Time
+
+		switch {
+		case ctx.hasSysUptime:
+			boot = ctx.exportTime.Add(-time.Duration(ctx.sysUptime) * time.Millisecond)
+		case !fl.systemInit.IsZero():
+			boot = fl.systemInit
+		default:
+			boot = d.systemInit[ctx.domain]
+		}
+
+		if !boot.IsZero() {
+			fl.Start = boot.Add(time.Duration(fl.startUptime) * time.Millisecond)
+			fl.End = boot.Add(time.Duration(fl.endUptime) * time.Millisecond)
+		}
+	}
+
+	if fl.Start.IsZero() {
+		fl.Start = ctx.exportTime
+	}
+
+	if fl.End.IsZero() || fl.End.Before(fl.Start) {
+		fl.End = fl.Start
+	}
+
+	if fl.SamplingInterval == 0 {
+		if fl.hasSamplerID {
+			fl.SamplingInterval = d.samplers[samplerKey{domainKey: ctx.domain, id: fl.samplerID}]
+		}
+
+		if fl.SamplingInterval == 0 {
+			fl.SamplingInterval = d.sampling[ctx.domain]
+		}
+	}
+
+	if d.ScaleSampled && fl.SamplingInterval > 1 {
+		fl.Packets *= uint64(fl.SamplingInterval)
+		fl.Bytes *= uint64(fl.SamplingInterval)
+	}
+}
+
+// set applies the value of a data record field to the flow.
+func (fl *Flow) set(f field, v []byte) {
+	if f.enterprise != 0 {
+		return
+	}
+
+	switch f.id {
+	case fieldOctetDeltaCount:
+		fl.Bytes = uintValue(v)
+	case fieldOctetTotalCount:
+		if fl.Bytes == 0 {
+			fl.Bytes = uintValue(v)
+		}
+	case fieldPacketDeltaCount:
+		fl.Packets = uintValue(v)
+	case fieldPacketTotalCount:
+		if fl.Packets == 0 {
+			fl.Packets = uintValue(v)
+		}
+	case fieldProtocolIdentifier:
+		fl.Protocol = uint8(uintValue(v))
+	case fieldTCPControlBits:
+		fl.TCPFlags = uint8(uintValue(v))
+	case fieldSourceTransportPort:
+		fl.SrcPort = uint16(uintValue(v))
+	case fieldDestinationTransportPort:
+		fl.DstPort = uint16(uintValue(v))
+	case fieldSourceIPv4Address, fieldSourceIPv6Address:
+		fl.SrcIP = ipValue(v)
+	case fieldDestinationIPv4Address, fieldDestinationIPv6Address:
+		fl.DstIP = ipValue(v)
+	case fieldSourceMacAddress, fieldPostSourceMacAddress:
+		if len(fl.SrcMAC) == 0 && len(v) == 6 {
+			fl.SrcMAC = append(fl.SrcMAC, v...)
+		}
+	case fieldDestinationMacAddress, fieldPostDestinationMacAddress:
+		if len(fl.DstMAC) == 0 && len(v) == 6 {
+			fl.DstMAC = append(fl.DstMAC, v...)
+		}
+	case fieldFlowStartSysUpTime:
+		fl.startUptime = uint32(uintValue(v))
+		fl.hasUptime = true
+	case fieldFlowEndSysUpTime:
+		fl.endUptime = uint32(uintValue(v))
+		fl.hasUptime = true
+	case fieldFlowStartSeconds:
+		fl.Start = time.Unix(int64(uintValue(v)), 0)
+	case fieldFlowEndSeconds:
+		fl.End = time.Unix(int64(uintValue(v)), 0)
+	case fieldFlowStartMilliseconds:
+		fl.Start = time.Unix(0, int64(uintValue(v))*int64(time.Millisecond))
+	case fieldFlowEndMilliseconds:
+		fl.End = time.Unix(0, int64(uintValue(v))*int64(time.Millisecond))
+	case fieldFlowStartMicroseconds, fieldFlowStartNanoseconds:
+		fl.Start = ntpValue(v)
+	case fieldFlowEndMicroseconds, fieldFlowEndNanoseconds:
+		fl.End = ntpValue(v)
+	case fieldSystemInitTimeMilliseconds:
+		fl.systemInit = time.Unix(0, int64(uintValue(v))*int64(time.Millisecond))
+	case fieldSamplingInterval, fieldSamplerRandomInterval:
+		fl.SamplingInterval = uint32(uintValue(v))
+	case fieldSamplerID, fieldSelectorID:
+		fl.samplerID = uintValue(v)
+		fl.hasSamplerID = true
+	}
+}
+
+// uintValue decodes an unsigned integer, exporters may use reduced-size encoding (RFC 7011 section 6.2).
+func uintValue(v []byte) uint64 {
+	var n uint64
+
+	for i, b := range v {
+		if i == 8 {
+			break
+		}
+
+		n = n<<8 | uint64(b)
+	}
+
+	return n
+}
+
+// ipValue copies an IPv4 or IPv6 address.
+func ipValue(v []byte) net.IP {
+	if len(v) != 4 && len(v) != 16 {
+		return nil
+	}
+
+	return append([]byte(nil), v...)
+}
+
+// seconds between the NTP epoch (1900) and the unix epoch.
+const ntpEpochOffset = 2208988800
+
+// ntpValue decodes the NTP timestamp format used for microsecond and nanosecond timestamps (RFC 7011 section 6.1.10).
+func ntpValue(v []byte) time.Time {
+	if len(v) != 8 {
+		return time.Time{}
+	}
+
+	var (
+		secs     = int64(binary.BigEndian.Uint32(v)) - ntpEpochOffset
+		fraction = uint64(binary.BigEndian.Uint32(v[4:]))
+	)
+
+	return time.Unix(secs, int64((fraction*uint64(time.Second))>>32))
+}
@@ -0,0 +1,568 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// Enterprise specific information elements for the netcap extensions of the exported flows.
+const (
+	// DefaultEnterpriseID is the private enterprise number of the netcap information elements.
+	// The number is reserved for documentation (RFC 5612), collectors have to be configured with it
+	// in order to decode the extensions.
+	DefaultEnterpriseID = 32473
+
+	fieldNetcapApplicationProto = 1
+	fieldNetcapJA3              = 2
+	fieldNetcapCommunityID      = 3
+)
+
+const (
+	// DefaultMaxMessageSize keeps the exported messages below the common path MTU.
+	DefaultMaxMessageSize = 1400
+
+	// DefaultTemplateRefresh is the interval for sending the templates again,
+	// collectors that were started after the exporter need them to decode the data sets.
+	DefaultTemplateRefresh = time.Minute
+
+	// DefaultActiveTimeout is the maximum time a record is buffered before it is sent.
+	DefaultActiveTimeout = 5 * time.Second
+
+	templateIDv4 = 256
+	templateIDv6 = 257
+
+	tcpFlagNS = 0x100
+)
+
+// ErrMessageSize is returned when a single record does not fit into the configured message size.
+var ErrMessageSize = errors.New("netflow: record exceeds maximum message size")
+
+// protocolNumbers maps the transport protocol names of the audit records to their IP protocol number.
+var protocolNumbers = make(map[string]uint8)
+
+func init() {
+	for i := 255; i >= 0; i-- {
+		name := layers.IPProtocol(i).String()
+		if !strings.HasPrefix(name, "Unknown") {
+			protocolNumbers[name] = uint8(i)
+		}
+	}
+}
+
+// Exporter encodes Connection audit records as IPFIX (RFC 7011) and sends them to a collector.
+// Records are batched into messages of at most MaxMessageSize bytes.
+// After Start, pending records are sent every ActiveTimeout and the templates every TemplateRefresh,
+// otherwise call Flush or Close to send the remaining records.
+type Exporter struct {
+	sync.Mutex
+
+	// ObservationDomain is announced in the header of each message.
+	ObservationDomain uint32
+
+	// EnterpriseID is used for the netcap specific information elements.
+	EnterpriseID uint32
+
+	// MaxMessageSize limits the size of a single message.
+	MaxMessageSize int
+
+	// TemplateRefresh is the interval after which the templates are sent again.
+	TemplateRefresh time.Duration
+
+	// ActiveTimeout is the maximum time a record is buffered before it is sent, once the exporter has been started.
+	ActiveTimeout time.Duration
+
+	w             io.Writer
+	templates     []byte
+	lastTemplates time.Time
+	sequence      uint32
+
+	// pending data sets for IPv4 and IPv6 connections
+	sets       [2][]byte
+	numPending uint32
+	numSent    int64
+
+	// stops the timers started by Start
+	stop    chan struct{}
+	stopped chan struct{}
+
+	// first error of a message sent by the timers, returned by Flush and Close
+	err error
+}
+
+// NewExporter returns an exporter sending the IPFIX messages via UDP to the collector at addr.
+func NewExporter(addr string) (*Exporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newExporter(conn), nil
+}
+
+func newExporter(w io.Writer) *Exporter {
+	return &Exporter{
+		EnterpriseID:    DefaultEnterpriseID,
+		MaxMessageSize:  DefaultMaxMessageSize,
+		TemplateRefresh: DefaultTemplateRefresh,
+		ActiveTimeout:   DefaultActiveTimeout,
+		w:               w,
+	}
+}
+
+// NumExported returns the number of records that have been sent.
+func (e *Exporter) NumExported() int64 {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.numSent
+}
+
+// Export adds the connection to the next message, ja3 is the fingerprint of the client hello if the connection used TLS.
+// Connections without IP addresses can not be represented and are skipped.
+func (e *Exporter) Export(c *types.Connection, ja3 string) error {
+	var (
+		srcIP = net.ParseIP(c.SrcIP)
+		dstIP = net.ParseIP(c.DstIP)
+	)
+
+	if srcIP == nil || dstIP == nil {
+		return nil
+	}
+
+	var (
+		v4     = srcIP.To4() != nil && dstIP.To4() != nil
+		record = encodeRecord(c, srcIP, dstIP, v4, ja3)
+		idx    = 1
+	)
+
+	if v4 {
+		idx = 0
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	size := len(record)
+	if len(e.sets[idx]) == 0 {
+		size += 4
+	}
+
+	if e.messageSize()+size > e.MaxMessageSize {
+		if err := e.flush(); err != nil {
+			return err
+		}
+
+		if e.messageSize()+len(record)+4 > e.MaxMessageSize {
+			return ErrMessageSize
+		}
+	}
+
+	e.sets[idx] = append(e.sets[idx], record...)
+	e.numPending++
+
+	return nil
+}
+
+// Start sends the pending records every ActiveTimeout and the templates every TemplateRefresh in the background,
+// so that records of a low traffic network are not held back and collectors started later receive the templates.
+// The timers are stopped by Close.
+func (e *Exporter) Start() {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.stop != nil {
+		return
+	}
+
+	if e.ActiveTimeout <= 0 {
+		e.ActiveTimeout = DefaultActiveTimeout
+	}
+
+	if e.TemplateRefresh <= 0 {
+		e.TemplateRefresh = DefaultTemplateRefresh
+	}
+
+	e.stop = make(chan struct{})
+	e.stopped = make(chan struct{})
+
+	go e.run(e.ActiveTimeout, e.TemplateRefresh)
+}
+
+// run sends the pending records and templates until the exporter is closed.
+func (e *Exporter) run(activeTimeout, templateRefresh time.Duration) {
+	defer close(e.stopped)
+
+	var (
+		flushTicker    = time.NewTicker(activeTimeout)
+		templateTicker = time.NewTicker(templateRefresh)
+	)
+
+	defer flushTicker.Stop()
+	defer templateTicker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-e.stop:
+			return
+		case <-flushTicker.C:
+			e.Lock()
+			err = e.flush()
+		case now := <-templateTicker.C:
+			e.Lock()
+			// the templates are part of the data messages as long as records are sent regularly
+			if now.Sub(e.lastTemplates) >= templateRefresh {
+				err = e.sendTemplates()
+			}
+		}
+
+		if err != nil && e.err == nil {
+			e.err = err
+		}
+
+		e.Unlock()
+	}
+}
+
+// Flush sends the pending records.
+// It returns the first error that occurred while sending records in the background.
+func (e *Exporter) Flush() error {
+	e.Lock()
+	defer e.Unlock()
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	return e.err
+}
+
+// Close stops the timers, sends the pending records and closes the underlying connection.
+func (e *Exporter) Close() error {
+	e.Lock()
+	if e.stop != nil {
+		close(e.stop)
+		e.Unlock()
+		<-e.stopped
+		e.Lock()
+
+		e.stop = nil
+	}
+	defer e.Unlock()
+
+	err := e.flush()
+	if err == nil {
+		err = e.err
+	}
+
+	if c, ok := e.w.(io.Closer); ok {
+		if errClose := c.Close(); err == nil {
+			err = errClose
+		}
+	}
+
+	return err
+}
+
+// messageSize returns the size of a message with the pending records,
+// the templates are always accounted for since they could be due when the message is sent.
+func (e *Exporter) messageSize() int {
+	size := ipfixHeaderLength + len(e.templateSet())
+
+	for _, s := range e.sets {
+		if len(s) > 0 {
+			size += 4 + len(s)
+		}
+	}
+
+	return size
+}
+
+// newMessage returns a message with the header filled in, except for the length.
+func (e *Exporter) newMessage(now time.Time) []byte {
+	msg := make([]byte, ipfixHeaderLength, e.messageSize())
+	binary.BigEndian.PutUint16(msg, VersionIPFIX)
+	binary.BigEndian.PutUint32(msg[4:], uint32(now.Unix()))
+	binary.BigEndian.PutUint32(msg[8:], e.sequence)
+	binary.BigEndian.PutUint32(msg[12:], e.ObservationDomain)
+
+	return msg
+}
+
+// sendTemplates sends a message that only contains the templates.
+func (e *Exporter) sendTemplates() error {
+	now := time.Now()
+
+	msg := append(e.newMessage(now), e.templateSet()...)
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+
+	if _, err := e.w.Write(msg); err != nil {
+		return err
+	}
+
+	e.lastTemplates = now
+
+	return nil
+}
+
+func (e *Exporter) flush() error {
+	if e.numPending == 0 {
+		return nil
+	}
+
+	var (
+		now = time.Now()
+		msg = e.newMessage(now)
+	)
+
+	withTemplates := e.lastTemplates.IsZero() || now.Sub(e.lastTemplates) >= e.TemplateRefresh
+	if withTemplates {
+		msg = append(msg, e.templateSet()...)
+	}
+
+	for i, s := range e.sets {
+		if len(s) == 0 {
+			continue
+		}
+
+		id := uint16(templateIDv4)
+		if i == 1 {
+			id = templateIDv6
+		}
+
+		msg = appendSetHeader(msg, id, len(s))
+		msg = append(msg, s...)
+	}
+
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+
+	if _, err := e.w.Write(msg); err != nil {
+		return err
+	}
+
+	if withTemplates {
+		e.lastTemplates = now
+	}
+
+	// the sequence number counts the data records sent before the current message
+	e.sequence += e.numPending
+	e.numSent += int64(e.numPending)
+	e.numPending = 0
+	e.sets[0] = e.sets[0][:0]
+	e.sets[1] = e.sets[1][:0]
+
+	return nil
+}
+
+// exportFields returns the field specifiers of the exported records.
+func (e *Exporter) exportFields(v4 bool) []field {
+	var (
+		srcIP = field{id: fieldSourceIPv6Address, length: net.IPv6len}
+		dstIP = field{id: fieldDestinationIPv6Address, length: net.IPv6len}
+	)
+
+	if v4 {
+		srcIP = field{id: fieldSourceIPv4Address, length: net.IPv4len}
+		dstIP = field{id: fieldDestinationIPv4Address, length: net.IPv4len}
+	}
+
+	return []field{
+		{id: fieldFlowStartMilliseconds, length: 8},
+		{id: fieldFlowEndMilliseconds, length: 8},
+		srcIP,
+		dstIP,
+		{id: fieldSourceTransportPort, length: 2},
+		{id: fieldDestinationTransportPort, length: 2},
+		{id: fieldProtocolIdentifier, length: 1},
+		{id: fieldTCPControlBits, length: 2},
+		{id: fieldOctetDeltaCount, length: 8},
+		{id: fieldPacketDeltaCount, length: 8},
+		{id: fieldSourceMacAddress, length: 6},
+		{id: fieldDestinationMacAddress, length: 6},
+		{id: fieldNetcapApplicationProto, length: variableLength, enterprise: e.EnterpriseID},
+		{id: fieldNetcapJA3, length: variableLength, enterprise: e.EnterpriseID},
+		{id: fieldNetcapCommunityID, length: variableLength, enterprise: e.EnterpriseID},
+	}
+}
+
+// templateSet returns the encoded template set for the IPv4 and IPv6 records.
+func (e *Exporter) templateSet() []byte {
+	if e.templates != nil {
+		return e.templates
+	}
+
+	var b []byte
+
+	for _, t := range []struct {
+		id uint16
+		v4 bool
+	}{
+		{templateIDv4, true},
+		{templateIDv6, false},
+	} {
+		fields := e.exportFields(t.v4)
+
+		b = appendUint16(b, t.id)
+		b = appendUint16(b, uint16(len(fields)))
+
+		for _, f := range fields {
+			if f.enterprise == 0 {
+				b = appendUint16(b, f.id)
+				b = appendUint16(b, f.length)
+
+				continue
+			}
+
+			b = appendUint16(b, f.id|enterpriseBit)
+			b = appendUint16(b, f.length)
+			b = appendUint32(b, f.enterprise)
+		}
+	}
+
+	e.templates = append(appendSetHeader(nil, ipfixTemplateSet, len(b)), b...)
+
+	return e.templates
+}
+
+// encodeRecord encodes a connection in the layout of the export template.
+func encodeRecord(c *types.Connection, srcIP, dstIP net.IP, v4 bool, ja3 string) []byte {
+	if v4 {
+		srcIP, dstIP = srcIP.To4(), dstIP.To4()
+	} else {
+		srcIP, dstIP = srcIP.To16(), dstIP.To16()
+	}
+
+	var (
+		proto   = protocolNumbers[c.TransportProto]
+		srcPort = portValue(c.SrcPort)
+		dstPort = portValue(c.DstPort)
+		b       = make([]byte, 0, 128)
+	)
+
+	b = appendUint64(b, uint64(c.TimestampFirst/int64(time.Millisecond)))
+	b = appendUint64(b, uint64(c.TimestampLast/int64(time.Millisecond)))
+	b = append(b, srcIP...)
+	b = append(b, dstIP...)
+	b = appendUint16(b, srcPort)
+	b = appendUint16(b, dstPort)
+	b = append(b, proto)
+	b = appendUint16(b, tcpControlBits(c))
+	b = appendUint64(b, uint64(c.TotalSize))
+	b = appendUint64(b, uint64(c.NumPackets))
+	b = append(b, macValue(c.SrcMAC)...)
+	b = append(b, macValue(c.DstMAC)...)
+	b = appendString(b, c.ApplicationProto)
+	b = appendString(b, ja3)
+
+	// ICMP type and code are not part of the audit record, so no community ID can be calculated
+	var communityID string
+	if proto != 0 && proto != uint8(layers.IPProtocolICMPv4) && proto != uint8(layers.IPProtocolICMPv6) {
+		communityID = utils.CommunityID(0, srcIP, dstIP, srcPort, dstPort, proto)
+	}
+
+	return appendString(b, communityID)
+}
+
+// tcpControlBits returns the union of the TCP flags seen for the connection.
+func tcpControlBits(c *types.Connection) uint16 {
+	var bits uint16
+
+	for _, f := range []struct {
+		bit     uint16
+		counter int32
+	}{
+		{tcpFlagFIN, c.NumFINFlags},
+		{tcpFlagSYN, c.NumSYNFlags},
+		{tcpFlagRST, c.NumRSTFlags},
+		{tcpFlagPSH, c.NumPSHFlags},
+		{tcpFlagACK, c.NumACKFlags},
+		{tcpFlagURG, c.NumURGFlags},
+		{tcpFlagECE, c.NumECEFlags},
+		{tcpFlagCWR, c.NumCWRFlags},
+		{tcpFlagNS, c.NumNSFlags},
+	} {
+		if f.counter > 0 {
+			bits |= f.bit
+		}
+	}
+
+	return bits
+}
+
+func portValue(port string) uint16 {
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return 0
+	}
+
+	return uint16(p)
+}
+
+// macValue returns the hardware address, or zeros if it is not an ethernet address.
+func macValue(addr string) []byte {
+	mac, err := net.ParseMAC(addr)
+	if err != nil || len(mac) != 6 {
+		return make([]byte, 6)
+	}
+
+	return mac
+}
+
+func appendSetHeader(b []byte, id uint16, length int) []byte {
+	b = appendUint16(b, id)
+
+	return appendUint16(b, uint16(4+length))
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
+
+// appendString encodes a variable length field (RFC 7011 section 7).
+func appendString(b []byte, s string) []byte {
+	if len(s) > 65535 {
+		s = s[:65535]
+	}
+
+	if len(s) < 255 {
+		b = append(b, byte(len(s)))
+	} else {
+		b = append(b, 255)
+		b = appendUint16(b, uint16(len(s)))
+	}
+
+	return append(b, s...)
+}
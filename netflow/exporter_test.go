@@ -0,0 +1,269 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// messages collects the messages written by the exporter.
+type messages [][]byte
+
+func (m *messages) Write(b []byte) (int, error) {
+	*m = append(*m, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestExportIPFIX(t *testing.T) {
+	var (
+		out   messages
+		e     = newExporter(&out)
+		start = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		tcp   = &types.Connection{
+			TimestampFirst:   start.UnixNano(),
+			TimestampLast:    start.Add(2 * time.Second).UnixNano(),
+			TransportProto:   "TCP",
+			ApplicationProto: "TLS",
+			SrcMAC:           "00:11:22:33:44:55",
+			DstMAC:           "66:77:88:99:aa:bb",
+			SrcIP:            "192.168.1.10",
+			DstIP:            "93.184.216.34",
+			SrcPort:          "51234",
+			DstPort:          "443",
+			TotalSize:        4200,
+			NumPackets:       12,
+			NumSYNFlags:      2,
+			NumACKFlags:      11,
+		}
+		udp = &types.Connection{
+			TimestampFirst: start.UnixNano(),
+			TimestampLast:  start.UnixNano(),
+			TransportProto: "UDP",
+			SrcIP:          "fe80::1",
+			DstIP:          "ff02::fb",
+			SrcPort:        "5353",
+			DstPort:        "5353",
+			TotalSize:      120,
+			NumPackets:     1,
+		}
+		ja3 = "e7d705a3286e19ea42f587b344ee6865"
+	)
+
+	for _, c := range []*types.Connection{tcp, udp, {SrcMAC: "00:11:22:33:44:55"}} {
+		if err := e.Export(c, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(out) != 0 {
+		t.Fatal("expected records to be buffered until flushed")
+	}
+
+	if err := e.Export(tcp, ja3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != 1 || e.NumExported() != 3 {
+		t.Fatal("expected a single message with 3 records, got", len(out), "messages and", e.NumExported(), "records")
+	}
+
+	msg := out[0]
+	if binary.BigEndian.Uint16(msg) != VersionIPFIX || int(binary.BigEndian.Uint16(msg[2:])) != len(msg) {
+		t.Fatal("invalid message header")
+	}
+
+	communityID := utils.CommunityID(0, net.ParseIP(tcp.SrcIP), net.ParseIP(tcp.DstIP), 51234, 443, 6)
+	for _, s := range []string{ja3, communityID, "TLS"} {
+		if !bytes.Contains(msg, []byte(s)) {
+			t.Fatal("expected message to contain", s)
+		}
+	}
+
+	flows, err := NewDecoder().Decode(msg, "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 3 {
+		t.Fatal("expected 3 flows, got", len(flows))
+	}
+
+	fl := flows[0]
+	if fl.Ident() != "192.168.1.10:51234->93.184.216.34:443" || fl.Protocol != 6 {
+		t.Fatal("unexpected flow", fl.Ident(), fl.Protocol)
+	}
+
+	if fl.Bytes != 4200 || fl.Packets != 12 || fl.TCPFlags != tcpFlagSYN|tcpFlagACK {
+		t.Fatal("unexpected counters", fl.Bytes, fl.Packets, fl.TCPFlags)
+	}
+
+	if !fl.Start.Equal(start) || fl.End.Sub(fl.Start) != 2*time.Second {
+		t.Fatal("unexpected timestamps", fl.Start, fl.End)
+	}
+
+	if fl.SrcMAC.String() != tcp.SrcMAC || fl.DstMAC.String() != tcp.DstMAC {
+		t.Fatal("unexpected hardware addresses", fl.SrcMAC, fl.DstMAC)
+	}
+
+	// the IPv6 set is written after the IPv4 set
+	if flows[2].DstIP.String() != "ff02::fb" || flows[2].Protocol != 17 || flows[2].DstPort != 5353 {
+		t.Fatal("unexpected IPv6 flow", flows[2].Ident())
+	}
+}
+
+func TestExportMessageSize(t *testing.T) {
+	var (
+		out messages
+		e   = newExporter(&out)
+		d   = NewDecoder()
+		c   = &types.Connection{
+			TransportProto: "UDP",
+			SrcIP:          "10.0.0.1",
+			DstIP:          "10.0.0.2",
+			SrcPort:        "53",
+			DstPort:        "40000",
+			NumPackets:     1,
+		}
+	)
+
+	e.MaxMessageSize = 400
+
+	for i := 0; i < 20; i++ {
+		if err := e.Export(c, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) < 2 {
+		t.Fatal("expected records to be split into several messages")
+	}
+
+	var (
+		numFlows int
+		sequence uint32
+	)
+
+	for i, msg := range out {
+		if len(msg) > e.MaxMessageSize {
+			t.Fatal("message exceeds maximum size:", len(msg))
+		}
+
+		if binary.BigEndian.Uint32(msg[8:]) != sequence {
+			t.Fatal("unexpected sequence number in message", i)
+		}
+
+		// templates are only sent with the first message
+		if hasTemplates := binary.BigEndian.Uint16(msg[ipfixHeaderLength:]) == ipfixTemplateSet; hasTemplates != (i == 0) {
+			t.Fatal("unexpected template set in message", i)
+		}
+
+		flows, err := d.Decode(msg, "127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		numFlows += len(flows)
+		sequence += uint32(len(flows))
+	}
+
+	if numFlows != 20 {
+		t.Fatal("expected 20 flows, got", numFlows)
+	}
+
+	e.MaxMessageSize = 40
+	if err := e.Export(c, ""); err != ErrMessageSize {
+		t.Fatal("expected ErrMessageSize, got", err)
+	}
+}
+
+// messageChan passes the messages written by the exporter to the test.
+type messageChan chan []byte
+
+func (m messageChan) Write(b []byte) (int, error) {
+	m <- append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func TestExportActiveTimeout(t *testing.T) {
+	var (
+		out = make(messageChan, 16)
+		e   = newExporter(out)
+		d   = NewDecoder()
+		c   = &types.Connection{
+			TransportProto: "UDP",
+			SrcIP:          "10.0.0.1",
+			DstIP:          "10.0.0.2",
+			SrcPort:        "53",
+			DstPort:        "40000",
+			NumPackets:     1,
+		}
+	)
+
+	e.ActiveTimeout = 10 * time.Millisecond
+	e.TemplateRefresh = 50 * time.Millisecond
+	e.Start()
+
+	if err := e.Export(c, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() []byte {
+		select {
+		case msg := <-out:
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+		return nil
+	}
+
+	// the record is sent without waiting for the message to fill up
+	flows, err := d.Decode(next(), "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 1 || flows[0].SrcPort != 53 {
+		t.Fatal("expected the exported flow, got", len(flows), "flows")
+	}
+
+	// without traffic, only the templates are sent again
+	msg := next()
+	if binary.BigEndian.Uint16(msg[ipfixHeaderLength:]) != ipfixTemplateSet || int(binary.BigEndian.Uint16(msg[2:])) != len(msg) {
+		t.Fatal("expected a template message")
+	}
+
+	if flows, err = d.Decode(msg, "127.0.0.1"); err != nil || len(flows) != 0 {
+		t.Fatal("expected no flows in template message, got", len(flows), err)
+	}
+
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
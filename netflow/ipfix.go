@@ -0,0 +1,154 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	ipfixHeaderLength = 16
+
+	ipfixTemplateSet        = 2
+	ipfixOptionsTemplateSet = 3
+
+	// enterprise bit of an information element identifier
+	enterpriseBit = 0x8000
+)
+
+// decodeIPFIX decodes an IPFIX message (RFC 7011).
+func (d *Decoder) decodeIPFIX(data []byte, exporter string) ([]*Flow, error) {
+	if len(data) < ipfixHeaderLength {
+		return nil, ErrShortPacket
+	}
+
+	length := int(binary.BigEndian.Uint16(data[2:]))
+	if length < ipfixHeaderLength || length > len(data) {
+		return nil, ErrShortPacket
+	}
+
+	ctx := &exportContext{
+		version:    VersionIPFIX,
+		exportTime: time.Unix(int64(binary.BigEndian.Uint32(data[4:])), 0),
+		domain: domainKey{
+			exporter: exporter,
+			domain:   binary.BigEndian.Uint32(data[12:]),
+		},
+	}
+
+	var (
+		flows []*Flow
+		sets  = data[ipfixHeaderLength:length]
+	)
+
+	for len(sets) >= 4 {
+		var (
+			id        = binary.BigEndian.Uint16(sets)
+			setLength = int(binary.BigEndian.Uint16(sets[2:]))
+		)
+
+		if setLength < 4 || setLength > len(sets) {
+			return flows, ErrInvalidSet
+		}
+
+		body := sets[4:setLength]
+		sets = sets[setLength:]
+
+		switch {
+		case id == ipfixTemplateSet:
+			if err := d.parseIPFIXTemplates(ctx, body, false); err != nil {
+				return flows, err
+			}
+		case id == ipfixOptionsTemplateSet:
+			if err := d.parseIPFIXTemplates(ctx, body, true); err != nil {
+				return flows, err
+			}
+		case id >= 256:
+			f, err := d.decodeDataSet(ctx, id, body)
+			flows = append(flows, f...)
+
+			if err != nil {
+				return flows, err
+			}
+		}
+	}
+
+	return flows, nil
+}
+
+// parseIPFIXTemplates parses the records of a template or options template set.
+// A template record without fields withdraws the template.
+func (d *Decoder) parseIPFIXTemplates(ctx *exportContext, data []byte, options bool) error {
+	headerLength := 4
+	if options {
+		headerLength = 6
+	}
+
+	for len(data) >= headerLength {
+		var (
+			id          = binary.BigEndian.Uint16(data)
+			count       = int(binary.BigEndian.Uint16(data[2:]))
+			scopeFields int
+			key         = templateKey{exporter: ctx.domain.exporter, domain: ctx.domain.domain, id: id}
+		)
+
+		if count == 0 {
+			delete(d.templates, key)
+			data = data[4:]
+
+			continue
+		}
+
+		if options {
+			scopeFields = int(binary.BigEndian.Uint16(data[4:]))
+		}
+
+		data = data[headerLength:]
+
+		t := &template{
+			fields:      make([]field, 0, count),
+			scopeFields: scopeFields,
+			options:     options,
+		}
+
+		for i := 0; i < count; i++ {
+			if len(data) < 4 {
+				return ErrShortPacket
+			}
+
+			f := field{
+				id:     binary.BigEndian.Uint16(data),
+				length: binary.BigEndian.Uint16(data[2:]),
+			}
+			data = data[4:]
+
+			if f.id&enterpriseBit != 0 {
+				if len(data) < 4 {
+					return ErrShortPacket
+				}
+
+				f.id &^= enterpriseBit
+				f.enterprise = binary.BigEndian.Uint32(data)
+				data = data[4:]
+			}
+
+			t.fields = append(t.fields, f)
+		}
+
+		d.templates[key] = t
+	}
+
+	return nil
+}
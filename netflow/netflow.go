@@ -0,0 +1,83 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package netflow implements decoding of NetFlow v5, NetFlow v9 and IPFIX export packets,
+// the conversion of the received flow records into netcap audit records,
+// and the export of netcap Connection audit records as IPFIX.
+package netflow
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// Protocol versions, as found in the first two bytes of an export packet.
+const (
+	VersionNetflow5 = 5
+	VersionNetflow9 = 9
+	VersionIPFIX    = 10
+)
+
+var (
+	// ErrShortPacket is returned when an export packet or set is truncated.
+	ErrShortPacket = errors.New("netflow: packet too short")
+
+	// ErrUnsupportedVersion is returned for export packets with an unknown version number.
+	ErrUnsupportedVersion = errors.New("netflow: unsupported version")
+
+	// ErrInvalidSet is returned when the length of a set or template is invalid.
+	ErrInvalidSet = errors.New("netflow: invalid set length")
+)
+
+// Flow is a single flow record received from an exporter,
+// normalized across the different protocol versions.
+type Flow struct {
+	// Exporter is the address of the device that sent the record.
+	Exporter string
+
+	// Version of the export protocol.
+	Version uint16
+
+	// ObservationDomain is the IPFIX observation domain or NetFlow v9 source ID.
+	ObservationDomain uint32
+
+	SrcIP    net.IP
+	DstIP    net.IP
+	SrcPort  uint16
+	DstPort  uint16
+	Protocol uint8
+
+	// TCPFlags is the union of all TCP flags seen for the flow.
+	TCPFlags uint8
+
+	SrcMAC net.HardwareAddr
+	DstMAC net.HardwareAddr
+
+	Packets uint64
+	Bytes   uint64
+
+	Start time.Time
+	End   time.Time
+
+	// SamplingInterval is the packet sampling interval that applied to the flow, 0 or 1 if unsampled.
+	// If the decoder scales sampled flows, Packets and Bytes have already been multiplied by it.
+	SamplingInterval uint32
+
+	// temporary values used for resolving timestamps and sampling.
+	startUptime, endUptime uint32
+	hasUptime              bool
+	systemInit             time.Time
+	samplerID              uint64
+	hasSamplerID           bool
+}
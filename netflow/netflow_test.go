@@ -0,0 +1,347 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// buffer is a helper for building export packets.
+type buffer []byte
+
+func (b *buffer) u8(v uint8) *buffer {
+	*b = append(*b, v)
+	return b
+}
+
+func (b *buffer) u16(v uint16) *buffer {
+	*b = append(*b, byte(v>>8), byte(v))
+	return b
+}
+
+func (b *buffer) u32(v uint32) *buffer {
+	*b = append(*b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	return b
+}
+
+func (b *buffer) bytes(v []byte) *buffer {
+	*b = append(*b, v...)
+	return b
+}
+
+// set wraps the body in a set header with the given ID.
+func set(id uint16, body buffer) buffer {
+	b := buffer{}
+	b.u16(id).u16(uint16(4 + len(body))).bytes(body)
+
+	return b
+}
+
+func TestDecodeV5(t *testing.T) {
+	b := buffer{}
+	b.u16(5).u16(1).u32(10000).u32(1600000000).u32(0).u32(1).u8(0).u8(1).u16(0x4000 | 10)
+
+	// record
+	b.bytes(net.IP{10, 0, 0, 1}).bytes(net.IP{10, 0, 0, 2}).u32(0)
+	b.u16(1).u16(2).u32(5).u32(500).u32(4000).u32(9000)
+	b.u16(43532).u16(80).u8(0).u8(0x12).u8(6).u8(0)
+	b.u16(0).u16(0).u8(24).u8(24).u16(0)
+
+	d := NewDecoder()
+
+	flows, err := d.Decode(b, "192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 1 {
+		t.Fatal("expected one flow, got", len(flows))
+	}
+
+	fl := flows[0]
+	if fl.SrcIP.String() != "10.0.0.1" || fl.DstIP.String() != "10.0.0.2" || fl.SrcPort != 43532 || fl.DstPort != 80 || fl.Protocol != 6 {
+		t.Fatal("unexpected flow", fl)
+	}
+
+	if fl.SamplingInterval != 10 || fl.Packets != 50 || fl.Bytes != 5000 {
+		t.Fatal("expected sampled counters to be scaled", fl.SamplingInterval, fl.Packets, fl.Bytes)
+	}
+
+	boot := time.Unix(1600000000, 0).Add(-10 * time.Second)
+	if !fl.Start.Equal(boot.Add(4*time.Second)) || !fl.End.Equal(boot.Add(9*time.Second)) {
+		t.Fatal("unexpected timestamps", fl.Start, fl.End)
+	}
+
+	c := fl.Connection()
+	if c.TransportProto != "TCP" || c.NumSYNFlags != 1 || c.NumACKFlags != 1 || c.NumFINFlags != 0 || c.Duration != int64(5*time.Second) {
+		t.Fatal("unexpected connection", c)
+	}
+}
+
+func TestDecodeV9(t *testing.T) {
+	var (
+		d      = NewDecoder()
+		header = func(sets ...buffer) buffer {
+			b := buffer{}
+			b.u16(9).u16(uint16(len(sets))).u32(60000).u32(1600000000).u32(1).u32(7)
+			for _, s := range sets {
+				b.bytes(s)
+			}
+			return b
+		}
+		tmpl = buffer{}
+		opts = buffer{}
+		data = buffer{}
+	)
+
+	// template 256: src, dst, sport, dport, proto, packets, bytes, first, last, sampler id
+	tmpl.u16(256).u16(10)
+	tmpl.u16(fieldSourceIPv4Address).u16(4).u16(fieldDestinationIPv4Address).u16(4)
+	tmpl.u16(fieldSourceTransportPort).u16(2).u16(fieldDestinationTransportPort).u16(2)
+	tmpl.u16(fieldProtocolIdentifier).u16(1).u16(fieldPacketDeltaCount).u16(4)
+	tmpl.u16(fieldOctetDeltaCount).u16(4).u16(fieldFlowStartSysUpTime).u16(4)
+	tmpl.u16(fieldFlowEndSysUpTime).u16(4).u16(fieldSamplerID).u16(1)
+
+	// options template 257: scope system (type 1, 4 bytes), sampler id, random interval
+	opts.u16(257).u16(4).u16(8)
+	opts.u16(1).u16(4)
+	opts.u16(fieldSamplerID).u16(1).u16(fieldSamplerRandomInterval).u16(4)
+	opts.u16(0) // padding
+
+	data.bytes(net.IP{10, 0, 0, 1}).bytes(net.IP{10, 0, 0, 2}).u16(53000).u16(53).u8(17).u32(1).u32(80).u32(50000).u32(50000).u8(3)
+	data.u8(0).u8(0).u8(0) // padding
+
+	optData := buffer{}
+	optData.u32(0).u8(3).u32(100)
+
+	// templates and data in the first packet, the options record is still unknown
+	flows, err := d.Decode(header(set(0, tmpl), set(1, opts), set(256, data)), "192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 1 || flows[0].SamplingInterval != 0 || flows[0].Packets != 1 {
+		t.Fatal("unexpected flows before sampling options", flows)
+	}
+
+	if flows[0].ObservationDomain != 7 || flows[0].DstPort != 53 {
+		t.Fatal("unexpected flow", flows[0])
+	}
+
+	if !flows[0].Start.Equal(time.Unix(1600000000, 0).Add(-10 * time.Second)) {
+		t.Fatal("unexpected start time", flows[0].Start)
+	}
+
+	// options data announces the sampler, then data is sent again
+	flows, err = d.Decode(header(set(257, optData), set(256, data)), "192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 1 || flows[0].SamplingInterval != 100 || flows[0].Packets != 100 || flows[0].Bytes != 8000 {
+		t.Fatal("expected sampled flow", flows)
+	}
+
+	// a different exporter does not share the templates
+	flows, err = d.Decode(header(set(256, data)), "192.168.1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 0 {
+		t.Fatal("expected no flows without template", flows)
+	}
+
+	if _, _, missing := d.Stats(); missing != 1 {
+		t.Fatal("expected one missing template, got", missing)
+	}
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	var (
+		d       = NewDecoder()
+		message = func(sets ...buffer) buffer {
+			var body buffer
+			for _, s := range sets {
+				body.bytes(s)
+			}
+
+			b := buffer{}
+			b.u16(10).u16(uint16(16 + len(body))).u32(1600000000).u32(1).u32(3).bytes(body)
+
+			return b
+		}
+		tmpl = buffer{}
+		opts = buffer{}
+		data = buffer{}
+	)
+
+	// template 300: IPv6 addresses, ports, protocol, flags, counters, timestamps,
+	// a variable length enterprise field and the selector id
+	tmpl.u16(300).u16(11)
+	tmpl.u16(fieldSourceIPv6Address).u16(16).u16(fieldDestinationIPv6Address).u16(16)
+	tmpl.u16(fieldSourceTransportPort).u16(2).u16(fieldDestinationTransportPort).u16(2)
+	tmpl.u16(fieldProtocolIdentifier).u16(1).u16(fieldTCPControlBits).u16(2)
+	tmpl.u16(fieldPacketDeltaCount).u16(8).u16(fieldOctetDeltaCount).u16(8)
+	tmpl.u16(fieldFlowStartMilliseconds).u16(8).u16(fieldFlowEndMilliseconds).u16(8)
+	tmpl.u16(enterpriseBit | 1).u16(variableLength).u32(29305)
+
+	// options template 301: scope selector id, packet interval and space
+	opts.u16(301).u16(3).u16(1)
+	opts.u16(fieldSelectorID).u16(8).u16(fieldSamplingPacketInterval).u16(4).u16(fieldSamplingPacketSpace).u16(4)
+
+	optData := buffer{}
+	optData.u32(0).u32(0).u32(1).u32(9)
+
+	var (
+		src   = net.ParseIP("2001:db8::1")
+		dst   = net.ParseIP("2001:db8::2")
+		start = uint64(1600000000000)
+	)
+
+	data.bytes(src).bytes(dst).u16(443).u16(50000).u8(6).u16(0x19)
+	data.u32(0).u32(4).u32(0).u32(400)
+	data.u32(uint32(start >> 32)).u32(uint32(start)).u32(uint32((start + 1500) >> 32)).u32(uint32(start + 1500))
+	data.u8(3).bytes([]byte("abc"))
+
+	flows, err := d.Decode(message(set(2, tmpl), set(3, opts), set(301, optData), set(300, data)), "192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 1 {
+		t.Fatal("expected one flow, got", len(flows))
+	}
+
+	fl := flows[0]
+	if !fl.SrcIP.Equal(src) || !fl.DstIP.Equal(dst) || fl.SrcPort != 443 || fl.Packets != 4 || fl.Bytes != 400 {
+		t.Fatal("unexpected flow", fl)
+	}
+
+	if fl.SamplingInterval != 0 {
+		t.Fatal("flow without selector id must not use the selector interval", fl.SamplingInterval)
+	}
+
+	if fl.End.Sub(fl.Start) != 1500*time.Millisecond {
+		t.Fatal("unexpected duration", fl.End.Sub(fl.Start))
+	}
+
+	c := fl.Connection()
+	if c.NetworkProto != "IPv6" || c.NumFINFlags != 1 || c.NumACKFlags != 1 || c.NumPSHFlags != 1 || c.NumSYNFlags != 0 {
+		t.Fatal("unexpected connection", c)
+	}
+
+	// withdraw the template
+	withdrawal := buffer{}
+	withdrawal.u16(300).u16(0)
+
+	flows, err = d.Decode(message(set(2, withdrawal), set(300, data)), "192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 0 {
+		t.Fatal("expected no flows after template withdrawal", flows)
+	}
+}
+
+func TestIPFIXSelectorSampling(t *testing.T) {
+	d := NewDecoder()
+
+	opts := buffer{}
+	opts.u16(301).u16(3).u16(1)
+	opts.u16(fieldSelectorID).u16(8).u16(fieldSamplingPacketInterval).u16(4).u16(fieldSamplingPacketSpace).u16(4)
+
+	optData := buffer{}
+	optData.u32(0).u32(5).u32(1).u32(9)
+
+	tmpl := buffer{}
+	tmpl.u16(300).u16(2).u16(fieldPacketDeltaCount).u16(4).u16(fieldSelectorID).u16(8)
+
+	data := buffer{}
+	data.u32(2).u32(0).u32(5)
+
+	var body buffer
+	for _, s := range []buffer{set(3, opts), set(301, optData), set(2, tmpl), set(300, data)} {
+		body.bytes(s)
+	}
+
+	msg := buffer{}
+	msg.u16(10).u16(uint16(16 + len(body))).u32(1600000000).u32(1).u32(0).bytes(body)
+
+	flows, err := d.Decode(msg, "192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flows) != 1 || flows[0].SamplingInterval != 10 || flows[0].Packets != 20 {
+		t.Fatal("expected selector sampling interval to apply", flows)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := NewDecoder()
+
+	if _, err := d.Decode([]byte{0, 1, 2, 3}, "x"); err != ErrUnsupportedVersion {
+		t.Fatal("expected unsupported version, got", err)
+	}
+
+	short := make([]byte, 10)
+	binary.BigEndian.PutUint16(short, VersionIPFIX)
+
+	if _, err := d.Decode(short, "x"); err != ErrShortPacket {
+		t.Fatal("expected short packet, got", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	var (
+		p  = NewProfiles()
+		fl = &Flow{
+			SrcIP:    net.IP{10, 0, 0, 1},
+			DstIP:    net.IP{10, 0, 0, 2},
+			SrcPort:  43532,
+			DstPort:  80,
+			Protocol: 6,
+			Packets:  10,
+			Bytes:    1000,
+			Start:    time.Unix(10, 0),
+			End:      time.Unix(20, 0),
+		}
+	)
+
+	p.Update(fl)
+	p.Update(fl)
+
+	items := p.Items()
+	if len(items) != 2 {
+		t.Fatal("expected two profiles, got", len(items))
+	}
+
+	src, dst := items[0], items[1]
+	if src.Addr != "10.0.0.1" || src.NumPackets != 20 || src.Bytes != 2000 {
+		t.Fatal("unexpected source profile", src)
+	}
+
+	if len(dst.DstPorts) != 1 || dst.DstPorts[0].PortNumber != 80 || dst.DstPorts[0].Stats.Packets != 20 {
+		t.Fatal("unexpected destination ports", dst.DstPorts)
+	}
+
+	if src.TimestampFirst != time.Unix(10, 0).UnixNano() || src.TimestampLast != time.Unix(20, 0).UnixNano() {
+		t.Fatal("unexpected timestamps", src.TimestampFirst, src.TimestampLast)
+	}
+}
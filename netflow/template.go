@@ -0,0 +1,90 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+// Information elements used by the decoder.
+// NetFlow v9 field types share their numbers with the corresponding IPFIX information elements (RFC 7012).
+const (
+	fieldOctetDeltaCount            = 1
+	fieldPacketDeltaCount           = 2
+	fieldProtocolIdentifier         = 4
+	fieldTCPControlBits             = 6
+	fieldSourceTransportPort        = 7
+	fieldSourceIPv4Address          = 8
+	fieldDestinationTransportPort   = 11
+	fieldDestinationIPv4Address     = 12
+	fieldFlowEndSysUpTime           = 21
+	fieldFlowStartSysUpTime         = 22
+	fieldSourceIPv6Address          = 27
+	fieldDestinationIPv6Address     = 28
+	fieldSamplingInterval           = 34
+	fieldSamplerID                  = 48
+	fieldSamplerRandomInterval      = 50
+	fieldSourceMacAddress           = 56
+	fieldPostDestinationMacAddress  = 57
+	fieldDestinationMacAddress      = 80
+	fieldPostSourceMacAddress       = 81
+	fieldOctetTotalCount            = 85
+	fieldPacketTotalCount           = 86
+	fieldFlowStartSeconds           = 150
+	fieldFlowEndSeconds             = 151
+	fieldFlowStartMilliseconds      = 152
+	fieldFlowEndMilliseconds        = 153
+	fieldFlowStartMicroseconds      = 154
+	fieldFlowEndMicroseconds        = 155
+	fieldFlowStartNanoseconds       = 156
+	fieldFlowEndNanoseconds         = 157
+	fieldSystemInitTimeMilliseconds = 160
+	fieldSelectorID                 = 302
+	fieldSamplingPacketInterval     = 305
+	fieldSamplingPacketSpace        = 306
+
+	// IPFIX field specifiers with this length are encoded with a variable length (RFC 7011 section 7).
+	variableLength = 65535
+)
+
+// field is a field specifier of a template.
+type field struct {
+	id         uint16
+	length     uint16
+	enterprise uint32
+}
+
+// template describes the layout of the records in a data set.
+type template struct {
+	fields []field
+
+	// number of leading scope fields, only set for options templates.
+	scopeFields int
+	options     bool
+}
+
+// templateKey identifies a template, template IDs are only unique per exporter and observation domain.
+type templateKey struct {
+	exporter string
+	domain   uint32
+	id       uint16
+}
+
+// domainKey identifies an observation domain of an exporter.
+type domainKey struct {
+	exporter string
+	domain   uint32
+}
+
+// samplerKey identifies a sampler or selector within an observation domain.
+type samplerKey struct {
+	domainKey
+	id uint64
+}
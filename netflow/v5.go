@@ -0,0 +1,77 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	v5HeaderLength = 24
+	v5RecordLength = 48
+)
+
+// decodeV5 decodes a NetFlow v5 export packet.
+// The sampling interval is taken from the packet header, the upper two bits encode the sampling mode.
+func (d *Decoder) decodeV5(data []byte, exporter string) ([]*Flow, error) {
+	if len(data) < v5HeaderLength {
+		return nil, ErrShortPacket
+	}
+
+	var (
+		count     = int(binary.BigEndian.Uint16(data[2:]))
+		sysUptime = time.Duration(binary.BigEndian.Uint32(data[4:])) * time.Millisecond
+		export    = time.Unix(int64(binary.BigEndian.Uint32(data[8:])), int64(binary.BigEndian.Uint32(data[12:])))
+		boot      = export.Add(-sysUptime)
+		sampling  = uint32(binary.BigEndian.Uint16(data[22:]) & 0x3fff)
+		engine    = uint32(data[20])<<8 | uint32(data[21])
+	)
+
+	if len(data) < v5HeaderLength+count*v5RecordLength {
+		return nil, ErrShortPacket
+	}
+
+	flows := make([]*Flow, 0, count)
+
+	for i := 0; i < count; i++ {
+		r := data[v5HeaderLength+i*v5RecordLength:]
+
+		fl := &Flow{
+			Exporter:          exporter,
+			Version:           VersionNetflow5,
+			ObservationDomain: engine,
+			SrcIP:             ipValue(r[0:4]),
+			DstIP:             ipValue(r[4:8]),
+			Packets:           uint64(binary.BigEndian.Uint32(r[16:])),
+			Bytes:             uint64(binary.BigEndian.Uint32(r[20:])),
+			Start:             boot.Add(time.Duration(binary.BigEndian.Uint32(r[24:])) * time.Millisecond),
+			End:               boot.Add(time.Duration(binary.BigEndian.Uint32(r[28:])) * time.Millisecond),
+			SrcPort:           binary.BigEndian.Uint16(r[32:]),
+			DstPort:           binary.BigEndian.Uint16(r[34:]),
+			TCPFlags:          r[37],
+			Protocol:          r[38],
+			SamplingInterval:  sampling,
+		}
+
+		if d.ScaleSampled && sampling > 1 {
+			fl.Packets *= uint64(sampling)
+			fl.Bytes *= uint64(sampling)
+		}
+
+		flows = append(flows, fl)
+	}
+
+	return flows, nil
+}
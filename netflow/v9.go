@@ -0,0 +1,161 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package netflow
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	v9HeaderLength = 20
+
+	v9TemplateFlowSet        = 0
+	v9OptionsTemplateFlowSet = 1
+)
+
+// decodeV9 decodes a NetFlow v9 export packet (RFC 3954).
+func (d *Decoder) decodeV9(data []byte, exporter string) ([]*Flow, error) {
+	if len(data) < v9HeaderLength {
+		return nil, ErrShortPacket
+	}
+
+	ctx := &exportContext{
+		version:      VersionNetflow9,
+		sysUptime:    binary.BigEndian.Uint32(data[4:]),
+		hasSysUptime: true,
+		exportTime:   time.Unix(int64(binary.BigEndian.Uint32(data[8:])), 0),
+		domain: domainKey{
+			exporter: exporter,
+			domain:   binary.BigEndian.Uint32(data[16:]),
+		},
+	}
+
+	var (
+		flows []*Flow
+		sets  = data[v9HeaderLength:]
+	)
+
+	for len(sets) >= 4 {
+		var (
+			id     = binary.BigEndian.Uint16(sets)
+			length = int(binary.BigEndian.Uint16(sets[2:]))
+		)
+
+		if length < 4 || length > len(sets) {
+			return flows, ErrInvalidSet
+		}
+
+		body := sets[4:length]
+		sets = sets[length:]
+
+		switch {
+		case id == v9TemplateFlowSet:
+			if err := d.parseV9Templates(ctx, body); err != nil {
+				return flows, err
+			}
+		case id == v9OptionsTemplateFlowSet:
+			if err := d.parseV9OptionsTemplates(ctx, body); err != nil {
+				return flows, err
+			}
+		case id >= 256:
+			f, err := d.decodeDataSet(ctx, id, body)
+			flows = append(flows, f...)
+
+			if err != nil {
+				return flows, err
+			}
+		}
+	}
+
+	return flows, nil
+}
+
+func (d *Decoder) parseV9Templates(ctx *exportContext, data []byte) error {
+	for len(data) >= 4 {
+		var (
+			id    = binary.BigEndian.Uint16(data)
+			count = int(binary.BigEndian.Uint16(data[2:]))
+		)
+
+		data = data[4:]
+
+		if len(data) < count*4 {
+			return ErrShortPacket
+		}
+
+		t := &template{
+			fields: parseV9Fields(data, count),
+		}
+		data = data[count*4:]
+
+		d.templates[templateKey{exporter: ctx.domain.exporter, domain: ctx.domain.domain, id: id}] = t
+	}
+
+	return nil
+}
+
+func (d *Decoder) parseV9OptionsTemplates(ctx *exportContext, data []byte) error {
+	// remaining bytes shorter than the options template header are padding
+	for len(data) >= 6 {
+		var (
+			id          = binary.BigEndian.Uint16(data)
+			scopeLength = int(binary.BigEndian.Uint16(data[2:]))
+			optLength   = int(binary.BigEndian.Uint16(data[4:]))
+		)
+
+		data = data[6:]
+
+		if scopeLength%4 != 0 || optLength%4 != 0 {
+			return ErrInvalidSet
+		}
+
+		if len(data) < scopeLength+optLength {
+			return ErrShortPacket
+		}
+
+		t := &template{
+			fields:      parseV9Fields(data, (scopeLength+optLength)/4),
+			scopeFields: scopeLength / 4,
+			options:     true,
+		}
+		data = data[scopeLength+optLength:]
+
+		// scope field types overlap with the regular field types in NetFlow v9,
+		// they are ignored to avoid misinterpreting them as sampling parameters.
+		for i := 0; i < t.scopeFields; i++ {
+			t.fields[i].enterprise = scopeEnterprise
+		}
+
+		d.templates[templateKey{exporter: ctx.domain.exporter, domain: ctx.domain.domain, id: id}] = t
+	}
+
+	return nil
+}
+
+// scopeEnterprise marks NetFlow v9 scope fields, so they are not treated as information elements.
+const scopeEnterprise = 0xffffffff
+
+func parseV9Fields(data []byte, count int) []field {
+	fields := make([]field, count)
+
+	for i := range fields {
+		fields[i] = field{
+			id:     binary.BigEndian.Uint16(data[i*4:]),
+			length: binary.BigEndian.Uint16(data[i*4+2:]),
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,285 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packetstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/defaults"
+)
+
+/*
+ * Segment Index Files
+ *
+ * The index of a segment starts with a header, followed by the flows of the segment:
+ *
+ *   magic | version | link type | first | last | number of flows | flow ...
+ *
+ * Each flow holds the protocol, the endpoints, the timestamps of the first and last packet
+ * and the offsets of its packets in the segment, encoded as differences to the preceding offset.
+ * Integers are encoded as varints, timestamps are unix nanoseconds.
+ */
+
+// ErrInvalidIndex is returned when a segment index is corrupted.
+var ErrInvalidIndex = errors.New("invalid packet store index")
+
+const indexVersion = 1
+
+var indexMagic = []byte("NCPI")
+
+// segmentIndex holds the flows of a segment.
+type segmentIndex struct {
+	num      int
+	linkType layers.LinkType
+
+	// timestamps of the first and last packet in the segment
+	first int64
+	last  int64
+
+	flows []*Flow
+}
+
+// overlaps returns true if the segment can contain packets in the time range, zero values are unbounded.
+func (s *segmentIndex) overlaps(from, to int64) bool {
+	return (from == 0 || s.last >= from) && (to == 0 || s.first < to)
+}
+
+// encode returns the binary representation of the index.
+func (s *segmentIndex) encode() []byte {
+	b := append([]byte{}, indexMagic...)
+	b = append(b, indexVersion)
+	b = appendUvarint(b, uint64(s.linkType))
+	b = appendVarint(b, s.first)
+	b = appendVarint(b, s.last)
+	b = appendUvarint(b, uint64(len(s.flows)))
+
+	for _, f := range s.flows {
+		b = append(b, f.Proto)
+		b = appendIP(b, f.SrcIP)
+		b = appendIP(b, f.DstIP)
+		b = appendUvarint(b, uint64(f.SrcPort))
+		b = appendUvarint(b, uint64(f.DstPort))
+		b = appendVarint(b, f.First)
+		b = appendUvarint(b, uint64(f.Last-f.First))
+		b = appendUvarint(b, uint64(len(f.offsets)))
+
+		var prev int64
+		for _, o := range f.offsets {
+			b = appendUvarint(b, uint64(o-prev))
+			prev = o
+		}
+	}
+
+	return b
+}
+
+// writeIndex writes the index of the segment to dir.
+// The index is written to a temporary file first, so that an index file is always complete.
+func writeIndex(dir string, s *segmentIndex) error {
+	var (
+		path = indexPath(dir, s.num)
+		tmp  = path + ".tmp"
+	)
+
+	if err := ioutil.WriteFile(tmp, s.encode(), defaults.FilePermission); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// readIndex reads the index of the segment with the number from dir.
+func readIndex(dir string, num int) (*segmentIndex, error) {
+	data, err := ioutil.ReadFile(indexPath(dir, num))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := decodeIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, indexPath(dir, num))
+	}
+
+	s.num = num
+
+	return s, nil
+}
+
+// decodeIndex parses the binary representation of an index.
+func decodeIndex(data []byte) (*segmentIndex, error) {
+	if len(data) < len(indexMagic)+1 || string(data[:len(indexMagic)]) != string(indexMagic) {
+		return nil, ErrInvalidIndex
+	}
+
+	if v := data[len(indexMagic)]; v != indexVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidIndex, v)
+	}
+
+	d := &decoder{data: data[len(indexMagic)+1:]}
+
+	s := &segmentIndex{
+		linkType: layers.LinkType(d.uvarint()),
+		first:    d.varint(),
+		last:     d.varint(),
+	}
+
+	numFlows := d.uvarint()
+
+	// each flow has a size of at least 10 bytes
+	if numFlows > uint64(len(d.data)/10) {
+		return nil, ErrInvalidIndex
+	}
+
+	s.flows = make([]*Flow, 0, numFlows)
+
+	for i := uint64(0); i < numFlows && d.err == nil; i++ {
+		f := &Flow{
+			Proto:   d.byte(),
+			SrcIP:   d.ip(),
+			DstIP:   d.ip(),
+			SrcPort: uint16(d.uvarint()),
+			DstPort: uint16(d.uvarint()),
+			First:   d.varint(),
+		}
+
+		f.Last = f.First + int64(d.uvarint())
+
+		numPackets := d.uvarint()
+		if numPackets > uint64(len(d.data)) {
+			return nil, ErrInvalidIndex
+		}
+
+		f.offsets = make([]int64, numPackets)
+
+		var prev int64
+		for j := range f.offsets {
+			prev += int64(d.uvarint())
+			f.offsets[j] = prev
+		}
+
+		s.flows = append(s.flows, f)
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return s, nil
+}
+
+// decoder reads the values of an index and records the first error.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = ErrInvalidIndex
+
+		return 0
+	}
+
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = ErrInvalidIndex
+
+		return 0
+	}
+
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+
+	if len(d.data) == 0 {
+		d.err = ErrInvalidIndex
+
+		return 0
+	}
+
+	v := d.data[0]
+	d.data = d.data[1:]
+
+	return v
+}
+
+func (d *decoder) ip() net.IP {
+	size := int(d.byte())
+	if d.err != nil {
+		return nil
+	}
+
+	if (size != net.IPv4len && size != net.IPv6len) || len(d.data) < size {
+		d.err = ErrInvalidIndex
+
+		return nil
+	}
+
+	ip := make(net.IP, size)
+	copy(ip, d.data)
+	d.data = d.data[size:]
+
+	return ip
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// appendIP appends the address with its length, IPv4 addresses are stored with 4 bytes.
+func appendIP(b []byte, ip net.IP) []byte {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	b = append(b, byte(len(ip)))
+
+	return append(b, ip...)
+}
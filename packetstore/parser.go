@@ -0,0 +1,151 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packetstore
+
+import (
+	"net"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// IP protocol numbers of the supported transport layers.
+const (
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+	protoSCTP   = 132
+)
+
+// endpoints of a packet, the addresses reference the packet data.
+type endpoints struct {
+	proto   uint8
+	srcIP   net.IP
+	dstIP   net.IP
+	srcPort uint16
+	dstPort uint16
+}
+
+// packetParser decodes the endpoints of packets without allocations.
+// Only the first network layer and the transport layer following it are decoded,
+// tunneled traffic is indexed by the outer flow.
+type packetParser struct {
+	linkType layers.LinkType
+
+	eth   layers.Ethernet
+	dot1q layers.Dot1Q
+	sll   layers.LinuxSLL
+	loop  layers.Loopback
+	ip4   layers.IPv4
+	ip6   layers.IPv6
+	tcp   layers.TCP
+	udp   layers.UDP
+	sctp  layers.SCTP
+	icmp4 layers.ICMPv4
+	icmp6 layers.ICMPv6
+
+	// parsers for the first layer types
+	parsers map[gopacket.LayerType]*gopacket.DecodingLayerParser
+	decoded []gopacket.LayerType
+}
+
+func newPacketParser(lt layers.LinkType) *packetParser {
+	return &packetParser{
+		linkType: lt,
+		parsers:  make(map[gopacket.LayerType]*gopacket.DecodingLayerParser),
+	}
+}
+
+// firstLayer returns the type of the first layer of the packet data.
+func (p *packetParser) firstLayer(data []byte) (gopacket.LayerType, bool) {
+	switch p.linkType {
+	case layers.LinkTypeEthernet:
+		return layers.LayerTypeEthernet, true
+	case layers.LinkTypeNull:
+		return layers.LayerTypeLoopback, true
+	case layers.LinkTypeLinuxSLL:
+		return layers.LayerTypeLinuxSLL, true
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4, layers.LinkTypeIPv6:
+		// raw packets can be IPv4 or IPv6
+		if len(data) > 0 && data[0]>>4 == 6 {
+			return layers.LayerTypeIPv6, true
+		}
+
+		return layers.LayerTypeIPv4, true
+	default:
+		return gopacket.LayerTypeZero, false
+	}
+}
+
+// parse returns the endpoints of the packet, ok is false if the packet has no IP layer.
+func (p *packetParser) parse(data []byte) (e endpoints, ok bool) {
+	first, supported := p.firstLayer(data)
+	if !supported {
+		return e, false
+	}
+
+	parser, exists := p.parsers[first]
+	if !exists {
+		parser = gopacket.NewDecodingLayerParser(first,
+			&p.eth, &p.dot1q, &p.sll, &p.loop, &p.ip4, &p.ip6, &p.tcp, &p.udp, &p.sctp, &p.icmp4, &p.icmp6,
+		)
+		parser.IgnoreUnsupported = true
+		p.parsers[first] = parser
+	}
+
+	// decoding errors of truncated packets are ignored, the layers decoded before are used
+	_ = parser.DecodeLayers(data, &p.decoded)
+
+	for _, typ := range p.decoded {
+		switch typ {
+		case layers.LayerTypeIPv4, layers.LayerTypeIPv6:
+			// IP in IP tunnels are indexed by the outer flow
+			if ok {
+				return e, ok
+			}
+
+			if typ == layers.LayerTypeIPv4 {
+				e.proto, e.srcIP, e.dstIP = uint8(p.ip4.Protocol), p.ip4.SrcIP, p.ip4.DstIP
+			} else {
+				e.proto, e.srcIP, e.dstIP = uint8(p.ip6.NextHeader), p.ip6.SrcIP, p.ip6.DstIP
+			}
+
+			ok = true
+		case layers.LayerTypeTCP:
+			e.proto, e.srcPort, e.dstPort = protoTCP, uint16(p.tcp.SrcPort), uint16(p.tcp.DstPort)
+
+			return e, ok
+		case layers.LayerTypeUDP:
+			e.proto, e.srcPort, e.dstPort = protoUDP, uint16(p.udp.SrcPort), uint16(p.udp.DstPort)
+
+			return e, ok
+		case layers.LayerTypeSCTP:
+			e.proto, e.srcPort, e.dstPort = protoSCTP, uint16(p.sctp.SrcPort), uint16(p.sctp.DstPort)
+
+			return e, ok
+		case layers.LayerTypeICMPv4:
+			// the message type and code are used as ports, like for the community ID
+			e.proto, e.srcPort, e.dstPort = protoICMP, uint16(p.icmp4.TypeCode.Type()), uint16(p.icmp4.TypeCode.Code())
+
+			return e, ok
+		case layers.LayerTypeICMPv6:
+			e.proto, e.srcPort, e.dstPort = protoICMPv6, uint16(p.icmp6.TypeCode.Type()), uint16(p.icmp6.TypeCode.Code())
+
+			return e, ok
+		}
+	}
+
+	return e, ok
+}
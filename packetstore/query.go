@@ -0,0 +1,272 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packetstore
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+var (
+	// ErrEmptyQuery is returned for queries without any criteria.
+	ErrEmptyQuery = errors.New("empty packet store query")
+
+	// ErrNoFlow is returned by QueryForRecord for audit records without flow information.
+	ErrNoFlow = errors.New("audit record has no flow information")
+)
+
+// Query selects packets from the store. All criteria that are set must match.
+type Query struct {
+	// Flow identifier in the netcap format: srcIP:srcPort->dstIP:dstPort
+	// both directions of the flow match, an encapsulation suffix is ignored
+	Flow string
+
+	// CommunityID of the flow
+	CommunityID string
+
+	// Host matches flows with the address as source or destination
+	Host string
+
+	// Peer matches flows between Host and the address, it requires Host to be set
+	Peer string
+
+	// Port matches flows with the port as source or destination, zero matches all ports
+	Port uint16
+
+	// From and To restrict the packets to the time range, zero values are unbounded
+	From time.Time
+	To   time.Time
+}
+
+// matcher is a parsed query.
+type matcher struct {
+	communityID string
+
+	// flow endpoints
+	flow             bool
+	srcIP, dstIP     net.IP
+	srcPort, dstPort uint16
+
+	host, peer net.IP
+	port       uint16
+
+	// time range in nanoseconds, zero values are unbounded
+	from, to int64
+}
+
+// newMatcher parses the query.
+func newMatcher(q Query) (*matcher, error) {
+	m := &matcher{
+		communityID: q.CommunityID,
+		port:        q.Port,
+	}
+
+	if !q.From.IsZero() {
+		m.from = q.From.UnixNano()
+	}
+
+	if !q.To.IsZero() {
+		m.to = q.To.UnixNano()
+	}
+
+	if q.Flow != "" {
+		var err error
+
+		m.srcIP, m.srcPort, m.dstIP, m.dstPort, err = parseFlowIdent(q.Flow)
+		if err != nil {
+			return nil, err
+		}
+
+		m.flow = true
+	}
+
+	if q.Host != "" {
+		if m.host = net.ParseIP(q.Host); m.host == nil {
+			return nil, fmt.Errorf("invalid host address: %q", q.Host)
+		}
+	}
+
+	if q.Peer != "" {
+		if m.host == nil {
+			return nil, errors.New("peer requires a host")
+		}
+
+		if m.peer = net.ParseIP(q.Peer); m.peer == nil {
+			return nil, fmt.Errorf("invalid peer address: %q", q.Peer)
+		}
+	}
+
+	if !m.flow && m.communityID == "" && m.host == nil && m.port == 0 && m.from == 0 && m.to == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	return m, nil
+}
+
+// match returns true if the flow matches the query.
+func (m *matcher) match(f *Flow) bool {
+	if (m.from != 0 && f.Last < m.from) || (m.to != 0 && f.First >= m.to) {
+		return false
+	}
+
+	if m.flow {
+		forward := m.srcIP.Equal(f.SrcIP) && m.srcPort == f.SrcPort && m.dstIP.Equal(f.DstIP) && m.dstPort == f.DstPort
+		reverse := m.srcIP.Equal(f.DstIP) && m.srcPort == f.DstPort && m.dstIP.Equal(f.SrcIP) && m.dstPort == f.SrcPort
+
+		if !forward && !reverse {
+			return false
+		}
+	}
+
+	if m.host != nil {
+		if m.peer != nil {
+			if !(m.host.Equal(f.SrcIP) && m.peer.Equal(f.DstIP)) && !(m.host.Equal(f.DstIP) && m.peer.Equal(f.SrcIP)) {
+				return false
+			}
+		} else if !m.host.Equal(f.SrcIP) && !m.host.Equal(f.DstIP) {
+			return false
+		}
+	}
+
+	if m.port != 0 && m.port != f.SrcPort && m.port != f.DstPort {
+		return false
+	}
+
+	return m.communityID == "" || m.communityID == f.CommunityID()
+}
+
+// matchTime returns true if the timestamp is in the time range of the query.
+func (m *matcher) matchTime(ts int64) bool {
+	return (m.from == 0 || ts >= m.from) && (m.to == 0 || ts < m.to)
+}
+
+// parseFlowIdent parses a flow identifier, the port is separated at the last colon to support IPv6 addresses.
+func parseFlowIdent(ident string) (srcIP net.IP, srcPort uint16, dstIP net.IP, dstPort uint16, err error) {
+	if idx := strings.IndexByte(ident, '@'); idx != -1 {
+		ident = ident[:idx]
+	}
+
+	arr := strings.Split(ident, "->")
+	if len(arr) != 2 {
+		return nil, 0, nil, 0, fmt.Errorf("invalid flow identifier: %q", ident)
+	}
+
+	endpoint := func(s string) (net.IP, uint16, error) {
+		idx := strings.LastIndexByte(s, ':')
+		if idx == -1 {
+			return nil, 0, fmt.Errorf("invalid flow identifier: %q", ident)
+		}
+
+		ip := net.ParseIP(s[:idx])
+		if ip == nil {
+			return nil, 0, fmt.Errorf("invalid address in flow identifier: %q", ident)
+		}
+
+		port, errPort := strconv.ParseUint(s[idx+1:], 10, 16)
+		if errPort != nil {
+			return nil, 0, fmt.Errorf("invalid port in flow identifier: %q", ident)
+		}
+
+		return ip, uint16(port), nil
+	}
+
+	if srcIP, srcPort, err = endpoint(arr[0]); err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	if dstIP, dstPort, err = endpoint(arr[1]); err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	return srcIP, srcPort, dstIP, dstPort, nil
+}
+
+// QueryForRecord returns the query for the packets of an audit record.
+// The flow is taken from the Flow field of the record, or from its source and destination
+// or client and server addresses and ports. Records with only addresses select all packets between the hosts.
+// If the record has the timestamps of its first and last packet, the packets are restricted to that range.
+func QueryForRecord(record proto.Message) (Query, error) {
+	v := reflect.ValueOf(record)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return Query{}, ErrNoFlow
+	}
+
+	field := func(name string) string {
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return ""
+		}
+
+		switch f.Kind() {
+		case reflect.String:
+			return f.String()
+		case reflect.Int32, reflect.Int64:
+			if f.Int() == 0 {
+				return ""
+			}
+
+			return strconv.FormatInt(f.Int(), 10)
+		default:
+			return ""
+		}
+	}
+
+	var q Query
+
+	if first, last := v.FieldByName("TimestampFirst"), v.FieldByName("TimestampLast"); first.IsValid() && last.IsValid() &&
+		first.Kind() == reflect.Int64 && last.Kind() == reflect.Int64 && first.Int() != 0 && last.Int() != 0 {
+		q.From = time.Unix(0, first.Int())
+
+		// the range is exclusive
+		q.To = time.Unix(0, last.Int()+1)
+	}
+
+	if flow := field("Flow"); flow != "" {
+		q.Flow = flow
+
+		return q, nil
+	}
+
+	for _, names := range [][4]string{
+		{"SrcIP", "SrcPort", "DstIP", "DstPort"},
+		{"ClientIP", "ClientPort", "ServerIP", "ServerPort"},
+	} {
+		srcIP, srcPort, dstIP, dstPort := field(names[0]), field(names[1]), field(names[2]), field(names[3])
+		if srcIP == "" || dstIP == "" {
+			continue
+		}
+
+		if srcPort != "" && dstPort != "" {
+			q.Flow = net.JoinHostPort(srcIP, srcPort) + "->" + net.JoinHostPort(dstIP, dstPort)
+			q.Flow = strings.NewReplacer("[", "", "]", "").Replace(q.Flow)
+		} else {
+			q.Host, q.Peer = srcIP, dstIP
+		}
+
+		return q, nil
+	}
+
+	return Query{}, ErrNoFlow
+}
@@ -0,0 +1,268 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packetstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+)
+
+var (
+	// ErrNoSegments is returned when a store has no completed segments.
+	ErrNoSegments = errors.New("packet store has no indexed segments")
+
+	// ErrMixedLinkTypes is returned when extracting packets from segments with different link types.
+	ErrMixedLinkTypes = errors.New("matched packets have different link types")
+
+	// errInvalidSegment is returned for segments that are no pcap files.
+	errInvalidSegment = errors.New("invalid packet store segment")
+)
+
+// pcap file magic numbers for microsecond and nanosecond timestamps.
+const (
+	magicMicros = 0xa1b2c3d4
+	magicNanos  = 0xa1b23c4d
+)
+
+// Store provides access to the packets of a packet store directory.
+type Store struct {
+	dir      string
+	segments []*segmentIndex
+}
+
+// Open reads the indexes of the store in dir.
+// Segments without an index are still being written and are ignored.
+func Open(dir string) (*Store, error) {
+	nums, err := segments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{dir: dir}
+
+	for _, num := range nums {
+		idx, errIndex := readIndex(dir, num)
+		if errIndex != nil {
+			if os.IsNotExist(errIndex) {
+				continue
+			}
+
+			return nil, errIndex
+		}
+
+		s.segments = append(s.segments, idx)
+	}
+
+	if len(s.segments) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoSegments, dir)
+	}
+
+	return s, nil
+}
+
+// LinkType returns the link type of the first segment.
+func (s *Store) LinkType() layers.LinkType {
+	return s.segments[0].linkType
+}
+
+// Flows returns the flows matching the query, a flow that spans several segments is returned for each of them.
+func (s *Store) Flows(q Query) ([]*Flow, error) {
+	m, err := newMatcher(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var flows []*Flow
+
+	for _, seg := range s.segments {
+		if !seg.overlaps(m.from, m.to) {
+			continue
+		}
+
+		for _, f := range seg.flows {
+			if m.match(f) {
+				flows = append(flows, f)
+			}
+		}
+	}
+
+	return flows, nil
+}
+
+// Packets calls fn for each packet matching the query, in the order in which the packets were written.
+// The packet data is only valid until fn returns.
+func (s *Store) Packets(q Query, fn func(ci gopacket.CaptureInfo, data []byte, lt layers.LinkType) error) error {
+	m, err := newMatcher(q)
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range s.segments {
+		if !seg.overlaps(m.from, m.to) {
+			continue
+		}
+
+		var offsets []int64
+
+		for _, f := range seg.flows {
+			if m.match(f) {
+				offsets = append(offsets, f.offsets...)
+			}
+		}
+
+		if len(offsets) == 0 {
+			continue
+		}
+
+		sort.Slice(offsets, func(i, j int) bool {
+			return offsets[i] < offsets[j]
+		})
+
+		if err = s.readPackets(seg, offsets, m, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Extract writes the packets matching the query as pcap file to w and returns the number of packets.
+func (s *Store) Extract(q Query, w io.Writer) (int, error) {
+	var (
+		pw       *pcapgo.Writer
+		linkType layers.LinkType
+		count    int
+	)
+
+	err := s.Packets(q, func(ci gopacket.CaptureInfo, data []byte, lt layers.LinkType) error {
+		if pw == nil {
+			pw = pcapgo.NewWriterNanos(w)
+			linkType = lt
+
+			if err := pw.WriteFileHeader(defaultSnapLen, lt); err != nil {
+				return err
+			}
+		} else if lt != linkType {
+			return ErrMixedLinkTypes
+		}
+
+		count++
+
+		return pw.WritePacket(ci, data)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	// write a valid empty file if no packets matched
+	if pw == nil {
+		err = pcapgo.NewWriterNanos(w).WriteFileHeader(defaultSnapLen, s.LinkType())
+	}
+
+	return count, err
+}
+
+// readPackets reads the packets at the offsets from the segment.
+func (s *Store) readPackets(seg *segmentIndex, offsets []int64, m *matcher, fn func(ci gopacket.CaptureInfo, data []byte, lt layers.LinkType) error) error {
+	f, err := os.Open(segmentPath(s.dir, seg.num))
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	var header [pcapFileHeaderSize]byte
+	if _, err = io.ReadFull(f, header[:]); err != nil {
+		return fmt.Errorf("%w: %s: %v", errInvalidSegment, f.Name(), err)
+	}
+
+	var (
+		order binary.ByteOrder = binary.LittleEndian
+		nanos bool
+	)
+
+	switch magic := binary.LittleEndian.Uint32(header[:4]); {
+	case magic == magicMicros:
+	case magic == magicNanos:
+		nanos = true
+	case binary.BigEndian.Uint32(header[:4]) == magicMicros:
+		order = binary.BigEndian
+	case binary.BigEndian.Uint32(header[:4]) == magicNanos:
+		order, nanos = binary.BigEndian, true
+	default:
+		return fmt.Errorf("%w: %s", errInvalidSegment, f.Name())
+	}
+
+	var (
+		rec  [pcapRecordHeaderSize]byte
+		data []byte
+	)
+
+	for _, o := range offsets {
+		if _, err = f.ReadAt(rec[:], o); err != nil {
+			return fmt.Errorf("%w: %s: offset %d: %v", errInvalidSegment, f.Name(), o, err)
+		}
+
+		var (
+			sec    = int64(order.Uint32(rec[0:4]))
+			frac   = int64(order.Uint32(rec[4:8]))
+			capLen = order.Uint32(rec[8:12])
+			length = order.Uint32(rec[12:16])
+		)
+
+		if !nanos {
+			frac *= 1000
+		}
+
+		ts := time.Unix(sec, frac)
+		if !m.matchTime(ts.UnixNano()) {
+			continue
+		}
+
+		if capLen > defaultSnapLen*4 {
+			return fmt.Errorf("%w: %s: offset %d: capture length %d", errInvalidSegment, f.Name(), o, capLen)
+		}
+
+		if cap(data) < int(capLen) {
+			data = make([]byte, capLen)
+		}
+
+		data = data[:capLen]
+
+		if _, err = f.ReadAt(data, o+pcapRecordHeaderSize); err != nil {
+			return fmt.Errorf("%w: %s: offset %d: %v", errInvalidSegment, f.Name(), o, err)
+		}
+
+		ci := gopacket.CaptureInfo{
+			Timestamp:     ts,
+			CaptureLength: int(capLen),
+			Length:        int(length),
+		}
+
+		if err = fn(ci, data, seg.linkType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
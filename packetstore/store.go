@@ -0,0 +1,109 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package packetstore implements a full packet capture store, that allows to retrieve
+// the packets of a flow or an audit record without scanning the whole capture.
+//
+// The raw packets are written into rotating pcap segments inside of the store directory.
+// For each segment an index file is written when the segment is completed,
+// which maps the flows of the segment to the file offsets of their packets:
+//
+//	segment-000001.pcap segment-000001.idx segment-000002.pcap segment-000002.idx ...
+//
+// Flows are identified by the IP addresses, ports and transport protocol of their packets.
+// Both directions of a connection belong to the same flow.
+package packetstore
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+const (
+	segmentPrefix    = "segment-"
+	segmentExtension = ".pcap"
+	indexExtension   = ".idx"
+)
+
+// segmentPath returns the path of the pcap segment with the number in dir.
+func segmentPath(dir string, num int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%06d%s", segmentPrefix, num, segmentExtension))
+}
+
+// indexPath returns the path of the index for the pcap segment with the number in dir.
+func indexPath(dir string, num int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%06d%s", segmentPrefix, num, indexExtension))
+}
+
+// flowKey identifies a flow, the endpoints are ordered so that both directions have the same key.
+// IPv4 addresses are stored in their 16 byte representation.
+type flowKey struct {
+	proto uint8
+	ipA   [16]byte
+	ipB   [16]byte
+	portA uint16
+	portB uint16
+}
+
+// Flow describes the packets of a flow in a segment.
+// The source is the endpoint that sent the first packet of the flow in the segment.
+type Flow struct {
+	Proto   uint8
+	SrcIP   net.IP
+	DstIP   net.IP
+	SrcPort uint16
+	DstPort uint16
+
+	// timestamps of the first and last packet in nanoseconds
+	First int64
+	Last  int64
+
+	// offsets of the packets in the segment, in ascending order
+	offsets []int64
+}
+
+// NumPackets returns the number of packets of the flow in the segment.
+func (f *Flow) NumPackets() int {
+	return len(f.offsets)
+}
+
+// Ident returns the flow identifier in the netcap format: srcIP:srcPort->dstIP:dstPort.
+func (f *Flow) Ident() string {
+	return utils.CreateFlowIdent(f.SrcIP.String(), strconv.Itoa(int(f.SrcPort)), f.DstIP.String(), strconv.Itoa(int(f.DstPort)), "")
+}
+
+// CommunityID returns the community ID flow hash of the flow.
+func (f *Flow) CommunityID() string {
+	return utils.CommunityID(0, f.SrcIP, f.DstIP, f.SrcPort, f.DstPort, f.Proto)
+}
+
+// newFlowKey returns the key for a packet between the endpoints.
+func newFlowKey(proto uint8, srcIP, dstIP net.IP, srcPort, dstPort uint16) flowKey {
+	a, b := srcIP.To16(), dstIP.To16()
+
+	if c := bytes.Compare(a, b); c > 0 || (c == 0 && srcPort > dstPort) {
+		a, b = b, a
+		srcPort, dstPort = dstPort, srcPort
+	}
+
+	k := flowKey{proto: proto, portA: srcPort, portB: dstPort}
+	copy(k.ipA[:], a)
+	copy(k.ipB[:], b)
+
+	return k
+}
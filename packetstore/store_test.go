@@ -0,0 +1,381 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packetstore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// packet returns an ethernet frame with a TCP or UDP packet between the endpoints.
+func packet(t *testing.T, udp bool, srcIP string, srcPort int, dstIP string, dstPort int) []byte {
+	t.Helper()
+
+	var (
+		src, dst = net.ParseIP(srcIP), net.ParseIP(dstIP)
+		eth      = &layers.Ethernet{
+			SrcMAC: net.HardwareAddr{0, 1, 2, 3, 4, 5},
+			DstMAC: net.HardwareAddr{0, 1, 2, 3, 4, 6},
+		}
+		network gopacket.NetworkLayer
+	)
+
+	if src.To4() != nil {
+		eth.EthernetType = layers.EthernetTypeIPv4
+		ip := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src.To4(), DstIP: dst.To4(), Protocol: layers.IPProtocolTCP}
+
+		if udp {
+			ip.Protocol = layers.IPProtocolUDP
+		}
+
+		network = ip
+	} else {
+		eth.EthernetType = layers.EthernetTypeIPv6
+		ip := &layers.IPv6{Version: 6, HopLimit: 64, SrcIP: src, DstIP: dst, NextHeader: layers.IPProtocolTCP}
+
+		if udp {
+			ip.NextHeader = layers.IPProtocolUDP
+		}
+
+		network = ip
+	}
+
+	var transport interface {
+		gopacket.SerializableLayer
+		SetNetworkLayerForChecksum(gopacket.NetworkLayer) error
+	}
+
+	if udp {
+		transport = &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: layers.UDPPort(dstPort)}
+	} else {
+		transport = &layers.TCP{SrcPort: layers.TCPPort(srcPort), DstPort: layers.TCPPort(dstPort), ACK: true}
+	}
+
+	if err := transport.SetNetworkLayerForChecksum(network); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		eth,
+		network.(gopacket.SerializableLayer),
+		transport,
+		gopacket.Payload("data"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+type testPacket struct {
+	udp              bool
+	srcIP, dstIP     string
+	srcPort, dstPort int
+}
+
+var (
+	start = time.Unix(1600000000, 123456789)
+
+	testPackets = []testPacket{
+		{false, "192.168.1.47", "165.227.109.154", 53032, 80},
+		{false, "165.227.109.154", "192.168.1.47", 80, 53032},
+		{true, "192.168.1.47", "8.8.8.8", 5353, 53},
+		{false, "192.168.1.47", "165.227.109.154", 53032, 80},
+		{true, "8.8.8.8", "192.168.1.47", 53, 5353},
+		{false, "fe80::1", "fe80::2", 41000, 443},
+		{false, "192.168.1.47", "10.0.0.1", 53033, 22},
+		{false, "165.227.109.154", "192.168.1.47", 80, 53032},
+		{false, "fe80::2", "fe80::1", 443, 41000},
+		{false, "192.168.1.47", "165.227.109.154", 53032, 80},
+	}
+)
+
+// writeStore writes the test packets into a store with small segments, each packet is one second apart.
+func writeStore(t *testing.T) (string, [][]byte) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "packetstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate after about three packets
+	w, err := NewWriter(Config{Dir: dir, SegmentSize: 250, LinkType: layers.LinkTypeEthernet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([][]byte, len(testPackets))
+
+	for i, p := range testPackets {
+		data[i] = packet(t, p.udp, p.srcIP, p.srcPort, p.dstIP, p.dstPort)
+
+		ci := gopacket.CaptureInfo{
+			Timestamp:     start.Add(time.Duration(i) * time.Second),
+			CaptureLength: len(data[i]),
+			Length:        len(data[i]),
+		}
+
+		if err = w.WritePacket(ci, data[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, data
+}
+
+// packetIndices returns the indices of the test packets matching the query.
+func packetIndices(t *testing.T, s *Store, q Query, data [][]byte) []int {
+	t.Helper()
+
+	var indices []int
+
+	err := s.Packets(q, func(ci gopacket.CaptureInfo, d []byte, lt layers.LinkType) error {
+		i := int(ci.Timestamp.Sub(start) / time.Second)
+
+		if !ci.Timestamp.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Fatal("unexpected timestamp", ci.Timestamp)
+		}
+
+		if lt != layers.LinkTypeEthernet || !bytes.Equal(d, data[i]) || ci.CaptureLength != len(d) {
+			t.Fatal("unexpected packet", i)
+		}
+
+		indices = append(indices, i)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return indices
+}
+
+func equalIndices(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestStore(t *testing.T) {
+	dir, data := writeStore(t)
+	defer os.RemoveAll(dir)
+
+	nums, err := segments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nums) < 3 {
+		t.Fatal("expected the segments to be rotated, got", nums)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.LinkType() != layers.LinkTypeEthernet {
+		t.Fatal("unexpected link type", s.LinkType())
+	}
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []int
+	}{
+		{"flow", Query{Flow: "192.168.1.47:53032->165.227.109.154:80"}, []int{0, 1, 3, 7, 9}},
+		{"reverse flow", Query{Flow: "165.227.109.154:80->192.168.1.47:53032@vlan:1"}, []int{0, 1, 3, 7, 9}},
+		{"ipv6 flow", Query{Flow: "fe80::2:443->fe80::1:41000"}, []int{5, 8}},
+		{"community id", Query{CommunityID: utils.CommunityID(0, net.ParseIP("8.8.8.8"), net.ParseIP("192.168.1.47"), 53, 5353, protoUDP)}, []int{2, 4}},
+		{"host", Query{Host: "192.168.1.47"}, []int{0, 1, 2, 3, 4, 6, 7, 9}},
+		{"host and peer", Query{Host: "10.0.0.1", Peer: "192.168.1.47"}, []int{6}},
+		{"port", Query{Port: 53}, []int{2, 4}},
+		{"time", Query{From: start.Add(4 * time.Second), To: start.Add(7 * time.Second)}, []int{4, 5, 6}},
+		{"flow and time", Query{Flow: "192.168.1.47:53032->165.227.109.154:80", From: start.Add(2 * time.Second)}, []int{3, 7, 9}},
+		{"no match", Query{Port: 8080}, nil},
+	}
+
+	for _, test := range tests {
+		if indices := packetIndices(t, s, test.query, data); !equalIndices(indices, test.expected) {
+			t.Fatal(test.name, "expected packets", test.expected, "got", indices)
+		}
+	}
+
+	if _, err = s.Flows(Query{}); !errors.Is(err, ErrEmptyQuery) {
+		t.Fatal("expected error for empty query, got", err)
+	}
+
+	if _, err = s.Flows(Query{Peer: "10.0.0.1"}); err == nil {
+		t.Fatal("expected error for peer without host")
+	}
+
+	flows, err := s.Flows(Query{Flow: "192.168.1.47:53032->165.227.109.154:80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var numPackets int
+	for _, f := range flows {
+		numPackets += f.NumPackets()
+
+		if f.Ident() != "192.168.1.47:53032->165.227.109.154:80" && f.Ident() != "165.227.109.154:80->192.168.1.47:53032" {
+			t.Fatal("unexpected flow", f.Ident())
+		}
+	}
+
+	if numPackets != 5 {
+		t.Fatal("expected 5 packets, got", numPackets)
+	}
+}
+
+func TestStoreExtract(t *testing.T) {
+	dir, data := writeStore(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	n, err := s.Extract(Query{Flow: "fe80::1:41000->fe80::2:443"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Fatal("expected 2 packets, got", n)
+	}
+
+	r, err := pcapgo.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []int{5, 8} {
+		d, ci, errRead := r.ReadPacketData()
+		if errRead != nil {
+			t.Fatal(errRead)
+		}
+
+		if !bytes.Equal(d, data[i]) || !ci.Timestamp.Equal(start.Add(time.Duration(i)*time.Second)) {
+			t.Fatal("unexpected packet", i)
+		}
+	}
+
+	if _, _, err = r.ReadPacketData(); !errors.Is(err, io.EOF) {
+		t.Fatal("expected EOF, got", err)
+	}
+
+	// a store that is written to again continues after the last segment
+	w, err := NewWriter(Config{Dir: dir, LinkType: layers.LinkTypeEthernet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ci := gopacket.CaptureInfo{Timestamp: start.Add(time.Hour), CaptureLength: len(data[0]), Length: len(data[0])}
+	if err = w.WritePacket(ci, data[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the segment is not indexed until the writer is closed
+	if s, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ = s.Extract(Query{Port: 53032}, ioutil.Discard); n != 5 {
+		t.Fatal("expected 5 packets before the segment is completed, got", n)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ = s.Extract(Query{Port: 53032}, ioutil.Discard); n != 6 {
+		t.Fatal("expected 6 packets, got", n)
+	}
+}
+
+func TestQueryForRecord(t *testing.T) {
+	q, err := QueryForRecord(&types.Connection{
+		SrcIP:          "192.168.1.47",
+		SrcPort:        "53032",
+		DstIP:          "165.227.109.154",
+		DstPort:        "80",
+		TimestampFirst: start.UnixNano(),
+		TimestampLast:  start.Add(time.Second).UnixNano(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Flow != "192.168.1.47:53032->165.227.109.154:80" || !q.From.Equal(start) || !q.To.After(start.Add(time.Second)) {
+		t.Fatal("unexpected query for connection", q)
+	}
+
+	if q, err = QueryForRecord(&types.File{SrcIP: "fe80::1", SrcPort: 41000, DstIP: "fe80::2", DstPort: 443}); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Flow != "fe80::1:41000->fe80::2:443" {
+		t.Fatal("unexpected query for file", q)
+	}
+
+	if q, err = QueryForRecord(&types.Credentials{Flow: "192.168.1.47:53033->10.0.0.1:22"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Flow != "192.168.1.47:53033->10.0.0.1:22" {
+		t.Fatal("unexpected query for credentials", q)
+	}
+
+	if _, err = QueryForRecord(&types.Ethernet{}); !errors.Is(err, ErrNoFlow) {
+		t.Fatal("expected error for record without flow, got", err)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packetstore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+
+	"github.com/dreadl0ck/netcap/defaults"
+)
+
+const (
+	// size of the pcap file header and the packet record header.
+	pcapFileHeaderSize   = 24
+	pcapRecordHeaderSize = 16
+
+	// defaultSnapLen is written into the segment headers if no snap length is configured.
+	defaultSnapLen = 262144
+)
+
+// Config configures a packet store writer.
+type Config struct {
+	// Dir is the directory of the store, it is created if it does not exist.
+	Dir string
+
+	// SegmentSize is the size in bytes at which a new segment is started.
+	// If it is zero, defaults.PacketStoreSegmentSize is used.
+	SegmentSize int64
+
+	// LinkType of the packets.
+	LinkType layers.LinkType
+
+	// SnapLen written into the segment headers.
+	SnapLen uint32
+}
+
+// Writer writes packets into the segments of a packet store and indexes their flows.
+// Writing to an existing store continues after its last segment.
+type Writer struct {
+	mu sync.Mutex
+
+	conf   Config
+	parser *packetParser
+
+	// current segment
+	file   *os.File
+	buf    *bufio.Writer
+	pw     *pcapgo.Writer
+	offset int64
+	index  *segmentIndex
+	flows  map[flowKey]*Flow
+
+	// number of the last segment
+	num int
+}
+
+// NewWriter creates the store directory if necessary and returns a writer for it.
+// The first segment is created with the first packet.
+func NewWriter(conf Config) (*Writer, error) {
+	if conf.SegmentSize <= 0 {
+		conf.SegmentSize = defaults.PacketStoreSegmentSize
+	}
+
+	if conf.SnapLen == 0 {
+		conf.SnapLen = defaultSnapLen
+	}
+
+	if err := os.MkdirAll(conf.Dir, defaults.DirectoryPermission); err != nil {
+		return nil, err
+	}
+
+	nums, err := segments(conf.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		conf:   conf,
+		parser: newPacketParser(conf.LinkType),
+	}
+
+	if len(nums) > 0 {
+		w.num = nums[len(nums)-1]
+	}
+
+	return w, nil
+}
+
+// WritePacket writes the packet into the current segment, which is completed once it reached the segment size.
+func (w *Writer) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.createSegment(); err != nil {
+			return err
+		}
+	}
+
+	offset := w.offset
+
+	if err := w.pw.WritePacket(ci, data); err != nil {
+		return err
+	}
+
+	w.offset += pcapRecordHeaderSize + int64(len(data))
+
+	ts := ci.Timestamp.UnixNano()
+	if w.index.first == 0 || ts < w.index.first {
+		w.index.first = ts
+	}
+
+	if ts > w.index.last {
+		w.index.last = ts
+	}
+
+	if e, ok := w.parser.parse(data); ok {
+		k := newFlowKey(e.proto, e.srcIP, e.dstIP, e.srcPort, e.dstPort)
+
+		f, exists := w.flows[k]
+		if !exists {
+			// the addresses reference the packet data, which is owned by the caller
+			f = &Flow{
+				Proto:   e.proto,
+				SrcIP:   append([]byte(nil), e.srcIP...),
+				DstIP:   append([]byte(nil), e.dstIP...),
+				SrcPort: e.srcPort,
+				DstPort: e.dstPort,
+				First:   ts,
+			}
+
+			w.flows[k] = f
+			w.index.flows = append(w.index.flows, f)
+		}
+
+		f.offsets = append(f.offsets, offset)
+
+		if ts < f.First {
+			f.First = ts
+		}
+
+		if ts > f.Last {
+			f.Last = ts
+		}
+	}
+
+	if w.offset >= w.conf.SegmentSize {
+		return w.closeSegment()
+	}
+
+	return nil
+}
+
+// Close completes the current segment.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.closeSegment()
+}
+
+// createSegment creates the next segment and writes its file header.
+func (w *Writer) createSegment() error {
+	f, err := os.Create(segmentPath(w.conf.Dir, w.num+1))
+	if err != nil {
+		return err
+	}
+
+	w.num++
+	w.file = f
+	w.buf = bufio.NewWriterSize(f, defaults.BufferSize)
+	w.pw = pcapgo.NewWriterNanos(w.buf)
+	w.offset = pcapFileHeaderSize
+	w.index = &segmentIndex{num: w.num, linkType: w.conf.LinkType}
+	w.flows = make(map[flowKey]*Flow)
+
+	return w.pw.WriteFileHeader(w.conf.SnapLen, w.conf.LinkType)
+}
+
+// closeSegment flushes and closes the current segment and writes its index.
+func (w *Writer) closeSegment() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.buf.Flush()
+	if err == nil {
+		err = w.file.Sync()
+	}
+
+	if errClose := w.file.Close(); err == nil {
+		err = errClose
+	}
+
+	w.file, w.buf, w.pw = nil, nil, nil
+
+	if err != nil {
+		return err
+	}
+
+	return writeIndex(w.conf.Dir, w.index)
+}
+
+// segments returns the numbers of the segments in dir in ascending order.
+func segments(dir string) ([]int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, segmentPrefix+"*"+segmentExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	nums := make([]int, 0, len(paths))
+
+	for _, p := range paths {
+		num, errNum := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), segmentPrefix), segmentExtension))
+		if errNum != nil {
+			continue
+		}
+
+		nums = append(nums, num)
+	}
+
+	sort.Ints(nums)
+
+	return nums, nil
+}
@@ -0,0 +1,319 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package parquet
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	pq "github.com/xitongsys/parquet-go/parquet"
+)
+
+// timestampField is the name of the int64 fields that hold nanoseconds since the unix epoch in all audit records.
+const timestampField = "Timestamp"
+
+// convertFunc copies a value of the record type into a value of the row type.
+type convertFunc func(v reflect.Value) reflect.Value
+
+// schema maps a Go struct type to the schema elements of the file
+// and to the row type handed to the parquet-go marshaller.
+//
+// The row type mirrors the struct with the unexported and XXX_ fields removed and byte slices stored as strings,
+// which parquet-go would otherwise treat as lists.
+// The element names are the Go field names, which is how parquet-go matches the fields of the rows.
+// Fields that refer to a struct type containing them, such as the linked GRE routing entries, are left out.
+type schema struct {
+	typ      reflect.Type
+	elements []*pq.SchemaElement
+	convert  convertFunc
+	leaves   int
+
+	// struct types on the path to the current node
+	parents map[reflect.Type]bool
+}
+
+func newSchema(t reflect.Type) (*schema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parquet: %s is not a struct", t)
+	}
+
+	s := &schema{
+		typ:     t,
+		parents: make(map[reflect.Type]bool),
+	}
+
+	root := &pq.SchemaElement{Name: t.Name()}
+	s.elements = append(s.elements, root)
+
+	_, conv, err := s.structure(root, t, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.leaves == 0 {
+		return nil, fmt.Errorf("parquet: %s has no exported fields", t)
+	}
+
+	s.convert = conv
+
+	return s, nil
+}
+
+// row returns the value for a record, which must be of the schema type.
+func (s *schema) row(v reflect.Value) interface{} {
+	return s.convert(v).Interface()
+}
+
+// structure adds the elements for the exported fields of a struct below the group element.
+func (s *schema) structure(group *pq.SchemaElement, t reflect.Type, path []string) (reflect.Type, convertFunc, error) {
+	var (
+		fields  []reflect.StructField
+		indices []int
+		convs   []convertFunc
+	)
+
+	s.parents[t] = true
+	defer delete(s.parents, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || strings.HasPrefix(f.Name, "XXX_") || s.parents[structType(f.Type)] {
+			continue
+		}
+
+		typ, conv, err := s.node(f.Name, f.Type, path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if f.Name == timestampField && len(path) == 0 && typ.Kind() == reflect.Int64 {
+			s.elements[len(s.elements)-1].LogicalType = &pq.LogicalType{
+				TIMESTAMP: &pq.TimestampType{
+					IsAdjustedToUTC: true,
+					Unit:            &pq.TimeUnit{NANOS: &pq.NanoSeconds{}},
+				},
+			}
+		}
+
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: typ})
+		indices = append(indices, i)
+		convs = append(convs, conv)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil, fmt.Errorf("parquet: %s has no exported fields", strings.Join(path, "."))
+	}
+
+	numChildren := int32(len(fields))
+	group.NumChildren = &numChildren
+
+	rowType := reflect.StructOf(fields)
+
+	return rowType, func(v reflect.Value) reflect.Value {
+		out := reflect.New(rowType).Elem()
+		for i, conv := range convs {
+			out.Field(i).Set(conv(v.Field(indices[i])))
+		}
+
+		return out
+	}, nil
+}
+
+// node adds the elements for a value of type t and returns its row type.
+// Messages are optional groups, lists and maps use the standard three level structure.
+func (s *schema) node(name string, t reflect.Type, path []string) (reflect.Type, convertFunc, error) {
+	path = append(path[:len(path):len(path)], name)
+
+	e := &pq.SchemaElement{
+		Name:           name,
+		RepetitionType: repetition(pq.FieldRepetitionType_REQUIRED),
+	}
+	s.elements = append(s.elements, e)
+
+	switch {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		e.Type = physical(pq.Type_BYTE_ARRAY)
+		s.leaves++
+
+		return reflect.TypeOf(""), func(v reflect.Value) reflect.Value {
+			return reflect.ValueOf(string(v.Bytes()))
+		}, nil
+
+	case t.Kind() == reflect.Slice:
+		// <name> (LIST) { repeated group list { <element> } }
+		e.ConvertedType = converted(pq.ConvertedType_LIST)
+		e.NumChildren = children(1)
+
+		s.elements = append(s.elements, &pq.SchemaElement{
+			Name:           "list",
+			RepetitionType: repetition(pq.FieldRepetitionType_REPEATED),
+			NumChildren:    children(1),
+		})
+
+		elem, conv, err := s.node("element", t.Elem(), append(path, "list"))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rowType := reflect.SliceOf(elem)
+
+		return rowType, func(v reflect.Value) reflect.Value {
+			out := reflect.MakeSlice(rowType, v.Len(), v.Len())
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(conv(v.Index(i)))
+			}
+
+			return out
+		}, nil
+
+	case t.Kind() == reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, nil, fmt.Errorf("parquet: unsupported map key type %s for %s", t.Key(), strings.Join(path, "."))
+		}
+
+		// <name> (MAP) { repeated group key_value { key, value } }
+		e.ConvertedType = converted(pq.ConvertedType_MAP)
+		e.NumChildren = children(1)
+
+		s.elements = append(s.elements, &pq.SchemaElement{
+			Name:           "key_value",
+			RepetitionType: repetition(pq.FieldRepetitionType_REPEATED),
+			NumChildren:    children(2),
+		})
+
+		kvPath := append(path, "key_value")
+
+		key, _, err := s.node("key", t.Key(), kvPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		value, conv, err := s.node("value", t.Elem(), kvPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rowType := reflect.MapOf(key, value)
+
+		return rowType, func(v reflect.Value) reflect.Value {
+			out := reflect.MakeMapWithSize(rowType, v.Len())
+			for it := v.MapRange(); it.Next(); {
+				out.SetMapIndex(it.Key(), conv(it.Value()))
+			}
+
+			return out
+		}, nil
+
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		// parquet-go increments the definition level for every pointer, they are always optional
+		e.RepetitionType = repetition(pq.FieldRepetitionType_OPTIONAL)
+
+		elem, conv, err := s.structure(e, t.Elem(), path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rowType := reflect.PtrTo(elem)
+
+		return rowType, func(v reflect.Value) reflect.Value {
+			if v.IsNil() {
+				return reflect.Zero(rowType)
+			}
+
+			out := reflect.New(elem)
+			out.Elem().Set(conv(v.Elem()))
+
+			return out
+		}, nil
+
+	case t.Kind() == reflect.Struct:
+		return s.structure(e, t, path)
+
+	default:
+		if err := leaf(e, t); err != nil {
+			return nil, nil, fmt.Errorf("%w for %s", err, strings.Join(path, "."))
+		}
+
+		s.leaves++
+
+		return t, func(v reflect.Value) reflect.Value {
+			return v
+		}, nil
+	}
+}
+
+// structType returns the struct type of messages and of the elements of lists and maps.
+func structType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// leaf sets the physical and converted type of a scalar element.
+func leaf(e *pq.SchemaElement, t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Bool:
+		e.Type = physical(pq.Type_BOOLEAN)
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		e.Type = physical(pq.Type_INT32)
+	case reflect.Int, reflect.Int64:
+		e.Type = physical(pq.Type_INT64)
+	case reflect.Uint8:
+		e.Type = physical(pq.Type_INT32)
+		e.ConvertedType = converted(pq.ConvertedType_UINT_8)
+	case reflect.Uint16:
+		e.Type = physical(pq.Type_INT32)
+		e.ConvertedType = converted(pq.ConvertedType_UINT_16)
+	case reflect.Uint32:
+		e.Type = physical(pq.Type_INT32)
+		e.ConvertedType = converted(pq.ConvertedType_UINT_32)
+	case reflect.Uint, reflect.Uint64:
+		e.Type = physical(pq.Type_INT64)
+		e.ConvertedType = converted(pq.ConvertedType_UINT_64)
+	case reflect.Float32:
+		e.Type = physical(pq.Type_FLOAT)
+	case reflect.Float64:
+		e.Type = physical(pq.Type_DOUBLE)
+	case reflect.String:
+		e.Type = physical(pq.Type_BYTE_ARRAY)
+		e.ConvertedType = converted(pq.ConvertedType_UTF8)
+	default:
+		return fmt.Errorf("parquet: unsupported type %s", t)
+	}
+
+	return nil
+}
+
+func physical(t pq.Type) *pq.Type {
+	return &t
+}
+
+func converted(t pq.ConvertedType) *pq.ConvertedType {
+	return &t
+}
+
+func repetition(r pq.FieldRepetitionType) *pq.FieldRepetitionType {
+	return &r
+}
+
+func children(n int32) *int32 {
+	return &n
+}
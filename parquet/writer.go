@@ -0,0 +1,188 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package parquet writes Apache Parquet files from Go structs, such as the netcap audit records.
+//
+// The encoding is done by github.com/xitongsys/parquet-go, this package derives the schema from the struct type:
+// nested messages become optional groups, slices become lists and maps with string keys become maps,
+// using the standard three level structure for both.
+// Int64 fields named Timestamp are annotated as timestamps in nanoseconds.
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	pq "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Codec is the compression codec for the data pages.
+type Codec int32
+
+// Compression codecs, the values are defined by the file format.
+const (
+	Uncompressed = Codec(pq.CompressionCodec_UNCOMPRESSED)
+	Snappy       = Codec(pq.CompressionCodec_SNAPPY)
+	Gzip         = Codec(pq.CompressionCodec_GZIP)
+	Zstd         = Codec(pq.CompressionCodec_ZSTD)
+)
+
+var codecNames = map[Codec]string{
+	Uncompressed: "none",
+	Snappy:       "snappy",
+	Gzip:         "gzip",
+	Zstd:         "zstd",
+}
+
+func (c Codec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Codec(%d)", int32(c))
+}
+
+// ParseCodec returns the codec for the name: none, snappy, gzip or zstd.
+func ParseCodec(name string) (Codec, error) {
+	for c, n := range codecNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return Uncompressed, fmt.Errorf("parquet: unknown codec %q, expected none, snappy, gzip or zstd", name)
+}
+
+// DefaultRowGroupSize is the amount of uncompressed data buffered per row group.
+const DefaultRowGroupSize = 8 * 1024 * 1024
+
+// Options configure a Writer.
+type Options struct {
+	// Compression codec for the data pages
+	Compression Codec
+
+	// RowGroupSize is the amount of uncompressed data after which a row group is written,
+	// rows are buffered in memory until then. Defaults to DefaultRowGroupSize.
+	RowGroupSize int
+
+	// CreatedBy is stored in the file metadata
+	CreatedBy string
+}
+
+// ErrClosed is returned when writing to a closed Writer.
+var ErrClosed = errors.New("parquet: writer is closed")
+
+// Writer writes structs of a single type as rows of a Parquet file.
+type Writer struct {
+	pw       *writer.ParquetWriter
+	schema   *schema
+	metadata map[string]string
+	closed   bool
+}
+
+// NewWriter returns a Writer for rows of the struct type t.
+func NewWriter(w io.Writer, t reflect.Type, opts Options) (*Writer, error) {
+	s, err := newSchema(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := codecNames[opts.Compression]; !ok {
+		return nil, fmt.Errorf("parquet: unsupported codec %s", opts.Compression)
+	}
+
+	if opts.RowGroupSize <= 0 {
+		opts.RowGroupSize = DefaultRowGroupSize
+	}
+
+	// the rows are marshalled on a single goroutine, the callers write from one goroutine per audit record type
+	pw, err := writer.NewParquetWriterFromWriter(w, s.elements, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pw.CompressionType = pq.CompressionCodec(opts.Compression)
+	pw.RowGroupSize = int64(opts.RowGroupSize)
+
+	if opts.CreatedBy != "" {
+		pw.Footer.CreatedBy = &opts.CreatedBy
+	}
+
+	return &Writer{
+		pw:       pw,
+		schema:   s,
+		metadata: make(map[string]string),
+	}, nil
+}
+
+// SetMetadata adds a key value pair to the file metadata.
+func (w *Writer) SetMetadata(key, value string) {
+	w.metadata[key] = value
+}
+
+// Write adds a row, v must be a struct or a pointer to a struct of the type of the Writer.
+// The row is copied, v can be reused after Write returns.
+func (w *Writer) Write(v interface{}) error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("parquet: nil row")
+		}
+
+		rv = rv.Elem()
+	}
+
+	if rv.Type() != w.schema.typ {
+		return fmt.Errorf("parquet: expected %s, got %s", w.schema.typ, rv.Type())
+	}
+
+	return w.pw.Write(w.schema.row(rv))
+}
+
+// Close writes the remaining rows and the file footer.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	keys := make([]string, 0, len(w.metadata))
+	for k := range w.metadata {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := w.metadata[k]
+		w.pw.Footer.KeyValueMetadata = append(w.pw.Footer.KeyValueMetadata, &pq.KeyValue{Key: k, Value: &v})
+	}
+
+	return w.pw.WriteStop()
+}
+
+// NumRows returns the number of rows written so far.
+func (w *Writer) NumRows() int64 {
+	// the footer counts the rows of the flushed pages
+	return w.pw.Footer.NumRows + int64(len(w.pw.Objs))
+}
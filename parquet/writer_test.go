@@ -0,0 +1,319 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package parquet
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/reader"
+	pqschema "github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+type testInner struct {
+	Name  string
+	Count uint32
+}
+
+type testRecord struct {
+	Timestamp int64
+	Flag      bool
+	Value     float64
+	Data      []byte
+	Tags      []string
+	Inner     *testInner
+	Items     []*testInner
+	Attrs     map[string]int64
+	hidden    int
+}
+
+func testRows() []*testRecord {
+	return []*testRecord{
+		{
+			Timestamp: 1,
+			Flag:      true,
+			Value:     1.5,
+			Data:      []byte{1, 2},
+			Tags:      []string{"a", "b"},
+			Inner:     &testInner{Name: "inner", Count: 4294967295},
+			Items:     []*testInner{{Name: "x", Count: 1}, {Name: "y", Count: 2}},
+			Attrs:     map[string]int64{"z": 26, "a": 1},
+		},
+		{
+			Timestamp: 2,
+		},
+		{
+			Timestamp: 3,
+			Flag:      true,
+			Tags:      []string{"c"},
+			Items:     []*testInner{nil},
+		},
+	}
+}
+
+// testList refers to itself, like the GRE routing entries.
+type testList struct {
+	Value int64
+	Next  *testList
+}
+
+// levels of the test schema: maximum repetition and definition level per column.
+var testLevels = map[string][2]int32{
+	"Timestamp":                {0, 0},
+	"Flag":                     {0, 0},
+	"Value":                    {0, 0},
+	"Data":                     {0, 0},
+	"Tags.list.element":        {1, 1},
+	"Inner.Name":               {0, 1},
+	"Inner.Count":              {0, 1},
+	"Items.list.element.Name":  {1, 2},
+	"Items.list.element.Count": {1, 2},
+	"Attrs.key_value.key":      {1, 1},
+	"Attrs.key_value.value":    {1, 1},
+}
+
+// memFile provides a written file to the parquet-go reader.
+type memFile struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newMemFile(data []byte) *memFile {
+	return &memFile{Reader: bytes.NewReader(data), data: data}
+}
+
+func (f *memFile) Open(string) (source.ParquetFile, error) {
+	return newMemFile(f.data), nil
+}
+
+func (f *memFile) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("read only")
+}
+
+func (f *memFile) Write([]byte) (int, error) {
+	return 0, errors.New("read only")
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+// testColumn is a column as returned by the reader, values of null or empty fields are nil.
+type testColumn struct {
+	rep, def []int32
+	values   []interface{}
+}
+
+func readFile(t *testing.T, data []byte) (*reader.ParquetReader, []testColumn) {
+	t.Helper()
+
+	r, err := reader.NewParquetColumnReader(newMemFile(data), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.ReadStop()
+
+	var columns []testColumn
+
+	for i := range r.SchemaHandler.ValueColumns {
+		values, rep, def, err := r.ReadColumnByIndex(int64(i), r.GetNumRows())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		columns = append(columns, testColumn{rep: rep, def: def, values: values})
+	}
+
+	return r, columns
+}
+
+func write(t *testing.T, opts Options, rows []*testRecord) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, reflect.TypeOf(testRecord{}), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.SetMetadata("netcap.type", "test")
+
+	for _, r := range rows {
+		if err = w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the rows are buffered as copies
+	for _, r := range rows {
+		if len(r.Data) > 0 {
+			r.Data[0] = 0
+		}
+	}
+
+	if err = w.Write(testInner{}); err == nil {
+		t.Fatal("expected an error for the wrong type")
+	}
+
+	if n := w.NumRows(); n != int64(len(rows)) {
+		t.Fatal("expected", len(rows), "rows, got", n)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Write(rows[0]); !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSchema(t *testing.T) {
+	s, err := newSchema(reflect.TypeOf(&testRecord{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := pqschema.NewSchemaHandlerFromSchemaList(s.elements)
+
+	if len(h.ValueColumns) != len(testLevels) || s.leaves != len(testLevels) {
+		t.Fatal("expected", len(testLevels), "columns, got", len(h.ValueColumns))
+	}
+
+	for _, c := range h.ValueColumns {
+		var (
+			path   = common.StrToPath(c)
+			key    = strings.SplitN(h.InPathToExPath[c], ".", 2)[1]
+			rep, _ = h.MaxRepetitionLevel(path)
+			def, _ = h.MaxDefinitionLevel(path)
+		)
+
+		if max, ok := testLevels[key]; !ok || max != [2]int32{rep, def} {
+			t.Fatal("unexpected levels for", key, rep, def)
+		}
+	}
+
+	if s, err = newSchema(reflect.TypeOf(testList{})); err != nil || s.leaves != 1 {
+		t.Fatal("expected the recursive field to be left out", err)
+	}
+
+	if _, err = newSchema(reflect.TypeOf(struct{ C chan int }{})); err == nil {
+		t.Fatal("expected an error for an unsupported type")
+	}
+
+	if _, err = newSchema(reflect.TypeOf(struct{ M map[int]string }{})); err == nil {
+		t.Fatal("expected an error for an unsupported map key")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	for _, codec := range []Codec{Uncompressed, Snappy, Gzip, Zstd} {
+		r, columns := readFile(t, write(t, Options{Compression: codec, CreatedBy: "netcap"}, testRows()))
+
+		if r.GetNumRows() != 3 || len(columns) != len(testLevels) || r.Footer.GetCreatedBy() != "netcap" {
+			t.Fatal(codec, "unexpected file metadata", r.GetNumRows(), len(columns), r.Footer.GetCreatedBy())
+		}
+
+		if kv := r.Footer.KeyValueMetadata; len(kv) != 1 || kv[0].Key != "netcap.type" || kv[0].GetValue() != "test" {
+			t.Fatal(codec, "unexpected key value metadata", kv)
+		}
+
+		// root, 4 scalars, Tags (3), Inner (3), Items (5), Attrs (4)
+		if len(r.Footer.Schema) != 20 {
+			t.Fatal(codec, "expected 20 schema elements, got", len(r.Footer.Schema))
+		}
+
+		if ts := r.Footer.Schema[1]; ts.Name != "Timestamp" || !ts.GetLogicalType().IsSetTIMESTAMP() {
+			t.Fatal(codec, "expected a timestamp logical type", ts)
+		}
+
+		expected := []testColumn{
+			{values: []interface{}{int64(1), int64(2), int64(3)}},
+			{values: []interface{}{true, false, true}},
+			{values: []interface{}{1.5, 0.0, 0.0}},
+			{values: []interface{}{"\x01\x02", "", ""}},
+			{rep: []int32{0, 1, 0, 0}, def: []int32{1, 1, 0, 1}, values: []interface{}{"a", "b", nil, "c"}},
+			{rep: []int32{0, 0, 0}, def: []int32{1, 0, 0}, values: []interface{}{"inner", nil, nil}},
+			{rep: []int32{0, 0, 0}, def: []int32{1, 0, 0}, values: []interface{}{int32(-1), nil, nil}},
+			{rep: []int32{0, 1, 0, 0}, def: []int32{2, 2, 0, 1}, values: []interface{}{"x", "y", nil, nil}},
+			{rep: []int32{0, 1, 0, 0}, def: []int32{2, 2, 0, 1}, values: []interface{}{int32(1), int32(2), nil, nil}},
+			{rep: []int32{0, 1, 0, 0}, def: []int32{1, 1, 0, 0}},
+			{rep: []int32{0, 1, 0, 0}, def: []int32{1, 1, 0, 0}},
+		}
+
+		for i, e := range expected {
+			c := columns[i]
+
+			if e.rep != nil && (!reflect.DeepEqual(c.rep, e.rep) || !reflect.DeepEqual(c.def, e.def)) {
+				t.Fatal(codec, i, "unexpected levels", c.rep, c.def)
+			}
+
+			if e.values != nil && !reflect.DeepEqual(c.values, e.values) {
+				t.Fatal(codec, i, "unexpected values", c.values)
+			}
+		}
+
+		// the order of the map entries is not defined
+		attrs := make(map[string]int64)
+		for i, k := range columns[9].values[:2] {
+			attrs[k.(string)] = columns[10].values[i].(int64)
+		}
+
+		if !reflect.DeepEqual(attrs, testRows()[0].Attrs) {
+			t.Fatal(codec, "unexpected map", attrs)
+		}
+	}
+}
+
+func TestRowGroups(t *testing.T) {
+	var rows []*testRecord
+	for i := 0; i < 10000; i++ {
+		rows = append(rows, testRows()...)
+	}
+
+	r, columns := readFile(t, write(t, Options{Compression: Snappy, RowGroupSize: 1024}, rows))
+
+	if n := len(r.Footer.RowGroups); n < 2 {
+		t.Fatal("expected multiple row groups, got", n)
+	}
+
+	if r.GetNumRows() != 30000 || len(columns[0].values) != 30000 || columns[0].values[29999] != int64(3) {
+		t.Fatal("unexpected timestamps", r.GetNumRows(), len(columns[0].values))
+	}
+
+	// Tags, one level per element and an empty level for the second row
+	if tags := columns[4]; len(tags.rep) != 40000 || tags.values[39999] != "c" {
+		t.Fatal("unexpected tags", len(tags.rep))
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	for _, c := range []Codec{Uncompressed, Snappy, Gzip, Zstd} {
+		if p, err := ParseCodec(c.String()); err != nil || p != c {
+			t.Fatal("failed to parse", c)
+		}
+	}
+
+	if _, err := ParseCodec("lzo"); err == nil {
+		t.Fatal("expected an error for an unsupported codec")
+	}
+}
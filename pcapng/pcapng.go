@@ -0,0 +1,225 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package pcapng implements reading and writing of the PCAP Next Generation capture file format,
+// including the metadata that is discarded by the gopacket pcapng reader:
+// multiple interfaces per section, packet comments, Enhanced Packet Block flags and interface statistics.
+//
+// The format is specified in https://tools.ietf.org/html/draft-tuexen-opsawg-pcapng.
+package pcapng
+
+import (
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// block types.
+const (
+	blockTypeInterfaceDescription = 0x00000001
+	blockTypePacket               = 0x00000002 // obsolete
+	blockTypeSimplePacket         = 0x00000003
+	blockTypeInterfaceStatistics  = 0x00000005
+	blockTypeEnhancedPacket       = 0x00000006
+	blockTypeSectionHeader        = 0x0A0D0D0A
+)
+
+const byteOrderMagic uint32 = 0x1A2B3C4D
+
+// option codes, the codes above 1 are specific to the block type.
+const (
+	optEndOfOpt = 0
+	optComment  = 1
+
+	optSHBHardware = 2
+	optSHBOS       = 3
+	optSHBUserAppl = 4
+
+	optIFName        = 2
+	optIFDescription = 3
+	optIFSpeed       = 8
+	optIFTsResol     = 9
+	optIFFilter      = 11
+	optIFOS          = 12
+	optIFFCSLen      = 13
+	optIFTsOffset    = 14
+	optIFHardware    = 15
+
+	optEPBFlags     = 2
+	optEPBDropCount = 4
+
+	optISBStartTime    = 2
+	optISBEndTime      = 3
+	optISBIfRecv       = 4
+	optISBIfDrop       = 5
+	optISBFilterAccept = 6
+	optISBOSDrop       = 7
+	optISBUsrDeliv     = 8
+)
+
+// maxBlockSize guards against allocating huge buffers for corrupted block lengths.
+const maxBlockSize = 64 * 1024 * 1024
+
+// Section holds the metadata of a Section Header Block.
+type Section struct {
+	MajorVersion uint16
+	MinorVersion uint16
+	Hardware     string
+	OS           string
+	Application  string
+	Comment      string
+}
+
+// Interface describes a capture interface, as defined by an Interface Description Block.
+// Interfaces are numbered in the order of their description blocks, starting at zero for each section.
+type Interface struct {
+	ID          int
+	Name        string
+	Description string
+	Comment     string
+	LinkType    layers.LinkType
+	SnapLen     uint32
+	Speed       uint64
+	Filter      string
+	OS          string
+	Hardware    string
+	FCSLen      uint8
+
+	// Statistics holds the most recent Interface Statistics Block of the interface, if any.
+	Statistics *InterfaceStatistics
+
+	// timestamp units per second and offset in seconds
+	tsUnits  uint64
+	tsOffset int64
+}
+
+// String returns the interface name, or its description or ID if the name is not set.
+func (i *Interface) String() string {
+	switch {
+	case i.Name != "":
+		return i.Name
+	case i.Description != "":
+		return i.Description
+	default:
+		return strconv.Itoa(i.ID)
+	}
+}
+
+// timestamp converts a timestamp in the resolution of the interface.
+func (i *Interface) timestamp(ts uint64) time.Time {
+	secs, frac := ts/i.tsUnits, ts%i.tsUnits
+
+	// frac is lower than the units, so the product fits into 128 bits and the quotient into 64
+	hi, lo := bits.Mul64(frac, uint64(time.Second))
+	nanos, _ := bits.Div64(hi, lo, i.tsUnits)
+
+	return time.Unix(int64(secs)+i.tsOffset, int64(nanos)).UTC()
+}
+
+// InterfaceStatistics holds the counters of an Interface Statistics Block.
+// Counters that are not present in the block are zero.
+type InterfaceStatistics struct {
+	Timestamp      time.Time
+	StartTime      time.Time
+	EndTime        time.Time
+	Received       uint64
+	Dropped        uint64
+	FilterAccepted uint64
+	OSDropped      uint64
+	Delivered      uint64
+	Comment        string
+}
+
+// Direction of a packet, from the Enhanced Packet Block flags.
+type Direction uint8
+
+// packet directions.
+const (
+	DirectionUnknown Direction = iota
+	DirectionInbound
+	DirectionOutbound
+)
+
+// String returns the name of the direction, or an empty string if it is unknown.
+func (d Direction) String() string {
+	switch d {
+	case DirectionInbound:
+		return "inbound"
+	case DirectionOutbound:
+		return "outbound"
+	default:
+		return ""
+	}
+}
+
+// ReceptionType of a packet, from the Enhanced Packet Block flags.
+type ReceptionType uint8
+
+// packet reception types.
+const (
+	ReceptionUnknown ReceptionType = iota
+	ReceptionUnicast
+	ReceptionMulticast
+	ReceptionBroadcast
+	ReceptionPromiscuous
+)
+
+// PacketInfo holds the metadata of a packet block.
+// The reader passes it in the AncillaryData of the gopacket.CaptureInfo, use Info to retrieve it.
+type PacketInfo struct {
+	// Interface the packet was captured on
+	Interface *Interface
+
+	// Comments of the packet
+	Comments []string
+
+	// Flags as defined for the epb_flags option
+	Flags uint32
+
+	// DropCount is the number of packets lost between this packet and the preceding one on the interface
+	DropCount uint64
+}
+
+// Direction returns the direction of the packet.
+func (p *PacketInfo) Direction() Direction {
+	return Direction(p.Flags & 0x3)
+}
+
+// ReceptionType returns the reception type of the packet.
+func (p *PacketInfo) ReceptionType() ReceptionType {
+	return ReceptionType((p.Flags >> 2) & 0x7)
+}
+
+// FCSLength returns the length of the frame check sequence in octets, zero if unknown.
+func (p *PacketInfo) FCSLength() int {
+	return int((p.Flags >> 5) & 0xf)
+}
+
+// LinkLayerErrors returns the link-layer-dependent error bits, for example the CRC error bit 24 of the flags.
+func (p *PacketInfo) LinkLayerErrors() uint16 {
+	return uint16(p.Flags >> 16)
+}
+
+// Info returns the pcapng metadata of a packet, or nil if it was not read from a pcapng file.
+func Info(ci *gopacket.CaptureInfo) *PacketInfo {
+	for _, d := range ci.AncillaryData {
+		if info, ok := d.(*PacketInfo); ok {
+			return info
+		}
+	}
+
+	return nil
+}
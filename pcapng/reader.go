@@ -0,0 +1,592 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pcapng
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+var (
+	// ErrNotPcapNG occurs when the input does not start with a Section Header Block.
+	ErrNotPcapNG = errors.New("not a pcapng file")
+
+	// ErrNoInterface occurs when the first section does not describe an interface before its end.
+	ErrNoInterface = errors.New("no interface description block")
+
+	errInvalidBlock     = errors.New("invalid pcapng block")
+	errUnknownInterface = errors.New("packet block references an unknown interface")
+)
+
+// Reader reads packets from a pcapng stream.
+// All sections of the stream are read, packets carry the metadata of their block as PacketInfo.
+type Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+
+	section    Section
+	interfaces []*Interface
+
+	// StatisticsHandler is called for each Interface Statistics Block, if set.
+	StatisticsHandler func(iface *Interface, stats *InterfaceStatistics)
+
+	// buffer and packet info reused by ZeroCopyReadPacketData
+	buf  []byte
+	info PacketInfo
+}
+
+// NewReader reads the header of a pcapng stream, up to the first interface description,
+// and returns a reader for its packets.
+func NewReader(r io.Reader) (*Reader, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	nr := &Reader{r: br}
+
+	typ, body, err := nr.readBlock(nil)
+	if err != nil {
+		if errors.Is(err, errInvalidBlock) || errors.Is(err, io.EOF) {
+			return nil, ErrNotPcapNG
+		}
+
+		return nil, err
+	}
+
+	if typ != blockTypeSectionHeader {
+		return nil, ErrNotPcapNG
+	}
+
+	if err = nr.handleSectionHeader(body); err != nil {
+		return nil, err
+	}
+
+	// the link type is known after the first interface description
+	for len(nr.interfaces) == 0 {
+		typ, body, err = nr.readBlock(nil)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, ErrNoInterface
+			}
+
+			return nil, err
+		}
+
+		switch typ {
+		case blockTypeInterfaceDescription:
+			err = nr.handleInterfaceDescription(body)
+		case blockTypeSectionHeader:
+			err = nr.handleSectionHeader(body)
+		case blockTypePacket, blockTypeSimplePacket, blockTypeEnhancedPacket, blockTypeInterfaceStatistics:
+			err = errUnknownInterface
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nr, nil
+}
+
+// LinkType returns the link type of the first interface.
+func (r *Reader) LinkType() layers.LinkType {
+	return r.interfaces[0].LinkType
+}
+
+// Section returns the metadata of the current section.
+func (r *Reader) Section() Section {
+	return r.section
+}
+
+// Interfaces returns the interfaces of the current section.
+func (r *Reader) Interfaces() []*Interface {
+	return r.interfaces
+}
+
+// ReadPacketData returns the next packet. The data is owned by the caller,
+// the PacketInfo of the packet is passed in the AncillaryData of the capture info.
+func (r *Reader) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	info := new(PacketInfo)
+
+	data, ci, err = r.readPacket(nil, info)
+	if err != nil {
+		return nil, ci, err
+	}
+
+	ci.AncillaryData = []interface{}{info}
+
+	return data, ci, nil
+}
+
+// ZeroCopyReadPacketData returns the next packet. The data and the PacketInfo in the AncillaryData
+// of the capture info are only valid until the next call.
+func (r *Reader) ZeroCopyReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	r.info = PacketInfo{Comments: r.info.Comments[:0]}
+
+	data, ci, err = r.readPacket(&r.buf, &r.info)
+	if err != nil {
+		return nil, ci, err
+	}
+
+	ci.AncillaryData = []interface{}{&r.info}
+
+	return data, ci, nil
+}
+
+// readPacket reads blocks until the next packet block, and handles all other blocks on the way.
+func (r *Reader) readPacket(buf *[]byte, info *PacketInfo) (data []byte, ci gopacket.CaptureInfo, err error) {
+	for {
+		typ, body, errRead := r.readBlock(buf)
+		if errRead != nil {
+			return nil, ci, errRead
+		}
+
+		switch typ {
+		case blockTypeEnhancedPacket:
+			return r.handleEnhancedPacket(body, info)
+		case blockTypeSimplePacket:
+			return r.handleSimplePacket(body, info)
+		case blockTypePacket:
+			return r.handlePacket(body, info)
+		case blockTypeInterfaceDescription:
+			err = r.handleInterfaceDescription(body)
+		case blockTypeInterfaceStatistics:
+			err = r.handleInterfaceStatistics(body)
+		case blockTypeSectionHeader:
+			// interfaces are local to a section
+			r.interfaces = nil
+			err = r.handleSectionHeader(body)
+		}
+
+		// other blocks, like name resolution and custom blocks, are skipped
+		if err != nil {
+			return nil, ci, err
+		}
+	}
+}
+
+// readBlock reads the next block and returns its type and body, without the trailing length.
+// The body is read into buf if it is not nil, otherwise a new slice is allocated.
+func (r *Reader) readBlock(buf *[]byte) (typ uint32, body []byte, err error) {
+	var hdr [12]byte
+
+	if _, err = io.ReadFull(r.r, hdr[:8]); err != nil {
+		return 0, nil, err
+	}
+
+	// the section header block type is a palindrome, its byte order magic sets the order of the section
+	if binary.LittleEndian.Uint32(hdr[:4]) == blockTypeSectionHeader {
+		if _, err = io.ReadFull(r.r, hdr[8:12]); err != nil {
+			return 0, nil, unexpected(err)
+		}
+
+		switch byteOrderMagic {
+		case binary.LittleEndian.Uint32(hdr[8:12]):
+			r.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(hdr[8:12]):
+			r.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("%w: unknown byte order magic %x", errInvalidBlock, hdr[8:12])
+		}
+	} else if r.order == nil {
+		return 0, nil, errInvalidBlock
+	}
+
+	typ = r.order.Uint32(hdr[:4])
+	length := r.order.Uint32(hdr[4:8])
+
+	if length < 12 || length%4 != 0 || length > maxBlockSize {
+		return 0, nil, fmt.Errorf("%w: block type %#x with length %d", errInvalidBlock, typ, length)
+	}
+
+	size := int(length) - 8
+
+	if buf != nil && cap(*buf) >= size {
+		body = (*buf)[:size]
+	} else {
+		body = make([]byte, size)
+		if buf != nil {
+			*buf = body
+		}
+	}
+
+	// the byte order magic has already been consumed
+	offset := 0
+	if typ == blockTypeSectionHeader {
+		offset = copy(body, hdr[8:12])
+	}
+
+	if _, err = io.ReadFull(r.r, body[offset:]); err != nil {
+		return 0, nil, unexpected(err)
+	}
+
+	if trailer := r.order.Uint32(body[size-4:]); trailer != length {
+		return 0, nil, fmt.Errorf("%w: block type %#x with length %d has trailing length %d", errInvalidBlock, typ, length, trailer)
+	}
+
+	return typ, body[:size-4], nil
+}
+
+// unexpected converts an EOF inside of a block to io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// options calls fn for each option in data, until the end of options or the end of data.
+func (r *Reader) options(data []byte, fn func(code uint16, value []byte)) {
+	for len(data) >= 4 {
+		code := r.order.Uint16(data[:2])
+		length := int(r.order.Uint16(data[2:4]))
+
+		if code == optEndOfOpt || 4+length > len(data) {
+			return
+		}
+
+		fn(code, data[4:4+length])
+
+		// values are padded to 32 bits
+		next := 4 + (length+3)&^3
+		if next > len(data) {
+			return
+		}
+
+		data = data[next:]
+	}
+}
+
+func (r *Reader) handleSectionHeader(body []byte) error {
+	if len(body) < 16 {
+		return errInvalidBlock
+	}
+
+	r.section = Section{
+		MajorVersion: r.order.Uint16(body[4:6]),
+		MinorVersion: r.order.Uint16(body[6:8]),
+	}
+
+	if r.section.MajorVersion != 1 {
+		return fmt.Errorf("unsupported pcapng version %d.%d", r.section.MajorVersion, r.section.MinorVersion)
+	}
+
+	r.options(body[16:], func(code uint16, value []byte) {
+		switch code {
+		case optComment:
+			r.section.Comment = string(value)
+		case optSHBHardware:
+			r.section.Hardware = string(value)
+		case optSHBOS:
+			r.section.OS = string(value)
+		case optSHBUserAppl:
+			r.section.Application = string(value)
+		}
+	})
+
+	return nil
+}
+
+func (r *Reader) handleInterfaceDescription(body []byte) error {
+	if len(body) < 8 {
+		return errInvalidBlock
+	}
+
+	iface := &Interface{
+		ID:       len(r.interfaces),
+		LinkType: layers.LinkType(r.order.Uint16(body[:2])),
+		SnapLen:  r.order.Uint32(body[4:8]),
+		tsUnits:  1e6,
+	}
+
+	var err error
+
+	r.options(body[8:], func(code uint16, value []byte) {
+		switch code {
+		case optComment:
+			iface.Comment = string(value)
+		case optIFName:
+			iface.Name = string(value)
+		case optIFDescription:
+			iface.Description = string(value)
+		case optIFSpeed:
+			if len(value) == 8 {
+				iface.Speed = r.order.Uint64(value)
+			}
+		case optIFTsResol:
+			if len(value) == 1 {
+				iface.tsUnits, err = timestampUnits(value[0])
+			}
+		case optIFFilter:
+			// the first octet is the filter type, zero for a libpcap filter string
+			if len(value) > 1 && value[0] == 0 {
+				iface.Filter = string(value[1:])
+			}
+		case optIFOS:
+			iface.OS = string(value)
+		case optIFFCSLen:
+			if len(value) == 1 {
+				iface.FCSLen = value[0]
+			}
+		case optIFTsOffset:
+			if len(value) == 8 {
+				iface.tsOffset = int64(r.order.Uint64(value))
+			}
+		case optIFHardware:
+			iface.Hardware = string(value)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	r.interfaces = append(r.interfaces, iface)
+
+	return nil
+}
+
+// timestampUnits returns the timestamp units per second for the if_tsresol option value.
+// The most significant bit selects a negative power of two instead of a negative power of ten.
+func timestampUnits(resol uint8) (uint64, error) {
+	exp := uint64(resol & 0x7f)
+
+	if resol&0x80 != 0 {
+		if exp > 63 {
+			return 0, fmt.Errorf("unsupported timestamp resolution 2^-%d", exp)
+		}
+
+		return 1 << exp, nil
+	}
+
+	if exp > 19 {
+		return 0, fmt.Errorf("unsupported timestamp resolution 10^-%d", exp)
+	}
+
+	units := uint64(1)
+	for i := uint64(0); i < exp; i++ {
+		units *= 10
+	}
+
+	return units, nil
+}
+
+func (r *Reader) iface(id uint32) (*Interface, error) {
+	if int(id) >= len(r.interfaces) {
+		return nil, fmt.Errorf("%w: %d", errUnknownInterface, id)
+	}
+
+	return r.interfaces[id], nil
+}
+
+// packetData returns the captured data of a packet block and its options.
+func packetData(body []byte, offset int, capLen uint32) (data, options []byte, err error) {
+	end := offset + int(capLen)
+	if end > len(body) || end < offset {
+		return nil, nil, fmt.Errorf("%w: captured length %d exceeds block", errInvalidBlock, capLen)
+	}
+
+	next := offset + (int(capLen)+3)&^3
+	if next > len(body) {
+		next = len(body)
+	}
+
+	return body[offset:end], body[next:], nil
+}
+
+func (r *Reader) handleEnhancedPacket(body []byte, info *PacketInfo) ([]byte, gopacket.CaptureInfo, error) {
+	var ci gopacket.CaptureInfo
+
+	if len(body) < 20 {
+		return nil, ci, errInvalidBlock
+	}
+
+	iface, err := r.iface(r.order.Uint32(body[:4]))
+	if err != nil {
+		return nil, ci, err
+	}
+
+	data, opts, err := packetData(body, 20, r.order.Uint32(body[12:16]))
+	if err != nil {
+		return nil, ci, err
+	}
+
+	ci.InterfaceIndex = iface.ID
+	ci.Timestamp = iface.timestamp(uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12])))
+	ci.CaptureLength = len(data)
+	ci.Length = int(r.order.Uint32(body[16:20]))
+
+	info.Interface = iface
+
+	r.options(opts, func(code uint16, value []byte) {
+		switch code {
+		case optComment:
+			info.Comments = append(info.Comments, string(value))
+		case optEPBFlags:
+			if len(value) == 4 {
+				info.Flags = r.order.Uint32(value)
+			}
+		case optEPBDropCount:
+			if len(value) == 8 {
+				info.DropCount = r.order.Uint64(value)
+			}
+		}
+	})
+
+	return data, ci, nil
+}
+
+// handleSimplePacket reads a packet of the first interface, simple packet blocks have no timestamp.
+func (r *Reader) handleSimplePacket(body []byte, info *PacketInfo) ([]byte, gopacket.CaptureInfo, error) {
+	var ci gopacket.CaptureInfo
+
+	if len(body) < 4 {
+		return nil, ci, errInvalidBlock
+	}
+
+	iface, err := r.iface(0)
+	if err != nil {
+		return nil, ci, err
+	}
+
+	length := r.order.Uint32(body[:4])
+
+	capLen := length
+	if iface.SnapLen != 0 && capLen > iface.SnapLen {
+		capLen = iface.SnapLen
+	}
+
+	if int(capLen) > len(body)-4 {
+		capLen = uint32(len(body) - 4)
+	}
+
+	data, _, err := packetData(body, 4, capLen)
+	if err != nil {
+		return nil, ci, err
+	}
+
+	ci.CaptureLength = len(data)
+	ci.Length = int(length)
+
+	info.Interface = iface
+
+	return data, ci, nil
+}
+
+// handlePacket reads an obsolete packet block, which has a 16 bit drop counter.
+func (r *Reader) handlePacket(body []byte, info *PacketInfo) ([]byte, gopacket.CaptureInfo, error) {
+	var ci gopacket.CaptureInfo
+
+	if len(body) < 20 {
+		return nil, ci, errInvalidBlock
+	}
+
+	iface, err := r.iface(uint32(r.order.Uint16(body[:2])))
+	if err != nil {
+		return nil, ci, err
+	}
+
+	data, opts, err := packetData(body, 20, r.order.Uint32(body[12:16]))
+	if err != nil {
+		return nil, ci, err
+	}
+
+	ci.InterfaceIndex = iface.ID
+	ci.Timestamp = iface.timestamp(uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12])))
+	ci.CaptureLength = len(data)
+	ci.Length = int(r.order.Uint32(body[16:20]))
+
+	info.Interface = iface
+	info.DropCount = uint64(r.order.Uint16(body[2:4]))
+
+	r.options(opts, func(code uint16, value []byte) {
+		switch code {
+		case optComment:
+			info.Comments = append(info.Comments, string(value))
+		case optEPBFlags:
+			if len(value) == 4 {
+				info.Flags = r.order.Uint32(value)
+			}
+		}
+	})
+
+	return data, ci, nil
+}
+
+func (r *Reader) handleInterfaceStatistics(body []byte) error {
+	if len(body) < 12 {
+		return errInvalidBlock
+	}
+
+	iface, err := r.iface(r.order.Uint32(body[:4]))
+	if err != nil {
+		return err
+	}
+
+	// the start and end time options use the same high and low word layout as the block timestamp
+	timestamp := func(value []byte) time.Time {
+		return iface.timestamp(uint64(r.order.Uint32(value[:4]))<<32 | uint64(r.order.Uint32(value[4:8])))
+	}
+
+	stats := &InterfaceStatistics{
+		Timestamp: timestamp(body[4:12]),
+	}
+
+	r.options(body[12:], func(code uint16, value []byte) {
+		if code == optComment {
+			stats.Comment = string(value)
+
+			return
+		}
+
+		if len(value) != 8 {
+			return
+		}
+
+		switch code {
+		case optISBStartTime:
+			stats.StartTime = timestamp(value)
+		case optISBEndTime:
+			stats.EndTime = timestamp(value)
+		case optISBIfRecv:
+			stats.Received = r.order.Uint64(value)
+		case optISBIfDrop:
+			stats.Dropped = r.order.Uint64(value)
+		case optISBFilterAccept:
+			stats.FilterAccepted = r.order.Uint64(value)
+		case optISBOSDrop:
+			stats.OSDropped = r.order.Uint64(value)
+		case optISBUsrDeliv:
+			stats.Delivered = r.order.Uint64(value)
+		}
+	})
+
+	iface.Statistics = stats
+
+	if r.StatisticsHandler != nil {
+		r.StatisticsHandler(iface, stats)
+	}
+
+	return nil
+}
@@ -0,0 +1,328 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+)
+
+// testBlocks builds pcapng blocks in the given byte order.
+type testBlocks struct {
+	bytes.Buffer
+	order binary.ByteOrder
+}
+
+func (b *testBlocks) u16(v uint16) []byte {
+	out := make([]byte, 2)
+	b.order.PutUint16(out, v)
+
+	return out
+}
+
+func (b *testBlocks) u32(v uint32) []byte {
+	out := make([]byte, 4)
+	b.order.PutUint32(out, v)
+
+	return out
+}
+
+func (b *testBlocks) u64(v uint64) []byte {
+	out := make([]byte, 8)
+	b.order.PutUint64(out, v)
+
+	return out
+}
+
+// ts encodes a timestamp as high and low word.
+func (b *testBlocks) ts(v uint64) []byte {
+	return append(b.u32(uint32(v>>32)), b.u32(uint32(v))...)
+}
+
+// pad pads packet data to 32 bits.
+func pad(data []byte) []byte {
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+
+	return data
+}
+
+func (b *testBlocks) option(code uint16, value []byte) []byte {
+	out := append(b.u16(code), b.u16(uint16(len(value)))...)
+	out = append(out, value...)
+
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+
+	return out
+}
+
+func (b *testBlocks) block(typ uint32, body ...[]byte) {
+	var data []byte
+	for _, d := range body {
+		data = append(data, d...)
+	}
+
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+
+	length := uint32(len(data) + 12)
+
+	b.Write(b.u32(typ))
+	b.Write(b.u32(length))
+	b.Write(data)
+	b.Write(b.u32(length))
+}
+
+func (b *testBlocks) sectionHeader(opts ...[]byte) {
+	b.block(blockTypeSectionHeader, append([][]byte{b.u32(byteOrderMagic), b.u16(1), b.u16(0), b.u64(^uint64(0))}, opts...)...)
+}
+
+func TestReader(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		b := &testBlocks{order: order}
+
+		b.sectionHeader(b.option(optSHBOS, []byte("Linux")), b.option(optSHBUserAppl, []byte("netcap")))
+		b.block(blockTypeInterfaceDescription, b.u16(uint16(layers.LinkTypeEthernet)), b.u16(0), b.u32(65535),
+			b.option(optIFName, []byte("eth0")),
+			b.option(optIFFilter, append([]byte{0}, "tcp"...)),
+		)
+		// name resolution blocks are skipped
+		b.block(0x00000004, b.u32(0))
+		// nanosecond resolution and an offset of one second
+		b.block(blockTypeInterfaceDescription, b.u16(uint16(layers.LinkTypeRaw)), b.u16(0), b.u32(0),
+			b.option(optIFDescription, []byte("tunnel")),
+			b.option(optIFTsResol, []byte{9}),
+			b.option(optIFTsOffset, b.u64(1)),
+		)
+		b.block(blockTypeEnhancedPacket, b.u32(0), b.ts(1500000), b.u32(3), b.u32(60), pad([]byte{1, 2, 3}),
+			b.option(optComment, []byte("first")),
+			b.option(optComment, []byte("second")),
+			b.option(optEPBFlags, b.u32(2|3<<2|1<<24)),
+			b.option(optEPBDropCount, b.u64(7)),
+		)
+		b.block(blockTypeEnhancedPacket, b.u32(1), b.ts(2000000042), b.u32(4), b.u32(4), []byte{4, 5, 6, 7})
+		b.block(blockTypeSimplePacket, b.u32(2), []byte{8, 9})
+		b.block(blockTypePacket, b.u16(0), b.u16(3), b.ts(3000000), b.u32(1), b.u32(1), pad([]byte{10}),
+			b.option(optEPBFlags, b.u32(1)),
+		)
+		b.block(blockTypeInterfaceStatistics, b.u32(0), b.ts(4000000),
+			b.option(optISBStartTime, b.ts(1000000)),
+			b.option(optISBIfRecv, b.u64(100)),
+			b.option(optISBIfDrop, b.u64(5)),
+			b.option(optISBOSDrop, b.u64(2)),
+		)
+		// a new section resets the interfaces
+		b.sectionHeader()
+		b.block(blockTypeInterfaceDescription, b.u16(uint16(layers.LinkTypeLinuxSLL)), b.u16(0), b.u32(0))
+		b.block(blockTypeEnhancedPacket, b.u32(0), b.ts(5000000), b.u32(1), b.u32(1), []byte{11})
+
+		var stats []*InterfaceStatistics
+
+		r, err := NewReader(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Fatal(order, err)
+		}
+
+		r.StatisticsHandler = func(iface *Interface, s *InterfaceStatistics) {
+			if iface.Name != "eth0" {
+				t.Fatal("unexpected interface", iface)
+			}
+
+			stats = append(stats, s)
+		}
+
+		if r.LinkType() != layers.LinkTypeEthernet || r.Section().OS != "Linux" || r.Section().Application != "netcap" {
+			t.Fatal(order, "unexpected header", r.LinkType(), r.Section())
+		}
+
+		// first packet
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			t.Fatal(order, err)
+		}
+
+		info := Info(&ci)
+		if info == nil || info.Interface.Name != "eth0" || info.Interface.Filter != "tcp" {
+			t.Fatal(order, "unexpected info", info)
+		}
+
+		if !bytes.Equal(data, []byte{1, 2, 3}) || ci.Length != 60 || !ci.Timestamp.Equal(time.Unix(1, 5e8)) {
+			t.Fatal(order, "unexpected packet", data, ci)
+		}
+
+		if len(info.Comments) != 2 || info.Comments[1] != "second" || info.DropCount != 7 {
+			t.Fatal(order, "unexpected comments or drop count", info.Comments, info.DropCount)
+		}
+
+		if info.Direction() != DirectionOutbound || info.ReceptionType() != ReceptionBroadcast || info.LinkLayerErrors() != 1<<8 {
+			t.Fatal(order, "unexpected flags", info.Flags)
+		}
+
+		// second interface
+		data, ci, err = r.ReadPacketData()
+		if err != nil {
+			t.Fatal(order, err)
+		}
+
+		info = Info(&ci)
+		if info.Interface.LinkType != layers.LinkTypeRaw || info.Interface.String() != "tunnel" || ci.InterfaceIndex != 1 {
+			t.Fatal(order, "unexpected interface", info.Interface)
+		}
+
+		if len(data) != 4 || !ci.Timestamp.Equal(time.Unix(3, 42)) {
+			t.Fatal(order, "unexpected packet", data, ci.Timestamp)
+		}
+
+		// simple packet, truncated to the block
+		data, ci, err = r.ReadPacketData()
+		if err != nil {
+			t.Fatal(order, err)
+		}
+
+		if !bytes.Equal(data, []byte{8, 9}) || ci.Length != 2 || Info(&ci).Interface.ID != 0 {
+			t.Fatal(order, "unexpected simple packet", data, ci)
+		}
+
+		// obsolete packet block
+		data, ci, err = r.ZeroCopyReadPacketData()
+		if err != nil {
+			t.Fatal(order, err)
+		}
+
+		info = Info(&ci)
+		if !bytes.Equal(data, []byte{10}) || info.DropCount != 3 || info.Direction() != DirectionInbound {
+			t.Fatal(order, "unexpected packet block", data, info)
+		}
+
+		// statistics are handled before the packet of the next section
+		_, ci, err = r.ReadPacketData()
+		if err != nil {
+			t.Fatal(order, err)
+		}
+
+		if len(stats) != 1 || stats[0].Received != 100 || stats[0].Dropped != 5 || stats[0].OSDropped != 2 {
+			t.Fatal(order, "unexpected statistics", stats)
+		}
+
+		if !stats[0].Timestamp.Equal(time.Unix(4, 0)) || !stats[0].StartTime.Equal(time.Unix(1, 0)) || !stats[0].EndTime.IsZero() {
+			t.Fatal(order, "unexpected statistics times", stats[0])
+		}
+
+		if info = Info(&ci); info.Interface.LinkType != layers.LinkTypeLinuxSLL || info.Interface.ID != 0 || len(r.Interfaces()) != 1 {
+			t.Fatal(order, "unexpected interface of the second section", info.Interface)
+		}
+
+		if _, _, err = r.ReadPacketData(); !errors.Is(err, io.EOF) {
+			t.Fatal(order, "expected EOF, got", err)
+		}
+	}
+}
+
+func TestReaderGoPacketCompatibility(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := pcapgo.NewNgWriter(&buf, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 1000, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		data := make([]byte, 60)
+		data[0] = byte(i)
+
+		err = w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     ts.Add(time.Duration(i) * time.Second),
+			CaptureLength: len(data),
+			Length:        len(data),
+		}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		data, ci, errRead := r.ReadPacketData()
+		if errRead != nil {
+			t.Fatal(errRead)
+		}
+
+		if data[0] != byte(i) || !ci.Timestamp.Equal(ts.Add(time.Duration(i)*time.Second)) {
+			t.Fatal("unexpected packet", i, data[0], ci.Timestamp)
+		}
+	}
+
+	if _, _, err = r.ReadPacketData(); !errors.Is(err, io.EOF) {
+		t.Fatal("expected EOF, got", err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte{0xd4, 0xc3, 0xb2, 0xa1, 0, 0, 0, 0})); !errors.Is(err, ErrNotPcapNG) {
+		t.Fatal("expected ErrNotPcapNG for pcap, got", err)
+	}
+
+	b := &testBlocks{order: binary.LittleEndian}
+	b.sectionHeader()
+
+	if _, err := NewReader(bytes.NewReader(b.Bytes())); !errors.Is(err, ErrNoInterface) {
+		t.Fatal("expected ErrNoInterface, got", err)
+	}
+
+	b.block(blockTypeInterfaceDescription, b.u16(uint16(layers.LinkTypeEthernet)), b.u16(0), b.u32(0))
+	b.block(blockTypeEnhancedPacket, b.u32(1), b.ts(0), b.u32(1), b.u32(1), []byte{1})
+
+	r, err := NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = r.ReadPacketData(); !errors.Is(err, errUnknownInterface) {
+		t.Fatal("expected errUnknownInterface, got", err)
+	}
+
+	// truncated block
+	data := b.Bytes()
+
+	r, err = NewReader(bytes.NewReader(data[:len(data)-2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = r.ReadPacketData(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("expected io.ErrUnexpectedEOF, got", err)
+	}
+}
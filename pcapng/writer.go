@@ -0,0 +1,241 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pcapng
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/dreadl0ck/gopacket"
+)
+
+// tsResolNanos is the if_tsresol option value for nanosecond timestamps.
+const tsResolNanos = 9
+
+// errForeignInterface occurs when a packet references an interface that was not added to the writer.
+var errForeignInterface = errors.New("packet references an interface that was not added to the writer")
+
+// Writer writes packets to a pcapng stream, as a single section in little endian byte order.
+// Timestamps are written with nanosecond resolution.
+// The writer does not buffer, wrap the underlying writer into a bufio.Writer for performance.
+type Writer struct {
+	w          io.Writer
+	interfaces []*Interface
+
+	// buffers reused for the packet blocks
+	buf  []byte
+	opts []byte
+}
+
+// NewWriter writes the Section Header Block for section and returns a writer for its interfaces and packets.
+// The version of the section is always 1.0.
+func NewWriter(w io.Writer, section Section) (*Writer, error) {
+	nw := &Writer{w: w}
+
+	body := appendUint32(nil, byteOrderMagic)
+	body = appendUint16(body, 1)
+	body = appendUint16(body, 0)
+
+	// the section length is unknown
+	body = appendUint64(body, math.MaxUint64)
+
+	var opts []byte
+	opts = appendStringOption(opts, optComment, section.Comment)
+	opts = appendStringOption(opts, optSHBHardware, section.Hardware)
+	opts = appendStringOption(opts, optSHBOS, section.OS)
+	opts = appendStringOption(opts, optSHBUserAppl, section.Application)
+
+	if err := nw.writeBlock(blockTypeSectionHeader, body, opts); err != nil {
+		return nil, err
+	}
+
+	return nw, nil
+}
+
+// AddInterface writes an Interface Description Block for iface and returns the added interface,
+// which must be set in the PacketInfo of the packets captured on it.
+// The ID and the statistics of iface are ignored, interfaces are numbered in the order they are added.
+func (w *Writer) AddInterface(iface Interface) (*Interface, error) {
+	body := appendUint16(nil, uint16(iface.LinkType))
+	body = appendUint16(body, 0)
+	body = appendUint32(body, iface.SnapLen)
+
+	var opts []byte
+	opts = appendStringOption(opts, optComment, iface.Comment)
+	opts = appendStringOption(opts, optIFName, iface.Name)
+	opts = appendStringOption(opts, optIFDescription, iface.Description)
+
+	if iface.Speed != 0 {
+		opts = appendOption(opts, optIFSpeed, appendUint64(nil, iface.Speed))
+	}
+
+	opts = appendOption(opts, optIFTsResol, []byte{tsResolNanos})
+
+	if iface.Filter != "" {
+		// the first octet is the filter type, zero for a libpcap filter string
+		opts = appendOption(opts, optIFFilter, append([]byte{0}, iface.Filter...))
+	}
+
+	opts = appendStringOption(opts, optIFOS, iface.OS)
+
+	if iface.FCSLen != 0 {
+		opts = appendOption(opts, optIFFCSLen, []byte{iface.FCSLen})
+	}
+
+	opts = appendStringOption(opts, optIFHardware, iface.Hardware)
+
+	if err := w.writeBlock(blockTypeInterfaceDescription, body, opts); err != nil {
+		return nil, err
+	}
+
+	iface.ID = len(w.interfaces)
+	iface.Statistics = nil
+	iface.tsUnits = 1e9
+	iface.tsOffset = 0
+
+	added := &iface
+	w.interfaces = append(w.interfaces, added)
+
+	return added, nil
+}
+
+// Interfaces returns the interfaces added to the writer.
+func (w *Writer) Interfaces() []*Interface {
+	return w.interfaces
+}
+
+// WritePacket writes an Enhanced Packet Block for the packet captured on the interface of info,
+// including the comments, flags and drop count of info.
+func (w *Writer) WritePacket(ci gopacket.CaptureInfo, data []byte, info *PacketInfo) error {
+	if info == nil || info.Interface == nil {
+		return ErrNoInterface
+	}
+
+	id := info.Interface.ID
+	if id < 0 || id >= len(w.interfaces) || w.interfaces[id] != info.Interface {
+		return fmt.Errorf("%w: %s", errForeignInterface, info.Interface)
+	}
+
+	if ci.CaptureLength != len(data) {
+		return fmt.Errorf("capture length %d does not match data length %d", ci.CaptureLength, len(data))
+	}
+
+	if ci.Length < ci.CaptureLength {
+		return fmt.Errorf("original length %d is smaller than capture length %d", ci.Length, ci.CaptureLength)
+	}
+
+	ts := uint64(ci.Timestamp.UnixNano())
+
+	body := appendUint32(w.buf[:0], uint32(id))
+	body = appendUint32(body, uint32(ts>>32))
+	body = appendUint32(body, uint32(ts))
+	body = appendUint32(body, uint32(ci.CaptureLength))
+	body = appendUint32(body, uint32(ci.Length))
+	body = appendPadded(body, data)
+
+	opts := w.opts[:0]
+	for _, comment := range info.Comments {
+		opts = appendStringOption(opts, optComment, comment)
+	}
+
+	if info.Flags != 0 {
+		opts = appendOption(opts, optEPBFlags, appendUint32(nil, info.Flags))
+	}
+
+	if info.DropCount != 0 {
+		opts = appendOption(opts, optEPBDropCount, appendUint64(nil, info.DropCount))
+	}
+
+	w.buf, w.opts = body, opts
+
+	return w.writeBlock(blockTypeEnhancedPacket, body, opts)
+}
+
+// writeBlock writes a block with the fixed part body and the options,
+// which are terminated with an end of options if there are any.
+func (w *Writer) writeBlock(typ uint32, body, opts []byte) error {
+	length := 12 + len(body) + len(opts)
+	if len(opts) > 0 {
+		length += 4
+	}
+
+	if length > maxBlockSize {
+		return fmt.Errorf("%w: block type %#x with length %d", errInvalidBlock, typ, length)
+	}
+
+	hdr := appendUint32(make([]byte, 0, 8), typ)
+	hdr = appendUint32(hdr, uint32(length))
+
+	trailer := make([]byte, 0, 8)
+	if len(opts) > 0 {
+		trailer = appendUint32(trailer, optEndOfOpt)
+	}
+
+	trailer = appendUint32(trailer, uint32(length))
+
+	for _, b := range [][]byte{hdr, body, opts, trailer} {
+		if _, err := w.w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendOption appends an option with the value, padded to 32 bits.
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = appendUint16(b, code)
+	b = appendUint16(b, uint16(len(value)))
+
+	return appendPadded(b, value)
+}
+
+// appendStringOption appends a string option, if the value is not empty.
+func appendStringOption(b []byte, code uint16, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	// option values are limited to 16 bits
+	if len(value) > math.MaxUint16 {
+		value = value[:math.MaxUint16]
+	}
+
+	return appendOption(b, code, []byte(value))
+}
+
+// appendPadded appends data, padded to 32 bits.
+func appendPadded(b, data []byte) []byte {
+	b = append(b, data...)
+
+	for i := len(data); i%4 != 0; i++ {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
@@ -0,0 +1,170 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pcapng
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/dreadl0ck/gopacket/pcapgo"
+)
+
+func writeTestCapture(t *testing.T) (*bytes.Buffer, time.Time) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, Section{Application: "netcap", Comment: "evidence"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eth, err := w.AddInterface(Interface{Name: "eth0", LinkType: layers.LinkTypeEthernet, SnapLen: 65535, Filter: "tcp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := w.AddInterface(Interface{Name: "tun0", LinkType: layers.LinkTypeRaw, Speed: 1e9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 123456789, time.UTC)
+
+	packets := []struct {
+		data []byte
+		info *PacketInfo
+	}{
+		{[]byte{1, 2, 3}, &PacketInfo{Interface: eth, Comments: []string{"Alert #1", "Credentials #2"}, Flags: 0x2, DropCount: 5}},
+		{[]byte{4, 5, 6, 7}, &PacketInfo{Interface: raw}},
+	}
+
+	for i, p := range packets {
+		err = w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     ts.Add(time.Duration(i) * time.Second),
+			CaptureLength: len(p.data),
+			Length:        len(p.data) + i,
+		}, p.data, p.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &buf, ts
+}
+
+func TestWriter(t *testing.T) {
+	buf, ts := writeTestCapture(t)
+
+	r, err := NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := r.Section(); s.Application != "netcap" || s.Comment != "evidence" || s.MajorVersion != 1 {
+		t.Fatal("unexpected section", s)
+	}
+
+	data, ci, err := r.ReadPacketData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := Info(&ci)
+	if !bytes.Equal(data, []byte{1, 2, 3}) || !ci.Timestamp.Equal(ts) || ci.Length != 3 {
+		t.Fatal("unexpected packet", data, ci)
+	}
+
+	if info.Interface.Name != "eth0" || info.Interface.Filter != "tcp" || info.Interface.SnapLen != 65535 {
+		t.Fatal("unexpected interface", info.Interface)
+	}
+
+	if len(info.Comments) != 2 || info.Comments[0] != "Alert #1" || info.Comments[1] != "Credentials #2" {
+		t.Fatal("unexpected comments", info.Comments)
+	}
+
+	if info.Direction() != DirectionOutbound || info.DropCount != 5 {
+		t.Fatal("unexpected flags or drop count", info.Flags, info.DropCount)
+	}
+
+	data, ci, err = r.ReadPacketData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info = Info(&ci)
+	if !bytes.Equal(data, []byte{4, 5, 6, 7}) || ci.Length != 5 || !ci.Timestamp.Equal(ts.Add(time.Second)) {
+		t.Fatal("unexpected packet", data, ci)
+	}
+
+	if info.Interface.ID != 1 || info.Interface.LinkType != layers.LinkTypeRaw || info.Interface.Speed != 1e9 || len(info.Comments) != 0 {
+		t.Fatal("unexpected packet info", info.Interface, info.Comments)
+	}
+
+	if _, _, err = r.ReadPacketData(); !errors.Is(err, io.EOF) {
+		t.Fatal("expected EOF, got", err)
+	}
+}
+
+func TestWriterGoPacketCompatibility(t *testing.T) {
+	buf, ts := writeTestCapture(t)
+
+	r, err := pcapgo.NewNgReader(buf, pcapgo.NgReaderOptions{WantMixedLinkType: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, ci, errRead := r.ReadPacketData()
+		if errRead != nil {
+			t.Fatal(errRead)
+		}
+
+		if ci.InterfaceIndex != i || !ci.Timestamp.Equal(ts.Add(time.Duration(i)*time.Second)) {
+			t.Fatal("unexpected packet", i, ci)
+		}
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	w, err := NewWriter(ioutil.Discard, Section{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ci := gopacket.CaptureInfo{CaptureLength: 1, Length: 1}
+
+	if err = w.WritePacket(ci, []byte{1}, &PacketInfo{}); !errors.Is(err, ErrNoInterface) {
+		t.Fatal("expected ErrNoInterface, got", err)
+	}
+
+	if err = w.WritePacket(ci, []byte{1}, &PacketInfo{Interface: &Interface{}}); !errors.Is(err, errForeignInterface) {
+		t.Fatal("expected errForeignInterface, got", err)
+	}
+
+	iface, err := w.AddInterface(Interface{LinkType: layers.LinkTypeEthernet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.WritePacket(ci, []byte{1, 2}, &PacketInfo{Interface: iface}); err == nil {
+		t.Fatal("expected error for mismatching capture length")
+	}
+}
@@ -145,6 +145,13 @@ type AssemblerContext interface {
 	GetCaptureInfo() gopacket.CaptureInfo
 }
 
+// EncapsulationContext can be implemented by an AssemblerContext
+// to keep apart connections with identical flows that were carried in different tunnels or VLANs.
+// The returned value must be identical for both directions of a connection.
+type EncapsulationContext interface {
+	GetEncapsulation() string
+}
+
 // Implements AssemblerContext for Assemble().
 type assemblerSimpleContext gopacket.CaptureInfo
 
@@ -183,9 +190,13 @@ func (a *Assembler) AssembleWithContext(netFlow gopacket.Flow, t *layers.TCP, ac
 		conn    *connection
 		half    *halfconnection
 		rev     *halfconnection
-		flowKey = &key{netFlow, t.TransportFlow()}
+		flowKey = &key{net: netFlow, transport: t.TransportFlow()}
 	)
 
+	if ec, ok := ac.(EncapsulationContext); ok {
+		flowKey.encapsulation = ec.GetEncapsulation()
+	}
+
 	// RACE
 	a.Lock()
 	a.ret = a.ret[:0]
@@ -0,0 +1,426 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package ip6defrag implements IPv6 fragment reassembly.
+// Datagrams are tracked by source, destination and fragment identification (RFC 8200 section 4.5),
+// overlapping fragments cause the entire datagram to be discarded (RFC 5722),
+// and the memory used for buffering incomplete datagrams is bounded.
+package ip6defrag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+const (
+	// DefaultTimeout after which incomplete datagrams are discarded, as recommended by RFC 8200.
+	DefaultTimeout = 60 * time.Second
+
+	// DefaultMaxMemory is the default limit for fragment data buffered for all incomplete datagrams.
+	DefaultMaxMemory = 32 * 1024 * 1024
+
+	// DefaultMaxFragments is the default limit for the number of fragments of a single datagram.
+	DefaultMaxFragments = 1024
+
+	// maximum value for the IPv6 payload length field
+	maxPayloadLength = 65535
+
+	// minimum interval between two scans for expired datagrams
+	purgeInterval = time.Second
+)
+
+var (
+	// ErrOverlap is returned when a fragment overlaps with a previously received one.
+	ErrOverlap = errors.New("overlapping IPv6 fragment, datagram discarded")
+
+	// ErrInvalidLength is returned for fragments that are not the last one and whose length is not a multiple of 8 bytes.
+	ErrInvalidLength = errors.New("IPv6 fragment length is not a multiple of 8")
+
+	// ErrInconsistent is returned when a fragment contradicts the datagram length announced by the last fragment.
+	ErrInconsistent = errors.New("IPv6 fragment inconsistent with datagram length, datagram discarded")
+
+	// ErrTooLarge is returned when the reassembled datagram would exceed the maximum IPv6 payload length.
+	ErrTooLarge = errors.New("reassembled IPv6 datagram exceeds maximum payload length")
+
+	// ErrMemoryLimit is returned when buffering the fragment would exceed the memory limit.
+	ErrMemoryLimit = errors.New("IPv6 fragment memory limit reached, datagram discarded")
+
+	// ErrTooManyFragments is returned when a datagram consists of more fragments than allowed.
+	ErrTooManyFragments = errors.New("too many IPv6 fragments, datagram discarded")
+)
+
+// key identifies the fragments of a single datagram.
+type key struct {
+	src [16]byte
+	dst [16]byte
+	id  uint32
+}
+
+type fragment struct {
+	offset int
+	data   []byte
+}
+
+// datagram holds the fragments received for an incomplete datagram.
+type datagram struct {
+	firstSeen time.Time
+	fragments []fragment // ordered by offset
+	size      int
+
+	// length of the fragmentable part, -1 until the last fragment was received.
+	total int
+
+	// bytes preceding the fragment header, taken from the first fragment:
+	// link layer headers, the IPv6 header and extension headers that are not fragmented.
+	header []byte
+
+	// index of the IPv6 header in header
+	ipStart int
+
+	// index of the next header field that has to point to the reassembled upper layer
+	nextHeaderPos int
+
+	nextHeader layers.IPProtocol
+	firstLayer gopacket.LayerType
+
+	// discarded datagrams are kept until they expire, to drop late fragments as required by RFC 5722.
+	discarded bool
+}
+
+// Defragmenter reassembles IPv6 datagrams from their fragments.
+// It is safe for concurrent use.
+type Defragmenter struct {
+	sync.Mutex
+
+	// Timeout after which incomplete datagrams are discarded.
+	Timeout time.Duration
+
+	// MaxMemory limits the fragment data buffered for all incomplete datagrams.
+	MaxMemory int
+
+	// MaxFragments limits the number of fragments of a single datagram.
+	MaxFragments int
+
+	datagrams map[key]*datagram
+	buffered  int
+	lastPurge time.Time
+
+	// statistics
+	reassembled int64
+	discarded   int64
+	expired     int64
+}
+
+// NewDefragmenter returns a Defragmenter with the default limits.
+func NewDefragmenter() *Defragmenter {
+	return &Defragmenter{
+		Timeout:      DefaultTimeout,
+		MaxMemory:    DefaultMaxMemory,
+		MaxFragments: DefaultMaxFragments,
+		datagrams:    make(map[key]*datagram),
+	}
+}
+
+// Stats returns the number of reassembled, discarded and expired datagrams.
+func (d *Defragmenter) Stats() (reassembled, discarded, expired int64) {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.reassembled, d.discarded, d.expired
+}
+
+// Buffered returns the number of fragment bytes currently held for incomplete datagrams.
+func (d *Defragmenter) Buffered() int {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.buffered
+}
+
+// DefragIPv6 processes the packet if it carries an IPv6 fragment.
+// It returns the reassembled packet once the last missing fragment of a datagram was received, and nil otherwise.
+// The reassembled packet is decoded with the given options, starting with the same layer as p,
+// and inherits the capture info of p.
+// An error is returned if the fragment was invalid or caused its datagram to be discarded.
+func (d *Defragmenter) DefragIPv6(p gopacket.Packet, opts gopacket.DecodeOptions) (gopacket.Packet, error) {
+	var (
+		ip6      *layers.IPv6
+		frag     *layers.IPv6Fragment
+		previous gopacket.Layer
+		tunneled bool
+		all      = p.Layers()
+	)
+
+	for i, l := range all {
+		if f, ok := l.(*layers.IPv6Fragment); ok {
+			frag = f
+			if i > 0 {
+				previous = all[i-1]
+			}
+
+			break
+		}
+
+		if v6, ok := l.(*layers.IPv6); ok {
+			if ip6 != nil {
+				tunneled = true
+			}
+			ip6 = v6
+		} else if _, ok := l.(gopacket.NetworkLayer); ok {
+			tunneled = true
+		}
+	}
+
+	if frag == nil || ip6 == nil || previous == nil {
+		return nil, nil
+	}
+
+	var (
+		data      = p.Data()
+		ipOff     = offset(data, ip6.Contents)
+		fragStart = offset(data, frag.Contents)
+		fragData  = frag.Payload
+		fragOff   = int(frag.FragmentOffset) * 8
+		ts        = p.Metadata().Timestamp
+	)
+
+	if ipOff < 0 || fragStart < ipOff+len(ip6.Contents) {
+		return nil, nil
+	}
+
+	// the next header field of the header preceding the fragment header
+	// has to point to the upper layer protocol in the reassembled datagram.
+	// the hop-by-hop options header is a separate layer, so only a plain IPv6 header needs special treatment.
+	nextHeaderPos := offset(data, previous.LayerContents())
+	if previous == gopacket.Layer(ip6) {
+		nextHeaderPos = ipOff + 6
+	}
+
+	if nextHeaderPos < ipOff || nextHeaderPos >= fragStart {
+		return nil, nil
+	}
+
+	if frag.MoreFragments && len(fragData)%8 != 0 {
+		return nil, ErrInvalidLength
+	}
+
+	if fragOff+len(fragData)+(fragStart-ipOff-40) > maxPayloadLength {
+		return nil, ErrTooLarge
+	}
+
+	// the tunnel headers preceding an inner IPv6 packet carry length fields that would not match the reassembled datagram,
+	// so it is decoded starting at the IPv6 layer.
+	var (
+		start      int
+		firstLayer = all[0].LayerType()
+	)
+	if tunneled {
+		start = ipOff
+		firstLayer = layers.LayerTypeIPv6
+	}
+
+	dg := &datagram{
+		total:      -1,
+		firstSeen:  ts,
+		firstLayer: firstLayer,
+	}
+
+	if fragOff == 0 {
+		dg.header = append([]byte(nil), data[start:fragStart]...)
+		dg.ipStart = ipOff - start
+		dg.nextHeaderPos = nextHeaderPos - start
+		dg.nextHeader = frag.NextHeader
+	}
+
+	// atomic fragments are processed in isolation (RFC 6946)
+	if fragOff == 0 && !frag.MoreFragments {
+		dg.fragments = []fragment{{data: fragData}}
+		dg.total = len(fragData)
+
+		return build(dg, p, opts), nil
+	}
+
+	k := key{id: frag.Identification}
+	copy(k.src[:], ip6.SrcIP.To16())
+	copy(k.dst[:], ip6.DstIP.To16())
+
+	d.Lock()
+	defer d.Unlock()
+
+	if ts.Sub(d.lastPurge) >= purgeInterval {
+		d.discardOlderThan(ts.Add(-d.Timeout))
+		d.lastPurge = ts
+	}
+
+	existing, ok := d.datagrams[k]
+	if !ok {
+		d.datagrams[k] = dg
+		existing = dg
+	} else if fragOff == 0 && existing.header == nil {
+		existing.header = dg.header
+		existing.ipStart = dg.ipStart
+		existing.nextHeaderPos = dg.nextHeaderPos
+		existing.nextHeader = dg.nextHeader
+		existing.firstLayer = dg.firstLayer
+	}
+	dg = existing
+
+	if dg.discarded {
+		return nil, nil
+	}
+
+	end := fragOff + len(fragData)
+
+	// check the fragment against the datagram length
+	if !frag.MoreFragments {
+		if dg.total != -1 && dg.total != end {
+			d.discard(dg)
+			return nil, ErrInconsistent
+		}
+		for _, f := range dg.fragments {
+			if f.offset+len(f.data) > end {
+				d.discard(dg)
+				return nil, ErrInconsistent
+			}
+		}
+		dg.total = end
+	} else if dg.total != -1 && end > dg.total {
+		d.discard(dg)
+		return nil, ErrInconsistent
+	}
+
+	// find insert position and check for overlaps
+	i := sort.Search(len(dg.fragments), func(i int) bool {
+		return dg.fragments[i].offset >= fragOff
+	})
+
+	if i < len(dg.fragments) && dg.fragments[i].offset == fragOff && bytes.Equal(dg.fragments[i].data, fragData) {
+		// exact duplicates are ignored
+		return nil, nil
+	}
+
+	if (i > 0 && dg.fragments[i-1].offset+len(dg.fragments[i-1].data) > fragOff) ||
+		(i < len(dg.fragments) && dg.fragments[i].offset < end) {
+		d.discard(dg)
+		return nil, ErrOverlap
+	}
+
+	if len(dg.fragments) >= d.MaxFragments {
+		d.discard(dg)
+		return nil, ErrTooManyFragments
+	}
+
+	if d.buffered+len(fragData) > d.MaxMemory {
+		d.discard(dg)
+		return nil, ErrMemoryLimit
+	}
+
+	dg.fragments = append(dg.fragments, fragment{})
+	copy(dg.fragments[i+1:], dg.fragments[i:])
+	dg.fragments[i] = fragment{
+		offset: fragOff,
+		data:   append([]byte(nil), fragData...),
+	}
+	dg.size += len(fragData)
+	d.buffered += len(fragData)
+
+	// fragments do not overlap, so the datagram is complete once their sizes add up to its length
+	if dg.total == -1 || dg.size != dg.total || dg.header == nil {
+		return nil, nil
+	}
+
+	delete(d.datagrams, k)
+	d.buffered -= dg.size
+	d.reassembled++
+
+	return build(dg, p, opts), nil
+}
+
+// DiscardOlderThan removes all incomplete datagrams whose first fragment was seen before t,
+// and returns the number of datagrams removed.
+func (d *Defragmenter) DiscardOlderThan(t time.Time) int {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.discardOlderThan(t)
+}
+
+func (d *Defragmenter) discardOlderThan(t time.Time) int {
+	var n int
+
+	for k, dg := range d.datagrams {
+		if dg.firstSeen.Before(t) {
+			if !dg.discarded {
+				d.expired++
+				n++
+			}
+			d.buffered -= dg.size
+			delete(d.datagrams, k)
+		}
+	}
+
+	return n
+}
+
+// discard releases the fragments of the datagram and marks it,
+// so that fragments arriving later are dropped until it expires.
+func (d *Defragmenter) discard(dg *datagram) {
+	d.buffered -= dg.size
+	d.discarded++
+
+	dg.fragments = nil
+	dg.size = 0
+	dg.header = nil
+	dg.discarded = true
+}
+
+// build assembles the datagram and decodes it.
+func build(dg *datagram, p gopacket.Packet, opts gopacket.DecodeOptions) gopacket.Packet {
+	data := make([]byte, 0, len(dg.header)+dg.total)
+	data = append(data, dg.header...)
+	for _, f := range dg.fragments {
+		data = append(data, f.data...)
+	}
+
+	data[dg.nextHeaderPos] = byte(dg.nextHeader)
+	binary.BigEndian.PutUint16(data[dg.ipStart+4:], uint16(len(data)-dg.ipStart-40))
+
+	out := gopacket.NewPacket(data, dg.firstLayer, opts)
+
+	md := out.Metadata()
+	md.CaptureInfo = p.Metadata().CaptureInfo
+	md.CaptureInfo.CaptureLength = len(data)
+	md.CaptureInfo.Length = len(data)
+	md.Timestamp = md.CaptureInfo.Timestamp
+	md.CaptureLength = md.CaptureInfo.CaptureLength
+	md.Length = md.CaptureInfo.Length
+
+	return out
+}
+
+// offset returns the position of the sub slice b in data, or -1 if b is not part of data.
+func offset(data, b []byte) int {
+	off := cap(data) - cap(b)
+	if off < 0 || off > len(data) {
+		return -1
+	}
+
+	return off
+}
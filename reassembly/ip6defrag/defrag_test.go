@@ -0,0 +1,237 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ip6defrag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+var (
+	testSrc = net.ParseIP("2001:db8::1")
+	testDst = net.ParseIP("2001:db8::2")
+)
+
+// fragmentPacket builds an ethernet frame carrying an IPv6 fragment.
+func fragmentPacket(id uint32, offset int, more bool, payload []byte, ts time.Time) gopacket.Packet {
+	var (
+		frame = make([]byte, 14+40+8)
+		ip    = frame[14:]
+	)
+
+	binary.BigEndian.PutUint16(frame[12:], uint16(layers.EthernetTypeIPv6))
+
+	ip[0] = 6 << 4
+	binary.BigEndian.PutUint16(ip[4:], uint16(8+len(payload)))
+	ip[6] = byte(layers.IPProtocolIPv6Fragment)
+	ip[7] = 64
+	copy(ip[8:], testSrc.To16())
+	copy(ip[24:], testDst.To16())
+
+	fh := ip[40:]
+	fh[0] = byte(layers.IPProtocolUDP)
+
+	offField := uint16(offset/8) << 3
+	if more {
+		offField |= 1
+	}
+	binary.BigEndian.PutUint16(fh[2:], offField)
+	binary.BigEndian.PutUint32(fh[4:], id)
+
+	frame = append(frame, payload...)
+
+	p := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+	p.Metadata().Timestamp = ts
+	p.Metadata().CaptureInfo.Timestamp = ts
+
+	return p
+}
+
+// udpDatagram returns a UDP header and payload of the given size.
+func udpDatagram(size int) []byte {
+	data := make([]byte, 8+size)
+	binary.BigEndian.PutUint16(data[0:], 5353)
+	binary.BigEndian.PutUint16(data[2:], 53)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(data)))
+	for i := 8; i < len(data); i++ {
+		data[i] = byte(i)
+	}
+
+	return data
+}
+
+func TestDefragIPv6InOrder(t *testing.T) {
+	var (
+		d    = NewDefragmenter()
+		ts   = time.Unix(1, 0)
+		data = udpDatagram(200)
+	)
+
+	out, err := d.DefragIPv6(fragmentPacket(1, 0, true, data[:104], ts), gopacket.Default)
+	if err != nil || out != nil {
+		t.Fatal("expected first fragment to be buffered", out, err)
+	}
+
+	out, err = d.DefragIPv6(fragmentPacket(1, 104, false, data[104:], ts), gopacket.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out == nil {
+		t.Fatal("expected reassembled packet")
+	}
+
+	if out.Layer(layers.LayerTypeEthernet) == nil {
+		t.Fatal("expected link layer to be preserved")
+	}
+
+	ip6, ok := out.NetworkLayer().(*layers.IPv6)
+	if !ok {
+		t.Fatal("expected IPv6 network layer")
+	}
+
+	if ip6.NextHeader != layers.IPProtocolUDP || int(ip6.Length) != len(data) {
+		t.Fatal("unexpected IPv6 header", ip6.NextHeader, ip6.Length)
+	}
+
+	udp, ok := out.TransportLayer().(*layers.UDP)
+	if !ok {
+		t.Fatal("expected UDP layer")
+	}
+
+	if !bytes.Equal(udp.Payload, data[8:]) {
+		t.Fatal("unexpected reassembled payload")
+	}
+
+	if d.Buffered() != 0 {
+		t.Fatal("expected no buffered data, got", d.Buffered())
+	}
+
+	if reassembled, _, _ := d.Stats(); reassembled != 1 {
+		t.Fatal("expected one reassembled datagram, got", reassembled)
+	}
+}
+
+func TestDefragIPv6OutOfOrder(t *testing.T) {
+	var (
+		d    = NewDefragmenter()
+		ts   = time.Unix(1, 0)
+		data = udpDatagram(300)
+	)
+
+	for _, f := range []struct {
+		offset, end int
+		more        bool
+	}{
+		{208, len(data), false},
+		{0, 104, true},
+		{104, 208, true},
+	} {
+		out, err := d.DefragIPv6(fragmentPacket(2, f.offset, f.more, data[f.offset:f.end], ts), gopacket.Default)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.offset == 104 {
+			if out == nil {
+				t.Fatal("expected reassembled packet")
+			}
+			if !bytes.Equal(out.TransportLayer().LayerPayload(), data[8:]) {
+				t.Fatal("unexpected reassembled payload")
+			}
+		} else if out != nil {
+			t.Fatal("unexpected packet before all fragments were received")
+		}
+	}
+}
+
+func TestDefragIPv6Overlap(t *testing.T) {
+	var (
+		d    = NewDefragmenter()
+		ts   = time.Unix(1, 0)
+		data = udpDatagram(200)
+	)
+
+	if _, err := d.DefragIPv6(fragmentPacket(3, 0, true, data[:104], ts), gopacket.Default); err != nil {
+		t.Fatal(err)
+	}
+
+	// exact duplicates are ignored
+	if out, err := d.DefragIPv6(fragmentPacket(3, 0, true, data[:104], ts), gopacket.Default); err != nil || out != nil {
+		t.Fatal("expected duplicate to be ignored", out, err)
+	}
+
+	if _, err := d.DefragIPv6(fragmentPacket(3, 96, true, data[96:200], ts), gopacket.Default); err != ErrOverlap {
+		t.Fatal("expected overlap error, got", err)
+	}
+
+	// the datagram was discarded, the remaining fragment must not complete it
+	out, err := d.DefragIPv6(fragmentPacket(3, 104, false, data[104:], ts), gopacket.Default)
+	if err != nil || out != nil {
+		t.Fatal("expected late fragment to be dropped", out, err)
+	}
+
+	if d.Buffered() != 0 {
+		t.Fatal("expected no buffered data, got", d.Buffered())
+	}
+}
+
+func TestDefragIPv6Timeout(t *testing.T) {
+	var (
+		d    = NewDefragmenter()
+		ts   = time.Unix(1, 0)
+		data = udpDatagram(200)
+	)
+
+	if _, err := d.DefragIPv6(fragmentPacket(4, 0, true, data[:104], ts), gopacket.Default); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := d.DefragIPv6(fragmentPacket(4, 104, false, data[104:], ts.Add(DefaultTimeout+time.Second)), gopacket.Default)
+	if err != nil || out != nil {
+		t.Fatal("expected expired datagram not to be reassembled", out, err)
+	}
+
+	if _, _, expired := d.Stats(); expired != 1 {
+		t.Fatal("expected one expired datagram, got", expired)
+	}
+}
+
+func TestDefragIPv6MemoryLimit(t *testing.T) {
+	var (
+		d    = NewDefragmenter()
+		ts   = time.Unix(1, 0)
+		data = udpDatagram(200)
+	)
+
+	d.MaxMemory = 100
+
+	if _, err := d.DefragIPv6(fragmentPacket(5, 0, true, data[:104], ts), gopacket.Default); err != ErrMemoryLimit {
+		t.Fatal("expected memory limit error, got", err)
+	}
+}
+
+func TestDefragIPv6InvalidLength(t *testing.T) {
+	data := udpDatagram(200)
+
+	if _, err := NewDefragmenter().DefragIPv6(fragmentPacket(6, 0, true, data[:100], time.Unix(1, 0)), gopacket.Default); err != ErrInvalidLength {
+		t.Fatal("expected invalid length error, got", err)
+	}
+}
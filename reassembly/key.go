@@ -6,15 +6,27 @@ import (
 	"github.com/dreadl0ck/gopacket"
 )
 
-type key [2]gopacket.Flow
+// key identifies a connection by its network and transport flows.
+// The encapsulation separates identical flows carried in different tunnels or VLANs,
+// it must be the same for both directions of a connection.
+type key struct {
+	net           gopacket.Flow
+	transport     gopacket.Flow
+	encapsulation string
+}
 
 func (k *key) String() string {
-	return fmt.Sprintf("%s:%s", k[0], k[1])
+	if k.encapsulation != "" {
+		return fmt.Sprintf("%s:%s@%s", k.net, k.transport, k.encapsulation)
+	}
+
+	return fmt.Sprintf("%s:%s", k.net, k.transport)
 }
 
 func (k *key) reverse() key {
 	return key{
-		k[0].Reverse(),
-		k[1].Reverse(),
+		net:           k.net.Reverse(),
+		transport:     k.transport.Reverse(),
+		encapsulation: k.encapsulation,
 	}
 }
@@ -157,7 +157,7 @@ func (p *StreamPool) getConnection(k *key, end bool, ts time.Time, ac AssemblerC
 		return conn, half, rev
 	}
 
-	s := p.factory.New(k[0], k[1], ac)
+	s := p.factory.New(k.net, k.transport, ac)
 
 	conn, half, rev = p.newConnection(k, s, ts)
 
@@ -1948,3 +1948,52 @@ func TestReassemblyObjectCaptureInfo(t *testing.T) {
 		}
 	}
 }
+
+/* Counts the streams created by the assembler */
+type testCountingFactory struct {
+	testMemoryFactory
+	streams int
+}
+
+func (tf *testCountingFactory) New(_, _ gopacket.Flow, _ AssemblerContext) Stream {
+	tf.streams++
+	return tf
+}
+
+type testEncapsulationContext struct {
+	encapsulation string
+}
+
+func (c *testEncapsulationContext) GetCaptureInfo() gopacket.CaptureInfo {
+	return gopacket.CaptureInfo{Timestamp: time.Unix(1, 0)}
+}
+
+func (c *testEncapsulationContext) GetEncapsulation() string {
+	return c.encapsulation
+}
+
+func TestEncapsulationKey(t *testing.T) {
+	fact := &testCountingFactory{}
+	a := NewAssembler(NewStreamPool(fact))
+
+	syn := func() *layers.TCP {
+		return &layers.TCP{
+			SrcPort:   1,
+			DstPort:   2,
+			SYN:       true,
+			Seq:       1000,
+			BaseLayer: layers.BaseLayer{Payload: []byte{}},
+		}
+	}
+
+	a.AssembleWithContext(netFlow, syn(), &testEncapsulationContext{encapsulation: "vxlan=1"})
+	a.AssembleWithContext(netFlow, syn(), &testEncapsulationContext{encapsulation: "vxlan=1"})
+	if fact.streams != 1 {
+		t.Fatal("expected a single stream for identical encapsulation, got", fact.streams)
+	}
+
+	a.AssembleWithContext(netFlow, syn(), &testEncapsulationContext{encapsulation: "vxlan=2"})
+	if fact.streams != 2 {
+		t.Fatal("expected a new stream for a different encapsulation, got", fact.streams)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+// fields of the capture interface metadata, which are also carried by the Ethernet, IPv4 and IPv6 records.
+const (
+	fieldInterface      = "Interface"
+	fieldComment        = "Comment"
+	fieldDropCount      = "DropCount"
+	fieldLinkType       = "LinkType"
+	fieldStartTime      = "StartTime"
+	fieldReceived       = "Received"
+	fieldDropped        = "Dropped"
+	fieldFilterAccepted = "FilterAccepted"
+	fieldOSDropped      = "OSDropped"
+	fieldDelivered      = "Delivered"
+)
+
+var fieldsCaptureStatistics = []string{
+	fieldTimestamp,
+	fieldInterface,      // string
+	fieldInterfaceID,    // int32
+	fieldLinkType,       // string
+	fieldStartTime,      // int64
+	fieldEndTime,        // int64
+	fieldReceived,       // uint64
+	fieldDropped,        // uint64
+	fieldFilterAccepted, // uint64
+	fieldOSDropped,      // uint64
+	fieldDelivered,      // uint64
+	fieldComment,        // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *CaptureStatistics) CSVHeader() []string {
+	return filter(fieldsCaptureStatistics)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *CaptureStatistics) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.Interface,                    // string
+		formatInt32(a.InterfaceID),     // int32
+		a.LinkType,                     // string
+		formatTimestamp(a.StartTime),   // int64
+		formatTimestamp(a.EndTime),     // int64
+		formatUint64(a.Received),       // uint64
+		formatUint64(a.Dropped),        // uint64
+		formatUint64(a.FilterAccepted), // uint64
+		formatUint64(a.OSDropped),      // uint64
+		formatUint64(a.Delivered),      // uint64
+		a.Comment,                      // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *CaptureStatistics) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *CaptureStatistics) JSON() (string, error) {
+	// convert unix timestamps from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+	a.StartTime /= int64(time.Millisecond)
+	a.EndTime /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var captureStatisticsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_CaptureStatistics.String()),
+		Help: Type_NC_CaptureStatistics.String() + " audit records",
+	},
+	[]string{fieldInterface, fieldLinkType},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *CaptureStatistics) Inc() {
+	captureStatisticsMetric.WithLabelValues(a.Interface, a.LinkType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *CaptureStatistics) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *CaptureStatistics) Src() string {
+	return ""
+}
+
+// Dst returns the destination address of the audit record.
+func (a *CaptureStatistics) Dst() string {
+	return ""
+}
+
+var captureStatisticsEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *CaptureStatistics) Encode() []string {
+	return filter([]string{
+		captureStatisticsEncoder.Int64(fieldTimestamp, a.Timestamp),
+		captureStatisticsEncoder.String(fieldInterface, a.Interface),           // string
+		captureStatisticsEncoder.Int32(fieldInterfaceID, a.InterfaceID),        // int32
+		captureStatisticsEncoder.String(fieldLinkType, a.LinkType),             // string
+		captureStatisticsEncoder.Int64(fieldStartTime, a.StartTime),            // int64
+		captureStatisticsEncoder.Int64(fieldEndTime, a.EndTime),                // int64
+		captureStatisticsEncoder.Uint64(fieldReceived, a.Received),             // uint64
+		captureStatisticsEncoder.Uint64(fieldDropped, a.Dropped),               // uint64
+		captureStatisticsEncoder.Uint64(fieldFilterAccepted, a.FilterAccepted), // uint64
+		captureStatisticsEncoder.Uint64(fieldOSDropped, a.OSDropped),           // uint64
+		captureStatisticsEncoder.Uint64(fieldDelivered, a.Delivered),           // uint64
+		captureStatisticsEncoder.String(fieldComment, a.Comment),               // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *CaptureStatistics) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *CaptureStatistics) NetcapType() Type {
+	return Type_NC_CaptureStatistics
+}
@@ -25,6 +25,7 @@ const (
 	fieldNumDeviceIPs       = "NumDeviceIPs"
 	fieldNumContacts        = "NumContacts"
 	fieldBytes              = "Bytes"
+	fieldDeviceModels       = "DeviceModels"
 )
 
 var fieldsDeviceProfile = []string{
@@ -35,6 +36,9 @@ var fieldsDeviceProfile = []string{
 	fieldNumContacts,
 	fieldNumPackets,
 	fieldBytes,
+	fieldHostnames,
+	fieldDeviceModels,
+	fieldServices,
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -52,6 +56,9 @@ func (d *DeviceProfile) CSVRecord() []string {
 		strconv.Itoa(len(d.Contacts)),
 		formatInt64(d.NumPackets),
 		formatUint64(d.Bytes),
+		join(d.Hostnames...),
+		join(d.DeviceModels...),
+		join(d.Services...),
 	})
 }
 
@@ -96,6 +103,9 @@ func (d *DeviceProfile) Encode() []string {
 		deviceProfileEncoder.Int(fieldNumContacts, len(d.Contacts)),
 		deviceProfileEncoder.Int64(fieldNumPackets, d.NumPackets),
 		deviceProfileEncoder.Uint64(fieldBytes, d.Bytes),
+		deviceProfileEncoder.String(fieldHostnames, join(d.Hostnames...)),
+		deviceProfileEncoder.String(fieldDeviceModels, join(d.DeviceModels...)),
+		deviceProfileEncoder.String(fieldServices, join(d.Services...)),
 	})
 }
 
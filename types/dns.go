@@ -66,6 +66,7 @@ var fieldsDNS = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldTransport,
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -117,6 +118,7 @@ func (d *DNS) CSVRecord() []string {
 		d.DstIP,
 		formatInt32(d.SrcPort),
 		formatInt32(d.DstPort),
+		d.Transport,
 	})
 }
 
@@ -246,6 +248,7 @@ func (d *DNS) SetPacketContext(ctx *PacketContext) {
 	d.DstIP = ctx.DstIP
 	d.SrcPort = ctx.SrcPort
 	d.DstPort = ctx.DstPort
+	d.Transport = ctx.Transport
 }
 
 // Src returns the source address of the audit record.
@@ -305,6 +308,7 @@ func (d *DNS) Encode() []string {
 		dnsEncoder.String(fieldDstIP, d.DstIP),
 		dnsEncoder.Int32(fieldSrcPort, d.SrcPort),
 		dnsEncoder.Int32(fieldDstPort, d.DstPort),
+		dnsEncoder.String(fieldTransport, d.Transport),
 	})
 }
 
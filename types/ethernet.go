@@ -37,6 +37,11 @@ var fieldsEthernet = []string{
 	fieldEthernetType,   // int32
 	fieldPayloadEntropy, // float64
 	fieldPayloadSize,    // int32
+	fieldInterface,      // string
+	fieldInterfaceID,    // int32
+	fieldDirection,      // string
+	fieldComment,        // string
+	fieldDropCount,      // uint64
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -53,6 +58,11 @@ func (eth *Ethernet) CSVRecord() []string {
 		formatInt32(eth.EthernetType),     // int32
 		formatFloat64(eth.PayloadEntropy), // float64
 		formatInt32(eth.PayloadSize),      // int32
+		eth.Interface,                     // string
+		formatInt32(eth.InterfaceID),      // int32
+		eth.Direction,                     // string
+		eth.Comment,                       // string
+		formatUint64(eth.DropCount),       // uint64
 	})
 }
 
@@ -119,7 +129,13 @@ func (eth *Ethernet) Inc() {
 }
 
 // SetPacketContext sets the associated packet context for the audit record.
-func (eth *Ethernet) SetPacketContext(*PacketContext) {}
+func (eth *Ethernet) SetPacketContext(ctx *PacketContext) {
+	eth.Interface = ctx.Interface
+	eth.InterfaceID = ctx.InterfaceID
+	eth.Direction = ctx.Direction
+	eth.Comment = ctx.Comment
+	eth.DropCount = ctx.DropCount
+}
 
 // Src returns the source address of the audit record.
 func (eth *Ethernet) Src() string {
@@ -142,6 +158,11 @@ func (eth *Ethernet) Encode() []string {
 		ethernetEncoder.Int32(fieldEthernetType, eth.EthernetType),
 		ethernetEncoder.Float64(fieldPayloadEntropy, eth.PayloadEntropy),
 		ethernetEncoder.Int32(fieldPayloadSize, eth.PayloadSize),
+		ethernetEncoder.String(fieldInterface, eth.Interface),
+		ethernetEncoder.Int32(fieldInterfaceID, eth.InterfaceID),
+		ethernetEncoder.String(fieldDirection, eth.Direction),
+		ethernetEncoder.String(fieldComment, eth.Comment),
+		ethernetEncoder.Uint64(fieldDropCount, eth.DropCount),
 	})
 }
 
@@ -0,0 +1,159 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldProtocolType     = "ProtocolType"
+	fieldMessageLength    = "MessageLength"
+	fieldTEID             = "TEID"
+	fieldNPDU             = "NPDU"
+	fieldExtensionHeaders = "ExtensionHeaders"
+	fieldInnerSrcIP       = "InnerSrcIP"
+	fieldInnerDstIP       = "InnerDstIP"
+	fieldInnerProtocol    = "InnerProtocol"
+	fieldTunnelSrcIP      = "TunnelSrcIP"
+	fieldTunnelDstIP      = "TunnelDstIP"
+)
+
+var fieldsGTPv1U = []string{
+	fieldTimestamp,
+	fieldSrcIP,            // string
+	fieldDstIP,            // string
+	fieldSrcPort,          // int32
+	fieldDstPort,          // int32
+	fieldVersion,          // int32
+	fieldProtocolType,     // int32
+	fieldMessageType,      // string
+	fieldMessageLength,    // int32
+	fieldTEID,             // uint32
+	fieldSequenceNumber,   // int32
+	fieldNPDU,             // int32
+	fieldExtensionHeaders, // []int32
+	fieldInnerSrcIP,       // string
+	fieldInnerDstIP,       // string
+	fieldInnerProtocol,    // int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *GTPv1U) CSVHeader() []string {
+	return filter(fieldsGTPv1U)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *GTPv1U) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                       // string
+		a.DstIP,                       // string
+		formatInt32(a.SrcPort),        // int32
+		formatInt32(a.DstPort),        // int32
+		formatInt32(a.Version),        // int32
+		formatInt32(a.ProtocolType),   // int32
+		a.MessageType,                 // string
+		formatInt32(a.MessageLength),  // int32
+		formatUint32(a.TEID),          // uint32
+		formatInt32(a.SequenceNumber), // int32
+		formatInt32(a.NPDU),           // int32
+		joinInts(a.ExtensionHeaders),  // []int32
+		a.InnerSrcIP,                  // string
+		a.InnerDstIP,                  // string
+		formatInt32(a.InnerProtocol),  // int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *GTPv1U) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *GTPv1U) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// TEIDs and inner addresses are not used as labels to keep the metric cardinality low.
+var gtpv1uMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_GTPv1U.String()),
+		Help: Type_NC_GTPv1U.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldDstIP, fieldMessageType},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *GTPv1U) Inc() {
+	gtpv1uMetric.WithLabelValues(a.SrcIP, a.DstIP, a.MessageType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *GTPv1U) SetPacketContext(ctx *PacketContext) {
+	a.SrcIP = ctx.SrcIP
+	a.DstIP = ctx.DstIP
+	a.SrcPort = ctx.SrcPort
+	a.DstPort = ctx.DstPort
+}
+
+// Src returns the source address of the audit record.
+func (a *GTPv1U) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *GTPv1U) Dst() string {
+	return a.DstIP
+}
+
+var gtpv1uEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *GTPv1U) Encode() []string {
+	return filter([]string{
+		gtpv1uEncoder.Int64(fieldTimestamp, a.Timestamp),
+		gtpv1uEncoder.String(fieldSrcIP, a.SrcIP),                                 // string
+		gtpv1uEncoder.String(fieldDstIP, a.DstIP),                                 // string
+		gtpv1uEncoder.Int32(fieldSrcPort, a.SrcPort),                              // int32
+		gtpv1uEncoder.Int32(fieldDstPort, a.DstPort),                              // int32
+		gtpv1uEncoder.Int32(fieldVersion, a.Version),                              // int32
+		gtpv1uEncoder.Int32(fieldProtocolType, a.ProtocolType),                    // int32
+		gtpv1uEncoder.String(fieldMessageType, a.MessageType),                     // string
+		gtpv1uEncoder.Int32(fieldMessageLength, a.MessageLength),                  // int32
+		gtpv1uEncoder.Uint32(fieldTEID, a.TEID),                                   // uint32
+		gtpv1uEncoder.Int32(fieldSequenceNumber, a.SequenceNumber),                // int32
+		gtpv1uEncoder.Int32(fieldNPDU, a.NPDU),                                    // int32
+		gtpv1uEncoder.String(fieldExtensionHeaders, joinInts(a.ExtensionHeaders)), // []int32
+		gtpv1uEncoder.String(fieldInnerSrcIP, a.InnerSrcIP),                       // string
+		gtpv1uEncoder.String(fieldInnerDstIP, a.InnerDstIP),                       // string
+		gtpv1uEncoder.Int32(fieldInnerProtocol, a.InnerProtocol),                  // int32
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *GTPv1U) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *GTPv1U) NetcapType() Type {
+	return Type_NC_GTPv1U
+}
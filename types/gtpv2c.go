@@ -0,0 +1,160 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldIMSI           = "IMSI"
+	fieldMSISDN         = "MSISDN"
+	fieldMEI            = "MEI"
+	fieldAPN            = "APN"
+	fieldRATType        = "RATType"
+	fieldServingNetwork = "ServingNetwork"
+	fieldPDNAddress     = "PDNAddress"
+	fieldCause          = "Cause"
+	fieldFTEIDs         = "FTEIDs"
+	fieldBearerIDs      = "BearerIDs"
+)
+
+var fieldsGTPv2C = []string{
+	fieldTimestamp,
+	fieldSrcIP,          // string
+	fieldDstIP,          // string
+	fieldSrcPort,        // int32
+	fieldDstPort,        // int32
+	fieldMessageType,    // string
+	fieldTEID,           // uint32
+	fieldSequenceNumber, // uint32
+	fieldIMSI,           // string
+	fieldMSISDN,         // string
+	fieldMEI,            // string
+	fieldAPN,            // string
+	fieldRATType,        // string
+	fieldServingNetwork, // string
+	fieldPDNAddress,     // string
+	fieldCause,          // string
+	fieldFTEIDs,         // []string
+	fieldBearerIDs,      // []int32
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *GTPv2C) CSVHeader() []string {
+	return filter(fieldsGTPv2C)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *GTPv2C) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                        // string
+		a.DstIP,                        // string
+		formatInt32(a.SrcPort),         // int32
+		formatInt32(a.DstPort),         // int32
+		a.MessageType,                  // string
+		formatUint32(a.TEID),           // uint32
+		formatUint32(a.SequenceNumber), // uint32
+		a.IMSI,                         // string
+		a.MSISDN,                       // string
+		a.MEI,                          // string
+		a.APN,                          // string
+		a.RATType,                      // string
+		a.ServingNetwork,               // string
+		a.PDNAddress,                   // string
+		a.Cause,                        // string
+		join(a.FTEIDs...),              // []string
+		joinInts(a.BearerIDs),          // []int32
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *GTPv2C) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *GTPv2C) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// subscriber identities and TEIDs are not used as labels to keep the metric cardinality low.
+var gtpv2cMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_GTPv2C.String()),
+		Help: Type_NC_GTPv2C.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldDstIP, fieldMessageType, fieldCause},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *GTPv2C) Inc() {
+	gtpv2cMetric.WithLabelValues(a.SrcIP, a.DstIP, a.MessageType, a.Cause).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *GTPv2C) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *GTPv2C) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *GTPv2C) Dst() string {
+	return a.DstIP
+}
+
+var gtpv2cEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *GTPv2C) Encode() []string {
+	return filter([]string{
+		gtpv2cEncoder.Int64(fieldTimestamp, a.Timestamp),
+		gtpv2cEncoder.String(fieldSrcIP, a.SrcIP),                   // string
+		gtpv2cEncoder.String(fieldDstIP, a.DstIP),                   // string
+		gtpv2cEncoder.Int32(fieldSrcPort, a.SrcPort),                // int32
+		gtpv2cEncoder.Int32(fieldDstPort, a.DstPort),                // int32
+		gtpv2cEncoder.String(fieldMessageType, a.MessageType),       // string
+		gtpv2cEncoder.Uint32(fieldTEID, a.TEID),                     // uint32
+		gtpv2cEncoder.Uint32(fieldSequenceNumber, a.SequenceNumber), // uint32
+		gtpv2cEncoder.String(fieldIMSI, a.IMSI),                     // string
+		gtpv2cEncoder.String(fieldMSISDN, a.MSISDN),                 // string
+		gtpv2cEncoder.String(fieldMEI, a.MEI),                       // string
+		gtpv2cEncoder.String(fieldAPN, a.APN),                       // string
+		gtpv2cEncoder.String(fieldRATType, a.RATType),               // string
+		gtpv2cEncoder.String(fieldServingNetwork, a.ServingNetwork), // string
+		gtpv2cEncoder.String(fieldPDNAddress, a.PDNAddress),         // string
+		gtpv2cEncoder.String(fieldCause, a.Cause),                   // string
+		gtpv2cEncoder.String(fieldFTEIDs, join(a.FTEIDs...)),        // []string
+		gtpv2cEncoder.String(fieldBearerIDs, joinInts(a.BearerIDs)), // []int32
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *GTPv2C) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *GTPv2C) NetcapType() Type {
+	return Type_NC_GTPv2C
+}
@@ -56,6 +56,8 @@ var fieldsHTTP = []string{
 	fieldReqContentEncoding,
 	fieldResContentEncoding,
 	fieldServerName,
+	fieldSrcPort,
+	fieldDstPort,
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -92,6 +94,8 @@ func (h *HTTP) CSVRecord() []string {
 		h.ReqContentEncoding,
 		h.ResContentEncoding,
 		h.ServerName,
+		formatInt32(h.SrcPort),
+		formatInt32(h.DstPort),
 	})
 }
 
@@ -186,6 +190,8 @@ func (h *HTTP) Encode() []string {
 		httpEncoder.String(fieldReqContentEncoding, h.ReqContentEncoding),
 		httpEncoder.String(fieldResContentEncoding, h.ResContentEncoding),
 		httpEncoder.String(fieldServerName, h.ServerName),
+		httpEncoder.Int32(fieldSrcPort, h.SrcPort),
+		httpEncoder.Int32(fieldDstPort, h.DstPort),
 	})
 }
 
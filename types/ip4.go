@@ -48,6 +48,14 @@ var fieldsIPv4 = []string{
 	//fieldOptions,        // []*IPv4Option
 	fieldPayloadEntropy, // float64
 	fieldPayloadSize,    // int32
+	fieldTEID,           // uint32
+	fieldTunnelSrcIP,    // string
+	fieldTunnelDstIP,    // string
+	fieldInterface,      // string
+	fieldInterfaceID,    // int32
+	fieldDirection,      // string
+	fieldComment,        // string
+	fieldDropCount,      // uint64
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -79,6 +87,14 @@ func (i *IPv4) CSVRecord() []string {
 		//strings.Join(opts, ""),        // []*IPv4Option
 		strconv.FormatFloat(i.PayloadEntropy, 'f', 6, 64), // float64
 		formatInt32(i.PayloadSize),                        // int32
+		formatUint32(i.TEID),                              // uint32
+		i.TunnelSrcIP,                                     // string
+		i.TunnelDstIP,                                     // string
+		i.Interface,                                       // string
+		formatInt32(i.InterfaceID),                        // int32
+		i.Direction,                                       // string
+		i.Comment,                                         // string
+		formatUint64(i.DropCount),                         // uint64
 	})
 }
 
@@ -177,6 +193,14 @@ func (i *IPv4) Inc() {
 func (i *IPv4) SetPacketContext(ctx *PacketContext) {
 	i.SrcPort = ctx.SrcPort
 	i.DstPort = ctx.DstPort
+	i.TEID = ctx.TEID
+	i.TunnelSrcIP = ctx.TunnelSrcIP
+	i.TunnelDstIP = ctx.TunnelDstIP
+	i.Interface = ctx.Interface
+	i.InterfaceID = ctx.InterfaceID
+	i.Direction = ctx.Direction
+	i.Comment = ctx.Comment
+	i.DropCount = ctx.DropCount
 }
 
 // Src returns the source address of the audit record.
@@ -214,6 +238,14 @@ func (i *IPv4) Encode() []string {
 		//ipv4Encoder.String(fieldOptions, strings.Join(opts, "")),   // []*IPv4Option
 		ipv4Encoder.Float64(fieldPayloadEntropy, i.PayloadEntropy), // float64
 		ipv4Encoder.Int32(fieldPayloadSize, i.PayloadSize),         // int32
+		ipv4Encoder.Uint32(fieldTEID, i.TEID),                      // uint32
+		ipv4Encoder.String(fieldTunnelSrcIP, i.TunnelSrcIP),        // string
+		ipv4Encoder.String(fieldTunnelDstIP, i.TunnelDstIP),        // string
+		ipv4Encoder.String(fieldInterface, i.Interface),            // string
+		ipv4Encoder.Int32(fieldInterfaceID, i.InterfaceID),         // int32
+		ipv4Encoder.String(fieldDirection, i.Direction),            // string
+		ipv4Encoder.String(fieldComment, i.Comment),                // string
+		ipv4Encoder.Uint64(fieldDropCount, i.DropCount),            // uint64
 	})
 }
 
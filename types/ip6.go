@@ -41,6 +41,14 @@ var fieldsIPv6 = []string{
 	fieldDstIP,          // string
 	fieldPayloadEntropy, // float64
 	fieldPayloadSize,    // int32
+	fieldTEID,           // uint32
+	fieldTunnelSrcIP,    // string
+	fieldTunnelDstIP,    // string
+	fieldInterface,      // string
+	fieldInterfaceID,    // int32
+	fieldDirection,      // string
+	fieldComment,        // string
+	fieldDropCount,      // uint64
 	//fieldHopByHop,       // *IPv6HopByHop
 }
 
@@ -63,6 +71,14 @@ func (i *IPv6) CSVRecord() []string {
 		i.DstIP,                     // string
 		strconv.FormatFloat(i.PayloadEntropy, 'f', 6, 64), // float64
 		formatInt32(i.PayloadSize),                        // int32
+		formatUint32(i.TEID),                              // uint32
+		i.TunnelSrcIP,                                     // string
+		i.TunnelDstIP,                                     // string
+		i.Interface,                                       // string
+		formatInt32(i.InterfaceID),                        // int32
+		i.Direction,                                       // string
+		i.Comment,                                         // string
+		formatUint64(i.DropCount),                         // uint64
 		//hop,                                               // *IPv6HopByHop
 	})
 }
@@ -150,6 +166,14 @@ func (i *IPv6) Inc() {
 func (i *IPv6) SetPacketContext(ctx *PacketContext) {
 	i.SrcPort = ctx.SrcPort
 	i.DstPort = ctx.DstPort
+	i.TEID = ctx.TEID
+	i.TunnelSrcIP = ctx.TunnelSrcIP
+	i.TunnelDstIP = ctx.TunnelDstIP
+	i.Interface = ctx.Interface
+	i.InterfaceID = ctx.InterfaceID
+	i.Direction = ctx.Direction
+	i.Comment = ctx.Comment
+	i.DropCount = ctx.DropCount
 }
 
 // Src returns the source address of the audit record.
@@ -178,6 +202,14 @@ func (i *IPv6) Encode() []string {
 		ipv6Encoder.String(fieldDstIP, i.DstIP),                    // string
 		ipv6Encoder.Float64(fieldPayloadEntropy, i.PayloadEntropy), // float64
 		ipv6Encoder.Int32(fieldPayloadSize, i.PayloadSize),         // int32
+		ipv6Encoder.Uint32(fieldTEID, i.TEID),                      // uint32
+		ipv6Encoder.String(fieldTunnelSrcIP, i.TunnelSrcIP),        // string
+		ipv6Encoder.String(fieldTunnelDstIP, i.TunnelDstIP),        // string
+		ipv6Encoder.String(fieldInterface, i.Interface),            // string
+		ipv6Encoder.Int32(fieldInterfaceID, i.InterfaceID),         // int32
+		ipv6Encoder.String(fieldDirection, i.Direction),            // string
+		ipv6Encoder.String(fieldComment, i.Comment),                // string
+		ipv6Encoder.Uint64(fieldDropCount, i.DropCount),            // uint64
 		// TODO: flatten
 		//hop,                                               // *IPv6HopByHop
 	})
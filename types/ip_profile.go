@@ -44,6 +44,7 @@ var fieldsIPProfile = []string{
 	//fieldDstPorts,       // map[string]*Port
 	//fieldSrcPorts,       // map[string]*Port
 	//fieldSNIs,           // map[string]int64
+	fieldEncapsulation, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -67,6 +68,7 @@ func (d *IPProfile) CSVRecord() []string {
 		// d.DstPorts,
 		// d.SrcPorts,
 		// d.SNIs,
+		d.Encapsulation,
 	})
 }
 
@@ -113,6 +115,7 @@ func (d *IPProfile) Encode() []string {
 		ipProfileEncoder.Int64(fieldTimestampLast, d.TimestampLast),
 		ipProfileEncoder.String(fieldApplications, join(d.Applications...)),
 		ipProfileEncoder.Uint64(fieldBytes, d.Bytes),
+		ipProfileEncoder.String(fieldEncapsulation, d.Encapsulation),
 	})
 }
 
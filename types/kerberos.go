@@ -0,0 +1,155 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldTransport            = "Transport"
+	fieldMessageType          = "MessageType"
+	fieldRealm                = "Realm"
+	fieldClientName           = "ClientName"
+	fieldServiceName          = "ServiceName"
+	fieldEncryptionTypes      = "EncryptionTypes"
+	fieldTicketEncryptionType = "TicketEncryptionType"
+	fieldPreAuthTypes         = "PreAuthTypes"
+	fieldErrorText            = "ErrorText"
+)
+
+var fieldsKerberos = []string{
+	fieldTimestamp,
+	fieldClientIP,             // string
+	fieldServerIP,             // string
+	fieldClientPort,           // int32
+	fieldServerPort,           // int32
+	fieldTransport,            // string
+	fieldMessageType,          // string
+	fieldRealm,                // string
+	fieldClientName,           // string
+	fieldServiceName,          // string
+	fieldEncryptionTypes,      // []int32
+	fieldTicketEncryptionType, // int32
+	fieldPreAuthTypes,         // []int32
+	fieldErrorCode,            // int32
+	fieldError,                // string
+	fieldErrorText,            // string
+	fieldFlow,                 // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *Kerberos) CSVHeader() []string {
+	return filter(fieldsKerberos)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *Kerberos) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                          // string
+		a.ServerIP,                          // string
+		formatInt32(a.ClientPort),           // int32
+		formatInt32(a.ServerPort),           // int32
+		a.Transport,                         // string
+		a.MessageType,                       // string
+		a.Realm,                             // string
+		a.ClientName,                        // string
+		a.ServiceName,                       // string
+		joinInts(a.EncryptionTypes),         // []int32
+		formatInt32(a.TicketEncryptionType), // int32
+		joinInts(a.PreAuthTypes),            // []int32
+		formatInt32(a.ErrorCode),            // int32
+		a.Error,                             // string
+		a.ErrorText,                         // string
+		a.Flow,                              // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *Kerberos) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *Kerberos) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var kerberosMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Kerberos.String()),
+		Help: Type_NC_Kerberos.String() + " audit records",
+	},
+	[]string{fieldClientIP, fieldServerIP, fieldMessageType, fieldRealm, fieldError},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *Kerberos) Inc() {
+	kerberosMetric.WithLabelValues(a.ClientIP, a.ServerIP, a.MessageType, a.Realm, a.Error).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *Kerberos) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *Kerberos) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *Kerberos) Dst() string {
+	return a.ServerIP
+}
+
+var kerberosEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *Kerberos) Encode() []string {
+	return filter([]string{
+		kerberosEncoder.Int64(fieldTimestamp, a.Timestamp),
+		kerberosEncoder.String(fieldClientIP, a.ClientIP),                         // string
+		kerberosEncoder.String(fieldServerIP, a.ServerIP),                         // string
+		kerberosEncoder.Int32(fieldClientPort, a.ClientPort),                      // int32
+		kerberosEncoder.Int32(fieldServerPort, a.ServerPort),                      // int32
+		kerberosEncoder.String(fieldTransport, a.Transport),                       // string
+		kerberosEncoder.String(fieldMessageType, a.MessageType),                   // string
+		kerberosEncoder.String(fieldRealm, a.Realm),                               // string
+		kerberosEncoder.String(fieldClientName, a.ClientName),                     // string
+		kerberosEncoder.String(fieldServiceName, a.ServiceName),                   // string
+		kerberosEncoder.String(fieldEncryptionTypes, joinInts(a.EncryptionTypes)), // []int32
+		kerberosEncoder.Int32(fieldTicketEncryptionType, a.TicketEncryptionType),  // int32
+		kerberosEncoder.String(fieldPreAuthTypes, joinInts(a.PreAuthTypes)),       // []int32
+		kerberosEncoder.Int32(fieldErrorCode, a.ErrorCode),                        // int32
+		kerberosEncoder.String(fieldError, a.Error),                               // string
+		kerberosEncoder.String(fieldErrorText, a.ErrorText),                       // string
+		kerberosEncoder.String(fieldFlow, a.Flow),                                 // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *Kerberos) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *Kerberos) NetcapType() Type {
+	return Type_NC_Kerberos
+}
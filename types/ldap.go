@@ -0,0 +1,170 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldDN                = "DN"
+	fieldAuthMethod        = "AuthMethod"
+	fieldScope             = "Scope"
+	fieldFilter            = "Filter"
+	fieldAttributes        = "Attributes"
+	fieldResultCode        = "ResultCode"
+	fieldResult            = "Result"
+	fieldDiagnosticMessage = "DiagnosticMessage"
+	fieldNumEntries        = "NumEntries"
+)
+
+var fieldsLDAP = []string{
+	fieldTimestamp,
+	fieldClientIP,          // string
+	fieldServerIP,          // string
+	fieldClientPort,        // int32
+	fieldServerPort,        // int32
+	fieldMessageID,         // int32
+	fieldOperation,         // string
+	fieldDN,                // string
+	fieldAuthMethod,        // string
+	fieldScope,             // string
+	fieldFilter,            // string
+	fieldAttributes,        // []string
+	fieldResultCode,        // int32
+	fieldResult,            // string
+	fieldDiagnosticMessage, // string
+	fieldNumEntries,        // int32
+	fieldLatency,           // int64
+	fieldFlow,              // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *LDAP) CSVHeader() []string {
+	return filter(fieldsLDAP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *LDAP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                // string
+		a.ServerIP,                // string
+		formatInt32(a.ClientPort), // int32
+		formatInt32(a.ServerPort), // int32
+		formatInt32(a.MessageID),  // int32
+		a.Operation,               // string
+		a.DN,                      // string
+		a.AuthMethod,              // string
+		a.Scope,                   // string
+		a.Filter,                  // string
+		join(a.Attributes...),     // []string
+		formatInt32(a.ResultCode), // int32
+		a.Result,                  // string
+		a.DiagnosticMessage,       // string
+		formatInt32(a.NumEntries), // int32
+		formatInt64(a.Latency),    // int64
+		a.Flow,                    // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *LDAP) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *LDAP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// the DN, filter and diagnostic messages are not used as labels to keep the metric cardinality low.
+var (
+	ldapMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: strings.ToLower(Type_NC_LDAP.String()),
+			Help: Type_NC_LDAP.String() + " audit records",
+		},
+		[]string{fieldClientIP, fieldServerIP, fieldOperation, fieldResult},
+	)
+	ldapLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    strings.ToLower(Type_NC_LDAP.String()) + "_latency",
+			Help:    Type_NC_LDAP.String() + " response latency in seconds",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		},
+		[]string{fieldServerIP, fieldOperation},
+	)
+)
+
+// Inc increments the metrics for the audit record.
+func (a *LDAP) Inc() {
+	ldapMetric.WithLabelValues(a.ClientIP, a.ServerIP, a.Operation, a.Result).Inc()
+	ldapLatency.WithLabelValues(a.ServerIP, a.Operation).Observe(time.Duration(a.Latency).Seconds())
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *LDAP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *LDAP) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *LDAP) Dst() string {
+	return a.ServerIP
+}
+
+var ldapEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *LDAP) Encode() []string {
+	return filter([]string{
+		ldapEncoder.Int64(fieldTimestamp, a.Timestamp),
+		ldapEncoder.String(fieldClientIP, a.ClientIP),                   // string
+		ldapEncoder.String(fieldServerIP, a.ServerIP),                   // string
+		ldapEncoder.Int32(fieldClientPort, a.ClientPort),                // int32
+		ldapEncoder.Int32(fieldServerPort, a.ServerPort),                // int32
+		ldapEncoder.Int32(fieldMessageID, a.MessageID),                  // int32
+		ldapEncoder.String(fieldOperation, a.Operation),                 // string
+		ldapEncoder.String(fieldDN, a.DN),                               // string
+		ldapEncoder.String(fieldAuthMethod, a.AuthMethod),               // string
+		ldapEncoder.String(fieldScope, a.Scope),                         // string
+		ldapEncoder.String(fieldFilter, a.Filter),                       // string
+		ldapEncoder.String(fieldAttributes, join(a.Attributes...)),      // []string
+		ldapEncoder.Int32(fieldResultCode, a.ResultCode),                // int32
+		ldapEncoder.String(fieldResult, a.Result),                       // string
+		ldapEncoder.String(fieldDiagnosticMessage, a.DiagnosticMessage), // string
+		ldapEncoder.Int32(fieldNumEntries, a.NumEntries),                // int32
+		ldapEncoder.Int64(fieldLatency, a.Latency),                      // int64
+		ldapEncoder.String(fieldFlow, a.Flow),                           // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *LDAP) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *LDAP) NetcapType() Type {
+	return Type_NC_LDAP
+}
@@ -0,0 +1,117 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+var fieldsLLMNR = []string{
+	fieldTimestamp,
+	fieldSrcIP,     // string
+	fieldDstIP,     // string
+	fieldSrcMAC,    // string
+	fieldResponse,  // bool
+	fieldName,      // string
+	fieldType,      // string
+	fieldAddresses, // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *LLMNR) CSVHeader() []string {
+	return filter(fieldsLLMNR)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *LLMNR) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                        // string
+		a.DstIP,                        // string
+		a.SrcMAC,                       // string
+		strconv.FormatBool(a.Response), // bool
+		a.Name,                         // string
+		a.Type,                         // string
+		join(a.Addresses...),           // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *LLMNR) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *LLMNR) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var llmnrMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_LLMNR.String()),
+		Help: Type_NC_LLMNR.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldType, fieldResponse},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *LLMNR) Inc() {
+	llmnrMetric.WithLabelValues(a.SrcIP, a.Type, strconv.FormatBool(a.Response)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *LLMNR) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *LLMNR) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *LLMNR) Dst() string {
+	return a.DstIP
+}
+
+var llmnrEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *LLMNR) Encode() []string {
+	return filter([]string{
+		llmnrEncoder.Int64(fieldTimestamp, a.Timestamp),
+		llmnrEncoder.String(fieldSrcIP, a.SrcIP),                  // string
+		llmnrEncoder.String(fieldDstIP, a.DstIP),                  // string
+		llmnrEncoder.String(fieldSrcMAC, a.SrcMAC),                // string
+		llmnrEncoder.Bool(a.Response),                             // bool
+		llmnrEncoder.String(fieldName, a.Name),                    // string
+		llmnrEncoder.String(fieldType, a.Type),                    // string
+		llmnrEncoder.String(fieldAddresses, join(a.Addresses...)), // []string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *LLMNR) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *LLMNR) NetcapType() Type {
+	return Type_NC_LLMNR
+}
@@ -0,0 +1,142 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldHostnames    = "Hostnames"
+	fieldServiceTypes = "ServiceTypes"
+	fieldServices     = "Services"
+	fieldTargets      = "Targets"
+	fieldTXTs         = "TXTs"
+	fieldModel        = "Model"
+)
+
+var fieldsMDNS = []string{
+	fieldTimestamp,
+	fieldSrcIP,        // string
+	fieldDstIP,        // string
+	fieldSrcMAC,       // string
+	fieldResponse,     // bool
+	fieldQuestions,    // []string
+	fieldHostnames,    // []string
+	fieldAddresses,    // []string
+	fieldServiceTypes, // []string
+	fieldServices,     // []string
+	fieldTargets,      // []string
+	fieldTXTs,         // []string
+	fieldModel,        // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *MDNS) CSVHeader() []string {
+	return filter(fieldsMDNS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *MDNS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                        // string
+		a.DstIP,                        // string
+		a.SrcMAC,                       // string
+		strconv.FormatBool(a.Response), // bool
+		join(a.Questions...),           // []string
+		join(a.Hostnames...),           // []string
+		join(a.Addresses...),           // []string
+		join(a.ServiceTypes...),        // []string
+		join(a.Services...),            // []string
+		join(a.Targets...),             // []string
+		join(a.TXTs...),                // []string
+		a.Model,                        // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *MDNS) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *MDNS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// announced names and services are not used as labels to keep the metric cardinality low.
+var mdnsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_MDNS.String()),
+		Help: Type_NC_MDNS.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldResponse},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *MDNS) Inc() {
+	mdnsMetric.WithLabelValues(a.SrcIP, strconv.FormatBool(a.Response)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *MDNS) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *MDNS) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *MDNS) Dst() string {
+	return a.DstIP
+}
+
+var mdnsEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *MDNS) Encode() []string {
+	return filter([]string{
+		mdnsEncoder.Int64(fieldTimestamp, a.Timestamp),
+		mdnsEncoder.String(fieldSrcIP, a.SrcIP),                        // string
+		mdnsEncoder.String(fieldDstIP, a.DstIP),                        // string
+		mdnsEncoder.String(fieldSrcMAC, a.SrcMAC),                      // string
+		mdnsEncoder.Bool(a.Response),                                   // bool
+		mdnsEncoder.String(fieldQuestions, join(a.Questions...)),       // []string
+		mdnsEncoder.String(fieldHostnames, join(a.Hostnames...)),       // []string
+		mdnsEncoder.String(fieldAddresses, join(a.Addresses...)),       // []string
+		mdnsEncoder.String(fieldServiceTypes, join(a.ServiceTypes...)), // []string
+		mdnsEncoder.String(fieldServices, join(a.Services...)),         // []string
+		mdnsEncoder.String(fieldTargets, join(a.Targets...)),           // []string
+		mdnsEncoder.String(fieldTXTs, join(a.TXTs...)),                 // []string
+		mdnsEncoder.String(fieldModel, a.Model),                        // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *MDNS) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *MDNS) NetcapType() Type {
+	return Type_NC_MDNS
+}
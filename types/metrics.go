@@ -57,6 +57,27 @@ var Metrics = []prometheus.Collector{
 	cipMetric,
 	lcmMetric,
 	pop3Metric,
+	mysqlMetric,
+	mysqlLatency,
+	postgresMetric,
+	postgresLatency,
+	redisMetric,
+	redisLatency,
+	kerberosMetric,
+	ldapMetric,
+	ldapLatency,
+	snmpMetric,
+	sipDialogMetric,
+	rtpStreamMetric,
+	pppoeMetric,
+	pppMetric,
+	mdnsMetric,
+	llmnrMetric,
+	nbnsMetric,
+	ssdpMetric,
+	gtpv1uMetric,
+	gtpv2cMetric,
+	captureStatisticsMetric,
 	connectionsMetric,
 	connTotalSize,
 	connAppPayloadSize,
@@ -0,0 +1,168 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldClientPort    = "ClientPort"
+	fieldServerPort    = "ServerPort"
+	fieldServerVersion = "ServerVersion"
+	fieldDatabase      = "Database"
+	fieldStatement     = "Statement"
+	fieldErrorCode     = "ErrorCode"
+	fieldError         = "Error"
+	fieldAffectedRows  = "AffectedRows"
+	fieldNumRows       = "NumRows"
+	fieldLatency       = "Latency"
+)
+
+var fieldsMySQL = []string{
+	fieldTimestamp,
+	fieldClientIP,      // string
+	fieldServerIP,      // string
+	fieldClientPort,    // int32
+	fieldServerPort,    // int32
+	fieldServerVersion, // string
+	fieldUser,          // string
+	fieldDatabase,      // string
+	fieldCommand,       // string
+	fieldStatement,     // string
+	fieldStatus,        // string
+	fieldErrorCode,     // int32
+	fieldError,         // string
+	fieldAffectedRows,  // int64
+	fieldNumRows,       // int64
+	fieldLatency,       // int64
+	fieldFlow,          // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *MySQL) CSVHeader() []string {
+	return filter(fieldsMySQL)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *MySQL) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                  // string
+		a.ServerIP,                  // string
+		formatInt32(a.ClientPort),   // int32
+		formatInt32(a.ServerPort),   // int32
+		a.ServerVersion,             // string
+		a.User,                      // string
+		a.Database,                  // string
+		a.Command,                   // string
+		a.Statement,                 // string
+		a.Status,                    // string
+		formatInt32(a.ErrorCode),    // int32
+		a.Error,                     // string
+		formatInt64(a.AffectedRows), // int64
+		formatInt64(a.NumRows),      // int64
+		formatInt64(a.Latency),      // int64
+		a.Flow,                      // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *MySQL) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *MySQL) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// the statement and error messages are not used as labels to keep the metric cardinality low.
+var (
+	mysqlMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: strings.ToLower(Type_NC_MySQL.String()),
+			Help: Type_NC_MySQL.String() + " audit records",
+		},
+		[]string{fieldClientIP, fieldServerIP, fieldUser, fieldDatabase, fieldCommand, fieldStatus},
+	)
+	mysqlLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    strings.ToLower(Type_NC_MySQL.String()) + "_latency",
+			Help:    Type_NC_MySQL.String() + " response latency in seconds",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		},
+		[]string{fieldServerIP, fieldCommand},
+	)
+)
+
+// Inc increments the metrics for the audit record.
+func (a *MySQL) Inc() {
+	mysqlMetric.WithLabelValues(a.ClientIP, a.ServerIP, a.User, a.Database, a.Command, a.Status).Inc()
+	mysqlLatency.WithLabelValues(a.ServerIP, a.Command).Observe(time.Duration(a.Latency).Seconds())
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *MySQL) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *MySQL) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *MySQL) Dst() string {
+	return a.ServerIP
+}
+
+var mysqlEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *MySQL) Encode() []string {
+	return filter([]string{
+		mysqlEncoder.Int64(fieldTimestamp, a.Timestamp),
+		mysqlEncoder.String(fieldClientIP, a.ClientIP),           // string
+		mysqlEncoder.String(fieldServerIP, a.ServerIP),           // string
+		mysqlEncoder.Int32(fieldClientPort, a.ClientPort),        // int32
+		mysqlEncoder.Int32(fieldServerPort, a.ServerPort),        // int32
+		mysqlEncoder.String(fieldServerVersion, a.ServerVersion), // string
+		mysqlEncoder.String(fieldUser, a.User),                   // string
+		mysqlEncoder.String(fieldDatabase, a.Database),           // string
+		mysqlEncoder.String(fieldCommand, a.Command),             // string
+		mysqlEncoder.String(fieldStatement, a.Statement),         // string
+		mysqlEncoder.String(fieldStatus, a.Status),               // string
+		mysqlEncoder.Int32(fieldErrorCode, a.ErrorCode),          // int32
+		mysqlEncoder.String(fieldError, a.Error),                 // string
+		mysqlEncoder.Int64(fieldAffectedRows, a.AffectedRows),    // int64
+		mysqlEncoder.Int64(fieldNumRows, a.NumRows),              // int64
+		mysqlEncoder.Int64(fieldLatency, a.Latency),              // int64
+		mysqlEncoder.String(fieldFlow, a.Flow),                   // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *MySQL) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *MySQL) NetcapType() Type {
+	return Type_NC_MySQL
+}
@@ -0,0 +1,128 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldSuffix = "Suffix"
+	fieldNames  = "Names"
+)
+
+var fieldsNBNS = []string{
+	fieldTimestamp,
+	fieldSrcIP,     // string
+	fieldDstIP,     // string
+	fieldSrcMAC,    // string
+	fieldResponse,  // bool
+	fieldOperation, // string
+	fieldName,      // string
+	fieldSuffix,    // string
+	fieldAddresses, // []string
+	fieldNames,     // []string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *NBNS) CSVHeader() []string {
+	return filter(fieldsNBNS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *NBNS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                        // string
+		a.DstIP,                        // string
+		a.SrcMAC,                       // string
+		strconv.FormatBool(a.Response), // bool
+		a.Operation,                    // string
+		a.Name,                         // string
+		a.Suffix,                       // string
+		join(a.Addresses...),           // []string
+		join(a.Names...),               // []string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *NBNS) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *NBNS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var nbnsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_NBNS.String()),
+		Help: Type_NC_NBNS.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldOperation, fieldSuffix, fieldResponse},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *NBNS) Inc() {
+	nbnsMetric.WithLabelValues(a.SrcIP, a.Operation, a.Suffix, strconv.FormatBool(a.Response)).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *NBNS) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *NBNS) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *NBNS) Dst() string {
+	return a.DstIP
+}
+
+var nbnsEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *NBNS) Encode() []string {
+	return filter([]string{
+		nbnsEncoder.Int64(fieldTimestamp, a.Timestamp),
+		nbnsEncoder.String(fieldSrcIP, a.SrcIP),                  // string
+		nbnsEncoder.String(fieldDstIP, a.DstIP),                  // string
+		nbnsEncoder.String(fieldSrcMAC, a.SrcMAC),                // string
+		nbnsEncoder.Bool(a.Response),                             // bool
+		nbnsEncoder.String(fieldOperation, a.Operation),          // string
+		nbnsEncoder.String(fieldName, a.Name),                    // string
+		nbnsEncoder.String(fieldSuffix, a.Suffix),                // string
+		nbnsEncoder.String(fieldAddresses, join(a.Addresses...)), // []string
+		nbnsEncoder.String(fieldNames, join(a.Names...)),         // []string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *NBNS) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *NBNS) NetcapType() Type {
+	return Type_NC_NBNS
+}
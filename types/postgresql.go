@@ -0,0 +1,164 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldApplication = "Application"
+	fieldCommandTag  = "CommandTag"
+	fieldSQLState    = "SQLState"
+)
+
+var fieldsPostgreSQL = []string{
+	fieldTimestamp,
+	fieldClientIP,      // string
+	fieldServerIP,      // string
+	fieldClientPort,    // int32
+	fieldServerPort,    // int32
+	fieldServerVersion, // string
+	fieldUser,          // string
+	fieldDatabase,      // string
+	fieldApplication,   // string
+	fieldCommand,       // string
+	fieldStatement,     // string
+	fieldStatus,        // string
+	fieldCommandTag,    // string
+	fieldSQLState,      // string
+	fieldError,         // string
+	fieldNumRows,       // int64
+	fieldLatency,       // int64
+	fieldFlow,          // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *PostgreSQL) CSVHeader() []string {
+	return filter(fieldsPostgreSQL)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *PostgreSQL) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                // string
+		a.ServerIP,                // string
+		formatInt32(a.ClientPort), // int32
+		formatInt32(a.ServerPort), // int32
+		a.ServerVersion,           // string
+		a.User,                    // string
+		a.Database,                // string
+		a.Application,             // string
+		a.Command,                 // string
+		a.Statement,               // string
+		a.Status,                  // string
+		a.CommandTag,              // string
+		a.SQLState,                // string
+		a.Error,                   // string
+		formatInt64(a.NumRows),    // int64
+		formatInt64(a.Latency),    // int64
+		a.Flow,                    // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *PostgreSQL) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *PostgreSQL) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// the statement and error messages are not used as labels to keep the metric cardinality low.
+var (
+	postgresMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: strings.ToLower(Type_NC_PostgreSQL.String()),
+			Help: Type_NC_PostgreSQL.String() + " audit records",
+		},
+		[]string{fieldClientIP, fieldServerIP, fieldUser, fieldDatabase, fieldCommand, fieldStatus},
+	)
+	postgresLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    strings.ToLower(Type_NC_PostgreSQL.String()) + "_latency",
+			Help:    Type_NC_PostgreSQL.String() + " response latency in seconds",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		},
+		[]string{fieldServerIP, fieldCommand},
+	)
+)
+
+// Inc increments the metrics for the audit record.
+func (a *PostgreSQL) Inc() {
+	postgresMetric.WithLabelValues(a.ClientIP, a.ServerIP, a.User, a.Database, a.Command, a.Status).Inc()
+	postgresLatency.WithLabelValues(a.ServerIP, a.Command).Observe(time.Duration(a.Latency).Seconds())
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *PostgreSQL) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *PostgreSQL) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *PostgreSQL) Dst() string {
+	return a.ServerIP
+}
+
+var postgresEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *PostgreSQL) Encode() []string {
+	return filter([]string{
+		postgresEncoder.Int64(fieldTimestamp, a.Timestamp),
+		postgresEncoder.String(fieldClientIP, a.ClientIP),           // string
+		postgresEncoder.String(fieldServerIP, a.ServerIP),           // string
+		postgresEncoder.Int32(fieldClientPort, a.ClientPort),        // int32
+		postgresEncoder.Int32(fieldServerPort, a.ServerPort),        // int32
+		postgresEncoder.String(fieldServerVersion, a.ServerVersion), // string
+		postgresEncoder.String(fieldUser, a.User),                   // string
+		postgresEncoder.String(fieldDatabase, a.Database),           // string
+		postgresEncoder.String(fieldApplication, a.Application),     // string
+		postgresEncoder.String(fieldCommand, a.Command),             // string
+		postgresEncoder.String(fieldStatement, a.Statement),         // string
+		postgresEncoder.String(fieldStatus, a.Status),               // string
+		postgresEncoder.String(fieldCommandTag, a.CommandTag),       // string
+		postgresEncoder.String(fieldSQLState, a.SQLState),           // string
+		postgresEncoder.String(fieldError, a.Error),                 // string
+		postgresEncoder.Int64(fieldNumRows, a.NumRows),              // int64
+		postgresEncoder.Int64(fieldLatency, a.Latency),              // int64
+		postgresEncoder.String(fieldFlow, a.Flow),                   // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *PostgreSQL) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *PostgreSQL) NetcapType() Type {
+	return Type_NC_PostgreSQL
+}
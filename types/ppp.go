@@ -0,0 +1,154 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldMRU          = "MRU"
+	fieldAuthProtocol = "AuthProtocol"
+	fieldMagicNumber  = "MagicNumber"
+	fieldPrimaryDNS   = "PrimaryDNS"
+	fieldSecondaryDNS = "SecondaryDNS"
+	fieldInterfaceID  = "InterfaceID"
+	fieldMessage      = "Message"
+)
+
+var fieldsPPP = []string{
+	fieldTimestamp,
+	fieldSrcMAC,       // string
+	fieldDstMAC,       // string
+	fieldSessionID,    // int32
+	fieldProtocol,     // string
+	fieldCode,         // string
+	fieldIdentifier,   // int32
+	fieldOptions,      // []string
+	fieldMRU,          // int32
+	fieldAuthProtocol, // string
+	fieldMagicNumber,  // uint32
+	fieldIPAddress,    // string
+	fieldPrimaryDNS,   // string
+	fieldSecondaryDNS, // string
+	fieldInterfaceID,  // string
+	fieldUser,         // string
+	fieldMessage,      // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *PPP) CSVHeader() []string {
+	return filter(fieldsPPP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *PPP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcMAC,                    // string
+		a.DstMAC,                    // string
+		formatInt32(a.SessionID),    // int32
+		a.Protocol,                  // string
+		a.Code,                      // string
+		formatInt32(a.Identifier),   // int32
+		join(a.Options...),          // []string
+		formatInt32(a.MRU),          // int32
+		a.AuthProtocol,              // string
+		formatUint32(a.MagicNumber), // uint32
+		a.IPAddress,                 // string
+		a.PrimaryDNS,                // string
+		a.SecondaryDNS,              // string
+		a.InterfaceID,               // string
+		a.User,                      // string
+		a.Message,                   // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *PPP) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *PPP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// options, addresses and user names are not used as labels to keep the metric cardinality low.
+var pppMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_PPP.String()),
+		Help: Type_NC_PPP.String() + " audit records",
+	},
+	[]string{fieldSrcMAC, fieldDstMAC, fieldProtocol, fieldCode},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *PPP) Inc() {
+	pppMetric.WithLabelValues(a.SrcMAC, a.DstMAC, a.Protocol, a.Code).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *PPP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *PPP) Src() string {
+	return a.SrcMAC
+}
+
+// Dst returns the destination address of the audit record.
+func (a *PPP) Dst() string {
+	return a.DstMAC
+}
+
+var pppEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *PPP) Encode() []string {
+	return filter([]string{
+		pppEncoder.Int64(fieldTimestamp, a.Timestamp),
+		pppEncoder.String(fieldSrcMAC, a.SrcMAC),             // string
+		pppEncoder.String(fieldDstMAC, a.DstMAC),             // string
+		pppEncoder.Int32(fieldSessionID, a.SessionID),        // int32
+		pppEncoder.String(fieldProtocol, a.Protocol),         // string
+		pppEncoder.String(fieldCode, a.Code),                 // string
+		pppEncoder.Int32(fieldIdentifier, a.Identifier),      // int32
+		pppEncoder.String(fieldOptions, join(a.Options...)),  // []string
+		pppEncoder.Int32(fieldMRU, a.MRU),                    // int32
+		pppEncoder.String(fieldAuthProtocol, a.AuthProtocol), // string
+		pppEncoder.Uint32(fieldMagicNumber, a.MagicNumber),   // uint32
+		pppEncoder.String(fieldIPAddress, a.IPAddress),       // string
+		pppEncoder.String(fieldPrimaryDNS, a.PrimaryDNS),     // string
+		pppEncoder.String(fieldSecondaryDNS, a.SecondaryDNS), // string
+		pppEncoder.String(fieldInterfaceID, a.InterfaceID),   // string
+		pppEncoder.String(fieldUser, a.User),                 // string
+		pppEncoder.String(fieldMessage, a.Message),           // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *PPP) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *PPP) NetcapType() Type {
+	return Type_NC_PPP
+}
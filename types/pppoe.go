@@ -0,0 +1,129 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldACName   = "ACName"
+	fieldHostUniq = "HostUniq"
+	fieldACCookie = "ACCookie"
+)
+
+var fieldsPPPoE = []string{
+	fieldTimestamp,
+	fieldSrcMAC,      // string
+	fieldDstMAC,      // string
+	fieldCode,        // string
+	fieldSessionID,   // int32
+	fieldServiceName, // string
+	fieldACName,      // string
+	fieldHostUniq,    // string
+	fieldACCookie,    // string
+	fieldError,       // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *PPPoE) CSVHeader() []string {
+	return filter(fieldsPPPoE)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *PPPoE) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcMAC,                 // string
+		a.DstMAC,                 // string
+		a.Code,                   // string
+		formatInt32(a.SessionID), // int32
+		a.ServiceName,            // string
+		a.ACName,                 // string
+		a.HostUniq,               // string
+		a.ACCookie,               // string
+		a.Error,                  // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *PPPoE) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *PPPoE) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// session IDs, host uniq values and cookies are not used as labels to keep the metric cardinality low.
+var pppoeMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_PPPoE.String()),
+		Help: Type_NC_PPPoE.String() + " audit records",
+	},
+	[]string{fieldSrcMAC, fieldDstMAC, fieldCode, fieldACName},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *PPPoE) Inc() {
+	pppoeMetric.WithLabelValues(a.SrcMAC, a.DstMAC, a.Code, a.ACName).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *PPPoE) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *PPPoE) Src() string {
+	return a.SrcMAC
+}
+
+// Dst returns the destination address of the audit record.
+func (a *PPPoE) Dst() string {
+	return a.DstMAC
+}
+
+var pppoeEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *PPPoE) Encode() []string {
+	return filter([]string{
+		pppoeEncoder.Int64(fieldTimestamp, a.Timestamp),
+		pppoeEncoder.String(fieldSrcMAC, a.SrcMAC),           // string
+		pppoeEncoder.String(fieldDstMAC, a.DstMAC),           // string
+		pppoeEncoder.String(fieldCode, a.Code),               // string
+		pppoeEncoder.Int32(fieldSessionID, a.SessionID),      // int32
+		pppoeEncoder.String(fieldServiceName, a.ServiceName), // string
+		pppoeEncoder.String(fieldACName, a.ACName),           // string
+		pppoeEncoder.String(fieldHostUniq, a.HostUniq),       // string
+		pppoeEncoder.String(fieldACCookie, a.ACCookie),       // string
+		pppoeEncoder.String(fieldError, a.Error),             // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *PPPoE) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *PPPoE) NetcapType() Type {
+	return Type_NC_PPPoE
+}
@@ -0,0 +1,147 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const fieldNumElements = "NumElements"
+
+var fieldsRedis = []string{
+	fieldTimestamp,
+	fieldClientIP,    // string
+	fieldServerIP,    // string
+	fieldClientPort,  // int32
+	fieldServerPort,  // int32
+	fieldUser,        // string
+	fieldDatabase,    // int32
+	fieldCommand,     // string
+	fieldStatement,   // string
+	fieldStatus,      // string
+	fieldError,       // string
+	fieldNumElements, // int64
+	fieldLatency,     // int64
+	fieldFlow,        // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *Redis) CSVHeader() []string {
+	return filter(fieldsRedis)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *Redis) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                 // string
+		a.ServerIP,                 // string
+		formatInt32(a.ClientPort),  // int32
+		formatInt32(a.ServerPort),  // int32
+		a.User,                     // string
+		formatInt32(a.Database),    // int32
+		a.Command,                  // string
+		a.Statement,                // string
+		a.Status,                   // string
+		a.Error,                    // string
+		formatInt64(a.NumElements), // int64
+		formatInt64(a.Latency),     // int64
+		a.Flow,                     // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *Redis) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *Redis) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var (
+	redisMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: strings.ToLower(Type_NC_Redis.String()),
+			Help: Type_NC_Redis.String() + " audit records",
+		},
+		[]string{fieldClientIP, fieldServerIP, fieldUser, fieldCommand, fieldStatus},
+	)
+	redisLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    strings.ToLower(Type_NC_Redis.String()) + "_latency",
+			Help:    Type_NC_Redis.String() + " response latency in seconds",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		},
+		[]string{fieldServerIP, fieldCommand},
+	)
+)
+
+// Inc increments the metrics for the audit record.
+func (a *Redis) Inc() {
+	redisMetric.WithLabelValues(a.ClientIP, a.ServerIP, a.User, a.Command, a.Status).Inc()
+	redisLatency.WithLabelValues(a.ServerIP, a.Command).Observe(time.Duration(a.Latency).Seconds())
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *Redis) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *Redis) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *Redis) Dst() string {
+	return a.ServerIP
+}
+
+var redisEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *Redis) Encode() []string {
+	return filter([]string{
+		redisEncoder.Int64(fieldTimestamp, a.Timestamp),
+		redisEncoder.String(fieldClientIP, a.ClientIP),      // string
+		redisEncoder.String(fieldServerIP, a.ServerIP),      // string
+		redisEncoder.Int32(fieldClientPort, a.ClientPort),   // int32
+		redisEncoder.Int32(fieldServerPort, a.ServerPort),   // int32
+		redisEncoder.String(fieldUser, a.User),              // string
+		redisEncoder.Int32(fieldDatabase, a.Database),       // int32
+		redisEncoder.String(fieldCommand, a.Command),        // string
+		redisEncoder.String(fieldStatement, a.Statement),    // string
+		redisEncoder.String(fieldStatus, a.Status),          // string
+		redisEncoder.String(fieldError, a.Error),            // string
+		redisEncoder.Int64(fieldNumElements, a.NumElements), // int64
+		redisEncoder.Int64(fieldLatency, a.Latency),         // int64
+		redisEncoder.String(fieldFlow, a.Flow),              // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *Redis) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *Redis) NetcapType() Type {
+	return Type_NC_Redis
+}
@@ -0,0 +1,185 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldSSRC            = "SSRC"
+	fieldPayloadType     = "PayloadType"
+	fieldCodec           = "Codec"
+	fieldClockRate       = "ClockRate"
+	fieldPayloadBytes    = "PayloadBytes"
+	fieldExpectedPackets = "ExpectedPackets"
+	fieldLostPackets     = "LostPackets"
+	fieldLossRate        = "LossRate"
+	fieldSequenceGaps    = "SequenceGaps"
+	fieldOutOfOrder      = "OutOfOrder"
+	fieldDuplicates      = "Duplicates"
+	fieldJitter          = "Jitter"
+	fieldMaxJitter       = "MaxJitter"
+	fieldRTCPPackets     = "RTCPPackets"
+	fieldReportedLost    = "ReportedLost"
+	fieldReportedJitter  = "ReportedJitter"
+)
+
+var fieldsRTPStream = []string{
+	fieldTimestampFirst,
+	fieldTimestampLast,   // int64
+	fieldSrcIP,           // string
+	fieldDstIP,           // string
+	fieldSrcPort,         // int32
+	fieldDstPort,         // int32
+	fieldSSRC,            // uint32
+	fieldPayloadType,     // int32
+	fieldCodec,           // string
+	fieldClockRate,       // int32
+	fieldCallID,          // string
+	fieldNumPackets,      // int64
+	fieldPayloadBytes,    // int64
+	fieldExpectedPackets, // int64
+	fieldLostPackets,     // int64
+	fieldLossRate,        // float64
+	fieldSequenceGaps,    // int32
+	fieldOutOfOrder,      // int32
+	fieldDuplicates,      // int32
+	fieldJitter,          // float64
+	fieldMaxJitter,       // float64
+	fieldRTCPPackets,     // int64
+	fieldReportedLost,    // int64
+	fieldReportedJitter,  // float64
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *RTPStream) CSVHeader() []string {
+	return filter(fieldsRTPStream)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *RTPStream) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.TimestampFirst),
+		formatInt64(a.TimestampLast),    // int64
+		a.SrcIP,                         // string
+		a.DstIP,                         // string
+		formatInt32(a.SrcPort),          // int32
+		formatInt32(a.DstPort),          // int32
+		formatUint32(a.SSRC),            // uint32
+		formatInt32(a.PayloadType),      // int32
+		a.Codec,                         // string
+		formatInt32(a.ClockRate),        // int32
+		a.CallID,                        // string
+		formatInt64(a.NumPackets),       // int64
+		formatInt64(a.PayloadBytes),     // int64
+		formatInt64(a.ExpectedPackets),  // int64
+		formatInt64(a.LostPackets),      // int64
+		formatFloat64(a.LossRate),       // float64
+		formatInt32(a.SequenceGaps),     // int32
+		formatInt32(a.OutOfOrder),       // int32
+		formatInt32(a.Duplicates),       // int32
+		formatFloat64(a.Jitter),         // float64
+		formatFloat64(a.MaxJitter),      // float64
+		formatInt64(a.RTCPPackets),      // int64
+		formatInt64(a.ReportedLost),     // int64
+		formatFloat64(a.ReportedJitter), // float64
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *RTPStream) Time() int64 {
+	return a.TimestampFirst
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *RTPStream) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.TimestampFirst /= int64(time.Millisecond)
+	a.TimestampLast /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// SSRCs and call IDs are not used as labels to keep the metric cardinality low.
+var rtpStreamMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_RTPStream.String()),
+		Help: Type_NC_RTPStream.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldDstIP, fieldCodec},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *RTPStream) Inc() {
+	rtpStreamMetric.WithLabelValues(a.SrcIP, a.DstIP, a.Codec).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *RTPStream) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *RTPStream) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *RTPStream) Dst() string {
+	return a.DstIP
+}
+
+var rtpStreamEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *RTPStream) Encode() []string {
+	return filter([]string{
+		rtpStreamEncoder.Int64(fieldTimestampFirst, a.TimestampFirst),
+		rtpStreamEncoder.Int64(fieldTimestampLast, a.TimestampLast),     // int64
+		rtpStreamEncoder.String(fieldSrcIP, a.SrcIP),                    // string
+		rtpStreamEncoder.String(fieldDstIP, a.DstIP),                    // string
+		rtpStreamEncoder.Int32(fieldSrcPort, a.SrcPort),                 // int32
+		rtpStreamEncoder.Int32(fieldDstPort, a.DstPort),                 // int32
+		rtpStreamEncoder.Uint32(fieldSSRC, a.SSRC),                      // uint32
+		rtpStreamEncoder.Int32(fieldPayloadType, a.PayloadType),         // int32
+		rtpStreamEncoder.String(fieldCodec, a.Codec),                    // string
+		rtpStreamEncoder.Int32(fieldClockRate, a.ClockRate),             // int32
+		rtpStreamEncoder.String(fieldCallID, a.CallID),                  // string
+		rtpStreamEncoder.Int64(fieldNumPackets, a.NumPackets),           // int64
+		rtpStreamEncoder.Int64(fieldPayloadBytes, a.PayloadBytes),       // int64
+		rtpStreamEncoder.Int64(fieldExpectedPackets, a.ExpectedPackets), // int64
+		rtpStreamEncoder.Int64(fieldLostPackets, a.LostPackets),         // int64
+		rtpStreamEncoder.Float64(fieldLossRate, a.LossRate),             // float64
+		rtpStreamEncoder.Int32(fieldSequenceGaps, a.SequenceGaps),       // int32
+		rtpStreamEncoder.Int32(fieldOutOfOrder, a.OutOfOrder),           // int32
+		rtpStreamEncoder.Int32(fieldDuplicates, a.Duplicates),           // int32
+		rtpStreamEncoder.Float64(fieldJitter, a.Jitter),                 // float64
+		rtpStreamEncoder.Float64(fieldMaxJitter, a.MaxJitter),           // float64
+		rtpStreamEncoder.Int64(fieldRTCPPackets, a.RTCPPackets),         // int64
+		rtpStreamEncoder.Int64(fieldReportedLost, a.ReportedLost),       // int64
+		rtpStreamEncoder.Float64(fieldReportedJitter, a.ReportedJitter), // float64
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *RTPStream) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *RTPStream) NetcapType() Type {
+	return Type_NC_RTPStream
+}
@@ -0,0 +1,166 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldCallID         = "CallID"
+	fieldCallerIP       = "CallerIP"
+	fieldCalleeIP       = "CalleeIP"
+	fieldCallerPort     = "CallerPort"
+	fieldCalleePort     = "CalleePort"
+	fieldCodecs         = "Codecs"
+	fieldMediaEndpoints = "MediaEndpoints"
+	fieldFinalStatus    = "FinalStatus"
+	fieldFinalReason    = "FinalReason"
+	fieldSetupTime      = "SetupTime"
+	fieldAnswerTime     = "AnswerTime"
+	fieldEndTime        = "EndTime"
+	fieldTerminatedBy   = "TerminatedBy"
+)
+
+var fieldsSIPDialog = []string{
+	fieldTimestamp,
+	fieldCallID,         // string
+	fieldFrom,           // string
+	fieldTo,             // string
+	fieldUserAgent,      // string
+	fieldCallerIP,       // string
+	fieldCalleeIP,       // string
+	fieldCallerPort,     // int32
+	fieldCalleePort,     // int32
+	fieldCodecs,         // []string
+	fieldMediaEndpoints, // []string
+	fieldFinalStatus,    // int32
+	fieldFinalReason,    // string
+	fieldSetupTime,      // int64
+	fieldAnswerTime,     // int64
+	fieldEndTime,        // int64
+	fieldDuration,       // int64
+	fieldTerminatedBy,   // string
+	fieldState,          // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *SIPDialog) CSVHeader() []string {
+	return filter(fieldsSIPDialog)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *SIPDialog) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.CallID,                   // string
+		a.From,                     // string
+		a.To,                       // string
+		a.UserAgent,                // string
+		a.CallerIP,                 // string
+		a.CalleeIP,                 // string
+		formatInt32(a.CallerPort),  // int32
+		formatInt32(a.CalleePort),  // int32
+		join(a.Codecs...),          // []string
+		join(a.MediaEndpoints...),  // []string
+		formatInt32(a.FinalStatus), // int32
+		a.FinalReason,              // string
+		formatInt64(a.SetupTime),   // int64
+		formatInt64(a.AnswerTime),  // int64
+		formatInt64(a.EndTime),     // int64
+		formatInt64(a.Duration),    // int64
+		a.TerminatedBy,             // string
+		a.State,                    // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *SIPDialog) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *SIPDialog) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// call IDs and the from and to headers are not used as labels to keep the metric cardinality low.
+var sipDialogMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_SIPDialog.String()),
+		Help: Type_NC_SIPDialog.String() + " audit records",
+	},
+	[]string{fieldCallerIP, fieldCalleeIP, fieldFinalStatus, fieldState},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *SIPDialog) Inc() {
+	sipDialogMetric.WithLabelValues(a.CallerIP, a.CalleeIP, formatInt32(a.FinalStatus), a.State).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *SIPDialog) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *SIPDialog) Src() string {
+	return a.CallerIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *SIPDialog) Dst() string {
+	return a.CalleeIP
+}
+
+var sipDialogEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *SIPDialog) Encode() []string {
+	return filter([]string{
+		sipDialogEncoder.Int64(fieldTimestamp, a.Timestamp),
+		sipDialogEncoder.String(fieldCallID, a.CallID),                          // string
+		sipDialogEncoder.String(fieldFrom, a.From),                              // string
+		sipDialogEncoder.String(fieldTo, a.To),                                  // string
+		sipDialogEncoder.String(fieldUserAgent, a.UserAgent),                    // string
+		sipDialogEncoder.String(fieldCallerIP, a.CallerIP),                      // string
+		sipDialogEncoder.String(fieldCalleeIP, a.CalleeIP),                      // string
+		sipDialogEncoder.Int32(fieldCallerPort, a.CallerPort),                   // int32
+		sipDialogEncoder.Int32(fieldCalleePort, a.CalleePort),                   // int32
+		sipDialogEncoder.String(fieldCodecs, join(a.Codecs...)),                 // []string
+		sipDialogEncoder.String(fieldMediaEndpoints, join(a.MediaEndpoints...)), // []string
+		sipDialogEncoder.Int32(fieldFinalStatus, a.FinalStatus),                 // int32
+		sipDialogEncoder.String(fieldFinalReason, a.FinalReason),                // string
+		sipDialogEncoder.Int64(fieldSetupTime, a.SetupTime),                     // int64
+		sipDialogEncoder.Int64(fieldAnswerTime, a.AnswerTime),                   // int64
+		sipDialogEncoder.Int64(fieldEndTime, a.EndTime),                         // int64
+		sipDialogEncoder.Int64(fieldDuration, a.Duration),                       // int64
+		sipDialogEncoder.String(fieldTerminatedBy, a.TerminatedBy),              // string
+		sipDialogEncoder.String(fieldState, a.State),                            // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *SIPDialog) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *SIPDialog) NetcapType() Type {
+	return Type_NC_SIPDialog
+}
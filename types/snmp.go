@@ -0,0 +1,226 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldCommunity       = "Community"
+	fieldPDUType         = "PDUType"
+	fieldRequestID       = "RequestID"
+	fieldErrorStatus     = "ErrorStatus"
+	fieldErrorIndex      = "ErrorIndex"
+	fieldVarBinds        = "VarBinds"
+	fieldEnterprise      = "Enterprise"
+	fieldAgentAddress    = "AgentAddress"
+	fieldGenericTrap     = "GenericTrap"
+	fieldSpecificTrap    = "SpecificTrap"
+	fieldUptime          = "Uptime"
+	fieldTrapOID         = "TrapOID"
+	fieldSecurityLevel   = "SecurityLevel"
+	fieldEngineID        = "EngineID"
+	fieldEngineBoots     = "EngineBoots"
+	fieldEngineTime      = "EngineTime"
+	fieldContextEngineID = "ContextEngineID"
+	fieldContextName     = "ContextName"
+	fieldEncrypted       = "Encrypted"
+)
+
+var fieldsSNMP = []string{
+	fieldTimestamp,
+	fieldSrcIP,           // string
+	fieldDstIP,           // string
+	fieldSrcPort,         // int32
+	fieldDstPort,         // int32
+	fieldVersion,         // string
+	fieldCommunity,       // string
+	fieldPDUType,         // string
+	fieldRequestID,       // int32
+	fieldErrorStatus,     // int32
+	fieldError,           // string
+	fieldErrorIndex,      // int32
+	fieldVarBinds,        // []*SNMPVarBind
+	fieldEnterprise,      // string
+	fieldAgentAddress,    // string
+	fieldGenericTrap,     // int32
+	fieldSpecificTrap,    // int32
+	fieldUptime,          // int64
+	fieldTrapOID,         // string
+	fieldMessageID,       // int32
+	fieldSecurityLevel,   // string
+	fieldEngineID,        // string
+	fieldEngineBoots,     // int32
+	fieldEngineTime,      // int32
+	fieldUser,            // string
+	fieldContextEngineID, // string
+	fieldContextName,     // string
+	fieldEncrypted,       // bool
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *SNMP) CSVHeader() []string {
+	return filter(fieldsSNMP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *SNMP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                         // string
+		a.DstIP,                         // string
+		formatInt32(a.SrcPort),          // int32
+		formatInt32(a.DstPort),          // int32
+		a.Version,                       // string
+		a.Community,                     // string
+		a.PDUType,                       // string
+		formatInt32(a.RequestID),        // int32
+		formatInt32(a.ErrorStatus),      // int32
+		a.Error,                         // string
+		formatInt32(a.ErrorIndex),       // int32
+		joinVarBinds(a.VarBinds),        // []*SNMPVarBind
+		a.Enterprise,                    // string
+		a.AgentAddress,                  // string
+		formatInt32(a.GenericTrap),      // int32
+		formatInt32(a.SpecificTrap),     // int32
+		formatInt64(a.Uptime),           // int64
+		a.TrapOID,                       // string
+		formatInt32(a.MessageID),        // int32
+		a.SecurityLevel,                 // string
+		a.EngineID,                      // string
+		formatInt32(a.EngineBoots),      // int32
+		formatInt32(a.EngineTime),       // int32
+		a.User,                          // string
+		a.ContextEngineID,               // string
+		a.ContextName,                   // string
+		strconv.FormatBool(a.Encrypted), // bool
+	})
+}
+
+func (v *SNMPVarBind) toString() string {
+	var b strings.Builder
+	b.WriteString(StructureBegin)
+	b.WriteString(v.OID)
+	b.WriteString(FieldSeparator)
+	b.WriteString(v.Type)
+	b.WriteString(FieldSeparator)
+	b.WriteString(v.Value)
+	b.WriteString(StructureEnd)
+	return b.String()
+}
+
+func joinVarBinds(binds []*SNMPVarBind) string {
+	vals := make([]string, len(binds))
+	for i, v := range binds {
+		vals[i] = v.toString()
+	}
+
+	return strings.Join(vals, "")
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *SNMP) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *SNMP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// request IDs, variable bindings and the v3 engine parameters are not used as labels to keep the metric cardinality low.
+var snmpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_SNMP.String()),
+		Help: Type_NC_SNMP.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldDstIP, fieldVersion, fieldPDUType, fieldError},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *SNMP) Inc() {
+	snmpMetric.WithLabelValues(a.SrcIP, a.DstIP, a.Version, a.PDUType, a.Error).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *SNMP) SetPacketContext(ctx *PacketContext) {
+	a.SrcIP = ctx.SrcIP
+	a.DstIP = ctx.DstIP
+	a.SrcPort = ctx.SrcPort
+	a.DstPort = ctx.DstPort
+}
+
+// Src returns the source address of the audit record.
+func (a *SNMP) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *SNMP) Dst() string {
+	return a.DstIP
+}
+
+var snmpEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *SNMP) Encode() []string {
+	return filter([]string{
+		snmpEncoder.Int64(fieldTimestamp, a.Timestamp),
+		snmpEncoder.String(fieldSrcIP, a.SrcIP),                     // string
+		snmpEncoder.String(fieldDstIP, a.DstIP),                     // string
+		snmpEncoder.Int32(fieldSrcPort, a.SrcPort),                  // int32
+		snmpEncoder.Int32(fieldDstPort, a.DstPort),                  // int32
+		snmpEncoder.String(fieldVersion, a.Version),                 // string
+		snmpEncoder.String(fieldCommunity, a.Community),             // string
+		snmpEncoder.String(fieldPDUType, a.PDUType),                 // string
+		snmpEncoder.Int32(fieldRequestID, a.RequestID),              // int32
+		snmpEncoder.Int32(fieldErrorStatus, a.ErrorStatus),          // int32
+		snmpEncoder.String(fieldError, a.Error),                     // string
+		snmpEncoder.Int32(fieldErrorIndex, a.ErrorIndex),            // int32
+		snmpEncoder.String(fieldVarBinds, joinVarBinds(a.VarBinds)), // []*SNMPVarBind
+		snmpEncoder.String(fieldEnterprise, a.Enterprise),           // string
+		snmpEncoder.String(fieldAgentAddress, a.AgentAddress),       // string
+		snmpEncoder.Int32(fieldGenericTrap, a.GenericTrap),          // int32
+		snmpEncoder.Int32(fieldSpecificTrap, a.SpecificTrap),        // int32
+		snmpEncoder.Int64(fieldUptime, a.Uptime),                    // int64
+		snmpEncoder.String(fieldTrapOID, a.TrapOID),                 // string
+		snmpEncoder.Int32(fieldMessageID, a.MessageID),              // int32
+		snmpEncoder.String(fieldSecurityLevel, a.SecurityLevel),     // string
+		snmpEncoder.String(fieldEngineID, a.EngineID),               // string
+		snmpEncoder.Int32(fieldEngineBoots, a.EngineBoots),          // int32
+		snmpEncoder.Int32(fieldEngineTime, a.EngineTime),            // int32
+		snmpEncoder.String(fieldUser, a.User),                       // string
+		snmpEncoder.String(fieldContextEngineID, a.ContextEngineID), // string
+		snmpEncoder.String(fieldContextName, a.ContextName),         // string
+		snmpEncoder.Bool(a.Encrypted),                               // bool
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *SNMP) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *SNMP) NetcapType() Type {
+	return Type_NC_SNMP
+}
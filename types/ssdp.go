@@ -0,0 +1,138 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldNotificationType    = "NotificationType"
+	fieldNotificationSubType = "NotificationSubType"
+	fieldUSN                 = "USN"
+)
+
+var fieldsSSDP = []string{
+	fieldTimestamp,
+	fieldSrcIP,               // string
+	fieldDstIP,               // string
+	fieldSrcPort,             // int32
+	fieldDstPort,             // int32
+	fieldSrcMAC,              // string
+	fieldMethod,              // string
+	fieldNotificationType,    // string
+	fieldNotificationSubType, // string
+	fieldUSN,                 // string
+	fieldLocation,            // string
+	fieldServer,              // string
+	fieldUserAgent,           // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *SSDP) CSVHeader() []string {
+	return filter(fieldsSSDP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *SSDP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                // string
+		a.DstIP,                // string
+		formatInt32(a.SrcPort), // int32
+		formatInt32(a.DstPort), // int32
+		a.SrcMAC,               // string
+		a.Method,               // string
+		a.NotificationType,     // string
+		a.NotificationSubType,  // string
+		a.USN,                  // string
+		a.Location,             // string
+		a.Server,               // string
+		a.UserAgent,            // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *SSDP) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *SSDP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+// unique service names and locations are not used as labels to keep the metric cardinality low.
+var ssdpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_SSDP.String()),
+		Help: Type_NC_SSDP.String() + " audit records",
+	},
+	[]string{fieldSrcIP, fieldMethod, fieldNotificationType},
+)
+
+// Inc increments the metrics for the audit record.
+func (a *SSDP) Inc() {
+	ssdpMetric.WithLabelValues(a.SrcIP, a.Method, a.NotificationType).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *SSDP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *SSDP) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *SSDP) Dst() string {
+	return a.DstIP
+}
+
+var ssdpEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *SSDP) Encode() []string {
+	return filter([]string{
+		ssdpEncoder.Int64(fieldTimestamp, a.Timestamp),
+		ssdpEncoder.String(fieldSrcIP, a.SrcIP),                             // string
+		ssdpEncoder.String(fieldDstIP, a.DstIP),                             // string
+		ssdpEncoder.Int32(fieldSrcPort, a.SrcPort),                          // int32
+		ssdpEncoder.Int32(fieldDstPort, a.DstPort),                          // int32
+		ssdpEncoder.String(fieldSrcMAC, a.SrcMAC),                           // string
+		ssdpEncoder.String(fieldMethod, a.Method),                           // string
+		ssdpEncoder.String(fieldNotificationType, a.NotificationType),       // string
+		ssdpEncoder.String(fieldNotificationSubType, a.NotificationSubType), // string
+		ssdpEncoder.String(fieldUSN, a.USN),                                 // string
+		ssdpEncoder.String(fieldLocation, a.Location),                       // string
+		ssdpEncoder.String(fieldServer, a.Server),                           // string
+		ssdpEncoder.String(fieldUserAgent, a.UserAgent),                     // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *SSDP) Analyze() {}
+
+// NetcapType returns the type of the current audit record
+func (a *SSDP) NetcapType() Type {
+	return Type_NC_SSDP
+}
@@ -67,6 +67,7 @@ var fieldsTLSClientHello = []string{
 	fieldDstMAC,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldEncapsulation,
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -104,6 +105,7 @@ func (t *TLSClientHello) CSVRecord() []string {
 		t.DstMAC,
 		formatInt32(t.SrcPort),
 		formatInt32(t.DstPort),
+		t.Encapsulation,
 	})
 }
 
@@ -177,6 +179,7 @@ func (t *TLSClientHello) Encode() []string {
 		tlsClientHelloEncoder.String(fieldDstMAC, t.DstMAC),
 		tlsClientHelloEncoder.Int32(fieldSrcPort, t.SrcPort),
 		tlsClientHelloEncoder.Int32(fieldDstPort, t.DstPort),
+		tlsClientHelloEncoder.String(fieldEncapsulation, t.Encapsulation),
 	})
 }
 
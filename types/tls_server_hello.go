@@ -71,6 +71,7 @@ var fieldsTLSServerHello = []string{
 	fieldSrcPort,
 	fieldDstPort,
 	fieldJa3S,
+	fieldEncapsulation,
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -108,6 +109,7 @@ func (t *TLSServerHello) CSVRecord() []string {
 		formatInt32(t.SrcPort),
 		formatInt32(t.DstPort),
 		t.Ja3S,
+		t.Encapsulation,
 	})
 }
 
@@ -181,6 +183,7 @@ func (t *TLSServerHello) Encode() []string {
 		tlsServerHelloEncoder.Int32(fieldSrcPort, t.SrcPort),
 		tlsServerHelloEncoder.Int32(fieldDstPort, t.DstPort),
 		tlsServerHelloEncoder.String(fieldJa3S, t.Ja3S),
+		tlsServerHelloEncoder.String(fieldEncapsulation, t.Encapsulation),
 	})
 }
 
@@ -0,0 +1,110 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+)
+
+// IP protocol numbers relevant for the community ID.
+const (
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+	protoSCTP   = 132
+)
+
+// ICMP message types and the type of the message sent in response,
+// used to map both directions of an ICMP exchange to the same community ID.
+var (
+	icmpCounterparts = map[uint16]uint16{
+		8: 0, 0: 8, // echo
+		13: 14, 14: 13, // timestamp
+		15: 16, 16: 15, // information
+		10: 9, 9: 10, // router solicitation and advertisement
+		17: 18, 18: 17, // address mask
+	}
+	icmpv6Counterparts = map[uint16]uint16{
+		128: 129, 129: 128, // echo
+		133: 134, 134: 133, // router solicitation and advertisement
+		135: 136, 136: 135, // neighbor solicitation and advertisement
+		130: 131, 131: 130, // multicast listener query and report
+		139: 140, 140: 139, // node information query and response
+		144: 145, 145: 144, // home agent address discovery
+	}
+)
+
+// CommunityID computes the version 1 community ID flow hash (https://github.com/corelight/community-id-spec)
+// which identifies a connection in the same way as Zeek, Suricata and other tools.
+// For ICMP and ICMPv6, the message type and code must be passed as source and destination port.
+// The seed is zero unless configured otherwise for the whole deployment.
+func CommunityID(seed uint16, srcIP, dstIP net.IP, srcPort, dstPort uint16, proto uint8) string {
+	if v4 := srcIP.To4(); v4 != nil {
+		srcIP = v4
+	}
+
+	if v4 := dstIP.To4(); v4 != nil {
+		dstIP = v4
+	}
+
+	oneWay := false
+
+	switch proto {
+	case protoICMP:
+		srcPort, dstPort, oneWay = icmpPorts(icmpCounterparts, srcPort, dstPort)
+	case protoICMPv6:
+		srcPort, dstPort, oneWay = icmpPorts(icmpv6Counterparts, srcPort, dstPort)
+	}
+
+	// order the endpoints, so that both directions produce the same hash
+	if !oneWay {
+		c := bytes.Compare(srcIP, dstIP)
+		if c > 0 || (c == 0 && srcPort > dstPort) {
+			srcIP, dstIP = dstIP, srcIP
+			srcPort, dstPort = dstPort, srcPort
+		}
+	}
+
+	b := make([]byte, 0, 2+2*net.IPv6len+2+4)
+	b = append(b, byte(seed>>8), byte(seed))
+	b = append(b, srcIP...)
+	b = append(b, dstIP...)
+	b = append(b, proto, 0)
+
+	switch proto {
+	case protoICMP, protoTCP, protoUDP, protoICMPv6, protoSCTP:
+		b = append(b, 0, 0, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-4:], srcPort)
+		binary.BigEndian.PutUint16(b[len(b)-2:], dstPort)
+	}
+
+	sum := sha1.Sum(b)
+
+	return "1:" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// icmpPorts returns the port equivalents for an ICMP message type and code.
+// Messages without a counterpart are one way and keep their direction.
+func icmpPorts(counterparts map[uint16]uint16, msgType, code uint16) (uint16, uint16, bool) {
+	if c, ok := counterparts[msgType]; ok {
+		return msgType, c, false
+	}
+
+	return msgType, code, true
+}
@@ -0,0 +1,78 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCommunityID(t *testing.T) {
+	tests := []struct {
+		name             string
+		srcIP, dstIP     string
+		srcPort, dstPort uint16
+		proto            uint8
+		expected         string
+	}{
+		{
+			name:     "tcp",
+			srcIP:    "128.232.110.120",
+			dstIP:    "66.35.250.204",
+			srcPort:  34855,
+			dstPort:  80,
+			proto:    6,
+			expected: "1:LQU9qZlK+B5F3KDmev6m5PMibrg=",
+		},
+		{
+			name:     "tcp reverse",
+			srcIP:    "66.35.250.204",
+			dstIP:    "128.232.110.120",
+			srcPort:  80,
+			dstPort:  34855,
+			proto:    6,
+			expected: "1:LQU9qZlK+B5F3KDmev6m5PMibrg=",
+		},
+		{
+			name:     "icmp echo",
+			srcIP:    "192.168.0.89",
+			dstIP:    "192.168.0.1",
+			srcPort:  8,
+			dstPort:  0,
+			proto:    1,
+			expected: "1:X0snYXpgwiv9TZtqg64sgzUn6Dk=",
+		},
+	}
+
+	for _, test := range tests {
+		id := CommunityID(0, net.ParseIP(test.srcIP), net.ParseIP(test.dstIP), test.srcPort, test.dstPort, test.proto)
+		if id != test.expected {
+			t.Fatal(test.name, "expected", test.expected, "got", id)
+		}
+	}
+
+	// echo request and reply belong to the same flow
+	var (
+		a = net.ParseIP("192.168.0.89")
+		b = net.ParseIP("192.168.0.1")
+	)
+
+	if CommunityID(0, a, b, 8, 0, 1) != CommunityID(0, b, a, 0, 0, 1) {
+		t.Fatal("expected echo request and reply to have the same community ID")
+	}
+
+	if CommunityID(0, a, b, 80, 443, 6) == CommunityID(1, a, b, 80, 443, 6) {
+		t.Fatal("expected the seed to change the community ID")
+	}
+}
@@ -0,0 +1,192 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+// Encapsulation describes the tags and tunnels a packet was carried in.
+// Overlay networks routinely reuse the same private address ranges for every tenant,
+// so the inner 5-tuple alone is not sufficient to tell flows apart.
+type Encapsulation struct {
+
+	// VLANs contains the 802.1Q identifiers, outermost first.
+	VLANs []uint16
+
+	// MPLSLabels contains the MPLS label stack as seen on the wire, outermost first.
+	MPLSLabels []uint32
+
+	// Tunnel is the name of the innermost tunnel protocol: vxlan, geneve, gre or gtp.
+	Tunnel string
+
+	// TunnelID is the VNI, GRE key or GTP TEID, empty if the tunnel does not carry one.
+	TunnelID string
+
+	// OuterSrcIP and OuterDstIP are the tunnel endpoints.
+	OuterSrcIP string
+	OuterDstIP string
+}
+
+// DecodeEncapsulation walks the layers of the packet and collects its encapsulation context.
+// It returns the innermost network and transport layers,
+// and a nil Encapsulation if the packet was neither tagged nor tunneled.
+func DecodeEncapsulation(p gopacket.Packet) (*Encapsulation, gopacket.NetworkLayer, gopacket.TransportLayer) {
+	var (
+		e         = new(Encapsulation)
+		found     bool
+		netLayer  gopacket.NetworkLayer
+		transport gopacket.TransportLayer
+	)
+
+	for _, l := range p.Layers() {
+		switch layer := l.(type) {
+		case *layers.Dot1Q:
+			e.VLANs = append(e.VLANs, layer.VLANIdentifier)
+			found = true
+		case *layers.MPLS:
+			e.MPLSLabels = append(e.MPLSLabels, layer.Label)
+			found = true
+		case *layers.VXLAN:
+			e.setTunnel("vxlan", strconv.FormatUint(uint64(layer.VNI), 10))
+			found = true
+		case *layers.Geneve:
+			e.setTunnel("geneve", strconv.FormatUint(uint64(layer.VNI), 10))
+			found = true
+		case *layers.GRE:
+			if layer.KeyPresent {
+				e.setTunnel("gre", strconv.FormatUint(uint64(layer.Key), 10))
+			} else {
+				e.setTunnel("gre", "")
+			}
+			found = true
+		case *layers.GTPv1U:
+			e.setTunnel("gtp", strconv.FormatUint(uint64(layer.TEID), 10))
+			found = true
+		case gopacket.NetworkLayer:
+			// a nested network layer turns the previous one into the tunnel endpoints
+			if netLayer != nil {
+				e.OuterSrcIP = netLayer.NetworkFlow().Src().String()
+				e.OuterDstIP = netLayer.NetworkFlow().Dst().String()
+				found = true
+			}
+			netLayer = layer
+			transport = nil
+		case gopacket.TransportLayer:
+			if transport == nil {
+				transport = layer
+			}
+		}
+	}
+
+	if !found {
+		return nil, netLayer, transport
+	}
+
+	return e, netLayer, transport
+}
+
+func (e *Encapsulation) setTunnel(name, id string) {
+	e.Tunnel = name
+	e.TunnelID = id
+}
+
+// String returns the directional representation of the encapsulation,
+// e.g: vlan=10+vxlan=5000+outer=10.0.0.1>10.0.0.2
+// The format does not contain an '@', so it can be appended to flow identifiers after one:
+// IPv6 tunnel endpoints contain colons, flow identifiers are therefore split at the '@' before parsing the flow.
+func (e *Encapsulation) String() string {
+	if e == nil {
+		return ""
+	}
+
+	return e.format(e.OuterSrcIP, e.OuterDstIP)
+}
+
+// Key returns a representation that is identical for both directions of a flow,
+// by ordering the tunnel endpoints.
+func (e *Encapsulation) Key() string {
+	if e == nil {
+		return ""
+	}
+
+	outer := []string{e.OuterSrcIP, e.OuterDstIP}
+	sort.Strings(outer)
+
+	return e.format(outer[0], outer[1])
+}
+
+// Tenant returns the representation without the tunnel endpoints,
+// to identify the address space a host lives in.
+func (e *Encapsulation) Tenant() string {
+	if e == nil {
+		return ""
+	}
+
+	return e.format("", "")
+}
+
+func (e *Encapsulation) format(outerSrc, outerDst string) string {
+	var segments []string
+
+	if len(e.VLANs) > 0 {
+		ids := make([]string, len(e.VLANs))
+		for i, id := range e.VLANs {
+			ids[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		segments = append(segments, "vlan="+strings.Join(ids, "."))
+	}
+
+	if len(e.MPLSLabels) > 0 {
+		labels := make([]string, len(e.MPLSLabels))
+		for i, label := range e.MPLSLabels {
+			labels[i] = strconv.FormatUint(uint64(label), 10)
+		}
+		segments = append(segments, "mpls="+strings.Join(labels, "."))
+	}
+
+	if e.Tunnel != "" {
+		if e.TunnelID != "" {
+			segments = append(segments, e.Tunnel+"="+e.TunnelID)
+		} else {
+			segments = append(segments, e.Tunnel)
+		}
+	}
+
+	if outerSrc != "" || outerDst != "" {
+		segments = append(segments, "outer="+outerSrc+">"+outerDst)
+	}
+
+	return strings.Join(segments, "+")
+}
+
+// ReverseEncapsulation swaps the tunnel endpoints of a directional encapsulation string.
+func ReverseEncapsulation(encapsulation string) string {
+	i := strings.Index(encapsulation, "outer=")
+	if i == -1 {
+		return encapsulation
+	}
+
+	endpoints := strings.Split(encapsulation[i+len("outer="):], ">")
+	if len(endpoints) != 2 {
+		return encapsulation
+	}
+
+	return encapsulation[:i] + "outer=" + endpoints[1] + ">" + endpoints[0]
+}
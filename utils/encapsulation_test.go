@@ -0,0 +1,127 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+func serializeTestPacket(t *testing.T, l ...gopacket.SerializableLayer) gopacket.Packet {
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, l...); err != nil {
+		t.Fatal(err)
+	}
+
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestDecodeEncapsulationVXLAN(t *testing.T) {
+	var (
+		mac  = net.HardwareAddr{0, 1, 2, 3, 4, 5}
+		tcp  = &layers.TCP{SrcPort: 43532, DstPort: 80}
+		ipv4 = &layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	)
+
+	if err := tcp.SetNetworkLayerForChecksum(ipv4); err != nil {
+		t.Fatal(err)
+	}
+
+	p := serializeTestPacket(t,
+		&layers.Ethernet{SrcMAC: mac, DstMAC: mac, EthernetType: layers.EthernetTypeDot1Q},
+		&layers.Dot1Q{VLANIdentifier: 10, Type: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{192, 168, 1, 2}, DstIP: net.IP{192, 168, 1, 1}},
+		&layers.UDP{SrcPort: 50000, DstPort: 4789},
+		&layers.VXLAN{ValidIDFlag: true, VNI: 5000},
+		&layers.Ethernet{SrcMAC: mac, DstMAC: mac, EthernetType: layers.EthernetTypeIPv4},
+		ipv4,
+		tcp,
+	)
+
+	e, netLayer, transport := DecodeEncapsulation(p)
+	if e == nil {
+		t.Fatal("expected encapsulation")
+	}
+
+	if netLayer == nil || netLayer.NetworkFlow().Src().String() != "10.0.0.1" {
+		t.Fatal("expected inner network layer, got", netLayer)
+	}
+
+	if transport == nil || transport.LayerType() != layers.LayerTypeTCP {
+		t.Fatal("expected inner transport layer, got", transport)
+	}
+
+	if s := e.String(); s != "vlan=10+vxlan=5000+outer=192.168.1.2>192.168.1.1" {
+		t.Fatal("unexpected encapsulation", s)
+	}
+
+	if k := e.Key(); k != "vlan=10+vxlan=5000+outer=192.168.1.1>192.168.1.2" {
+		t.Fatal("unexpected key", k)
+	}
+
+	if tenant := e.Tenant(); tenant != "vlan=10+vxlan=5000" {
+		t.Fatal("unexpected tenant", tenant)
+	}
+
+	if r := ReverseEncapsulation(e.String()); r != "vlan=10+vxlan=5000+outer=192.168.1.1>192.168.1.2" {
+		t.Fatal("unexpected reversed encapsulation", r)
+	}
+}
+
+func TestDecodeEncapsulationMPLS(t *testing.T) {
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+
+	p := serializeTestPacket(t,
+		&layers.Ethernet{SrcMAC: mac, DstMAC: mac, EthernetType: layers.EthernetTypeMPLSUnicast},
+		&layers.MPLS{Label: 100, TTL: 64},
+		&layers.MPLS{Label: 200, StackBottom: true, TTL: 64},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}},
+		&layers.UDP{SrcPort: 5000, DstPort: 53},
+	)
+
+	e, _, _ := DecodeEncapsulation(p)
+	if e == nil {
+		t.Fatal("expected encapsulation")
+	}
+
+	if s := e.String(); s != "mpls=100.200" {
+		t.Fatal("unexpected encapsulation", s)
+	}
+}
+
+func TestDecodeEncapsulationPlain(t *testing.T) {
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+
+	p := serializeTestPacket(t,
+		&layers.Ethernet{SrcMAC: mac, DstMAC: mac, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}},
+		&layers.UDP{SrcPort: 5000, DstPort: 53},
+	)
+
+	e, netLayer, transport := DecodeEncapsulation(p)
+	if e != nil {
+		t.Fatal("unexpected encapsulation", e)
+	}
+
+	if netLayer == nil || transport == nil {
+		t.Fatal("expected network and transport layer")
+	}
+
+	if e.String() != "" || e.Key() != "" {
+		t.Fatal("expected empty encapsulation strings for nil value")
+	}
+}
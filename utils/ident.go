@@ -19,7 +19,7 @@ import (
 	"github.com/dreadl0ck/gopacket"
 )
 
-var flowIdentReplacer = strings.NewReplacer(":", "-", "->", "--")
+var flowIdentReplacer = strings.NewReplacer(":", "-", "->", "--", ">", "-")
 
 // CleanIdent will clean a path
 func CleanIdent(path string) string {
@@ -28,7 +28,8 @@ func CleanIdent(path string) string {
 
 // CreateFlowIdentFromLayerFlows creates a flow identifier string.
 // format: srcIP:srcPort->dstIP:dstPort
-func CreateFlowIdentFromLayerFlows(net gopacket.Flow, trans gopacket.Flow) string {
+// if the flow was tagged or tunneled, the encapsulation is appended: srcIP:srcPort->dstIP:dstPort@encapsulation
+func CreateFlowIdentFromLayerFlows(net gopacket.Flow, trans gopacket.Flow, encapsulation string) string {
 	// IPv4:
 	// echo "255.255.255.255:65000->255.255.255.255:65000" | wc -c
 	// 45
@@ -44,12 +45,18 @@ func CreateFlowIdentFromLayerFlows(net gopacket.Flow, trans gopacket.Flow) strin
 	b = append(b, []byte(":")...)
 	b = append(b, []byte(trans.Dst().String())...)
 
+	if encapsulation != "" {
+		b = append(b, '@')
+		b = append(b, []byte(encapsulation)...)
+	}
+
 	return string(b)
 }
 
 // CreateFlowIdent creates a flow identifier string.
 // format: srcIP:srcPort->dstIP:dstPort
-func CreateFlowIdent(srcIP, srcPort, dstIP, dstPort string) string {
+// if the flow was tagged or tunneled, the encapsulation is appended: srcIP:srcPort->dstIP:dstPort@encapsulation
+func CreateFlowIdent(srcIP, srcPort, dstIP, dstPort, encapsulation string) string {
 	// IPv4:
 	// echo "255.255.255.255:65000->255.255.255.255:65000" | wc -c
 	// 45
@@ -65,15 +72,23 @@ func CreateFlowIdent(srcIP, srcPort, dstIP, dstPort string) string {
 	b = append(b, []byte(":")...)
 	b = append(b, []byte(dstPort)...)
 
+	if encapsulation != "" {
+		b = append(b, '@')
+		b = append(b, []byte(encapsulation)...)
+	}
+
 	return string(b)
 }
 
 // ReverseFlowIdent reverses the flow identifier.
 // e.g: 192.168.1.47:53032->165.227.109.154:80
 // will return: 165.227.109.154:80->192.168.1.47:53032
+// the tunnel endpoints of an encapsulation suffix are swapped as well.
 // TODO: benchmark and improve performance
 // TODO: IPv6
 func ReverseFlowIdent(i string) string {
+	i, encapsulation := splitEncapsulation(i)
+
 	arr := strings.Split(i, "->")
 	if len(arr) != 2 {
 		return ""
@@ -89,15 +104,18 @@ func ReverseFlowIdent(i string) string {
 		return ""
 	}
 
-	return CreateFlowIdent(dst[0], dst[1], src[0], src[1])
+	return CreateFlowIdent(dst[0], dst[1], src[0], src[1], ReverseEncapsulation(encapsulation))
 }
 
 // ParseFlowIdent parses the flow identifier.
 // e.g: 192.168.1.47:53032->165.227.109.154:80
 // will return: 192.168.1.47, 53032, 165.227.109.154, 80
+// an encapsulation suffix is ignored, use ParseFlowIdentEncapsulation to retrieve it.
 // TODO: benchmark and improve performance
 // TODO: IPv6
 func ParseFlowIdent(i string) (srcIP, srcPort, dstIP, dstPort string) {
+	i, _ = splitEncapsulation(i)
+
 	arr := strings.Split(i, "->")
 	if len(arr) != 2 {
 		return
@@ -115,3 +133,19 @@ func ParseFlowIdent(i string) (srcIP, srcPort, dstIP, dstPort string) {
 
 	return src[0], src[1], dst[0], dst[1]
 }
+
+// ParseFlowIdentEncapsulation returns the encapsulation suffix of the flow identifier,
+// or an empty string if the flow was neither tagged nor tunneled.
+func ParseFlowIdentEncapsulation(i string) string {
+	_, encapsulation := splitEncapsulation(i)
+
+	return encapsulation
+}
+
+func splitEncapsulation(i string) (ident, encapsulation string) {
+	if idx := strings.IndexByte(i, '@'); idx != -1 {
+		return i[:idx], i[idx+1:]
+	}
+
+	return i, ""
+}
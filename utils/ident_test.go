@@ -39,7 +39,7 @@ func TestCreateFlowIdentFromLayerFlows(t *testing.T) {
 		log.Fatal(err)
 	}
 
-	ident := CreateFlowIdentFromLayerFlows(netFlow, trans)
+	ident := CreateFlowIdentFromLayerFlows(netFlow, trans, "")
 	if ident != "1.2.3.4:3456->5.6.7.8:80" {
 		t.Fatal("unexpected ident", ident)
 	}
@@ -65,22 +65,43 @@ func BenchmarkFlowIdentFromLayerFlows(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
-		CreateFlowIdentFromLayerFlows(netFlow, trans)
+		CreateFlowIdentFromLayerFlows(netFlow, trans, "")
 	}
 }
 
 func TestCreateFlowIdent(t *testing.T) {
-	ident := CreateFlowIdent("127.0.0.1", "43532", "127.0.0.1", "80")
+	ident := CreateFlowIdent("127.0.0.1", "43532", "127.0.0.1", "80", "")
 	if ident != "127.0.0.1:43532->127.0.0.1:80" {
 		t.Fatal("unexpected ident", ident)
 	}
 }
 
+func TestCreateFlowIdentEncapsulation(t *testing.T) {
+	ident := CreateFlowIdent("10.0.0.1", "43532", "10.0.0.2", "80", "vxlan=5000+outer=192.168.1.1>192.168.1.2")
+	if ident != "10.0.0.1:43532->10.0.0.2:80@vxlan=5000+outer=192.168.1.1>192.168.1.2" {
+		t.Fatal("unexpected ident", ident)
+	}
+
+	res := ReverseFlowIdent(ident)
+	if res != "10.0.0.2:80->10.0.0.1:43532@vxlan=5000+outer=192.168.1.2>192.168.1.1" {
+		t.Fatal("unexpected reversed ident", res)
+	}
+
+	srcIP, srcPort, dstIP, dstPort := ParseFlowIdent(ident)
+	if srcIP != "10.0.0.1" || srcPort != "43532" || dstIP != "10.0.0.2" || dstPort != "80" {
+		t.Fatal("unexpected parse result", srcIP, srcPort, dstIP, dstPort)
+	}
+
+	if enc := ParseFlowIdentEncapsulation(ident); enc != "vxlan=5000+outer=192.168.1.1>192.168.1.2" {
+		t.Fatal("unexpected encapsulation", enc)
+	}
+}
+
 func BenchmarkCreateFlowIdent(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
-		CreateFlowIdent("127.0.0.1", "43532", "127.0.0.1", "80")
+		CreateFlowIdent("127.0.0.1", "43532", "127.0.0.1", "80", "")
 	}
 }
 
@@ -247,7 +247,11 @@ func Progress(current, total int64) string {
 
 // TrimFileExtension returns the netcap file name without file extension.
 func TrimFileExtension(file string) string {
-	return strings.TrimSuffix(strings.TrimSuffix(file, ".gz"), defaults.FileExtension)
+	for _, ext := range []string{".gz", ".zst", ".lz4"} {
+		file = strings.TrimSuffix(file, ext)
+	}
+
+	return strings.TrimSuffix(file, defaults.FileExtension)
 }
 
 // TimeToUTC returns a time string in netcap format to a UTC string.
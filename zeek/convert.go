@@ -0,0 +1,496 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package zeek
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// converter creates audit records of a single type from the records of a log.
+type converter struct {
+	typ     types.Type
+	convert func(r Record) []proto.Message
+}
+
+// converters maps the supported logs to their audit record type.
+var converters = map[string]converter{
+	"conn":   {types.Type_NC_Connection, toConnection},
+	"dns":    {types.Type_NC_DNS, toDNS},
+	"http":   {types.Type_NC_HTTP, toHTTP},
+	"ssl":    {types.Type_NC_TLSClientHello, toTLSClientHello},
+	"x509":   {types.Type_NC_File, toCertificateFile},
+	"files":  {types.Type_NC_File, toFile},
+	"smtp":   {types.Type_NC_SMTP, toSMTP},
+	"ssh":    {types.Type_NC_SSH, toSSH},
+	"notice": {types.Type_NC_Alert, toAlert},
+}
+
+// Logs returns the names of the supported logs.
+func Logs() []string {
+	return []string{"conn", "dns", "http", "ssl", "x509", "files", "smtp", "ssh", "notice"}
+}
+
+// Type returns the audit record type the records of a log are converted to.
+func Type(path string) (types.Type, bool) {
+	c, ok := converters[path]
+
+	return c.typ, ok
+}
+
+// Convert converts a record of the log to audit records of the type returned by Type.
+func Convert(path string, r Record) ([]proto.Message, error) {
+	c, ok := converters[path]
+	if !ok {
+		return nil, ErrUnsupportedLog
+	}
+
+	return c.convert(r), nil
+}
+
+// TLS versions as logged by Zeek.
+var tlsVersions = map[string]int32{
+	"SSLv2":   0x0002,
+	"SSLv3":   0x0300,
+	"TLSv10":  0x0301,
+	"TLSv11":  0x0302,
+	"TLSv12":  0x0303,
+	"TLSv13":  0x0304,
+	"DTLSv10": 0xfeff,
+	"DTLSv12": 0xfefd,
+}
+
+func toConnection(r Record) []proto.Message {
+	var (
+		ts       = r.Time("ts")
+		duration = r.Duration("duration")
+		srcIP    = r.String("id.orig_h")
+	)
+
+	c := &types.Connection{
+		TimestampFirst:      ts.UnixNano(),
+		TimestampLast:       ts.Add(duration).UnixNano(),
+		Duration:            duration.Nanoseconds(),
+		UID:                 r.String("uid"),
+		NetworkProto:        networkProto(srcIP),
+		TransportProto:      transportProto(r.String("proto"), srcIP),
+		ApplicationProto:    strings.Replace(r.String("service"), ",", "/", -1),
+		SrcMAC:              r.String("orig_l2_addr"),
+		DstMAC:              r.String("resp_l2_addr"),
+		SrcIP:               srcIP,
+		SrcPort:             r.String("id.orig_p"),
+		DstIP:               r.String("id.resp_h"),
+		DstPort:             r.String("id.resp_p"),
+		TotalSize:           int32(r.Int("orig_ip_bytes") + r.Int("resp_ip_bytes")),
+		AppPayloadSize:      int32(r.Int("orig_bytes") + r.Int("resp_bytes")),
+		NumPackets:          int32(r.Int("orig_pkts") + r.Int("resp_pkts")),
+		BytesClientToServer: r.Int("orig_ip_bytes"),
+		BytesServerToClient: r.Int("resp_ip_bytes"),
+	}
+
+	if c.SrcMAC != "" || c.DstMAC != "" {
+		c.LinkProto = layers.LayerTypeEthernet.String()
+	}
+
+	if r.Has("vlan") {
+		e := &utils.Encapsulation{VLANs: []uint16{uint16(r.Int("vlan"))}}
+		if r.Has("inner_vlan") {
+			e.VLANs = append(e.VLANs, uint16(r.Int("inner_vlan")))
+		}
+
+		c.Encapsulation = e.String()
+	}
+
+	countHistory(c, r.String("history"))
+
+	return []proto.Message{c}
+}
+
+// countHistory sets the TCP flag counters from the connection history.
+// Zeek logs repeated events only for exponentially growing counts,
+// so the counters are a lower bound of the actual number of flags.
+func countHistory(c *types.Connection, history string) {
+	for _, h := range history {
+		switch h {
+		case 's', 'S':
+			c.NumSYNFlags++
+		case 'h', 'H':
+			c.NumSYNFlags++
+			c.NumACKFlags++
+		case 'a', 'A':
+			c.NumACKFlags++
+		case 'f', 'F':
+			c.NumFINFlags++
+		case 'r', 'R':
+			c.NumRSTFlags++
+		}
+	}
+}
+
+func toDNS(r Record) []proto.Message {
+	var (
+		query = r.String("query")
+		qtype = layers.DNSType(r.Int("qtype"))
+		ttls  = r.Strings("TTLs")
+	)
+
+	msg := &types.DNS{
+		Timestamp:    r.Time("ts").UnixNano(),
+		ID:           int32(r.Int("trans_id")),
+		QR:           r.Has("rcode"),
+		AA:           r.Bool("AA"),
+		TC:           r.Bool("TC"),
+		RD:           r.Bool("RD"),
+		RA:           r.Bool("RA"),
+		Z:            int32(r.Int("Z")),
+		ResponseCode: int32(r.Int("rcode")),
+		SrcIP:        r.String("id.orig_h"),
+		DstIP:        r.String("id.resp_h"),
+		SrcPort:      int32(r.Int("id.orig_p")),
+		DstPort:      int32(r.Int("id.resp_p")),
+		Transport:    transportProto(r.String("proto"), r.String("id.orig_h")),
+	}
+
+	if query != "" {
+		msg.Questions = []*types.DNSQuestion{{
+			Name:  query,
+			Type:  int32(qtype),
+			Class: int32(r.Int("qclass")),
+		}}
+		msg.QDCount = 1
+	}
+
+	for i, a := range r.Strings("answers") {
+		rr := &types.DNSResourceRecord{
+			Name:  query,
+			Class: int32(r.Int("qclass")),
+		}
+
+		if i < len(ttls) {
+			rr.TTL = uint32(seconds(ttls[i]) / int64(time.Second))
+		}
+
+		setAnswer(rr, a, qtype)
+
+		msg.Answers = append(msg.Answers, rr)
+	}
+
+	msg.ANCount = int32(len(msg.Answers))
+
+	return []proto.Message{msg}
+}
+
+// setAnswer sets the type and data of a resource record.
+// Zeek only logs the answer data, so the type is derived from the data and the type of the question.
+func setAnswer(rr *types.DNSResourceRecord, answer string, qtype layers.DNSType) {
+	if ip := net.ParseIP(answer); ip != nil {
+		rr.Type = int32(layers.DNSTypeAAAA)
+		if ip.To4() != nil {
+			rr.Type = int32(layers.DNSTypeA)
+		}
+
+		rr.IP = answer
+
+		return
+	}
+
+	switch qtype {
+	case layers.DNSTypePTR:
+		rr.PTR = []byte(answer)
+	case layers.DNSTypeNS:
+		rr.NS = []byte(answer)
+	case layers.DNSTypeMX:
+		rr.MX = &types.DNSMX{Name: answer}
+	case layers.DNSTypeTXT:
+		rr.TXTs = [][]byte{[]byte(answer)}
+	default:
+		rr.Type = int32(layers.DNSTypeCNAME)
+		rr.CNAME = []byte(answer)
+
+		return
+	}
+
+	rr.Type = int32(qtype)
+}
+
+func toHTTP(r Record) []proto.Message {
+	h := &types.HTTP{
+		Timestamp:              r.Time("ts").UnixNano(),
+		Method:                 r.String("method"),
+		Host:                   r.String("host"),
+		UserAgent:              r.String("user_agent"),
+		Referer:                r.String("referrer"),
+		ReqContentLength:       int32(r.Int("request_body_len")),
+		URL:                    r.String("uri"),
+		ResContentLength:       int32(r.Int("response_body_len")),
+		StatusCode:             int32(r.Int("status_code")),
+		SrcIP:                  r.String("id.orig_h"),
+		DstIP:                  r.String("id.resp_h"),
+		SrcPort:                int32(r.Int("id.orig_p")),
+		DstPort:                int32(r.Int("id.resp_p")),
+		ContentTypeDetected:    r.String("orig_mime_types"),
+		ResContentTypeDetected: r.String("resp_mime_types"),
+	}
+
+	if v := r.String("version"); v != "" {
+		h.Proto = "HTTP/" + v
+	}
+
+	return []proto.Message{h}
+}
+
+func toTLSClientHello(r Record) []proto.Message {
+	hello := &types.TLSClientHello{
+		Timestamp: r.Time("ts").UnixNano(),
+		Version:   tlsVersions[r.String("version")],
+		SNI:       r.String("server_name"),
+		Ja3:       r.String("ja3"),
+		SrcIP:     r.String("id.orig_h"),
+		DstIP:     r.String("id.resp_h"),
+		SrcPort:   int32(r.Int("id.orig_p")),
+		DstPort:   int32(r.Int("id.resp_p")),
+	}
+
+	if p := r.String("next_protocol"); p != "" {
+		hello.ALPNs = []string{p}
+	}
+
+	return []proto.Message{hello}
+}
+
+func toCertificateFile(r Record) []proto.Message {
+	contentType := "application/x-x509-user-cert"
+	if r.Bool("basic_constraints.ca") {
+		contentType = "application/x-x509-ca-cert"
+	}
+
+	name := commonName(r.String("certificate.subject"))
+	if name == "" {
+		name = r.String("id")
+	}
+
+	return []proto.Message{&types.File{
+		Timestamp:           r.Time("ts").UnixNano(),
+		Name:                name,
+		Hash:                r.String("fingerprint"),
+		Ident:               r.String("id"),
+		Source:              "x509",
+		ContentType:         contentType,
+		ContentTypeDetected: contentType,
+		Host:                r.String("san.dns"),
+	}}
+}
+
+// commonName returns the CN attribute of a distinguished name.
+func commonName(dn string) string {
+	for _, attr := range strings.Split(dn, ",") {
+		if strings.HasPrefix(attr, "CN=") {
+			return strings.TrimPrefix(attr, "CN=")
+		}
+	}
+
+	return ""
+}
+
+func toFile(r Record) []proto.Message {
+	f := &types.File{
+		Timestamp:           r.Time("ts").UnixNano(),
+		Name:                r.String("filename"),
+		Length:              r.Int("total_bytes"),
+		Hash:                r.String("md5"),
+		Location:            r.String("extracted"),
+		Ident:               r.String("conn_uids"),
+		Source:              r.String("source"),
+		ContentType:         r.String("mime_type"),
+		ContentTypeDetected: r.String("mime_type"),
+		SrcIP:               r.String("tx_hosts"),
+		DstIP:               r.String("rx_hosts"),
+	}
+
+	if f.Name == "" {
+		f.Name = r.String("fuid")
+	}
+
+	if f.Length == 0 {
+		f.Length = r.Int("seen_bytes")
+	}
+
+	if f.Hash == "" {
+		f.Hash = r.String("sha256")
+	}
+
+	// since Zeek 5 the connection is logged instead of the transmitting and receiving hosts
+	if r.Has("id.orig_h") {
+		f.Ident = ident(r)
+		f.SrcIP, f.DstIP = r.String("id.orig_h"), r.String("id.resp_h")
+		f.SrcPort, f.DstPort = int32(r.Int("id.orig_p")), int32(r.Int("id.resp_p"))
+
+		// the file was sent by the responder
+		if r.Has("is_orig") && !r.Bool("is_orig") {
+			f.Ident = utils.ReverseFlowIdent(f.Ident)
+			f.SrcIP, f.DstIP = f.DstIP, f.SrcIP
+			f.SrcPort, f.DstPort = f.DstPort, f.SrcPort
+		}
+	}
+
+	return []proto.Message{f}
+}
+
+func toSMTP(r Record) []proto.Message {
+	var commands []string
+
+	if r.Has("helo") {
+		commands = append(commands, "HELO")
+	}
+
+	if r.Has("mailfrom") {
+		commands = append(commands, "MAIL FROM")
+	}
+
+	for range r.Strings("rcptto") {
+		commands = append(commands, "RCPT TO")
+	}
+
+	if r.Has("date") || r.Has("from") || r.Has("subject") || r.Has("msg_id") {
+		commands = append(commands, "DATA")
+	}
+
+	msg := &types.SMTP{
+		Timestamp:   r.Time("ts").UnixNano(),
+		IsEncrypted: r.Bool("tls"),
+		SrcIP:       r.String("id.orig_h"),
+		DstIP:       r.String("id.resp_h"),
+		SrcPort:     int32(r.Int("id.orig_p")),
+		DstPort:     int32(r.Int("id.resp_p")),
+		Commands:    commands,
+	}
+
+	if id := r.String("msg_id"); id != "" {
+		msg.MailIDs = []string{id}
+	}
+
+	return []proto.Message{msg}
+}
+
+func toSSH(r Record) []proto.Message {
+	var (
+		ts         = r.Time("ts").UnixNano()
+		flow       = ident(r)
+		algorithms = strings.Join([]string{
+			r.String("kex_alg"),
+			r.String("cipher_alg"),
+			r.String("mac_alg"),
+			r.String("compression_alg"),
+		}, ";")
+		client = &types.SSH{
+			Timestamp:  ts,
+			HASSH:      r.String("hassh"),
+			Flow:       flow,
+			Ident:      r.String("client"),
+			Algorithms: algorithms,
+			IsClient:   true,
+		}
+		server = &types.SSH{
+			Timestamp:  ts,
+			HASSH:      r.String("hasshServer"),
+			Flow:       utils.ReverseFlowIdent(flow),
+			Ident:      r.String("server"),
+			Algorithms: algorithms,
+		}
+	)
+
+	// the hassh package logs the offered algorithms, which are used for the fingerprint
+	if r.Has("hasshAlgorithms") {
+		client.Algorithms = r.String("hasshAlgorithms")
+	}
+
+	if r.Has("hasshServerAlgorithms") {
+		server.Algorithms = r.String("hasshServerAlgorithms")
+	}
+
+	return []proto.Message{client, server}
+}
+
+func toAlert(r Record) []proto.Message {
+	a := &types.Alert{
+		Timestamp:   r.Time("ts").UnixNano(),
+		Name:        r.String("note"),
+		Description: r.String("msg"),
+		SrcIP:       r.String("id.orig_h"),
+		SrcPort:     r.String("id.orig_p"),
+		DstIP:       r.String("id.resp_h"),
+		DstPort:     r.String("id.resp_p"),
+		Protocol:    r.String("proto"),
+		Notes:       r.String("sub"),
+	}
+
+	// notices that are not related to a connection only carry the involved hosts
+	if a.SrcIP == "" {
+		a.SrcIP = r.String("src")
+	}
+
+	if a.DstIP == "" {
+		a.DstIP = r.String("dst")
+	}
+
+	if a.DstPort == "" {
+		a.DstPort = r.String("p")
+	}
+
+	return []proto.Message{a}
+}
+
+// ident returns the flow identifier for the connection of a record.
+func ident(r Record) string {
+	return utils.CreateFlowIdent(
+		r.String("id.orig_h"),
+		r.String("id.orig_p"),
+		r.String("id.resp_h"),
+		r.String("id.resp_p"),
+		"",
+	)
+}
+
+func networkProto(ip string) string {
+	if strings.Contains(ip, ":") {
+		return layers.LayerTypeIPv6.String()
+	}
+
+	return layers.LayerTypeIPv4.String()
+}
+
+// transportProto returns the name of the transport protocol in the notation of the netcap audit records.
+func transportProto(proto, ip string) string {
+	switch proto {
+	case "tcp":
+		return layers.IPProtocolTCP.String()
+	case "udp":
+		return layers.IPProtocolUDP.String()
+	case "icmp":
+		if strings.Contains(ip, ":") {
+			return layers.IPProtocolICMPv6.String()
+		}
+
+		return layers.IPProtocolICMPv4.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,237 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package zeek
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestamp format of the #open and #close header lines.
+const headerTimeFormat = "2006-01-02-15-04-05"
+
+// Encoder writes records of a log in the TSV or the JSON format.
+type Encoder struct {
+	w    io.Writer
+	log  *Log
+	json bool
+	buf  []byte
+}
+
+// NewEncoder returns an encoder that writes records of the log to w,
+// in the JSON format of Zeek if json is true and in the TSV format otherwise.
+// The encoder does not buffer, wrap w in a bufio.Writer for that.
+func NewEncoder(w io.Writer, l *Log, json bool) *Encoder {
+	return &Encoder{
+		w:    w,
+		log:  l,
+		json: json,
+	}
+}
+
+// WriteHeader writes the meta data lines of the TSV format, it does nothing for JSON.
+func (e *Encoder) WriteHeader(open time.Time) error {
+	if e.json {
+		return nil
+	}
+
+	var (
+		names = make([]string, len(e.log.Fields))
+		typs  = make([]string, len(e.log.Fields))
+	)
+
+	for i, f := range e.log.Fields {
+		names[i] = f.Name
+		typs[i] = f.Type
+	}
+
+	header := []string{
+		"#separator \\x09",
+		"#set_separator" + defaultSeparator + defaultSetSeparator,
+		"#empty_field" + defaultSeparator + defaultEmptyField,
+		"#unset_field" + defaultSeparator + defaultUnsetField,
+		"#path" + defaultSeparator + e.log.Path,
+		"#open" + defaultSeparator + open.Format(headerTimeFormat),
+		"#fields" + defaultSeparator + strings.Join(names, defaultSeparator),
+		"#types" + defaultSeparator + strings.Join(typs, defaultSeparator),
+	}
+
+	_, err := io.WriteString(e.w, strings.Join(header, "\n")+"\n")
+
+	return err
+}
+
+// WriteFooter writes the #close line of the TSV format, it does nothing for JSON.
+func (e *Encoder) WriteFooter(closed time.Time) error {
+	if e.json {
+		return nil
+	}
+
+	_, err := io.WriteString(e.w, "#close"+defaultSeparator+closed.Format(headerTimeFormat)+"\n")
+
+	return err
+}
+
+// Encode writes a record, fields that are not part of the log are ignored.
+func (e *Encoder) Encode(r Record) error {
+	if e.json {
+		e.buf = e.appendJSON(e.buf[:0], r)
+	} else {
+		e.buf = e.appendTSV(e.buf[:0], r)
+	}
+
+	e.buf = append(e.buf, '\n')
+
+	_, err := e.w.Write(e.buf)
+
+	return err
+}
+
+func (e *Encoder) appendTSV(b []byte, r Record) []byte {
+	for i, f := range e.log.Fields {
+		if i > 0 {
+			b = append(b, defaultSeparator...)
+		}
+
+		values, ok := r[f.Name]
+
+		switch {
+		case !ok:
+			b = append(b, defaultUnsetField...)
+		case f.Container():
+			if len(values) == 0 {
+				b = append(b, defaultEmptyField...)
+
+				continue
+			}
+
+			for j, v := range values {
+				if j > 0 {
+					b = append(b, defaultSetSeparator...)
+				}
+
+				b = appendEscaped(b, v, true)
+			}
+		case len(values) == 0 || values[0] == "":
+			b = append(b, defaultEmptyField...)
+		default:
+			b = appendEscaped(b, values[0], false)
+		}
+	}
+
+	return b
+}
+
+// appendEscaped adds a TSV value, escaping non printable characters,
+// separators and values that would be mistaken for an empty or unset field.
+func appendEscaped(b []byte, v string, element bool) []byte {
+	if v == defaultUnsetField || v == defaultEmptyField {
+		return appendHex(b, v[0], v[1:])
+	}
+
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+
+		if c < 0x20 || c >= 0x7f || c == '\\' || (element && c == defaultSetSeparator[0]) {
+			b = appendHex(b, c, "")
+		} else {
+			b = append(b, c)
+		}
+	}
+
+	return b
+}
+
+func appendHex(b []byte, c byte, rest string) []byte {
+	const digits = "0123456789abcdef"
+
+	b = append(b, '\\', 'x', digits[c>>4], digits[c&0xf])
+
+	return append(b, rest...)
+}
+
+func (e *Encoder) appendJSON(b []byte, r Record) []byte {
+	b = append(b, '{')
+	first := true
+
+	for _, f := range e.log.Fields {
+		values, ok := r[f.Name]
+		if !ok {
+			continue
+		}
+
+		if !first {
+			b = append(b, ',')
+		}
+
+		first = false
+
+		b = appendJSONString(b, f.Name)
+		b = append(b, ':')
+
+		if !f.Container() {
+			var v string
+			if len(values) > 0 {
+				v = values[0]
+			}
+
+			b = appendJSONValue(b, f.Type, v)
+
+			continue
+		}
+
+		b = append(b, '[')
+
+		for j, v := range values {
+			if j > 0 {
+				b = append(b, ',')
+			}
+
+			b = appendJSONValue(b, f.elementType(), v)
+		}
+
+		b = append(b, ']')
+	}
+
+	return append(b, '}')
+}
+
+// appendJSONValue adds a value in the JSON notation of its Zeek type.
+func appendJSONValue(b []byte, typ, v string) []byte {
+	switch typ {
+	case "time", "interval", "count", "int", "port", "double":
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return append(b, v...)
+		}
+	case "bool":
+		switch v {
+		case "T":
+			return append(b, "true"...)
+		case "F":
+			return append(b, "false"...)
+		}
+	}
+
+	return appendJSONString(b, v)
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	// marshaling a string never fails
+	data, _ := json.Marshal(s)
+
+	return append(b, data...)
+}
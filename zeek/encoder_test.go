@@ -0,0 +1,130 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package zeek
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	l, ok := LogFor(types.Type_NC_Connection)
+	if !ok {
+		t.Fatal("no log for connections")
+	}
+
+	ts := time.Unix(1577836800, 123456000)
+	conn := &types.Connection{
+		TimestampFirst:      ts.UnixNano(),
+		Duration:            int64(1500 * time.Millisecond),
+		UID:                 "-",
+		TransportProto:      "TCP",
+		ApplicationProto:    "TLS",
+		SrcIP:               "192.168.1.10",
+		SrcPort:             "51234",
+		DstIP:               "93.184.216.34",
+		DstPort:             "443",
+		BytesClientToServer: 1049,
+		BytesServerToClient: 4632,
+	}
+
+	for _, json := range []bool{false, true} {
+		var (
+			buf bytes.Buffer
+			e   = NewEncoder(&buf, l, json)
+		)
+
+		if err := e.WriteHeader(ts); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := e.Encode(l.Record(conn)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := e.WriteFooter(ts); err != nil {
+			t.Fatal(err)
+		}
+
+		if json && strings.Contains(buf.String(), "#") {
+			t.Fatal("unexpected header in JSON log", buf.String())
+		}
+
+		rec, err := NewReader(&buf).Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, err := Convert("conn", rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := msgs[0].(*types.Connection)
+		if c.UID != "-" || c.TransportProto != "TCP" || c.ApplicationProto != "ssl" || c.DstPort != "443" {
+			t.Fatal("unexpected connection", json, c)
+		}
+
+		if c.TimestampFirst != conn.TimestampFirst || c.Duration != conn.Duration || c.TotalSize != 5681 {
+			t.Fatal("unexpected values", json, c)
+		}
+
+		if rec.Has("orig_bytes") || !rec.Has("tunnel_parents") {
+			t.Fatal("unexpected unset or empty fields", json, rec)
+		}
+	}
+}
+
+func TestEncodeTSVEscaping(t *testing.T) {
+	l := newLog("test", nil, "name:string names:set[string] empty:string")
+
+	var buf bytes.Buffer
+
+	err := NewEncoder(&buf, l, false).Encode(Record{
+		"name":  {"a\tb\\c"},
+		"names": {"x,y", "(empty)"},
+		"empty": {""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != "a\\x09b\\x5cc\tx\\x2cy,\\x28empty)\t(empty)\n" {
+		t.Fatalf("unexpected encoding %q", got)
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	l := newLog("test", nil, "ts:time n:count ok:bool tags:set[enum] host:addr")
+
+	var buf bytes.Buffer
+
+	err := NewEncoder(&buf, l, true).Encode(Record{
+		"ts":   {"1577836800.123456"},
+		"n":    {"not a number"},
+		"ok":   {"T"},
+		"tags": {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != `{"ts":1577836800.123456,"n":"not a number","ok":true,"tags":[]}`+"\n" {
+		t.Fatal("unexpected encoding", got)
+	}
+}
@@ -0,0 +1,514 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package zeek
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// Field describes a column of a log.
+type Field struct {
+	Name string
+	Type string
+}
+
+// Container returns true for set and vector fields.
+func (f Field) Container() bool {
+	return strings.HasPrefix(f.Type, "set[") || strings.HasPrefix(f.Type, "vector[") || strings.HasPrefix(f.Type, "table[")
+}
+
+// elementType returns the type of the elements for containers, or the field type.
+func (f Field) elementType() string {
+	if i := strings.IndexByte(f.Type, '['); i > 0 {
+		return strings.TrimSuffix(f.Type[i+1:], "]")
+	}
+
+	return f.Type
+}
+
+// Log describes a Zeek log that audit records of a type can be written to.
+type Log struct {
+	// Path is the name of the log, for example conn.
+	Path string
+
+	// Fields of the log, in the order of the Zeek default scripts.
+	Fields []Field
+
+	record func(msg proto.Message) Record
+}
+
+// Record converts an audit record to a record of the log.
+// It returns nil if the audit record has the wrong type or can not be represented in the log.
+func (l *Log) Record(msg proto.Message) Record {
+	return l.record(msg)
+}
+
+// connection identifier fields shared by most logs.
+const connID = "id.orig_h:addr id.orig_p:port id.resp_h:addr id.resp_p:port"
+
+// logs maps the audit record types to the Zeek logs they are written to.
+var logs = map[types.Type]*Log{
+	types.Type_NC_Connection: newLog("conn", fromConnection,
+		"ts:time uid:string "+connID+" proto:enum service:string duration:interval orig_bytes:count resp_bytes:count conn_state:string local_orig:bool local_resp:bool missed_bytes:count history:string orig_pkts:count orig_ip_bytes:count resp_pkts:count resp_ip_bytes:count tunnel_parents:set[string]"),
+	types.Type_NC_DNS: newLog("dns", fromDNS,
+		"ts:time uid:string "+connID+" proto:enum trans_id:count rtt:interval query:string qclass:count qclass_name:string qtype:count qtype_name:string rcode:count rcode_name:string AA:bool TC:bool RD:bool RA:bool Z:count answers:vector[string] TTLs:vector[interval] rejected:bool"),
+	types.Type_NC_HTTP: newLog("http", fromHTTP,
+		"ts:time uid:string "+connID+" trans_depth:count method:string host:string uri:string referrer:string version:string user_agent:string origin:string request_body_len:count response_body_len:count status_code:count status_msg:string info_code:count info_msg:string tags:set[enum] username:string password:string proxied:set[string] orig_fuids:vector[string] orig_filenames:vector[string] orig_mime_types:vector[string] resp_fuids:vector[string] resp_filenames:vector[string] resp_mime_types:vector[string]"),
+	types.Type_NC_TLSClientHello: newLog("ssl", fromTLSClientHello,
+		"ts:time uid:string "+connID+" version:string cipher:string curve:string server_name:string resumed:bool last_alert:string next_protocol:string established:bool cert_chain_fuids:vector[string] client_cert_chain_fuids:vector[string] subject:string issuer:string client_subject:string client_issuer:string validation_status:string ja3:string"),
+	types.Type_NC_File: newLog("files", fromFile,
+		"ts:time fuid:string tx_hosts:set[addr] rx_hosts:set[addr] conn_uids:set[string] source:string depth:count analyzers:set[string] mime_type:string filename:string duration:interval local_orig:bool is_orig:bool seen_bytes:count total_bytes:count missing_bytes:count overflow_bytes:count timedout:bool parent_fuid:string md5:string sha1:string sha256:string extracted:string extracted_cutoff:bool extracted_size:count"),
+	types.Type_NC_SMTP: newLog("smtp", fromSMTP,
+		"ts:time uid:string "+connID+" trans_depth:count helo:string mailfrom:string rcptto:set[string] date:string from:string to:set[string] cc:set[string] reply_to:string msg_id:string in_reply_to:string subject:string x_originating_ip:addr first_received:string second_received:string last_reply:string path:vector[addr] user_agent:string tls:bool fuids:vector[string] is_webmail:bool"),
+	types.Type_NC_SSH: newLog("ssh", fromSSH,
+		"ts:time uid:string "+connID+" version:count auth_success:bool auth_attempts:count direction:enum client:string server:string cipher_alg:string mac_alg:string compression_alg:string kex_alg:string host_key_alg:string host_key:string hassh:string hasshServer:string hasshAlgorithms:string hasshServerAlgorithms:string"),
+	types.Type_NC_Alert: newLog("notice", fromAlert,
+		"ts:time uid:string "+connID+" fuid:string file_mime_type:string file_desc:string proto:enum note:enum msg:string sub:string src:addr dst:addr p:port n:count peer_descr:string actions:set[enum] suppress_for:interval"),
+}
+
+func newLog(path string, record func(msg proto.Message) Record, fields string) *Log {
+	l := &Log{
+		Path:   path,
+		record: record,
+	}
+
+	for _, f := range strings.Fields(fields) {
+		i := strings.IndexByte(f, ':')
+		l.Fields = append(l.Fields, Field{Name: f[:i], Type: f[i+1:]})
+	}
+
+	return l
+}
+
+// LogFor returns the Zeek log for an audit record type.
+func LogFor(t types.Type) (*Log, bool) {
+	l, ok := logs[t]
+
+	return l, ok
+}
+
+// set adds a value to the record, empty values are left unset.
+func (r Record) set(name, value string) {
+	if value != "" {
+		r[name] = []string{value}
+	}
+}
+
+// setConnID sets the connection identifier fields.
+func (r Record) setConnID(srcIP, srcPort, dstIP, dstPort string) {
+	r.set("id.orig_h", srcIP)
+	r.set("id.orig_p", srcPort)
+	r.set("id.resp_h", dstIP)
+	r.set("id.resp_p", dstPort)
+}
+
+// timeValue formats a unix timestamp in nanoseconds as epoch seconds with microsecond precision.
+func timeValue(ns int64) string {
+	return intervalValue(ns)
+}
+
+// intervalValue formats a duration in nanoseconds as seconds with microsecond precision.
+func intervalValue(ns int64) string {
+	sign := ""
+	if ns < 0 {
+		sign = "-"
+		ns = -ns
+	}
+
+	micros := strconv.FormatInt(ns%int64(time.Second)/int64(time.Microsecond), 10)
+
+	return sign + strconv.FormatInt(ns/int64(time.Second), 10) + "." + strings.Repeat("0", 6-len(micros)) + micros
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "T"
+	}
+
+	return "F"
+}
+
+func countValue(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// portValue formats a port number, zero is treated as unknown.
+func portValue(port int32) string {
+	if port == 0 {
+		return ""
+	}
+
+	return countValue(int64(port))
+}
+
+// protoValue returns the transport protocol in the notation of Zeek.
+func protoValue(transport string) string {
+	switch transport {
+	case layers.IPProtocolTCP.String(), "tcp":
+		return "tcp"
+	case layers.IPProtocolUDP.String(), "udp":
+		return "udp"
+	case layers.IPProtocolICMPv4.String(), layers.IPProtocolICMPv6.String(), "ICMP", "icmp":
+		return "icmp"
+	case "":
+		return ""
+	}
+
+	return "unknown_transport"
+}
+
+// serviceValue returns the Zeek service name for an application protocol.
+func serviceValue(app string) string {
+	switch app {
+	case "", gopacketPayload:
+		return ""
+	case "TLS":
+		return "ssl"
+	}
+
+	return strings.ToLower(app)
+}
+
+// mimeType strips the parameters from a content type, as Zeek only logs the mime type.
+func mimeType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		return strings.TrimSpace(contentType[:i])
+	}
+
+	return contentType
+}
+
+// name of the gopacket layer for undecoded application data.
+const gopacketPayload = "Payload"
+
+func fromConnection(msg proto.Message) Record {
+	c, ok := msg.(*types.Connection)
+	if !ok || c.SrcIP == "" {
+		// Zeek only tracks IP connections
+		return nil
+	}
+
+	r := Record{
+		"ts":             {timeValue(c.TimestampFirst)},
+		"duration":       {intervalValue(c.Duration)},
+		"orig_ip_bytes":  {countValue(c.BytesClientToServer)},
+		"resp_ip_bytes":  {countValue(c.BytesServerToClient)},
+		"tunnel_parents": {},
+	}
+
+	r.set("uid", c.UID)
+	r.setConnID(c.SrcIP, c.SrcPort, c.DstIP, c.DstPort)
+	r.set("proto", protoValue(c.TransportProto))
+
+	if c.TransportProto == "" {
+		r.set("proto", "unknown_transport")
+	}
+	r.set("service", serviceValue(c.ApplicationProto))
+
+	return r
+}
+
+// names of the DNS classes and response codes, as used by Zeek.
+var (
+	dnsClasses = map[int32]string{
+		1:   "C_INTERNET",
+		3:   "C_CHAOS",
+		4:   "C_HESIOD",
+		254: "C_NONE",
+		255: "C_ANY",
+	}
+	dnsResponseCodes = map[int32]string{
+		0:  "NOERROR",
+		1:  "FORMERR",
+		2:  "SERVFAIL",
+		3:  "NXDOMAIN",
+		4:  "NOTIMP",
+		5:  "REFUSED",
+		6:  "YXDOMAIN",
+		7:  "YXRRSET",
+		8:  "NXRRSET",
+		9:  "NOTAUTH",
+		10: "NOTZONE",
+	}
+)
+
+func fromDNS(msg proto.Message) Record {
+	d, ok := msg.(*types.DNS)
+	if !ok {
+		return nil
+	}
+
+	r := Record{
+		"ts":       {timeValue(d.Timestamp)},
+		"trans_id": {countValue(int64(d.ID))},
+		"AA":       {boolValue(d.AA)},
+		"TC":       {boolValue(d.TC)},
+		"RD":       {boolValue(d.RD)},
+		"RA":       {boolValue(d.RA)},
+		"Z":        {countValue(int64(d.Z))},
+	}
+
+	r.setConnID(d.SrcIP, portValue(d.SrcPort), d.DstIP, portValue(d.DstPort))
+	r.set("proto", protoValue(d.Transport))
+
+	if len(d.Questions) > 0 {
+		q := d.Questions[0]
+
+		r.set("query", q.Name)
+		r.set("qclass", countValue(int64(q.Class)))
+		r.set("qtype", countValue(int64(q.Type)))
+
+		if name, ok := dnsClasses[q.Class]; ok {
+			r.set("qclass_name", name)
+		} else {
+			r.set("qclass_name", "qclass-"+countValue(int64(q.Class)))
+		}
+
+		if name := layers.DNSType(q.Type).String(); name != "Unknown" {
+			r.set("qtype_name", name)
+		} else {
+			r.set("qtype_name", "query-"+countValue(int64(q.Type)))
+		}
+	}
+
+	// answers are only available in responses
+	if !d.QR {
+		return r
+	}
+
+	r.set("rcode", countValue(int64(d.ResponseCode)))
+
+	if name, ok := dnsResponseCodes[d.ResponseCode]; ok {
+		r.set("rcode_name", name)
+	} else {
+		r.set("rcode_name", "rcode-"+countValue(int64(d.ResponseCode)))
+	}
+
+	var answers, ttls []string
+
+	for _, a := range d.Answers {
+		if v := answerValue(a); v != "" {
+			answers = append(answers, v)
+			ttls = append(ttls, intervalValue(int64(a.TTL)*int64(time.Second)))
+		}
+	}
+
+	if len(answers) > 0 {
+		r["answers"] = answers
+		r["TTLs"] = ttls
+	}
+
+	return r
+}
+
+// answerValue returns the data of a resource record in the notation of the answers field.
+func answerValue(rr *types.DNSResourceRecord) string {
+	switch {
+	case rr.IP != "":
+		return rr.IP
+	case len(rr.CNAME) > 0:
+		return string(rr.CNAME)
+	case len(rr.PTR) > 0:
+		return string(rr.PTR)
+	case len(rr.NS) > 0:
+		return string(rr.NS)
+	case rr.MX != nil:
+		return rr.MX.Name
+	case len(rr.TXTs) > 0:
+		txts := make([]string, len(rr.TXTs))
+		for i, t := range rr.TXTs {
+			txts[i] = string(t)
+		}
+
+		return strings.Join(txts, " ")
+	}
+
+	return ""
+}
+
+func fromHTTP(msg proto.Message) Record {
+	h, ok := msg.(*types.HTTP)
+	if !ok {
+		return nil
+	}
+
+	r := Record{
+		"ts":                {timeValue(h.Timestamp)},
+		"request_body_len":  {countValue(int64(h.ReqContentLength))},
+		"response_body_len": {countValue(int64(h.ResContentLength))},
+		"tags":              {},
+	}
+
+	r.setConnID(h.SrcIP, portValue(h.SrcPort), h.DstIP, portValue(h.DstPort))
+	r.set("method", h.Method)
+	r.set("host", h.Host)
+	r.set("uri", h.URL)
+	r.set("referrer", h.Referer)
+	r.set("version", strings.TrimPrefix(h.Proto, "HTTP/"))
+	r.set("user_agent", h.UserAgent)
+	r.set("orig_mime_types", mimeType(h.ContentTypeDetected))
+	r.set("resp_mime_types", mimeType(h.ResContentTypeDetected))
+
+	if h.StatusCode != 0 {
+		r.set("status_code", countValue(int64(h.StatusCode)))
+		r.set("status_msg", strings.ToUpper(http.StatusText(int(h.StatusCode))))
+	}
+
+	return r
+}
+
+func fromTLSClientHello(msg proto.Message) Record {
+	h, ok := msg.(*types.TLSClientHello)
+	if !ok {
+		return nil
+	}
+
+	r := Record{
+		"ts": {timeValue(h.Timestamp)},
+	}
+
+	r.setConnID(h.SrcIP, portValue(h.SrcPort), h.DstIP, portValue(h.DstPort))
+	r.set("server_name", h.SNI)
+	r.set("ja3", h.Ja3)
+
+	for name, v := range tlsVersions {
+		if v == h.Version {
+			r.set("version", name)
+		}
+	}
+
+	if len(h.ALPNs) > 0 {
+		r.set("next_protocol", h.ALPNs[0])
+	}
+
+	return r
+}
+
+func fromFile(msg proto.Message) Record {
+	f, ok := msg.(*types.File)
+	if !ok {
+		return nil
+	}
+
+	r := Record{
+		"ts":         {timeValue(f.Timestamp)},
+		"conn_uids":  {},
+		"analyzers":  {},
+		"seen_bytes": {countValue(f.Length)},
+	}
+
+	r.set("tx_hosts", f.SrcIP)
+	r.set("rx_hosts", f.DstIP)
+	r.set("source", f.Source)
+	r.set("filename", f.Name)
+	r.set("total_bytes", countValue(f.Length))
+	r.set("md5", f.Hash)
+	r.set("extracted", f.Location)
+
+	r.set("mime_type", mimeType(f.ContentTypeDetected))
+	if f.ContentTypeDetected == "" {
+		r.set("mime_type", mimeType(f.ContentType))
+	}
+
+	return r
+}
+
+func fromSMTP(msg proto.Message) Record {
+	s, ok := msg.(*types.SMTP)
+	if !ok {
+		return nil
+	}
+
+	r := Record{
+		"ts":    {timeValue(s.Timestamp)},
+		"tls":   {boolValue(s.IsEncrypted)},
+		"path":  {},
+		"fuids": {},
+	}
+
+	r.setConnID(s.SrcIP, portValue(s.SrcPort), s.DstIP, portValue(s.DstPort))
+
+	return r
+}
+
+func fromSSH(msg proto.Message) Record {
+	s, ok := msg.(*types.SSH)
+	if !ok {
+		return nil
+	}
+
+	// the connection id always refers to the client as originator
+	flow := s.Flow
+	if !s.IsClient {
+		flow = utils.ReverseFlowIdent(flow)
+	}
+
+	srcIP, srcPort, dstIP, dstPort := utils.ParseFlowIdent(flow)
+
+	r := Record{
+		"ts": {timeValue(s.Timestamp)},
+	}
+
+	r.setConnID(srcIP, srcPort, dstIP, dstPort)
+
+	if strings.HasPrefix(s.Ident, "SSH-2") {
+		r.set("version", "2")
+	} else if strings.HasPrefix(s.Ident, "SSH-1") {
+		r.set("version", "1")
+	}
+
+	if s.IsClient {
+		r.set("client", s.Ident)
+		r.set("hassh", s.HASSH)
+		r.set("hasshAlgorithms", s.Algorithms)
+	} else {
+		r.set("server", s.Ident)
+		r.set("hasshServer", s.HASSH)
+		r.set("hasshServerAlgorithms", s.Algorithms)
+	}
+
+	return r
+}
+
+func fromAlert(msg proto.Message) Record {
+	a, ok := msg.(*types.Alert)
+	if !ok {
+		return nil
+	}
+
+	r := Record{
+		"ts":      {timeValue(a.Timestamp)},
+		"actions": {"Notice::ACTION_LOG"},
+	}
+
+	r.setConnID(a.SrcIP, a.SrcPort, a.DstIP, a.DstPort)
+	r.set("proto", protoValue(a.Protocol))
+	r.set("note", a.Name)
+	r.set("msg", a.Description)
+	r.set("sub", a.Notes)
+	r.set("src", a.SrcIP)
+	r.set("dst", a.DstIP)
+	r.set("p", a.DstPort)
+
+	return r
+}
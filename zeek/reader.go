@@ -0,0 +1,270 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package zeek
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// defaults of the TSV format, used until the header overrides them.
+const (
+	defaultSeparator    = "\t"
+	defaultSetSeparator = ","
+	defaultEmptyField   = "(empty)"
+	defaultUnsetField   = "-"
+)
+
+// Reader reads records from a Zeek log in the TSV or the JSON format,
+// the format is detected from the first line of the input.
+type Reader struct {
+	r *bufio.Reader
+
+	// detected format
+	tsv  bool
+	json bool
+
+	path string
+
+	// TSV header
+	separator    string
+	setSeparator string
+	emptyField   string
+	unsetField   string
+	fields       []string
+	containers   []bool
+}
+
+// NewReader returns a reader for the log data.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:            bufio.NewReaderSize(r, 64*1024),
+		separator:    defaultSeparator,
+		setSeparator: defaultSetSeparator,
+		emptyField:   defaultEmptyField,
+		unsetField:   defaultUnsetField,
+	}
+}
+
+// Path returns the name of the log the last record belongs to,
+// as announced in the TSV header or the _path field of JSON records.
+// It is empty when the log data does not contain the information.
+func (r *Reader) Path() string {
+	return r.path
+}
+
+// Next returns the next record, or io.EOF at the end of the log.
+func (r *Reader) Next() (Record, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+
+		if !r.tsv && !r.json {
+			switch line[0] {
+			case '#':
+				r.tsv = true
+			case '{':
+				r.json = true
+			default:
+				return nil, ErrUnknownFormat
+			}
+		}
+
+		if r.json {
+			return r.parseJSON(line)
+		}
+
+		if line[0] == '#' {
+			r.parseHeader(string(line))
+
+			continue
+		}
+
+		return r.parseTSV(string(line)), nil
+	}
+}
+
+// parseHeader handles the meta data lines of the TSV format.
+func (r *Reader) parseHeader(line string) {
+	// the separator line is always separated by a space
+	if strings.HasPrefix(line, "#separator ") {
+		r.separator = unescape(strings.TrimPrefix(line, "#separator "))
+
+		return
+	}
+
+	parts := strings.Split(line, r.separator)
+
+	switch parts[0] {
+	case "#set_separator":
+		if len(parts) > 1 {
+			r.setSeparator = unescape(parts[1])
+		}
+	case "#empty_field":
+		if len(parts) > 1 {
+			r.emptyField = parts[1]
+		}
+	case "#unset_field":
+		if len(parts) > 1 {
+			r.unsetField = parts[1]
+		}
+	case "#path":
+		if len(parts) > 1 {
+			r.path = parts[1]
+		}
+	case "#fields":
+		r.fields = parts[1:]
+		r.containers = make([]bool, len(r.fields))
+	case "#types":
+		for i, t := range parts[1:] {
+			if i < len(r.containers) {
+				r.containers[i] = Field{Type: t}.Container()
+			}
+		}
+	}
+}
+
+func (r *Reader) parseTSV(line string) Record {
+	var (
+		values = strings.Split(line, r.separator)
+		rec    = make(Record, len(values))
+	)
+
+	for i, v := range values {
+		if i >= len(r.fields) || v == r.unsetField {
+			continue
+		}
+
+		switch {
+		case !r.containers[i]:
+			if v == r.emptyField {
+				v = ""
+			}
+
+			rec[r.fields[i]] = []string{unescape(v)}
+		case v == r.emptyField:
+			rec[r.fields[i]] = []string{}
+		default:
+			elems := strings.Split(v, r.setSeparator)
+			for j, e := range elems {
+				elems[j] = unescape(e)
+			}
+
+			rec[r.fields[i]] = elems
+		}
+	}
+
+	return rec
+}
+
+func (r *Reader) parseJSON(line []byte) (Record, error) {
+	var (
+		values = make(map[string]interface{})
+		d      = json.NewDecoder(bytes.NewReader(line))
+	)
+
+	d.UseNumber()
+
+	if err := d.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	rec := make(Record, len(values))
+	addJSON(rec, "", values)
+
+	if p := rec.String("_path"); p != "" {
+		r.path = p
+	}
+
+	return rec, nil
+}
+
+// addJSON adds the JSON values to the record, nested objects are flattened into the dotted field names used by the TSV format.
+func addJSON(rec Record, prefix string, values map[string]interface{}) {
+	for k, v := range values {
+		name := prefix + k
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			addJSON(rec, name+".", val)
+		case []interface{}:
+			elems := make([]string, 0, len(val))
+
+			for _, e := range val {
+				if s, ok := jsonString(e); ok {
+					elems = append(elems, s)
+				}
+			}
+
+			rec[name] = elems
+		default:
+			if s, ok := jsonString(v); ok {
+				rec[name] = []string{s}
+			}
+		}
+	}
+}
+
+// jsonString returns the TSV representation of a JSON value.
+func jsonString(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		if val {
+			return "T", true
+		}
+
+		return "F", true
+	}
+
+	return "", false
+}
+
+// unescape decodes the \xHH escape sequences used by Zeek for non printable characters and separators.
+func unescape(s string) string {
+	if !strings.Contains(s, "\\x") {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
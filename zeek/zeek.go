@@ -0,0 +1,152 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package zeek implements reading of Zeek logs in the TSV and JSON format,
+// and the conversion of the log records into netcap audit records.
+package zeek
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnknownFormat is returned when the input is neither a TSV nor a JSON log.
+	ErrUnknownFormat = errors.New("zeek: unknown log format")
+
+	// ErrUnsupportedLog is returned when converting records of a log that has no matching audit record type.
+	ErrUnsupportedLog = errors.New("zeek: unsupported log")
+)
+
+// Record is a single log entry, indexed by the field name.
+// Values are kept in the textual representation of the TSV format,
+// container fields hold one element per value and unset fields are omitted.
+type Record map[string][]string
+
+// Has returns true if the field is set.
+func (r Record) Has(name string) bool {
+	_, ok := r[name]
+	return ok
+}
+
+// String returns the value of the field, or the first element for containers.
+func (r Record) String(name string) string {
+	if v := r[name]; len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+// Strings returns all elements of a container field.
+func (r Record) Strings(name string) []string {
+	return r[name]
+}
+
+// Int returns the value of a numeric field, or zero if it is unset or invalid.
+func (r Record) Int(name string) int64 {
+	i, err := strconv.ParseInt(r.String(name), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return i
+}
+
+// Bool returns the value of a boolean field.
+func (r Record) Bool(name string) bool {
+	switch r.String(name) {
+	case "T", "true":
+		return true
+	}
+
+	return false
+}
+
+// Time returns the value of a time field, which is either in epoch seconds or ISO 8601.
+func (r Record) Time(name string) time.Time {
+	v := r.String(name)
+	if v == "" {
+		return time.Time{}
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t
+	}
+
+	return time.Unix(0, seconds(v))
+}
+
+// Duration returns the value of an interval field.
+func (r Record) Duration(name string) time.Duration {
+	return time.Duration(seconds(r.String(name)))
+}
+
+// seconds parses a decimal number of seconds into nanoseconds,
+// without the precision loss of a float64 for epoch timestamps.
+func seconds(v string) int64 {
+	if strings.ContainsAny(v, "eE") {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0
+		}
+
+		return int64(f * float64(time.Second))
+	}
+
+	var (
+		sec, frac = v, ""
+		neg       = strings.HasPrefix(v, "-")
+	)
+
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		sec, frac = v[:i], v[i+1:]
+	}
+
+	if len(frac) > 9 {
+		frac = frac[:9]
+	}
+
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil && sec != "" && sec != "-" {
+		return 0
+	}
+
+	var ns int64
+	if frac != "" {
+		ns, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return 0
+		}
+	}
+
+	if neg {
+		ns = -ns
+	}
+
+	return s*int64(time.Second) + ns
+}
+
+// LogPath returns the name of the log for a file name,
+// for example conn for conn.log or the rotated conn.00:00:00-01:00:00.log.gz.
+func LogPath(fileName string) string {
+	base := filepath.Base(fileName)
+	if i := strings.IndexByte(base, '.'); i > 0 {
+		return base[:i]
+	}
+
+	return base
+}
@@ -0,0 +1,257 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package zeek
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const connLog = `#separator \x09
+#set_separator	,
+#empty_field	(empty)
+#unset_field	-
+#path	conn
+#open	2020-01-01-00-00-00
+#fields	ts	uid	id.orig_h	id.orig_p	id.resp_h	id.resp_p	proto	service	duration	orig_bytes	resp_bytes	conn_state	local_orig	local_resp	missed_bytes	history	orig_pkts	orig_ip_bytes	resp_pkts	resp_ip_bytes	tunnel_parents	vlan
+#types	time	string	addr	port	addr	port	enum	string	interval	count	count	string	bool	bool	count	string	count	count	count	count	set[string]	int
+1577836800.123456	CHhAvVGS1DHFjwGM9	192.168.1.10	51234	93.184.216.34	443	tcp	ssl	1.500000	517	4200	SF	-	-	0	ShADadFf	10	1049	8	4632	(empty)	10
+1577836801.000001	C4J4Th3PJpwUYZZ6gc	10.0.0.1	8	10.0.0.2	0	icmp	-	-	-	-	OTH	-	-	0	-	1	84	0	0	Cab\x2cc,Cdef	-
+#close	2020-01-01-01-00-00
+`
+
+func TestReadTSV(t *testing.T) {
+	r := NewReader(strings.NewReader(connLog))
+
+	var records []Record
+
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, rec)
+	}
+
+	if r.Path() != "conn" || len(records) != 2 {
+		t.Fatal("unexpected path or number of records", r.Path(), len(records))
+	}
+
+	first := records[0]
+	if first.String("uid") != "CHhAvVGS1DHFjwGM9" || first.Int("id.resp_p") != 443 {
+		t.Fatal("unexpected values", first)
+	}
+
+	if !first.Time("ts").Equal(time.Unix(1577836800, 123456000)) || first.Duration("duration") != 1500*time.Millisecond {
+		t.Fatal("unexpected time values", first.Time("ts"), first.Duration("duration"))
+	}
+
+	if !first.Has("tunnel_parents") || len(first.Strings("tunnel_parents")) != 0 {
+		t.Fatal("expected an empty set", first.Strings("tunnel_parents"))
+	}
+
+	second := records[1]
+	if second.Has("service") || second.Has("local_orig") {
+		t.Fatal("expected unset fields to be omitted")
+	}
+
+	if parents := second.Strings("tunnel_parents"); len(parents) != 2 || parents[0] != "Cab,c" {
+		t.Fatal("unexpected set elements", parents)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	const dnsLog = `{"_path":"dns","ts":"2020-01-01T00:00:00.250000Z","uid":"CXWv6p3arKYeMETxOg","id.orig_h":"192.168.1.10","id.orig_p":53412,"id.resp_h":"8.8.8.8","id.resp_p":53,"proto":"udp","trans_id":4660,"query":"example.com","qclass":1,"qtype":1,"rcode":0,"AA":false,"TC":false,"RD":true,"RA":true,"Z":0,"answers":["www.example.com","93.184.216.34"],"TTLs":[300.0,60.0],"rejected":false}
+{"ts":1577836801.5,"id":{"orig_h":"fe80::1","orig_p":5353,"resp_h":"ff02::fb","resp_p":5353},"query":"_services._dns-sd._udp.local","qtype":12}
+`
+
+	r := NewReader(strings.NewReader(dnsLog))
+
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Path() != "dns" {
+		t.Fatal("expected path from _path field, got", r.Path())
+	}
+
+	msgs, err := Convert(r.Path(), rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dns := msgs[0].(*types.DNS)
+	if dns.ID != 4660 || !dns.QR || !dns.RD || dns.AA || dns.Timestamp != time.Date(2020, 1, 1, 0, 0, 0, 250000000, time.UTC).UnixNano() {
+		t.Fatal("unexpected header", dns)
+	}
+
+	if dns.SrcPort != 53412 || dns.DstPort != 53 || dns.Transport != "UDP" {
+		t.Fatal("unexpected connection id", dns.SrcPort, dns.DstPort, dns.Transport)
+	}
+
+	if len(dns.Questions) != 1 || dns.Questions[0].Name != "example.com" || dns.ANCount != 2 {
+		t.Fatal("unexpected question or answer count", dns.Questions, dns.ANCount)
+	}
+
+	if a := dns.Answers[0]; a.Type != 5 || string(a.CNAME) != "www.example.com" || a.TTL != 300 {
+		t.Fatal("unexpected CNAME answer", a)
+	}
+
+	if a := dns.Answers[1]; a.Type != 1 || a.IP != "93.184.216.34" || a.TTL != 60 {
+		t.Fatal("unexpected A answer", a)
+	}
+
+	// nested objects are flattened
+	rec, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.String("id.orig_h") != "fe80::1" || rec.Int("id.resp_p") != 5353 || rec.Time("ts").UnixNano() != 1577836801500000000 {
+		t.Fatal("unexpected record", rec)
+	}
+
+	if _, err = r.Next(); err != io.EOF {
+		t.Fatal("expected io.EOF, got", err)
+	}
+
+	if _, err = NewReader(strings.NewReader("ts,uid\n")).Next(); err != ErrUnknownFormat {
+		t.Fatal("expected ErrUnknownFormat, got", err)
+	}
+}
+
+func TestConvertConnection(t *testing.T) {
+	r := NewReader(strings.NewReader(connLog))
+
+	rec, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := Convert("conn", rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := msgs[0].(*types.Connection)
+	if c.UID != "CHhAvVGS1DHFjwGM9" || c.TransportProto != "TCP" || c.NetworkProto != "IPv4" || c.ApplicationProto != "ssl" {
+		t.Fatal("unexpected protocols", c)
+	}
+
+	if c.TotalSize != 5681 || c.NumPackets != 18 || c.BytesClientToServer != 1049 || c.AppPayloadSize != 4717 {
+		t.Fatal("unexpected counters", c)
+	}
+
+	if c.Duration != int64(1500*time.Millisecond) || c.TimestampLast-c.TimestampFirst != c.Duration {
+		t.Fatal("unexpected timestamps", c)
+	}
+
+	if c.NumSYNFlags != 2 || c.NumACKFlags != 3 || c.NumFINFlags != 2 || c.Encapsulation != "vlan=10" {
+		t.Fatal("unexpected flags or encapsulation", c)
+	}
+
+	rec, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ = Convert("conn", rec)
+	if c = msgs[0].(*types.Connection); c.TransportProto != "ICMPv4" || c.Encapsulation != "" {
+		t.Fatal("unexpected ICMP connection", c)
+	}
+
+	if _, err = Convert("weird", rec); err != ErrUnsupportedLog {
+		t.Fatal("expected ErrUnsupportedLog, got", err)
+	}
+}
+
+func TestConvertRecords(t *testing.T) {
+	conn := Record{
+		"ts":        {"1577836800.0"},
+		"id.orig_h": {"10.0.0.1"},
+		"id.orig_p": {"40000"},
+		"id.resp_h": {"10.0.0.2"},
+		"id.resp_p": {"22"},
+	}
+
+	with := func(values Record) Record {
+		r := make(Record)
+		for k, v := range conn {
+			r[k] = v
+		}
+
+		for k, v := range values {
+			r[k] = v
+		}
+
+		return r
+	}
+
+	msgs, _ := Convert("ssh", with(Record{"client": {"SSH-2.0-OpenSSH_8.2"}, "server": {"SSH-2.0-OpenSSH_7.4"}, "hassh": {"ec7378c1a92f5a8dde7e8b7a1ddf33d1"}}))
+	if len(msgs) != 2 {
+		t.Fatal("expected records for client and server")
+	}
+
+	if client, server := msgs[0].(*types.SSH), msgs[1].(*types.SSH); !client.IsClient || server.IsClient || client.Flow != "10.0.0.1:40000->10.0.0.2:22" || server.Flow != "10.0.0.2:22->10.0.0.1:40000" || client.HASSH == "" {
+		t.Fatal("unexpected ssh records", client, server)
+	}
+
+	// a file downloaded from the responder, as logged by Zeek 5
+	msgs, _ = Convert("files", with(Record{"fuid": {"FBtZ7y1ppK8iIeY622"}, "is_orig": {"F"}, "seen_bytes": {"1024"}, "mime_type": {"text/html"}, "source": {"HTTP"}}))
+	if f := msgs[0].(*types.File); f.Name != "FBtZ7y1ppK8iIeY622" || f.Length != 1024 || f.SrcIP != "10.0.0.2" || f.SrcPort != 22 || f.Ident != "10.0.0.2:22->10.0.0.1:40000" {
+		t.Fatal("unexpected file", f)
+	}
+
+	msgs, _ = Convert("x509", Record{"id": {"FnXjLd1X6wsBJwIXP1"}, "certificate.subject": {"CN=www.example.org,O=Example,C=US"}, "san.dns": {"www.example.org", "example.org"}})
+	if f := msgs[0].(*types.File); f.Name != "www.example.org" || f.Host != "www.example.org" || f.Source != "x509" {
+		t.Fatal("unexpected certificate", f)
+	}
+
+	msgs, _ = Convert("smtp", with(Record{"helo": {"mail.example.com"}, "mailfrom": {"a@example.com"}, "rcptto": {"b@example.com", "c@example.com"}, "msg_id": {"<1@example.com>"}, "tls": {"F"}}))
+	if s := msgs[0].(*types.SMTP); strings.Join(s.Commands, ";") != "HELO;MAIL FROM;RCPT TO;RCPT TO;DATA" || len(s.MailIDs) != 1 {
+		t.Fatal("unexpected smtp", s)
+	}
+
+	msgs, _ = Convert("notice", Record{"ts": {"1577836800.0"}, "note": {"Scan::Port_Scan"}, "msg": {"10.0.0.1 scanned at least 15 unique ports"}, "src": {"10.0.0.1"}})
+	if a := msgs[0].(*types.Alert); a.Name != "Scan::Port_Scan" || a.SrcIP != "10.0.0.1" {
+		t.Fatal("unexpected alert", a)
+	}
+
+	msgs, _ = Convert("ssl", with(Record{"version": {"TLSv12"}, "server_name": {"example.com"}, "next_protocol": {"h2"}}))
+	if h := msgs[0].(*types.TLSClientHello); h.Version != 0x0303 || h.SNI != "example.com" || h.ALPNs[0] != "h2" {
+		t.Fatal("unexpected client hello", h)
+	}
+}
+
+func TestLogPath(t *testing.T) {
+	for name, expected := range map[string]string{
+		"conn.log": "conn",
+		"/logs/2020-01-01/dns.00:00:00-01:00:00.log.gz": "dns",
+		"x509.log": "x509",
+	} {
+		if p := LogPath(name); p != expected {
+			t.Fatal("expected", expected, "for", name, "got", p)
+		}
+	}
+}